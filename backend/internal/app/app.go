@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -12,8 +13,11 @@ import (
 	"github.com/typefunco/dealer_dev_platform/internal/config"
 	"github.com/typefunco/dealer_dev_platform/internal/database"
 	"github.com/typefunco/dealer_dev_platform/internal/delivery"
+	"github.com/typefunco/dealer_dev_platform/internal/mailer"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 	"github.com/typefunco/dealer_dev_platform/internal/repository"
 	"github.com/typefunco/dealer_dev_platform/internal/service/aftersales"
+	"github.com/typefunco/dealer_dev_platform/internal/service/apikey"
 	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
 	"github.com/typefunco/dealer_dev_platform/internal/service/dealer"
 	"github.com/typefunco/dealer_dev_platform/internal/service/dealerdev"
@@ -21,11 +25,19 @@ import (
 	"github.com/typefunco/dealer_dev_platform/internal/service/performance"
 	"github.com/typefunco/dealer_dev_platform/internal/service/performance_aftersales"
 	"github.com/typefunco/dealer_dev_platform/internal/service/performance_sales"
+	"github.com/typefunco/dealer_dev_platform/internal/service/promotion"
+	"github.com/typefunco/dealer_dev_platform/internal/service/region"
 	"github.com/typefunco/dealer_dev_platform/internal/service/sales"
 	"github.com/typefunco/dealer_dev_platform/internal/service/user"
 	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
 )
 
+// regionRefreshInterval период перечитывания справочника регионов из БД.
+const regionRefreshInterval = time.Minute
+
+// importTrashPurgeInterval период очистки корзины загрузок от кварталов с истекшим сроком хранения.
+const importTrashPurgeInterval = time.Hour
+
 // RunApp запускает приложение.
 func RunApp() {
 	// Инициализация логгера
@@ -75,7 +87,6 @@ func RunApp() {
 func run(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config, logger *slog.Logger) error {
 	// Инициализация репозиториев
 	dealerRepo := repository.NewDealerRepository(pool)
-	excelDealerRepo := repository.NewExcelDealerRepository(pool, logger)
 	dealerDevRepo := repository.NewDealerDevRepository(pool)
 	salesRepo := repository.NewSalesRepository(pool)
 	performanceRepo := repository.NewPerformanceRepository(pool)
@@ -85,26 +96,111 @@ func run(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config, logger *sl
 	authRepo := repository.NewAuthRepository(pool, logger)
 	userRepo := repository.NewUserRepository(pool, logger)
 	dynamicRepo := repository.NewDynamicTableRepository(pool, logger)
+	regionRepo := repository.NewRegionRepository(pool, logger)
+	promotionRepo := repository.NewDealerNetPromotionRepository(pool, logger)
+	catalogRepo := repository.NewImportCatalogRepository(pool, logger)
+	serviceAccountRepo := repository.NewServiceAccountRepository(pool, logger)
+	twoFactorRepo := repository.NewTwoFactorRepository(pool, logger)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(pool, logger)
+	sessionRepo := repository.NewSessionRepository(pool, logger)
+	passwordTokenRepo := repository.NewPasswordTokenRepository(pool, logger)
 
 	logger.Info("Repositories initialized")
 
 	// Инициализация сервисов
 	jwtService := jwt.NewService()
-	authService := auth.NewService(authRepo, jwtService, logger)
+	authProviders, err := newAuthProviders(cfg.LDAP)
+	if err != nil {
+		return err
+	}
+	if cfg.LDAP.Enabled() {
+		logger.Info("LDAP authentication enabled", slog.String("url", cfg.LDAP.URL))
+	}
+	oidcProvider, err := newOIDCProvider(cfg.OIDC)
+	if err != nil {
+		return err
+	}
+	if oidcProvider != nil {
+		logger.Info("OIDC login enabled", slog.String("issuer", cfg.OIDC.IssuerURL))
+	}
+	mail, err := newMailer(cfg.Mail)
+	if err != nil {
+		return err
+	}
+	if mail != nil {
+		logger.Info("Email enabled", slog.String("smtp_host", cfg.Mail.SMTPHost), slog.String("file_dir", cfg.Mail.FileDir))
+		if cfg.Mail.BaseURL == "" {
+			logger.Warn("APP_BASE_URL is not set, invitations and password reset are disabled")
+		}
+	}
+	authService := auth.NewService(authRepo, jwtService, auth.Options{
+		Providers: authProviders,
+		OIDC:      oidcProvider,
+		TwoFactor: twoFactorRepo,
+		Throttle: auth.ThrottleConfig{
+			MaxFailures:     cfg.LoginThrottle.MaxFailures,
+			LockoutDuration: cfg.LoginThrottle.LockoutDuration,
+			BackoffBase:     cfg.LoginThrottle.BackoffBase,
+			BackoffMax:      cfg.LoginThrottle.BackoffMax,
+			IPMaxFailures:   cfg.LoginThrottle.IPMaxFailures,
+			Window:          cfg.LoginThrottle.Window,
+		},
+		Attempts: loginAttemptRepo,
+		Sessions: sessionRepo,
+		PasswordPolicy: auth.PasswordPolicy{
+			MinLength:     cfg.PasswordPolicy.MinLength,
+			RequireUpper:  cfg.PasswordPolicy.RequireUpper,
+			RequireLower:  cfg.PasswordPolicy.RequireLower,
+			RequireDigit:  cfg.PasswordPolicy.RequireDigit,
+			RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+		},
+		Mailer:         mail,
+		PasswordTokens: passwordTokenRepo,
+		PasswordLinks: auth.PasswordLinkConfig{
+			BaseURL:   cfg.Mail.BaseURL,
+			InviteTTL: cfg.Mail.InviteTTL,
+			ResetTTL:  cfg.Mail.ResetTTL,
+		},
+	}, logger)
 	perfService := performance.NewService(performanceRepo, logger)
 	perfSalesService := performance_sales.NewService(performanceSalesRepo, logger)
 	perfASService := performance_aftersales.NewService(performanceASRepo, logger)
 	userService := user.NewService(userRepo, logger)
-	afterSalesService := aftersales.NewService(afterSalesRepo, excelDealerRepo, logger)
-	dealerService := dealer.NewService(dealerRepo, excelDealerRepo, logger)
-	salesService := sales.NewService(salesRepo, excelDealerRepo, logger)
-	dealerDevService := dealerdev.NewService(dealerDevRepo, excelDealerRepo, logger)
-	excelService := excel.NewService(dynamicRepo, logger)
+	afterSalesService := aftersales.NewService(afterSalesRepo, logger)
+	dealerService := dealer.NewService(dealerRepo, logger)
+	salesService := sales.NewService(salesRepo, logger)
+	dealerDevService := dealerdev.NewService(dealerDevRepo, logger)
+	promotionService := promotion.NewService(promotionRepo, logger)
+	excelService := excel.NewService(dynamicRepo, catalogRepo, promotionService, excel.Options{
+		TrashRetention: cfg.ImportTrashRetention,
+		HeaderRows:     cfg.ExcelHeaderRows,
+	}, logger)
+	regionService := region.NewService(regionRepo, logger)
+	apiKeyService := apikey.NewService(serviceAccountRepo, logger)
+
+	// Загружаем справочник регионов; при ошибке остается встроенный список
+	if err := regionService.Load(ctx); err != nil {
+		logger.Warn("Failed to load region registry, using built-in regions", slog.String("error", err.Error()))
+	}
+	go regionService.StartRefresh(ctx, regionRefreshInterval)
+	go excelService.StartPurge(ctx, importTrashPurgeInterval)
+
+	// Переносим кварталы, загруженные до появления переноса в нормализованные таблицы
+	go func() {
+		promoted, err := promotionService.PromotePending(ctx)
+		if err != nil {
+			logger.Error("Failed to promote pending dealer_net periods", slog.String("error", err.Error()))
+			return
+		}
+		if promoted > 0 {
+			logger.Info("Pending dealer_net periods promoted", slog.Int("periods", promoted))
+		}
+	}()
 
 	logger.Info("Services initialized")
 
 	// Инициализация HTTP сервера
-	server := delivery.NewServer(authService, jwtService, perfService, perfSalesService, perfASService, userService, afterSalesService, dealerService, salesService, dealerDevService, excelService, dynamicRepo, pool, cfg.MaxFileSize, logger)
+	server := delivery.NewServer(authService, jwtService, perfService, perfSalesService, perfASService, userService, afterSalesService, dealerService, salesService, dealerDevService, excelService, regionService, apiKeyService, cfg.MaxFileSize, logger)
 	logger.Info("HTTP server initialized", slog.String("port", cfg.ServerPort))
 
 	// Graceful shutdown
@@ -141,3 +237,103 @@ func shutdown(ctx context.Context, pool *pgxpool.Pool, logger *slog.Logger) erro
 
 	return nil
 }
+
+// newAuthProviders создает внешние провайдеры входа по конфигурации.
+// Локальные учетные записи auth.Service проверяет сам, после внешних провайдеров.
+func newAuthProviders(cfg config.LDAPConfig) ([]auth.Provider, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	groupRoles, err := auth.ParseGroupMappings(cfg.GroupRoles)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP_GROUP_ROLES: %w", err)
+	}
+
+	groupRegions, err := auth.ParseGroupMappings(cfg.GroupRegions)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP_GROUP_REGIONS: %w", err)
+	}
+
+	provider, err := auth.NewLDAPProvider(auth.LDAPConfig{
+		URL:                cfg.URL,
+		StartTLS:           cfg.StartTLS,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		BindDN:             cfg.BindDN,
+		BindPassword:       cfg.BindPassword,
+		BaseDN:             cfg.BaseDN,
+		UserFilter:         cfg.UserFilter,
+		GroupBaseDN:        cfg.GroupBaseDN,
+		GroupFilter:        cfg.GroupFilter,
+		GroupRoles:         groupRoles,
+		GroupRegions:       groupRegions,
+		DefaultRole:        model.UserRole(cfg.DefaultRole),
+		Timeout:            cfg.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []auth.Provider{provider}, nil
+}
+
+// newMailer создает отправку писем по конфигурации: SMTP, если задан сервер, иначе файлы в каталоге;
+// nil - письма не отправляются.
+func newMailer(cfg config.MailConfig) (auth.Mailer, error) {
+	switch {
+	case cfg.SMTPHost != "":
+		smtpMailer, err := mailer.NewSMTP(mailer.SMTPConfig{
+			Host:               cfg.SMTPHost,
+			Port:               cfg.SMTPPort,
+			Username:           cfg.SMTPUsername,
+			Password:           cfg.SMTPPassword,
+			From:               cfg.From,
+			ImplicitTLS:        cfg.SMTPImplicitTLS,
+			InsecureSkipVerify: cfg.SMTPInsecureSkipVerify,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("MAIL_SMTP_HOST: %w", err)
+		}
+		return smtpMailer, nil
+	case cfg.FileDir != "":
+		fileSink, err := mailer.NewFileSink(cfg.FileDir, cfg.From)
+		if err != nil {
+			return nil, fmt.Errorf("MAIL_FILE_DIR: %w", err)
+		}
+		return fileSink, nil
+	}
+	return nil, nil
+}
+
+// newOIDCProvider создает провайдера входа через OIDC по конфигурации; nil - вход выключен.
+func newOIDCProvider(cfg config.OIDCConfig) (*auth.OIDCProvider, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	groupRoles, err := auth.ParseGroupMappings(cfg.GroupRoles)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC_GROUP_ROLES: %w", err)
+	}
+
+	groupRegions, err := auth.ParseGroupMappings(cfg.GroupRegions)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC_GROUP_REGIONS: %w", err)
+	}
+
+	return auth.NewOIDCProvider(auth.OIDCConfig{
+		IssuerURL:    cfg.IssuerURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+		FrontendURL:  cfg.FrontendURL,
+		LoginClaim:   cfg.LoginClaim,
+		GroupsClaim:  cfg.GroupsClaim,
+		RegionClaim:  cfg.RegionClaim,
+		GroupRoles:   groupRoles,
+		GroupRegions: groupRegions,
+		DefaultRole:  model.UserRole(cfg.DefaultRole),
+		TrustIDPMFA:  cfg.TrustIDPMFA,
+	})
+}
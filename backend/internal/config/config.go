@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config содержит конфигурацию приложения.
@@ -14,6 +16,82 @@ type Config struct {
 	MaxFileSize int64  // Максимальный размер файла в байтах (по умолчанию 100MB)
 	LogLevel    string // Уровень логирования (по умолчанию INFO)
 	DBMaxConns  int32  // Максимальное количество соединений с БД (по умолчанию 25)
+
+	ImportTrashRetention time.Duration // Срок хранения удаленных кварталов в корзине (по умолчанию 30 дней)
+	ExcelHeaderRows      int           // Строк заголовка в листах dealer_net (по умолчанию 0 - определяется автоматически)
+
+	LDAP LDAPConfig // Вход через LDAP/Active Directory; выключен, если LDAP_URL не задан
+	OIDC OIDCConfig // Вход через корпоративный портал (OpenID Connect); выключен, если OIDC_ISSUER_URL не задан
+
+	LoginThrottle  LoginThrottleConfig  // Задержки после неудачных входов и блокировка учетных записей
+	PasswordPolicy PasswordPolicyConfig // Требования к паролю при смене пароля пользователем
+
+	Mail MailConfig // Отправка приглашений и ссылок для сброса пароля; выключена без MAIL_SMTP_HOST и MAIL_FILE_DIR
+}
+
+// LDAPConfig содержит настройки входа через LDAP/Active Directory.
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupRoles         string // Сопоставления "группа=роль" через ";"
+	GroupRegions       string // Сопоставления "группа=регион" через ";"
+	DefaultRole        string
+	Timeout            time.Duration
+}
+
+// Enabled сообщает, настроен ли вход через LDAP.
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// OIDCConfig содержит настройки входа через OpenID Connect.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string   // Адрес /auth/oidc/callback, зарегистрированный у провайдера
+	Scopes       []string // Дополнительные scope через запятую; openid добавляется всегда
+	LoginClaim   string
+	GroupsClaim  string
+	RegionClaim  string
+	GroupRoles   string // Сопоставления "группа=роль" через ";"
+	GroupRegions string // Сопоставления "группа=регион" через ";"
+	DefaultRole  string
+	FrontendURL  string // Адрес фронтенда, куда возвращается пользователь после входа; пусто - тот же хост
+	TrustIDPMFA  bool   // Не запрашивать второй фактор платформы, если портал подтвердил его в claim amr
+}
+
+// MailConfig содержит настройки отправки писем и ссылок из них.
+type MailConfig struct {
+	SMTPHost               string
+	SMTPPort               int // По умолчанию 25
+	SMTPUsername           string
+	SMTPPassword           string
+	SMTPImplicitTLS        bool // Соединение сразу по TLS (порт 465); иначе STARTTLS, если сервер его поддерживает
+	SMTPInsecureSkipVerify bool
+	From                   string // Адрес отправителя
+	FileDir                string // Каталог для писем вместо SMTP (разработка и тесты)
+
+	BaseURL   string        // Адрес фронтенда для ссылок в письмах
+	InviteTTL time.Duration // Срок приглашения (по умолчанию 72 часа)
+	ResetTTL  time.Duration // Срок ссылки для сброса пароля (по умолчанию 60 минут)
+}
+
+// Enabled сообщает, настроена ли отправка писем.
+func (c MailConfig) Enabled() bool {
+	return c.SMTPHost != "" || c.FileDir != ""
+}
+
+// Enabled сообщает, настроен ли вход через OIDC.
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
 }
 
 // Load загружает конфигурацию из переменных окружения.
@@ -26,6 +104,8 @@ func Load() (*Config, error) {
 		MaxFileSize: 100 * 1024 * 1024, // 100MB по умолчанию
 		LogLevel:    "INFO",
 		DBMaxConns:  25,
+
+		ImportTrashRetention: 30 * 24 * time.Hour, // 30 дней по умолчанию
 	}
 
 	// Парсим MaxFileSize из переменной окружения
@@ -47,6 +127,26 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Парсим срок хранения корзины загрузок из переменной окружения
+	if retentionStr := os.Getenv("IMPORT_TRASH_RETENTION_DAYS"); retentionStr != "" {
+		if retentionDays, err := strconv.Atoi(retentionStr); err == nil && retentionDays > 0 {
+			cfg.ImportTrashRetention = time.Duration(retentionDays) * 24 * time.Hour
+		}
+	}
+
+	// Парсим количество строк заголовка листов из переменной окружения
+	if headerRowsStr := os.Getenv("EXCEL_HEADER_ROWS"); headerRowsStr != "" {
+		if headerRows, err := strconv.Atoi(headerRowsStr); err == nil && headerRows >= 0 {
+			cfg.ExcelHeaderRows = headerRows
+		}
+	}
+
+	cfg.LDAP = loadLDAPConfig()
+	cfg.OIDC = loadOIDCConfig()
+	cfg.LoginThrottle = loadLoginThrottleConfig()
+	cfg.PasswordPolicy = loadPasswordPolicyConfig()
+	cfg.Mail = loadMailConfig()
+
 	// Валидация обязательных полей
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
@@ -63,6 +163,149 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// LoginThrottleConfig содержит ограничения попыток входа. Нулевое значение выключает ограничение.
+type LoginThrottleConfig struct {
+	MaxFailures     int           // Неудачных входов подряд до блокировки учетной записи (по умолчанию 5)
+	LockoutDuration time.Duration // Срок блокировки (по умолчанию 15 минут)
+	BackoffBase     time.Duration // Задержка после первой неудачи, удваивается с каждой следующей (по умолчанию 1 секунда)
+	BackoffMax      time.Duration // Наибольшая задержка (по умолчанию 60 секунд)
+	IPMaxFailures   int           // Неудач с одного адреса до задержек по адресу (по умолчанию 20)
+	Window          time.Duration // Через сколько после последней неудачи задержки забываются (по умолчанию 15 минут)
+}
+
+// PasswordPolicyConfig содержит требования к новому паролю.
+type PasswordPolicyConfig struct {
+	MinLength     int  // Наименьшая длина (по умолчанию 8)
+	RequireUpper  bool // Заглавная буква (по умолчанию нет)
+	RequireLower  bool // Строчная буква (по умолчанию нет)
+	RequireDigit  bool // Цифра (по умолчанию да)
+	RequireSymbol bool // Специальный символ (по умолчанию нет)
+}
+
+// loadLDAPConfig загружает настройки LDAP из переменных окружения.
+func loadLDAPConfig() LDAPConfig {
+	cfg := LDAPConfig{
+		URL:          os.Getenv("LDAP_URL"),
+		BindDN:       os.Getenv("LDAP_BIND_DN"),
+		BindPassword: os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:       os.Getenv("LDAP_BASE_DN"),
+		UserFilter:   os.Getenv("LDAP_USER_FILTER"),
+		GroupBaseDN:  os.Getenv("LDAP_GROUP_BASE_DN"),
+		GroupFilter:  os.Getenv("LDAP_GROUP_FILTER"),
+		GroupRoles:   os.Getenv("LDAP_GROUP_ROLES"),
+		GroupRegions: os.Getenv("LDAP_GROUP_REGIONS"),
+		DefaultRole:  os.Getenv("LDAP_DEFAULT_ROLE"),
+	}
+
+	if startTLS, err := strconv.ParseBool(os.Getenv("LDAP_START_TLS")); err == nil {
+		cfg.StartTLS = startTLS
+	}
+
+	if skipVerify, err := strconv.ParseBool(os.Getenv("LDAP_INSECURE_SKIP_VERIFY")); err == nil {
+		cfg.InsecureSkipVerify = skipVerify
+	}
+
+	if timeoutStr := os.Getenv("LDAP_TIMEOUT_SECONDS"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil && timeout > 0 {
+			cfg.Timeout = time.Duration(timeout) * time.Second
+		}
+	}
+
+	return cfg
+}
+
+// loadLoginThrottleConfig загружает ограничения попыток входа из переменных окружения.
+func loadLoginThrottleConfig() LoginThrottleConfig {
+	cfg := LoginThrottleConfig{
+		MaxFailures:     envInt("LOGIN_MAX_FAILURES", 5),
+		LockoutDuration: time.Duration(envInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+		BackoffBase:     time.Duration(envInt("LOGIN_BACKOFF_BASE_SECONDS", 1)) * time.Second,
+		BackoffMax:      time.Duration(envInt("LOGIN_BACKOFF_MAX_SECONDS", 60)) * time.Second,
+		IPMaxFailures:   envInt("LOGIN_IP_MAX_FAILURES", 20),
+		Window:          time.Duration(envInt("LOGIN_WINDOW_MINUTES", 15)) * time.Minute,
+	}
+
+	// Блокировка без срока не снималась бы сама
+	if cfg.LockoutDuration == 0 {
+		cfg.MaxFailures = 0
+	}
+
+	return cfg
+}
+
+// loadPasswordPolicyConfig загружает политику паролей из переменных окружения.
+func loadPasswordPolicyConfig() PasswordPolicyConfig {
+	return PasswordPolicyConfig{
+		MinLength:     envInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:  envBool("PASSWORD_REQUIRE_UPPERCASE", false),
+		RequireLower:  envBool("PASSWORD_REQUIRE_LOWERCASE", false),
+		RequireDigit:  envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: envBool("PASSWORD_REQUIRE_SYMBOL", false),
+	}
+}
+
+// loadMailConfig загружает настройки отправки писем из переменных окружения.
+func loadMailConfig() MailConfig {
+	return MailConfig{
+		SMTPHost:               os.Getenv("MAIL_SMTP_HOST"),
+		SMTPPort:               envInt("MAIL_SMTP_PORT", 25),
+		SMTPUsername:           os.Getenv("MAIL_SMTP_USERNAME"),
+		SMTPPassword:           os.Getenv("MAIL_SMTP_PASSWORD"),
+		SMTPImplicitTLS:        envBool("MAIL_SMTP_IMPLICIT_TLS", false),
+		SMTPInsecureSkipVerify: envBool("MAIL_SMTP_INSECURE_SKIP_VERIFY", false),
+		From:                   os.Getenv("MAIL_FROM"),
+		FileDir:                os.Getenv("MAIL_FILE_DIR"),
+		BaseURL:                strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"),
+		InviteTTL:              time.Duration(envInt("INVITE_TTL_HOURS", 72)) * time.Hour,
+		ResetTTL:               time.Duration(envInt("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute,
+	}
+}
+
+// envBool читает флаг из переменной окружения.
+func envBool(name string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// envInt читает неотрицательное число из переменной окружения; 0 выключает настройку.
+func envInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// loadOIDCConfig загружает настройки OIDC из переменных окружения.
+func loadOIDCConfig() OIDCConfig {
+	cfg := OIDCConfig{
+		IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		LoginClaim:   os.Getenv("OIDC_LOGIN_CLAIM"),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		RegionClaim:  os.Getenv("OIDC_REGION_CLAIM"),
+		GroupRoles:   os.Getenv("OIDC_GROUP_ROLES"),
+		GroupRegions: os.Getenv("OIDC_GROUP_REGIONS"),
+		DefaultRole:  os.Getenv("OIDC_DEFAULT_ROLE"),
+		FrontendURL:  strings.TrimRight(os.Getenv("OIDC_FRONTEND_URL"), "/"),
+		TrustIDPMFA:  envBool("OIDC_TRUST_IDP_MFA", false),
+	}
+
+	// Парсим список scope из переменной окружения
+	for _, scope := range strings.Split(os.Getenv("OIDC_SCOPES"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			cfg.Scopes = append(cfg.Scopes, scope)
+		}
+	}
+
+	return cfg
+}
+
 // MaskDSN маскирует пароль в DSN для безопасного логирования.
 func MaskDSN(dsn string) string {
 	if len(dsn) > 20 {
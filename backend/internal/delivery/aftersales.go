@@ -1,29 +1,31 @@
 package delivery
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 	"github.com/typefunco/dealer_dev_platform/internal/utils"
 )
 
 // AfterSalesDealerResponse представляет дилера с данными After Sales для API.
 type AfterSalesDealerResponse struct {
-	ID                    string  `json:"id"`
-	Name                  string  `json:"name"`
-	City                  string  `json:"city"`
-	RStockPercent         *int    `json:"rStockPercent"`         // Recommended Stock
-	WStockPercent         *int    `json:"wStockPercent"`         // Warranty Stock
-	FlhPercent            *int    `json:"flhPercent"`            // Foton Labor Hours
-	FlhSharePercent       *string `json:"flhSharePercent"`       // Foton Labour Hours Share
-	WarrantyHours         *int    `json:"warrantyHours"`         // Foton Warranty Hours
-	ServiceContractsHours *int    `json:"serviceContractsHours"` // Service Contracts
-	AsTrainings           *bool   `json:"asTrainings"`           // AS Trainings status
-	CSI                   *string `json:"csi"`                   // Customer Satisfaction Index
-	AsDecision            *string `json:"asDecision"`            // AS Decision
-	SparePartsSalesQ3     *string `json:"sparePartsSalesQ3"`     // Spare Parts Sales Q3
-	SparePartsSalesYtd    *string `json:"sparePartsSalesYtd"`    // Spare Parts Sales YTD %
+	ID                     string  `json:"id"`
+	Name                   string  `json:"name"`
+	City                   string  `json:"city"`
+	RStockPercent          *int    `json:"rStockPercent"`          // Recommended Stock
+	WStockPercent          *int    `json:"wStockPercent"`          // Warranty Stock
+	FlhPercent             *int    `json:"flhPercent"`             // Foton Labor Hours
+	FlhSharePercent        *string `json:"flhSharePercent"`        // Foton Labour Hours Share
+	WarrantyHours          *int    `json:"warrantyHours"`          // Foton Warranty Hours
+	ServiceContractsHours  *int    `json:"serviceContractsHours"`  // Service Contracts
+	AsTrainings            *bool   `json:"asTrainings"`            // AS Trainings status
+	CSI                    *string `json:"csi"`                    // Customer Satisfaction Index
+	AsDecision             *string `json:"asDecision"`             // AS Decision
+	SparePartsSalesQuarter *string `json:"sparePartsSalesQuarter"` // Spare Parts Sales за квартал выборки
+	SparePartsSalesYtd     *string `json:"sparePartsSalesYtd"`     // Spare Parts Sales YTD %
 }
 
 // GetAfterSalesData возвращает данные послепродажного обслуживания с поддержкой фильтров.
@@ -72,19 +74,141 @@ func (s *Server) GetAfterSalesData(c echo.Context) error {
 	response := make([]AfterSalesDealerResponse, 0, len(afterSalesList))
 	for _, as := range afterSalesList {
 		response = append(response, AfterSalesDealerResponse{
-			ID:                    strconv.FormatInt(int64(as.DealerID), 10),
-			Name:                  as.DealerNameRu,
-			City:                  as.City,
-			RStockPercent:         &as.RecommendedStock,
-			WStockPercent:         &as.WarrantyStock,
-			FlhPercent:            &as.FotonLaborHours,
-			WarrantyHours:         &as.FotonWarrantyHours,
-			ServiceContractsHours: &as.ServiceContracts,
-			AsTrainings:           &as.ASTrainings,
-			CSI:                   as.CSI,
-			AsDecision:            &as.ASDecision,
+			ID:                     strconv.FormatInt(int64(as.DealerID), 10),
+			Name:                   as.DealerNameRu,
+			City:                   as.City,
+			RStockPercent:          &as.RecommendedStock,
+			WStockPercent:          &as.WarrantyStock,
+			FlhPercent:             &as.FotonLaborHours,
+			FlhSharePercent:        &as.FotonLabourHoursShare,
+			WarrantyHours:          &as.FotonWarrantyHours,
+			ServiceContractsHours:  &as.ServiceContracts,
+			AsTrainings:            &as.ASTrainings,
+			CSI:                    as.CSI,
+			AsDecision:             &as.ASDecision,
+			SparePartsSalesQuarter: &as.SparePartsSalesQuarter,
+			SparePartsSalesYtd:     &as.SparePartsSalesYtdPct,
 		})
 	}
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// GetAfterSalesRecord возвращает запись after_sales дилера за квартал с версией в заголовке ETag.
+// @Summary Get after_sales record
+// @Description Получение записи показателей дилера за квартал; ETag используется в If-Match при изменении
+// @Tags after_sales
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Success 200 {object} model.AfterSales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/after_sales/{dealerId}/{year}/{quarter} [get]
+func (s *Server) GetAfterSalesRecord(c echo.Context) error {
+	return getRecord(s, c, "GetAfterSalesRecord", s.afterSalesRecords())
+}
+
+// CreateAfterSalesRecord создает запись after_sales дилера за квартал.
+// @Summary Create after_sales record
+// @Description Создание записи показателей дилера за квартал с валидацией полей
+// @Tags after_sales
+// @Accept json
+// @Produce json
+// @Param record body model.AfterSales true "Record"
+// @Success 201 {object} model.AfterSales
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/after_sales [post]
+func (s *Server) CreateAfterSalesRecord(c echo.Context) error {
+	return createRecord(s, c, "CreateAfterSalesRecord", s.afterSalesRecords())
+}
+
+// ReplaceAfterSalesRecord полностью заменяет запись after_sales дилера за квартал.
+// @Summary Replace after_sales record
+// @Description Полная замена записи показателей дилера за квартал
+// @Tags after_sales
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param record body model.AfterSales true "Record"
+// @Success 200 {object} model.AfterSales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/after_sales/{dealerId}/{year}/{quarter} [put]
+func (s *Server) ReplaceAfterSalesRecord(c echo.Context) error {
+	return replaceRecord(s, c, "ReplaceAfterSalesRecord", s.afterSalesRecords())
+}
+
+// PatchAfterSalesRecord частично обновляет запись after_sales дилера за квартал.
+// @Summary Patch after_sales record
+// @Description Частичное обновление записи показателей дилера за квартал (JSON Merge Patch, только разрешенные поля)
+// @Tags after_sales
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param updates body map[string]interface{} true "JSON Merge Patch с изменяемыми полями"
+// @Success 200 {object} model.AfterSales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/after_sales/{dealerId}/{year}/{quarter} [patch]
+func (s *Server) PatchAfterSalesRecord(c echo.Context) error {
+	return patchRecord(s, c, "PatchAfterSalesRecord", s.afterSalesRecords())
+}
+
+// DeleteAfterSalesRecord удаляет запись after_sales дилера за квартал.
+// @Summary Delete after_sales record
+// @Description Удаление записи показателей дилера за квартал
+// @Tags after_sales
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/after_sales/{dealerId}/{year}/{quarter} [delete]
+func (s *Server) DeleteAfterSalesRecord(c echo.Context) error {
+	return deleteRecord(s, c, "DeleteAfterSalesRecord", s.afterSalesRecords())
+}
+
+// afterSalesRecords операции записи after_sales для общих обработчиков записей показателей.
+func (s *Server) afterSalesRecords() recordService[model.AfterSales] {
+	return recordService[model.AfterSales]{
+		get: s.afterSalesService.GetAfterSalesByDealerAndPeriod,
+		create: func(ctx context.Context, record *model.AfterSales) error {
+			_, err := s.afterSalesService.CreateAfterSales(ctx, record)
+			return err
+		},
+		replace:     s.afterSalesService.ReplaceAfterSales,
+		patch:       s.afterSalesService.PatchAfterSales,
+		delete:      s.afterSalesService.DeleteAfterSalesByDealerAndPeriod,
+		version:     func(record *model.AfterSales) int { return record.Version },
+		patchSchema: model.AfterSalesPatchSchema,
+	}
+}
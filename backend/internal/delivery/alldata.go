@@ -228,12 +228,7 @@ func (s *Server) GetAllData(c echo.Context) error {
 			allData.SalesTargetFact = nil
 			serviceContractsFloat := float64(sales.ServiceContractsSales)
 			allData.ServiceContractsSales = &serviceContractsFloat
-			var salesTrainingsStatus model.SalesTrainingsStatus
-			if sales.SalesTrainings {
-				salesTrainingsStatus = model.SalesTrainingsYes
-			} else {
-				salesTrainingsStatus = model.SalesTrainingsNo
-			}
+			salesTrainingsStatus := model.YesNoOf(sales.SalesTrainings)
 			allData.SalesTrainings = &salesTrainingsStatus
 			allData.SalesRecommendation = &sales.SalesDecision
 		}
@@ -2,10 +2,14 @@ package delivery
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
 )
 
 const (
@@ -26,9 +30,21 @@ type LoginResponse struct {
 		IsAdmin bool   `json:"is_admin"`
 		Role    string `json:"role"`
 	} `json:"user"`
+
+	// RecoveryCodes коды восстановления, если второй фактор подключался во время входа
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// TwoFactorChallengeResponse ответ на вход, когда пароль верный, но нужен второй фактор.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired  bool      `json:"two_factor_required"`
+	ChallengeToken     string    `json:"challenge_token"`
+	EnrollmentRequired bool      `json:"enrollment_required"` // Сначала подключить приложение: POST /auth/login/2fa/enroll
+	ExpiresAt          time.Time `json:"expires_at"`
 }
 
-// Login - ручка логина.
+// Login - ручка логина. Если пользователю нужен второй фактор, вместо токена
+// возвращается TwoFactorChallengeResponse, а токен выдает POST /auth/login/2fa.
 func (s *Server) Login(c echo.Context) error {
 	var req LoginRequest
 	err := c.Bind(&req)
@@ -41,7 +57,23 @@ func (s *Server) Login(c echo.Context) error {
 	ctx, deadline := context.WithTimeout(context.Background(), ttl)
 	defer deadline()
 
-	claims, err := s.authService.Login(ctx, req.Login, req.Password)
+	claims, err := s.authService.Login(ctx, req.Login, req.Password, c.RealIP())
+	var throttled *auth.LoginThrottled
+	if errors.As(err, &throttled) {
+		return loginThrottledResponse(c, throttled)
+	}
+	var challenge *auth.TwoFactorChallenge
+	if errors.As(err, &challenge) {
+		return c.JSON(http.StatusOK, TwoFactorChallengeResponse{
+			TwoFactorRequired:  true,
+			ChallengeToken:     challenge.Token,
+			EnrollmentRequired: challenge.EnrollmentRequired,
+			ExpiresAt:          challenge.ExpiresAt,
+		})
+	}
+	if errors.Is(err, model.ErrAccountInactive) {
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Account is suspended or deactivated"})
+	}
 	if err != nil {
 		s.logger.Error("Login failed", "login", req.Login, "error", err)
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{
@@ -49,8 +81,7 @@ func (s *Server) Login(c echo.Context) error {
 		})
 	}
 
-	// Генерируем новый токен для ответа
-	token, err := s.authService.GenerateToken(claims.Login, claims.IsAdmin, claims.Role)
+	response, err := s.loginResponse(ctx, c, claims)
 	if err != nil {
 		s.logger.Error("Failed to generate JWT", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -58,7 +89,17 @@ func (s *Server) Login(c echo.Context) error {
 		})
 	}
 
-	response := LoginResponse{
+	return c.JSON(http.StatusOK, response)
+}
+
+// loginResponse выпускает токен новой сессии для ответа на вход.
+func (s *Server) loginResponse(ctx context.Context, c echo.Context, claims *jwt.JWTClaims) (*LoginResponse, error) {
+	token, err := s.authService.StartSession(ctx, claims, c.RealIP(), c.Request().UserAgent())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &LoginResponse{
 		Token: token,
 		User: struct {
 			Login   string `json:"login"`
@@ -71,5 +112,5 @@ func (s *Server) Login(c echo.Context) error {
 		},
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return response, nil
 }
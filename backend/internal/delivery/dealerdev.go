@@ -1,10 +1,12 @@
 package delivery
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 )
 
 // DealerDevResponse представляет дилера с данными Dealer Development для API.
@@ -95,3 +97,122 @@ func (s *Server) GetDealerDevData(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// GetDealerDevRecord возвращает запись dealer_dev дилера за квартал с версией в заголовке ETag.
+// @Summary Get dealer_dev record
+// @Description Получение записи показателей дилера за квартал; ETag используется в If-Match при изменении
+// @Tags dealer_dev
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Success 200 {object} model.DealerDevelopment
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dealer_dev/{dealerId}/{year}/{quarter} [get]
+func (s *Server) GetDealerDevRecord(c echo.Context) error {
+	return getRecord(s, c, "GetDealerDevRecord", s.dealerDevRecords())
+}
+
+// CreateDealerDevRecord создает запись dealer_dev дилера за квартал.
+// @Summary Create dealer_dev record
+// @Description Создание записи показателей дилера за квартал с валидацией полей
+// @Tags dealer_dev
+// @Accept json
+// @Produce json
+// @Param record body model.DealerDevelopment true "Record"
+// @Success 201 {object} model.DealerDevelopment
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dealer_dev [post]
+func (s *Server) CreateDealerDevRecord(c echo.Context) error {
+	return createRecord(s, c, "CreateDealerDevRecord", s.dealerDevRecords())
+}
+
+// ReplaceDealerDevRecord полностью заменяет запись dealer_dev дилера за квартал.
+// @Summary Replace dealer_dev record
+// @Description Полная замена записи показателей дилера за квартал
+// @Tags dealer_dev
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param record body model.DealerDevelopment true "Record"
+// @Success 200 {object} model.DealerDevelopment
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dealer_dev/{dealerId}/{year}/{quarter} [put]
+func (s *Server) ReplaceDealerDevRecord(c echo.Context) error {
+	return replaceRecord(s, c, "ReplaceDealerDevRecord", s.dealerDevRecords())
+}
+
+// PatchDealerDevRecord частично обновляет запись dealer_dev дилера за квартал.
+// @Summary Patch dealer_dev record
+// @Description Частичное обновление записи показателей дилера за квартал (JSON Merge Patch, только разрешенные поля)
+// @Tags dealer_dev
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param updates body map[string]interface{} true "JSON Merge Patch с изменяемыми полями"
+// @Success 200 {object} model.DealerDevelopment
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dealer_dev/{dealerId}/{year}/{quarter} [patch]
+func (s *Server) PatchDealerDevRecord(c echo.Context) error {
+	return patchRecord(s, c, "PatchDealerDevRecord", s.dealerDevRecords())
+}
+
+// DeleteDealerDevRecord удаляет запись dealer_dev дилера за квартал.
+// @Summary Delete dealer_dev record
+// @Description Удаление записи показателей дилера за квартал
+// @Tags dealer_dev
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dealer_dev/{dealerId}/{year}/{quarter} [delete]
+func (s *Server) DeleteDealerDevRecord(c echo.Context) error {
+	return deleteRecord(s, c, "DeleteDealerDevRecord", s.dealerDevRecords())
+}
+
+// dealerDevRecords операции записи dealer_dev для общих обработчиков записей показателей.
+func (s *Server) dealerDevRecords() recordService[model.DealerDevelopment] {
+	return recordService[model.DealerDevelopment]{
+		get: s.dealerDevService.GetDealerDevByDealerAndPeriod,
+		create: func(ctx context.Context, record *model.DealerDevelopment) error {
+			_, err := s.dealerDevService.CreateDealerDev(ctx, record)
+			return err
+		},
+		replace:     s.dealerDevService.ReplaceDealerDev,
+		patch:       s.dealerDevService.PatchDealerDev,
+		delete:      s.dealerDevService.DeleteDealerDevByDealerAndPeriod,
+		version:     func(record *model.DealerDevelopment) int { return record.Version },
+		patchSchema: model.DealerDevelopmentPatchSchema,
+	}
+}
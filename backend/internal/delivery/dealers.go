@@ -1,6 +1,7 @@
 package delivery
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -51,43 +52,17 @@ func (s *Server) GetDealerCard(c echo.Context) error {
 	}
 
 	// Получение данных из сервиса
-	var cardData *model.DealerCardData
-
-	// Если указаны год и квартал, используем Excel данные
-	if year > 0 && quarter != "" {
-		s.logger.Info("Getting dealer card from Excel table",
+	cardData, err := s.dealerService.GetDealerCard(c.Request().Context(), id, quarter, year)
+	if err != nil {
+		s.logger.Error("GetDealerCard: failed to get dealer card",
 			slog.Int64("id", id),
-			slog.Int("year", year),
 			slog.String("quarter", quarter),
+			slog.Int("year", year),
+			slog.String("error", err.Error()),
 		)
-
-		// Получаем карточку дилера по ID из Excel таблицы
-		cardData, err = s.dealerService.GetDealerByIDFromExcel(c.Request().Context(), year, quarter, int(id))
-		if err != nil {
-			s.logger.Error("GetDealerCard: failed to get dealer card from Excel",
-				slog.Int64("id", id),
-				slog.Int("year", year),
-				slog.String("quarter", quarter),
-				slog.String("error", err.Error()),
-			)
-			return c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: "Dealer data not found in Excel table",
-			})
-		}
-	} else {
-		// Используем обычные данные
-		cardData, err = s.dealerService.GetDealerCard(c.Request().Context(), id, quarter, year)
-		if err != nil {
-			s.logger.Error("GetDealerCard: failed to get dealer card",
-				slog.Int64("id", id),
-				slog.String("quarter", quarter),
-				slog.Int("year", year),
-				slog.String("error", err.Error()),
-			)
-			return c.JSON(http.StatusNotFound, ErrorResponse{
-				Error: "Dealer not found or data unavailable",
-			})
-		}
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "Dealer not found or data unavailable",
+		})
 	}
 
 	return c.JSON(http.StatusOK, cardData)
@@ -118,41 +93,19 @@ func (s *Server) GetDealers(c echo.Context) error {
 	var dealers []*model.Dealer
 	var err error
 
-	// Если указаны год и квартал, используем Excel данные
-	if filters.Year > 0 && filters.Quarter != "" {
-		s.logger.Info("Getting dealers from Excel table",
-			slog.Int("year", filters.Year),
-			slog.String("quarter", filters.Quarter),
-			slog.String("region", filters.Region),
-		)
-
-		dealers, err = s.dealerService.GetDealersFromExcel(c.Request().Context(), filters.Year, filters.Quarter, filters)
-		if err != nil {
-			s.logger.Error("GetDealers: failed to get dealers from Excel",
-				slog.Int("year", filters.Year),
-				slog.String("quarter", filters.Quarter),
-				slog.String("error", err.Error()),
-			)
-			return c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error: "Failed to get dealers from Excel data",
-			})
-		}
+	if filters.HasPeriodFilter() || filters.Region != "" || len(filters.DealerIDs) > 0 || filters.Limit > 0 || filters.Offset > 0 {
+		dealers, err = s.dealerService.GetDealersWithFilters(c.Request().Context(), filters)
 	} else {
-		// Используем обычные данные из основной таблицы
-		if filters.Region != "" || len(filters.DealerIDs) > 0 || filters.Limit > 0 || filters.Offset > 0 {
-			dealers, err = s.dealerService.GetDealersWithFilters(c.Request().Context(), filters)
-		} else {
-			dealers, err = s.dealerService.GetAllDealers(c.Request().Context())
-		}
+		dealers, err = s.dealerService.GetAllDealers(c.Request().Context())
+	}
 
-		if err != nil {
-			s.logger.Error("GetDealers: failed to get dealers",
-				slog.String("error", err.Error()),
-			)
-			return c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error: "Failed to get dealers",
-			})
-		}
+	if err != nil {
+		s.logger.Error("GetDealers: failed to get dealers",
+			slog.String("error", err.Error()),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to get dealers",
+		})
 	}
 
 	return c.JSON(http.StatusOK, dealers)
@@ -189,6 +142,61 @@ func (s *Server) GetDealerByID(c echo.Context) error {
 		})
 	}
 
+	setETag(c, dealer.Version)
+	return c.JSON(http.StatusOK, dealer)
+}
+
+// UpdateDealerJointDecision изменяет совместное решение по дилеру.
+// @Summary Update dealer joint decision
+// @Description Изменение совместного решения по дилеру с проверкой версии (If-Match)
+// @Tags dealers
+// @Accept json
+// @Produce json
+// @Param id path int true "Dealer ID"
+// @Param If-Match header string true "Версия дилера (ETag), * - без проверки"
+// @Param request body model.JointDecisionRequest true "Joint decision"
+// @Success 200 {object} model.Dealer
+// @Header 200 {string} ETag "Версия дилера"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/dealers/{id}/joint-decision [put]
+func (s *Server) UpdateDealerJointDecision(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid dealer ID",
+		})
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		return s.recordErrorResponse(c, "UpdateDealerJointDecision", err)
+	}
+
+	var req model.JointDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	dealer, err := s.dealerService.SetJointDecision(c.Request().Context(), id, version, req.JointDecision)
+	if err != nil {
+		if errors.Is(err, model.ErrVersionConflict) {
+			current, getErr := s.dealerService.GetDealerByID(c.Request().Context(), id)
+			if getErr != nil {
+				return s.recordErrorResponse(c, "UpdateDealerJointDecision", getErr)
+			}
+			return versionConflictResponse(c, current.Version, current)
+		}
+		return s.recordErrorResponse(c, "UpdateDealerJointDecision", err)
+	}
+
+	setETag(c, dealer.Version)
 	return c.JSON(http.StatusOK, dealer)
 }
 
@@ -324,20 +324,20 @@ func (s *Server) getAfterSalesData(c echo.Context, filters *model.FilterParams)
 	response := make([]AfterSalesDealerResponse, 0, len(afterSalesList))
 	for _, as := range afterSalesList {
 		response = append(response, AfterSalesDealerResponse{
-			ID:                    strconv.Itoa(as.DealerID),
-			Name:                  as.DealerNameRu,
-			City:                  as.City,
-			RStockPercent:         &as.RecommendedStock,
-			WStockPercent:         &as.WarrantyStock,
-			FlhPercent:            &as.FotonLaborHours,
-			FlhSharePercent:       &as.FotonLabourHoursShare,
-			WarrantyHours:         &as.FotonWarrantyHours,
-			ServiceContractsHours: &as.ServiceContracts,
-			AsTrainings:           &as.ASTrainings,
-			CSI:                   as.CSI,
-			AsDecision:            &as.ASDecision,
-			SparePartsSalesQ3:     &as.SparePartsSalesQ3,
-			SparePartsSalesYtd:    &as.SparePartsSalesYtd,
+			ID:                     strconv.Itoa(as.DealerID),
+			Name:                   as.DealerNameRu,
+			City:                   as.City,
+			RStockPercent:          &as.RecommendedStock,
+			WStockPercent:          &as.WarrantyStock,
+			FlhPercent:             &as.FotonLaborHours,
+			FlhSharePercent:        &as.FotonLabourHoursShare,
+			WarrantyHours:          &as.FotonWarrantyHours,
+			ServiceContractsHours:  &as.ServiceContracts,
+			AsTrainings:            &as.ASTrainings,
+			CSI:                    as.CSI,
+			AsDecision:             &as.ASDecision,
+			SparePartsSalesQuarter: &as.SparePartsSalesQuarter,
+			SparePartsSalesYtd:     &as.SparePartsSalesYtdPct,
 		})
 	}
 
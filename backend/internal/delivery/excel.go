@@ -1,29 +1,45 @@
 package delivery
 
 import (
-	"context"
+	"bytes"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/service/excel"
 )
 
+// uploadMemoryLimit объем multipart-формы, который держится в памяти при загрузке файла.
+const uploadMemoryLimit = 8 << 20
+
 // UploadExcelFile обрабатывает загрузку Excel файла.
 // @Summary Upload Excel file
-// @Description Загружает Excel файл и конвертирует его в таблицы PostgreSQL
+// @Description Загружает файл dealer_net (XLSX, ODS, CSV или zip-архив с CSV по регионам и manifest.json) в таблицы PostgreSQL
 // @Tags excel
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "Excel file (.xlsx)"
+// @Param file formData file true "Dealer net file (.xlsx, .ods, .csv, .zip)"
 // @Success 200 {object} model.ExcelUploadResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} model.ExcelUploadResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/excel/upload [post]
 func (s *Server) UploadExcelFile(c echo.Context) error {
+	// Файлы больше лимита сохраняются во временные файлы, а не держатся в памяти
+	if err := c.Request().ParseMultipartForm(uploadMemoryLimit); err != nil {
+		s.logger.Error("Failed to parse multipart form", slog.String("error", err.Error()))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "No file provided",
+		})
+	}
+
 	// Получаем файл из формы
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -46,10 +62,10 @@ func (s *Server) UploadExcelFile(c echo.Context) error {
 	}
 
 	// Проверяем расширение файла
-	if !isExcelFile(file.Filename) {
+	if !excel.IsSupportedFile(file.Filename) {
 		s.logger.Error("Invalid file type", slog.String("file_name", file.Filename))
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Only .xlsx files are supported",
+			Error: fmt.Sprintf("Only %s files are supported", strings.Join(excel.SupportedExtensions(), ", ")),
 		})
 	}
 
@@ -72,7 +88,7 @@ func (s *Server) UploadExcelFile(c echo.Context) error {
 	)
 
 	// Обрабатываем файл
-	result, err := s.excelService.ProcessExcelFile(c.Request().Context(), src, file.Filename)
+	result, err := s.excelService.ProcessExcelFile(c.Request().Context(), src, file.Filename, currentLogin(c))
 	if err != nil {
 		s.logger.Error("Failed to process Excel file",
 			slog.String("file_name", file.Filename),
@@ -83,6 +99,24 @@ func (s *Server) UploadExcelFile(c echo.Context) error {
 		})
 	}
 
+	// Листы с ошибками: транзакция откатана, возвращаем отчет
+	if !result.Success {
+		s.logger.Error("Excel file rejected",
+			slog.String("file_name", file.Filename),
+			slog.Int("errors", len(result.Errors)),
+		)
+		return c.JSON(http.StatusUnprocessableEntity, model.ExcelUploadResponse{
+			Status:         "error",
+			Message:        "Файл не загружен: исправьте ошибки в листах",
+			TablesCreated:  []string{},
+			ProcessingTime: result.ProcessingTime,
+			Errors:         result.Errors,
+			ColumnReports:  result.ColumnReports,
+			ValueIssues:    result.ValueIssues,
+			DatasetID:      result.DatasetID,
+		})
+	}
+
 	// Формируем ответ
 	response := model.ExcelUploadResponse{
 		Status:         "success",
@@ -90,6 +124,11 @@ func (s *Server) UploadExcelFile(c echo.Context) error {
 		TablesCreated:  make([]string, len(result.TablesCreated)),
 		RowsInserted:   result.TotalRows,
 		ProcessingTime: result.ProcessingTime,
+		ColumnReports:  result.ColumnReports,
+		FormulaReports: result.FormulaReports,
+		FormulaErrors:  result.FormulaErrors,
+		ValueIssues:    result.ValueIssues,
+		DatasetID:      result.DatasetID,
 	}
 
 	for i, table := range result.TablesCreated {
@@ -106,75 +145,72 @@ func (s *Server) UploadExcelFile(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
-// GetExcelTables возвращает список созданных таблиц из Excel файлов.
-// @Summary Get Excel tables
-// @Description Возвращает список всех динамически созданных таблиц из Excel файлов
+// GetImports возвращает каталог загрузок Excel файлов.
+// @Summary Get imports
+// @Description Возвращает каталог загрузок: период, тип, исходный файл, количество строк, автора, статус и схему
 // @Tags excel
 // @Produce json
-// @Success 200 {array} model.ExcelTableMetadata
+// @Success 200 {array} model.ImportDataset
 // @Failure 500 {object} ErrorResponse
-// @Router /api/excel/tables [get]
-func (s *Server) GetExcelTables(c echo.Context) error {
-	tables, err := s.excelService.GetDynamicTables(c.Request().Context())
+// @Router /api/admin/excel/imports [get]
+func (s *Server) GetImports(c echo.Context) error {
+	datasets, err := s.excelService.ListImports(c.Request().Context())
 	if err != nil {
-		s.logger.Error("Failed to get dynamic tables", slog.String("error", err.Error()))
+		s.logger.Error("Failed to get imports", slog.String("error", err.Error()))
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: "Failed to get dynamic tables",
+			Error: "Failed to get imports",
 		})
 	}
 
-	return c.JSON(http.StatusOK, tables)
+	return c.JSON(http.StatusOK, datasets)
 }
 
-// GetExcelTableMetadata возвращает метаданные конкретной таблицы.
-// @Summary Get Excel table metadata
-// @Description Возвращает метаданные указанной таблицы
+// GetImport возвращает запись каталога загрузок.
+// @Summary Get import
+// @Description Возвращает запись каталога загрузок по ID
 // @Tags excel
 // @Produce json
-// @Param tableName path string true "Table Name"
-// @Success 200 {object} model.ExcelTableMetadata
+// @Param id path int true "Import ID"
+// @Success 200 {object} model.ImportDataset
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /api/excel/tables/{tableName} [get]
-func (s *Server) GetExcelTableMetadata(c echo.Context) error {
-	tableName := c.Param("tableName")
-	if tableName == "" {
+// @Router /api/admin/excel/imports/{id} [get]
+func (s *Server) GetImport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Table name is required",
+			Error: "Invalid import ID",
 		})
 	}
 
-	metadata, err := s.excelService.GetTableMetadata(c.Request().Context(), tableName)
+	dataset, err := s.excelService.GetImport(c.Request().Context(), id)
 	if err != nil {
-		s.logger.Error("Failed to get table metadata",
-			slog.String("table_name", tableName),
-			slog.String("error", err.Error()),
-		)
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Error: "Table not found",
-		})
+		return s.importError(c, "GetImport", err)
 	}
 
-	return c.JSON(http.StatusOK, metadata)
+	return c.JSON(http.StatusOK, dataset)
 }
 
-// GetExcelTableData возвращает данные из указанной таблицы.
-// @Summary Get Excel table data
-// @Description Возвращает данные из указанной таблицы с пагинацией
+// GetImportData возвращает строки загрузки с пагинацией.
+// @Summary Get import data
+// @Description Возвращает строки загрузки dealer_net с пагинацией
 // @Tags excel
 // @Produce json
-// @Param tableName path string true "Table Name"
+// @Param id path int true "Import ID"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(50)
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /api/excel/tables/{tableName}/data [get]
-func (s *Server) GetExcelTableData(c echo.Context) error {
-	tableName := c.Param("tableName")
-	if tableName == "" {
+// @Router /api/admin/excel/imports/{id}/data [get]
+func (s *Server) GetImportData(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Table name is required",
+			Error: "Invalid import ID",
 		})
 	}
 
@@ -197,28 +233,10 @@ func (s *Server) GetExcelTableData(c echo.Context) error {
 
 	offset := (page - 1) * limit
 
-	// Получаем данные из таблицы
-	data, err := s.getTableData(c.Request().Context(), tableName, limit, offset)
+	// Получаем строки загрузки за квартал
+	_, data, totalCount, err := s.excelService.GetImportData(c.Request().Context(), id, limit, offset)
 	if err != nil {
-		s.logger.Error("Failed to get table data",
-			slog.String("table_name", tableName),
-			slog.String("error", err.Error()),
-		)
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: "Failed to get table data",
-		})
-	}
-
-	// Получаем общее количество записей
-	totalCount, err := s.getTableCount(c.Request().Context(), tableName)
-	if err != nil {
-		s.logger.Error("Failed to get table count",
-			slog.String("table_name", tableName),
-			slog.String("error", err.Error()),
-		)
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: "Failed to get table count",
-		})
+		return s.importError(c, "GetImportData", err)
 	}
 
 	response := map[string]interface{}{
@@ -234,121 +252,173 @@ func (s *Server) GetExcelTableData(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
-// DeleteExcelTable удаляет указанную таблицу.
-// @Summary Delete Excel table
-// @Description Удаляет указанную динамически созданную таблицу
+// ExportImport выгружает квартал загрузки в XLSX в формате шаблона загрузки.
+// @Summary Export import data
+// @Description Лист на регион, колонки с названиями квартала загрузки (spare_parts_sales_q1 и т.д.); файл можно загрузить повторно
 // @Tags excel
-// @Produce json
-// @Param tableName path string true "Table Name"
-// @Success 200 {object} map[string]string
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param id path int true "Import ID"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /api/excel/tables/{tableName} [delete]
-func (s *Server) DeleteExcelTable(c echo.Context) error {
-	tableName := c.Param("tableName")
-	if tableName == "" {
+// @Router /api/admin/excel/imports/{id}/export [get]
+func (s *Server) ExportImport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Table name is required",
+			Error: "Invalid import ID",
 		})
 	}
 
-	// Проверяем, что таблица существует
-	exists, err := s.dynamicRepo.TableExists(c.Request().Context(), tableName)
+	var buf bytes.Buffer
+	dataset, err := s.excelService.ExportImport(c.Request().Context(), id, &buf)
 	if err != nil {
-		s.logger.Error("Failed to check table existence",
-			slog.String("table_name", tableName),
-			slog.String("error", err.Error()),
-		)
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: "Failed to check table existence",
-		})
+		return s.importError(c, "ExportImport", err)
 	}
 
-	if !exists {
-		return c.JSON(http.StatusNotFound, ErrorResponse{
-			Error: "Table not found",
-		})
-	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", excel.ExportFileName(dataset)))
+	return c.Blob(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", buf.Bytes())
+}
 
-	// Удаляем таблицу
-	query := fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName)
-	_, err = s.pool.Exec(c.Request().Context(), query)
+// DeleteImport переносит квартал загрузки в корзину.
+// @Summary Delete import
+// @Description Переносит строки загрузки dealer_net в корзину; квартал можно восстановить до окончания срока хранения
+// @Tags excel
+// @Produce json
+// @Param id path int true "Import ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/excel/imports/{id} [delete]
+func (s *Server) DeleteImport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		s.logger.Error("Failed to delete table",
-			slog.String("table_name", tableName),
-			slog.String("error", err.Error()),
-		)
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: "Failed to delete table",
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid import ID",
 		})
 	}
 
-	s.logger.Info("Table deleted successfully", slog.String("table_name", tableName))
+	if err := s.excelService.DeleteImport(c.Request().Context(), id, currentLogin(c)); err != nil {
+		return s.importError(c, "DeleteImport", err)
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Table deleted successfully",
+		"message": "Import moved to trash",
 	})
 }
 
-// isExcelFile проверяет, является ли файл Excel файлом.
-func isExcelFile(filename string) bool {
-	return len(filename) > 5 && filename[len(filename)-5:] == ".xlsx"
-}
+// GetDeletedImports возвращает корзину загрузок.
+// @Summary Get deleted imports
+// @Description Возвращает удаленные кварталы, которые можно восстановить, и время их окончательного удаления
+// @Tags excel
+// @Produce json
+// @Success 200 {array} model.ImportDataset
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/excel/imports/trash [get]
+func (s *Server) GetDeletedImports(c echo.Context) error {
+	datasets, err := s.excelService.ListDeletedImports(c.Request().Context())
+	if err != nil {
+		s.logger.Error("Failed to get deleted imports", slog.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to get deleted imports",
+		})
+	}
 
-// getTableData получает данные из таблицы с пагинацией.
-func (s *Server) getTableData(ctx context.Context, tableName string, limit, offset int) ([]map[string]interface{}, error) {
-	query := fmt.Sprintf("SELECT * FROM %s ORDER BY id LIMIT $1 OFFSET $2", tableName)
+	return c.JSON(http.StatusOK, datasets)
+}
 
-	rows, err := s.pool.Query(ctx, query, limit, offset)
+// RestoreImport возвращает квартал загрузки из корзины.
+// @Summary Restore import
+// @Description Возвращает строки удаленной загрузки dealer_net в dealer_metrics
+// @Tags excel
+// @Produce json
+// @Param id path int true "Import ID"
+// @Success 200 {object} model.ImportDataset
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/excel/imports/{id}/restore [post]
+func (s *Server) RestoreImport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		return nil, err
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid import ID",
+		})
 	}
-	defer rows.Close()
 
-	var result []map[string]interface{}
-	columns := rows.FieldDescriptions()
+	dataset, err := s.excelService.RestoreImport(c.Request().Context(), id)
+	if err != nil {
+		return s.importError(c, "RestoreImport", err)
+	}
 
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
+	return c.JSON(http.StatusOK, dataset)
+}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
-		}
+// PromoteImport повторно переносит загрузку в нормализованные таблицы.
+// @Summary Promote import
+// @Description Повторно переносит загрузку dealer_net в таблицы dealers, sales, dealer_dev и after_sales
+// @Tags excel
+// @Produce json
+// @Param id path int true "Import ID"
+// @Success 200 {object} model.PromotionResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/excel/imports/{id}/promote [post]
+func (s *Server) PromoteImport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid import ID",
+		})
+	}
 
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			row[col.Name] = values[i]
-		}
-		result = append(result, row)
+	result, err := s.excelService.PromoteImport(c.Request().Context(), id)
+	if err != nil {
+		return s.importError(c, "PromoteImport", err)
 	}
 
-	return result, rows.Err()
+	return c.JSON(http.StatusOK, result)
 }
 
-// getTableCount получает общее количество записей в таблице.
-func (s *Server) getTableCount(ctx context.Context, tableName string) (int, error) {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
-
-	var count int
-	err := s.pool.QueryRow(ctx, query).Scan(&count)
-	if err != nil {
-		return 0, err
+// importError отвечает на ошибку операции с каталогом загрузок.
+func (s *Server) importError(c echo.Context, handler string, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Import not found"})
+	case errors.Is(err, excel.ErrDatasetUnavailable):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Import data is not available"})
+	case errors.Is(err, excel.ErrDatasetNotRestorable):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Import is not in trash"})
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Quarter has been imported again, delete the current import first"})
 	}
 
-	return count, nil
+	s.logger.Error(handler+": failed to process import", slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error: "Failed to process import",
+	})
+}
+
+// currentLogin возвращает логин пользователя, установленный middleware аутентификации.
+func currentLogin(c echo.Context) string {
+	login, _ := c.Get("user_login").(string)
+	return login
 }
 
 // UploadBrandsFile обрабатывает загрузку файла с брендами и побочными бизнесами.
 // @Summary Upload brands file
-// @Description Загружает Excel файл с брендами и обновляет существующие записи дилеров в БД
+// @Description Загружает файл с брендами (XLSX, ODS или CSV) и обновляет существующие записи дилеров в БД
 // @Tags excel
 // @Accept multipart/form-data
 // @Produce json
-// @Param file formData file true "Excel file with brands and byside businesses (.xlsx)"
+// @Param file formData file true "File with brands and byside businesses (.xlsx, .ods, .csv)"
 // @Success 200 {object} model.BrandsUploadResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -376,10 +446,10 @@ func (s *Server) UploadBrandsFile(c echo.Context) error {
 	}
 
 	// Проверяем расширение файла
-	if !isExcelFile(file.Filename) {
+	if !excel.IsSupportedFile(file.Filename) {
 		s.logger.Error("Invalid file type", slog.String("file_name", file.Filename))
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Only .xlsx files are supported",
+			Error: fmt.Sprintf("Only %s files are supported", strings.Join(excel.SupportedExtensions(), ", ")),
 		})
 	}
 
@@ -402,7 +472,7 @@ func (s *Server) UploadBrandsFile(c echo.Context) error {
 	)
 
 	// Обрабатываем файл
-	result, err := s.excelService.ProcessBrandsFile(c.Request().Context(), src, file.Filename)
+	result, err := s.excelService.ProcessBrandsFile(c.Request().Context(), src, file.Filename, currentLogin(c))
 	if err != nil {
 		s.logger.Error("Failed to process brands file",
 			slog.String("file_name", file.Filename),
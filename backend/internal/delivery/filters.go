@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 	"github.com/typefunco/dealer_dev_platform/internal/utils"
 )
 
@@ -102,6 +103,7 @@ func ParseFilters(c echo.Context) (*FilterRequest, error) {
 	if err := utils.ValidateFilters(filters.Quarter, filters.Year, filters.Region); err != nil {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	filters.Region, _ = model.Regions().Canonical(filters.Region)
 
 	return filters, nil
 }
@@ -139,18 +141,20 @@ func CreateFilterResponse(data interface{}, filters *FilterRequest, total int) *
 // @Success 200 {object} AvailableFiltersResponse
 // @Router /api/filters [get]
 func (s *Server) GetAvailableFilters(c echo.Context) error {
+	regions := []map[string]string{
+		{"id": model.AllRussia, "name": "All Russia", "name_ru": "Вся Россия"},
+	}
+	for _, region := range model.Regions().List() {
+		regions = append(regions, map[string]string{
+			"id":      region.Name,
+			"code":    region.Code,
+			"name":    region.Name,
+			"name_ru": region.NameRu,
+		})
+	}
+
 	response := map[string]interface{}{
-		"regions": []map[string]string{
-			{"id": "all-russia", "name": "All Russia"},
-			{"id": "Central", "name": "Central"},
-			{"id": "North West", "name": "North West"},
-			{"id": "Volga", "name": "Volga"},
-			{"id": "South", "name": "South"},
-			{"id": "Kavkaz", "name": "Kavkaz"},
-			{"id": "Ural", "name": "Ural"},
-			{"id": "Siberia", "name": "Siberia"},
-			{"id": "Far East", "name": "Far East"},
-		},
+		"regions": regions,
 		"quarters": []map[string]string{
 			{"id": "Q1", "name": "Q1"},
 			{"id": "Q2", "name": "Q2"},
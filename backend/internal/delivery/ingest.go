@@ -0,0 +1,92 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/service/excel"
+)
+
+const (
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength длина ключа, которую вмещает ingest_requests.
+	maxIdempotencyKeyLength = 255
+)
+
+// IngestDealerMetrics загружает показатели дилеров в JSON как альтернатива файлу dealer_net.
+// @Summary Ingest dealer metrics
+// @Description Принимает пакет показателей дилеров по кварталам, проверяет их по словарю колонок загрузки Excel и сохраняет в одной транзакции.
+// @Description Пакет сохраняется целиком или не сохраняется вовсе; в ответе - результат по каждой записи.
+// @Description Повтор запроса с тем же Idempotency-Key возвращает сохраненный ответ с заголовком Idempotent-Replayed: true.
+// @Tags excel
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string true "Unique key of the batch"
+// @Param request body model.IngestDealerMetricsRequest true "Dealer metrics"
+// @Success 200 {object} model.IngestDealerMetricsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 422 {object} model.IngestDealerMetricsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/ingest/dealer-metrics [post]
+func (s *Server) IngestDealerMetrics(c echo.Context) error {
+	key := strings.TrimSpace(c.Request().Header.Get(headerIdempotencyKey))
+	if key == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Idempotency-Key header is required",
+		})
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Idempotency-Key must not exceed %d characters", maxIdempotencyKeyLength),
+		})
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, s.maxFileSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+				Error: fmt.Sprintf("Request size exceeds maximum allowed size of %d MB", s.maxFileSize/(1024*1024)),
+			})
+		}
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Failed to read request body",
+		})
+	}
+
+	response, err := s.excelService.IngestDealerMetrics(c.Request().Context(), currentLogin(c), key, body)
+	switch {
+	case errors.Is(err, excel.ErrInvalidIngestRequest):
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, excel.ErrIdempotencyKeyReused):
+		return c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error: "Idempotency-Key has already been used with a different request",
+		})
+	case err != nil:
+		s.logger.Error("IngestDealerMetrics: failed to ingest dealer metrics",
+			slog.String("idempotency_key", key),
+			slog.String("error", err.Error()),
+		)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to ingest dealer metrics",
+		})
+	}
+
+	if response.Replayed {
+		c.Response().Header().Set(headerIdempotentReplayed, "true")
+	}
+
+	if response.Status == model.IngestStatusRejected {
+		return c.JSON(http.StatusUnprocessableEntity, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,219 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
+)
+
+// InviteUserResponse ответ на приглашение пользователя.
+type InviteUserResponse struct {
+	User           UserAPIResponse `json:"user"`
+	InvitationSent bool            `json:"invitationSent"` // false - пользователь создан, но письмо не ушло; его можно отправить повторно
+}
+
+// PasswordTokenRequest запрос с токеном из ссылки.
+type PasswordTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// InviteUser создает пользователя без пароля и отправляет ему приглашение на почту.
+// @Summary Invite user by email
+// @Description Пользователь получает одноразовую ссылку, по которой задает пароль сам
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body CreateUserRequest true "User data"
+// @Success 201 {object} InviteUserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/users/invite [post]
+func (s *Server) InviteUser(c echo.Context) error {
+	if !s.authService.PasswordEmailsEnabled() {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Email is not configured"})
+	}
+
+	var req CreateUserRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+	if req.Email == "" || req.FirstName == "" || req.LastName == "" || req.Region == "" || req.Position == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "All fields are required: email, firstName, lastName, region, position",
+		})
+	}
+
+	role := mapPositionToRole(req.Position)
+	ctx := c.Request().Context()
+	user, err := s.userService.InviteUser(ctx, model.UserCreateRequest{
+		Login:     req.Email,
+		IsAdmin:   role == model.UserRoleAdmin,
+		Role:      role,
+		Region:    req.Region,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+	})
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "User with this email already exists"})
+	}
+	if err != nil {
+		s.logger.Error("InviteUser: failed to create user", "email", req.Email, "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create user"})
+	}
+
+	sent := true
+	if err := s.authService.SendInvitation(ctx, user.Login, currentLogin(c)); err != nil {
+		s.logger.Error("InviteUser: failed to send invitation", "login", user.Login, "error", err)
+		sent = false
+	}
+
+	setETag(c, user.Version)
+	return c.JSON(http.StatusCreated, InviteUserResponse{User: toUserAPIResponse(user), InvitationSent: sent})
+}
+
+// ResendInvitation отправляет приглашение повторно; прежняя ссылка перестает действовать.
+// @Summary Resend invitation
+// @Tags users
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /api/admin/users/{id}/invite [post]
+func (s *Server) ResendInvitation(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	}
+
+	ctx := c.Request().Context()
+	user, err := s.userService.GetUserByID(ctx, id)
+	if err != nil {
+		s.logger.Error("ResendInvitation: failed to get user", slog.Int64("id", id), slog.String("error", err.Error()))
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	err = s.authService.SendInvitation(ctx, user.Login, currentLogin(c))
+	switch {
+	case err == nil:
+		return c.NoContent(http.StatusNoContent)
+	case errors.Is(err, auth.ErrEmailDisabled):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Email is not configured"})
+	case errors.Is(err, auth.ErrUnknownUser):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	case errors.Is(err, auth.ErrPasswordAlreadySet):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "User has already set a password"})
+	case errors.Is(err, auth.ErrExternalPassword):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Password is managed by the identity provider"})
+	case errors.Is(err, model.ErrAccountInactive):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "User is suspended or deactivated"})
+	case errors.Is(err, auth.ErrNoEmail):
+		return c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "User has no email address"})
+	}
+
+	s.logger.Error("ResendInvitation: failed to send invitation", slog.Int64("id", id), slog.String("error", err.Error()))
+	return c.JSON(http.StatusBadGateway, ErrorResponse{Error: "Failed to send invitation email"})
+}
+
+// ForgotPassword отправляет ссылку для сброса пароля.
+// @Summary Request password reset
+// @Description Ответ не зависит от того, есть ли пользователь с таким логином или почтой
+// @Tags auth
+// @Accept json
+// @Param request body model.ForgotPasswordRequest true "Login or email"
+// @Success 202
+// @Failure 404 {object} ErrorResponse
+// @Router /auth/password/forgot [post]
+func (s *Server) ForgotPassword(c echo.Context) error {
+	var req model.ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil || req.Login == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "login is required"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ttl)
+	defer cancel()
+
+	err := s.authService.RequestPasswordReset(ctx, req.Login)
+	if errors.Is(err, auth.ErrEmailDisabled) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Password reset is not configured"})
+	}
+	if err != nil {
+		s.logger.Error("ForgotPassword: failed to request password reset", slog.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to request password reset"})
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// CheckPasswordLink проверяет ссылку из письма до ввода пароля.
+// @Summary Check password link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body PasswordTokenRequest true "Token"
+// @Success 200 {object} model.PasswordToken
+// @Failure 400 {object} ErrorResponse
+// @Router /auth/password/check [post]
+func (s *Server) CheckPasswordLink(c echo.Context) error {
+	var req PasswordTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+
+	token, err := s.authService.CheckPasswordToken(c.Request().Context(), req.Token)
+	if err != nil {
+		return s.passwordLinkError(c, "CheckPasswordLink", err)
+	}
+
+	return c.JSON(http.StatusOK, token)
+}
+
+// ResetPassword задает пароль по ссылке из приглашения или письма о сбросе.
+// @Summary Set password by email link
+// @Description Ссылка одноразовая. Блокировка входа снимается, все сессии пользователя завершаются
+// @Tags auth
+// @Accept json
+// @Param request body model.PasswordResetRequest true "Token and new password"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Router /auth/password/reset [post]
+func (s *Server) ResetPassword(c echo.Context) error {
+	var req model.PasswordResetRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if err := s.authService.ResetPassword(c.Request().Context(), req); err != nil {
+		return s.passwordLinkError(c, "ResetPassword", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// passwordLinkError преобразует ошибку установки пароля по ссылке в HTTP ответ.
+func (s *Server) passwordLinkError(c echo.Context, handler string, err error) error {
+	var fields model.ValidationErrors
+	switch {
+	case errors.Is(err, auth.ErrInvalidPasswordToken):
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Link is invalid or expired"})
+	case errors.As(err, &fields):
+		return c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  "Password does not meet the password policy",
+			Fields: fields,
+		})
+	}
+
+	s.logger.Error(handler+": failed to process password link", slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to process password link"})
+}
@@ -0,0 +1,101 @@
+package delivery
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
+)
+
+// GetLoginAttempts возвращает журнал попыток входа.
+// @Summary Get login attempts
+// @Description Попытки входа по паролю, второму фактору и через корпоративный портал, новые первыми
+// @Tags auth
+// @Produce json
+// @Param login query string false "Login"
+// @Param ip query string false "Client IP"
+// @Param success query bool false "Only successful or only failed attempts"
+// @Param since query string false "RFC 3339 time"
+// @Param limit query int false "Limit (default 100, max 1000)"
+// @Success 200 {array} model.LoginAttempt
+// @Failure 400 {object} ErrorResponse
+// @Router /api/admin/login-attempts [get]
+func (s *Server) GetLoginAttempts(c echo.Context) error {
+	filter := model.LoginAttemptFilter{
+		Login: c.QueryParam("login"),
+		IP:    c.QueryParam("ip"),
+	}
+
+	if successStr := c.QueryParam("success"); successStr != "" {
+		success, err := strconv.ParseBool(successStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid success parameter"})
+		}
+		filter.Success = &success
+	}
+
+	if sinceStr := c.QueryParam("since"); sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid since parameter, expected RFC 3339 time"})
+		}
+		filter.Since = &since
+	}
+
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit parameter"})
+		}
+		filter.Limit = limit
+	}
+
+	attempts, err := s.authService.ListLoginAttempts(c.Request().Context(), filter)
+	if err != nil {
+		s.logger.Error("GetLoginAttempts: failed to list login attempts", slog.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get login attempts"})
+	}
+
+	return c.JSON(http.StatusOK, attempts)
+}
+
+// UnlockUser снимает блокировку входа после неудачных попыток.
+// @Summary Unlock user
+// @Tags auth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/users/{id}/unlock [post]
+func (s *Server) UnlockUser(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	}
+
+	if err := s.authService.UnlockUser(c.Request().Context(), id, currentLogin(c)); err != nil {
+		if errors.Is(err, auth.ErrUnknownUser) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		}
+		s.logger.Error("UnlockUser: failed to unlock user", slog.Int64("id", id), slog.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to unlock user"})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// loginThrottledResponse отвечает 429 со временем до следующей попытки.
+func loginThrottledResponse(c echo.Context, throttled *auth.LoginThrottled) error {
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(throttled.RetryAfterSeconds()))
+
+	message := "Too many failed login attempts, please try again later"
+	if throttled.Locked {
+		message = "Account is temporarily locked after too many failed login attempts"
+	}
+	return c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: message})
+}
@@ -0,0 +1,262 @@
+package delivery
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
+	"github.com/typefunco/dealer_dev_platform/internal/service/user"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+)
+
+// RevokedSessionsResponse число отозванных сессий.
+type RevokedSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// sessionClaims возвращает claims токена пользователя. Запросы с ключом API
+// не относятся к сессии, и эндпоинты /api/me для них недоступны.
+func sessionClaims(c echo.Context) (*jwt.JWTClaims, bool) {
+	claims, ok := c.Get("user").(*jwt.JWTClaims)
+	return claims, ok && claims != nil
+}
+
+// userSessionRequired ответ на запрос к /api/me с ключом API.
+func userSessionRequired(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Only available for user sessions"})
+}
+
+// GetMe возвращает профиль текущего пользователя.
+// @Summary Get current user
+// @Tags me
+// @Produce json
+// @Success 200 {object} model.UserResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/me [get]
+func (s *Server) GetMe(c echo.Context) error {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return userSessionRequired(c)
+	}
+
+	me, err := s.userService.GetUserByLogin(c.Request().Context(), claims.Login)
+	if err != nil {
+		return s.profileError(c, "GetMe", err)
+	}
+
+	setETag(c, me.Version)
+	return c.JSON(http.StatusOK, me)
+}
+
+// UpdateMe меняет имя и почту текущего пользователя.
+// @Summary Update current user profile
+// @Description Роль, регион и права меняет только администратор. Профиль пользователей
+// @Description каталога и корпоративного портала обновляется провайдером при входе.
+// @Description If-Match необязателен: без него изменения применяются без проверки версии
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param If-Match header string false "ETag профиля"
+// @Param request body model.ProfileUpdateRequest true "Profile"
+// @Success 200 {object} model.UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse "Версия устарела или почта занята другим пользователем"
+// @Failure 422 {object} ValidationErrorResponse
+// @Router /api/me [patch]
+func (s *Server) UpdateMe(c echo.Context) error {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return userSessionRequired(c)
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil && !errors.Is(err, errIfMatchRequired) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	var expectedVersion *int
+	if version > 0 {
+		expectedVersion = &version
+	}
+
+	var req model.ProfileUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+
+	ctx := c.Request().Context()
+	me, err := s.userService.UpdateProfile(ctx, claims.Login, req, expectedVersion)
+	if errors.Is(err, model.ErrVersionConflict) {
+		current, getErr := s.userService.GetUserByLogin(ctx, claims.Login)
+		if getErr != nil {
+			return s.profileError(c, "UpdateMe", getErr)
+		}
+		return versionConflictResponse(c, current.Version, current)
+	}
+	if err != nil {
+		return s.profileError(c, "UpdateMe", err)
+	}
+
+	setETag(c, me.Version)
+	return c.JSON(http.StatusOK, me)
+}
+
+// ChangeMyPassword меняет пароль текущего пользователя.
+// @Summary Change current user password
+// @Description Требует текущий пароль; новый пароль проверяется по политике паролей.
+// @Description После смены остальные сессии пользователя завершаются
+// @Tags me
+// @Accept json
+// @Param request body model.PasswordChangeRequest true "Passwords"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /api/me/password [post]
+func (s *Server) ChangeMyPassword(c echo.Context) error {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return userSessionRequired(c)
+	}
+
+	var req model.PasswordChangeRequest
+	if err := c.Bind(&req); err != nil || req.CurrentPassword == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "current_password and new_password are required"})
+	}
+
+	err := s.authService.ChangePassword(c.Request().Context(), claims, req, c.RealIP())
+	var throttled *auth.LoginThrottled
+	var fields model.ValidationErrors
+	switch {
+	case err == nil:
+		return c.NoContent(http.StatusNoContent)
+	case errors.As(err, &throttled):
+		return loginThrottledResponse(c, throttled)
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Current password is incorrect"})
+	case errors.As(err, &fields):
+		return c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  "Password does not meet the password policy",
+			Fields: fields,
+		})
+	case errors.Is(err, auth.ErrExternalPassword):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Password is managed by the identity provider"})
+	case errors.Is(err, auth.ErrUnknownUser):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	s.logger.Error("ChangeMyPassword: failed to change password", slog.String("login", claims.Login), slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to change password"})
+}
+
+// GetMySessions возвращает активные сессии текущего пользователя.
+// @Summary List current user sessions
+// @Description Сессия, из которой пришел запрос, отмечена current
+// @Tags me
+// @Produce json
+// @Success 200 {array} model.Session
+// @Failure 403 {object} ErrorResponse
+// @Router /api/me/sessions [get]
+func (s *Server) GetMySessions(c echo.Context) error {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return userSessionRequired(c)
+	}
+
+	sessions, err := s.authService.ListSessions(c.Request().Context(), claims)
+	if err != nil {
+		s.logger.Error("GetMySessions: failed to list sessions", slog.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get sessions"})
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeMyOtherSessions завершает все сессии текущего пользователя, кроме текущей.
+// @Summary Sign out other sessions
+// @Tags me
+// @Produce json
+// @Success 200 {object} RevokedSessionsResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/me/sessions [delete]
+func (s *Server) RevokeMyOtherSessions(c echo.Context) error {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return userSessionRequired(c)
+	}
+
+	revoked, err := s.authService.RevokeOtherSessions(c.Request().Context(), claims)
+	if err != nil {
+		s.logger.Error("RevokeMyOtherSessions: failed to revoke sessions", slog.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke sessions"})
+	}
+
+	return c.JSON(http.StatusOK, RevokedSessionsResponse{Revoked: revoked})
+}
+
+// RevokeMySession завершает сессию текущего пользователя. current - выход из текущей сессии.
+// @Summary Revoke session
+// @Tags me
+// @Param id path string true "Session ID or current"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/me/sessions/{id} [delete]
+func (s *Server) RevokeMySession(c echo.Context) error {
+	claims, ok := sessionClaims(c)
+	if !ok {
+		return userSessionRequired(c)
+	}
+
+	ctx := c.Request().Context()
+	if c.Param("id") == "current" {
+		if err := s.authService.Logout(ctx, claims); err != nil {
+			s.logger.Error("RevokeMySession: failed to sign out", slog.String("error", err.Error()))
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke session"})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid session ID"})
+	}
+
+	err = s.authService.RevokeSession(ctx, claims.Login, id)
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Session not found"})
+	}
+	if err != nil {
+		s.logger.Error("RevokeMySession: failed to revoke session", slog.Int64("session_id", id), slog.String("error", err.Error()))
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke session"})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// profileError преобразует ошибку сервиса пользователей при работе с профилем в HTTP ответ.
+func (s *Server) profileError(c echo.Context, handler string, err error) error {
+	var fields model.ValidationErrors
+	switch {
+	case errors.As(err, &fields):
+		return c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: fields,
+		})
+	case errors.Is(err, user.ErrProfileManagedExternally):
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Profile is managed by the identity provider"})
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Email is already used by another user"})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	s.logger.Error(handler+": failed to process profile request", slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to process profile request"})
+}
@@ -0,0 +1,159 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
+)
+
+const (
+	// oidcStateCookie привязывает вход к браузеру, который его начал (защита от login CSRF).
+	oidcStateCookie = "oidc_state"
+
+	// oidcCallbackPage страница фронтенда, которая забирает токен после входа через OIDC.
+	oidcCallbackPage = "/login/sso"
+)
+
+// AuthProvidersResponse способы входа, доступные на странице логина.
+type AuthProvidersResponse struct {
+	Password      bool `json:"password"`
+	OIDC          bool `json:"oidc"`
+	PasswordReset bool `json:"password_reset"` // Ссылка "Забыли пароль?": сброс пароля по почте
+}
+
+// GetAuthProviders возвращает доступные способы входа.
+// @Summary Get login methods
+// @Tags auth
+// @Produce json
+// @Success 200 {object} AuthProvidersResponse
+// @Router /auth/providers [get]
+func (s *Server) GetAuthProviders(c echo.Context) error {
+	return c.JSON(http.StatusOK, AuthProvidersResponse{
+		Password:      true,
+		OIDC:          s.authService.OIDCEnabled(),
+		PasswordReset: s.authService.PasswordEmailsEnabled(),
+	})
+}
+
+// OIDCLogin начинает вход через корпоративный портал: перенаправляет на провайдера OIDC.
+// @Summary Start OIDC login
+// @Description Перенаправляет на страницу входа провайдера (authorization code + PKCE).
+// @Description После входа пользователь возвращается на /login/sso фронтенда с токеном платформы во фрагменте адреса
+// @Tags auth
+// @Param redirect query string false "Страница платформы после входа"
+// @Success 302
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /auth/oidc/login [get]
+func (s *Server) OIDCLogin(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ttl)
+	defer cancel()
+
+	authURL, state, err := s.authService.OIDCLoginURL(ctx, safeRedirect(c.QueryParam("redirect")))
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCDisabled) {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "OIDC login is not configured"})
+		}
+		s.logger.Error("OIDCLogin: failed to start login", "error", err)
+		return c.JSON(http.StatusBadGateway, ErrorResponse{Error: "Identity provider is unavailable"})
+	}
+
+	c.SetCookie(s.oidcStateCookie(c, state, 600))
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback завершает вход через корпоративный портал и выпускает токен платформы.
+// @Summary Complete OIDC login
+// @Description Принимает код от провайдера, проверяет ID token и перенаправляет на фронтенд
+// @Description с токеном (#token=...&redirect=...), вторым шагом входа (#challenge_token=...&enrollment_required=...&expires_at=...&redirect=...)
+// @Description или ошибкой (#error=...). Второй шаг завершается через /auth/login/2fa, как при входе по паролю
+// @Tags auth
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 302
+// @Router /auth/oidc/callback [get]
+func (s *Server) OIDCCallback(c echo.Context) error {
+	state := c.QueryParam("state")
+	cookie, err := c.Cookie(oidcStateCookie)
+	c.SetCookie(s.oidcStateCookie(c, "", -1))
+
+	if providerError := c.QueryParam("error"); providerError != "" {
+		s.logger.Warn("OIDCCallback: provider returned an error", "error", providerError, "description", c.QueryParam("error_description"))
+		return s.oidcRedirect(c, url.Values{"error": {"Identity provider rejected the login"}})
+	}
+
+	if err != nil || state == "" || cookie.Value != state {
+		return s.oidcRedirect(c, url.Values{"error": {"Login session expired, please try again"}})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ttl)
+	defer cancel()
+
+	claims, redirect, err := s.authService.OIDCLogin(ctx, state, c.QueryParam("code"), c.RealIP())
+	var challenge *auth.TwoFactorChallenge
+	if errors.As(err, &challenge) {
+		return s.oidcRedirect(c, url.Values{
+			"challenge_token":     {challenge.Token},
+			"enrollment_required": {strconv.FormatBool(challenge.EnrollmentRequired)},
+			"expires_at":          {challenge.ExpiresAt.Format(time.RFC3339)},
+			"redirect":            {redirect},
+		})
+	}
+	if err != nil {
+		s.logger.Error("OIDCCallback: login failed", "error", err)
+		message := "Login failed"
+		switch {
+		case errors.Is(err, auth.ErrInvalidOIDCState):
+			message = "Login session expired, please try again"
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			message = "Your account has no access to the platform"
+		case errors.Is(err, model.ErrAccountInactive):
+			message = "Account is suspended or deactivated"
+		}
+		return s.oidcRedirect(c, url.Values{"error": {message}})
+	}
+
+	token, err := s.authService.StartSession(ctx, claims, c.RealIP(), c.Request().UserAgent())
+	if err != nil {
+		s.logger.Error("Failed to generate JWT", "error", err)
+		return s.oidcRedirect(c, url.Values{"error": {"Failed to generate token"}})
+	}
+
+	return s.oidcRedirect(c, url.Values{"token": {token}, "redirect": {redirect}})
+}
+
+// oidcRedirect возвращает пользователя на страницу фронтенда. Данные передаются во фрагменте:
+// он не уходит на сервер и не попадает в логи прокси.
+func (s *Server) oidcRedirect(c echo.Context, fragment url.Values) error {
+	return c.Redirect(http.StatusFound, s.authService.OIDCFrontendURL()+oidcCallbackPage+"#"+fragment.Encode())
+}
+
+// oidcStateCookie cookie со state незавершенного входа.
+func (s *Server) oidcStateCookie(c echo.Context, state string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// safeRedirect пропускает только пути внутри платформы, чтобы вход нельзя было
+// использовать для перенаправления на чужой сайт.
+func safeRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return "/"
+	}
+	return redirect
+}
@@ -1,10 +1,12 @@
 package delivery
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 	"github.com/typefunco/dealer_dev_platform/internal/utils"
 )
 
@@ -107,3 +109,122 @@ func calculateRAP(rank int16) string {
 		return "None"
 	}
 }
+
+// GetPerformanceRecord возвращает запись performance дилера за квартал с версией в заголовке ETag.
+// @Summary Get performance record
+// @Description Получение записи показателей дилера за квартал; ETag используется в If-Match при изменении
+// @Tags performance
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Success 200 {object} model.PerformanceSales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/performance/{dealerId}/{year}/{quarter} [get]
+func (s *Server) GetPerformanceRecord(c echo.Context) error {
+	return getRecord(s, c, "GetPerformanceRecord", s.performanceRecords())
+}
+
+// CreatePerformanceRecord создает запись performance дилера за квартал.
+// @Summary Create performance record
+// @Description Создание записи показателей дилера за квартал с валидацией полей
+// @Tags performance
+// @Accept json
+// @Produce json
+// @Param record body model.PerformanceSales true "Record"
+// @Success 201 {object} model.PerformanceSales
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/performance [post]
+func (s *Server) CreatePerformanceRecord(c echo.Context) error {
+	return createRecord(s, c, "CreatePerformanceRecord", s.performanceRecords())
+}
+
+// ReplacePerformanceRecord полностью заменяет запись performance дилера за квартал.
+// @Summary Replace performance record
+// @Description Полная замена записи показателей дилера за квартал
+// @Tags performance
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param record body model.PerformanceSales true "Record"
+// @Success 200 {object} model.PerformanceSales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/performance/{dealerId}/{year}/{quarter} [put]
+func (s *Server) ReplacePerformanceRecord(c echo.Context) error {
+	return replaceRecord(s, c, "ReplacePerformanceRecord", s.performanceRecords())
+}
+
+// PatchPerformanceRecord частично обновляет запись performance дилера за квартал.
+// @Summary Patch performance record
+// @Description Частичное обновление записи показателей дилера за квартал (JSON Merge Patch, только разрешенные поля)
+// @Tags performance
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param updates body map[string]interface{} true "JSON Merge Patch с изменяемыми полями"
+// @Success 200 {object} model.PerformanceSales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/performance/{dealerId}/{year}/{quarter} [patch]
+func (s *Server) PatchPerformanceRecord(c echo.Context) error {
+	return patchRecord(s, c, "PatchPerformanceRecord", s.performanceRecords())
+}
+
+// DeletePerformanceRecord удаляет запись performance дилера за квартал.
+// @Summary Delete performance record
+// @Description Удаление записи показателей дилера за квартал
+// @Tags performance
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/performance/{dealerId}/{year}/{quarter} [delete]
+func (s *Server) DeletePerformanceRecord(c echo.Context) error {
+	return deleteRecord(s, c, "DeletePerformanceRecord", s.performanceRecords())
+}
+
+// performanceRecords операции записи performance для общих обработчиков записей показателей.
+func (s *Server) performanceRecords() recordService[model.PerformanceSales] {
+	return recordService[model.PerformanceSales]{
+		get: s.perfService.GetPerformanceByDealerAndPeriod,
+		create: func(ctx context.Context, record *model.PerformanceSales) error {
+			_, err := s.perfService.CreatePerformance(ctx, record)
+			return err
+		},
+		replace:     s.perfService.ReplacePerformance,
+		patch:       s.perfService.PatchPerformance,
+		delete:      s.perfService.DeletePerformanceByDealerAndPeriod,
+		version:     func(record *model.PerformanceSales) int { return record.Version },
+		patchSchema: model.PerformanceSalesPatchSchema,
+	}
+}
@@ -0,0 +1,285 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils"
+)
+
+// ValidationErrorResponse ответ с ошибками валидации по полям.
+type ValidationErrorResponse struct {
+	Error  string                 `json:"error"`
+	Fields model.ValidationErrors `json:"fields"`
+}
+
+// parseDealerPeriodKey читает ключ записи показателей из пути /:dealerId/:year/:quarter.
+func parseDealerPeriodKey(c echo.Context) (model.DealerPeriodKey, error) {
+	dealerID, err := strconv.Atoi(c.Param("dealerId"))
+	if err != nil || dealerID <= 0 {
+		return model.DealerPeriodKey{}, fmt.Errorf("invalid dealer ID: %s", c.Param("dealerId"))
+	}
+
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || !utils.IsValidYear(year) {
+		return model.DealerPeriodKey{}, fmt.Errorf("invalid year: %s", c.Param("year"))
+	}
+
+	quarter := c.Param("quarter")
+	if !utils.IsValidQuarter(quarter) {
+		return model.DealerPeriodKey{}, fmt.Errorf("invalid quarter: %s", quarter)
+	}
+
+	return model.DealerPeriodKey{
+		DealerID: dealerID,
+		Quarter:  utils.NormalizeQuarter(quarter),
+		Year:     year,
+	}, nil
+}
+
+// errInvalidRequestBody тело запроса не удалось прочитать.
+var errInvalidRequestBody = errors.New("invalid request body")
+
+const (
+	headerIfMatch = "If-Match"
+	headerETag    = "ETag"
+)
+
+var (
+	// errIfMatchRequired изменяющий запрос пришел без заголовка If-Match.
+	errIfMatchRequired = errors.New("If-Match header is required")
+
+	// errInvalidIfMatch заголовок If-Match не содержит версию записи.
+	errInvalidIfMatch = errors.New("invalid If-Match header")
+)
+
+// VersionConflictResponse ответ на изменение устаревшей версии записи.
+type VersionConflictResponse struct {
+	Error   string      `json:"error"`
+	Current interface{} `json:"current"`
+}
+
+// parseIfMatch читает ожидаемую версию записи из заголовка If-Match.
+// Принимает ETag в виде "3", W/"3" или 3; "*" означает изменение без проверки версии (0).
+func parseIfMatch(c echo.Context) (int, error) {
+	value := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if value == "" {
+		return 0, errIfMatchRequired
+	}
+	if value == "*" {
+		return 0, nil
+	}
+
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	version, err := strconv.Atoi(value)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("%w: %s", errInvalidIfMatch, c.Request().Header.Get(headerIfMatch))
+	}
+
+	return version, nil
+}
+
+// setETag выставляет заголовок ETag по версии записи.
+func setETag(c echo.Context, version int) {
+	c.Response().Header().Set(headerETag, strconv.Quote(strconv.Itoa(version)))
+}
+
+// versionConflictResponse возвращает 409 с текущим состоянием записи, чтобы клиент мог повторить изменение.
+func versionConflictResponse(c echo.Context, version int, current interface{}) error {
+	setETag(c, version)
+	return c.JSON(http.StatusConflict, VersionConflictResponse{
+		Error:   "Record was modified by another request",
+		Current: current,
+	})
+}
+
+// bindRecordPatch читает тело PATCH запроса в формате JSON Merge Patch и проверяет его по схеме сущности.
+func bindRecordPatch(c echo.Context, schema model.PatchSchema) (model.Patch, error) {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return model.Patch{}, fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+	}
+
+	patch, err := schema.Decode(body)
+	if err != nil {
+		var validationErrs model.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return model.Patch{}, err
+		}
+		return model.Patch{}, fmt.Errorf("%w: %v", errInvalidRequestBody, err)
+	}
+
+	return patch, nil
+}
+
+// recordErrorResponse преобразует ошибку сервисов показателей дилеров в HTTP ответ.
+func (s *Server) recordErrorResponse(c echo.Context, handler string, err error) error {
+	var validationErrs model.ValidationErrors
+	switch {
+	case errors.Is(err, errInvalidRequestBody), errors.Is(err, model.ErrEmptyPatch), errors.Is(err, errInvalidIfMatch):
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, errIfMatchRequired):
+		return c.JSON(http.StatusPreconditionRequired, ErrorResponse{Error: err.Error()})
+	case errors.As(err, &validationErrs):
+		return c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: validationErrs,
+		})
+	case errors.Is(err, repository.ErrInvalidReference):
+		return c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: model.ValidationErrors{{Field: "dealer_id", Message: "dealer does not exist"}},
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Record not found"})
+	case errors.Is(err, model.ErrVersionConflict):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Record was modified by another request"})
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Record for this dealer and period already exists"})
+	}
+
+	s.logger.Error(handler+": failed to save record", "error", err)
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error: "Failed to save record",
+	})
+}
+
+// recordService операции сервиса над записью показателей дилера за квартал, общие для
+// sales, dealer_dev, after_sales и performance.
+type recordService[T any] struct {
+	get         func(ctx context.Context, key model.DealerPeriodKey) (*T, error)
+	create      func(ctx context.Context, record *T) error
+	replace     func(ctx context.Context, key model.DealerPeriodKey, version int, record *T) (*T, error)
+	patch       func(ctx context.Context, key model.DealerPeriodKey, version int, patch model.Patch) (*T, error)
+	delete      func(ctx context.Context, key model.DealerPeriodKey, version int) error
+	version     func(record *T) int
+	patchSchema model.PatchSchema
+}
+
+// getRecord отдает запись показателей с версией в заголовке ETag.
+func getRecord[T any](s *Server, c echo.Context, handler string, svc recordService[T]) error {
+	key, err := parseDealerPeriodKey(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	record, err := svc.get(c.Request().Context(), key)
+	if err != nil {
+		return s.recordErrorResponse(c, handler, err)
+	}
+
+	setETag(c, svc.version(record))
+	return c.JSON(http.StatusOK, record)
+}
+
+// createRecord создает запись показателей из тела запроса.
+func createRecord[T any](s *Server, c echo.Context, handler string, svc recordService[T]) error {
+	var record T
+	if err := c.Bind(&record); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	if err := svc.create(c.Request().Context(), &record); err != nil {
+		return s.recordErrorResponse(c, handler, err)
+	}
+
+	setETag(c, svc.version(&record))
+	return c.JSON(http.StatusCreated, record)
+}
+
+// replaceRecord полностью заменяет запись показателей версии из If-Match.
+func replaceRecord[T any](s *Server, c echo.Context, handler string, svc recordService[T]) error {
+	key, err := parseDealerPeriodKey(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		return s.recordErrorResponse(c, handler, err)
+	}
+
+	var record T
+	if err := c.Bind(&record); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	updated, err := svc.replace(c.Request().Context(), key, version, &record)
+	if err != nil {
+		return recordWriteError(s, c, handler, svc, key, err)
+	}
+
+	setETag(c, svc.version(updated))
+	return c.JSON(http.StatusOK, updated)
+}
+
+// patchRecord применяет к записи показателей JSON Merge Patch по схеме сущности.
+func patchRecord[T any](s *Server, c echo.Context, handler string, svc recordService[T]) error {
+	key, err := parseDealerPeriodKey(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		return s.recordErrorResponse(c, handler, err)
+	}
+
+	patch, err := bindRecordPatch(c, svc.patchSchema)
+	if err != nil {
+		return s.recordErrorResponse(c, handler, err)
+	}
+
+	updated, err := svc.patch(c.Request().Context(), key, version, patch)
+	if err != nil {
+		return recordWriteError(s, c, handler, svc, key, err)
+	}
+
+	setETag(c, svc.version(updated))
+	return c.JSON(http.StatusOK, updated)
+}
+
+// deleteRecord удаляет запись показателей версии из If-Match.
+func deleteRecord[T any](s *Server, c echo.Context, handler string, svc recordService[T]) error {
+	key, err := parseDealerPeriodKey(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		return s.recordErrorResponse(c, handler, err)
+	}
+
+	if err := svc.delete(c.Request().Context(), key, version); err != nil {
+		return recordWriteError(s, c, handler, svc, key, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// recordWriteError отвечает на ошибку изменения записи; при конфликте версий возвращает текущее состояние записи.
+func recordWriteError[T any](s *Server, c echo.Context, handler string, svc recordService[T], key model.DealerPeriodKey, err error) error {
+	if !errors.Is(err, model.ErrVersionConflict) {
+		return s.recordErrorResponse(c, handler, err)
+	}
+
+	current, getErr := svc.get(c.Request().Context(), key)
+	if getErr != nil {
+		return s.recordErrorResponse(c, handler, getErr)
+	}
+
+	return versionConflictResponse(c, svc.version(current), current)
+}
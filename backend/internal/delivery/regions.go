@@ -0,0 +1,87 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/service/region"
+)
+
+// GetRegions возвращает справочник регионов.
+// @Summary Get regions
+// @Description Получение справочника регионов с названиями и алиасами
+// @Tags regions
+// @Produce json
+// @Success 200 {array} model.Region
+// @Router /api/regions [get]
+func (s *Server) GetRegions(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.regionService.GetRegions())
+}
+
+// AddRegionAlias добавляет алиас региону.
+// @Summary Add region alias
+// @Description Добавление альтернативного написания региона для названий листов и параметров запросов
+// @Tags regions
+// @Accept json
+// @Produce json
+// @Param code path string true "Region code"
+// @Param request body model.RegionAliasRequest true "Alias"
+// @Success 201 {object} model.Region
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/regions/{code}/aliases [post]
+func (s *Server) AddRegionAlias(c echo.Context) error {
+	var req model.RegionAliasRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	updated, err := s.regionService.AddAlias(c.Request().Context(), c.Param("code"), req.Alias)
+	if err != nil {
+		return s.regionErrorResponse(c, "AddRegionAlias", err)
+	}
+
+	return c.JSON(http.StatusCreated, updated)
+}
+
+// DeleteRegionAlias удаляет алиас региона.
+// @Summary Delete region alias
+// @Description Удаление альтернативного написания региона
+// @Tags regions
+// @Param code path string true "Region code"
+// @Param alias path string true "Alias"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/regions/{code}/aliases/{alias} [delete]
+func (s *Server) DeleteRegionAlias(c echo.Context) error {
+	if err := s.regionService.DeleteAlias(c.Request().Context(), c.Param("code"), c.Param("alias")); err != nil {
+		return s.regionErrorResponse(c, "DeleteRegionAlias", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// regionErrorResponse преобразует ошибку сервиса регионов в HTTP ответ.
+func (s *Server) regionErrorResponse(c echo.Context, handler string, err error) error {
+	switch {
+	case errors.Is(err, region.ErrInvalidAlias):
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Alias must be a non-empty region name"})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Region or alias not found"})
+	case errors.Is(err, region.ErrAliasConflict), errors.Is(err, repository.ErrAlreadyExists):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	}
+
+	s.logger.Error(handler+": failed to update region aliases", "error", err)
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error: "Failed to update region aliases",
+	})
+}
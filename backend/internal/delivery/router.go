@@ -5,12 +5,12 @@ import (
 	"log/slog"
 	"net/http"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	authMiddleware "github.com/typefunco/dealer_dev_platform/internal/middleware"
-	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 	"github.com/typefunco/dealer_dev_platform/internal/service/aftersales"
+	"github.com/typefunco/dealer_dev_platform/internal/service/apikey"
 	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
 	"github.com/typefunco/dealer_dev_platform/internal/service/dealer"
 	"github.com/typefunco/dealer_dev_platform/internal/service/dealerdev"
@@ -18,6 +18,7 @@ import (
 	"github.com/typefunco/dealer_dev_platform/internal/service/performance"
 	"github.com/typefunco/dealer_dev_platform/internal/service/performance_aftersales"
 	"github.com/typefunco/dealer_dev_platform/internal/service/performance_sales"
+	"github.com/typefunco/dealer_dev_platform/internal/service/region"
 	"github.com/typefunco/dealer_dev_platform/internal/service/sales"
 	"github.com/typefunco/dealer_dev_platform/internal/service/user"
 	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
@@ -36,8 +37,8 @@ type Server struct {
 	salesService      *sales.Service
 	dealerDevService  *dealerdev.Service
 	excelService      *excel.Service
-	dynamicRepo       repository.DynamicTableRepository
-	pool              *pgxpool.Pool
+	regionService     *region.Service
+	apiKeyService     *apikey.Service
 	maxFileSize       int64
 	srv               *echo.Echo
 	logger            *slog.Logger
@@ -56,11 +57,17 @@ func NewServer(
 	salesService *sales.Service,
 	dealerDevService *dealerdev.Service,
 	excelService *excel.Service,
-	dynamicRepo repository.DynamicTableRepository,
-	pool *pgxpool.Pool,
+	regionService *region.Service,
+	apiKeyService *apikey.Service,
 	maxFileSize int64,
 	logger *slog.Logger,
 ) *Server {
+	srv := echo.New()
+	// Адрес клиента берется из X-Real-IP, который ставит nginx, и только если запрос пришел
+	// из внутренней сети. X-Forwarded-For клиент может подделать и обойти списки адресов ключей API
+	// и ограничения входа по адресу
+	srv.IPExtractor = echo.ExtractIPFromRealIPHeader()
+
 	return &Server{
 		authService:       authService,
 		jwtService:        jwtService,
@@ -73,10 +80,10 @@ func NewServer(
 		salesService:      salesService,
 		dealerDevService:  dealerDevService,
 		excelService:      excelService,
-		dynamicRepo:       dynamicRepo,
-		pool:              pool,
+		regionService:     regionService,
+		apiKeyService:     apiKeyService,
 		maxFileSize:       maxFileSize,
-		srv:               echo.New(),
+		srv:               srv,
 		logger:            logger,
 	}
 }
@@ -110,6 +117,13 @@ func (s *Server) RunServer() {
 			echo.HeaderXRealIP,
 			echo.HeaderXForwardedFor,
 			echo.HeaderXForwardedProto,
+			headerIfMatch,
+			headerIdempotencyKey,
+			authMiddleware.HeaderAPIKey,
+		},
+		ExposeHeaders: []string{
+			headerETag,
+			headerIdempotentReplayed,
 		},
 		AllowMethods: []string{
 			http.MethodGet,
@@ -126,65 +140,162 @@ func (s *Server) RunServer() {
 
 	// Auth routes (без middleware)
 	s.srv.POST("/auth/login", s.Login)
+	s.srv.POST("/auth/login/2fa", s.LoginTwoFactor)
+	s.srv.POST("/auth/login/2fa/enroll", s.LoginTwoFactorEnroll)
+	s.srv.GET("/auth/providers", s.GetAuthProviders)
+	s.srv.POST("/auth/password/forgot", s.ForgotPassword)
+	s.srv.POST("/auth/password/check", s.CheckPasswordLink)
+	s.srv.POST("/auth/password/reset", s.ResetPassword)
+	s.srv.GET("/auth/oidc/login", s.OIDCLogin)
+	s.srv.GET("/auth/oidc/callback", s.OIDCCallback)
 
 	// Health check (без middleware)
 	s.srv.GET("/health", s.Health)
 
 	// API group с обязательной аутентификацией
 	api := s.srv.Group("/api")
-	api.Use(authMiddleware.AuthMiddleware(s.jwtService))
+	api.Use(authMiddleware.AuthMiddleware(s.jwtService, s.apiKeyService, s.authService))
+
+	// Ключ API проходит только на маршруты, которые разрешают ключам свою область доступа.
+	// Профиль, сессии и пользователи доступны только людям
+	users := authMiddleware.UserMiddleware()
+	readers := authMiddleware.ScopeMiddleware(model.APIKeyScopeRead)
 
 	// User management routes (только чтение для всех пользователей)
-	api.GET("/users", s.GetUsers)           // Получить список пользователей с фильтрами
-	api.GET("/users/stats", s.GetUserStats) // Получить статистику по регионам
-	api.GET("/users/:id", s.GetUserByID)    // Получить пользователя по ID
+	api.GET("/users", s.GetUsers, users)           // Получить список пользователей с фильтрами
+	api.GET("/users/stats", s.GetUserStats, users) // Получить статистику по регионам
+	api.GET("/users/:id", s.GetUserByID, users)    // Получить пользователя по ID
+
+	// Профиль, пароль и сессии текущего пользователя
+	api.GET("/me", s.GetMe, users)
+	api.PATCH("/me", s.UpdateMe, users)
+	api.POST("/me/password", s.ChangeMyPassword, users)
+	api.GET("/me/sessions", s.GetMySessions, users)
+	api.DELETE("/me/sessions", s.RevokeMyOtherSessions, users)
+	api.DELETE("/me/sessions/:id", s.RevokeMySession, users)
+
+	// Второй фактор текущего пользователя
+	api.GET("/me/2fa", s.GetTwoFactorStatus, users)
+	api.POST("/me/2fa/enroll", s.EnrollTwoFactor, users)
+	api.POST("/me/2fa/confirm", s.ConfirmTwoFactor, users)
+	api.POST("/me/2fa/disable", s.DisableTwoFactor, users)
+	api.POST("/me/2fa/recovery-codes", s.RegenerateRecoveryCodes, users)
 
 	// After Sales routes
-	api.GET("/aftersales", s.GetAfterSalesData) // Получить данные After Sales по региону (legacy)
+	api.GET("/aftersales", s.GetAfterSalesData, readers) // Получить данные After Sales по региону (legacy)
 
 	// Dealer routes
-	api.GET("/dealers/list", s.GetDealersList)    // Получить упрощенный список дилеров для UI
-	api.GET("/dealers", s.GetDealers)             // Получить список дилеров
-	api.GET("/dealers/:id", s.GetDealerByID)      // Получить базовую информацию о дилере
-	api.GET("/dealers/:id/card", s.GetDealerCard) // Получить полную карточку дилера
+	api.GET("/dealers/list", s.GetDealersList, readers)    // Получить упрощенный список дилеров для UI
+	api.GET("/dealers", s.GetDealers, readers)             // Получить список дилеров
+	api.GET("/dealers/:id", s.GetDealerByID, readers)      // Получить базовую информацию о дилере
+	api.GET("/dealers/:id/card", s.GetDealerCard, readers) // Получить полную карточку дилера
 
 	// Унифицированные маршруты для всех типов таблиц (без префикса dynamic)
-	api.GET("/dealer_dev", s.GetDynamicData)  // Dealer Development
-	api.GET("/sales", s.GetDynamicData)       // Sales Team
-	api.GET("/after_sales", s.GetDynamicData) // After Sales
-	api.GET("/performance", s.GetDynamicData) // Performance
+	api.GET("/dealer_dev", s.GetDynamicData, readers)  // Dealer Development
+	api.GET("/sales", s.GetDynamicData, readers)       // Sales Team
+	api.GET("/after_sales", s.GetDynamicData, readers) // After Sales
+	api.GET("/performance", s.GetDynamicData, readers) // Performance
+
+	// Канонические показатели дилера за квартал (ключ - дилер, год, квартал).
+	// Изменение требует If-Match с версией из ETag
+	writeScope := authMiddleware.ScopeMiddleware(model.APIKeyScopeWrite)
+	writers := []echo.MiddlewareFunc{writeScope, authMiddleware.RoleMiddleware(model.UserRoleManager)}
+	salesWriters := []echo.MiddlewareFunc{writeScope, authMiddleware.RoleMiddleware(model.UserRoleManager, model.UserRoleSales)}
+
+	api.PUT("/dealers/:id/joint-decision", s.UpdateDealerJointDecision, writers...)
+
+	api.GET("/sales/:dealerId/:year/:quarter", s.GetSalesRecord, readers)
+	api.POST("/sales", s.CreateSalesRecord, salesWriters...)
+	api.PUT("/sales/:dealerId/:year/:quarter", s.ReplaceSalesRecord, salesWriters...)
+	api.PATCH("/sales/:dealerId/:year/:quarter", s.PatchSalesRecord, salesWriters...)
+	api.DELETE("/sales/:dealerId/:year/:quarter", s.DeleteSalesRecord, writers...)
+
+	api.GET("/dealer_dev/:dealerId/:year/:quarter", s.GetDealerDevRecord, readers)
+	api.POST("/dealer_dev", s.CreateDealerDevRecord, writers...)
+	api.PUT("/dealer_dev/:dealerId/:year/:quarter", s.ReplaceDealerDevRecord, writers...)
+	api.PATCH("/dealer_dev/:dealerId/:year/:quarter", s.PatchDealerDevRecord, writers...)
+	api.DELETE("/dealer_dev/:dealerId/:year/:quarter", s.DeleteDealerDevRecord, writers...)
+
+	api.GET("/after_sales/:dealerId/:year/:quarter", s.GetAfterSalesRecord, readers)
+	api.POST("/after_sales", s.CreateAfterSalesRecord, writers...)
+	api.PUT("/after_sales/:dealerId/:year/:quarter", s.ReplaceAfterSalesRecord, writers...)
+	api.PATCH("/after_sales/:dealerId/:year/:quarter", s.PatchAfterSalesRecord, writers...)
+	api.DELETE("/after_sales/:dealerId/:year/:quarter", s.DeleteAfterSalesRecord, writers...)
+
+	api.GET("/performance/:dealerId/:year/:quarter", s.GetPerformanceRecord, readers)
+	api.POST("/performance", s.CreatePerformanceRecord, writers...)
+	api.PUT("/performance/:dealerId/:year/:quarter", s.ReplacePerformanceRecord, writers...)
+	api.PATCH("/performance/:dealerId/:year/:quarter", s.PatchPerformanceRecord, writers...)
+	api.DELETE("/performance/:dealerId/:year/:quarter", s.DeletePerformanceRecord, writers...)
 
 	// Legacy routes (сохраняем для обратной совместимости)
-	api.GET("/dealerdev", s.GetDealerDevData) // Получить данные Dealer Development
+	api.GET("/dealerdev", s.GetDealerDevData, readers) // Получить данные Dealer Development
 
 	// Quarter Comparison routes
-	api.GET("/quarter-comparison", s.GetQuarterComparison) // Сравнение кварталов
+	api.GET("/quarter-comparison", s.GetQuarterComparison, readers) // Сравнение кварталов
 
 	// All Data routes (комплексные данные всех таблиц)
-	api.GET("/all-data", s.GetAllData) // Получить все данные дилеров (DealerDev + Sales + Performance + AfterSales)
+	api.GET("/all-data", s.GetAllData, readers) // Получить все данные дилеров (DealerDev + Sales + Performance + AfterSales)
 
 	// Filter routes
-	api.GET("/filters", s.GetAvailableFilters) // Получить доступные фильтры
+	api.GET("/filters", s.GetAvailableFilters, readers) // Получить доступные фильтры
+
+	// Region routes
+	api.GET("/regions", s.GetRegions, readers) // Получить справочник регионов
 
 	// Analytics routes
-	api.GET("/analytics", s.GetAnalytics) // Получить аналитические данные
+	api.GET("/analytics", s.GetAnalytics, readers) // Получить аналитические данные
 
-	// Admin routes (только для администраторов)
+	// Загрузка показателей из внешних систем в JSON (только для админов), альтернатива файлу dealer_net
+	api.POST("/ingest/dealer-metrics", s.IngestDealerMetrics, authMiddleware.AdminMiddleware(model.APIKeyScopeIngestWrite))
+
+	// Excel operations routes (только для админов и ключей с областями imports:*)
+	excel := api.Group("/admin/excel")
+	importsRead := authMiddleware.AdminMiddleware(model.APIKeyScopeImportsRead)
+	importsWrite := authMiddleware.AdminMiddleware(model.APIKeyScopeImportsWrite)
+	excel.POST("/upload", s.UploadExcelFile, importsWrite)            // Загрузка Excel файла
+	excel.POST("/brands/upload", s.UploadBrandsFile, importsWrite)    // Загрузка файла с брендами и побочными бизнесами
+	excel.GET("/imports", s.GetImports, importsRead)                  // Каталог загрузок
+	excel.GET("/imports/trash", s.GetDeletedImports, importsRead)     // Корзина загрузок
+	excel.GET("/imports/:id", s.GetImport, importsRead)               // Запись каталога загрузок
+	excel.GET("/imports/:id/data", s.GetImportData, importsRead)      // Данные загрузки
+	excel.GET("/imports/:id/export", s.ExportImport, importsRead)     // Выгрузка квартала в шаблон загрузки
+	excel.DELETE("/imports/:id", s.DeleteImport, importsWrite)        // Перенос квартала в корзину
+	excel.POST("/imports/:id/restore", s.RestoreImport, importsWrite) // Восстановление квартала из корзины
+	excel.POST("/imports/:id/promote", s.PromoteImport, importsWrite) // Повторный перенос в нормализованные таблицы
+
+	// Admin routes (только для администраторов, ключи API не допускаются)
 	admin := api.Group("/admin")
 	admin.Use(authMiddleware.AdminMiddleware())
 
-	// Excel operations routes (только для админов)
-	admin.POST("/excel/upload", s.UploadExcelFile)                  // Загрузка Excel файла
-	admin.POST("/excel/brands/upload", s.UploadBrandsFile)          // Загрузка файла с брендами и побочными бизнесами
-	admin.GET("/excel/tables", s.GetExcelTables)                    // Список созданных таблиц
-	admin.GET("/excel/tables/:tableName", s.GetExcelTableMetadata)  // Метаданные таблицы
-	admin.GET("/excel/tables/:tableName/data", s.GetExcelTableData) // Данные таблицы
-	admin.DELETE("/excel/tables/:tableName", s.DeleteExcelTable)    // Удаление таблицы
+	// Region aliases routes (только для админов)
+	admin.POST("/regions/:code/aliases", s.AddRegionAlias)             // Добавить алиас региона
+	admin.DELETE("/regions/:code/aliases/:alias", s.DeleteRegionAlias) // Удалить алиас региона
+
+	// Service accounts and API keys routes (только для админов)
+	admin.GET("/service-accounts", s.GetServiceAccounts)                   // Сервисные учетные записи
+	admin.POST("/service-accounts", s.CreateServiceAccount)                // Создать сервисную учетную запись
+	admin.GET("/service-accounts/:id", s.GetServiceAccount)                // Сервисная учетная запись
+	admin.DELETE("/service-accounts/:id", s.DeleteServiceAccount)          // Удалить учетную запись и ее ключи
+	admin.GET("/service-accounts/:id/keys", s.GetAPIKeys)                  // Ключи API учетной записи
+	admin.POST("/service-accounts/:id/keys", s.CreateAPIKey)               // Выпустить ключ API
+	admin.POST("/service-accounts/:id/keys/:keyId/revoke", s.RevokeAPIKey) // Отозвать ключ API
 
 	// User management routes (только для админов)
-	admin.POST("/users", s.CreateUser)       // Создать пользователя
-	admin.PUT("/users/:id", s.UpdateUser)    // Обновить пользователя
-	admin.DELETE("/users/:id", s.DeleteUser) // Удалить пользователя
+	admin.POST("/users", s.CreateUser)                    // Создать пользователя
+	admin.POST("/users/invite", s.InviteUser)             // Пригласить пользователя по почте
+	admin.POST("/users/:id/invite", s.ResendInvitation)   // Отправить приглашение повторно
+	admin.PUT("/users/:id", s.UpdateUser)                 // Обновить пользователя
+	admin.DELETE("/users/:id", s.DeleteUser)              // Отключить пользователя (строка и его записи остаются)
+	admin.PUT("/users/:id/status", s.ChangeUserStatus)    // Приостановить, отключить или вернуть в работу
+	admin.POST("/users/:id/reactivate", s.ReactivateUser) // Вернуть учетную запись в работу
+	admin.DELETE("/users/:id/2fa", s.ResetUserTwoFactor)  // Сбросить второй фактор пользователя
+	admin.POST("/users/:id/unlock", s.UnlockUser)         // Снять блокировку входа
+
+	// Политика входа
+	admin.GET("/auth-policy", s.GetAuthPolicy)       // Политика входа
+	admin.PUT("/auth-policy", s.UpdateAuthPolicy)    // Изменить политику входа (обязательный второй фактор для администраторов)
+	admin.GET("/login-attempts", s.GetLoginAttempts) // Журнал попыток входа
 
 	// Bulk operations routes (только для админов)
 	admin.POST("/bulk", s.BulkOperations)    // Массовые операции
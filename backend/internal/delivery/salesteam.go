@@ -1,10 +1,12 @@
 package delivery
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 )
 
 // SalesTeamDealerResponse представляет дилера с данными Sales Team для API.
@@ -108,3 +110,122 @@ func (s *Server) GetSalesTeamData(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// GetSalesRecord возвращает запись sales дилера за квартал с версией в заголовке ETag.
+// @Summary Get sales record
+// @Description Получение записи показателей дилера за квартал; ETag используется в If-Match при изменении
+// @Tags sales
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Success 200 {object} model.Sales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sales/{dealerId}/{year}/{quarter} [get]
+func (s *Server) GetSalesRecord(c echo.Context) error {
+	return getRecord(s, c, "GetSalesRecord", s.salesRecords())
+}
+
+// CreateSalesRecord создает запись sales дилера за квартал.
+// @Summary Create sales record
+// @Description Создание записи показателей дилера за квартал с валидацией полей
+// @Tags sales
+// @Accept json
+// @Produce json
+// @Param record body model.Sales true "Record"
+// @Success 201 {object} model.Sales
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sales [post]
+func (s *Server) CreateSalesRecord(c echo.Context) error {
+	return createRecord(s, c, "CreateSalesRecord", s.salesRecords())
+}
+
+// ReplaceSalesRecord полностью заменяет запись sales дилера за квартал.
+// @Summary Replace sales record
+// @Description Полная замена записи показателей дилера за квартал
+// @Tags sales
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param record body model.Sales true "Record"
+// @Success 200 {object} model.Sales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sales/{dealerId}/{year}/{quarter} [put]
+func (s *Server) ReplaceSalesRecord(c echo.Context) error {
+	return replaceRecord(s, c, "ReplaceSalesRecord", s.salesRecords())
+}
+
+// PatchSalesRecord частично обновляет запись sales дилера за квартал.
+// @Summary Patch sales record
+// @Description Частичное обновление записи показателей дилера за квартал (JSON Merge Patch, только разрешенные поля)
+// @Tags sales
+// @Accept json
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Param updates body map[string]interface{} true "JSON Merge Patch с изменяемыми полями"
+// @Success 200 {object} model.Sales
+// @Header 200 {string} ETag "Версия записи"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sales/{dealerId}/{year}/{quarter} [patch]
+func (s *Server) PatchSalesRecord(c echo.Context) error {
+	return patchRecord(s, c, "PatchSalesRecord", s.salesRecords())
+}
+
+// DeleteSalesRecord удаляет запись sales дилера за квартал.
+// @Summary Delete sales record
+// @Description Удаление записи показателей дилера за квартал
+// @Tags sales
+// @Produce json
+// @Param dealerId path int true "Dealer ID"
+// @Param year path int true "Year"
+// @Param quarter path string true "Quarter"
+// @Param If-Match header string true "Версия записи (ETag), * - без проверки"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 428 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sales/{dealerId}/{year}/{quarter} [delete]
+func (s *Server) DeleteSalesRecord(c echo.Context) error {
+	return deleteRecord(s, c, "DeleteSalesRecord", s.salesRecords())
+}
+
+// salesRecords операции записи sales для общих обработчиков записей показателей.
+func (s *Server) salesRecords() recordService[model.Sales] {
+	return recordService[model.Sales]{
+		get: s.salesService.GetSalesByDealerAndPeriod,
+		create: func(ctx context.Context, record *model.Sales) error {
+			_, err := s.salesService.CreateSales(ctx, record)
+			return err
+		},
+		replace:     s.salesService.ReplaceSales,
+		patch:       s.salesService.PatchSales,
+		delete:      s.salesService.DeleteSalesByDealerAndPeriod,
+		version:     func(record *model.Sales) int { return record.Version },
+		patchSchema: model.SalesPatchSchema,
+	}
+}
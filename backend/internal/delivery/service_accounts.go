@@ -0,0 +1,231 @@
+package delivery
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/service/apikey"
+)
+
+// GetServiceAccounts возвращает сервисные учетные записи.
+// @Summary Get service accounts
+// @Description Возвращает учетные записи интеграций, которые входят по ключам API
+// @Tags service-accounts
+// @Produce json
+// @Success 200 {array} model.ServiceAccount
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/service-accounts [get]
+func (s *Server) GetServiceAccounts(c echo.Context) error {
+	accounts, err := s.apiKeyService.ListServiceAccounts(c.Request().Context())
+	if err != nil {
+		return s.serviceAccountError(c, "GetServiceAccounts", err)
+	}
+
+	if accounts == nil {
+		accounts = []*model.ServiceAccount{}
+	}
+	return c.JSON(http.StatusOK, accounts)
+}
+
+// CreateServiceAccount создает сервисную учетную запись.
+// @Summary Create service account
+// @Description Создает учетную запись интеграции. Права задаются ролью и флагом администратора, как у пользователей
+// @Tags service-accounts
+// @Accept json
+// @Produce json
+// @Param request body model.ServiceAccountCreateRequest true "Service account"
+// @Success 201 {object} model.ServiceAccount
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/service-accounts [post]
+func (s *Server) CreateServiceAccount(c echo.Context) error {
+	var req model.ServiceAccountCreateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	account, err := s.apiKeyService.CreateServiceAccount(c.Request().Context(), req, currentLogin(c))
+	if err != nil {
+		return s.serviceAccountError(c, "CreateServiceAccount", err)
+	}
+
+	return c.JSON(http.StatusCreated, account)
+}
+
+// GetServiceAccount возвращает сервисную учетную запись.
+// @Summary Get service account
+// @Tags service-accounts
+// @Produce json
+// @Param id path int true "Service account ID"
+// @Success 200 {object} model.ServiceAccount
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/service-accounts/{id} [get]
+func (s *Server) GetServiceAccount(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid service account ID",
+		})
+	}
+
+	account, err := s.apiKeyService.GetServiceAccount(c.Request().Context(), id)
+	if err != nil {
+		return s.serviceAccountError(c, "GetServiceAccount", err)
+	}
+
+	return c.JSON(http.StatusOK, account)
+}
+
+// DeleteServiceAccount удаляет сервисную учетную запись вместе с ключами.
+// @Summary Delete service account
+// @Tags service-accounts
+// @Param id path int true "Service account ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/service-accounts/{id} [delete]
+func (s *Server) DeleteServiceAccount(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid service account ID",
+		})
+	}
+
+	if err := s.apiKeyService.DeleteServiceAccount(c.Request().Context(), id, currentLogin(c)); err != nil {
+		return s.serviceAccountError(c, "DeleteServiceAccount", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetAPIKeys возвращает ключи API учетной записи.
+// @Summary Get API keys
+// @Description Возвращает ключи учетной записи; значения ключей не хранятся и не возвращаются
+// @Tags service-accounts
+// @Produce json
+// @Param id path int true "Service account ID"
+// @Success 200 {array} model.APIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/service-accounts/{id}/keys [get]
+func (s *Server) GetAPIKeys(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid service account ID",
+		})
+	}
+
+	keys, err := s.apiKeyService.ListAPIKeys(c.Request().Context(), id)
+	if err != nil {
+		return s.serviceAccountError(c, "GetAPIKeys", err)
+	}
+
+	if keys == nil {
+		keys = []*model.APIKey{}
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey выпускает ключ API учетной записи.
+// @Summary Create API key
+// @Description Выпускает ключ с областями доступа (read, write, ingest:write, imports:read, imports:write),
+// @Description необязательным сроком действия и списком разрешенных адресов (IP или CIDR).
+// @Description Области ingest:write и imports:* выдаются только учетным записям администраторов, write - ролям manager и sales.
+// @Description Значение ключа возвращается только в этом ответе. Ключ передается в заголовке Authorization: Bearer или X-API-Key
+// @Tags service-accounts
+// @Accept json
+// @Produce json
+// @Param id path int true "Service account ID"
+// @Param request body model.APIKeyCreateRequest true "API key"
+// @Success 201 {object} model.CreatedAPIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/service-accounts/{id}/keys [post]
+func (s *Server) CreateAPIKey(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid service account ID",
+		})
+	}
+
+	var req model.APIKeyCreateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	key, err := s.apiKeyService.CreateAPIKey(c.Request().Context(), id, req, currentLogin(c))
+	if err != nil {
+		return s.serviceAccountError(c, "CreateAPIKey", err)
+	}
+
+	return c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey отзывает ключ API.
+// @Summary Revoke API key
+// @Tags service-accounts
+// @Produce json
+// @Param id path int true "Service account ID"
+// @Param keyId path int true "API key ID"
+// @Success 200 {object} model.APIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/service-accounts/{id}/keys/{keyId}/revoke [post]
+func (s *Server) RevokeAPIKey(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid service account ID",
+		})
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("keyId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid API key ID",
+		})
+	}
+
+	key, err := s.apiKeyService.RevokeAPIKey(c.Request().Context(), id, keyID, currentLogin(c))
+	if err != nil {
+		return s.serviceAccountError(c, "RevokeAPIKey", err)
+	}
+
+	return c.JSON(http.StatusOK, key)
+}
+
+// serviceAccountError отвечает на ошибку операции с сервисными учетными записями.
+func (s *Server) serviceAccountError(c echo.Context, handler string, err error) error {
+	switch {
+	case errors.Is(err, apikey.ErrInvalidRequest):
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "Service account or API key not found"})
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Service account with this name already exists"})
+	}
+
+	s.logger.Error(handler+": failed to process service account", slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error: "Failed to process service account",
+	})
+}
@@ -0,0 +1,288 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/service/auth"
+)
+
+// TwoFactorLoginRequest второй шаг входа.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // Код из приложения или код восстановления
+}
+
+// TwoFactorEnrollRequest подключение второго фактора во время входа.
+type TwoFactorEnrollRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+// TwoFactorCodeRequest запрос с кодом второго фактора.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse новые коды восстановления: показываются один раз.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginTwoFactor завершает вход кодом второго фактора и выдает токен.
+// @Summary Complete login with a two-factor code
+// @Description Принимает challenge_token из ответа /auth/login и код TOTP или код восстановления.
+// @Description Если второй фактор подключался во время входа, в ответе есть коды восстановления
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorLoginRequest true "Challenge and code"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/login/2fa [post]
+func (s *Server) LoginTwoFactor(c echo.Context) error {
+	var req TwoFactorLoginRequest
+	if err := c.Bind(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "challenge_token and code are required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ttl)
+	defer cancel()
+
+	claims, recoveryCodes, err := s.authService.CompleteTwoFactorLogin(ctx, req.ChallengeToken, req.Code, c.RealIP())
+	if err != nil {
+		return s.twoFactorError(c, "LoginTwoFactor", err)
+	}
+
+	response, err := s.loginResponse(ctx, c, claims)
+	if err != nil {
+		s.logger.Error("Failed to generate JWT", "error", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error: "Failed to generate token",
+		})
+	}
+	response.RecoveryCodes = recoveryCodes
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// LoginTwoFactorEnroll выдает секрет для подключения второго фактора во время входа,
+// когда политика требует его от администратора.
+// @Summary Enroll two-factor authentication during login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorEnrollRequest true "Challenge"
+// @Success 200 {object} model.TwoFactorEnrollment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/login/2fa/enroll [post]
+func (s *Server) LoginTwoFactorEnroll(c echo.Context) error {
+	var req TwoFactorEnrollRequest
+	if err := c.Bind(&req); err != nil || req.ChallengeToken == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "challenge_token is required",
+		})
+	}
+
+	enrollment, err := s.authService.StartChallengeEnrollment(c.Request().Context(), req.ChallengeToken)
+	if err != nil {
+		return s.twoFactorError(c, "LoginTwoFactorEnroll", err)
+	}
+
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+// GetTwoFactorStatus возвращает состояние второго фактора текущего пользователя.
+// @Summary Get two-factor status
+// @Tags two-factor
+// @Produce json
+// @Success 200 {object} model.TwoFactorStatus
+// @Failure 404 {object} ErrorResponse
+// @Router /api/me/2fa [get]
+func (s *Server) GetTwoFactorStatus(c echo.Context) error {
+	status, err := s.authService.TwoFactorStatus(c.Request().Context(), currentLogin(c))
+	if err != nil {
+		return s.twoFactorError(c, "GetTwoFactorStatus", err)
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// EnrollTwoFactor начинает подключение второго фактора текущего пользователя.
+// @Summary Start two-factor enrollment
+// @Description Возвращает секрет, otpauth:// URI и QR-код для приложения-аутентификатора.
+// @Description Второй фактор включается после подтверждения кодом: POST /api/me/2fa/confirm
+// @Tags two-factor
+// @Produce json
+// @Success 200 {object} model.TwoFactorEnrollment
+// @Failure 409 {object} ErrorResponse
+// @Router /api/me/2fa/enroll [post]
+func (s *Server) EnrollTwoFactor(c echo.Context) error {
+	enrollment, err := s.authService.StartTwoFactorEnrollment(c.Request().Context(), currentLogin(c))
+	if err != nil {
+		return s.twoFactorError(c, "EnrollTwoFactor", err)
+	}
+
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactor включает второй фактор по коду из приложения.
+// @Summary Confirm two-factor enrollment
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "Code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/me/2fa/confirm [post]
+func (s *Server) ConfirmTwoFactor(c echo.Context) error {
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+
+	codes, err := s.authService.ConfirmTwoFactor(c.Request().Context(), currentLogin(c), req.Code)
+	if err != nil {
+		return s.twoFactorError(c, "ConfirmTwoFactor", err)
+	}
+
+	return c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor отключает второй фактор текущего пользователя.
+// @Summary Disable two-factor authentication
+// @Tags two-factor
+// @Accept json
+// @Param request body TwoFactorCodeRequest true "Code"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/me/2fa/disable [post]
+func (s *Server) DisableTwoFactor(c echo.Context) error {
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+
+	if err := s.authService.DisableTwoFactor(c.Request().Context(), currentLogin(c), req.Code); err != nil {
+		return s.twoFactorError(c, "DisableTwoFactor", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes выпускает новые коды восстановления текущего пользователя.
+// @Summary Regenerate recovery codes
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "Code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/me/2fa/recovery-codes [post]
+func (s *Server) RegenerateRecoveryCodes(c echo.Context) error {
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+
+	codes, err := s.authService.RegenerateRecoveryCodes(c.Request().Context(), currentLogin(c), req.Code)
+	if err != nil {
+		return s.twoFactorError(c, "RegenerateRecoveryCodes", err)
+	}
+
+	return c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// ResetUserTwoFactor сбрасывает второй фактор пользователя, потерявшего телефон и коды восстановления.
+// @Summary Reset user's two-factor authentication
+// @Tags two-factor
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/admin/users/{id}/2fa [delete]
+func (s *Server) ResetUserTwoFactor(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	}
+
+	if err := s.authService.ResetTwoFactor(c.Request().Context(), id, currentLogin(c)); err != nil {
+		return s.twoFactorError(c, "ResetUserTwoFactor", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetAuthPolicy возвращает политику входа.
+// @Summary Get auth policy
+// @Tags two-factor
+// @Produce json
+// @Success 200 {object} model.AuthPolicy
+// @Router /api/admin/auth-policy [get]
+func (s *Server) GetAuthPolicy(c echo.Context) error {
+	policy, err := s.authService.GetAuthPolicy(c.Request().Context())
+	if err != nil {
+		return s.twoFactorError(c, "GetAuthPolicy", err)
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdateAuthPolicy меняет политику входа.
+// @Summary Update auth policy
+// @Description require_admin_2fa: администраторы входят только со вторым фактором.
+// @Description Администраторы без второго фактора подключат его при следующем входе
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param request body model.AuthPolicyUpdateRequest true "Policy"
+// @Success 200 {object} model.AuthPolicy
+// @Failure 400 {object} ErrorResponse
+// @Router /api/admin/auth-policy [put]
+func (s *Server) UpdateAuthPolicy(c echo.Context) error {
+	var req model.AuthPolicyUpdateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+
+	policy, err := s.authService.UpdateAuthPolicy(c.Request().Context(), req, currentLogin(c))
+	if err != nil {
+		return s.twoFactorError(c, "UpdateAuthPolicy", err)
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+// twoFactorError переводит ошибки второго фактора в HTTP-ответ.
+func (s *Server) twoFactorError(c echo.Context, handler string, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidChallenge):
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Login session expired, please sign in again"})
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid verification code"})
+	case errors.Is(err, auth.ErrTwoFactorMandatory):
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Two-factor authentication is required for administrators"})
+	case errors.Is(err, auth.ErrTwoFactorAlreadyEnabled):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Two-factor authentication is already enabled"})
+	case errors.Is(err, auth.ErrTwoFactorNotEnabled):
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Two-factor authentication is not enabled"})
+	case errors.Is(err, auth.ErrUnknownUser):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	s.logger.Error(handler+": failed to process two-factor request", slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error: "Failed to process two-factor request",
+	})
+}
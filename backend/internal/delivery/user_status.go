@@ -0,0 +1,142 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/service/user"
+)
+
+// statusFilterAll значение параметра status для списка пользователей в любом состоянии.
+const statusFilterAll = "all"
+
+// ReactivateUserRequest запрос на возврат учетной записи в работу.
+type ReactivateUserRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// ChangeUserStatus приостанавливает, отключает или возвращает в работу учетную запись.
+// @Summary Change user status
+// @Description Приостановка (suspended) действует с effective_from до effective_until, отключение (deactivated) - с effective_from.
+// @Description Записи пользователя остаются; вход и токены приостановленного или отключенного пользователя не принимаются
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "Версия пользователя (ETag)"
+// @Param request body model.UserStatusChange true "Status, reason and effective dates"
+// @Success 200 {object} UserAPIResponse
+// @Header 200 {string} ETag "Версия пользователя"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ValidationErrorResponse
+// @Router /api/admin/users/{id}/status [put]
+func (s *Server) ChangeUserStatus(c echo.Context) error {
+	var change model.UserStatusChange
+	if err := c.Bind(&change); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+	}
+	return s.changeUserStatus(c, change)
+}
+
+// ReactivateUser возвращает приостановленную или отключенную учетную запись в работу.
+// @Summary Reactivate user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "Версия пользователя (ETag)"
+// @Param request body ReactivateUserRequest false "Reason"
+// @Success 200 {object} UserAPIResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/users/{id}/reactivate [post]
+func (s *Server) ReactivateUser(c echo.Context) error {
+	var req ReactivateUserRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		}
+	}
+	return s.changeUserStatus(c, model.UserStatusChange{Status: model.UserStatusActive, Reason: req.Reason})
+}
+
+// changeUserStatus применяет смену состояния пользователя из пути запроса.
+func (s *Server) changeUserStatus(c echo.Context, change model.UserStatusChange) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil && !errors.Is(err, errIfMatchRequired) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if version > 0 {
+		change.ExpectedVersion = &version
+	}
+	change.ChangedBy = currentLogin(c)
+
+	ctx := c.Request().Context()
+	updated, err := s.userService.ChangeStatus(ctx, id, change)
+	if errors.Is(err, model.ErrVersionConflict) {
+		current, getErr := s.userService.GetUserByID(ctx, id)
+		if getErr != nil {
+			return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		}
+		return versionConflictResponse(c, current.Version, toUserAPIResponse(current))
+	}
+	if err != nil {
+		return s.userStatusError(c, "ChangeUserStatus", id, err)
+	}
+
+	setETag(c, updated.Version)
+	return c.JSON(http.StatusOK, toUserAPIResponse(updated))
+}
+
+// userStatusError преобразует ошибку смены состояния пользователя в HTTP ответ.
+func (s *Server) userStatusError(c echo.Context, handler string, id int64, err error) error {
+	var fields model.ValidationErrors
+	switch {
+	case errors.As(err, &fields):
+		return c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{Error: "Invalid status change", Fields: fields})
+	case errors.Is(err, user.ErrOwnStatus):
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "You cannot suspend or deactivate your own account"})
+	case errors.Is(err, repository.ErrNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	}
+
+	s.logger.Error(handler+": failed to change user status", slog.Int64("id", id), slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to change user status"})
+}
+
+// parseStatusFilter разбирает параметр status списка пользователей: состояния через запятую
+// или all. Без параметра возвращаются действующие и приостановленные, как до появления отключения.
+func parseStatusFilter(value string) ([]model.UserStatus, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return []model.UserStatus{model.UserStatusActive, model.UserStatusSuspended}, nil
+	case statusFilterAll:
+		return nil, nil
+	}
+
+	var statuses []model.UserStatus
+	for _, part := range strings.Split(value, ",") {
+		status := model.UserStatus(strings.TrimSpace(part))
+		if !status.Valid() {
+			return nil, fmt.Errorf("invalid status %q: expected active, suspended, deactivated or all", part)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -2,13 +2,16 @@ package delivery
 
 import (
 	"crypto/rand"
+	"errors"
 	"math/big"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
 )
 
 // CreateUserRequest представляет запрос на создание пользователя через API.
@@ -27,7 +30,6 @@ type UpdateUserRequest struct {
 	LastName  *string `json:"lastName,omitempty"`
 	Region    *string `json:"region,omitempty"`
 	Position  *string `json:"position,omitempty"`
-	Status    *string `json:"status,omitempty"`
 }
 
 // UserFilterRequest представляет параметры фильтрации из query string.
@@ -35,20 +37,30 @@ type UserFilterRequest struct {
 	SearchTerm string `query:"search"`
 	Region     string `query:"region"`
 	Position   string `query:"position"`
+	Status     string `query:"status"` // Состояния через запятую или all; по умолчанию active,suspended
 	Page       int    `query:"page"`
 	Limit      int    `query:"limit"`
 }
 
 // UserAPIResponse представляет пользователя для API (с дополнительными полями для фронтенда).
 type UserAPIResponse struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Region    string `json:"region"`
-	Position  string `json:"position"`
-	CreatedAt string `json:"createdAt"`
-	Status    string `json:"status"`
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	Region      string `json:"region"`
+	Position    string `json:"position"`
+	CreatedAt   string `json:"createdAt"`
+	Status      string `json:"status"`                // Действующее состояние: active, suspended или deactivated
+	LockedUntil string `json:"lockedUntil,omitempty"` // Вход временно заблокирован после неудачных попыток
+	Version     int    `json:"version"`
+
+	ScheduledStatus string `json:"scheduledStatus,omitempty"` // Приостановка или отключение с будущей даты statusFrom
+	StatusReason    string `json:"statusReason,omitempty"`
+	StatusFrom      string `json:"statusFrom,omitempty"`
+	StatusUntil     string `json:"statusUntil,omitempty"`
+	StatusChangedBy string `json:"statusChangedBy,omitempty"`
+	StatusChangedAt string `json:"statusChangedAt,omitempty"`
 }
 
 // RegionStatsResponse представляет статистику по региону.
@@ -79,6 +91,7 @@ type UserCredentials struct {
 // @Param search query string false "Поиск по имени"
 // @Param region query string false "Фильтр по региону"
 // @Param position query string false "Фильтр по позиции"
+// @Param status query string false "Состояния через запятую (active, suspended, deactivated) или all" default(active,suspended)
 // @Param page query int false "Номер страницы" default(1)
 // @Param limit query int false "Количество на странице" default(10)
 // @Success 200 {array} UserAPIResponse
@@ -102,8 +115,13 @@ func (s *Server) GetUsers(c echo.Context) error {
 		filterReq.Limit = 10
 	}
 
+	statuses, err := parseStatusFilter(filterReq.Status)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
 	// Построение фильтра для сервиса
-	filter := model.UserFilter{}
+	filter := model.UserFilter{Statuses: statuses}
 
 	if filterReq.Region != "" {
 		filter.Region = &filterReq.Region
@@ -190,6 +208,7 @@ func (s *Server) GetUserByID(c echo.Context) error {
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.JSON(http.StatusOK, toUserAPIResponse(user))
 }
 
@@ -202,6 +221,7 @@ func (s *Server) GetUserByID(c echo.Context) error {
 // @Param user body CreateUserRequest true "User data"
 // @Success 201 {object} CreateUserResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/users [post]
 func (s *Server) CreateUser(c echo.Context) error {
@@ -242,6 +262,9 @@ func (s *Server) CreateUser(c echo.Context) error {
 	}
 
 	user, err := s.userService.CreateUser(c.Request().Context(), createReq)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "User with this email already exists"})
+	}
 	if err != nil {
 		s.logger.Error("CreateUser: failed to create user", "email", req.Email, "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -268,10 +291,14 @@ func (s *Server) CreateUser(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param If-Match header string true "Версия пользователя (ETag), * - без проверки"
 // @Param user body UpdateUserRequest true "User data"
 // @Success 200 {object} UserAPIResponse
+// @Header 200 {string} ETag "Версия пользователя"
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} VersionConflictResponse
+// @Failure 428 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/users/{id} [put]
 func (s *Server) UpdateUser(c echo.Context) error {
@@ -283,6 +310,14 @@ func (s *Server) UpdateUser(c echo.Context) error {
 		})
 	}
 
+	version, err := parseIfMatch(c)
+	if err != nil {
+		if errors.Is(err, errIfMatchRequired) {
+			return c.JSON(http.StatusPreconditionRequired, ErrorResponse{Error: err.Error()})
+		}
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
 	var req UpdateUserRequest
 	if err := c.Bind(&req); err != nil {
 		s.logger.Error("UpdateUser: failed to bind request", "error", err)
@@ -298,6 +333,9 @@ func (s *Server) UpdateUser(c echo.Context) error {
 		LastName:  req.LastName,
 		Region:    req.Region,
 	}
+	if version > 0 {
+		update.ExpectedVersion = &version
+	}
 
 	// Маппинг position на role если передан
 	if req.Position != nil {
@@ -307,6 +345,18 @@ func (s *Server) UpdateUser(c echo.Context) error {
 
 	// Обновление через сервис
 	user, err := s.userService.UpdateUser(c.Request().Context(), id, update)
+	if errors.Is(err, model.ErrVersionConflict) {
+		current, getErr := s.userService.GetUserByID(c.Request().Context(), id)
+		if getErr != nil {
+			return c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "User not found",
+			})
+		}
+		return versionConflictResponse(c, current.Version, toUserAPIResponse(current))
+	}
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return c.JSON(http.StatusConflict, ErrorResponse{Error: "Email is already used by another user"})
+	}
 	if err != nil {
 		s.logger.Error("UpdateUser: failed to update user", "id", id, "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -314,21 +364,25 @@ func (s *Server) UpdateUser(c echo.Context) error {
 		})
 	}
 
+	setETag(c, user.Version)
 	return c.JSON(http.StatusOK, toUserAPIResponse(user))
 }
 
-// DeleteUser удаляет пользователя.
-// @Summary Delete user
-// @Description Удаление пользователя
+// DeleteUser отключает пользователя вместо удаления: созданные им записи остаются,
+// вернуть учетную запись можно через POST /api/admin/users/{id}/reactivate.
+// @Summary Deactivate user
+// @Description Отключение пользователя (status deactivated); строка пользователя не удаляется
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param reason query string false "Причина отключения"
 // @Success 204
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /api/users/{id} [delete]
+// @Router /api/admin/users/{id} [delete]
 func (s *Server) DeleteUser(c echo.Context) error {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -338,12 +392,9 @@ func (s *Server) DeleteUser(c echo.Context) error {
 		})
 	}
 
-	err = s.userService.DeleteUser(c.Request().Context(), id)
+	_, err = s.userService.DeactivateUser(c.Request().Context(), id, c.QueryParam("reason"), currentLogin(c))
 	if err != nil {
-		s.logger.Error("DeleteUser: failed to delete user", "id", id, "error", err)
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: "Failed to delete user",
-		})
+		return s.userStatusError(c, "DeleteUser", id, err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -359,8 +410,10 @@ func (s *Server) DeleteUser(c echo.Context) error {
 // @Failure 500 {object} ErrorResponse
 // @Router /api/users/stats [get]
 func (s *Server) GetUserStats(c echo.Context) error {
-	// Получение всех пользователей
-	users, err := s.userService.GetUsers(c.Request().Context(), model.UserFilter{})
+	// Получение всех пользователей, кроме отключенных
+	users, err := s.userService.GetUsers(c.Request().Context(), model.UserFilter{
+		Statuses: []model.UserStatus{model.UserStatusActive, model.UserStatusSuspended},
+	})
 	if err != nil {
 		s.logger.Error("GetUserStats: failed to get users", "error", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -368,29 +421,30 @@ func (s *Server) GetUserStats(c echo.Context) error {
 		})
 	}
 
-	// Список всех регионов
-	regions := []string{
-		"Central", "Caucasus", "Volga", "Ural",
-		"Siberia", "Far East", "North-West", "South",
-	}
+	// Список всех регионов из справочника
+	regions := model.Regions().List()
 
-	// Группировка по регионам
+	// Группировка по регионам с учетом алиасов
 	regionMap := make(map[string][]*model.UserResponse)
 	for _, user := range users {
-		regionMap[user.Region] = append(regionMap[user.Region], user)
+		region := user.Region
+		if canonical, ok := model.Regions().Canonical(region); ok {
+			region = canonical
+		}
+		regionMap[region] = append(regionMap[region], user)
 	}
 
 	// Формирование статистики
 	regionStats := make([]RegionStatsResponse, 0, len(regions))
 	for _, region := range regions {
-		usersInRegion := regionMap[region]
+		usersInRegion := regionMap[region.Name]
 		apiUsers := make([]UserAPIResponse, 0, len(usersInRegion))
 		for _, user := range usersInRegion {
 			apiUsers = append(apiUsers, toUserAPIResponse(user))
 		}
 
 		regionStats = append(regionStats, RegionStatsResponse{
-			Region:    region,
+			Region:    region.Name,
 			UserCount: len(usersInRegion),
 			Users:     apiUsers,
 		})
@@ -411,16 +465,45 @@ type ErrorResponse struct {
 
 // toUserAPIResponse преобразует UserResponse в UserAPIResponse.
 func toUserAPIResponse(user *model.UserResponse) UserAPIResponse {
+	now := time.Now()
+	var lockedUntil string
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		lockedUntil = user.LockedUntil.Format(time.RFC3339)
+	}
+
+	status := user.StatusAt(now)
+	var scheduledStatus string
+	if user.Status != status && user.StatusFrom != nil && user.StatusFrom.After(now) {
+		scheduledStatus = string(user.Status)
+	}
+
 	return UserAPIResponse{
-		ID:        strconv.FormatInt(user.ID, 10),
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Region:    user.Region,
-		Position:  string(user.Role), // Role мапится на Position
-		CreatedAt: user.CreatedAt.Format("2006-01-02"),
-		Status:    "active", // По умолчанию все пользователи активны
+		ID:          strconv.FormatInt(user.ID, 10),
+		Email:       user.Email,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Region:      user.Region,
+		Position:    string(user.Role), // Role мапится на Position
+		CreatedAt:   user.CreatedAt.Format("2006-01-02"),
+		Status:      string(status),
+		LockedUntil: lockedUntil,
+		Version:     user.Version,
+
+		ScheduledStatus: scheduledStatus,
+		StatusReason:    user.StatusReason,
+		StatusFrom:      formatOptionalTime(user.StatusFrom),
+		StatusUntil:     formatOptionalTime(user.StatusUntil),
+		StatusChangedBy: user.StatusChangedBy,
+		StatusChangedAt: formatOptionalTime(user.StatusChangedAt),
+	}
+}
+
+// formatOptionalTime форматирует время в RFC 3339; nil - пустая строка.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
 	}
+	return t.Format(time.RFC3339)
 }
 
 // filterUsersByName фильтрует пользователей по имени или фамилии.
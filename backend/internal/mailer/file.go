@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSink складывает письма в каталог файлами .eml вместо отправки.
+// Для разработки и тестов: письмо открывается любым почтовым клиентом.
+type FileSink struct {
+	dir  string
+	from string
+}
+
+// NewFileSink создает файловый приемник писем; каталог создается при необходимости.
+func NewFileSink(dir, from string) (*FileSink, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mailer.NewFileSink: directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("mailer.NewFileSink: %w", err)
+	}
+	if from == "" {
+		from = "noreply@localhost"
+	}
+	return &FileSink{dir: dir, from: from}, nil
+}
+
+// Send записывает письмо в файл <время>-<случайный суффикс>.eml.
+func (f *FileSink) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := compose(f.from, msg, now)
+	if err != nil {
+		return fmt.Errorf("FileSink.Send: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("FileSink.Send: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	// Файл доступен только владельцу: в письме ссылка для установки пароля
+	if err := os.WriteFile(filepath.Join(f.dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("FileSink.Send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// defaultSMTPTimeout ограничивает отправку, если в контексте нет своего срока.
+const defaultSMTPTimeout = 30 * time.Second
+
+// SMTPConfig настройки SMTP сервера.
+type SMTPConfig struct {
+	Host     string
+	Port     int // По умолчанию 25
+	Username string
+	Password string
+	From     string // Адрес отправителя, например "Dealer Platform <noreply@corp.local>"
+
+	// ImplicitTLS соединение сразу по TLS (порт 465). Без него STARTTLS включается,
+	// если сервер его поддерживает.
+	ImplicitTLS        bool
+	InsecureSkipVerify bool
+}
+
+// SMTP отправляет письма через SMTP сервер.
+type SMTP struct {
+	cfg SMTPConfig
+}
+
+// NewSMTP создает отправку писем через SMTP.
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("mailer.NewSMTP: host is required")
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("mailer.NewSMTP: invalid sender %q: %w", cfg.From, err)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 25
+	}
+	return &SMTP{cfg: cfg}, nil
+}
+
+// Send отправляет письмо. Пароль передается только по защищенному соединению
+// или на localhost: так работает smtp.PlainAuth.
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := compose(m.cfg.From, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("SMTP.Send: %w", err)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultSMTPTimeout)
+		defer cancel()
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: m.cfg.Host, InsecureSkipVerify: m.cfg.InsecureSkipVerify}
+
+	var conn net.Conn
+	if m.cfg.ImplicitTLS {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("SMTP.Send: failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP.Send: %w", err)
+	}
+	defer client.Close()
+
+	if !m.cfg.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("SMTP.Send: STARTTLS failed: %w", err)
+			}
+		}
+	}
+
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP.Send: authentication failed: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(m.cfg.From)
+	to, _ := mail.ParseAddress(msg.To)
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP.Send: MAIL FROM: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP.Send: RCPT TO: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP.Send: DATA: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("SMTP.Send: failed to write message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP.Send: message rejected: %w", err)
+	}
+
+	return client.Quit()
+}
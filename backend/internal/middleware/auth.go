@@ -1,15 +1,33 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
 	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
 )
 
+// HeaderAPIKey заголовок с ключом API сервисной учетной записи.
+const HeaderAPIKey = "X-API-Key"
+
+// APIKeyAuthenticator проверяет ключи API сервисных учетных записей.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey, clientIP string) (*model.APIKeyPrincipal, error)
+}
+
+// SessionValidator проверяет, что сессия JWT не отозвана.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, claims *jwt.JWTClaims, clientIP string) error
+}
+
 // AuthMiddleware проверяет JWT токен из Authorization header (localStorage)
-func AuthMiddleware(jwtService *jwt.Service) echo.MiddlewareFunc {
+// или ключ API сервисной учетной записи (Authorization: Bearer ddp_... либо X-API-Key).
+// Для JWT дополнительно проверяется сессия: отозванный токен не принимается до истечения срока.
+func AuthMiddleware(jwtService *jwt.Service, apiKeys APIKeyAuthenticator, sessions SessionValidator) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			var token string
@@ -31,12 +49,21 @@ func AuthMiddleware(jwtService *jwt.Service) echo.MiddlewareFunc {
 				}
 			}
 
+			// Ключ API можно передать отдельным заголовком
+			if token == "" {
+				token = c.Request().Header.Get(HeaderAPIKey)
+			}
+
 			if token == "" {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": "Authentication required",
 				})
 			}
 
+			if strings.HasPrefix(token, model.APIKeyPrefix) {
+				return authenticateAPIKey(c, next, apiKeys, token)
+			}
+
 			// Валидируем токен
 			claims, err := jwtService.ValidateJWT(token)
 			if err != nil {
@@ -45,6 +72,25 @@ func AuthMiddleware(jwtService *jwt.Service) echo.MiddlewareFunc {
 				})
 			}
 
+			if sessions != nil {
+				err := sessions.ValidateSession(c.Request().Context(), claims, c.RealIP())
+				if errors.Is(err, model.ErrSessionRevoked) {
+					return c.JSON(http.StatusUnauthorized, map[string]string{
+						"error": "Session expired, please sign in again",
+					})
+				}
+				if errors.Is(err, model.ErrAccountInactive) {
+					return c.JSON(http.StatusUnauthorized, map[string]string{
+						"error": "Account is suspended or deactivated",
+					})
+				}
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{
+						"error": "Failed to check session",
+					})
+				}
+			}
+
 			// Сохраняем информацию о пользователе в контекст
 			c.Set("user", claims)
 			c.Set("user_login", claims.Login)
@@ -56,10 +102,95 @@ func AuthMiddleware(jwtService *jwt.Service) echo.MiddlewareFunc {
 	}
 }
 
-// AdminMiddleware проверяет, что пользователь является администратором
-func AdminMiddleware() echo.MiddlewareFunc {
+// authenticateAPIKey проверяет ключ API и сохраняет сервисную учетную запись в контекст.
+// В user_login записывается исполнитель вида svc:<учетная запись>/<префикс ключа>,
+// поэтому в каталоге загрузок и логах видно, что действие выполнил ключ, а не человек.
+func authenticateAPIKey(c echo.Context, next echo.HandlerFunc, apiKeys APIKeyAuthenticator, rawKey string) error {
+	if apiKeys == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid token",
+		})
+	}
+
+	// c.RealIP() доверяет X-Real-IP только от прокси из внутренней сети (IPExtractor сервера)
+	principal, err := apiKeys.Authenticate(c.Request().Context(), rawKey, c.RealIP())
+	if errors.Is(err, model.ErrAPIKeyIPNotAllowed) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "API key is not allowed from this address",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid API key",
+		})
+	}
+
+	// Ключ не получает прав администратора: доступ к маршруту дают только выданные ключу области
+	c.Set(apiKeyContextKey, principal)
+	c.Set("user_login", principal.Actor())
+	c.Set("user_is_admin", false)
+	c.Set("user_role", string(principal.Account.Role))
+
+	return next(c)
+}
+
+// apiKeyContextKey ключ контекста, под которым лежит сервисная учетная запись, вошедшая по ключу API.
+const apiKeyContextKey = "api_key"
+
+// apiKeyPrincipal возвращает сервисную учетную запись запроса или nil, если вошел пользователь.
+func apiKeyPrincipal(c echo.Context) *model.APIKeyPrincipal {
+	principal, _ := c.Get(apiKeyContextKey).(*model.APIKeyPrincipal)
+	return principal
+}
+
+// requireScopes проверяет, что ключу выданы все области. Без областей ключ не допускается.
+func requireScopes(c echo.Context, next echo.HandlerFunc, principal *model.APIKeyPrincipal, scopes []model.APIKeyScope) error {
+	if len(scopes) == 0 {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "API keys are not allowed for this endpoint",
+		})
+	}
+
+	for _, scope := range scopes {
+		if !principal.Key.HasScope(scope) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "API key does not have the " + string(scope) + " scope",
+			})
+		}
+	}
+
+	return next(c)
+}
+
+// ScopeMiddleware допускает ключ API, только если ему выданы области scopes.
+// Пользователей не проверяет: их права задают RoleMiddleware и AdminMiddleware.
+func ScopeMiddleware(scopes ...model.APIKeyScope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if principal := apiKeyPrincipal(c); principal != nil {
+				return requireScopes(c, next, principal, scopes)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// UserMiddleware не допускает ключи API: профиль, сессии и управление пользователями доступны только людям.
+func UserMiddleware() echo.MiddlewareFunc {
+	return ScopeMiddleware()
+}
+
+// AdminMiddleware проверяет, что пользователь является администратором.
+// Ключ API допускается, только если маршрут разрешает ключам области scopes и все они выданы ключу;
+// без scopes маршрут закрыт для ключей.
+func AdminMiddleware(scopes ...model.APIKeyScope) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if principal := apiKeyPrincipal(c); principal != nil {
+				return requireScopes(c, next, principal, scopes)
+			}
+
 			isAdmin, ok := c.Get("user_is_admin").(bool)
 			if !ok || !isAdmin {
 				return c.JSON(http.StatusForbidden, map[string]string{
@@ -72,6 +203,29 @@ func AdminMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// RoleMiddleware проверяет, что роль пользователя входит в список разрешенных.
+// Администраторы проходят проверку независимо от роли.
+func RoleMiddleware(roles ...model.UserRole) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if isAdmin, ok := c.Get("user_is_admin").(bool); ok && isAdmin {
+				return next(c)
+			}
+
+			role, _ := c.Get("user_role").(string)
+			for _, allowed := range roles {
+				if model.UserRole(role) == allowed {
+					return next(c)
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Insufficient permissions",
+			})
+		}
+	}
+}
+
 // OptionalAuthMiddleware проверяет JWT токен, но не требует его наличия
 func OptionalAuthMiddleware(jwtService *jwt.Service) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -2,14 +2,12 @@ package model
 
 import "time"
 
-// ASTrainingsStatus представляет статус тренингов по послепродажному обслуживанию.
-type ASTrainingsStatus string
+// ASTrainingsStatus статус тренингов по послепродажному обслуживанию: Yes или No.
+type ASTrainingsStatus = YesNo
 
 const (
-	ASTrainingsYes ASTrainingsStatus = "Yes"
-	ASTrainingsNo  ASTrainingsStatus = "No"
-	ASTrainingsY   ASTrainingsStatus = "Y"
-	ASTrainingsN   ASTrainingsStatus = "N"
+	ASTrainingsYes = Yes
+	ASTrainingsNo  = No
 )
 
 // AfterSales отвечает за блок AfterSales (послепродажное обслуживание).
@@ -36,6 +34,13 @@ type AfterSales struct {
 	CSI        *string `json:"csi" db:"csi"`
 	ASDecision string  `json:"as_decision" db:"as_decision"`
 
+	// Spare parts (из Excel, хранятся как в исходном файле)
+	SparePartsSalesQuarter string `json:"spare_parts_sales_quarter" db:"spare_parts_sales_quarter"` // За квартал периода выборки
+	SparePartsSalesYtdPct  string `json:"spare_parts_sales_ytd_pct" db:"spare_parts_sales_ytd_pct"`
+	FotonLabourHoursShare  string `json:"foton_labour_hours_share" db:"foton_labour_hours_share"`
+
+	Version int `json:"version" db:"version"` // Версия записи для оптимистичной блокировки
+
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -48,8 +53,22 @@ type AfterSalesWithDetails struct {
 	City         string `json:"city"`
 	Region       string `json:"region"`
 	Manager      string `json:"manager"`
-	// Дополнительные поля для Excel данных
-	SparePartsSalesQ3     string `json:"spare_parts_sales_q3"`
-	SparePartsSalesYtd    string `json:"spare_parts_sales_ytd_percent"`
-	FotonLabourHoursShare string `json:"foton_labour_hours_share"`
+}
+
+// AfterSalesPatchSchema поля AfterSales, которые можно менять частичным обновлением.
+var AfterSalesPatchSchema = PatchSchema{
+	Fields: map[string]PatchField{
+		"recommended_stock":         {Type: PatchInt, Min: PatchBound(0)},
+		"warranty_stock":            {Type: PatchInt, Min: PatchBound(0)},
+		"foton_labor_hours":         {Type: PatchInt, Min: PatchBound(0)},
+		"foton_warranty_hours":      {Type: PatchInt, Min: PatchBound(0)},
+		"service_contracts":         {Type: PatchInt, Min: PatchBound(0)},
+		"as_trainings":              {Type: PatchBool},
+		"csi":                       {Type: PatchString, Nullable: true},
+		"as_decision":               {Type: PatchString, Enum: Decisions},
+		"spare_parts_sales_quarter": {Type: PatchString},
+		"spare_parts_sales_ytd_pct": {Type: PatchString},
+		"foton_labour_hours_share":  {Type: PatchString},
+	},
+	ReadOnly: recordKeyFields,
 }
@@ -13,10 +13,17 @@ type Dealer struct {
 	City          string    `json:"city" db:"city"`
 	Manager       string    `json:"manager" db:"manager"`               // Менеджер, отвечающий за дилера
 	JointDecision *string   `json:"joint_decision" db:"joint_decision"` // Joint Decision (заполняется вручную через UI)
+	Version       int       `json:"version" db:"version"`               // Версия записи для оптимистичной блокировки
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// JointDecisionRequest запрос на изменение совместного решения по дилеру.
+// null очищает решение.
+type JointDecisionRequest struct {
+	JointDecision *string `json:"joint_decision"`
+}
+
 // DealerBrand представляет связь между дилером и брендами в его портфеле.
 type DealerBrand struct {
 	ID        int64     `json:"id" db:"id"`
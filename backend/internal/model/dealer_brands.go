@@ -15,6 +15,9 @@ type BrandsUploadResponse struct {
 	UpdatedCount    int      `json:"updated_count"`     // Количество обновленных дилеров
 	NotFoundDealers []string `json:"not_found_dealers"` // Список дилеров, которые не найдены
 	ProcessingTime  string   `json:"processing_time"`   // Время обработки
+
+	Promotion *PromotionResult `json:"promotion,omitempty"`  // Перенос в нормализованные таблицы
+	DatasetID int64            `json:"dataset_id,omitempty"` // Запись в каталоге загрузок
 }
 
 // BrandsFileInfo содержит метаданные о файле с брендами.
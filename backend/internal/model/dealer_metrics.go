@@ -0,0 +1,93 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DealerMetricColumns колонки показателей в таблице dealer_metrics.
+// Колонки загрузки, которых нет в списке, сохраняются в JSONB-колонку extra.
+var DealerMetricColumns = []string{
+	"dealer",
+	"region",
+	"city",
+	"manager",
+	"class",
+	"check_list_percent",
+	"marketing_investments",
+	"branding",
+	"dealer_development",
+	"stock_hdt",
+	"stock_mdt",
+	"stock_ldt",
+	"buyout_hdt",
+	"buyout_mdt",
+	"buyout_ldt",
+	"sales",
+	"service_contracts_sales",
+	ColumnSparePartsSalesQuarter,
+	ColumnSparePartsSalesYtdPct,
+	"warranty_stock_percent",
+	"recommended_stock_percent",
+	"foton_labour_hours",
+	"foton_labour_hours_share",
+	"warranty_hours",
+	"service_contracts_hours",
+	"as_trainings",
+	"aftersales",
+	"joint_decision",
+	"brands_in_portfolio",
+	"byside_businesses",
+}
+
+// dealerMetricColumnSet множество колонок показателей для быстрой проверки.
+var dealerMetricColumnSet = func() map[string]bool {
+	set := make(map[string]bool, len(DealerMetricColumns))
+	for _, column := range DealerMetricColumns {
+		set[column] = true
+	}
+	return set
+}()
+
+// IsDealerMetricColumn проверяет, что колонка хранится в dealer_metrics отдельным полем.
+func IsDealerMetricColumn(column string) bool {
+	return dealerMetricColumnSet[column]
+}
+
+// QuarterStart возвращает первый день квартала - значение колонки period в dealer_metrics.
+func QuarterStart(year int, quarter string) (time.Time, error) {
+	quarter = strings.ToUpper(strings.TrimSpace(quarter))
+	if len(quarter) != 2 || quarter[0] != 'Q' || quarter[1] < '1' || quarter[1] > '4' {
+		return time.Time{}, fmt.Errorf("invalid quarter: %s", quarter)
+	}
+
+	month := time.Month(int(quarter[1]-'0')*3 - 2)
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// DealerNetPeriodName название загрузки dealer_net за квартал в API: dealer_net_<год>_<квартал>.
+func DealerNetPeriodName(year int, quarter string) string {
+	return fmt.Sprintf("dealer_net_%d_%s", year, strings.ToLower(quarter))
+}
+
+// ParseDealerNetPeriodName разбирает название загрузки dealer_net_<год>_<квартал> в год и квартал.
+func ParseDealerNetPeriodName(name string) (int, string, bool) {
+	parts := strings.Split(name, "_")
+	if len(parts) != 4 || parts[0] != "dealer" || parts[1] != "net" {
+		return 0, "", false
+	}
+
+	year, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, "", false
+	}
+
+	quarter := strings.ToUpper(parts[3])
+	if _, err := QuarterStart(year, quarter); err != nil {
+		return 0, "", false
+	}
+
+	return year, quarter, true
+}
@@ -12,14 +12,12 @@ const (
 	DealershipClassD DealershipClass = "D"
 )
 
-// BrandingStatus представляет статус брендинга.
-type BrandingStatus string
+// BrandingStatus статус брендинга: Yes или No.
+type BrandingStatus = YesNo
 
 const (
-	BrandingYes BrandingStatus = "Yes"
-	BrandingNo  BrandingStatus = "No"
-	BrandingY   BrandingStatus = "Y"
-	BrandingN   BrandingStatus = "N"
+	BrandingYes = Yes
+	BrandingNo  = No
 )
 
 // DealerDevelopment отвечает за блок Dealer Development.
@@ -34,6 +32,7 @@ type DealerDevelopment struct {
 	Branding             bool      `json:"branding" db:"branding"`                                   // Y, N, Yes, No
 	MarketingInvestments int64     `json:"marketing_investments" db:"marketing_investments"`         // Marketing Investments Rub
 	DDRecommendation     string    `json:"dealer_dev_recommendation" db:"dealer_dev_recommendation"` // Recommendation (из Excel)
+	Version              int       `json:"version" db:"version"`                                     // Версия записи для оптимистичной блокировки
 	CreatedAt            time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -54,3 +53,22 @@ type DealerDevelopmentWithDetails struct {
 
 // DealerDevWithDetails - алиас для совместимости со старым кодом.
 type DealerDevWithDetails = DealerDevelopmentWithDetails
+
+// DealerDevelopmentPatchSchema поля DealerDevelopment, которые можно менять частичным обновлением.
+var DealerDevelopmentPatchSchema = PatchSchema{
+	Fields: map[string]PatchField{
+		"check_list_score": {Type: PatchInt, Min: PatchBound(0), Max: PatchBound(100)},
+		"dealership_class": {
+			Column: "dealer_ship_class",
+			Type:   PatchString,
+			Enum: []string{
+				string(DealershipClassA), string(DealershipClassB),
+				string(DealershipClassC), string(DealershipClassD),
+			},
+		},
+		"branding":                  {Type: PatchBool},
+		"marketing_investments":     {Type: PatchInt, Min: PatchBound(0)},
+		"dealer_dev_recommendation": {Type: PatchString, Enum: Decisions},
+	},
+	ReadOnly: recordKeyFields,
+}
@@ -45,23 +45,13 @@ func (p *DynamicTableParams) Validate() error {
 		return fmt.Errorf("invalid quarter: %s", p.Quarter)
 	}
 
-	// Валидируем регионы
-	validRegions := map[string]bool{
-		"all-russia": true,
-		"Central":    true,
-		"North West": true,
-		"Volga":      true,
-		"South":      true,
-		"Kavkaz":     true,
-		"Ural":       true,
-		"Siberia":    true,
-		"Far East":   true,
-	}
-
-	for _, region := range p.Regions {
-		if !validRegions[region] {
+	// Валидируем регионы и приводим их к хранимым названиям
+	for i, region := range p.Regions {
+		canonical, ok := Regions().Canonical(region)
+		if !ok {
 			return fmt.Errorf("invalid region: %s", region)
 		}
+		p.Regions[i] = canonical
 	}
 
 	return nil
@@ -90,7 +80,7 @@ func ParseFromContext(c echo.Context) (*DynamicTableParams, error) {
 		regionsStr = c.QueryParam("region") // обратная совместимость
 	}
 	if regionsStr == "" {
-		regionsStr = "all-russia" // значение по умолчанию
+		regionsStr = AllRussia // значение по умолчанию
 	}
 
 	regions := []string{}
@@ -100,12 +90,7 @@ func ParseFromContext(c echo.Context) (*DynamicTableParams, error) {
 		for _, part := range regionParts {
 			region := strings.TrimSpace(part)
 			if region != "" {
-				// Применяем маппинг регионов
-				if mappedRegion := mapRegion(region); mappedRegion != "" {
-					regions = append(regions, mappedRegion)
-				} else {
-					regions = append(regions, region)
-				}
+				regions = append(regions, region)
 			}
 		}
 	}
@@ -138,23 +123,3 @@ func ParseFromContext(c echo.Context) (*DynamicTableParams, error) {
 
 	return params, nil
 }
-
-// mapRegion применяет маппинг регионов для совместимости с фронтендом
-func mapRegion(region string) string {
-	regionMapping := map[string]string{
-		"all":        "all-russia",
-		"central":    "Central",
-		"north-west": "North West",
-		"volga":      "Volga",
-		"south":      "South",
-		"n-caucasus": "Kavkaz",
-		"ural":       "Ural",
-		"siberia":    "Siberia",
-		"far-east":   "Far East",
-	}
-
-	if mapped, exists := regionMapping[region]; exists {
-		return mapped
-	}
-	return ""
-}
@@ -49,17 +49,25 @@ type ExcelUploadRequest struct {
 
 // ExcelUploadResponse представляет ответ на загрузку Excel файла.
 type ExcelUploadResponse struct {
-	Status         string           `json:"status"`
-	Message        string           `json:"message"`
-	TablesCreated  []string         `json:"tables_created"`
-	RowsInserted   int              `json:"rows_inserted"`
-	ProcessingTime time.Duration    `json:"processing_time"`
-	Details        []ExcelTableData `json:"details,omitempty"`
+	Status         string               `json:"status"`
+	Message        string               `json:"message"`
+	TablesCreated  []string             `json:"tables_created"`
+	RowsInserted   int                  `json:"rows_inserted"`
+	ProcessingTime time.Duration        `json:"processing_time"`
+	Errors         []ExcelError         `json:"errors,omitempty"`
+	ColumnReports  []ExcelColumnReport  `json:"column_reports,omitempty"`
+	FormulaReports []ExcelFormulaReport `json:"formula_reports,omitempty"`
+	FormulaErrors  []ExcelError         `json:"formula_errors,omitempty"`
+	ValueIssues    []ExcelValueIssue    `json:"value_issues,omitempty"`
+	Details        []ExcelTableData     `json:"details,omitempty"`
+	DatasetID      int64                `json:"dataset_id,omitempty"` // Запись в каталоге загрузок
 }
 
 // ExcelTableMetadata содержит метаданные о созданной таблице.
 type ExcelTableMetadata struct {
 	TableName string    `json:"table_name"`
+	Year      int       `json:"year,omitempty"`
+	Quarter   string    `json:"quarter,omitempty"`
 	RowsCount int       `json:"rows_count"`
 	CreatedAt time.Time `json:"created_at"`
 	Columns   []string  `json:"columns"`
@@ -74,13 +82,47 @@ type ExcelError struct {
 	Error     string `json:"error"`
 }
 
+// ExcelColumnReport содержит результат сопоставления заголовков листа со словарем.
+type ExcelColumnReport struct {
+	SheetName string   `json:"sheet_name"`
+	Unmapped  []string `json:"unmapped,omitempty"` // Заголовки, которых нет в словаре
+	Missing   []string `json:"missing,omitempty"`  // Отсутствующие обязательные колонки
+}
+
+// ExcelFormulaReport содержит ячейки колонки листа, значения которых вычислены при загрузке.
+// Вычисляются только формулы без сохраненного в файле значения; остальные значения берутся из файла.
+type ExcelFormulaReport struct {
+	SheetName string   `json:"sheet_name"`
+	Column    string   `json:"column"`
+	Computed  []string `json:"computed,omitempty"` // Вычисленные ячейки (C3, C4, ...)
+	Literal   int      `json:"literal"`            // Значения, взятые из файла
+	Failed    int      `json:"failed,omitempty"`   // Формулы, которые не удалось вычислить
+}
+
+// ExcelValueIssue ячейка, значение которой не удалось разобрать по типу колонки.
+// Значение сохраняется как есть, колонка остается в загрузке.
+type ExcelValueIssue struct {
+	SheetName string    `json:"sheet_name"`
+	Cell      string    `json:"cell"` // Адрес ячейки (C3)
+	Column    string    `json:"column"`
+	Kind      ValueKind `json:"kind"`
+	Value     string    `json:"value"`
+	Reason    string    `json:"reason,omitempty"` // Почему значение не принято, если это не просто нераспознанная запись
+}
+
 // ExcelProcessingResult содержит результат обработки Excel файла.
 type ExcelProcessingResult struct {
 	Success        bool                 `json:"success"`
 	TablesCreated  []ExcelTableMetadata `json:"tables_created"`
 	Errors         []ExcelError         `json:"errors,omitempty"`
+	ColumnReports  []ExcelColumnReport  `json:"column_reports,omitempty"`
+	FormulaReports []ExcelFormulaReport `json:"formula_reports,omitempty"` // Вычисленные при загрузке ячейки
+	FormulaErrors  []ExcelError         `json:"formula_errors,omitempty"`  // Формулы, которые не удалось вычислить
+	ValueIssues    []ExcelValueIssue    `json:"value_issues,omitempty"`    // Значения, которые не удалось нормализовать
 	TotalRows      int                  `json:"total_rows"`
 	ProcessingTime time.Duration        `json:"processing_time"`
+	Promotion      *PromotionResult     `json:"promotion,omitempty"`  // Перенос в нормализованные таблицы
+	DatasetID      int64                `json:"dataset_id,omitempty"` // Запись в каталоге загрузок
 }
 
 // DynamicTableColumn представляет колонку динамически созданной таблицы.
@@ -48,22 +48,13 @@ func (f *FilterParams) Validate() error {
 		return fmt.Errorf("invalid year: %d", f.Year)
 	}
 
-	// Валидация региона
+	// Валидация региона и приведение к хранимому названию
 	if f.Region != "" {
-		validRegions := map[string]bool{
-			"all-russia": true,
-			"Central":    true,
-			"North West": true,
-			"Volga":      true,
-			"South":      true,
-			"Kavkaz":     true,
-			"Ural":       true,
-			"Siberia":    true,
-			"Far East":   true,
-		}
-		if !validRegions[f.Region] {
+		canonical, ok := Regions().Canonical(f.Region)
+		if !ok {
 			return fmt.Errorf("invalid region: %s", f.Region)
 		}
+		f.Region = canonical
 	}
 
 	// Валидация пагинации
@@ -111,19 +102,6 @@ func (f *FilterParams) GetPeriodFromFilters() (time.Time, error) {
 	return time.Date(f.Year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
 }
 
-// RegionMapping маппинг регионов для API.
-var RegionMapping = map[string]string{
-	"all":        "all-russia",
-	"central":    "Central",
-	"north-west": "North West",
-	"volga":      "Volga",
-	"south":      "South",
-	"n-caucasus": "Kavkaz",
-	"ural":       "Ural",
-	"siberia":    "Siberia",
-	"far-east":   "Far East",
-}
-
 // QuarterMapping маппинг кварталов для API.
 var QuarterMapping = map[string]string{
 	"q1": "Q1",
@@ -134,8 +112,8 @@ var QuarterMapping = map[string]string{
 
 // GetMappedRegion возвращает маппированное значение региона.
 func (f *FilterParams) GetMappedRegion() string {
-	if mapped, exists := RegionMapping[f.Region]; exists {
-		return mapped
+	if canonical, ok := Regions().Canonical(f.Region); ok {
+		return canonical
 	}
 	return f.Region
 }
@@ -150,7 +128,7 @@ func (f *FilterParams) GetMappedQuarter() string {
 
 // HasRegionFilter проверяет, есть ли фильтр по региону.
 func (f *FilterParams) HasRegionFilter() bool {
-	return f.Region != "" && f.Region != "allAll"
+	return f.Region != "" && !IsAllRussia(f.Region)
 }
 
 // HasPeriodFilter проверяет, есть ли фильтр по периоду.
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// ImportKind тип загруженного набора данных.
+type ImportKind string
+
+const (
+	ImportKindDealerNet ImportKind = "dealer_net" // Показатели дилеров за квартал
+	ImportKindBrands    ImportKind = "brands"     // Бренды и побочные бизнесы к загруженному кварталу
+	ImportKindIngest    ImportKind = "ingest"     // Показатели дилеров, переданные через API
+)
+
+// HoldsQuarterData сообщает, пишет ли загрузка этого типа строки квартала в dealer_metrics:
+// файл dealer_net или пакет показателей через API. В квартале актуальна одна такая запись каталога.
+func (k ImportKind) HoldsQuarterData() bool {
+	return k == ImportKindDealerNet || k == ImportKindIngest
+}
+
+// QuarterDataKinds типы загрузок, которые пишут строки квартала в dealer_metrics.
+var QuarterDataKinds = []ImportKind{ImportKindDealerNet, ImportKindIngest}
+
+// ImportStatus статус загруженного набора данных.
+type ImportStatus string
+
+const (
+	ImportStatusCompleted  ImportStatus = "completed"  // Данные загружены и актуальны
+	ImportStatusFailed     ImportStatus = "failed"     // Загрузка отклонена, данные не сохранены
+	ImportStatusSuperseded ImportStatus = "superseded" // Квартал загружен повторно, данные заменены
+	ImportStatusDeleted    ImportStatus = "deleted"    // Данные в корзине, квартал можно восстановить
+	ImportStatusPurged     ImportStatus = "purged"     // Данные удалены окончательно
+)
+
+// ImportSchema структура загруженного файла.
+type ImportSchema struct {
+	Columns        []string             `json:"columns"`
+	ColumnReports  []ExcelColumnReport  `json:"column_reports,omitempty"`
+	FormulaReports []ExcelFormulaReport `json:"formula_reports,omitempty"`
+	ValueIssues    []ExcelValueIssue    `json:"value_issues,omitempty"`
+}
+
+// ImportDataset запись каталога загрузок.
+type ImportDataset struct {
+	ID         int64        `json:"id" db:"id"`
+	Kind       ImportKind   `json:"kind" db:"kind"`
+	Year       int          `json:"year" db:"year"`
+	Quarter    string       `json:"quarter" db:"quarter"`
+	SourceFile string       `json:"source_file" db:"source_file"`
+	RowCount   int          `json:"row_count" db:"row_count"`
+	ImportedBy string       `json:"imported_by,omitempty" db:"imported_by"`
+	Status     ImportStatus `json:"status" db:"status"`
+	Schema     ImportSchema `json:"schema" db:"schema"`
+	Error      string       `json:"error,omitempty" db:"error"`
+	DeletedAt  *time.Time   `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy  string       `json:"deleted_by,omitempty" db:"deleted_by"`
+	PurgeAt    *time.Time   `json:"purge_at,omitempty" db:"-"` // Когда корзина будет очищена; заполняет сервис
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// HasData сообщает, лежат ли строки набора в dealer_metrics.
+func (d *ImportDataset) HasData() bool {
+	return d.Kind.HoldsQuarterData() && d.Status == ImportStatusCompleted
+}
+
+// IsRestorable сообщает, лежит ли набор в корзине и может ли быть восстановлен.
+func (d *ImportDataset) IsRestorable() bool {
+	return d.Kind.HoldsQuarterData() && d.Status == ImportStatusDeleted && d.DeletedAt != nil
+}
+
+// ArchiveTableName таблица в схеме archive, куда перенесены строки удаленного набора.
+func (d *ImportDataset) ArchiveTableName() string {
+	return fmt.Sprintf("dealer_metrics_import_%d", d.ID)
+}
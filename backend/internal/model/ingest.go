@@ -0,0 +1,76 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MaxIngestRecords максимальное количество записей в одном запросе загрузки через API.
+const MaxIngestRecords = 10000
+
+// IngestRecordStatus результат обработки записи загрузки через API.
+type IngestRecordStatus string
+
+const (
+	IngestRecordCreated  IngestRecordStatus = "created"  // Дилера не было в квартале, строка добавлена
+	IngestRecordReplaced IngestRecordStatus = "replaced" // Строка дилера за квартал обновлена
+	IngestRecordRejected IngestRecordStatus = "rejected" // Запись не прошла проверку
+	IngestRecordSkipped  IngestRecordStatus = "skipped"  // Запись корректна, но пакет отклонен из-за других записей
+)
+
+// IngestStatus результат запроса загрузки через API.
+type IngestStatus string
+
+const (
+	IngestStatusCompleted IngestStatus = "completed" // Все записи сохранены
+	IngestStatusRejected  IngestStatus = "rejected"  // Есть ошибки в записях, ничего не сохранено
+)
+
+// IngestDealerMetricsRequest пакет показателей дилеров за кварталы из внешней системы.
+type IngestDealerMetricsRequest struct {
+	Records []IngestDealerMetricsRecord `json:"records"`
+}
+
+// IngestDealerMetricsRecord показатели одного дилера за квартал.
+// Ключи Metrics - колонки из словаря заголовков загрузки Excel (канонические названия или варианты заголовков).
+// Запись дополняет строку дилера за квартал: показатели, которых нет в Metrics или которые равны null,
+// сохраняют прежние значения.
+type IngestDealerMetricsRecord struct {
+	Year    int                        `json:"year"`
+	Quarter string                     `json:"quarter"`
+	Dealer  string                     `json:"dealer"`
+	City    string                     `json:"city"`
+	Region  string                     `json:"region"`
+	Metrics map[string]json.RawMessage `json:"metrics,omitempty"`
+}
+
+// IngestRecordResult результат обработки записи.
+type IngestRecordResult struct {
+	Index   int                `json:"index"` // Позиция записи в запросе (с 0)
+	Dealer  string             `json:"dealer,omitempty"`
+	Year    int                `json:"year,omitempty"`
+	Quarter string             `json:"quarter,omitempty"`
+	Status  IngestRecordStatus `json:"status"`
+	Errors  []string           `json:"errors,omitempty"`
+}
+
+// IngestDealerMetricsResponse отчет о загрузке через API.
+type IngestDealerMetricsResponse struct {
+	Status     IngestStatus         `json:"status"`
+	Created    int                  `json:"created"`
+	Replaced   int                  `json:"replaced"`
+	Rejected   int                  `json:"rejected"`
+	Records    []IngestRecordResult `json:"records"`
+	DatasetIDs []int64              `json:"dataset_ids,omitempty"` // Записи каталога загрузок по кварталам
+	Promotion  []*PromotionResult   `json:"promotion,omitempty"`   // Перенос кварталов в нормализованные таблицы
+	Replayed   bool                 `json:"-"`                     // Ответ повторен по Idempotency-Key
+}
+
+// IngestRequest сохраненный результат запроса с Idempotency-Key.
+type IngestRequest struct {
+	Owner          string                       `json:"owner" db:"owner"`
+	IdempotencyKey string                       `json:"idempotency_key" db:"idempotency_key"`
+	RequestHash    string                       `json:"request_hash" db:"request_hash"`
+	Response       *IngestDealerMetricsResponse `json:"response" db:"response"`
+	CreatedAt      time.Time                    `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// LoginMethod способ входа.
+type LoginMethod string
+
+const (
+	LoginMethodPassword  LoginMethod = "password"   // Логин и пароль (локальная учетная запись или LDAP)
+	LoginMethodTwoFactor LoginMethod = "two_factor" // Код второго фактора после верного пароля
+	LoginMethodOIDC      LoginMethod = "oidc"       // Корпоративный портал
+)
+
+// LoginResult итог попытки входа.
+type LoginResult string
+
+const (
+	LoginResultSuccess            LoginResult = "success"
+	LoginResultInvalidCredentials LoginResult = "invalid_credentials"
+	LoginResultInvalidCode        LoginResult = "invalid_code"
+	LoginResultThrottled          LoginResult = "throttled" // Отклонена без проверки пароля: слишком частые неудачи
+	LoginResultLocked             LoginResult = "locked"    // Отклонена без проверки пароля: учетная запись заблокирована
+	LoginResultNoAccess           LoginResult = "no_access" // Провайдер принял вход, но у пользователя нет доступа к платформе
+	LoginResultInactive           LoginResult = "inactive"  // Пароль верный, но учетная запись приостановлена или отключена
+	LoginResultError              LoginResult = "error"     // Провайдер недоступен или вернул ошибку
+)
+
+// LoginAttempt запись журнала попыток входа.
+type LoginAttempt struct {
+	ID        int64       `json:"id" db:"id"`
+	Login     string      `json:"login" db:"login"`
+	IP        string      `json:"ip,omitempty" db:"ip"`
+	Method    LoginMethod `json:"method" db:"method"`
+	Success   bool        `json:"success" db:"success"`
+	Result    LoginResult `json:"result" db:"result"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+}
+
+// LoginAttemptFilter фильтры журнала попыток входа. Пустые поля не участвуют в фильтрации.
+type LoginAttemptFilter struct {
+	Login   string
+	IP      string
+	Success *bool
+	Since   *time.Time
+	Limit   int
+}
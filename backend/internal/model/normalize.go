@@ -0,0 +1,309 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ValueKind тип значения колонки загрузки, по которому нормализуется ячейка.
+type ValueKind string
+
+const (
+	ValueKindText    ValueKind = "text"    // Хранится как есть
+	ValueKindNumber  ValueKind = "number"  // 1 234 567,89; 12,5 млн; $1.2m
+	ValueKindPercent ValueKind = "percent" // 85 %; 87,6%
+	ValueKindBool    ValueKind = "bool"    // Y/N, Yes/No, Да/Нет, +/-
+	ValueKindDate    ValueKind = "date"    // 31.12.2025, 2025-12-31, 12/31/2025, 31 декабря 2025
+)
+
+// ErrUnrecognizedValue значение ячейки не удалось разобрать.
+var ErrUnrecognizedValue = errors.New("unrecognized value")
+
+// ErrAmbiguousNumber в числе одна запятая перед тремя цифрами: 12,500 - это 12500 в английской записи
+// и 12,5 в русской. Такое значение не угадывается, а попадает в отчет загрузки.
+var ErrAmbiguousNumber = fmt.Errorf("%w: ambiguous thousands separator", ErrUnrecognizedValue)
+
+// ambiguousThousands число с одной запятой, которую можно прочитать и как разряды, и как десятичную.
+// Ведущий ноль (0,125) однозначно указывает на десятичную запятую.
+var ambiguousThousands = regexp.MustCompile(`^[1-9][0-9]{0,2},[0-9]{3}$`)
+
+// YesNo флаг в ответах API. Варианты записи во входных данных разбирает ParseYesNo.
+type YesNo string
+
+const (
+	Yes YesNo = "Yes"
+	No  YesNo = "No"
+)
+
+// YesNoOf возвращает флаг для логического значения.
+func YesNoOf(value bool) YesNo {
+	if value {
+		return Yes
+	}
+	return No
+}
+
+// NormalizeValue приводит значение ячейки к каноническому виду для хранения:
+// числа и проценты - десятичная точка без разрядов, флаги - Yes/No, даты - ГГГГ-ММ-ДД.
+func NormalizeValue(kind ValueKind, value string) (string, error) {
+	switch kind {
+	case ValueKindNumber:
+		number, err := ParseNumber(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case ValueKindPercent:
+		percent, err := ParsePercent(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(percent, 'f', -1, 64), nil
+	case ValueKindBool:
+		flag, err := ParseYesNo(value)
+		if err != nil {
+			return "", err
+		}
+		return string(YesNoOf(flag)), nil
+	case ValueKindDate:
+		date, err := ParseDate(value)
+		if err != nil {
+			return "", err
+		}
+		return date.Format(time.DateOnly), nil
+	}
+	return value, nil
+}
+
+// numberScales множители единиц: тыс., млн, млрд и английские k, m, bn.
+var numberScales = []struct {
+	suffixes []string
+	factor   float64
+}{
+	{[]string{"тыс.", "тыс", "thousands", "thousand", "k"}, 1e3},
+	{[]string{"млн.", "млн", "millions", "million", "mln", "mio", "m"}, 1e6},
+	{[]string{"млрд.", "млрд", "billions", "billion", "bn", "b"}, 1e9},
+}
+
+// currencySigns обозначения валют, которые отбрасываются.
+var currencySigns = []string{"руб.", "руб", "р.", "rub", "₽", "usd", "$", "eur", "€"}
+
+// ParseNumber разбирает число в русской или английской записи:
+// пробелы и апострофы в разрядах, десятичная запятая или точка, единицы (тыс., млн, k, m), знак валюты,
+// минус или скобки для отрицательных значений. Знак процента не допускается - для него есть ParsePercent.
+// Запись вида 12,500 неоднозначна и возвращает ErrAmbiguousNumber.
+func ParseNumber(value string) (float64, error) {
+	return parseNumber(value, true)
+}
+
+// parseNumber разбирает число; strict запрещает неоднозначную запятую в разрядах.
+func parseNumber(value string, strict bool) (float64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	if s == "" || strings.Contains(s, "%") {
+		return 0, fmt.Errorf("%q: %w", value, ErrUnrecognizedValue)
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, strings.TrimSpace(s[1:len(s)-1])
+	}
+
+	// Единицы и валюта могут идти в любом порядке: "12,5 млн руб.", "$1.2m"
+	factor := 1.0
+	for changed := true; changed; {
+		changed = false
+		for _, sign := range currencySigns {
+			if trimmed, ok := trimAffix(s, sign); ok {
+				s, changed = trimmed, true
+			}
+		}
+		if factor == 1 {
+			for _, scale := range numberScales {
+				for _, suffix := range scale.suffixes {
+					if trimmed, ok := strings.CutSuffix(s, suffix); ok && trimmed != "" && !isLetter(trimmed[len(trimmed)-1]) {
+						s, factor, changed = strings.TrimSpace(trimmed), scale.factor, true
+						break
+					}
+				}
+				if factor != 1 {
+					break
+				}
+			}
+		}
+	}
+
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		negative, s = !negative, rest
+	} else if rest, ok := strings.CutPrefix(s, "\u2212"); ok {
+		negative, s = !negative, rest
+	}
+
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "\u2009", "", "'", "", "\u2019", "").Replace(s)
+
+	if strict && ambiguousThousands.MatchString(s) {
+		return 0, fmt.Errorf("%q: %w", value, ErrAmbiguousNumber)
+	}
+
+	number, err := parseDecimal(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q: %w", value, ErrUnrecognizedValue)
+	}
+	if negative {
+		number = -number
+	}
+	return number * factor, nil
+}
+
+// ParsePercent разбирает процент: "85 %", "87,6%" и "85" дают 85.
+// Разрядов в процентах не бывает, поэтому одиночная запятая всегда десятичная: "12,125%" - 12.125.
+func ParsePercent(value string) (float64, error) {
+	s := strings.TrimSpace(value)
+	s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	number, err := parseNumber(s, false)
+	if err != nil {
+		return 0, fmt.Errorf("%q: %w", value, ErrUnrecognizedValue)
+	}
+	return number, nil
+}
+
+// parseDecimal разбирает число без пробелов, выбирая десятичный разделитель:
+// при запятой и точке десятичный - последний из них, повторяющийся разделитель - разряды,
+// одиночная запятая - десятичная, как в русских шаблонах (неоднозначные 12,500 отсекает ParseNumber).
+func parseDecimal(s string) (float64, error) {
+	commas, dots := strings.Count(s, ","), strings.Count(s, ".")
+	switch {
+	case commas > 0 && dots > 0:
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case commas > 1:
+		s = strings.ReplaceAll(s, ",", "")
+	case commas == 1:
+		s = strings.Replace(s, ",", ".", 1)
+	case dots > 1:
+		s = strings.ReplaceAll(s, ".", "")
+	}
+
+	if s == "" || strings.Count(s, ".") > 1 {
+		return 0, ErrUnrecognizedValue
+	}
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && s[i] != '.' {
+			return 0, ErrUnrecognizedValue
+		}
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(number, 0) {
+		return 0, ErrUnrecognizedValue
+	}
+	return number, nil
+}
+
+// trimAffix отрезает обозначение в начале или в конце строки.
+func trimAffix(s, affix string) (string, bool) {
+	if rest, ok := strings.CutSuffix(s, affix); ok {
+		return strings.TrimSpace(rest), true
+	}
+	if rest, ok := strings.CutPrefix(s, affix); ok {
+		return strings.TrimSpace(rest), true
+	}
+	return s, false
+}
+
+// isLetter проверяет, что байт - латинская буква; суффикс "m" не отрезается от слова.
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// ParseYesNo разбирает флаг: Y/N, Yes/No, Да/Нет, Д/Н, +/-, true/false, 1/0.
+func ParseYesNo(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "y", "yes", "да", "д", "+", "true", "1", "есть", "✓", "✔":
+		return true, nil
+	case "n", "no", "нет", "н", "-", "–", "—", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q: %w", value, ErrUnrecognizedValue)
+}
+
+// dateLayouts форматы дат в шаблонах. Дата с точками - русская (день первым),
+// с косой чертой - английская (месяц первым), с дефисами и двузначным годом - формат Excel по умолчанию.
+var dateLayouts = []string{
+	time.DateOnly,
+	time.DateTime,
+	"2006-01-02T15:04:05",
+	"02.01.2006",
+	"2.1.2006",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+	"02.01.06",
+	"1/2/2006",
+	"01/02/2006",
+	"1/2/06",
+	"01-02-06",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"02-Jan-06",
+	"2-Jan-2006",
+}
+
+// ruMonths русские названия месяцев в родительном падеже и сокращения.
+var ruMonths = strings.NewReplacer(
+	"января", "January", "февраля", "February", "марта", "March", "апреля", "April",
+	"мая", "May", "июня", "June", "июля", "July", "августа", "August",
+	"сентября", "September", "октября", "October", "ноября", "November", "декабря", "December",
+	"янв", "Jan", "фев", "Feb", "мар", "Mar", "апр", "Apr", "май", "May", "июн", "Jun",
+	"июл", "Jul", "авг", "Aug", "сен", "Sep", "окт", "Oct", "ноя", "Nov", "дек", "Dec",
+)
+
+// excelEpoch начало отсчета дат Excel: дата хранится числом дней с 30.12.1899.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ParseDate разбирает дату в русской или английской записи либо серийный номер даты Excel.
+func ParseDate(value string) (time.Time, error) {
+	s := strings.TrimSpace(value)
+	s = strings.TrimSuffix(strings.TrimSuffix(s, " г."), " г")
+	s = ruMonths.Replace(strings.ToLower(s))
+	s = strings.TrimSuffix(s, ".")
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, titleMonth(s)); err == nil {
+			return date, nil
+		}
+	}
+
+	// Английская дата с днем больше 12 записана как день/месяц/год
+	if date, err := time.Parse("2/1/2006", s); err == nil {
+		return date, nil
+	}
+
+	// Серийный номер даты Excel (1955-2119)
+	if serial, err := strconv.ParseFloat(s, 64); err == nil && serial >= 20000 && serial < 80000 {
+		return excelEpoch.AddDate(0, 0, int(serial)), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q: %w", value, ErrUnrecognizedValue)
+}
+
+// titleMonth возвращает строку с заглавной первой буквой месяца, как ожидает time.Parse.
+func titleMonth(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if r >= 'a' && r <= 'z' && (i == 0 || !unicode.IsLetter(runes[i-1])) {
+			runes[i] = r - 'a' + 'A'
+		}
+	}
+	return string(runes)
+}
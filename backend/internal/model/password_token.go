@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// PasswordTokenPurpose назначение ссылки для установки пароля.
+type PasswordTokenPurpose string
+
+const (
+	PasswordTokenInvite PasswordTokenPurpose = "invite" // Приглашение: новый пользователь задает первый пароль
+	PasswordTokenReset  PasswordTokenPurpose = "reset"  // Сброс забытого пароля
+)
+
+// PasswordToken одноразовая ссылка для установки пароля. Хранится только хэш токена.
+type PasswordToken struct {
+	ID        int64                `json:"-" db:"id"`
+	UserID    int64                `json:"-" db:"user_id"`
+	Login     string               `json:"login" db:"login"`
+	Purpose   PasswordTokenPurpose `json:"purpose" db:"purpose"`
+	TokenHash string               `json:"-" db:"token_hash"`
+	CreatedBy string               `json:"-" db:"created_by"`
+	CreatedAt time.Time            `json:"-" db:"created_at"`
+	ExpiresAt time.Time            `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time           `json:"-" db:"used_at"`
+}
+
+// Active сообщает, можно ли воспользоваться ссылкой в момент now.
+func (t *PasswordToken) Active(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// ForgotPasswordRequest запрос ссылки для сброса пароля по логину или почте.
+type ForgotPasswordRequest struct {
+	Login string `json:"login"`
+}
+
+// PasswordResetRequest установка пароля по ссылке из письма.
+type PasswordResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
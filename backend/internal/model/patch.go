@@ -0,0 +1,226 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrEmptyPatch в изменениях нет ни одного поля.
+var ErrEmptyPatch = errors.New("no fields to update")
+
+// PatchFieldType тип значения изменяемого поля.
+type PatchFieldType int
+
+const (
+	PatchInt PatchFieldType = iota
+	PatchFloat
+	PatchString
+	PatchBool
+)
+
+// String возвращает название типа для сообщений об ошибках.
+func (t PatchFieldType) String() string {
+	switch t {
+	case PatchInt:
+		return "an integer"
+	case PatchFloat:
+		return "a number"
+	case PatchString:
+		return "a string"
+	case PatchBool:
+		return "a boolean"
+	}
+	return "unknown"
+}
+
+// PatchField описание поля, которое разрешено изменять частичным обновлением.
+type PatchField struct {
+	Column     string         // Колонка в БД (если пусто, совпадает с JSON-названием)
+	Type       PatchFieldType // Тип значения
+	Nullable   bool           // Разрешено значение null
+	Min        *float64       // Минимальное значение для чисел
+	Max        *float64       // Максимальное значение для чисел
+	Enum       []string       // Допустимые значения для строк
+	AllowEmpty bool           // Пустая строка допустима, даже если задан Enum
+}
+
+// PatchSchema набор изменяемых полей сущности.
+type PatchSchema struct {
+	Fields   map[string]PatchField // JSON-название -> описание поля
+	ReadOnly []string              // Поля сущности, которые нельзя менять частичным обновлением
+}
+
+// Patch проверенные по PatchSchema частичные изменения сущности.
+// Создается только через PatchSchema.Decode, поэтому в SQL попадают лишь разрешенные колонки.
+type Patch struct {
+	fields  map[string]interface{}
+	columns map[string]interface{}
+}
+
+// PatchBound возвращает указатель на границу значения поля.
+func PatchBound(value float64) *float64 {
+	return &value
+}
+
+// recordKeyFields поля ключа записи показателей дилера, общие для всех сущностей.
+var recordKeyFields = []string{"id", "dealer_id", "quarter", "year", "version", "created_at", "updated_at"}
+
+// Decode разбирает тело запроса в формате JSON Merge Patch (RFC 7396) и проверяет его по схеме.
+// null означает сброс значения поля. Неизвестные и запрещенные поля возвращаются как ошибки валидации.
+func (s PatchSchema) Decode(data []byte) (Patch, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return Patch{}, fmt.Errorf("PatchSchema.Decode: body must be a JSON object: %w", err)
+	}
+
+	return s.Validate(raw)
+}
+
+// Validate проверяет уже разобранные изменения по схеме.
+func (s PatchSchema) Validate(raw map[string]interface{}) (Patch, error) {
+	if len(raw) == 0 {
+		return Patch{}, ErrEmptyPatch
+	}
+
+	readOnly := make(map[string]bool, len(s.ReadOnly))
+	for _, name := range s.ReadOnly {
+		readOnly[name] = true
+	}
+
+	// Сортируем названия, чтобы порядок ошибок не зависел от порядка обхода map
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs ValidationErrors
+	patch := Patch{
+		fields:  make(map[string]interface{}, len(raw)),
+		columns: make(map[string]interface{}, len(raw)),
+	}
+
+	for _, name := range names {
+		if readOnly[name] {
+			errs.Add(name, "cannot be changed")
+			continue
+		}
+
+		field, ok := s.Fields[name]
+		if !ok {
+			errs.Add(name, "unknown field")
+			continue
+		}
+
+		value, err := field.convert(raw[name])
+		if err != nil {
+			errs.Add(name, "%s", err.Error())
+			continue
+		}
+
+		column := field.Column
+		if column == "" {
+			column = name
+		}
+		patch.fields[name] = value
+		patch.columns[column] = value
+	}
+
+	if err := errs.Err(); err != nil {
+		return Patch{}, err
+	}
+
+	return patch, nil
+}
+
+// convert приводит значение из JSON к типу поля и проверяет ограничения.
+func (f PatchField) convert(value interface{}) (interface{}, error) {
+	if value == nil {
+		if !f.Nullable {
+			return nil, errors.New("cannot be null")
+		}
+		return nil, nil
+	}
+
+	switch f.Type {
+	case PatchInt, PatchFloat:
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("must be %s", f.Type)
+		}
+		n, err := number.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("must be %s", f.Type)
+		}
+		if f.Type == PatchInt && n != math.Trunc(n) {
+			return nil, fmt.Errorf("must be %s", f.Type)
+		}
+		if f.Min != nil && n < *f.Min {
+			return nil, fmt.Errorf("must be at least %v", *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return nil, fmt.Errorf("must be at most %v", *f.Max)
+		}
+		if f.Type == PatchInt {
+			return int64(n), nil
+		}
+		return n, nil
+
+	case PatchString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be %s", f.Type)
+		}
+		if len(f.Enum) > 0 && !(str == "" && f.AllowEmpty) {
+			for _, allowed := range f.Enum {
+				if str == allowed {
+					return str, nil
+				}
+			}
+			return nil, fmt.Errorf("must be one of %v", f.Enum)
+		}
+		return str, nil
+
+	case PatchBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be %s", f.Type)
+		}
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("unsupported field type %d", f.Type)
+}
+
+// IsEmpty проверяет, что в изменениях нет полей.
+func (p Patch) IsEmpty() bool {
+	return len(p.columns) == 0
+}
+
+// Columns возвращает изменения в виде колонка -> значение для UPDATE.
+func (p Patch) Columns() map[string]interface{} {
+	columns := make(map[string]interface{}, len(p.columns)+1)
+	for column, value := range p.columns {
+		columns[column] = value
+	}
+	return columns
+}
+
+// Apply накладывает изменения на структуру сущности по ее JSON-тегам.
+func (p Patch) Apply(dst interface{}) error {
+	data, err := json.Marshal(p.fields)
+	if err != nil {
+		return fmt.Errorf("Patch.Apply: %w", err)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("Patch.Apply: %w", err)
+	}
+	return nil
+}
@@ -19,10 +19,24 @@ type PerformanceSales struct {
 	SalesMarginPct    *float64 `json:"sales_margin_pct" db:"sales_margin_pct"`         // Маржа % = (margin / revenue) * 100
 	SalesProfitPct    *float64 `json:"sales_profit_pct" db:"sales_profit_pct"`         // Рентабельность %
 
+	Version int `json:"version" db:"version"` // Версия записи для оптимистичной блокировки
+
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// PerformanceSalesPatchSchema поля PerformanceSales, которые можно менять частичным обновлением.
+// quantity_sold, sales_revenue_no_vat и sales_cost не хранятся в таблице performance.
+var PerformanceSalesPatchSchema = PatchSchema{
+	Fields: map[string]PatchField{
+		"sales_revenue":    {Column: "sales_revenue_rub", Type: PatchFloat, Nullable: true, Min: PatchBound(0)},
+		"sales_margin":     {Column: "sales_profit_rub", Type: PatchFloat, Nullable: true, Min: PatchBound(0)},
+		"sales_margin_pct": {Column: "sales_margin_percent", Type: PatchFloat, Nullable: true, Min: PatchBound(-100), Max: PatchBound(100)},
+		"sales_profit_pct": {Column: "sales_profit_percent", Type: PatchFloat, Nullable: true, Min: PatchBound(-100), Max: PatchBound(100)},
+	},
+	ReadOnly: recordKeyFields,
+}
+
 // PerformanceAfterSales отвечает за блок Performance AfterSales (финансовая производительность запчастей).
 // Содержит информацию о выручке, прибыли и марже по продажам запчастей.
 type PerformanceAfterSales struct {
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Периодо-независимые колонки dealer_net.
+const (
+	ColumnSparePartsSalesQuarter = "spare_parts_sales_quarter" // Продажи запчастей за квартал загрузки
+	ColumnSparePartsSalesYtdPct  = "spare_parts_sales_ytd_pct" // Динамика продаж запчастей YTD, %
+)
+
+// periodColumnExportNames названия колонок в шаблоне: %s заменяется на квартал (q1..q4).
+var periodColumnExportNames = map[string]string{
+	ColumnSparePartsSalesQuarter: "spare_parts_sales_%s",
+	ColumnSparePartsSalesYtdPct:  "spare_parts_sales_ytd_percent",
+}
+
+// PeriodColumnName возвращает название колонки в терминах квартала шаблона (обратное преобразование для экспорта).
+// Для колонок, не зависящих от периода, возвращает исходное название.
+func PeriodColumnName(column string, quarter string) string {
+	pattern, ok := periodColumnExportNames[column]
+	if !ok {
+		return column
+	}
+	if !strings.Contains(pattern, "%s") {
+		return pattern
+	}
+	return fmt.Sprintf(pattern, strings.ToLower(quarter))
+}
+
+// PeriodColumnNames возвращает названия колонок для выгрузки квартала в шаблоне загрузки:
+// периодо-независимые колонки получают названия квартала, остальные остаются как есть.
+func PeriodColumnNames(columns []string, quarter string) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = PeriodColumnName(column, quarter)
+	}
+	return names
+}
@@ -0,0 +1,32 @@
+package model
+
+// DealerNetRecord показатели дилера из одной строки dealer_net, разложенные по нормализованным таблицам.
+// Ключ периода (квартал и год) и DealerID заполняются при сохранении.
+type DealerNetRecord struct {
+	Dealer            Dealer
+	Sales             Sales
+	DealerDevelopment DealerDevelopment
+	AfterSales        AfterSales
+
+	// Brands и Businesses равны nil, если в строке нет данных о портфеле;
+	// в этом случае сохраненные бренды и бизнесы дилера не меняются.
+	Brands     []string
+	Businesses []string
+}
+
+// PromotionResult итог переноса таблицы dealer_net в нормализованные таблицы.
+type PromotionResult struct {
+	Year            int      `json:"year"`
+	Quarter         string   `json:"quarter"`
+	RowsRead        int      `json:"rows_read"`
+	DealersMatched  int      `json:"dealers_matched"`
+	DealersCreated  int      `json:"dealers_created"`
+	RecordsUpserted int      `json:"records_upserted"` // Строк, записанных в sales, dealer_dev и after_sales
+	SkippedRows     []string `json:"skipped_rows,omitempty"`
+}
+
+// PromotionPeriod квартал загрузки dealer_net, ожидающий переноса в нормализованные таблицы.
+type PromotionPeriod struct {
+	Year    int
+	Quarter string
+}
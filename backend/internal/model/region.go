@@ -1,8 +1,139 @@
 package model
 
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// AllRussia значение фильтра по всем регионам.
+const AllRussia = "all-russia"
+
 // Region представляет регион России.
 type Region struct {
-	ID   int64  `json:"id" db:"id"`
-	Code string `json:"code" db:"code"` // all-russia, center, north-west, volga и т.д.
-	Name string `json:"name" db:"name"` // All Russia, Center, North West, Volga и т.д.
+	ID      int64    `json:"id" db:"id"`
+	Code    string   `json:"code" db:"code"`       // CENTRAL, NORTH_WEST, VOLGA и т.д.
+	Name    string   `json:"name" db:"name"`       // Central, North West, Volga и т.д. - значение, которое хранится в данных
+	NameRu  string   `json:"name_ru" db:"name_ru"` // Центр, Северо-Запад, Поволжье и т.д.
+	Aliases []string `json:"aliases"`              // Альтернативные написания в названиях листов и параметрах запросов
+}
+
+// RegionAlias представляет альтернативное написание региона.
+type RegionAlias struct {
+	ID        int64     `json:"id" db:"id"`
+	RegionID  int64     `json:"region_id" db:"region_id"`
+	Alias     string    `json:"alias" db:"alias"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// RegionAliasRequest запрос на добавление алиаса региона.
+type RegionAliasRequest struct {
+	Alias string `json:"alias"`
+}
+
+// RegionRegistry справочник регионов с поиском по коду, названиям и алиасам.
+type RegionRegistry struct {
+	regions []Region
+	index   map[string]int
+}
+
+// NewRegionRegistry создает справочник из списка регионов.
+func NewRegionRegistry(regions []Region) *RegionRegistry {
+	r := &RegionRegistry{
+		regions: regions,
+		index:   make(map[string]int, len(regions)*4),
+	}
+
+	for i, region := range regions {
+		keys := append([]string{region.Code, region.Name, region.NameRu}, region.Aliases...)
+		for _, key := range keys {
+			if key = NormalizeRegionKey(key); key != "" {
+				r.index[key] = i
+			}
+		}
+	}
+
+	return r
+}
+
+// NormalizeRegionKey приводит написание региона к ключу поиска.
+func NormalizeRegionKey(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	value = strings.NewReplacer("-", " ", "_", " ", "ё", "е").Replace(value)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// Resolve ищет регион по коду, названию или алиасу без учета регистра.
+func (r *RegionRegistry) Resolve(value string) (*Region, bool) {
+	i, ok := r.index[NormalizeRegionKey(value)]
+	if !ok {
+		return nil, false
+	}
+	region := r.regions[i]
+	return &region, true
+}
+
+// Canonical возвращает хранимое название региона или AllRussia.
+func (r *RegionRegistry) Canonical(value string) (string, bool) {
+	if IsAllRussia(value) {
+		return AllRussia, true
+	}
+	region, ok := r.Resolve(value)
+	if !ok {
+		return "", false
+	}
+	return region.Name, true
+}
+
+// IsValid проверяет, что значение является регионом или AllRussia.
+func (r *RegionRegistry) IsValid(value string) bool {
+	_, ok := r.Canonical(value)
+	return ok
+}
+
+// List возвращает копию списка регионов.
+func (r *RegionRegistry) List() []Region {
+	regions := make([]Region, len(r.regions))
+	copy(regions, r.regions)
+	return regions
+}
+
+// IsAllRussia проверяет, что значение означает все регионы.
+func IsAllRussia(value string) bool {
+	switch NormalizeRegionKey(value) {
+	case "all russia", "all", "вся россия":
+		return true
+	}
+	return false
+}
+
+// DefaultRegions встроенный список регионов, совпадающий с миграциями.
+// Используется до загрузки справочника из БД.
+func DefaultRegions() []Region {
+	return []Region{
+		{Code: "CENTRAL", Name: "Central", NameRu: "Центр", Aliases: []string{"Center", "Центральный"}},
+		{Code: "NORTH_WEST", Name: "North West", NameRu: "Северо-Запад", Aliases: []string{"NW"}},
+		{Code: "VOLGA", Name: "Volga", NameRu: "Поволжье", Aliases: []string{"Волга"}},
+		{Code: "SOUTH", Name: "South", NameRu: "Юг"},
+		{Code: "URAL", Name: "Ural", NameRu: "Урал"},
+		{Code: "SIBERIA", Name: "Siberia", NameRu: "Сибирь"},
+		{Code: "FAR_EAST", Name: "Far East", NameRu: "Дальний Восток", Aliases: []string{"FE"}},
+		{Code: "CAUCASUS", Name: "Caucasus", NameRu: "Кавказ", Aliases: []string{"Kavkaz", "N-Caucasus", "Северный Кавказ"}},
+	}
+}
+
+var regionRegistry atomic.Pointer[RegionRegistry]
+
+func init() {
+	regionRegistry.Store(NewRegionRegistry(DefaultRegions()))
+}
+
+// Regions возвращает текущий справочник регионов.
+func Regions() *RegionRegistry {
+	return regionRegistry.Load()
+}
+
+// SetRegionRegistry заменяет текущий справочник регионов.
+func SetRegionRegistry(registry *RegionRegistry) {
+	regionRegistry.Store(registry)
 }
@@ -2,14 +2,12 @@ package model
 
 import "time"
 
-// SalesTrainingsStatus представляет статус тренингов по продажам.
-type SalesTrainingsStatus string
+// SalesTrainingsStatus статус тренингов по продажам: Yes или No.
+type SalesTrainingsStatus = YesNo
 
 const (
-	SalesTrainingsYes SalesTrainingsStatus = "Yes"
-	SalesTrainingsNo  SalesTrainingsStatus = "No"
-	SalesTrainingsY   SalesTrainingsStatus = "Y"
-	SalesTrainingsN   SalesTrainingsStatus = "N"
+	SalesTrainingsYes = Yes
+	SalesTrainingsNo  = No
 )
 
 // Sales отвечает за блок Dealer Sales.
@@ -45,6 +43,8 @@ type Sales struct {
 	// Recommendation (из Excel)
 	SalesDecision string `json:"sales_decision" db:"sales_decision"` // Sales decision
 
+	Version int `json:"version" db:"version"` // Версия записи для оптимистичной блокировки
+
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -60,3 +60,21 @@ type SalesWithDetails struct {
 	Manager      string `json:"manager"`
 	Ruft         string `json:"ruft"`
 }
+
+// SalesPatchSchema поля Sales, которые можно менять частичным обновлением.
+var SalesPatchSchema = PatchSchema{
+	Fields: map[string]PatchField{
+		"sales_target":            {Type: PatchString},
+		"stock_hdt":               {Type: PatchInt, Min: PatchBound(0)},
+		"stock_mdt":               {Type: PatchInt, Min: PatchBound(0)},
+		"stock_ldt":               {Type: PatchInt, Min: PatchBound(0)},
+		"buyout_hdt":              {Type: PatchInt, Min: PatchBound(0)},
+		"buyout_mdt":              {Type: PatchInt, Min: PatchBound(0)},
+		"buyout_ldt":              {Type: PatchInt, Min: PatchBound(0)},
+		"foton_salesmen":          {Type: PatchInt, Min: PatchBound(0)},
+		"service_contracts_sales": {Type: PatchInt, Min: PatchBound(0)},
+		"sales_trainings":         {Type: PatchBool},
+		"sales_decision":          {Type: PatchString, Enum: Decisions, AllowEmpty: true},
+	},
+	ReadOnly: recordKeyFields,
+}
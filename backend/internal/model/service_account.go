@@ -0,0 +1,134 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrInvalidAPIKey ключ не найден, не совпал, отозван или истек. Причина не раскрывается клиенту.
+	ErrInvalidAPIKey = errors.New("invalid API key")
+
+	// ErrAPIKeyIPNotAllowed вход по ключу с адреса, которого нет в списке разрешенных.
+	ErrAPIKeyIPNotAllowed = errors.New("IP address is not allowed for this API key")
+)
+
+// APIKeyPrefix префикс ключей API: по нему ключ отличается от JWT в заголовке Authorization.
+const APIKeyPrefix = "ddp_"
+
+// APIKeyScope область доступа ключа API. Ключ проходит только на маршруты, которым нужна выданная ему область.
+type APIKeyScope string
+
+const (
+	APIKeyScopeRead         APIKeyScope = "read"          // Чтение данных дилеров и показателей
+	APIKeyScopeWrite        APIKeyScope = "write"         // Изменение показателей дилеров в пределах роли учетной записи
+	APIKeyScopeIngestWrite  APIKeyScope = "ingest:write"  // Загрузка показателей через /api/ingest/dealer-metrics
+	APIKeyScopeImportsRead  APIKeyScope = "imports:read"  // Каталог загрузок, данные и выгрузка кварталов
+	APIKeyScopeImportsWrite APIKeyScope = "imports:write" // Загрузка файлов, удаление, восстановление и перенос кварталов
+)
+
+// APIKeyScopes области, которые можно выдать ключу.
+var APIKeyScopes = []APIKeyScope{
+	APIKeyScopeRead,
+	APIKeyScopeWrite,
+	APIKeyScopeIngestWrite,
+	APIKeyScopeImportsRead,
+	APIKeyScopeImportsWrite,
+}
+
+// IsValid сообщает, известна ли область.
+func (s APIKeyScope) IsValid() bool {
+	return slices.Contains(APIKeyScopes, s)
+}
+
+// RequiresAdmin сообщает, открывает ли область административные маршруты:
+// такие области выдаются только ключам учетных записей администраторов.
+func (s APIKeyScope) RequiresAdmin() bool {
+	switch s {
+	case APIKeyScopeIngestWrite, APIKeyScopeImportsRead, APIKeyScopeImportsWrite:
+		return true
+	default:
+		return false
+	}
+}
+
+// ServiceAccount учетная запись интеграции (загрузка в BI, обновление отчетов Power BI).
+// Входит в систему только по ключам API, пароля у нее нет.
+type ServiceAccount struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description,omitempty" db:"description"`
+	IsAdmin     bool      `json:"is_admin" db:"is_admin"` // Ключам можно выдавать административные области
+	Role        UserRole  `json:"role" db:"role"`
+	CreatedBy   string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ServiceAccountCreateRequest запрос на создание сервисной учетной записи.
+type ServiceAccountCreateRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	IsAdmin     bool     `json:"is_admin"`
+	Role        UserRole `json:"role"`
+}
+
+// APIKey ключ API сервисной учетной записи. Сам ключ не хранится, только его SHA-256.
+type APIKey struct {
+	ID               int64         `json:"id" db:"id"`
+	ServiceAccountID int64         `json:"service_account_id" db:"service_account_id"`
+	Name             string        `json:"name" db:"name"`
+	Prefix           string        `json:"prefix" db:"prefix"` // Открытая часть ключа, по которой он ищется и виден в аудите
+	Hash             string        `json:"-" db:"key_hash"`
+	AllowedIPs       []string      `json:"allowed_ips,omitempty" db:"allowed_ips"` // Адреса и подсети; пустой список - без ограничений
+	Scopes           []APIKeyScope `json:"scopes" db:"scopes"`
+	ExpiresAt        *time.Time    `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt       *time.Time    `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP       string        `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	RevokedAt        *time.Time    `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy        string        `json:"revoked_by,omitempty" db:"revoked_by"`
+	CreatedBy        string        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+}
+
+// HasScope сообщает, выдана ли ключу область.
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// IsActive сообщает, можно ли войти по ключу в момент now.
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyCreateRequest запрос на выпуск ключа API.
+type APIKeyCreateRequest struct {
+	Name       string        `json:"name"`
+	Scopes     []APIKeyScope `json:"scopes"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty"`
+	AllowedIPs []string      `json:"allowed_ips,omitempty"`
+}
+
+// CreatedAPIKey выпущенный ключ: значение ключа показывается только один раз.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyPrincipal сервисная учетная запись, вошедшая по ключу API.
+type APIKeyPrincipal struct {
+	Account *ServiceAccount
+	Key     *APIKey
+}
+
+// Actor возвращает исполнителя для аудита (imported_by, deleted_by):
+// по нему видно, что действие выполнил ключ, а не человек.
+func (p *APIKeyPrincipal) Actor() string {
+	return APIKeyActor(p.Account.Name, p.Key.Prefix)
+}
+
+// APIKeyActor исполнитель действия по ключу API: svc:<учетная запись>/<префикс ключа>.
+func APIKeyActor(account, prefix string) string {
+	return "svc:" + account + "/" + prefix
+}
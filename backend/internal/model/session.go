@@ -0,0 +1,34 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSessionRevoked токен отозван, истек или выдан без сессии.
+var ErrSessionRevoked = errors.New("session is revoked or expired")
+
+// Session сессия пользователя: один выданный JWT. Отозванная сессия больше не принимается,
+// даже если срок действия токена еще не истек.
+type Session struct {
+	ID         int64      `json:"id" db:"id"`
+	JTI        string     `json:"-" db:"jti"` // ID токена (claim jti)
+	UserID     int64      `json:"-" db:"user_id"`
+	IP         string     `json:"ip,omitempty" db:"ip"`
+	UserAgent  string     `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"-" db:"revoked_at"`
+	Current    bool       `json:"current"` // Сессия, из которой пришел запрос
+
+	// Состояние учетной записи владельца: токен приостановленного или отключенного пользователя не принимается.
+	UserStatus      UserStatus `json:"-"`
+	UserStatusFrom  *time.Time `json:"-"`
+	UserStatusUntil *time.Time `json:"-"`
+}
+
+// Active сообщает, принимается ли сессия в момент now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
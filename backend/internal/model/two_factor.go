@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// TOTP второй фактор пользователя (RFC 6238). Секрет хранится открыто: он нужен для проверки кодов.
+type TOTP struct {
+	UserID       int64      `db:"user_id"`
+	Secret       string     `db:"secret"`     // base32 без выравнивания
+	EnabledAt    *time.Time `db:"enabled_at"` // nil - подключение начато, но не подтверждено кодом
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// Enabled сообщает, подтверждено ли подключение второго фактора.
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// TwoFactorStatus состояние второго фактора пользователя.
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	Required          bool       `json:"required"` // Политика требует второй фактор, отключить его нельзя
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollment данные для подключения приложения-аутентификатора.
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`           // Для ручного ввода в приложение
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth://totp/...
+	QRCode          string `json:"qr_code"`          // PNG с provisioning URI в виде data URL
+}
+
+// AuthPolicy политика входа, которую задают администраторы.
+type AuthPolicy struct {
+	RequireAdmin2FA bool      `json:"require_admin_2fa" db:"require_admin_2fa"` // Администраторы входят только со вторым фактором
+	UpdatedBy       string    `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AuthPolicyUpdateRequest запрос на изменение политики входа.
+type AuthPolicyUpdateRequest struct {
+	RequireAdmin2FA bool `json:"require_admin_2fa"`
+}
@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // UserRole представляет роль пользователя в системе.
 type UserRole string
@@ -12,33 +15,109 @@ const (
 	UserRoleViewer  UserRole = "viewer"  // Пользователь только для просмотра
 )
 
+// AuthSource источник учетной записи пользователя.
+type AuthSource string
+
+const (
+	AuthSourceLocal AuthSource = "local" // Пароль хранит платформа
+	AuthSourceLDAP  AuthSource = "ldap"  // Вход через LDAP/Active Directory, роль и регион берутся из групп каталога
+	AuthSourceOIDC  AuthSource = "oidc"  // Вход через корпоративный портал (OpenID Connect), роль и регион берутся из claims
+)
+
+// UserStatus состояние учетной записи.
+type UserStatus string
+
+const (
+	UserStatusActive      UserStatus = "active"      // Вход разрешен
+	UserStatusSuspended   UserStatus = "suspended"   // Временно приостановлена, например на время отпуска или проверки
+	UserStatusDeactivated UserStatus = "deactivated" // Отключена вместо удаления: созданные пользователем записи остаются
+)
+
+// ErrAccountInactive учетная запись приостановлена или отключена: вход и токены не принимаются.
+var ErrAccountInactive = errors.New("account is suspended or deactivated")
+
+// Valid сообщает, известно ли состояние.
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserStatusActive, UserStatusSuspended, UserStatusDeactivated:
+		return true
+	}
+	return false
+}
+
+// EffectiveUserStatus состояние учетной записи в момент now: приостановка и отключение действуют
+// с from до until, nil - без ограничения. Вне этого интервала учетная запись активна.
+func EffectiveUserStatus(status UserStatus, from, until *time.Time, now time.Time) UserStatus {
+	if status == "" || status == UserStatusActive {
+		return UserStatusActive
+	}
+	if from != nil && now.Before(*from) {
+		return UserStatusActive
+	}
+	if until != nil && !now.Before(*until) {
+		return UserStatusActive
+	}
+	return status
+}
+
 // User структура пользователя системы.
 // Содержит информацию для аутентификации и авторизации.
 type User struct {
-	ID        int64     `json:"id" db:"id"`
-	Login     string    `json:"login" db:"login"`
-	Password  string    `json:"password,omitempty" db:"password"` // omitempty для исключения из JSON ответов
-	IsAdmin   bool      `json:"is_admin" db:"is_admin"`
-	Role      UserRole  `json:"role" db:"role"`     // manager, sales, admin, viewer
-	Region    string    `json:"region" db:"region"` // Регион, за который отвечает пользователь
-	FirstName string    `json:"first_name" db:"first_name"`
-	LastName  string    `json:"last_name" db:"last_name"`
-	Email     string    `json:"email" db:"email"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID          int64      `json:"id" db:"id"`
+	Login       string     `json:"login" db:"login"`
+	Password    string     `json:"password,omitempty" db:"password"` // omitempty для исключения из JSON ответов
+	IsAdmin     bool       `json:"is_admin" db:"is_admin"`
+	Role        UserRole   `json:"role" db:"role"`     // manager, sales, admin, viewer
+	Region      string     `json:"region" db:"region"` // Регион, за который отвечает пользователь
+	FirstName   string     `json:"first_name" db:"first_name"`
+	LastName    string     `json:"last_name" db:"last_name"`
+	Email       string     `json:"email" db:"email"`
+	AuthSource  AuthSource `json:"auth_source" db:"auth_source"`             // local, ldap или oidc
+	ExternalID  string     `json:"external_id,omitempty" db:"external_id"`   // DN в каталоге LDAP или subject провайдера OIDC
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"` // Вход заблокирован после неудачных попыток
+	Version     int        `json:"version" db:"version"`                     // Версия записи для оптимистичной блокировки
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+
+	Status          UserStatus `json:"status" db:"status"` // Сохраненное состояние; действующее возвращает StatusAt
+	StatusReason    string     `json:"status_reason,omitempty" db:"status_reason"`
+	StatusFrom      *time.Time `json:"status_from,omitempty" db:"status_from"`   // Начало приостановки или отключения
+	StatusUntil     *time.Time `json:"status_until,omitempty" db:"status_until"` // Окончание приостановки
+	StatusChangedBy string     `json:"status_changed_by,omitempty" db:"status_changed_by"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty" db:"status_changed_at"`
+}
+
+// StatusAt возвращает состояние учетной записи в момент now.
+func (u *User) StatusAt(now time.Time) UserStatus {
+	return EffectiveUserStatus(u.Status, u.StatusFrom, u.StatusUntil, now)
 }
 
 // UserResponse представляет данные пользователя для API ответов.
 type UserResponse struct {
-	ID        int64     `json:"id"`
-	Login     string    `json:"login"`
-	IsAdmin   bool      `json:"is_admin"`
-	Role      UserRole  `json:"role"`
-	Region    string    `json:"region"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int64      `json:"id"`
+	Login       string     `json:"login"`
+	IsAdmin     bool       `json:"is_admin"`
+	Role        UserRole   `json:"role"`
+	Region      string     `json:"region"`
+	FirstName   string     `json:"first_name"`
+	LastName    string     `json:"last_name"`
+	Email       string     `json:"email"`
+	AuthSource  AuthSource `json:"auth_source"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	Version     int        `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	Status          UserStatus `json:"status"` // Сохраненное состояние; действующее возвращает StatusAt
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusFrom      *time.Time `json:"status_from,omitempty"`
+	StatusUntil     *time.Time `json:"status_until,omitempty"`
+	StatusChangedBy string     `json:"status_changed_by,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+}
+
+// StatusAt возвращает состояние учетной записи в момент now.
+func (u *UserResponse) StatusAt(now time.Time) UserStatus {
+	return EffectiveUserStatus(u.Status, u.StatusFrom, u.StatusUntil, now)
 }
 
 // UserFilter представляет фильтры для поиска пользователей.
@@ -53,6 +132,9 @@ type UserFilter struct {
 	Region    *string   `json:"region,omitempty"`
 	FirstName *string   `json:"first_name,omitempty"`
 	LastName  *string   `json:"last_name,omitempty"`
+
+	// Statuses действующие на момент запроса состояния учетной записи; пусто - любые.
+	Statuses []UserStatus `json:"statuses,omitempty"`
 }
 
 // UserUpdate представляет поля для обновления пользователя.
@@ -66,6 +148,10 @@ type UserUpdate struct {
 	FirstName *string   `json:"first_name,omitempty"`
 	LastName  *string   `json:"last_name,omitempty"`
 	Email     *string   `json:"email,omitempty"`
+
+	// ExpectedVersion версия, на основе которой клиент сделал изменения (If-Match).
+	// Если задана и не совпадает с текущей, обновление не выполняется.
+	ExpectedVersion *int `json:"-"`
 }
 
 // UserCreateRequest представляет запрос на создание нового пользователя.
@@ -79,3 +165,28 @@ type UserCreateRequest struct {
 	LastName  string   `json:"last_name,omitempty"`
 	Email     string   `json:"email,omitempty"`
 }
+
+// UserStatusChange смена состояния учетной записи администратором.
+// При возврате в active причина необязательна, а даты не задаются.
+type UserStatusChange struct {
+	Status UserStatus `json:"status"`
+	Reason string     `json:"reason,omitempty"`
+	From   *time.Time `json:"effective_from,omitempty"`  // nil - сразу
+	Until  *time.Time `json:"effective_until,omitempty"` // Только для приостановки; nil - до возврата администратором
+
+	ChangedBy       string `json:"-"`
+	ExpectedVersion *int   `json:"-"` // Версия из If-Match; nil - без проверки
+}
+
+// ProfileUpdateRequest изменение собственного профиля. Роль, регион и права меняет только администратор.
+type ProfileUpdateRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Email     *string `json:"email,omitempty"`
+}
+
+// PasswordChangeRequest смена собственного пароля.
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError ошибка валидации отдельного поля.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors список ошибок валидации по полям.
+type ValidationErrors []FieldError
+
+// Add добавляет ошибку поля.
+func (v *ValidationErrors) Add(field, format string, args ...interface{}) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err возвращает ошибку, если в списке есть хотя бы одна ошибка поля, иначе nil.
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// Error реализует интерфейс error.
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldErr := range v {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// DealerPeriodKey ключ записи показателей дилера за квартал.
+type DealerPeriodKey struct {
+	DealerID int    `json:"dealer_id"`
+	Quarter  string `json:"quarter"` // Q1, Q2, Q3, Q4
+	Year     int    `json:"year"`
+}
+
+// Decisions допустимые значения решений по дилеру (sales_decision, as_decision, dealer_dev_recommendation).
+var Decisions = []string{"Planned Result", "Needs Development", "Find New Candidate", "Close Down"}
+
+// IsValidDecision проверяет, что решение входит в список Decisions.
+func IsValidDecision(decision string) bool {
+	for _, valid := range Decisions {
+		if decision == valid {
+			return true
+		}
+	}
+	return false
+}
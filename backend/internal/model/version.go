@@ -0,0 +1,15 @@
+package model
+
+import "errors"
+
+// ErrVersionConflict запись была изменена после того, как клиент ее прочитал.
+var ErrVersionConflict = errors.New("version conflict")
+
+// CheckVersion сверяет версию, с которой клиент начал редактирование, с текущей версией записи.
+// Версия 0 означает изменение без проверки версии.
+func CheckVersion(expected, current int) error {
+	if expected > 0 && expected != current {
+		return ErrVersionConflict
+	}
+	return nil
+}
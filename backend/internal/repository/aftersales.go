@@ -38,6 +38,7 @@ func (r *AfterSalesRepository) Create(ctx context.Context, as *model.AfterSales)
 			"recommended_stock", "warranty_stock", "foton_labor_hours",
 			"foton_warranty_hours", "service_contracts", "as_trainings",
 			"csi", "as_decision",
+			"spare_parts_sales_quarter", "spare_parts_sales_ytd_pct", "foton_labour_hours_share",
 			"created_at", "updated_at",
 		).
 		Values(
@@ -45,9 +46,10 @@ func (r *AfterSalesRepository) Create(ctx context.Context, as *model.AfterSales)
 			as.RecommendedStock, as.WarrantyStock, as.FotonLaborHours,
 			as.FotonWarrantyHours, as.ServiceContracts, as.ASTrainings,
 			as.CSI, as.ASDecision,
+			as.SparePartsSalesQuarter, as.SparePartsSalesYtdPct, as.FotonLabourHoursShare,
 			as.CreatedAt, as.UpdatedAt,
 		).
-		Suffix("RETURNING id")
+		Suffix("RETURNING id, version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -55,9 +57,9 @@ func (r *AfterSalesRepository) Create(ctx context.Context, as *model.AfterSales)
 	}
 
 	var id int64
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id, &as.Version)
 	if err != nil {
-		return 0, fmt.Errorf("AfterSalesRepository.Create: error inserting: %w", err)
+		return 0, fmt.Errorf("AfterSalesRepository.Create: error inserting: %w", classifyError(err))
 	}
 
 	as.ID = int(id)
@@ -71,7 +73,8 @@ func (r *AfterSalesRepository) GetByID(ctx context.Context, id int64) (*model.Af
 		"recommended_stock", "warranty_stock", "foton_labor_hours",
 		"foton_warranty_hours", "service_contracts", "as_trainings",
 		"csi", "as_decision",
-		"created_at", "updated_at",
+		"spare_parts_sales_quarter", "spare_parts_sales_ytd_pct", "foton_labour_hours_share",
+		"version", "created_at", "updated_at",
 	).From(afterSalesTableName).Where(squirrel.Eq{"id": id})
 
 	sql, args, err := query.ToSql()
@@ -85,10 +88,11 @@ func (r *AfterSalesRepository) GetByID(ctx context.Context, id int64) (*model.Af
 		&as.RecommendedStock, &as.WarrantyStock, &as.FotonLaborHours,
 		&as.FotonWarrantyHours, &as.ServiceContracts, &as.ASTrainings,
 		&as.CSI, &as.ASDecision,
-		&as.CreatedAt, &as.UpdatedAt,
+		&as.SparePartsSalesQuarter, &as.SparePartsSalesYtdPct, &as.FotonLabourHoursShare,
+		&as.Version, &as.CreatedAt, &as.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("AfterSalesRepository.GetByID: error scanning: %w", err)
+		return nil, fmt.Errorf("AfterSalesRepository.GetByID: error scanning: %w", classifyError(err))
 	}
 
 	return as, nil
@@ -121,7 +125,7 @@ func (r *AfterSalesRepository) GetByDealerAndPeriodTime(ctx context.Context, dea
 		&as.CreatedAt, &as.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("AfterSalesRepository.GetByDealerAndPeriod: error scanning: %w", err)
+		return nil, fmt.Errorf("AfterSalesRepository.GetByDealerAndPeriod: error scanning: %w", classifyError(err))
 	}
 
 	return as, nil
@@ -168,6 +172,7 @@ func (r *AfterSalesRepository) GetAllByPeriodTime(ctx context.Context, period ti
 }
 
 // UpdateFull обновляет всю запись послепродажного обслуживания целиком.
+// Если версия записи задана, обновление выполняется только при совпадении с текущей версией в БД.
 func (r *AfterSalesRepository) UpdateFull(ctx context.Context, as *model.AfterSales) error {
 	as.UpdatedAt = time.Now()
 
@@ -183,17 +188,23 @@ func (r *AfterSalesRepository) UpdateFull(ctx context.Context, as *model.AfterSa
 		Set("as_trainings", as.ASTrainings).
 		Set("csi", as.CSI).
 		Set("as_decision", as.ASDecision).
+		Set("spare_parts_sales_quarter", as.SparePartsSalesQuarter).
+		Set("spare_parts_sales_ytd_pct", as.SparePartsSalesYtdPct).
+		Set("foton_labour_hours_share", as.FotonLabourHoursShare).
 		Set("updated_at", as.UpdatedAt).
-		Where(squirrel.Eq{"id": as.ID})
+		Set("version", nextVersion).
+		Where(versionCondition(as.ID, as.Version)).
+		Suffix("RETURNING version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("AfterSalesRepository.UpdateFull: error building query: %w", err)
 	}
 
-	_, err = r.pool.Exec(ctx, sql, args...)
+	version := as.Version
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&as.Version)
 	if err != nil {
-		return fmt.Errorf("AfterSalesRepository.UpdateFull: error updating: %w", err)
+		return fmt.Errorf("AfterSalesRepository.UpdateFull: record with id %d: %w", as.ID, versionedWriteError(err, version))
 	}
 
 	return nil
@@ -207,14 +218,15 @@ func (r *AfterSalesRepository) GetWithDetailsByPeriod(ctx context.Context, quart
 		"aftersales.recommended_stock", "aftersales.warranty_stock", "aftersales.foton_labor_hours",
 		"aftersales.foton_warranty_hours", "aftersales.service_contracts", "aftersales.as_trainings",
 		"aftersales.csi", "aftersales.as_decision",
-		"aftersales.created_at", "aftersales.updated_at",
+		"aftersales.spare_parts_sales_quarter", "aftersales.spare_parts_sales_ytd_pct", "aftersales.foton_labour_hours_share",
+		"aftersales.version", "aftersales.created_at", "aftersales.updated_at",
 		"d.name", "d.city", "d.region", "d.manager",
 	).
 		From(afterSalesTableName + " aftersales").
 		Join("dealers d ON aftersales.dealer_id = d.id").
 		Where(squirrel.Eq{"aftersales.quarter": quarter, "aftersales.year": year})
 
-	if region != "all-russia" {
+	if region != "" && !model.IsAllRussia(region) {
 		queryBuilder = queryBuilder.Where(squirrel.Eq{"d.region": region})
 	}
 
@@ -239,7 +251,8 @@ func (r *AfterSalesRepository) GetWithDetailsByPeriod(ctx context.Context, quart
 			&aswd.RecommendedStock, &aswd.WarrantyStock, &aswd.FotonLaborHours,
 			&aswd.FotonWarrantyHours, &aswd.ServiceContracts, &aswd.ASTrainings,
 			&aswd.CSI, &aswd.ASDecision,
-			&aswd.CreatedAt, &aswd.UpdatedAt,
+			&aswd.SparePartsSalesQuarter, &aswd.SparePartsSalesYtdPct, &aswd.FotonLabourHoursShare,
+			&aswd.Version, &aswd.CreatedAt, &aswd.UpdatedAt,
 			&aswd.DealerNameRu, &aswd.City, &aswd.Region, &aswd.Manager,
 		)
 		if err != nil {
@@ -252,8 +265,8 @@ func (r *AfterSalesRepository) GetWithDetailsByPeriod(ctx context.Context, quart
 }
 
 // Delete удаляет запись послепродажного обслуживания.
-func (r *AfterSalesRepository) Delete(ctx context.Context, id int64) error {
-	query := r.sq.Delete(afterSalesTableName).Where(squirrel.Eq{"id": id})
+func (r *AfterSalesRepository) Delete(ctx context.Context, id int64, version int) error {
+	query := r.sq.Delete(afterSalesTableName).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -262,24 +275,26 @@ func (r *AfterSalesRepository) Delete(ctx context.Context, id int64) error {
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("AfterSalesRepository.Delete: error deleting: %w", err)
+		return fmt.Errorf("AfterSalesRepository.Delete: error deleting: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("AfterSalesRepository.Delete: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("AfterSalesRepository.Delete: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
 }
 
-// Update обновляет данные послепродажного обслуживания.
-func (r *AfterSalesRepository) Update(ctx context.Context, id int64, updates map[string]interface{}) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+// Update обновляет данные послепродажного обслуживания по проверенному набору изменений.
+func (r *AfterSalesRepository) Update(ctx context.Context, id int64, version int, patch model.Patch) error {
+	if patch.IsEmpty() {
+		return fmt.Errorf("AfterSalesRepository.Update: %w", model.ErrEmptyPatch)
 	}
 
+	updates := patch.Columns()
 	updates["updated_at"] = time.Now()
-	query := r.sq.Update(afterSalesTableName).SetMap(updates).Where(squirrel.Eq{"id": id})
+	updates["version"] = nextVersion
+	query := r.sq.Update(afterSalesTableName).SetMap(updates).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -288,35 +303,50 @@ func (r *AfterSalesRepository) Update(ctx context.Context, id int64, updates map
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("AfterSalesRepository.Update: error updating: %w", err)
+		return fmt.Errorf("AfterSalesRepository.Update: error updating: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("AfterSalesRepository.Update: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("AfterSalesRepository.Update: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
 }
 
-// GetByDealerAndPeriod получает запись послепродажного обслуживания по дилеру и периоду (с кварталом и годом).
+// GetByDealerAndPeriod получает запись послепродажного обслуживания по дилеру, кварталу и году.
 func (r *AfterSalesRepository) GetByDealerAndPeriod(ctx context.Context, dealerID int64, quarter string, year int) (*model.AfterSales, error) {
-	// Преобразуем quarter/year в period
-	var month int
-	switch quarter {
-	case "q1":
-		month = 1
-	case "q2":
-		month = 4
-	case "q3":
-		month = 7
-	case "q4":
-		month = 10
-	default:
-		return nil, fmt.Errorf("invalid quarter: %s", quarter)
+	query := r.sq.Select(
+		"id", "dealer_id", "quarter", "year",
+		"recommended_stock", "warranty_stock", "foton_labor_hours",
+		"foton_warranty_hours", "service_contracts", "as_trainings",
+		"csi", "as_decision",
+		"spare_parts_sales_quarter", "spare_parts_sales_ytd_pct", "foton_labour_hours_share",
+		"version", "created_at", "updated_at",
+	).From(afterSalesTableName).Where(squirrel.Eq{
+		"dealer_id": dealerID,
+		"quarter":   quarter,
+		"year":      year,
+	})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("AfterSalesRepository.GetByDealerAndPeriod: error building query: %w", err)
 	}
-	period := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 
-	return r.GetByDealerAndPeriodTime(ctx, int(dealerID), period)
+	as := &model.AfterSales{}
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&as.ID, &as.DealerID, &as.Quarter, &as.Year,
+		&as.RecommendedStock, &as.WarrantyStock, &as.FotonLaborHours,
+		&as.FotonWarrantyHours, &as.ServiceContracts, &as.ASTrainings,
+		&as.CSI, &as.ASDecision,
+		&as.SparePartsSalesQuarter, &as.SparePartsSalesYtdPct, &as.FotonLabourHoursShare,
+		&as.Version, &as.CreatedAt, &as.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("AfterSalesRepository.GetByDealerAndPeriod: error scanning: %w", classifyError(err))
+	}
+
+	return as, nil
 }
 
 // GetAllByPeriod получает все записи послепродажного обслуживания за указанный период (с кварталом и годом).
@@ -347,7 +377,8 @@ func (r *AfterSalesRepository) GetWithFilters(ctx context.Context, filters *mode
 		"aftersales.recommended_stock", "aftersales.warranty_stock", "aftersales.foton_labor_hours",
 		"aftersales.foton_warranty_hours", "aftersales.service_contracts", "aftersales.as_trainings",
 		"aftersales.csi", "aftersales.as_decision",
-		"aftersales.created_at", "aftersales.updated_at",
+		"aftersales.spare_parts_sales_quarter", "aftersales.spare_parts_sales_ytd_pct", "aftersales.foton_labour_hours_share",
+		"aftersales.version", "aftersales.created_at", "aftersales.updated_at",
 		"d.name", "d.city", "d.region", "d.manager",
 	).
 		From(afterSalesTableName + " aftersales").
@@ -407,7 +438,8 @@ func (r *AfterSalesRepository) GetWithFilters(ctx context.Context, filters *mode
 			&aswd.RecommendedStock, &aswd.WarrantyStock, &aswd.FotonLaborHours,
 			&aswd.FotonWarrantyHours, &aswd.ServiceContracts, &aswd.ASTrainings,
 			&aswd.CSI, &aswd.ASDecision,
-			&aswd.CreatedAt, &aswd.UpdatedAt,
+			&aswd.SparePartsSalesQuarter, &aswd.SparePartsSalesYtdPct, &aswd.FotonLabourHoursShare,
+			&aswd.Version, &aswd.CreatedAt, &aswd.UpdatedAt,
 			&aswd.DealerNameRu, &aswd.City, &aswd.Region, &aswd.Manager,
 		)
 		if err != nil {
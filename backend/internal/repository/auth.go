@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/typefunco/dealer_dev_platform/internal/model"
 )
@@ -64,7 +67,8 @@ func (repo *AuthRepository) DeleteUser(ctx context.Context, login string) error
 
 func (repo *AuthRepository) GetUser(ctx context.Context, login string) (*model.User, error) {
 	var user model.User
-	query := repo.sq.Select("id", "login", "password", "is_admin", "role", "region", "first_name", "last_name", "email", "created_at", "updated_at").
+	query := repo.sq.Select("id", "login", "password", "is_admin", "role", "region", "first_name", "last_name", "email", "auth_source", "locked_until", "created_at", "updated_at",
+		"status", "status_from", "status_until").
 		From(usersTableName).
 		Where(squirrel.Eq{"login": login})
 
@@ -80,7 +84,8 @@ func (repo *AuthRepository) GetUser(ctx context.Context, login string) (*model.U
 	defer rows.Close()
 
 	if rows.Next() {
-		err := rows.Scan(&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.Region, &user.FirstName, &user.LastName, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.Region, &user.FirstName, &user.LastName, &user.Email, &user.AuthSource, &user.LockedUntil, &user.CreatedAt, &user.UpdatedAt,
+			&user.Status, &user.StatusFrom, &user.StatusUntil)
 		if err != nil {
 			repo.logger.Error("AuthRepository.GetUser error parse sql")
 			return nil, fmt.Errorf("AuthRepository.GetUser error parse sql: %w", err)
@@ -91,6 +96,184 @@ func (repo *AuthRepository) GetUser(ctx context.Context, login string) (*model.U
 	return nil, nil
 }
 
+// GetUserByEmail возвращает пользователя с адресом почты email без учета регистра.
+// Если такого пользователя нет или адрес указан у нескольких пользователей, возвращается nil.
+func (repo *AuthRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	query := repo.sq.Select("id", "login", "password", "is_admin", "role", "COALESCE(first_name, '')", "COALESCE(last_name, '')", "COALESCE(email, '')", "auth_source",
+		"status", "status_from", "status_until").
+		From(usersTableName).
+		Where(squirrel.Expr("LOWER(email) = LOWER(?)", email)).
+		Limit(2)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("AuthRepository.GetUserByEmail error creating query: %w", err)
+	}
+
+	rows, err := repo.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("AuthRepository.GetUserByEmail error exec query: %w", err)
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.FirstName, &user.LastName, &user.Email, &user.AuthSource,
+			&user.Status, &user.StatusFrom, &user.StatusUntil); err != nil {
+			return nil, fmt.Errorf("AuthRepository.GetUserByEmail error parse sql: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("AuthRepository.GetUserByEmail error iterating rows: %w", err)
+	}
+
+	if len(users) != 1 {
+		return nil, nil
+	}
+	return &users[0], nil
+}
+
+// UpsertExternalUser создает или обновляет пользователя внешнего провайдера (LDAP, OIDC) при входе.
+// Роль, регион и контакты перезаписываются данными провайдера. Внешние провайдеры описывают
+// одних и тех же сотрудников, поэтому запись переходит к тому, через кого был последний вход.
+// Локальную учетную запись с тем же логином метод не трогает и возвращает ErrAlreadyExists.
+func (repo *AuthRepository) UpsertExternalUser(ctx context.Context, user model.User) (*model.User, error) {
+	query := repo.sq.Insert(usersTableName).
+		Columns("login", "password", "is_admin", "role", "region", "first_name", "last_name", "email", "auth_source", "external_id", "created_at", "updated_at").
+		Values(user.Login, "", user.IsAdmin, user.Role, user.Region, user.FirstName, user.LastName, user.Email, user.AuthSource, user.ExternalID, squirrel.Expr("NOW()"), squirrel.Expr("NOW()")).
+		Suffix(`ON CONFLICT (login) DO UPDATE SET
+			is_admin = EXCLUDED.is_admin,
+			role = EXCLUDED.role,
+			region = EXCLUDED.region,
+			first_name = EXCLUDED.first_name,
+			last_name = EXCLUDED.last_name,
+			email = EXCLUDED.email,
+			auth_source = EXCLUDED.auth_source,
+			external_id = EXCLUDED.external_id,
+			version = users.version + 1,
+			updated_at = NOW()
+		WHERE users.auth_source <> 'local'
+		RETURNING id, login, is_admin, role, region, first_name, last_name, email, auth_source, external_id, version, created_at, updated_at,
+			status, status_from, status_until`)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("AuthRepository.UpsertExternalUser error creating query: %w", err)
+	}
+
+	var stored model.User
+	err = repo.pool.QueryRow(ctx, sql, args...).Scan(&stored.ID, &stored.Login, &stored.IsAdmin, &stored.Role, &stored.Region,
+		&stored.FirstName, &stored.LastName, &stored.Email, &stored.AuthSource, &stored.ExternalID, &stored.Version, &stored.CreatedAt, &stored.UpdatedAt,
+		&stored.Status, &stored.StatusFrom, &stored.StatusUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("AuthRepository.UpsertExternalUser: login %q belongs to another account: %w", user.Login, ErrAlreadyExists)
+		}
+		return nil, fmt.Errorf("AuthRepository.UpsertExternalUser error exec query: %w", err)
+	}
+
+	return &stored, nil
+}
+
+// UpdatePassword меняет пароль (bcrypt-хэш) локальной учетной записи. Если пользователя нет, возвращается ErrNotFound.
+func (repo *AuthRepository) UpdatePassword(ctx context.Context, login, password string) error {
+	query := repo.sq.Update(usersTableName).
+		Set("password", password).
+		Set("version", squirrel.Expr("version + 1")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"login": login, "auth_source": model.AuthSourceLocal})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("AuthRepository.UpdatePassword error creating query: %w", err)
+	}
+
+	tag, err := repo.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("AuthRepository.UpdatePassword error exec query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("AuthRepository.UpdatePassword: user %q: %w", login, ErrNotFound)
+	}
+
+	return nil
+}
+
+// RegisterLoginFailure увеличивает счетчик неудачных входов подряд. Когда счетчик достигает
+// maxFailures, вход блокируется до lockUntil. Счет после истекшей блокировки начинается заново.
+// Возвращает время окончания блокировки или nil, если пользователя с таким логином нет.
+func (repo *AuthRepository) RegisterLoginFailure(ctx context.Context, login string, maxFailures int, now, lockUntil time.Time) (*time.Time, error) {
+	// Значения справа в SET берутся из строки до обновления
+	failures := squirrel.Expr("CASE WHEN locked_until <= ? THEN 1 ELSE failed_login_count + 1 END", now)
+	query := repo.sq.Update(usersTableName).
+		Set("failed_login_count", failures).
+		Set("locked_until", squirrel.Expr(
+			"CASE WHEN (CASE WHEN locked_until <= ? THEN 1 ELSE failed_login_count + 1 END) >= ? THEN ?::timestamp WHEN locked_until <= ? THEN NULL ELSE locked_until END",
+			now, maxFailures, lockUntil, now,
+		)).
+		Where(squirrel.Eq{"login": login}).
+		Suffix("RETURNING locked_until")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("AuthRepository.RegisterLoginFailure error creating query: %w", err)
+	}
+
+	var lockedUntil *time.Time
+	err = repo.pool.QueryRow(ctx, sql, args...).Scan(&lockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("AuthRepository.RegisterLoginFailure error exec query: %w", err)
+	}
+
+	return lockedUntil, nil
+}
+
+// ResetLoginFailures обнуляет счетчик неудачных входов после успешного входа.
+func (repo *AuthRepository) ResetLoginFailures(ctx context.Context, login string) error {
+	query := repo.sq.Update(usersTableName).
+		Set("failed_login_count", 0).
+		Set("locked_until", nil).
+		Where(squirrel.Eq{"login": login}).
+		Where(squirrel.Or{squirrel.Gt{"failed_login_count": 0}, squirrel.NotEq{"locked_until": nil}})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("AuthRepository.ResetLoginFailures error creating query: %w", err)
+	}
+
+	if _, err := repo.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("AuthRepository.ResetLoginFailures error exec query: %w", err)
+	}
+
+	return nil
+}
+
+// UnlockUser снимает блокировку входа и обнуляет счетчик неудачных входов. Возвращает логин пользователя.
+func (repo *AuthRepository) UnlockUser(ctx context.Context, id int64) (string, error) {
+	query := repo.sq.Update(usersTableName).
+		Set("failed_login_count", 0).
+		Set("locked_until", nil).
+		Where(squirrel.Eq{"id": id}).
+		Suffix("RETURNING login")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", fmt.Errorf("AuthRepository.UnlockUser error creating query: %w", err)
+	}
+
+	var login string
+	if err := repo.pool.QueryRow(ctx, sql, args...).Scan(&login); err != nil {
+		return "", fmt.Errorf("AuthRepository.UnlockUser error exec query: %w", classifyError(err))
+	}
+
+	return login, nil
+}
+
 func (repo *AuthRepository) Ping(ctx context.Context) error {
 	return repo.pool.Ping(ctx)
 }
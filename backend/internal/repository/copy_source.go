@@ -0,0 +1,41 @@
+package repository
+
+// CopyProgressStep количество строк между вызовами колбэка прогресса при COPY.
+const CopyProgressStep = 1000
+
+// InsertProgressFunc колбэк прогресса вставки: inserted - записано строк, total - всего строк (0, если неизвестно).
+type InsertProgressFunc func(inserted, total int)
+
+// progressCopySource источник строк для pgx.CopyFrom с отчетом о прогрессе.
+type progressCopySource struct {
+	rows     [][]interface{}
+	idx      int
+	progress InsertProgressFunc
+}
+
+// newProgressCopySource создает источник строк для COPY.
+func newProgressCopySource(rows [][]interface{}, progress InsertProgressFunc) *progressCopySource {
+	return &progressCopySource{
+		rows:     rows,
+		progress: progress,
+	}
+}
+
+// Next переходит к следующей строке и периодически сообщает о прогрессе.
+func (s *progressCopySource) Next() bool {
+	if s.progress != nil && s.idx > 0 && s.idx%CopyProgressStep == 0 {
+		s.progress(s.idx, len(s.rows))
+	}
+	s.idx++
+	return s.idx <= len(s.rows)
+}
+
+// Values возвращает значения текущей строки.
+func (s *progressCopySource) Values() ([]any, error) {
+	return s.rows[s.idx-1], nil
+}
+
+// Err возвращает ошибку источника.
+func (s *progressCopySource) Err() error {
+	return nil
+}
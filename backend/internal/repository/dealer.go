@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -54,7 +56,7 @@ func (r *DealerRepository) Create(ctx context.Context, dealer *model.Dealer) (in
 
 // GetByID получает дилера по ID.
 func (r *DealerRepository) GetByID(ctx context.Context, id int) (*model.Dealer, error) {
-	query := r.sq.Select("id", "name", "city", "region", "manager", "created_at", "updated_at").
+	query := r.sq.Select("id", "name", "city", "region", "manager", "joint_decision", "version", "created_at", "updated_at").
 		From(dealerTableName).
 		Where(squirrel.Eq{"id": id})
 
@@ -66,10 +68,10 @@ func (r *DealerRepository) GetByID(ctx context.Context, id int) (*model.Dealer,
 	dealer := &model.Dealer{}
 	err = r.pool.QueryRow(ctx, sql, args...).Scan(
 		&dealer.DealerID, &dealer.DealerNameRu, &dealer.City, &dealer.Region, &dealer.Manager,
-		&dealer.CreatedAt, &dealer.UpdatedAt,
+		&dealer.JointDecision, &dealer.Version, &dealer.CreatedAt, &dealer.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("DealerRepository.GetByID: error scanning: %w", err)
+		return nil, fmt.Errorf("DealerRepository.GetByID: dealer with id %d: %w", id, classifyError(err))
 	}
 
 	return dealer, nil
@@ -156,6 +158,17 @@ func (r *DealerRepository) GetWithFilters(ctx context.Context, filters *model.Fi
 		query = query.Where(squirrel.Eq{"id": filters.DealerIDs})
 	}
 
+	// Период: только дилеры, у которых есть показатели за квартал
+	if filters.HasPeriodFilter() {
+		quarter, year := filters.GetMappedQuarter(), filters.Year
+		query = query.Where(squirrel.Expr(
+			"id IN (SELECT dealer_id FROM "+salesTableName+" WHERE quarter = ? AND year = ?"+
+				" UNION SELECT dealer_id FROM "+dealerDevTableName+" WHERE quarter = ? AND year = ?"+
+				" UNION SELECT dealer_id FROM "+afterSalesTableName+" WHERE quarter = ? AND year = ?)",
+			quarter, year, quarter, year, quarter, year,
+		))
+	}
+
 	// Сортировка
 	if filters.SortBy != "" {
 		order := "ASC"
@@ -281,10 +294,7 @@ func (r *DealerRepository) GetDealerCardData(ctx context.Context, dealerID int,
 		cardData.CheckListScore = &checklistScore
 		class := model.DealershipClass(ddData.DealershipClass)
 		cardData.DealershipClass = &class
-		branding := model.BrandingStatus("No")
-		if ddData.Branding {
-			branding = model.BrandingStatus("Yes")
-		}
+		branding := model.YesNoOf(ddData.Branding)
 		cardData.Branding = &branding
 		marketingInvestments := float64(ddData.MarketingInvestments)
 		cardData.MarketingInvestments = &marketingInvestments
@@ -320,10 +330,7 @@ func (r *DealerRepository) GetDealerCardData(ctx context.Context, dealerID int,
 		cardData.BuyoutMDT = &salesData.BuyoutMDT
 		cardData.BuyoutLDT = &salesData.BuyoutLDT
 		cardData.FotonSalesPersonnel = &salesData.FotonSalesmen
-		salesTrainings := model.SalesTrainingsStatus("No")
-		if salesData.SalesTrainings {
-			salesTrainings = model.SalesTrainingsStatus("Yes")
-		}
+		salesTrainings := model.YesNoOf(salesData.SalesTrainings)
 		cardData.SalesTrainings = &salesTrainings
 		serviceContractsSales := float64(salesData.ServiceContractsSales)
 		cardData.ServiceContractsSales = &serviceContractsSales
@@ -344,11 +351,36 @@ func (r *DealerRepository) GetDealerCardData(ctx context.Context, dealerID int,
 		cardData.ASMarginPct = &asMarginPct
 		asProfitPct := float64(asData.RecommendedStock)
 		cardData.ASProfitPct = &asProfitPct
+
+		recommendedStock := float64(asData.RecommendedStock)
+		cardData.RecommendedStockPct = &recommendedStock
+		warrantyStock := float64(asData.WarrantyStock)
+		cardData.WarrantyStockPct = &warrantyStock
+		laborHours := float64(asData.FotonLaborHours)
+		cardData.FotonLaborHoursPct = &laborHours
+		warrantyHours := float64(asData.FotonWarrantyHours)
+		cardData.WarrantyHours = &warrantyHours
+		serviceContractsHours := float64(asData.ServiceContracts)
+		cardData.ServiceContractsHours = &serviceContractsHours
+		asTrainings := model.YesNoOf(asData.ASTrainings)
+		cardData.ASTrainings = &asTrainings
+		cardData.SparePartsSalesQ = parseOptionalFloat(asData.SparePartsSalesQuarter)
+		cardData.SparePartsSalesYtdPct = parseOptionalFloat(asData.SparePartsSalesYtdPct)
+		cardData.ASRecommendation = &asData.ASDecision
 	}
 
 	return cardData, nil
 }
 
+// parseOptionalFloat разбирает число из строкового показателя; пустое или нечисловое значение дает nil.
+func parseOptionalFloat(value string) *float64 {
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
 // getQuarterFromTime определяет квартал по времени
 func getQuarterFromTime(t time.Time) string {
 	month := t.Month()
@@ -370,7 +402,7 @@ func getQuarterFromTime(t time.Time) string {
 func (r *DealerRepository) getDealerDevData(ctx context.Context, dealerID int, quarter string, year int) (*model.DealerDevelopment, error) {
 	query := r.sq.Select(
 		"id", "dealer_id", "quarter", "year",
-		"check_list_score", "dealer_ship_class", "branding",
+		"check_list_score", "COALESCE(dealer_ship_class, '')", "branding",
 		"marketing_investments", "dealer_dev_recommendation",
 		"created_at", "updated_at",
 	).From("dealer_dev").Where(squirrel.Eq{
@@ -438,6 +470,7 @@ func (r *DealerRepository) getAfterSalesData(ctx context.Context, dealerID int,
 		"id", "dealer_id", "quarter", "year",
 		"recommended_stock", "warranty_stock", "foton_labor_hours",
 		"service_contracts", "as_trainings", "csi", "foton_warranty_hours", "as_decision",
+		"spare_parts_sales_quarter", "spare_parts_sales_ytd_pct",
 		"created_at", "updated_at",
 	).From("after_sales").Where(squirrel.Eq{
 		"dealer_id": dealerID,
@@ -455,6 +488,7 @@ func (r *DealerRepository) getAfterSalesData(ctx context.Context, dealerID int,
 		&as.ID, &as.DealerID, &as.Quarter, &as.Year,
 		&as.RecommendedStock, &as.WarrantyStock, &as.FotonLaborHours,
 		&as.ServiceContracts, &as.ASTrainings, &as.CSI, &as.FotonWarrantyHours, &as.ASDecision,
+		&as.SparePartsSalesQuarter, &as.SparePartsSalesYtdPct,
 		&as.CreatedAt, &as.UpdatedAt,
 	)
 	if err != nil {
@@ -622,3 +656,29 @@ func (r *DealerRepository) UpdateFull(ctx context.Context, dealer *model.Dealer)
 
 	return nil
 }
+
+// UpdateJointDecision обновляет совместное решение по дилеру.
+// Если версия дилера задана, обновление выполняется только при совпадении с текущей версией в БД.
+func (r *DealerRepository) UpdateJointDecision(ctx context.Context, dealer *model.Dealer) error {
+	dealer.UpdatedAt = time.Now()
+
+	query := r.sq.Update(dealerTableName).
+		Set("joint_decision", dealer.JointDecision).
+		Set("updated_at", dealer.UpdatedAt).
+		Set("version", nextVersion).
+		Where(versionCondition(dealer.DealerID, dealer.Version)).
+		Suffix("RETURNING version")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("DealerRepository.UpdateJointDecision: error building query: %w", err)
+	}
+
+	version := dealer.Version
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&dealer.Version)
+	if err != nil {
+		return fmt.Errorf("DealerRepository.UpdateJointDecision: dealer with id %d: %w", dealer.DealerID, versionedWriteError(err, version))
+	}
+
+	return nil
+}
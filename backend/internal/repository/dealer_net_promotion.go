@@ -0,0 +1,382 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const (
+	// dealerBrandsTableName бренды в портфеле дилера.
+	dealerBrandsTableName = "dealer_brands"
+
+	// dealerBusinessesTableName побочные бизнесы дилера.
+	dealerBusinessesTableName = "dealer_businesses"
+
+	// dealerMetricsPromotionsTableName перенесенные кварталы dealer_metrics.
+	dealerMetricsPromotionsTableName = "dealer_metrics_promotions"
+
+	// updateImportedDealerQuery обновляет регион, менеджера и совместное решение дилера из загрузки.
+	// Пустые значения из файла не затирают сохраненные, версия растет только при реальном изменении.
+	updateImportedDealerQuery = `
+		UPDATE dealers
+		SET region = COALESCE(NULLIF($2, ''), region),
+		    manager = COALESCE(NULLIF($3, ''), manager),
+		    joint_decision = COALESCE($4, joint_decision),
+		    version = version + 1,
+		    updated_at = NOW()
+		WHERE id = $1
+		  AND (COALESCE(NULLIF($2, ''), region) IS DISTINCT FROM region
+		       OR COALESCE(NULLIF($3, ''), manager) IS DISTINCT FROM manager
+		       OR COALESCE($4, joint_decision) IS DISTINCT FROM joint_decision)`
+
+	// markPromotedQuery отмечает квартал перенесенным.
+	markPromotedQuery = `
+		INSERT INTO ` + dealerMetricsPromotionsTableName + ` (period, year, quarter)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (period) DO UPDATE SET promoted_at = NOW()`
+
+	// pendingPeriodsQuery кварталы dealer_metrics, которые еще не переносились, от старых к новым.
+	pendingPeriodsQuery = `
+		SELECT m.year, m.quarter
+		FROM ` + dealerMetricsTableName + ` m
+		WHERE NOT EXISTS (SELECT 1 FROM ` + dealerMetricsPromotionsTableName + ` p WHERE p.period = m.period)
+		GROUP BY m.period, m.year, m.quarter
+		ORDER BY m.period`
+)
+
+// DealerNetPromotionRepository переносит строки dealer_net в нормализованные таблицы.
+// Все методы работают в транзакции загрузки, чтобы staging и нормализованные данные менялись вместе.
+type DealerNetPromotionRepository struct {
+	pool   *pgxpool.Pool
+	sq     squirrel.StatementBuilderType
+	logger *slog.Logger
+}
+
+// NewDealerNetPromotionRepository конструктор.
+func NewDealerNetPromotionRepository(pool *pgxpool.Pool, logger *slog.Logger) *DealerNetPromotionRepository {
+	return &DealerNetPromotionRepository{
+		pool:   pool,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		logger: logger,
+	}
+}
+
+// BeginTransaction начинает транзакцию.
+func (r *DealerNetPromotionRepository) BeginTransaction(ctx context.Context) (pgx.Tx, error) {
+	return r.pool.Begin(ctx)
+}
+
+// LoadDealerNetRows читает строки загрузки dealer_net за квартал из dealer_metrics.
+// Каждая строка возвращается как значения по названиям колонок показателей; пустые ячейки пропускаются.
+func (r *DealerNetPromotionRepository) LoadDealerNetRows(ctx context.Context, tx pgx.Tx, year int, quarter string) ([]map[string]string, error) {
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return nil, fmt.Errorf("DealerNetPromotionRepository.LoadDealerNetRows: %w", err)
+	}
+
+	query := `
+		SELECT jsonb_strip_nulls(to_jsonb(m) - $2::text[])
+		FROM ` + dealerMetricsTableName + ` m
+		WHERE period = $1
+		ORDER BY id`
+
+	rows, err := tx.Query(ctx, query, period, dealerMetricsHiddenColumns)
+	if err != nil {
+		return nil, fmt.Errorf("DealerNetPromotionRepository.LoadDealerNetRows: error querying: %w", err)
+	}
+	defer rows.Close()
+
+	var result []map[string]string
+	for rows.Next() {
+		var values map[string]string
+		if err := rows.Scan(&values); err != nil {
+			return nil, fmt.Errorf("DealerNetPromotionRepository.LoadDealerNetRows: error scanning: %w", err)
+		}
+
+		row := make(map[string]string, len(values))
+		for name, value := range values {
+			if text := strings.TrimSpace(value); text != "" {
+				row[name] = text
+			}
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("DealerNetPromotionRepository.LoadDealerNetRows: error reading rows: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("DealerNetPromotionRepository.LoadDealerNetRows: %s: %w", model.DealerNetPeriodName(year, quarter), ErrNotFound)
+	}
+
+	return result, nil
+}
+
+// SaveDealerNetRecords сохраняет показатели дилеров за квартал в нормализованные таблицы.
+// Дилер определяется по названию и городу без учета регистра; неизвестные дилеры создаются.
+// Записи sales, dealer_dev и after_sales за квартал перезаписываются значениями из загрузки.
+func (r *DealerNetPromotionRepository) SaveDealerNetRecords(ctx context.Context, tx pgx.Tx, year int, quarter string, records []*model.DealerNetRecord, result *model.PromotionResult) error {
+	dealers, err := r.loadDealerIdentities(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: %w", err)
+	}
+
+	seen := make(map[int]bool, len(records))
+	for _, rec := range records {
+		dealerID, created, err := r.resolveDealer(ctx, tx, dealers, &rec.Dealer)
+		if err != nil {
+			return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: dealer %q: %w", rec.Dealer.DealerNameRu, err)
+		}
+		if created {
+			result.DealersCreated++
+		} else if !seen[dealerID] {
+			result.DealersMatched++
+		}
+		seen[dealerID] = true
+
+		if err := r.upsertPeriodRecord(ctx, tx, salesTableName, dealerID, quarter, year, promotedSalesColumns(&rec.Sales)); err != nil {
+			return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: dealer %q: %w", rec.Dealer.DealerNameRu, err)
+		}
+		if err := r.upsertPeriodRecord(ctx, tx, dealerDevTableName, dealerID, quarter, year, promotedDealerDevColumns(&rec.DealerDevelopment)); err != nil {
+			return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: dealer %q: %w", rec.Dealer.DealerNameRu, err)
+		}
+		if err := r.upsertPeriodRecord(ctx, tx, afterSalesTableName, dealerID, quarter, year, promotedAfterSalesColumns(&rec.AfterSales)); err != nil {
+			return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: dealer %q: %w", rec.Dealer.DealerNameRu, err)
+		}
+		result.RecordsUpserted += 3
+
+		if rec.Brands != nil {
+			if err := r.replaceDealerValues(ctx, tx, dealerBrandsTableName, "brand_name", dealerID, rec.Brands); err != nil {
+				return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: dealer %q: %w", rec.Dealer.DealerNameRu, err)
+			}
+		}
+		if rec.Businesses != nil {
+			if err := r.replaceDealerValues(ctx, tx, dealerBusinessesTableName, "business_type", dealerID, rec.Businesses); err != nil {
+				return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: dealer %q: %w", rec.Dealer.DealerNameRu, err)
+			}
+		}
+	}
+
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: %w", err)
+	}
+	if _, err := tx.Exec(ctx, markPromotedQuery, period, year, quarter); err != nil {
+		return fmt.Errorf("DealerNetPromotionRepository.SaveDealerNetRecords: error marking period promoted: %w", err)
+	}
+
+	r.logger.Info("Dealer net records promoted",
+		slog.Int("year", year),
+		slog.String("quarter", quarter),
+		slog.Int("records", len(records)),
+		slog.Int("dealers_created", result.DealersCreated),
+		slog.Int("dealers_matched", result.DealersMatched),
+	)
+
+	return nil
+}
+
+// ListPendingPeriods возвращает загруженные кварталы dealer_metrics, которые еще не переносились
+// в нормализованные таблицы, от старых к новым.
+func (r *DealerNetPromotionRepository) ListPendingPeriods(ctx context.Context) ([]model.PromotionPeriod, error) {
+	rows, err := r.pool.Query(ctx, pendingPeriodsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("DealerNetPromotionRepository.ListPendingPeriods: error querying: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []model.PromotionPeriod
+	for rows.Next() {
+		var period model.PromotionPeriod
+		if err := rows.Scan(&period.Year, &period.Quarter); err != nil {
+			return nil, fmt.Errorf("DealerNetPromotionRepository.ListPendingPeriods: error scanning row: %w", err)
+		}
+		periods = append(periods, period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("DealerNetPromotionRepository.ListPendingPeriods: %w", err)
+	}
+
+	return periods, nil
+}
+
+// loadDealerIdentities возвращает ID дилеров по ключу название+город.
+// При дублях используется дилер с наименьшим ID.
+func (r *DealerNetPromotionRepository) loadDealerIdentities(ctx context.Context, tx pgx.Tx) (map[string]int, error) {
+	rows, err := tx.Query(ctx, "SELECT id, name, COALESCE(city, '') FROM "+dealerTableName+" ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error querying dealers: %w", err)
+	}
+	defer rows.Close()
+
+	dealers := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var name, city string
+		if err := rows.Scan(&id, &name, &city); err != nil {
+			return nil, fmt.Errorf("error scanning dealer: %w", err)
+		}
+		key := dealerIdentityKey(name, city)
+		if _, ok := dealers[key]; !ok {
+			dealers[key] = id
+		}
+	}
+
+	return dealers, rows.Err()
+}
+
+// resolveDealer находит дилера по названию и городу или создает нового.
+// Для найденного дилера обновляются регион, менеджер и совместное решение из загрузки.
+func (r *DealerNetPromotionRepository) resolveDealer(ctx context.Context, tx pgx.Tx, dealers map[string]int, dealer *model.Dealer) (int, bool, error) {
+	key := dealerIdentityKey(dealer.DealerNameRu, dealer.City)
+
+	if id, ok := dealers[key]; ok {
+		if _, err := tx.Exec(ctx, updateImportedDealerQuery, id, dealer.Region, dealer.Manager, dealer.JointDecision); err != nil {
+			return 0, false, fmt.Errorf("error updating dealer %d: %w", id, err)
+		}
+		return id, false, nil
+	}
+
+	query := r.sq.Insert(dealerTableName).
+		Columns("name", "city", "region", "manager", "joint_decision").
+		Values(dealer.DealerNameRu, dealer.City, dealer.Region, dealer.Manager, dealer.JointDecision).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, false, fmt.Errorf("error building dealer insert: %w", err)
+	}
+
+	var id int
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return 0, false, fmt.Errorf("error inserting dealer: %w", classifyError(err))
+	}
+
+	dealers[key] = id
+	return id, true, nil
+}
+
+// upsertPeriodRecord вставляет или перезаписывает запись показателей дилера за квартал.
+// Колонки, которых нет в загрузке (например, CSI), при перезаписи сохраняются.
+func (r *DealerNetPromotionRepository) upsertPeriodRecord(ctx context.Context, tx pgx.Tx, table string, dealerID int, quarter string, year int, columns map[string]interface{}) error {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]interface{}, len(columns)+3)
+	for name, value := range columns {
+		values[name] = value
+	}
+	values["dealer_id"] = dealerID
+	values["quarter"] = quarter
+	values["year"] = year
+
+	sets := make([]string, 0, len(names)+2)
+	for _, name := range names {
+		sets = append(sets, name+" = EXCLUDED."+name)
+	}
+	sets = append(sets, "updated_at = NOW()", "version = "+table+".version + 1")
+
+	query := r.sq.Insert(table).
+		SetMap(values).
+		Suffix("ON CONFLICT (dealer_id, quarter, year) DO UPDATE SET " + strings.Join(sets, ", "))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("error building %s upsert: %w", table, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("error upserting %s: %w", table, classifyError(err))
+	}
+
+	return nil
+}
+
+// replaceDealerValues заменяет список брендов или бизнесов дилера.
+func (r *DealerNetPromotionRepository) replaceDealerValues(ctx context.Context, tx pgx.Tx, table, column string, dealerID int, values []string) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE dealer_id = $1", dealerID); err != nil {
+		return fmt.Errorf("error clearing %s: %w", table, err)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	query := r.sq.Insert(table).Columns("dealer_id", column)
+	for _, value := range values {
+		query = query.Values(dealerID, value)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("error building %s insert: %w", table, err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("error inserting %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// dealerIdentityKey ключ дилера: название и город без учета регистра и крайних пробелов.
+func dealerIdentityKey(name, city string) string {
+	return strings.ToLower(strings.TrimSpace(name)) + "|" + strings.ToLower(strings.TrimSpace(city))
+}
+
+// promotedSalesColumns колонки sales, которые заполняются из dealer_net.
+func promotedSalesColumns(s *model.Sales) map[string]interface{} {
+	return map[string]interface{}{
+		"stock_hdt":               s.StockHDT,
+		"stock_mdt":               s.StockMDT,
+		"stock_ldt":               s.StockLDT,
+		"buyout_hdt":              s.BuyoutHDT,
+		"buyout_mdt":              s.BuyoutMDT,
+		"buyout_ldt":              s.BuyoutLDT,
+		"service_contracts_sales": s.ServiceContractsSales,
+		"sales_decision":          s.SalesDecision,
+	}
+}
+
+// promotedDealerDevColumns колонки dealer_dev, которые заполняются из dealer_net.
+func promotedDealerDevColumns(dd *model.DealerDevelopment) map[string]interface{} {
+	// Класс ограничен значениями A-D, пустой класс хранится как NULL
+	var class interface{}
+	if dd.DealershipClass != "" {
+		class = dd.DealershipClass
+	}
+
+	return map[string]interface{}{
+		"check_list_score":          dd.CheckListScore,
+		"dealer_ship_class":         class,
+		"branding":                  dd.Branding,
+		"marketing_investments":     dd.MarketingInvestments,
+		"dealer_dev_recommendation": dd.DDRecommendation,
+	}
+}
+
+// promotedAfterSalesColumns колонки after_sales, которые заполняются из dealer_net.
+func promotedAfterSalesColumns(as *model.AfterSales) map[string]interface{} {
+	return map[string]interface{}{
+		"recommended_stock":         as.RecommendedStock,
+		"warranty_stock":            as.WarrantyStock,
+		"foton_labor_hours":         as.FotonLaborHours,
+		"foton_warranty_hours":      as.FotonWarrantyHours,
+		"service_contracts":         as.ServiceContracts,
+		"as_trainings":              as.ASTrainings,
+		"as_decision":               as.ASDecision,
+		"spare_parts_sales_quarter": as.SparePartsSalesQuarter,
+		"spare_parts_sales_ytd_pct": as.SparePartsSalesYtdPct,
+		"foton_labour_hours_share":  as.FotonLabourHoursShare,
+	}
+}
@@ -45,7 +45,7 @@ func (r *DealerDevRepository) Create(ctx context.Context, dd *model.DealerDevelo
 			dd.MarketingInvestments, dd.DDRecommendation,
 			dd.CreatedAt, dd.UpdatedAt,
 		).
-		Suffix("RETURNING id")
+		Suffix("RETURNING id, version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -53,9 +53,9 @@ func (r *DealerDevRepository) Create(ctx context.Context, dd *model.DealerDevelo
 	}
 
 	var id int64
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id, &dd.Version)
 	if err != nil {
-		return 0, fmt.Errorf("DealerDevRepository.Create: error inserting: %w", err)
+		return 0, fmt.Errorf("DealerDevRepository.Create: error inserting: %w", classifyError(err))
 	}
 
 	dd.ID = int(id)
@@ -66,9 +66,9 @@ func (r *DealerDevRepository) Create(ctx context.Context, dd *model.DealerDevelo
 func (r *DealerDevRepository) GetByID(ctx context.Context, id int) (*model.DealerDevelopment, error) {
 	query := r.sq.Select(
 		"id", "dealer_id", "quarter", "year",
-		"check_list_score", "dealer_ship_class", "branding",
+		"check_list_score", "COALESCE(dealer_ship_class, '')", "branding",
 		"marketing_investments", "dealer_dev_recommendation",
-		"created_at", "updated_at",
+		"version", "created_at", "updated_at",
 	).From(dealerDevTableName).Where(squirrel.Eq{"id": id})
 
 	sql, args, err := query.ToSql()
@@ -81,25 +81,26 @@ func (r *DealerDevRepository) GetByID(ctx context.Context, id int) (*model.Deale
 		&dd.ID, &dd.DealerID, &dd.Quarter, &dd.Year,
 		&dd.CheckListScore, &dd.DealershipClass, &dd.Branding,
 		&dd.MarketingInvestments, &dd.DDRecommendation,
-		&dd.CreatedAt, &dd.UpdatedAt,
+		&dd.Version, &dd.CreatedAt, &dd.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("DealerDevRepository.GetByID: error scanning: %w", err)
+		return nil, fmt.Errorf("DealerDevRepository.GetByID: error scanning: %w", classifyError(err))
 	}
 
 	return dd, nil
 }
 
-// GetByDealerAndPeriod получает запись развития дилера по дилеру и периоду.
-func (r *DealerDevRepository) GetByDealerAndPeriod(ctx context.Context, dealerID int, period time.Time) (*model.DealerDevelopment, error) {
+// GetByDealerAndPeriod получает запись развития дилера по дилеру, кварталу и году.
+func (r *DealerDevRepository) GetByDealerAndPeriod(ctx context.Context, dealerID int, quarter string, year int) (*model.DealerDevelopment, error) {
 	query := r.sq.Select(
 		"id", "dealer_id", "quarter", "year",
-		"check_list_score", "dealer_ship_class", "branding",
+		"check_list_score", "COALESCE(dealer_ship_class, '')", "branding",
 		"marketing_investments", "dealer_dev_recommendation",
-		"created_at", "updated_at",
+		"version", "created_at", "updated_at",
 	).From(dealerDevTableName).Where(squirrel.Eq{
 		"dealer_id": dealerID,
-		"period":    period,
+		"quarter":   quarter,
+		"year":      year,
 	})
 
 	sql, args, err := query.ToSql()
@@ -112,10 +113,10 @@ func (r *DealerDevRepository) GetByDealerAndPeriod(ctx context.Context, dealerID
 		&dd.ID, &dd.DealerID, &dd.Quarter, &dd.Year,
 		&dd.CheckListScore, &dd.DealershipClass, &dd.Branding,
 		&dd.MarketingInvestments, &dd.DDRecommendation,
-		&dd.CreatedAt, &dd.UpdatedAt,
+		&dd.Version, &dd.CreatedAt, &dd.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("DealerDevRepository.GetByDealerAndPeriod: error scanning: %w", err)
+		return nil, fmt.Errorf("DealerDevRepository.GetByDealerAndPeriod: error scanning: %w", classifyError(err))
 	}
 
 	return dd, nil
@@ -125,7 +126,7 @@ func (r *DealerDevRepository) GetByDealerAndPeriod(ctx context.Context, dealerID
 func (r *DealerDevRepository) GetAllByPeriod(ctx context.Context, period time.Time) ([]*model.DealerDevelopment, error) {
 	query := r.sq.Select(
 		"id", "dealer_id", "quarter", "year",
-		"check_list_score", "dealer_ship_class", "branding",
+		"check_list_score", "COALESCE(dealer_ship_class, '')", "branding",
 		"marketing_investments", "dealer_dev_recommendation",
 		"created_at", "updated_at",
 	).From(dealerDevTableName).Where(squirrel.Eq{"period": period})
@@ -160,6 +161,7 @@ func (r *DealerDevRepository) GetAllByPeriod(ctx context.Context, period time.Ti
 }
 
 // UpdateFull обновляет всю запись развития дилера целиком.
+// Если версия записи задана, обновление выполняется только при совпадении с текущей версией в БД.
 func (r *DealerDevRepository) UpdateFull(ctx context.Context, dd *model.DealerDevelopment) error {
 	dd.UpdatedAt = time.Now()
 
@@ -173,16 +175,19 @@ func (r *DealerDevRepository) UpdateFull(ctx context.Context, dd *model.DealerDe
 		Set("marketing_investments", dd.MarketingInvestments).
 		Set("dealer_dev_recommendation", dd.DDRecommendation).
 		Set("updated_at", dd.UpdatedAt).
-		Where(squirrel.Eq{"id": dd.ID})
+		Set("version", nextVersion).
+		Where(versionCondition(dd.ID, dd.Version)).
+		Suffix("RETURNING version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("DealerDevRepository.UpdateFull: error building query: %w", err)
 	}
 
-	_, err = r.pool.Exec(ctx, sql, args...)
+	version := dd.Version
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&dd.Version)
 	if err != nil {
-		return fmt.Errorf("DealerDevRepository.UpdateFull: error updating: %w", err)
+		return fmt.Errorf("DealerDevRepository.UpdateFull: record with id %d: %w", dd.ID, versionedWriteError(err, version))
 	}
 
 	return nil
@@ -192,19 +197,23 @@ func (r *DealerDevRepository) UpdateFull(ctx context.Context, dd *model.DealerDe
 func (r *DealerDevRepository) GetWithDetailsByPeriod(ctx context.Context, quarter string, year int, region string) ([]*model.DealerDevWithDetails, error) {
 	queryBuilder := r.sq.Select(
 		"dd.id", "dd.dealer_id", "dd.quarter", "dd.year",
-		"dd.check_list_score", "dd.dealer_ship_class", "dd.branding",
+		"dd.check_list_score", "COALESCE(dd.dealer_ship_class, '')", "dd.branding",
 		"dd.marketing_investments", "dd.dealer_dev_recommendation",
-		"dd.created_at", "dd.updated_at",
+		"dd.version", "dd.created_at", "dd.updated_at",
 		"d.name", "d.city", "d.region", "d.manager",
+		"COALESCE((SELECT string_agg(b.brand_name, ', ' ORDER BY b.id) FROM dealer_brands b WHERE b.dealer_id = d.id), '')",
+		"COALESCE((SELECT string_agg(bb.business_type, ', ' ORDER BY bb.id) FROM dealer_businesses bb WHERE bb.dealer_id = d.id), '')",
 	).
 		From(dealerDevTableName + " dd").
 		Join("dealers d ON dd.dealer_id = d.id").
 		Where(squirrel.Eq{"dd.quarter": quarter, "dd.year": year})
 
-	if region != "all-russia" {
+	if region != "" && !model.IsAllRussia(region) {
 		queryBuilder = queryBuilder.Where(squirrel.Eq{"d.region": region})
 	}
 
+	queryBuilder = queryBuilder.OrderBy("d.name")
+
 	sql, args, err := queryBuilder.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("DealerDevRepository.GetWithDetailsByPeriod: error building query: %w", err)
@@ -223,8 +232,9 @@ func (r *DealerDevRepository) GetWithDetailsByPeriod(ctx context.Context, quarte
 			&ddwd.ID, &ddwd.DealerID, &ddwd.Quarter, &ddwd.Year,
 			&ddwd.CheckListScore, &ddwd.DealershipClass, &ddwd.Branding,
 			&ddwd.MarketingInvestments, &ddwd.DDRecommendation,
-			&ddwd.CreatedAt, &ddwd.UpdatedAt,
+			&ddwd.Version, &ddwd.CreatedAt, &ddwd.UpdatedAt,
 			&ddwd.DealerNameRu, &ddwd.City, &ddwd.Region, &ddwd.Manager,
+			&ddwd.BrandsInPortfolio, &ddwd.BySideBusinesses,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("DealerDevRepository.GetWithDetailsByPeriod: error scanning: %w", err)
@@ -236,8 +246,8 @@ func (r *DealerDevRepository) GetWithDetailsByPeriod(ctx context.Context, quarte
 }
 
 // Delete удаляет запись развития дилера.
-func (r *DealerDevRepository) Delete(ctx context.Context, id int) error {
-	query := r.sq.Delete(dealerDevTableName).Where(squirrel.Eq{"id": id})
+func (r *DealerDevRepository) Delete(ctx context.Context, id int, version int) error {
+	query := r.sq.Delete(dealerDevTableName).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -246,24 +256,26 @@ func (r *DealerDevRepository) Delete(ctx context.Context, id int) error {
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("DealerDevRepository.Delete: error deleting: %w", err)
+		return fmt.Errorf("DealerDevRepository.Delete: error deleting: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("DealerDevRepository.Delete: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("DealerDevRepository.Delete: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
 }
 
-// Update обновляет данные развития дилера.
-func (r *DealerDevRepository) Update(ctx context.Context, id int, updates map[string]interface{}) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+// Update обновляет данные развития дилера по проверенному набору изменений.
+func (r *DealerDevRepository) Update(ctx context.Context, id int, version int, patch model.Patch) error {
+	if patch.IsEmpty() {
+		return fmt.Errorf("DealerDevRepository.Update: %w", model.ErrEmptyPatch)
 	}
 
+	updates := patch.Columns()
 	updates["updated_at"] = time.Now()
-	query := r.sq.Update(dealerDevTableName).SetMap(updates).Where(squirrel.Eq{"id": id})
+	updates["version"] = nextVersion
+	query := r.sq.Update(dealerDevTableName).SetMap(updates).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -272,11 +284,11 @@ func (r *DealerDevRepository) Update(ctx context.Context, id int, updates map[st
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("DealerDevRepository.Update: error updating: %w", err)
+		return fmt.Errorf("DealerDevRepository.Update: error updating: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("DealerDevRepository.Update: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("DealerDevRepository.Update: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
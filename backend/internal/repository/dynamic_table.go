@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,25 +14,40 @@ import (
 	"github.com/typefunco/dealer_dev_platform/internal/model"
 )
 
-// DynamicTableRepository интерфейс репозитория для работы с динамическими таблицами.
+// dealerMetricsTableName таблица показателей дилеров, партиционированная по кварталам.
+const dealerMetricsTableName = "dealer_metrics"
+
+// dealerMetricsArchiveSchema схема, куда переносятся партиции удаленных кварталов до окончательного удаления.
+const dealerMetricsArchiveSchema = "archive"
+
+// dealerMetricsHiddenColumns служебные колонки dealer_metrics, которые не отдаются как данные загрузки.
+var dealerMetricsHiddenColumns = []string{"id", "period", "year", "quarter", "extra", "created_at"}
+
+// DynamicTableRepository интерфейс репозитория для работы с загрузками dealer_net.
 type DynamicTableRepository interface {
-	// CreateDealerNetTable создает таблицу dealer_net для указанного года и квартала
-	CreateDealerNetTable(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string) error
+	// PrepareDealerMetricsPeriod создает партицию квартала и удаляет ранее загруженные строки периода
+	PrepareDealerMetricsPeriod(ctx context.Context, tx pgx.Tx, year int, quarter string) error
 
-	// InsertDealerNetData вставляет данные в таблицу dealer_net
-	InsertDealerNetData(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, rows [][]interface{}) error
+	// InsertDealerMetrics вставляет строки квартала в dealer_metrics
+	InsertDealerMetrics(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, rows [][]interface{}, progress InsertProgressFunc) error
 
-	// GetDealerNetTableName возвращает название таблицы dealer_net для указанного года и квартала
-	GetDealerNetTableName(year int, quarter string) string
+	// MergeDealerMetrics обновляет строки дилеров квартала, не трогая остальных дилеров
+	MergeDealerMetrics(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, rows [][]interface{}) ([]bool, error)
 
-	// TableExists проверяет существование таблицы
-	TableExists(ctx context.Context, tableName string) (bool, error)
+	// DealerMetricsPeriodExists проверяет, загружен ли квартал
+	DealerMetricsPeriodExists(ctx context.Context, year int, quarter string) (bool, error)
 
-	// GetTableMetadata возвращает метаданные таблицы
-	GetTableMetadata(ctx context.Context, tableName string) (*model.ExcelTableMetadata, error)
+	// GetDealerMetricsRows возвращает строки загрузки за квартал с пагинацией и их общее количество
+	GetDealerMetricsRows(ctx context.Context, year int, quarter string, limit, offset int) ([]map[string]interface{}, int, error)
 
-	// ListDynamicTables возвращает список всех динамически созданных таблиц
-	ListDynamicTables(ctx context.Context) ([]*model.ExcelTableMetadata, error)
+	// ArchiveDealerMetricsPeriod переносит партицию квартала в архив
+	ArchiveDealerMetricsPeriod(ctx context.Context, tx pgx.Tx, year int, quarter string, archiveTable string) error
+
+	// RestoreDealerMetricsPeriod возвращает архив квартала в dealer_metrics
+	RestoreDealerMetricsPeriod(ctx context.Context, tx pgx.Tx, year int, quarter string, archiveTable string) error
+
+	// DropArchivedDealerMetrics окончательно удаляет архив квартала
+	DropArchivedDealerMetrics(ctx context.Context, tx pgx.Tx, archiveTable string) error
 
 	// BeginTransaction начинает транзакцию
 	BeginTransaction(ctx context.Context) (pgx.Tx, error)
@@ -38,11 +55,11 @@ type DynamicTableRepository interface {
 	// UpdateDealerBrands обновляет бренды и побочные бизнесы дилеров
 	UpdateDealerBrands(ctx context.Context, tx pgx.Tx, year int, quarter string, updates []model.BrandsUpdate) (int, []string, error)
 
-	// CheckDealerExists проверяет существование дилера в таблице
+	// CheckDealerExists проверяет, есть ли дилер в загрузке за квартал
 	CheckDealerExists(ctx context.Context, year int, quarter string, dealerName string, city string) (bool, error)
 }
 
-// dynamicTableRepository реализация репозитория для динамических таблиц.
+// dynamicTableRepository реализация репозитория для загрузок dealer_net.
 type dynamicTableRepository struct {
 	pool   *pgxpool.Pool
 	logger *slog.Logger
@@ -56,476 +73,441 @@ func NewDynamicTableRepository(pool *pgxpool.Pool, logger *slog.Logger) DynamicT
 	}
 }
 
-// GetDealerNetTableName возвращает название таблицы dealer_net для указанного года и квартала.
-func (r *dynamicTableRepository) GetDealerNetTableName(year int, quarter string) string {
-	return fmt.Sprintf("dealer_net_%d_%s", year, strings.ToLower(quarter))
-}
-
-// CreateDealerNetTable создает таблицу dealer_net для указанного года и квартала.
-func (r *dynamicTableRepository) CreateDealerNetTable(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string) error {
-	tableName := r.GetDealerNetTableName(year, quarter)
-
-	var columnDefs []string
-	columnDefs = append(columnDefs, "id BIGSERIAL PRIMARY KEY")
-
-	for _, col := range columns {
-		if col == "dealer" {
-			columnDefs = append(columnDefs, fmt.Sprintf("%s TEXT NOT NULL", col))
-		} else {
-			columnDefs = append(columnDefs, fmt.Sprintf("%s TEXT", col))
-		}
+// PrepareDealerMetricsPeriod создает партицию квартала и удаляет ранее загруженные строки периода.
+// Повторная загрузка квартала заменяет предыдущую.
+func (r *dynamicTableRepository) PrepareDealerMetricsPeriod(ctx context.Context, tx pgx.Tx, year int, quarter string) error {
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return fmt.Errorf("DynamicTableRepository.PrepareDealerMetricsPeriod: %w", err)
 	}
 
-	// Добавляем специальные поля для brands и byside_businesses
-	columnDefs = append(columnDefs, "brands_in_portfolio TEXT")
-	columnDefs = append(columnDefs, "byside_businesses TEXT")
-
-	columnDefs = append(columnDefs, "created_at TIMESTAMP DEFAULT NOW()")
+	if err := ensureDealerMetricsPartition(ctx, tx, period); err != nil {
+		return fmt.Errorf("DynamicTableRepository.PrepareDealerMetricsPeriod: %w", err)
+	}
 
-	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)",
-		tableName,
-		strings.Join(columnDefs, ",\n    "))
+	tag, err := tx.Exec(ctx, "DELETE FROM "+dealerMetricsTableName+" WHERE period = $1", period)
+	if err != nil {
+		return fmt.Errorf("DynamicTableRepository.PrepareDealerMetricsPeriod: error clearing period: %w", err)
+	}
 
-	r.logger.Info("Creating dealer_net table",
-		slog.String("table_name", tableName),
+	r.logger.Info("Dealer metrics period prepared",
 		slog.Int("year", year),
 		slog.String("quarter", quarter),
-		slog.Int("columns_count", len(columns)),
+		slog.Int64("replaced_rows", tag.RowsAffected()),
 	)
 
-	_, err := tx.Exec(ctx, query)
-	if err != nil {
-		r.logger.Error("Failed to create dealer_net table",
-			slog.String("table_name", tableName),
-			slog.String("error", err.Error()),
-		)
-		return fmt.Errorf("failed to create table %s: %w", tableName, err)
-	}
-
-	r.logger.Info("Dealer_net table created successfully",
-		slog.String("table_name", tableName),
-	)
+	return nil
+}
 
+// ensureDealerMetricsPartition создает партицию квартала, если ее еще нет.
+func ensureDealerMetricsPartition(ctx context.Context, tx pgx.Tx, period time.Time) error {
+	if _, err := tx.Exec(ctx, "SELECT ensure_dealer_metrics_partition($1)", period); err != nil {
+		return fmt.Errorf("error creating partition: %w", err)
+	}
 	return nil
 }
 
-// InsertDealerNetData вставляет данные в таблицу dealer_net.
-func (r *dynamicTableRepository) InsertDealerNetData(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, rows [][]interface{}) error {
-	if len(rows) == 0 {
-		r.logger.Info("No data to insert",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-		)
-		return nil
+// MergeDealerMetrics обновляет строки дилеров квартала: прежние строки тех же дилера и города удаляются,
+// новые вставляются через COPY. Колонки dealer и city обязательны. Строка может быть неполной:
+// показатели без значения (nil или пустая строка) берутся из прежней строки дилера, а не очищаются.
+// Возвращает для каждой строки, была ли у дилера строка в квартале до обновления.
+func (r *dynamicTableRepository) MergeDealerMetrics(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, rows [][]interface{}) ([]bool, error) {
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return nil, fmt.Errorf("DynamicTableRepository.MergeDealerMetrics: %w", err)
 	}
 
-	tableName := r.GetDealerNetTableName(year, quarter)
+	dealerIndex, cityIndex := -1, -1
+	for i, column := range columns {
+		switch column {
+		case "dealer":
+			dealerIndex = i
+		case "city":
+			cityIndex = i
+		}
+	}
+	if dealerIndex < 0 || cityIndex < 0 {
+		return nil, fmt.Errorf("DynamicTableRepository.MergeDealerMetrics: columns dealer and city are required")
+	}
 
-	// Строим запрос для вставки
-	placeholders := make([]string, len(rows))
-	args := make([]interface{}, 0, len(rows)*len(columns))
+	if err := ensureDealerMetricsPartition(ctx, tx, period); err != nil {
+		return nil, fmt.Errorf("DynamicTableRepository.MergeDealerMetrics: %w", err)
+	}
 
+	dealers := make([]string, len(rows))
+	cities := make([]string, len(rows))
 	for i, row := range rows {
-		rowPlaceholders := make([]string, len(columns))
-		for j := range columns {
-			rowPlaceholders[j] = fmt.Sprintf("$%d", len(args)+j+1)
-		}
-		placeholders[i] = fmt.Sprintf("(%s)", strings.Join(rowPlaceholders, ", "))
+		dealers[i], _ = row[dealerIndex].(string)
+		cities[i], _ = row[cityIndex].(string)
+	}
+
+	// Удаляем прежние строки дилеров и забираем их значения для показателей, которых нет в новых строках
+	deleted, err := tx.Query(ctx, `
+		DELETE FROM `+dealerMetricsTableName+` m
+		USING unnest($2::text[], $3::text[]) WITH ORDINALITY AS k(dealer, city, n)
+		WHERE m.period = $1
+			AND LOWER(m.dealer) = LOWER(k.dealer)
+			AND LOWER(COALESCE(m.city, '')) = LOWER(k.city)
+		RETURNING k.n, jsonb_strip_nulls(to_jsonb(m) - $4::text[]) || m.extra`, period, dealers, cities, dealerMetricsHiddenColumns)
+	if err != nil {
+		return nil, fmt.Errorf("DynamicTableRepository.MergeDealerMetrics: error deleting rows: %w", err)
+	}
 
-		for _, value := range row {
-			args = append(args, value)
+	replaced := make([]bool, len(rows))
+	previous := make([]map[string]interface{}, len(rows))
+	for deleted.Next() {
+		var n int64
+		var values map[string]interface{}
+		if err := deleted.Scan(&n, &values); err != nil {
+			deleted.Close()
+			return nil, fmt.Errorf("DynamicTableRepository.MergeDealerMetrics: error scanning row: %w", err)
 		}
+		replaced[n-1] = true
+		previous[n-1] = values
 	}
-
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
-
-	r.logger.Info("Inserting dealer_net data",
-		slog.String("table_name", tableName),
-		slog.Int("year", year),
-		slog.String("quarter", quarter),
-		slog.Int("rows", len(rows)),
-		slog.Int("columns", len(columns)),
-	)
-
-	_, err := tx.Exec(ctx, query, args...)
-	if err != nil {
-		r.logger.Error("Failed to insert dealer_net data",
-			slog.String("table_name", tableName),
-			slog.String("error", err.Error()),
-		)
-		return fmt.Errorf("failed to insert data into table %s: %w", tableName, err)
+	deleted.Close()
+	if err := deleted.Err(); err != nil {
+		return nil, fmt.Errorf("DynamicTableRepository.MergeDealerMetrics: error deleting rows: %w", err)
 	}
 
-	r.logger.Info("Dealer_net data inserted successfully",
-		slog.String("table_name", tableName),
-		slog.Int("rows_inserted", len(rows)),
-	)
+	columns, rows = mergeDealerMetricsRows(columns, rows, previous)
+	if err := r.InsertDealerMetrics(ctx, tx, year, quarter, columns, rows, nil); err != nil {
+		return nil, fmt.Errorf("DynamicTableRepository.MergeDealerMetrics: %w", err)
+	}
 
-	return nil
+	return replaced, nil
 }
 
-// CreateTable создает таблицу с заданной схемой.
-func (r *dynamicTableRepository) CreateTable(ctx context.Context, schema *model.DynamicTableSchema) error {
-	// Санитизация названия таблицы
-	tableName := r.sanitizeTableName(schema.TableName)
-
-	// Проверяем, что таблица не существует
-	exists, err := r.TableExists(ctx, tableName)
-	if err != nil {
-		return fmt.Errorf("failed to check table existence: %w", err)
+// mergeDealerMetricsRows дополняет строки значениями прежних строк дилеров.
+// Колонки прежних строк, которых нет в columns, добавляются в конец по алфавиту.
+func mergeDealerMetricsRows(columns []string, rows [][]interface{}, previous []map[string]interface{}) ([]string, [][]interface{}) {
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[column] = true
 	}
 
-	if exists {
-		r.logger.Info("Table already exists, skipping creation", slog.String("table", tableName))
-		return nil
+	var added []string
+	for _, values := range previous {
+		for column := range values {
+			if !known[column] {
+				known[column] = true
+				added = append(added, column)
+			}
+		}
 	}
+	sort.Strings(added)
+	merged := append(slices.Clip(columns), added...)
 
-	// Строим SQL для создания таблицы
-	query := r.buildCreateTableQuery(tableName, schema.Columns)
-
-	r.logger.Info("Creating table",
-		slog.String("table", tableName),
-		slog.String("query", query),
-	)
-
-	_, err = r.pool.Exec(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to create table %s: %w", tableName, err)
+	mergedRows := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		mergedRow := make([]interface{}, len(merged))
+		copy(mergedRow, row)
+		for j, column := range merged {
+			if !isBlankMetric(mergedRow[j]) {
+				continue
+			}
+			if value, ok := previous[i][column]; ok {
+				mergedRow[j] = fmt.Sprint(value)
+			}
+		}
+		mergedRows[i] = mergedRow
 	}
 
-	r.logger.Info("Table created successfully", slog.String("table", tableName))
-	return nil
+	return merged, mergedRows
+}
+
+// isBlankMetric сообщает, что значения показателя нет в строке.
+func isBlankMetric(value interface{}) bool {
+	s, ok := value.(string)
+	return value == nil || (ok && s == "")
 }
 
-// InsertData вставляет данные в таблицу.
-func (r *dynamicTableRepository) InsertData(ctx context.Context, tableName string, columns []string, rows [][]interface{}) error {
+// InsertDealerMetrics вставляет строки квартала в dealer_metrics через COPY.
+// Колонки, которых нет в model.DealerMetricColumns, складываются в JSONB-колонку extra.
+func (r *dynamicTableRepository) InsertDealerMetrics(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, rows [][]interface{}, progress InsertProgressFunc) error {
 	if len(rows) == 0 {
-		r.logger.Info("No data to insert", slog.String("table", tableName))
+		r.logger.Info("No data to insert",
+			slog.Int("year", year),
+			slog.String("quarter", quarter),
+		)
 		return nil
 	}
 
-	// Санитизация названия таблицы
-	tableName = r.sanitizeTableName(tableName)
-
-	// Санитизация названий колонок
-	sanitizedColumns := make([]string, len(columns))
-	for i, col := range columns {
-		sanitizedColumns[i] = r.sanitizeColumnName(col)
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return fmt.Errorf("DynamicTableRepository.InsertDealerMetrics: %w", err)
 	}
+	quarter = strings.ToUpper(quarter)
 
-	// Строим SQL для вставки данных
-	query := r.buildInsertQuery(tableName, sanitizedColumns, len(rows))
+	copyColumns, metricRows := dealerMetricsCopyRows(period, year, quarter, columns, rows)
 
-	r.logger.Info("Inserting data",
-		slog.String("table", tableName),
+	r.logger.Info("Inserting dealer metrics",
+		slog.Int("year", year),
+		slog.String("quarter", quarter),
 		slog.Int("rows", len(rows)),
 		slog.Int("columns", len(columns)),
 	)
 
-	// Подготавливаем данные для вставки
-	args := make([]interface{}, 0, len(rows)*len(columns))
-	for _, row := range rows {
-		for i := range sanitizedColumns {
-			if i < len(row) {
-				args = append(args, row[i])
-			} else {
-				args = append(args, nil)
-			}
-		}
+	source := newProgressCopySource(metricRows, progress)
+	inserted, err := tx.CopyFrom(ctx, pgx.Identifier{dealerMetricsTableName}, copyColumns, source)
+	if err != nil {
+		r.logger.Error("Failed to insert dealer metrics",
+			slog.Int("year", year),
+			slog.String("quarter", quarter),
+			slog.Int64("rows_inserted", inserted),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("DynamicTableRepository.InsertDealerMetrics: error copying rows: %w", err)
 	}
 
-	_, err := r.pool.Exec(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("failed to insert data into table %s: %w", tableName, err)
+	if progress != nil {
+		progress(int(inserted), len(rows))
 	}
 
-	r.logger.Info("Data inserted successfully",
-		slog.String("table", tableName),
-		slog.Int("rows", len(rows)),
+	r.logger.Info("Dealer metrics inserted successfully",
+		slog.Int("year", year),
+		slog.String("quarter", quarter),
+		slog.Int64("rows_inserted", inserted),
 	)
 
 	return nil
 }
 
-// TableExists проверяет существование таблицы.
-func (r *dynamicTableRepository) TableExists(ctx context.Context, tableName string) (bool, error) {
-	query := `
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_schema = 'public' 
-			AND table_name = $1
-		)
-	`
+// dealerMetricsCopyRows раскладывает строки загрузки по колонкам dealer_metrics и extra.
+func dealerMetricsCopyRows(period time.Time, year int, quarter string, columns []string, rows [][]interface{}) ([]string, [][]interface{}) {
+	copyColumns := []string{"period", "year", "quarter"}
+	var metricIndexes, extraIndexes []int
+	for i, column := range columns {
+		if model.IsDealerMetricColumn(column) {
+			copyColumns = append(copyColumns, column)
+			metricIndexes = append(metricIndexes, i)
+		} else {
+			extraIndexes = append(extraIndexes, i)
+		}
+	}
+	copyColumns = append(copyColumns, "extra")
 
-	var exists bool
-	err := r.pool.QueryRow(ctx, query, tableName).Scan(&exists)
-	if err != nil {
-		return false, fmt.Errorf("failed to check table existence: %w", err)
+	metricRows := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		values := make([]interface{}, 0, len(copyColumns))
+		values = append(values, period, year, quarter)
+		for _, index := range metricIndexes {
+			values = append(values, row[index])
+		}
+
+		extra := make(map[string]string, len(extraIndexes))
+		for _, index := range extraIndexes {
+			if value, ok := row[index].(string); ok && value != "" {
+				extra[columns[index]] = value
+			}
+		}
+		metricRows[i] = append(values, extra)
 	}
 
-	return exists, nil
+	return copyColumns, metricRows
 }
 
-// GetTableMetadata возвращает метаданные таблицы.
-func (r *dynamicTableRepository) GetTableMetadata(ctx context.Context, tableName string) (*model.ExcelTableMetadata, error) {
-	// Проверяем существование таблицы
-	exists, err := r.TableExists(ctx, tableName)
+// DealerMetricsPeriodExists проверяет, загружен ли квартал.
+func (r *dynamicTableRepository) DealerMetricsPeriodExists(ctx context.Context, year int, quarter string) (bool, error) {
+	period, err := model.QuarterStart(year, quarter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check table existence: %w", err)
+		return false, fmt.Errorf("DynamicTableRepository.DealerMetricsPeriodExists: %w", err)
 	}
 
-	if !exists {
-		return nil, fmt.Errorf("table %s does not exist", tableName)
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM " + dealerMetricsTableName + " WHERE period = $1)"
+	if err := r.pool.QueryRow(ctx, query, period).Scan(&exists); err != nil {
+		return false, fmt.Errorf("DynamicTableRepository.DealerMetricsPeriodExists: error querying: %w", err)
 	}
 
-	// Получаем количество строк
-	var rowsCount int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", r.sanitizeTableName(tableName))
-	err = r.pool.QueryRow(ctx, countQuery).Scan(&rowsCount)
+	return exists, nil
+}
+
+// GetDealerMetricsRows возвращает строки загрузки за квартал с пагинацией и их общее количество.
+// Каждая строка содержит заполненные колонки показателей и значения из extra.
+func (r *dynamicTableRepository) GetDealerMetricsRows(ctx context.Context, year int, quarter string, limit, offset int) ([]map[string]interface{}, int, error) {
+	period, err := model.QuarterStart(year, quarter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get row count: %w", err)
+		return nil, 0, fmt.Errorf("DynamicTableRepository.GetDealerMetricsRows: %w", err)
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM " + dealerMetricsTableName + " WHERE period = $1"
+	if err := r.pool.QueryRow(ctx, countQuery, period).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("DynamicTableRepository.GetDealerMetricsRows: error counting rows: %w", err)
 	}
 
-	// Получаем информацию о колонках
-	columnsQuery := `
-		SELECT column_name 
-		FROM information_schema.columns 
-		WHERE table_schema = 'public' 
-		AND table_name = $1 
-		AND column_name != 'id' 
-		AND column_name != 'created_at'
-		ORDER BY ordinal_position
-	`
+	query := `
+		SELECT jsonb_strip_nulls(to_jsonb(m) - $2::text[]) || m.extra || jsonb_build_object('id', m.id)
+		FROM ` + dealerMetricsTableName + ` m
+		WHERE period = $1
+		ORDER BY id
+		LIMIT $3 OFFSET $4`
 
-	rows, err := r.pool.Query(ctx, columnsQuery, tableName)
+	rows, err := r.pool.Query(ctx, query, period, dealerMetricsHiddenColumns, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get column information: %w", err)
+		return nil, 0, fmt.Errorf("DynamicTableRepository.GetDealerMetricsRows: error querying: %w", err)
 	}
 	defer rows.Close()
 
-	var columns []string
+	var result []map[string]interface{}
 	for rows.Next() {
-		var columnName string
-		if err := rows.Scan(&columnName); err != nil {
-			return nil, fmt.Errorf("failed to scan column name: %w", err)
+		var row map[string]interface{}
+		if err := rows.Scan(&row); err != nil {
+			return nil, 0, fmt.Errorf("DynamicTableRepository.GetDealerMetricsRows: error scanning: %w", err)
 		}
-		columns = append(columns, columnName)
+		result = append(result, row)
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating columns: %w", err)
+		return nil, 0, fmt.Errorf("DynamicTableRepository.GetDealerMetricsRows: error reading rows: %w", err)
 	}
 
-	return &model.ExcelTableMetadata{
-		TableName: tableName,
-		RowsCount: rowsCount,
-		CreatedAt: time.Now(),
-		Columns:   columns,
-	}, nil
+	return result, total, nil
 }
 
-// ListDynamicTables возвращает список всех динамически созданных таблиц.
-func (r *dynamicTableRepository) ListDynamicTables(ctx context.Context) ([]*model.ExcelTableMetadata, error) {
-	query := `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = 'public' 
-		AND table_name LIKE '%_q%_%'
-		ORDER BY table_name
-	`
-
-	rows, err := r.pool.Query(ctx, query)
+// ArchiveDealerMetricsPeriod переносит партицию квартала в схему archive под именем archiveTable.
+// Строки не удаляются: квартал можно восстановить, пока архив не удален по сроку хранения.
+// Нормализованные таблицы не меняются: загрузка - только исходные данные.
+func (r *dynamicTableRepository) ArchiveDealerMetricsPeriod(ctx context.Context, tx pgx.Tx, year int, quarter string, archiveTable string) error {
+	partition, err := dealerMetricsPartitionName(year, quarter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list dynamic tables: %w", err)
+		return fmt.Errorf("DynamicTableRepository.ArchiveDealerMetricsPeriod: %w", err)
 	}
-	defer rows.Close()
-
-	var tables []*model.ExcelTableMetadata
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return nil, fmt.Errorf("failed to scan table name: %w", err)
-		}
 
-		metadata, err := r.GetTableMetadata(ctx, tableName)
-		if err != nil {
-			r.logger.Warn("Failed to get metadata for table",
-				slog.String("table", tableName),
-				slog.String("error", err.Error()),
-			)
-			continue
-		}
-
-		tables = append(tables, metadata)
+	partitionIdent := pgx.Identifier{"public", partition}.Sanitize()
+	statements := []string{
+		"ALTER TABLE " + dealerMetricsTableName + " DETACH PARTITION " + partitionIdent,
+		"ALTER TABLE " + partitionIdent + " RENAME TO " + pgx.Identifier{archiveTable}.Sanitize(),
+		"ALTER TABLE " + pgx.Identifier{"public", archiveTable}.Sanitize() + " SET SCHEMA " + pgx.Identifier{dealerMetricsArchiveSchema}.Sanitize(),
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating tables: %w", err)
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("DynamicTableRepository.ArchiveDealerMetricsPeriod: error archiving partition %s: %w", partition, err)
+		}
 	}
 
-	return tables, nil
-}
+	r.logger.Info("Dealer metrics period archived",
+		slog.Int("year", year),
+		slog.String("quarter", quarter),
+		slog.String("archive_table", archiveTable),
+	)
 
-// BeginTransaction начинает транзакцию.
-func (r *dynamicTableRepository) BeginTransaction(ctx context.Context) (pgx.Tx, error) {
-	return r.pool.Begin(ctx)
+	return nil
 }
 
-// sanitizeTableName санитизирует название таблицы для безопасности.
-func (r *dynamicTableRepository) sanitizeTableName(name string) string {
-	// Приводим к нижнему регистру
-	name = strings.ToLower(name)
-
-	// Заменяем спецсимволы на underscore
-	name = strings.ReplaceAll(name, " ", "_")
-	name = strings.ReplaceAll(name, "-", "_")
-	name = strings.ReplaceAll(name, ".", "_")
-	name = strings.ReplaceAll(name, "/", "_")
-	name = strings.ReplaceAll(name, "\\", "_")
-
-	// Убираем множественные underscore
-	for strings.Contains(name, "__") {
-		name = strings.ReplaceAll(name, "__", "_")
+// RestoreDealerMetricsPeriod возвращает архив квартала в dealer_metrics.
+// Пустая партиция квартала заменяется архивом, партиция с данными - ErrAlreadyExists.
+func (r *dynamicTableRepository) RestoreDealerMetricsPeriod(ctx context.Context, tx pgx.Tx, year int, quarter string, archiveTable string) error {
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return fmt.Errorf("DynamicTableRepository.RestoreDealerMetricsPeriod: %w", err)
 	}
-
-	// Убираем начальные и конечные underscore
-	name = strings.Trim(name, "_")
-
-	// Ограничиваем длину
-	if len(name) > 63 {
-		name = name[:63]
+	partition, err := dealerMetricsPartitionName(year, quarter)
+	if err != nil {
+		return fmt.Errorf("DynamicTableRepository.RestoreDealerMetricsPeriod: %w", err)
 	}
 
-	return name
-}
-
-// sanitizeColumnName санитизирует название колонки для безопасности.
-func (r *dynamicTableRepository) sanitizeColumnName(name string) string {
-	// Приводим к нижнему регистру
-	name = strings.ToLower(name)
-
-	// Заменяем спецсимволы на underscore
-	name = strings.ReplaceAll(name, " ", "_")
-	name = strings.ReplaceAll(name, "-", "_")
-	name = strings.ReplaceAll(name, ".", "_")
-	name = strings.ReplaceAll(name, "/", "_")
-	name = strings.ReplaceAll(name, "\\", "_")
-	name = strings.ReplaceAll(name, "(", "_")
-	name = strings.ReplaceAll(name, ")", "_")
-	name = strings.ReplaceAll(name, "%", "_percent")
+	partitionIdent := pgx.Identifier{"public", partition}.Sanitize()
 
-	// Убираем множественные underscore
-	for strings.Contains(name, "__") {
-		name = strings.ReplaceAll(name, "__", "_")
+	var partitionExists bool
+	if err := tx.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", partitionIdent).Scan(&partitionExists); err != nil {
+		return fmt.Errorf("DynamicTableRepository.RestoreDealerMetricsPeriod: error checking partition: %w", err)
 	}
-
-	// Убираем начальные и конечные underscore
-	name = strings.Trim(name, "_")
-
-	// Если название пустое или начинается с цифры, добавляем префикс
-	if name == "" || (len(name) > 0 && name[0] >= '0' && name[0] <= '9') {
-		name = "column_" + name
+	if partitionExists {
+		var hasRows bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM "+partitionIdent+")").Scan(&hasRows); err != nil {
+			return fmt.Errorf("DynamicTableRepository.RestoreDealerMetricsPeriod: error checking partition rows: %w", err)
+		}
+		if hasRows {
+			return fmt.Errorf("DynamicTableRepository.RestoreDealerMetricsPeriod: period %d %s: %w", year, quarter, ErrAlreadyExists)
+		}
+		if _, err := tx.Exec(ctx, "DROP TABLE "+partitionIdent); err != nil {
+			return fmt.Errorf("DynamicTableRepository.RestoreDealerMetricsPeriod: error dropping empty partition: %w", err)
+		}
 	}
 
-	// Ограничиваем длину
-	if len(name) > 63 {
-		name = name[:63]
+	statements := []string{
+		"ALTER TABLE " + pgx.Identifier{dealerMetricsArchiveSchema, archiveTable}.Sanitize() + " SET SCHEMA public",
+		"ALTER TABLE " + pgx.Identifier{"public", archiveTable}.Sanitize() + " RENAME TO " + pgx.Identifier{partition}.Sanitize(),
+		fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')",
+			dealerMetricsTableName, partitionIdent, period.Format(time.DateOnly), period.AddDate(0, 3, 0).Format(time.DateOnly)),
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("DynamicTableRepository.RestoreDealerMetricsPeriod: error restoring %s: %w", archiveTable, err)
+		}
 	}
 
-	return name
-}
-
-// buildCreateTableQuery строит SQL запрос для создания таблицы.
-func (r *dynamicTableRepository) buildCreateTableQuery(tableName string, columns []model.DynamicTableColumn) string {
-	var columnDefs []string
+	r.logger.Info("Dealer metrics period restored",
+		slog.Int("year", year),
+		slog.String("quarter", quarter),
+		slog.String("archive_table", archiveTable),
+	)
 
-	// Добавляем стандартные колонки
-	columnDefs = append(columnDefs, "id BIGSERIAL PRIMARY KEY")
+	return nil
+}
 
-	// Добавляем колонки из схемы
-	for _, col := range columns {
-		columnDefs = append(columnDefs, fmt.Sprintf("%s TEXT", col.Name))
+// DropArchivedDealerMetrics окончательно удаляет архив квартала.
+func (r *dynamicTableRepository) DropArchivedDealerMetrics(ctx context.Context, tx pgx.Tx, archiveTable string) error {
+	if _, err := tx.Exec(ctx, "DROP TABLE IF EXISTS "+pgx.Identifier{dealerMetricsArchiveSchema, archiveTable}.Sanitize()); err != nil {
+		return fmt.Errorf("DynamicTableRepository.DropArchivedDealerMetrics: error dropping %s: %w", archiveTable, err)
 	}
 
-	// Добавляем колонку created_at
-	columnDefs = append(columnDefs, "created_at TIMESTAMP DEFAULT NOW()")
+	r.logger.Info("Archived dealer metrics purged", slog.String("archive_table", archiveTable))
 
-	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)",
-		tableName,
-		strings.Join(columnDefs, ",\n    "))
-
-	return query
+	return nil
 }
 
-// buildInsertQuery строит SQL запрос для вставки данных.
-func (r *dynamicTableRepository) buildInsertQuery(tableName string, columns []string, rowCount int) string {
-	// Создаем плейсхолдеры для значений
-	var placeholders []string
-	for i := 0; i < rowCount; i++ {
-		var rowPlaceholders []string
-		for j := 0; j < len(columns); j++ {
-			rowPlaceholders = append(rowPlaceholders, fmt.Sprintf("$%d", i*len(columns)+j+1))
-		}
-		placeholders = append(placeholders, fmt.Sprintf("(%s)", strings.Join(rowPlaceholders, ", ")))
+// dealerMetricsPartitionName название партиции квартала, как в ensure_dealer_metrics_partition.
+func dealerMetricsPartitionName(year int, quarter string) (string, error) {
+	if _, err := model.QuarterStart(year, quarter); err != nil {
+		return "", err
 	}
+	return fmt.Sprintf("%s_%d_%s", dealerMetricsTableName, year, strings.ToLower(quarter)), nil
+}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
-
-	return query
+// BeginTransaction начинает транзакцию.
+func (r *dynamicTableRepository) BeginTransaction(ctx context.Context) (pgx.Tx, error) {
+	return r.pool.Begin(ctx)
 }
 
-// UpdateDealerBrands обновляет бренды и побочные бизнесы дилеров.
+// UpdateDealerBrands обновляет бренды и побочные бизнесы дилеров в загрузке за квартал.
+// Если город не указан, дилер ищется только по названию.
 func (r *dynamicTableRepository) UpdateDealerBrands(ctx context.Context, tx pgx.Tx, year int, quarter string, updates []model.BrandsUpdate) (int, []string, error) {
-	tableName := r.GetDealerNetTableName(year, quarter)
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return 0, nil, fmt.Errorf("DynamicTableRepository.UpdateDealerBrands: %w", err)
+	}
+
+	query := `
+		UPDATE ` + dealerMetricsTableName + `
+		SET brands_in_portfolio = $1, byside_businesses = $2
+		WHERE period = $3 AND dealer = $4 AND ($5 = '' OR city = $5)`
 
 	var updatedCount int
 	var notFoundDealers []string
 
 	for _, update := range updates {
-		// Проверяем, существует ли дилер
-		exists, err := r.CheckDealerExists(ctx, year, quarter, update.DealerName, update.City)
-		if err != nil {
-			return 0, nil, fmt.Errorf("failed to check dealer existence: %w", err)
-		}
-
-		if !exists {
-			notFoundDealers = append(notFoundDealers, fmt.Sprintf("%s (%s)", update.DealerName, update.City))
-			continue
-		}
-
-		// Обновляем бренды и побочные бизнесы
-		// Если city пустой, обновляем только по dealer, иначе по dealer + city
-		var query string
-		var args []interface{}
-
-		if update.City == "" {
-			query = fmt.Sprintf("UPDATE %s SET brands_in_portfolio = $1, byside_businesses = $2 WHERE dealer = $3", tableName)
-			args = []interface{}{update.Brands, update.BysideBusinesses, update.DealerName}
-		} else {
-			query = fmt.Sprintf("UPDATE %s SET brands_in_portfolio = $1, byside_businesses = $2 WHERE dealer = $3 AND city = $4", tableName)
-			args = []interface{}{update.Brands, update.BysideBusinesses, update.DealerName, update.City}
-		}
-
-		_, err = tx.Exec(ctx, query, args...)
+		tag, err := tx.Exec(ctx, query, update.Brands, update.BysideBusinesses, period, update.DealerName, update.City)
 		if err != nil {
 			r.logger.Error("Failed to update dealer brands",
 				slog.String("dealer", update.DealerName),
 				slog.String("error", err.Error()),
 			)
-			return 0, nil, fmt.Errorf("failed to update dealer brands for %s: %w", update.DealerName, err)
+			return 0, nil, fmt.Errorf("DynamicTableRepository.UpdateDealerBrands: error updating %s: %w", update.DealerName, err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			notFoundDealers = append(notFoundDealers, fmt.Sprintf("%s (%s)", update.DealerName, update.City))
+			continue
 		}
 
 		updatedCount++
 	}
 
 	r.logger.Info("Updated dealer brands",
-		slog.String("table_name", tableName),
+		slog.Int("year", year),
+		slog.String("quarter", quarter),
 		slog.Int("updated_count", updatedCount),
 		slog.Int("not_found_count", len(notFoundDealers)),
 	)
@@ -533,28 +515,22 @@ func (r *dynamicTableRepository) UpdateDealerBrands(ctx context.Context, tx pgx.
 	return updatedCount, notFoundDealers, nil
 }
 
-// CheckDealerExists проверяет существование дилера в таблице.
+// CheckDealerExists проверяет, есть ли дилер в загрузке за квартал.
 func (r *dynamicTableRepository) CheckDealerExists(ctx context.Context, year int, quarter string, dealerName string, city string) (bool, error) {
-	tableName := r.GetDealerNetTableName(year, quarter)
-
-	// Если city пустой, ищем только по dealer
-	if city == "" {
-		query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE dealer = $1)", tableName)
-		var exists bool
-		err := r.pool.QueryRow(ctx, query, dealerName).Scan(&exists)
-		if err != nil {
-			return false, fmt.Errorf("failed to check dealer existence: %w", err)
-		}
-		return exists, nil
+	period, err := model.QuarterStart(year, quarter)
+	if err != nil {
+		return false, fmt.Errorf("DynamicTableRepository.CheckDealerExists: %w", err)
 	}
 
-	// Ищем по dealer AND city
-	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE dealer = $1 AND city = $2)", tableName)
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM ` + dealerMetricsTableName + `
+			WHERE period = $1 AND dealer = $2 AND ($3 = '' OR city = $3)
+		)`
 
 	var exists bool
-	err := r.pool.QueryRow(ctx, query, dealerName, city).Scan(&exists)
-	if err != nil {
-		return false, fmt.Errorf("failed to check dealer existence: %w", err)
+	if err := r.pool.QueryRow(ctx, query, period, dealerName, city).Scan(&exists); err != nil {
+		return false, fmt.Errorf("DynamicTableRepository.CheckDealerExists: error querying: %w", err)
 	}
 
 	return exists, nil
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// uniqueViolationCode код ошибки PostgreSQL при нарушении уникальности.
+	uniqueViolationCode = "23505"
+
+	// foreignKeyViolationCode код ошибки PostgreSQL при нарушении внешнего ключа.
+	foreignKeyViolationCode = "23503"
+)
+
+var (
+	// ErrNotFound запись не найдена.
+	ErrNotFound = errors.New("not found")
+
+	// ErrAlreadyExists запись с таким уникальным значением уже существует.
+	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrInvalidReference запись ссылается на несуществующую связанную запись (например, дилера).
+	ErrInvalidReference = errors.New("invalid reference")
+)
+
+// classifyError заменяет ошибки драйвера на ошибки репозитория, которые разбирают вызывающие слои.
+func classifyError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case uniqueViolationCode:
+			return ErrAlreadyExists
+		case foreignKeyViolationCode:
+			return ErrInvalidReference
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+// importDatasetsTableName каталог загруженных наборов данных.
+const importDatasetsTableName = "import_datasets"
+
+// ingestRequestsTableName ответы на запросы загрузки через API по Idempotency-Key.
+const ingestRequestsTableName = "ingest_requests"
+
+// importDatasetColumns колонки каталога в порядке сканирования.
+var importDatasetColumns = []string{
+	"id", "kind", "year", "quarter", "source_file", "row_count", "COALESCE(imported_by, '')",
+	"status", "schema", "COALESCE(error, '')", "deleted_at", "COALESCE(deleted_by, '')", "created_at", "updated_at",
+}
+
+// ImportCatalogRepository репозиторий каталога загрузок.
+type ImportCatalogRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	sq     squirrel.StatementBuilderType
+}
+
+// NewImportCatalogRepository создает новый экземпляр репозитория каталога загрузок.
+func NewImportCatalogRepository(pool *pgxpool.Pool, logger *slog.Logger) *ImportCatalogRepository {
+	return &ImportCatalogRepository{
+		pool:   pool,
+		logger: logger,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// RecordImport добавляет успешную загрузку в каталог в транзакции загрузки.
+// Загрузка dealer_net заменяет данные квартала, поэтому прежние записи квартала помечаются замененными.
+// Пакет через API дополняет квартал: замененными помечаются только прежние записи с данными квартала,
+// чтобы данные квартала по-прежнему адресовала одна запись, а файл брендов оставался актуальным.
+func (r *ImportCatalogRepository) RecordImport(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error {
+	if dataset.Kind.HoldsQuarterData() {
+		supersede := r.sq.Update(importDatasetsTableName).
+			Set("status", model.ImportStatusSuperseded).
+			Set("updated_at", squirrel.Expr("NOW()")).
+			Where(squirrel.Eq{
+				"year":    dataset.Year,
+				"quarter": dataset.Quarter,
+				"status":  model.ImportStatusCompleted,
+			})
+		if dataset.Kind == model.ImportKindIngest {
+			supersede = supersede.Where(squirrel.Eq{"kind": model.QuarterDataKinds})
+		}
+
+		sql, args, err := supersede.ToSql()
+		if err != nil {
+			return fmt.Errorf("ImportCatalogRepository.RecordImport: error building query: %w", err)
+		}
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf("ImportCatalogRepository.RecordImport: error superseding period: %w", err)
+		}
+	}
+
+	dataset.Status = model.ImportStatusCompleted
+	if err := r.insert(ctx, tx, dataset); err != nil {
+		return fmt.Errorf("ImportCatalogRepository.RecordImport: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFailedImport добавляет отклоненную загрузку в каталог.
+// Транзакция загрузки к этому моменту откатана, поэтому запись делается отдельно.
+func (r *ImportCatalogRepository) RecordFailedImport(ctx context.Context, dataset *model.ImportDataset) error {
+	dataset.Status = model.ImportStatusFailed
+	if err := r.insert(ctx, r.pool, dataset); err != nil {
+		return fmt.Errorf("ImportCatalogRepository.RecordFailedImport: %w", err)
+	}
+
+	return nil
+}
+
+// rowQuerier общий интерфейс пула и транзакции для запросов с одной строкой результата.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insert сохраняет запись каталога и заполняет ID и даты.
+func (r *ImportCatalogRepository) insert(ctx context.Context, q rowQuerier, dataset *model.ImportDataset) error {
+	query := r.sq.Insert(importDatasetsTableName).
+		Columns("kind", "year", "quarter", "source_file", "row_count", "imported_by", "status", "schema", "error").
+		Values(
+			dataset.Kind, dataset.Year, dataset.Quarter, dataset.SourceFile, dataset.RowCount,
+			squirrel.Expr("NULLIF(?, '')", dataset.ImportedBy), dataset.Status, dataset.Schema, squirrel.Expr("NULLIF(?, '')", dataset.Error),
+		).
+		Suffix("RETURNING id, created_at, updated_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	if err := q.QueryRow(ctx, sql, args...).Scan(&dataset.ID, &dataset.CreatedAt, &dataset.UpdatedAt); err != nil {
+		return fmt.Errorf("error inserting: %w", err)
+	}
+
+	return nil
+}
+
+// List возвращает записи каталога, начиная с последних загрузок.
+func (r *ImportCatalogRepository) List(ctx context.Context) ([]*model.ImportDataset, error) {
+	query := r.sq.Select(importDatasetColumns...).
+		From(importDatasetsTableName).
+		OrderBy("id DESC")
+
+	datasets, err := r.list(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ImportCatalogRepository.List: %w", err)
+	}
+
+	return datasets, nil
+}
+
+// list выполняет выборку записей каталога.
+func (r *ImportCatalogRepository) list(ctx context.Context, query squirrel.SelectBuilder) ([]*model.ImportDataset, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying: %w", err)
+	}
+	defer rows.Close()
+
+	var datasets []*model.ImportDataset
+	for rows.Next() {
+		dataset, err := scanImportDataset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		datasets = append(datasets, dataset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return datasets, nil
+}
+
+// GetByID возвращает запись каталога по ID.
+func (r *ImportCatalogRepository) GetByID(ctx context.Context, id int64) (*model.ImportDataset, error) {
+	query := r.sq.Select(importDatasetColumns...).
+		From(importDatasetsTableName).
+		Where(squirrel.Eq{"id": id})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("ImportCatalogRepository.GetByID: error building query: %w", err)
+	}
+
+	dataset, err := scanImportDataset(r.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		return nil, fmt.Errorf("ImportCatalogRepository.GetByID: %w", classifyError(err))
+	}
+
+	return dataset, nil
+}
+
+// MarkPeriodDeleted переносит актуальные записи квартала в корзину в транзакции удаления данных.
+// Вместе с загрузкой dealer_net удаляются и примененные к ней бренды; у всех записей одно время удаления.
+func (r *ImportCatalogRepository) MarkPeriodDeleted(ctx context.Context, tx pgx.Tx, year int, quarter string, deletedBy string) error {
+	query := r.sq.Update(importDatasetsTableName).
+		Set("status", model.ImportStatusDeleted).
+		Set("deleted_at", squirrel.Expr("NOW()")).
+		Set("deleted_by", squirrel.Expr("NULLIF(?, '')", deletedBy)).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{
+			"year":    year,
+			"quarter": quarter,
+			"status":  model.ImportStatusCompleted,
+		})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ImportCatalogRepository.MarkPeriodDeleted: error building query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("ImportCatalogRepository.MarkPeriodDeleted: error updating: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeleted возвращает загрузки с данными квартала в корзине, начиная с последних удаленных.
+func (r *ImportCatalogRepository) ListDeleted(ctx context.Context) ([]*model.ImportDataset, error) {
+	query := r.sq.Select(importDatasetColumns...).
+		From(importDatasetsTableName).
+		Where(squirrel.Eq{
+			"kind":   model.QuarterDataKinds,
+			"status": model.ImportStatusDeleted,
+		}).
+		Where("deleted_at IS NOT NULL").
+		OrderBy("deleted_at DESC", "id DESC")
+
+	datasets, err := r.list(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ImportCatalogRepository.ListDeleted: %w", err)
+	}
+
+	return datasets, nil
+}
+
+// ListExpired возвращает загрузки с данными квартала, удаленные раньше before.
+func (r *ImportCatalogRepository) ListExpired(ctx context.Context, before time.Time) ([]*model.ImportDataset, error) {
+	query := r.sq.Select(importDatasetColumns...).
+		From(importDatasetsTableName).
+		Where(squirrel.Eq{
+			"kind":   model.QuarterDataKinds,
+			"status": model.ImportStatusDeleted,
+		}).
+		Where(squirrel.Lt{"deleted_at": before}).
+		OrderBy("deleted_at")
+
+	datasets, err := r.list(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ImportCatalogRepository.ListExpired: %w", err)
+	}
+
+	return datasets, nil
+}
+
+// Restore возвращает из корзины записи квартала, удаленные вместе с набором.
+// Если у квартала уже есть актуальная загрузка с данными, возвращается ErrAlreadyExists.
+func (r *ImportCatalogRepository) Restore(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error {
+	query := r.sq.Update(importDatasetsTableName).
+		Set("status", model.ImportStatusCompleted).
+		Set("deleted_at", nil).
+		Set("deleted_by", nil).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{
+			"year":       dataset.Year,
+			"quarter":    dataset.Quarter,
+			"status":     model.ImportStatusDeleted,
+			"deleted_at": dataset.DeletedAt,
+		})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ImportCatalogRepository.Restore: error building query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("ImportCatalogRepository.Restore: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// MarkPurged помечает окончательно удаленными записи квартала, удаленные вместе с набором.
+func (r *ImportCatalogRepository) MarkPurged(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error {
+	query := r.sq.Update(importDatasetsTableName).
+		Set("status", model.ImportStatusPurged).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{
+			"year":       dataset.Year,
+			"quarter":    dataset.Quarter,
+			"status":     model.ImportStatusDeleted,
+			"deleted_at": dataset.DeletedAt,
+		})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ImportCatalogRepository.MarkPurged: error building query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("ImportCatalogRepository.MarkPurged: error updating: %w", err)
+	}
+
+	return nil
+}
+
+// GetIngestRequest возвращает сохраненный ответ на запрос загрузки через API по ключу идемпотентности.
+func (r *ImportCatalogRepository) GetIngestRequest(ctx context.Context, owner, key string) (*model.IngestRequest, error) {
+	query := r.sq.Select("owner", "idempotency_key", "request_hash", "response", "created_at").
+		From(ingestRequestsTableName).
+		Where(squirrel.Eq{"owner": owner, "idempotency_key": key})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("ImportCatalogRepository.GetIngestRequest: error building query: %w", err)
+	}
+
+	var request model.IngestRequest
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&request.Owner, &request.IdempotencyKey, &request.RequestHash, &request.Response, &request.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ImportCatalogRepository.GetIngestRequest: %w", classifyError(err))
+	}
+
+	return &request, nil
+}
+
+// RecordIngestRequest сохраняет ответ на запрос загрузки через API в транзакции загрузки.
+// Если ключ уже занят параллельным запросом, возвращается ErrAlreadyExists.
+func (r *ImportCatalogRepository) RecordIngestRequest(ctx context.Context, tx pgx.Tx, request *model.IngestRequest) error {
+	query := r.sq.Insert(ingestRequestsTableName).
+		Columns("owner", "idempotency_key", "request_hash", "response").
+		Values(request.Owner, request.IdempotencyKey, request.RequestHash, request.Response).
+		Suffix("RETURNING created_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ImportCatalogRepository.RecordIngestRequest: error building query: %w", err)
+	}
+
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&request.CreatedAt); err != nil {
+		return fmt.Errorf("ImportCatalogRepository.RecordIngestRequest: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// PurgeIngestRequests удаляет ключи идемпотентности, сохраненные раньше before.
+func (r *ImportCatalogRepository) PurgeIngestRequests(ctx context.Context, before time.Time) (int64, error) {
+	query := r.sq.Delete(ingestRequestsTableName).
+		Where(squirrel.Lt{"created_at": before})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("ImportCatalogRepository.PurgeIngestRequests: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("ImportCatalogRepository.PurgeIngestRequests: error deleting: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// scanImportDataset сканирует запись каталога в порядке importDatasetColumns.
+func scanImportDataset(row pgx.Row) (*model.ImportDataset, error) {
+	var dataset model.ImportDataset
+	err := row.Scan(
+		&dataset.ID, &dataset.Kind, &dataset.Year, &dataset.Quarter, &dataset.SourceFile, &dataset.RowCount,
+		&dataset.ImportedBy, &dataset.Status, &dataset.Schema, &dataset.Error, &dataset.DeletedAt, &dataset.DeletedBy,
+		&dataset.CreatedAt, &dataset.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &dataset, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const (
+	loginAttemptsTableName = "login_attempts"
+
+	defaultLoginAttemptsLimit = 100
+	maxLoginAttemptsLimit     = 1000
+)
+
+// LoginAttemptRepository репозиторий журнала попыток входа.
+type LoginAttemptRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	sq     squirrel.StatementBuilderType
+}
+
+// NewLoginAttemptRepository создает новый экземпляр репозитория журнала попыток входа.
+func NewLoginAttemptRepository(pool *pgxpool.Pool, logger *slog.Logger) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		pool:   pool,
+		logger: logger,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// RecordLoginAttempt сохраняет попытку входа.
+func (r *LoginAttemptRepository) RecordLoginAttempt(ctx context.Context, attempt model.LoginAttempt) error {
+	query := r.sq.Insert(loginAttemptsTableName).
+		Columns("login", "ip", "method", "success", "result").
+		Values(attempt.Login, squirrel.Expr("NULLIF(?, '')", attempt.IP), attempt.Method, attempt.Success, attempt.Result)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("LoginAttemptRepository.RecordLoginAttempt: error building query: %w", err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("LoginAttemptRepository.RecordLoginAttempt: error executing query: %w", err)
+	}
+
+	return nil
+}
+
+// ListLoginAttempts возвращает попытки входа, новые первыми.
+func (r *LoginAttemptRepository) ListLoginAttempts(ctx context.Context, filter model.LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	query := r.sq.Select("id", "login", "COALESCE(ip, '')", "method", "success", "result", "created_at").
+		From(loginAttemptsTableName).
+		OrderBy("created_at DESC", "id DESC")
+
+	if filter.Login != "" {
+		query = query.Where(squirrel.Eq{"login": filter.Login})
+	}
+	if filter.IP != "" {
+		query = query.Where(squirrel.Eq{"ip": filter.IP})
+	}
+	if filter.Success != nil {
+		query = query.Where(squirrel.Eq{"success": *filter.Success})
+	}
+	if filter.Since != nil {
+		query = query.Where(squirrel.GtOrEq{"created_at": *filter.Since})
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultLoginAttemptsLimit
+	}
+	if limit > maxLoginAttemptsLimit {
+		limit = maxLoginAttemptsLimit
+	}
+	query = query.Limit(uint64(limit))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("LoginAttemptRepository.ListLoginAttempts: error building query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("LoginAttemptRepository.ListLoginAttempts: error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := make([]model.LoginAttempt, 0)
+	for rows.Next() {
+		var attempt model.LoginAttempt
+		if err := rows.Scan(&attempt.ID, &attempt.Login, &attempt.IP, &attempt.Method, &attempt.Success, &attempt.Result, &attempt.CreatedAt); err != nil {
+			return nil, fmt.Errorf("LoginAttemptRepository.ListLoginAttempts: error scanning row: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("LoginAttemptRepository.ListLoginAttempts: error iterating rows: %w", err)
+	}
+
+	return attempts, nil
+}
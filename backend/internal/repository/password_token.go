@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const passwordTokensTableName = "password_tokens"
+
+// PasswordTokenRepository репозиторий ссылок для установки пароля.
+type PasswordTokenRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	sq     squirrel.StatementBuilderType
+}
+
+// NewPasswordTokenRepository создает новый экземпляр репозитория ссылок для установки пароля.
+func NewPasswordTokenRepository(pool *pgxpool.Pool, logger *slog.Logger) *PasswordTokenRepository {
+	return &PasswordTokenRepository{
+		pool:   pool,
+		logger: logger,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// CreatePasswordToken сохраняет ссылку и заполняет ID и дату создания. Неиспользованные ссылки
+// пользователя с тем же назначением удаляются: действует только последняя отправленная.
+func (r *PasswordTokenRepository) CreatePasswordToken(ctx context.Context, token *model.PasswordToken) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.CreatePasswordToken: error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	cleanup := r.sq.Delete(passwordTokensTableName).
+		Where(squirrel.Eq{"user_id": token.UserID, "purpose": token.Purpose, "used_at": nil})
+
+	sql, args, err := cleanup.ToSql()
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.CreatePasswordToken: error building cleanup query: %w", err)
+	}
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("PasswordTokenRepository.CreatePasswordToken: error deleting previous tokens: %w", err)
+	}
+
+	query := r.sq.Insert(passwordTokensTableName).
+		Columns("user_id", "purpose", "token_hash", "created_by", "expires_at").
+		Values(token.UserID, token.Purpose, token.TokenHash, squirrel.Expr("NULLIF(?, '')", token.CreatedBy), token.ExpiresAt).
+		Suffix("RETURNING id, created_at")
+
+	sql, args, err = query.ToSql()
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.CreatePasswordToken: error building query: %w", err)
+	}
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return fmt.Errorf("PasswordTokenRepository.CreatePasswordToken: error executing query: %w", classifyError(err))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("PasswordTokenRepository.CreatePasswordToken: error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetPasswordToken возвращает ссылку по хэшу токена вместе с логином пользователя.
+// Если ее нет, возвращается ErrNotFound.
+func (r *PasswordTokenRepository) GetPasswordToken(ctx context.Context, tokenHash string) (*model.PasswordToken, error) {
+	query := r.sq.Select(
+		"t.id", "t.user_id", "u.login", "t.purpose", "t.token_hash",
+		"COALESCE(t.created_by, '')", "t.created_at", "t.expires_at", "t.used_at",
+	).From(passwordTokensTableName + " t").
+		Join(usersTableName + " u ON u.id = t.user_id").
+		Where(squirrel.Eq{"t.token_hash": tokenHash})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("PasswordTokenRepository.GetPasswordToken: error building query: %w", err)
+	}
+
+	var token model.PasswordToken
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&token.ID, &token.UserID, &token.Login, &token.Purpose, &token.TokenHash,
+		&token.CreatedBy, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("PasswordTokenRepository.GetPasswordToken: %w", classifyError(err))
+	}
+
+	return &token, nil
+}
+
+// UsePasswordToken погашает ссылку и в той же транзакции устанавливает пароль (bcrypt-хэш) локальной
+// учетной записи, снимая блокировку входа. Остальные неиспользованные ссылки пользователя удаляются.
+// Если ссылка уже использована или истекла, возвращается ErrNotFound.
+func (r *PasswordTokenRepository) UsePasswordToken(ctx context.Context, id int64, password string, now time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	use := r.sq.Update(passwordTokensTableName).
+		Set("used_at", now).
+		Where(squirrel.Eq{"id": id, "used_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING user_id")
+
+	sql, args, err := use.ToSql()
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: error building query: %w", err)
+	}
+	var userID int64
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&userID); err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: token %d: %w", id, classifyError(err))
+	}
+
+	update := r.sq.Update(usersTableName).
+		Set("password", password).
+		Set("failed_login_count", 0).
+		Set("locked_until", nil).
+		Set("version", squirrel.Expr("version + 1")).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": userID, "auth_source": model.AuthSourceLocal})
+
+	sql, args, err = update.ToSql()
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: error building update query: %w", err)
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: error updating password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: user %d: %w", userID, ErrNotFound)
+	}
+
+	cleanup := r.sq.Delete(passwordTokensTableName).
+		Where(squirrel.Eq{"user_id": userID, "used_at": nil})
+
+	sql, args, err = cleanup.ToSql()
+	if err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: error building cleanup query: %w", err)
+	}
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: error deleting other tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("PasswordTokenRepository.UsePasswordToken: error committing transaction: %w", err)
+	}
+
+	return nil
+}
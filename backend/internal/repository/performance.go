@@ -3,9 +3,11 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/typefunco/dealer_dev_platform/internal/model"
 )
@@ -26,6 +28,27 @@ func NewPerformanceRepository(pool *pgxpool.Pool) *PerformanceRepository {
 	}
 }
 
+// performanceColumns колонки таблицы performance, которые соответствуют полям model.PerformanceSales.
+var performanceColumns = []string{
+	"id", "dealer_id", "quarter", "year",
+	"sales_revenue_rub", "sales_profit_rub", "sales_margin_percent", "sales_profit_percent",
+	"version", "created_at", "updated_at",
+}
+
+// scanPerformance сканирует строку с колонками performanceColumns.
+func scanPerformance(row pgx.Row) (*model.PerformanceSales, error) {
+	perf := &model.PerformanceSales{}
+	err := row.Scan(
+		&perf.ID, &perf.DealerID, &perf.Quarter, &perf.Year,
+		&perf.SalesRevenue, &perf.SalesMargin, &perf.SalesMarginPct, &perf.SalesProfitPct,
+		&perf.Version, &perf.CreatedAt, &perf.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return perf, nil
+}
+
 // Create создает новую запись производительности продаж.
 func (r *PerformanceRepository) Create(ctx context.Context, perf *model.PerformanceSales) (int64, error) {
 	now := time.Now()
@@ -35,19 +58,15 @@ func (r *PerformanceRepository) Create(ctx context.Context, perf *model.Performa
 	query := r.sq.Insert(performanceTableName).
 		Columns(
 			"dealer_id", "quarter", "year",
-			"sales_revenue_rub", "sales_profit_rub", "sales_profit_percent", "sales_margin_percent",
-			"after_sales_revenue_rub", "after_sales_profit_rub", "after_sales_margin_percent",
-			"marketing_investment", "foton_rank", "performance_decision",
+			"sales_revenue_rub", "sales_profit_rub", "sales_margin_percent", "sales_profit_percent",
 			"created_at", "updated_at",
 		).
 		Values(
 			perf.DealerID, perf.Quarter, perf.Year,
-			nil, nil, nil, nil, // sales fields - пока nil, так как структура изменилась
-			nil, nil, nil, // after sales fields - пока nil
-			nil, nil, nil, // marketing, rank, decision - пока nil
+			perf.SalesRevenue, perf.SalesMargin, perf.SalesMarginPct, perf.SalesProfitPct,
 			perf.CreatedAt, perf.UpdatedAt,
 		).
-		Suffix("RETURNING id")
+		Suffix("RETURNING id, version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -55,9 +74,9 @@ func (r *PerformanceRepository) Create(ctx context.Context, perf *model.Performa
 	}
 
 	var id int64
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id, &perf.Version)
 	if err != nil {
-		return 0, fmt.Errorf("PerformanceRepository.Create: error inserting: %w", err)
+		return 0, fmt.Errorf("PerformanceRepository.Create: error inserting: %w", classifyError(err))
 	}
 
 	perf.ID = int(id)
@@ -66,58 +85,27 @@ func (r *PerformanceRepository) Create(ctx context.Context, perf *model.Performa
 
 // GetByID получает запись производительности продаж по ID.
 func (r *PerformanceRepository) GetByID(ctx context.Context, id int64) (*model.PerformanceSales, error) {
-	query := r.sq.Select(
-		"id", "dealer_id", "period",
-		"quantity_sold", "sales_revenue", "sales_revenue_no_vat", "sales_cost",
-		"sales_margin", "sales_margin_pct", "sales_profit_pct",
-		"created_at", "updated_at",
-	).From(performanceTableName).Where(squirrel.Eq{"id": id})
+	query := r.sq.Select(performanceColumns...).From(performanceTableName).Where(squirrel.Eq{"id": id})
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("PerformanceRepository.GetByID: error building query: %w", err)
 	}
 
-	perf := &model.PerformanceSales{}
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(
-		&perf.ID, &perf.DealerID, &perf.Quarter, &perf.Year,
-		&perf.QuantitySold, &perf.SalesRevenue, &perf.SalesRevenueNoVat, &perf.SalesCost,
-		&perf.SalesMargin, &perf.SalesMarginPct, &perf.SalesProfitPct,
-		&perf.CreatedAt, &perf.UpdatedAt,
-	)
+	perf, err := scanPerformance(r.pool.QueryRow(ctx, sql, args...))
 	if err != nil {
-		return nil, fmt.Errorf("PerformanceRepository.GetByID: error scanning: %w", err)
+		return nil, fmt.Errorf("PerformanceRepository.GetByID: error scanning: %w", classifyError(err))
 	}
 
 	return perf, nil
 }
 
-// GetByDealerAndPeriod получает запись производительности продаж по дилеру и периоду.
+// GetByDealerAndPeriod получает запись производительности продаж по дилеру, кварталу и году.
 func (r *PerformanceRepository) GetByDealerAndPeriod(ctx context.Context, dealerID int64, quarter string, year int) (*model.PerformanceSales, error) {
-	// Преобразуем quarter/year в period
-	var month int
-	switch quarter {
-	case "q1":
-		month = 1
-	case "q2":
-		month = 4
-	case "q3":
-		month = 7
-	case "q4":
-		month = 10
-	default:
-		return nil, fmt.Errorf("invalid quarter: %s", quarter)
-	}
-	period := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-
-	query := r.sq.Select(
-		"id", "dealer_id", "period",
-		"quantity_sold", "sales_revenue", "sales_revenue_no_vat", "sales_cost",
-		"sales_margin", "sales_margin_pct", "sales_profit_pct",
-		"created_at", "updated_at",
-	).From(performanceTableName).Where(squirrel.Eq{
+	query := r.sq.Select(performanceColumns...).From(performanceTableName).Where(squirrel.Eq{
 		"dealer_id": dealerID,
-		"period":    period,
+		"quarter":   quarter,
+		"year":      year,
 	})
 
 	sql, args, err := query.ToSql()
@@ -125,15 +113,9 @@ func (r *PerformanceRepository) GetByDealerAndPeriod(ctx context.Context, dealer
 		return nil, fmt.Errorf("PerformanceRepository.GetByDealerAndPeriod: error building query: %w", err)
 	}
 
-	perf := &model.PerformanceSales{}
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(
-		&perf.ID, &perf.DealerID, &perf.Quarter, &perf.Year,
-		&perf.QuantitySold, &perf.SalesRevenue, &perf.SalesRevenueNoVat, &perf.SalesCost,
-		&perf.SalesMargin, &perf.SalesMarginPct, &perf.SalesProfitPct,
-		&perf.CreatedAt, &perf.UpdatedAt,
-	)
+	perf, err := scanPerformance(r.pool.QueryRow(ctx, sql, args...))
 	if err != nil {
-		return nil, fmt.Errorf("PerformanceRepository.GetByDealerAndPeriod: error scanning: %w", err)
+		return nil, fmt.Errorf("PerformanceRepository.GetByDealerAndPeriod: error scanning: %w", classifyError(err))
 	}
 
 	return perf, nil
@@ -141,28 +123,10 @@ func (r *PerformanceRepository) GetByDealerAndPeriod(ctx context.Context, dealer
 
 // GetAllByPeriod получает все записи производительности продаж за указанный период.
 func (r *PerformanceRepository) GetAllByPeriod(ctx context.Context, quarter string, year int) ([]*model.PerformanceSales, error) {
-	// Преобразуем quarter/year в period
-	var month int
-	switch quarter {
-	case "q1":
-		month = 1
-	case "q2":
-		month = 4
-	case "q3":
-		month = 7
-	case "q4":
-		month = 10
-	default:
-		return nil, fmt.Errorf("invalid quarter: %s", quarter)
-	}
-	period := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-
-	query := r.sq.Select(
-		"id", "dealer_id", "period",
-		"quantity_sold", "sales_revenue", "sales_revenue_no_vat", "sales_cost",
-		"sales_margin", "sales_margin_pct", "sales_profit_pct",
-		"created_at", "updated_at",
-	).From(performanceTableName).Where(squirrel.Eq{"period": period})
+	query := r.sq.Select(performanceColumns...).From(performanceTableName).Where(squirrel.Eq{
+		"quarter": strings.ToUpper(quarter),
+		"year":    year,
+	})
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -177,13 +141,7 @@ func (r *PerformanceRepository) GetAllByPeriod(ctx context.Context, quarter stri
 
 	var performances []*model.PerformanceSales
 	for rows.Next() {
-		perf := &model.PerformanceSales{}
-		err = rows.Scan(
-			&perf.ID, &perf.DealerID, &perf.Quarter, &perf.Year,
-			&perf.QuantitySold, &perf.SalesRevenue, &perf.SalesRevenueNoVat, &perf.SalesCost,
-			&perf.SalesMargin, &perf.SalesMarginPct, &perf.SalesProfitPct,
-			&perf.CreatedAt, &perf.UpdatedAt,
-		)
+		perf, err := scanPerformance(rows)
 		if err != nil {
 			return nil, fmt.Errorf("PerformanceRepository.GetAllByPeriod: error scanning: %w", err)
 		}
@@ -194,6 +152,7 @@ func (r *PerformanceRepository) GetAllByPeriod(ctx context.Context, quarter stri
 }
 
 // UpdateFull обновляет всю запись производительности продаж целиком.
+// Если версия записи задана, обновление выполняется только при совпадении с текущей версией в БД.
 func (r *PerformanceRepository) UpdateFull(ctx context.Context, perf *model.PerformanceSales) error {
 	perf.UpdatedAt = time.Now()
 
@@ -201,24 +160,24 @@ func (r *PerformanceRepository) UpdateFull(ctx context.Context, perf *model.Perf
 		Set("dealer_id", perf.DealerID).
 		Set("quarter", perf.Quarter).
 		Set("year", perf.Year).
-		Set("quantity_sold", perf.QuantitySold).
-		Set("sales_revenue", perf.SalesRevenue).
-		Set("sales_revenue_no_vat", perf.SalesRevenueNoVat).
-		Set("sales_cost", perf.SalesCost).
-		Set("sales_margin", perf.SalesMargin).
-		Set("sales_margin_pct", perf.SalesMarginPct).
-		Set("sales_profit_pct", perf.SalesProfitPct).
+		Set("sales_revenue_rub", perf.SalesRevenue).
+		Set("sales_profit_rub", perf.SalesMargin).
+		Set("sales_margin_percent", perf.SalesMarginPct).
+		Set("sales_profit_percent", perf.SalesProfitPct).
 		Set("updated_at", perf.UpdatedAt).
-		Where(squirrel.Eq{"id": perf.ID})
+		Set("version", nextVersion).
+		Where(versionCondition(perf.ID, perf.Version)).
+		Suffix("RETURNING version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("PerformanceRepository.UpdateFull: error building query: %w", err)
 	}
 
-	_, err = r.pool.Exec(ctx, sql, args...)
+	version := perf.Version
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&perf.Version)
 	if err != nil {
-		return fmt.Errorf("PerformanceRepository.UpdateFull: error updating: %w", err)
+		return fmt.Errorf("PerformanceRepository.UpdateFull: record with id %d: %w", perf.ID, versionedWriteError(err, version))
 	}
 
 	return nil
@@ -269,8 +228,8 @@ func (r *PerformanceRepository) GetWithDetailsByPeriod(ctx context.Context, quar
 }
 
 // Delete удаляет запись производительности продаж.
-func (r *PerformanceRepository) Delete(ctx context.Context, id int64) error {
-	query := r.sq.Delete(performanceTableName).Where(squirrel.Eq{"id": id})
+func (r *PerformanceRepository) Delete(ctx context.Context, id int64, version int) error {
+	query := r.sq.Delete(performanceTableName).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -279,24 +238,26 @@ func (r *PerformanceRepository) Delete(ctx context.Context, id int64) error {
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("PerformanceRepository.Delete: error deleting: %w", err)
+		return fmt.Errorf("PerformanceRepository.Delete: error deleting: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("PerformanceRepository.Delete: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("PerformanceRepository.Delete: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
 }
 
-// Update обновляет данные производительности продаж.
-func (r *PerformanceRepository) Update(ctx context.Context, id int64, updates map[string]interface{}) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+// Update обновляет данные производительности продаж по проверенному набору изменений.
+func (r *PerformanceRepository) Update(ctx context.Context, id int64, version int, patch model.Patch) error {
+	if patch.IsEmpty() {
+		return fmt.Errorf("PerformanceRepository.Update: %w", model.ErrEmptyPatch)
 	}
 
+	updates := patch.Columns()
 	updates["updated_at"] = time.Now()
-	query := r.sq.Update(performanceTableName).SetMap(updates).Where(squirrel.Eq{"id": id})
+	updates["version"] = nextVersion
+	query := r.sq.Update(performanceTableName).SetMap(updates).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -305,11 +266,11 @@ func (r *PerformanceRepository) Update(ctx context.Context, id int64, updates ma
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("PerformanceRepository.Update: error updating: %w", err)
+		return fmt.Errorf("PerformanceRepository.Update: error updating: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("PerformanceRepository.Update: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("PerformanceRepository.Update: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const (
+	regionsTableName       = "regions"
+	regionAliasesTableName = "region_aliases"
+)
+
+// RegionRepository репозиторий справочника регионов.
+type RegionRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	sq     squirrel.StatementBuilderType
+}
+
+// NewRegionRepository создает новый экземпляр репозитория регионов.
+func NewRegionRepository(pool *pgxpool.Pool, logger *slog.Logger) *RegionRepository {
+	return &RegionRepository{
+		pool:   pool,
+		logger: logger,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// ListRegions возвращает все регионы вместе с алиасами.
+func (r *RegionRepository) ListRegions(ctx context.Context) ([]model.Region, error) {
+	query := r.sq.Select(
+		"r.id", "r.code", "r.name", "r.name_ru",
+		"COALESCE(array_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}')",
+	).
+		From(regionsTableName+" r").
+		LeftJoin(regionAliasesTableName+" a ON a.region_id = r.id").
+		GroupBy("r.id", "r.code", "r.name", "r.name_ru").
+		OrderBy("r.id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("RegionRepository.ListRegions: error building query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("RegionRepository.ListRegions: error querying: %w", err)
+	}
+	defer rows.Close()
+
+	var regions []model.Region
+	for rows.Next() {
+		var region model.Region
+		if err := rows.Scan(&region.ID, &region.Code, &region.Name, &region.NameRu, &region.Aliases); err != nil {
+			return nil, fmt.Errorf("RegionRepository.ListRegions: error scanning row: %w", err)
+		}
+		regions = append(regions, region)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("RegionRepository.ListRegions: error iterating rows: %w", err)
+	}
+
+	return regions, nil
+}
+
+// GetRegionByCode возвращает регион по коду.
+func (r *RegionRepository) GetRegionByCode(ctx context.Context, code string) (*model.Region, error) {
+	query := r.sq.Select("id", "code", "name", "name_ru").
+		From(regionsTableName).
+		Where(squirrel.Eq{"code": code})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("RegionRepository.GetRegionByCode: error building query: %w", err)
+	}
+
+	var region model.Region
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&region.ID, &region.Code, &region.Name, &region.NameRu)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("RegionRepository.GetRegionByCode: region %s: %w", code, ErrNotFound)
+		}
+		return nil, fmt.Errorf("RegionRepository.GetRegionByCode: error scanning row: %w", err)
+	}
+
+	return &region, nil
+}
+
+// AddAlias добавляет алиас региону.
+func (r *RegionRepository) AddAlias(ctx context.Context, regionID int64, alias string) (*model.RegionAlias, error) {
+	query := r.sq.Insert(regionAliasesTableName).
+		Columns("region_id", "alias").
+		Values(regionID, alias).
+		Suffix("RETURNING id, region_id, alias, created_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("RegionRepository.AddAlias: error building query: %w", err)
+	}
+
+	var created model.RegionAlias
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&created.ID, &created.RegionID, &created.Alias, &created.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return nil, fmt.Errorf("RegionRepository.AddAlias: alias %s: %w", alias, ErrAlreadyExists)
+		}
+		return nil, fmt.Errorf("RegionRepository.AddAlias: error executing query: %w", err)
+	}
+
+	r.logger.Info("Region alias added",
+		slog.Int64("region_id", regionID),
+		slog.String("alias", alias),
+	)
+
+	return &created, nil
+}
+
+// DeleteAlias удаляет алиас региона.
+func (r *RegionRepository) DeleteAlias(ctx context.Context, regionID int64, alias string) error {
+	query := r.sq.Delete(regionAliasesTableName).
+		Where(squirrel.Eq{"region_id": regionID}).
+		Where(squirrel.Expr("LOWER(alias) = LOWER(?)", alias))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("RegionRepository.DeleteAlias: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("RegionRepository.DeleteAlias: error executing query: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("RegionRepository.DeleteAlias: alias %s: %w", alias, ErrNotFound)
+	}
+
+	r.logger.Info("Region alias deleted",
+		slog.Int64("region_id", regionID),
+		slog.String("alias", alias),
+	)
+
+	return nil
+}
@@ -47,7 +47,7 @@ func (r *SalesRepository) Create(ctx context.Context, sales *model.Sales) (int64
 			sales.FotonSalesmen, sales.ServiceContractsSales, sales.SalesTrainings, sales.SalesDecision,
 			sales.CreatedAt, sales.UpdatedAt,
 		).
-		Suffix("RETURNING id")
+		Suffix("RETURNING id, version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -55,9 +55,9 @@ func (r *SalesRepository) Create(ctx context.Context, sales *model.Sales) (int64
 	}
 
 	var id int64
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&id, &sales.Version)
 	if err != nil {
-		return 0, fmt.Errorf("SalesRepository.Create: error inserting: %w", err)
+		return 0, fmt.Errorf("SalesRepository.Create: error inserting: %w", classifyError(err))
 	}
 
 	sales.ID = int(id)
@@ -71,7 +71,7 @@ func (r *SalesRepository) GetByID(ctx context.Context, id int64) (*model.Sales,
 		"sales_target", "stock_hdt", "stock_mdt", "stock_ldt",
 		"buyout_hdt", "buyout_mdt", "buyout_ldt",
 		"foton_salesmen", "service_contracts_sales", "sales_trainings", "sales_decision",
-		"created_at", "updated_at",
+		"version", "created_at", "updated_at",
 	).From(salesTableName).Where(squirrel.Eq{"id": id})
 
 	sql, args, err := query.ToSql()
@@ -85,10 +85,10 @@ func (r *SalesRepository) GetByID(ctx context.Context, id int64) (*model.Sales,
 		&sales.SalesTarget, &sales.StockHDT, &sales.StockMDT, &sales.StockLDT,
 		&sales.BuyoutHDT, &sales.BuyoutMDT, &sales.BuyoutLDT,
 		&sales.FotonSalesmen, &sales.ServiceContractsSales, &sales.SalesTrainings, &sales.SalesDecision,
-		&sales.CreatedAt, &sales.UpdatedAt,
+		&sales.Version, &sales.CreatedAt, &sales.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("SalesRepository.GetByID: error scanning: %w", err)
+		return nil, fmt.Errorf("SalesRepository.GetByID: error scanning: %w", classifyError(err))
 	}
 
 	return sales, nil
@@ -113,7 +113,7 @@ func (r *SalesRepository) GetByDealerAndPeriodTime(ctx context.Context, dealerID
 		"sales_target", "stock_hdt", "stock_mdt", "stock_ldt",
 		"buyout_hdt", "buyout_mdt", "buyout_ldt",
 		"foton_salesmen", "service_contracts_sales", "sales_trainings", "sales_decision",
-		"created_at", "updated_at",
+		"version", "created_at", "updated_at",
 	).From(salesTableName).Where(squirrel.Eq{
 		"dealer_id": dealerID,
 		"quarter":   quarter,
@@ -131,10 +131,10 @@ func (r *SalesRepository) GetByDealerAndPeriodTime(ctx context.Context, dealerID
 		&sales.SalesTarget, &sales.StockHDT, &sales.StockMDT, &sales.StockLDT,
 		&sales.BuyoutHDT, &sales.BuyoutMDT, &sales.BuyoutLDT,
 		&sales.FotonSalesmen, &sales.ServiceContractsSales, &sales.SalesTrainings, &sales.SalesDecision,
-		&sales.CreatedAt, &sales.UpdatedAt,
+		&sales.Version, &sales.CreatedAt, &sales.UpdatedAt,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("SalesRepository.GetByDealerAndPeriod: error scanning: %w", err)
+		return nil, fmt.Errorf("SalesRepository.GetByDealerAndPeriod: error scanning: %w", classifyError(err))
 	}
 
 	return sales, nil
@@ -193,6 +193,7 @@ func (r *SalesRepository) GetAllByPeriodTime(ctx context.Context, quarter string
 }
 
 // UpdateFull обновляет всю запись продаж целиком.
+// Если версия записи задана, обновление выполняется только при совпадении с текущей версией в БД.
 func (r *SalesRepository) UpdateFull(ctx context.Context, sales *model.Sales) error {
 	sales.UpdatedAt = time.Now()
 
@@ -212,28 +213,27 @@ func (r *SalesRepository) UpdateFull(ctx context.Context, sales *model.Sales) er
 		Set("sales_trainings", sales.SalesTrainings).
 		Set("sales_decision", sales.SalesDecision).
 		Set("updated_at", sales.UpdatedAt).
-		Where(squirrel.Eq{"id": sales.ID})
+		Set("version", nextVersion).
+		Where(versionCondition(sales.ID, sales.Version)).
+		Suffix("RETURNING version")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("SalesRepository.UpdateFull: error building query: %w", err)
 	}
 
-	result, err := r.pool.Exec(ctx, sql, args...)
+	version := sales.Version
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&sales.Version)
 	if err != nil {
-		return fmt.Errorf("SalesRepository.UpdateFull: error updating: %w", err)
-	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("SalesRepository.UpdateFull: no rows affected, record with id %d not found", sales.ID)
+		return fmt.Errorf("SalesRepository.UpdateFull: record with id %d: %w", sales.ID, versionedWriteError(err, version))
 	}
 
 	return nil
 }
 
 // Delete удаляет запись продаж.
-func (r *SalesRepository) Delete(ctx context.Context, id int64) error {
-	query := r.sq.Delete(salesTableName).Where(squirrel.Eq{"id": id})
+func (r *SalesRepository) Delete(ctx context.Context, id int64, version int) error {
+	query := r.sq.Delete(salesTableName).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -242,24 +242,26 @@ func (r *SalesRepository) Delete(ctx context.Context, id int64) error {
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("SalesRepository.Delete: error deleting: %w", err)
+		return fmt.Errorf("SalesRepository.Delete: error deleting: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("SalesRepository.Delete: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("SalesRepository.Delete: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
 }
 
-// Update обновляет данные продаж.
-func (r *SalesRepository) Update(ctx context.Context, id int64, updates map[string]interface{}) error {
-	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+// Update обновляет данные продаж по проверенному набору изменений.
+func (r *SalesRepository) Update(ctx context.Context, id int64, version int, patch model.Patch) error {
+	if patch.IsEmpty() {
+		return fmt.Errorf("SalesRepository.Update: %w", model.ErrEmptyPatch)
 	}
 
+	updates := patch.Columns()
 	updates["updated_at"] = time.Now()
-	query := r.sq.Update(salesTableName).SetMap(updates).Where(squirrel.Eq{"id": id})
+	updates["version"] = nextVersion
+	query := r.sq.Update(salesTableName).SetMap(updates).Where(versionCondition(id, version))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -268,11 +270,11 @@ func (r *SalesRepository) Update(ctx context.Context, id int64, updates map[stri
 
 	result, err := r.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("SalesRepository.Update: error updating: %w", err)
+		return fmt.Errorf("SalesRepository.Update: error updating: %w", classifyError(err))
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("SalesRepository.Update: no rows affected, record with id %d not found", id)
+		return fmt.Errorf("SalesRepository.Update: record with id %d: %w", id, noRowsError(version))
 	}
 
 	return nil
@@ -298,17 +300,19 @@ func (r *SalesRepository) GetWithDetailsByPeriodTime(ctx context.Context, quarte
 		"s.sales_target", "s.stock_hdt", "s.stock_mdt", "s.stock_ldt",
 		"s.buyout_hdt", "s.buyout_mdt", "s.buyout_ldt",
 		"s.foton_salesmen", "s.service_contracts_sales", "s.sales_trainings", "s.sales_decision",
-		"s.created_at", "s.updated_at",
+		"s.version", "s.created_at", "s.updated_at",
 		"d.name", "d.name", "d.city", "d.region", "d.manager", "d.name",
 	).
 		From(salesTableName + " s").
 		Join("dealers d ON s.dealer_id = d.id").
 		Where(squirrel.Eq{"s.quarter": quarter, "s.year": year})
 
-	if region != "all-russia" {
+	if region != "" && !model.IsAllRussia(region) {
 		queryBuilder = queryBuilder.Where(squirrel.Eq{"d.region": region})
 	}
 
+	queryBuilder = queryBuilder.OrderBy("d.name")
+
 	sql, args, err := queryBuilder.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("SalesRepository.GetWithDetailsByPeriodTime: error building query: %w", err)
@@ -328,7 +332,7 @@ func (r *SalesRepository) GetWithDetailsByPeriodTime(ctx context.Context, quarte
 			&swd.SalesTarget, &swd.StockHDT, &swd.StockMDT, &swd.StockLDT,
 			&swd.BuyoutHDT, &swd.BuyoutMDT, &swd.BuyoutLDT,
 			&swd.FotonSalesmen, &swd.ServiceContractsSales, &swd.SalesTrainings, &swd.SalesDecision,
-			&swd.CreatedAt, &swd.UpdatedAt,
+			&swd.Version, &swd.CreatedAt, &swd.UpdatedAt,
 			&swd.DealerNameRu, &swd.DealerNameEn, &swd.City, &swd.Region, &swd.Manager, &swd.Ruft,
 		)
 		if err != nil {
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const (
+	serviceAccountsTableName = "service_accounts"
+	apiKeysTableName         = "api_keys"
+)
+
+// serviceAccountColumns колонки сервисной учетной записи в порядке сканирования.
+var serviceAccountColumns = []string{
+	"id", "name", "COALESCE(description, '')", "is_admin", "role", "COALESCE(created_by, '')", "created_at", "updated_at",
+}
+
+// apiKeyColumns колонки ключа API в порядке сканирования.
+var apiKeyColumns = []string{
+	"id", "service_account_id", "name", "prefix", "key_hash", "allowed_ips", "scopes", "expires_at", "last_used_at",
+	"COALESCE(last_used_ip, '')", "revoked_at", "COALESCE(revoked_by, '')", "COALESCE(created_by, '')", "created_at",
+}
+
+// ServiceAccountRepository репозиторий сервисных учетных записей и их ключей API.
+type ServiceAccountRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	sq     squirrel.StatementBuilderType
+}
+
+// NewServiceAccountRepository создает новый экземпляр репозитория сервисных учетных записей.
+func NewServiceAccountRepository(pool *pgxpool.Pool, logger *slog.Logger) *ServiceAccountRepository {
+	return &ServiceAccountRepository{
+		pool:   pool,
+		logger: logger,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// CreateServiceAccount сохраняет учетную запись и заполняет ID и даты.
+// Если имя занято, возвращается ErrAlreadyExists.
+func (r *ServiceAccountRepository) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error {
+	query := r.sq.Insert(serviceAccountsTableName).
+		Columns("name", "description", "is_admin", "role", "created_by").
+		Values(
+			account.Name, squirrel.Expr("NULLIF(?, '')", account.Description), account.IsAdmin, account.Role,
+			squirrel.Expr("NULLIF(?, '')", account.CreatedBy),
+		).
+		Suffix("RETURNING id, created_at, updated_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ServiceAccountRepository.CreateServiceAccount: error building query: %w", err)
+	}
+
+	if err := r.pool.QueryRow(ctx, sql, args...).Scan(&account.ID, &account.CreatedAt, &account.UpdatedAt); err != nil {
+		return fmt.Errorf("ServiceAccountRepository.CreateServiceAccount: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// ListServiceAccounts возвращает учетные записи по имени.
+func (r *ServiceAccountRepository) ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	query := r.sq.Select(serviceAccountColumns...).
+		From(serviceAccountsTableName).
+		OrderBy("name")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.ListServiceAccounts: error building query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.ListServiceAccounts: error querying: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*model.ServiceAccount
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAccountRepository.ListServiceAccounts: error scanning row: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.ListServiceAccounts: error iterating rows: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// GetServiceAccount возвращает учетную запись по ID.
+func (r *ServiceAccountRepository) GetServiceAccount(ctx context.Context, id int64) (*model.ServiceAccount, error) {
+	query := r.sq.Select(serviceAccountColumns...).
+		From(serviceAccountsTableName).
+		Where(squirrel.Eq{"id": id})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.GetServiceAccount: error building query: %w", err)
+	}
+
+	account, err := scanServiceAccount(r.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.GetServiceAccount: %w", classifyError(err))
+	}
+
+	return account, nil
+}
+
+// DeleteServiceAccount удаляет учетную запись вместе с ключами.
+func (r *ServiceAccountRepository) DeleteServiceAccount(ctx context.Context, id int64) error {
+	query := r.sq.Delete(serviceAccountsTableName).Where(squirrel.Eq{"id": id})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ServiceAccountRepository.DeleteServiceAccount: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("ServiceAccountRepository.DeleteServiceAccount: error deleting: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("ServiceAccountRepository.DeleteServiceAccount: %w", ErrNotFound)
+	}
+
+	return nil
+}
+
+// CreateAPIKey сохраняет ключ и заполняет ID и дату создания.
+func (r *ServiceAccountRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	allowedIPs := key.AllowedIPs
+	if allowedIPs == nil {
+		allowedIPs = []string{}
+	}
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	query := r.sq.Insert(apiKeysTableName).
+		Columns("service_account_id", "name", "prefix", "key_hash", "allowed_ips", "scopes", "expires_at", "created_by").
+		Values(
+			key.ServiceAccountID, key.Name, key.Prefix, key.Hash, allowedIPs, scopes, key.ExpiresAt,
+			squirrel.Expr("NULLIF(?, '')", key.CreatedBy),
+		).
+		Suffix("RETURNING id, created_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ServiceAccountRepository.CreateAPIKey: error building query: %w", err)
+	}
+
+	if err := r.pool.QueryRow(ctx, sql, args...).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return fmt.Errorf("ServiceAccountRepository.CreateAPIKey: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// ListAPIKeys возвращает ключи учетной записи, начиная с последних выпущенных.
+func (r *ServiceAccountRepository) ListAPIKeys(ctx context.Context, accountID int64) ([]*model.APIKey, error) {
+	query := r.sq.Select(apiKeyColumns...).
+		From(apiKeysTableName).
+		Where(squirrel.Eq{"service_account_id": accountID}).
+		OrderBy("id DESC")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.ListAPIKeys: error building query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.ListAPIKeys: error querying: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceAccountRepository.ListAPIKeys: error scanning row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.ListAPIKeys: error iterating rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// GetAPIKeyByPrefix возвращает ключ по открытому префиксу вместе с учетной записью.
+func (r *ServiceAccountRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKeyPrincipal, error) {
+	key, err := r.getAPIKey(ctx, squirrel.Eq{"prefix": prefix})
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.GetAPIKeyByPrefix: %w", err)
+	}
+
+	account, err := r.GetServiceAccount(ctx, key.ServiceAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.GetAPIKeyByPrefix: %w", err)
+	}
+
+	return &model.APIKeyPrincipal{Account: account, Key: key}, nil
+}
+
+// RevokeAPIKey отзывает ключ учетной записи; повторный отзыв не меняет время и автора отзыва.
+func (r *ServiceAccountRepository) RevokeAPIKey(ctx context.Context, accountID, keyID int64, revokedBy string) (*model.APIKey, error) {
+	query := r.sq.Update(apiKeysTableName).
+		Set("revoked_at", squirrel.Expr("COALESCE(revoked_at, NOW())")).
+		Set("revoked_by", squirrel.Expr("CASE WHEN revoked_at IS NULL THEN NULLIF(?, '') ELSE revoked_by END", revokedBy)).
+		Where(squirrel.Eq{"id": keyID, "service_account_id": accountID})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.RevokeAPIKey: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.RevokeAPIKey: error updating: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, fmt.Errorf("ServiceAccountRepository.RevokeAPIKey: %w", ErrNotFound)
+	}
+
+	key, err := r.getAPIKey(ctx, squirrel.Eq{"id": keyID})
+	if err != nil {
+		return nil, fmt.Errorf("ServiceAccountRepository.RevokeAPIKey: %w", err)
+	}
+
+	return key, nil
+}
+
+// TouchAPIKey запоминает время и адрес последнего входа по ключу.
+func (r *ServiceAccountRepository) TouchAPIKey(ctx context.Context, keyID int64, ip string) error {
+	query := r.sq.Update(apiKeysTableName).
+		Set("last_used_at", squirrel.Expr("NOW()")).
+		Set("last_used_ip", squirrel.Expr("NULLIF(?, '')", ip)).
+		Where(squirrel.Eq{"id": keyID})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("ServiceAccountRepository.TouchAPIKey: error building query: %w", err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("ServiceAccountRepository.TouchAPIKey: error updating: %w", err)
+	}
+
+	return nil
+}
+
+// getAPIKey возвращает один ключ по условию.
+func (r *ServiceAccountRepository) getAPIKey(ctx context.Context, where squirrel.Eq) (*model.APIKey, error) {
+	query := r.sq.Select(apiKeyColumns...).
+		From(apiKeysTableName).
+		Where(where)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	return key, nil
+}
+
+// scanServiceAccount сканирует учетную запись в порядке serviceAccountColumns.
+func scanServiceAccount(row pgx.Row) (*model.ServiceAccount, error) {
+	var account model.ServiceAccount
+	err := row.Scan(
+		&account.ID, &account.Name, &account.Description, &account.IsAdmin, &account.Role, &account.CreatedBy,
+		&account.CreatedAt, &account.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// scanAPIKey сканирует ключ в порядке apiKeyColumns.
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes []string
+	err := row.Scan(
+		&key.ID, &key.ServiceAccountID, &key.Name, &key.Prefix, &key.Hash, &key.AllowedIPs, &scopes, &key.ExpiresAt,
+		&key.LastUsedAt, &key.LastUsedIP, &key.RevokedAt, &key.RevokedBy, &key.CreatedBy, &key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = make([]model.APIKeyScope, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = model.APIKeyScope(scope)
+	}
+	return &key, nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const userSessionsTableName = "user_sessions"
+
+// sessionColumns колонки сессии в порядке сканирования.
+var sessionColumns = []string{
+	"s.id", "s.jti", "s.user_id", "COALESCE(s.ip, '')", "COALESCE(s.user_agent, '')",
+	"s.created_at", "s.last_seen_at", "s.expires_at", "s.revoked_at",
+}
+
+// SessionRepository репозиторий сессий пользователей.
+type SessionRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	sq     squirrel.StatementBuilderType
+}
+
+// NewSessionRepository создает новый экземпляр репозитория сессий.
+func NewSessionRepository(pool *pgxpool.Pool, logger *slog.Logger) *SessionRepository {
+	return &SessionRepository{
+		pool:   pool,
+		logger: logger,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// CreateSession сохраняет сессию пользователя с логином login и заполняет ID и даты.
+// Истекшие сессии пользователя удаляются тут же, чтобы таблица не росла.
+// Если пользователя нет, возвращается ErrNotFound.
+func (r *SessionRepository) CreateSession(ctx context.Context, login string, session *model.Session) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("SessionRepository.CreateSession: error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := r.sq.Insert(userSessionsTableName).
+		Columns("jti", "user_id", "ip", "user_agent", "expires_at").
+		Select(r.sq.Select().
+			Column("?", session.JTI).
+			Column("id").
+			Column("NULLIF(?, '')", session.IP).
+			Column("NULLIF(?, '')", session.UserAgent).
+			Column("?::timestamp", session.ExpiresAt).
+			From(usersTableName).
+			Where(squirrel.Eq{"login": login})).
+		Suffix("RETURNING id, user_id, created_at, last_seen_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("SessionRepository.CreateSession: error building query: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, sql, args...).Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("SessionRepository.CreateSession: error executing query: %w", classifyError(err))
+	}
+
+	cleanup := r.sq.Delete(userSessionsTableName).
+		Where(squirrel.Eq{"user_id": session.UserID}).
+		Where(squirrel.Lt{"expires_at": session.CreatedAt})
+
+	sql, args, err = cleanup.ToSql()
+	if err != nil {
+		return fmt.Errorf("SessionRepository.CreateSession: error building cleanup query: %w", err)
+	}
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("SessionRepository.CreateSession: error deleting expired sessions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("SessionRepository.CreateSession: error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetSessionByJTI возвращает сессию по ID токена вместе с состоянием учетной записи владельца.
+// Если ее нет, возвращается ErrNotFound.
+func (r *SessionRepository) GetSessionByJTI(ctx context.Context, jti string) (*model.Session, error) {
+	query := r.sq.Select(sessionColumns...).
+		Columns("u.status", "u.status_from", "u.status_until").
+		From(userSessionsTableName + " s").
+		Join(usersTableName + " u ON u.id = s.user_id").
+		Where(squirrel.Eq{"s.jti": jti})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("SessionRepository.GetSessionByJTI: error building query: %w", err)
+	}
+
+	var session model.Session
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(
+		&session.ID, &session.JTI, &session.UserID, &session.IP, &session.UserAgent,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt,
+		&session.UserStatus, &session.UserStatusFrom, &session.UserStatusUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("SessionRepository.GetSessionByJTI: %w", classifyError(err))
+	}
+
+	return &session, nil
+}
+
+// TouchSession отмечает время последнего запроса и адрес клиента.
+func (r *SessionRepository) TouchSession(ctx context.Context, id int64, ip string, seenAt time.Time) error {
+	query := r.sq.Update(userSessionsTableName).
+		Set("last_seen_at", seenAt).
+		Set("ip", squirrel.Expr("COALESCE(NULLIF(?, ''), ip)", ip)).
+		Where(squirrel.Eq{"id": id})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("SessionRepository.TouchSession: error building query: %w", err)
+	}
+
+	if _, err := r.pool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("SessionRepository.TouchSession: error executing query: %w", err)
+	}
+
+	return nil
+}
+
+// ListActiveSessions возвращает неотозванные и неистекшие сессии пользователя, последние активные первыми.
+func (r *SessionRepository) ListActiveSessions(ctx context.Context, login string, now time.Time) ([]model.Session, error) {
+	query := r.sq.Select(sessionColumns...).
+		From(userSessionsTableName+" s").
+		Join(usersTableName+" u ON u.id = s.user_id").
+		Where(squirrel.Eq{"u.login": login, "s.revoked_at": nil}).
+		Where(squirrel.Gt{"s.expires_at": now}).
+		OrderBy("s.last_seen_at DESC", "s.id DESC")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("SessionRepository.ListActiveSessions: error building query: %w", err)
+	}
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SessionRepository.ListActiveSessions: error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]model.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("SessionRepository.ListActiveSessions: error scanning row: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SessionRepository.ListActiveSessions: error iterating rows: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession отзывает активную сессию пользователя. Если такой сессии нет, возвращается ErrNotFound.
+func (r *SessionRepository) RevokeSession(ctx context.Context, login string, id int64, now time.Time) error {
+	query := r.sq.Update(userSessionsTableName).
+		Set("revoked_at", now).
+		Where(squirrel.Eq{"id": id, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Where(squirrel.Expr("user_id = (SELECT id FROM "+usersTableName+" WHERE login = ?)", login))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("SessionRepository.RevokeSession: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("SessionRepository.RevokeSession: error executing query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("SessionRepository.RevokeSession: session %d: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions отзывает все активные сессии пользователя, кроме сессии с ID токена exceptJTI.
+// Возвращает число отозванных сессий.
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, login, exceptJTI string, now time.Time) (int64, error) {
+	query := r.sq.Update(userSessionsTableName).
+		Set("revoked_at", now).
+		Where(squirrel.Eq{"revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Where(squirrel.NotEq{"jti": exceptJTI}).
+		Where(squirrel.Expr("user_id = (SELECT id FROM "+usersTableName+" WHERE login = ?)", login))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("SessionRepository.RevokeUserSessions: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("SessionRepository.RevokeUserSessions: error executing query: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// scanSession читает сессию в порядке sessionColumns.
+func scanSession(row pgx.Row) (*model.Session, error) {
+	var session model.Session
+	err := row.Scan(
+		&session.ID, &session.JTI, &session.UserID, &session.IP, &session.UserAgent,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const (
+	userTOTPTableName          = "user_totp"
+	userRecoveryCodesTableName = "user_recovery_codes"
+	authPolicyTableName        = "auth_policy"
+)
+
+// TwoFactorRepository репозиторий второго фактора (TOTP, коды восстановления) и политики входа.
+type TwoFactorRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+	sq     squirrel.StatementBuilderType
+}
+
+// NewTwoFactorRepository создает новый экземпляр репозитория второго фактора.
+func NewTwoFactorRepository(pool *pgxpool.Pool, logger *slog.Logger) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		pool:   pool,
+		logger: logger,
+		sq:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// GetTOTP возвращает TOTP пользователя; если подключение не начиналось, возвращается ErrNotFound.
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID int64) (*model.TOTP, error) {
+	query := r.sq.Select("user_id", "secret", "enabled_at", "last_used_step", "created_at").
+		From(userTOTPTableName).
+		Where(squirrel.Eq{"user_id": userID})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("TwoFactorRepository.GetTOTP: error building query: %w", err)
+	}
+
+	var totp model.TOTP
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&totp.UserID, &totp.Secret, &totp.EnabledAt, &totp.LastUsedStep, &totp.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("TwoFactorRepository.GetTOTP: %w", classifyError(err))
+	}
+
+	return &totp, nil
+}
+
+// SaveTOTPSecret сохраняет секрет неподтвержденного подключения, заменяя предыдущий неподтвержденный.
+// Подключенный второй фактор не перезаписывается: возвращается ErrAlreadyExists.
+func (r *TwoFactorRepository) SaveTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	query := r.sq.Insert(userTOTPTableName).
+		Columns("user_id", "secret").
+		Values(userID, secret).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_used_step = 0,
+			created_at = NOW(),
+			updated_at = NOW()
+		WHERE user_totp.enabled_at IS NULL`)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.SaveTOTPSecret: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.SaveTOTPSecret: %w", classifyError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("TwoFactorRepository.SaveTOTPSecret: two-factor authentication is already enabled: %w", ErrAlreadyExists)
+	}
+
+	return nil
+}
+
+// EnableTOTP подтверждает подключение: запоминает использованный интервал и заменяет коды восстановления.
+// Если подключение не начато или уже подтверждено, возвращается ErrNotFound.
+func (r *TwoFactorRepository) EnableTOTP(ctx context.Context, userID, step int64, codeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.EnableTOTP: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := r.sq.Update(userTOTPTableName).
+		Set("enabled_at", squirrel.Expr("NOW()")).
+		Set("last_used_step", step).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID, "enabled_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.EnableTOTP: error building query: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.EnableTOTP: error updating: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("TwoFactorRepository.EnableTOTP: %w", ErrNotFound)
+	}
+
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return fmt.Errorf("TwoFactorRepository.EnableTOTP: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("TwoFactorRepository.EnableTOTP: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep принимает код интервала step, если он позже последнего принятого.
+// Возвращает false для повторно использованного кода.
+func (r *TwoFactorRepository) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	query := r.sq.Update(userTOTPTableName).
+		Set("last_used_step", step).
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID}).
+		Where(squirrel.NotEq{"enabled_at": nil}).
+		Where(squirrel.Lt{"last_used_step": step})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("TwoFactorRepository.UseTOTPStep: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("TwoFactorRepository.UseTOTPStep: error updating: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode погашает неиспользованный код восстановления. Возвращает false, если кода нет или он уже использован.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	query := r.sq.Update(userRecoveryCodesTableName).
+		Set("used_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"user_id": userID, "code_hash": codeHash, "used_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("TwoFactorRepository.UseRecoveryCode: error building query: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("TwoFactorRepository.UseRecoveryCode: error updating: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReplaceRecoveryCodes заменяет коды восстановления пользователя новыми.
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.ReplaceRecoveryCodes: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return fmt.Errorf("TwoFactorRepository.ReplaceRecoveryCodes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("TwoFactorRepository.ReplaceRecoveryCodes: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes возвращает число неиспользованных кодов восстановления.
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	query := r.sq.Select("COUNT(*)").
+		From(userRecoveryCodesTableName).
+		Where(squirrel.Eq{"user_id": userID, "used_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("TwoFactorRepository.CountRecoveryCodes: error building query: %w", err)
+	}
+
+	var count int
+	if err := r.pool.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("TwoFactorRepository.CountRecoveryCodes: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteTOTP отключает второй фактор пользователя и удаляет его коды восстановления.
+// Если второй фактор не подключался, возвращается ErrNotFound.
+func (r *TwoFactorRepository) DeleteTOTP(ctx context.Context, userID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.DeleteTOTP: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return fmt.Errorf("TwoFactorRepository.DeleteTOTP: %w", err)
+	}
+
+	sql, args, err := r.sq.Delete(userTOTPTableName).Where(squirrel.Eq{"user_id": userID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.DeleteTOTP: error building query: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.DeleteTOTP: error deleting: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("TwoFactorRepository.DeleteTOTP: %w", ErrNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("TwoFactorRepository.DeleteTOTP: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuthPolicy возвращает политику входа.
+func (r *TwoFactorRepository) GetAuthPolicy(ctx context.Context) (*model.AuthPolicy, error) {
+	query := r.sq.Select("require_admin_2fa", "COALESCE(updated_by, '')", "updated_at").
+		From(authPolicyTableName).
+		Where(squirrel.Eq{"id": 1})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("TwoFactorRepository.GetAuthPolicy: error building query: %w", err)
+	}
+
+	var policy model.AuthPolicy
+	if err := r.pool.QueryRow(ctx, sql, args...).Scan(&policy.RequireAdmin2FA, &policy.UpdatedBy, &policy.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("TwoFactorRepository.GetAuthPolicy: %w", classifyError(err))
+	}
+
+	return &policy, nil
+}
+
+// UpdateAuthPolicy сохраняет политику входа.
+func (r *TwoFactorRepository) UpdateAuthPolicy(ctx context.Context, policy *model.AuthPolicy) error {
+	query := r.sq.Insert(authPolicyTableName).
+		Columns("id", "require_admin_2fa", "updated_by", "updated_at").
+		Values(1, policy.RequireAdmin2FA, squirrel.Expr("NULLIF(?, '')", policy.UpdatedBy), squirrel.Expr("NOW()")).
+		Suffix(`ON CONFLICT (id) DO UPDATE SET
+			require_admin_2fa = EXCLUDED.require_admin_2fa,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("TwoFactorRepository.UpdateAuthPolicy: error building query: %w", err)
+	}
+
+	if err := r.pool.QueryRow(ctx, sql, args...).Scan(&policy.UpdatedAt); err != nil {
+		return fmt.Errorf("TwoFactorRepository.UpdateAuthPolicy: %w", err)
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes удаляет коды восстановления пользователя и сохраняет новые в транзакции tx.
+func (r *TwoFactorRepository) replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	sql, args, err := r.sq.Delete(userRecoveryCodesTableName).Where(squirrel.Eq{"user_id": userID}).ToSql()
+	if err != nil {
+		return fmt.Errorf("error building delete query: %w", err)
+	}
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	insert := r.sq.Insert(userRecoveryCodesTableName).Columns("user_id", "code_hash")
+	for _, hash := range codeHashes {
+		insert = insert.Values(userID, hash)
+	}
+
+	sql, args, err = insert.ToSql()
+	if err != nil {
+		return fmt.Errorf("error building insert query: %w", err)
+	}
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("error saving recovery codes: %w", err)
+	}
+
+	return nil
+}
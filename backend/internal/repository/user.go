@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"log/slog"
@@ -30,8 +32,8 @@ type UserRepository interface {
 	// UpdateUser обновляет пользователя по ID.
 	UpdateUser(ctx context.Context, id int64, update model.UserUpdate) (*model.User, error)
 
-	// DeleteUser удаляет пользователя по ID.
-	DeleteUser(ctx context.Context, id int64) error
+	// SetUserStatus меняет состояние учетной записи; записи пользователя при этом остаются.
+	SetUserStatus(ctx context.Context, id int64, change model.UserStatusChange, now time.Time) (*model.User, error)
 }
 
 // userRepository реализация UserRepository для работы с PostgreSQL.
@@ -52,10 +54,7 @@ func NewUserRepository(pool *pgxpool.Pool, logger *slog.Logger) UserRepository {
 
 // GetUserByID возвращает пользователя по ID.
 func (r *userRepository) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
-	query := r.sq.Select(
-		"id", "login", "password", "is_admin", "role", "region",
-		"first_name", "last_name", "email", "created_at", "updated_at",
-	).From(usersTableName).
+	query := r.sq.Select(userColumns...).From(usersTableName).
 		Where(squirrel.Eq{"id": id})
 
 	sql, args, err := query.ToSql()
@@ -63,27 +62,20 @@ func (r *userRepository) GetUserByID(ctx context.Context, id int64) (*model.User
 		return nil, fmt.Errorf("UserRepository.GetUserByID: failed to build query: %w", err)
 	}
 
-	var user model.User
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(
-		&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.Region,
-		&user.FirstName, &user.LastName, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(r.pool.QueryRow(ctx, sql, args...))
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("UserRepository.GetUserByID: user not found")
+			return nil, fmt.Errorf("UserRepository.GetUserByID: user %d: %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("UserRepository.GetUserByID: failed to scan user: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // GetUserByLogin возвращает пользователя по логину.
 func (r *userRepository) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
-	query := r.sq.Select(
-		"id", "login", "password", "is_admin", "role", "region",
-		"first_name", "last_name", "email", "created_at", "updated_at",
-	).From(usersTableName).
+	query := r.sq.Select(userColumns...).From(usersTableName).
 		Where(squirrel.Eq{"login": login})
 
 	sql, args, err := query.ToSql()
@@ -91,11 +83,7 @@ func (r *userRepository) GetUserByLogin(ctx context.Context, login string) (*mod
 		return nil, fmt.Errorf("UserRepository.GetUserByLogin: failed to build query: %w", err)
 	}
 
-	var user model.User
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(
-		&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.Region,
-		&user.FirstName, &user.LastName, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(r.pool.QueryRow(ctx, sql, args...))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("UserRepository.GetUserByLogin: user not found")
@@ -103,15 +91,12 @@ func (r *userRepository) GetUserByLogin(ctx context.Context, login string) (*mod
 		return nil, fmt.Errorf("UserRepository.GetUserByLogin: failed to scan user: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // GetUsers возвращает список пользователей согласно фильтру.
 func (r *userRepository) GetUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, error) {
-	query := r.sq.Select(
-		"id", "login", "password", "is_admin", "role", "region",
-		"first_name", "last_name", "email", "created_at", "updated_at",
-	).From(usersTableName)
+	query := r.sq.Select(userColumns...).From(usersTableName)
 
 	// Применяем фильтры
 	query = r.applyFilters(query, filter)
@@ -129,16 +114,12 @@ func (r *userRepository) GetUsers(ctx context.Context, filter model.UserFilter)
 
 	var users []*model.User
 	for rows.Next() {
-		var user model.User
-		err := rows.Scan(
-			&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.Region,
-			&user.FirstName, &user.LastName, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			r.logger.Error("UserRepository.GetUsers: failed to scan user", "error", err)
 			continue
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -163,25 +144,28 @@ func (r *userRepository) CreateUser(ctx context.Context, user *model.User) (*mod
 			user.Login, user.Password, user.IsAdmin, user.Role, user.Region,
 			user.FirstName, user.LastName, user.Email, user.CreatedAt, user.UpdatedAt,
 		).
-		Suffix("RETURNING id, created_at, updated_at")
+		Suffix("RETURNING id, auth_source, status, version, created_at, updated_at")
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("UserRepository.CreateUser: failed to build query: %w", err)
 	}
 
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	err = r.pool.QueryRow(ctx, sql, args...).Scan(&user.ID, &user.AuthSource, &user.Status, &user.Version, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("UserRepository.CreateUser: failed to create user: %w", err)
+		return nil, fmt.Errorf("UserRepository.CreateUser: failed to create user: %w", classifyError(err))
 	}
 
 	return user, nil
 }
 
 // UpdateUser обновляет пользователя по ID.
+// Если задана ожидаемая версия и она не совпадает с текущей, возвращается ErrVersionConflict;
+// если логин или почта заняты другим пользователем - ErrAlreadyExists.
 func (r *userRepository) UpdateUser(ctx context.Context, id int64, update model.UserUpdate) (*model.User, error) {
 	query := r.sq.Update(usersTableName).
-		Set("updated_at", time.Now())
+		Set("updated_at", time.Now()).
+		Set("version", nextVersion)
 
 	// Применяем только не-nil поля для обновления
 	if update.Login != nil {
@@ -209,48 +193,93 @@ func (r *userRepository) UpdateUser(ctx context.Context, id int64, update model.
 		query = query.Set("email", *update.Email)
 	}
 
-	query = query.Where(squirrel.Eq{"id": id}).
-		Suffix("RETURNING id, login, password, is_admin, role, region, first_name, last_name, email, created_at, updated_at")
+	var expectedVersion int
+	if update.ExpectedVersion != nil {
+		expectedVersion = *update.ExpectedVersion
+	}
+
+	query = query.Where(versionCondition(id, expectedVersion)).
+		Suffix("RETURNING " + strings.Join(userColumns, ", "))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("UserRepository.UpdateUser: failed to build query: %w", err)
 	}
 
-	var user model.User
-	err = r.pool.QueryRow(ctx, sql, args...).Scan(
-		&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.Region,
-		&user.FirstName, &user.LastName, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(r.pool.QueryRow(ctx, sql, args...))
 	if err != nil {
 		if err == pgx.ErrNoRows {
+			if expectedVersion > 0 {
+				return nil, fmt.Errorf("UserRepository.UpdateUser: user %d: %w", id, ErrVersionConflict)
+			}
 			return nil, fmt.Errorf("UserRepository.UpdateUser: user not found")
 		}
-		return nil, fmt.Errorf("UserRepository.UpdateUser: failed to update user: %w", err)
+		return nil, fmt.Errorf("UserRepository.UpdateUser: failed to update user: %w", classifyError(err))
 	}
 
-	return &user, nil
+	return user, nil
 }
 
-// DeleteUser удаляет пользователя по ID.
-func (r *userRepository) DeleteUser(ctx context.Context, id int64) error {
-	query := r.sq.Delete(usersTableName).Where(squirrel.Eq{"id": id})
+// SetUserStatus меняет состояние учетной записи. Если новое состояние уже действует, сессии
+// пользователя отзываются в той же транзакции. Если задана ожидаемая версия и она не совпадает
+// с текущей, возвращается ErrVersionConflict; если пользователя нет - ErrNotFound.
+func (r *userRepository) SetUserStatus(ctx context.Context, id int64, change model.UserStatusChange, now time.Time) (*model.User, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("UserRepository.SetUserStatus: failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var expectedVersion int
+	if change.ExpectedVersion != nil {
+		expectedVersion = *change.ExpectedVersion
+	}
+
+	query := r.sq.Update(usersTableName).
+		Set("status", change.Status).
+		Set("status_reason", squirrel.Expr("NULLIF(?, '')", change.Reason)).
+		Set("status_from", change.From).
+		Set("status_until", change.Until).
+		Set("status_changed_by", squirrel.Expr("NULLIF(?, '')", change.ChangedBy)).
+		Set("status_changed_at", now).
+		Set("updated_at", now).
+		Set("version", nextVersion).
+		Where(versionCondition(id, expectedVersion)).
+		Suffix("RETURNING " + strings.Join(userColumns, ", "))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf("UserRepository.DeleteUser: failed to build query: %w", err)
+		return nil, fmt.Errorf("UserRepository.SetUserStatus: failed to build query: %w", err)
 	}
 
-	result, err := r.pool.Exec(ctx, sql, args...)
+	user, err := scanUser(tx.QueryRow(ctx, sql, args...))
 	if err != nil {
-		return fmt.Errorf("UserRepository.DeleteUser: failed to delete user: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) && expectedVersion > 0 {
+			return nil, fmt.Errorf("UserRepository.SetUserStatus: user %d: %w", id, ErrVersionConflict)
+		}
+		return nil, fmt.Errorf("UserRepository.SetUserStatus: user %d: %w", id, classifyError(err))
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("UserRepository.DeleteUser: user not found")
+	if user.StatusAt(now) != model.UserStatusActive {
+		revoke := r.sq.Update(userSessionsTableName).
+			Set("revoked_at", now).
+			Where(squirrel.Eq{"user_id": id, "revoked_at": nil}).
+			Where(squirrel.Gt{"expires_at": now})
+
+		sql, args, err = revoke.ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("UserRepository.SetUserStatus: failed to build revoke query: %w", err)
+		}
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return nil, fmt.Errorf("UserRepository.SetUserStatus: failed to revoke sessions: %w", err)
+		}
 	}
 
-	return nil
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("UserRepository.SetUserStatus: failed to commit transaction: %w", err)
+	}
+
+	return user, nil
 }
 
 // applyFilters применяет фильтры к запросу.
@@ -279,6 +308,44 @@ func (r *userRepository) applyFilters(query squirrel.SelectBuilder, filter model
 	if filter.LastName != nil {
 		query = query.Where(squirrel.Eq{"last_name": *filter.LastName})
 	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		now := time.Now()
+		query = query.Where(squirrel.Expr(effectiveStatusSQL+" = ANY(?)", now, now, statuses))
+	}
 
 	return query
 }
+
+// effectiveStatusSQL состояние учетной записи на момент параметра (передается дважды),
+// как model.EffectiveUserStatus.
+const effectiveStatusSQL = `(CASE WHEN status <> 'active'
+	AND (status_from IS NULL OR status_from <= ?)
+	AND (status_until IS NULL OR status_until > ?)
+	THEN status ELSE 'active' END)`
+
+// userColumns колонки пользователя в порядке сканирования.
+var userColumns = []string{
+	"id", "login", "password", "is_admin", "role", "region",
+	"first_name", "last_name", "email", "auth_source", "locked_until", "version", "created_at", "updated_at",
+	"status", "COALESCE(status_reason, '')", "status_from", "status_until",
+	"COALESCE(status_changed_by, '')", "status_changed_at",
+}
+
+// scanUser читает пользователя в порядке userColumns.
+func scanUser(row pgx.Row) (*model.User, error) {
+	var user model.User
+	err := row.Scan(
+		&user.ID, &user.Login, &user.Password, &user.IsAdmin, &user.Role, &user.Region,
+		&user.FirstName, &user.LastName, &user.Email, &user.AuthSource, &user.LockedUntil, &user.Version, &user.CreatedAt, &user.UpdatedAt,
+		&user.Status, &user.StatusReason, &user.StatusFrom, &user.StatusUntil,
+		&user.StatusChangedBy, &user.StatusChangedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+// ErrVersionConflict запись была изменена после того, как клиент ее прочитал.
+var ErrVersionConflict = model.ErrVersionConflict
+
+// versionCondition условие UPDATE/DELETE по ID и ожидаемой версии записи.
+// Версия 0 означает изменение без проверки версии.
+func versionCondition(id interface{}, version int) squirrel.Eq {
+	cond := squirrel.Eq{"id": id}
+	if version > 0 {
+		cond["version"] = version
+	}
+	return cond
+}
+
+// versionedWriteError преобразует ошибку UPDATE ... RETURNING с условием по версии.
+// Если ни одна строка не изменилась, при заданной версии это конфликт, иначе запись не найдена.
+func versionedWriteError(err error, version int) error {
+	if errors.Is(err, pgx.ErrNoRows) && version > 0 {
+		return ErrVersionConflict
+	}
+	return classifyError(err)
+}
+
+// nextVersion выражение увеличения версии записи.
+var nextVersion = squirrel.Expr("version + 1")
+
+// noRowsError ошибка для UPDATE/DELETE с условием по версии, не затронувшего ни одной строки.
+func noRowsError(version int) error {
+	if version > 0 {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/utils"
 )
 
 // Repository интерфейс репозитория AfterSales.
@@ -15,37 +16,33 @@ type Repository interface {
 	GetByDealerAndPeriod(ctx context.Context, dealerID int64, quarter string, year int) (*model.AfterSales, error)
 	GetAllByPeriod(ctx context.Context, quarter string, year int) ([]*model.AfterSales, error)
 	GetWithFilters(ctx context.Context, filters *model.FilterParams) ([]*model.AfterSalesWithDetails, error)
-	Update(ctx context.Context, id int64, updates map[string]interface{}) error
+	Update(ctx context.Context, id int64, version int, patch model.Patch) error
 	UpdateFull(ctx context.Context, as *model.AfterSales) error
-	Delete(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id int64, version int) error
 	GetWithDetailsByPeriod(ctx context.Context, quarter string, year int, region string) ([]*model.AfterSalesWithDetails, error)
 }
 
-// ExcelRepository интерфейс репозитория для работы с Excel данными автозапчастей.
-type ExcelRepository interface {
-	GetDealerNetTableName(year int, quarter string) string
-	TableExists(ctx context.Context, year int, quarter string) (bool, error)
-	GetAfterSalesDataFromExcel(ctx context.Context, year int, quarter string, region string) ([]*model.AfterSalesWithDetails, error)
-}
-
 // Service сервис для работы с данными послепродажного обслуживания.
 type Service struct {
-	repo      Repository
-	excelRepo ExcelRepository
-	logger    *slog.Logger
+	repo   Repository
+	logger *slog.Logger
 }
 
 // NewService создает новый экземпляр сервиса AfterSales.
-func NewService(repo Repository, excelRepo ExcelRepository, logger *slog.Logger) *Service {
+func NewService(repo Repository, logger *slog.Logger) *Service {
 	return &Service{
-		repo:      repo,
-		excelRepo: excelRepo,
-		logger:    logger,
+		repo:   repo,
+		logger: logger,
 	}
 }
 
 // GetAfterSalesByPeriod возвращает список данных послепродажного обслуживания за период.
 func (s *Service) GetAfterSalesByPeriod(ctx context.Context, quarter string, year int, region string) ([]*model.AfterSalesWithDetails, error) {
+	quarter = utils.NormalizeQuarter(quarter)
+	if canonical, ok := model.Regions().Canonical(region); ok {
+		region = canonical
+	}
+
 	// Валидация квартала
 	if !isValidQuarter(quarter) {
 		return nil, fmt.Errorf("AfterSalesService.GetAfterSalesByPeriod: invalid quarter: %s", quarter)
@@ -56,44 +53,6 @@ func (s *Service) GetAfterSalesByPeriod(ctx context.Context, quarter string, yea
 		return nil, fmt.Errorf("AfterSalesService.GetAfterSalesByPeriod: invalid year: %d", year)
 	}
 
-	// Если указаны год и квартал, используем Excel данные
-	if year > 0 && quarter != "" {
-		s.logger.Info("Getting after sales data from Excel table",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-			slog.String("region", region),
-		)
-
-		// Проверяем существование таблицы
-		exists, err := s.excelRepo.TableExists(ctx, year, quarter)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check Excel table existence: %w", err)
-		}
-
-		if !exists {
-			s.logger.Warn("Excel table does not exist",
-				slog.Int("year", year),
-				slog.String("quarter", quarter),
-			)
-			return []*model.AfterSalesWithDetails{}, nil
-		}
-
-		// Получаем данные из Excel таблицы
-		afterSalesData, err := s.excelRepo.GetAfterSalesDataFromExcel(ctx, year, quarter, region)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get after sales data from Excel: %w", err)
-		}
-
-		s.logger.Info("After sales data retrieved from Excel",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-			slog.String("region", region),
-			slog.Int("count", len(afterSalesData)),
-		)
-
-		return afterSalesData, nil
-	}
-
 	afterSalesList, err := s.repo.GetWithDetailsByPeriod(ctx, quarter, year, region)
 	if err != nil {
 		s.logger.Error("AfterSalesService.GetAfterSalesByPeriod: failed to get after sales data",
@@ -122,44 +81,6 @@ func (s *Service) GetAfterSalesWithFilters(ctx context.Context, filters *model.F
 		return nil, fmt.Errorf("AfterSalesService.GetAfterSalesWithFilters: validation failed: %w", err)
 	}
 
-	// Если указаны год и квартал, используем Excel данные
-	if filters.Year > 0 && filters.Quarter != "" {
-		s.logger.Info("Getting after sales data from Excel table",
-			slog.Int("year", filters.Year),
-			slog.String("quarter", filters.Quarter),
-			slog.String("region", filters.Region),
-		)
-
-		// Проверяем существование таблицы
-		exists, err := s.excelRepo.TableExists(ctx, filters.Year, filters.Quarter)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check Excel table existence: %w", err)
-		}
-
-		if !exists {
-			s.logger.Warn("Excel table does not exist",
-				slog.Int("year", filters.Year),
-				slog.String("quarter", filters.Quarter),
-			)
-			return []*model.AfterSalesWithDetails{}, nil
-		}
-
-		// Получаем данные из Excel таблицы
-		afterSalesData, err := s.excelRepo.GetAfterSalesDataFromExcel(ctx, filters.Year, filters.Quarter, filters.Region)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get after sales data from Excel: %w", err)
-		}
-
-		s.logger.Info("After sales data retrieved from Excel",
-			slog.Int("year", filters.Year),
-			slog.String("quarter", filters.Quarter),
-			slog.String("region", filters.Region),
-			slog.Int("count", len(afterSalesData)),
-		)
-
-		return afterSalesData, nil
-	}
-
 	afterSalesList, err := s.repo.GetWithFilters(ctx, filters)
 	if err != nil {
 		s.logger.Error("AfterSalesService.GetAfterSalesWithFilters: failed to get after sales data",
@@ -197,6 +118,8 @@ func (s *Service) GetAfterSalesByID(ctx context.Context, id int64) (*model.After
 
 // CreateAfterSales создает новую запись послепродажного обслуживания.
 func (s *Service) CreateAfterSales(ctx context.Context, as *model.AfterSales) (int64, error) {
+	as.Quarter = utils.NormalizeQuarter(as.Quarter)
+
 	// Валидация
 	if err := s.validateAfterSales(as); err != nil {
 		return 0, fmt.Errorf("AfterSalesService.CreateAfterSales: validation failed: %w", err)
@@ -224,16 +147,17 @@ func (s *Service) CreateAfterSales(ctx context.Context, as *model.AfterSales) (i
 }
 
 // UpdateAfterSales обновляет данные послепродажного обслуживания.
-func (s *Service) UpdateAfterSales(ctx context.Context, id int64, updates map[string]interface{}) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) UpdateAfterSales(ctx context.Context, id int64, version int, patch model.Patch) error {
 	if id <= 0 {
 		return fmt.Errorf("AfterSalesService.UpdateAfterSales: invalid ID: %d", id)
 	}
 
-	if len(updates) == 0 {
-		return fmt.Errorf("AfterSalesService.UpdateAfterSales: no fields to update")
+	if patch.IsEmpty() {
+		return fmt.Errorf("AfterSalesService.UpdateAfterSales: %w", model.ErrEmptyPatch)
 	}
 
-	err := s.repo.Update(ctx, id, updates)
+	err := s.repo.Update(ctx, id, version, patch)
 	if err != nil {
 		s.logger.Error("AfterSalesService.UpdateAfterSales: failed to update",
 			"id", id,
@@ -250,12 +174,13 @@ func (s *Service) UpdateAfterSales(ctx context.Context, id int64, updates map[st
 }
 
 // DeleteAfterSales удаляет запись послепродажного обслуживания.
-func (s *Service) DeleteAfterSales(ctx context.Context, id int64) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) DeleteAfterSales(ctx context.Context, id int64, version int) error {
 	if id <= 0 {
 		return fmt.Errorf("AfterSalesService.DeleteAfterSales: invalid ID: %d", id)
 	}
 
-	err := s.repo.Delete(ctx, id)
+	err := s.repo.Delete(ctx, id, version)
 	if err != nil {
 		s.logger.Error("AfterSalesService.DeleteAfterSales: failed to delete",
 			"id", id,
@@ -271,34 +196,126 @@ func (s *Service) DeleteAfterSales(ctx context.Context, id int64) error {
 	return nil
 }
 
-// validateAfterSales валидирует данные послепродажного обслуживания.
-func (s *Service) validateAfterSales(as *model.AfterSales) error {
-	if as.DealerID <= 0 {
-		return fmt.Errorf("dealer_id is required")
+// GetAfterSalesByDealerAndPeriod возвращает запись послепродажного обслуживания дилера за квартал.
+func (s *Service) GetAfterSalesByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey) (*model.AfterSales, error) {
+	key.Quarter = utils.NormalizeQuarter(key.Quarter)
+
+	as, err := s.repo.GetByDealerAndPeriod(ctx, int64(key.DealerID), key.Quarter, key.Year)
+	if err != nil {
+		return nil, fmt.Errorf("AfterSalesService.GetAfterSalesByDealerAndPeriod: %w", err)
+	}
+
+	return as, nil
+}
+
+// ReplaceAfterSales полностью заменяет запись послепродажного обслуживания дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) ReplaceAfterSales(ctx context.Context, key model.DealerPeriodKey, version int, as *model.AfterSales) (*model.AfterSales, error) {
+	current, err := s.GetAfterSalesByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("AfterSalesService.ReplaceAfterSales: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("AfterSalesService.ReplaceAfterSales: %w", err)
 	}
 
-	// Валидация квартала и года
-	if as.Quarter == "" {
-		return fmt.Errorf("quarter is required")
+	// Ключ записи берется из пути, а не из тела запроса
+	as.ID = current.ID
+	as.DealerID = current.DealerID
+	as.Quarter = current.Quarter
+	as.Year = current.Year
+	as.CreatedAt = current.CreatedAt
+	as.Version = current.Version
+
+	if err := s.validateAfterSales(as); err != nil {
+		return nil, fmt.Errorf("AfterSalesService.ReplaceAfterSales: validation failed: %w", err)
 	}
-	if as.Year <= 0 {
-		return fmt.Errorf("year is required")
+
+	if err := s.repo.UpdateFull(ctx, as); err != nil {
+		s.logger.Error("AfterSalesService.ReplaceAfterSales: failed to update",
+			"id", as.ID,
+			"error", err,
+		)
+		return nil, fmt.Errorf("AfterSalesService.ReplaceAfterSales: %w", err)
 	}
 
-	// Валидация решения
-	validDecisions := []string{"Planned Result", "Needs Development", "Find New Candidate", "Close Down"}
-	isValid := false
-	for _, decision := range validDecisions {
-		if as.ASDecision == decision {
-			isValid = true
-			break
+	s.logger.Info("AfterSalesService.ReplaceAfterSales: successfully updated",
+		"id", as.ID,
+	)
+
+	return as, nil
+}
+
+// PatchAfterSales частично обновляет запись послепродажного обслуживания дилера за квартал.
+// Изменения проверяются схемой на уровне полей, итоговая запись - правилами validateAfterSales.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) PatchAfterSales(ctx context.Context, key model.DealerPeriodKey, version int, patch model.Patch) (*model.AfterSales, error) {
+	current, err := s.GetAfterSalesByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("AfterSalesService.PatchAfterSales: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("AfterSalesService.PatchAfterSales: %w", err)
+	}
+
+	merged := *current
+	if err := patch.Apply(&merged); err != nil {
+		return nil, fmt.Errorf("AfterSalesService.PatchAfterSales: %w", err)
+	}
+
+	if err := s.validateAfterSales(&merged); err != nil {
+		return nil, fmt.Errorf("AfterSalesService.PatchAfterSales: validation failed: %w", err)
+	}
+
+	if err := s.UpdateAfterSales(ctx, int64(current.ID), current.Version, patch); err != nil {
+		return nil, fmt.Errorf("AfterSalesService.PatchAfterSales: %w", err)
+	}
+
+	return s.GetAfterSalesByDealerAndPeriod(ctx, key)
+}
+
+// DeleteAfterSalesByDealerAndPeriod удаляет запись послепродажного обслуживания дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) DeleteAfterSalesByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey, version int) error {
+	current, err := s.GetAfterSalesByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return fmt.Errorf("AfterSalesService.DeleteAfterSalesByDealerAndPeriod: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return fmt.Errorf("AfterSalesService.DeleteAfterSalesByDealerAndPeriod: %w", err)
+	}
+
+	return s.DeleteAfterSales(ctx, int64(current.ID), current.Version)
+}
+
+// validateAfterSales валидирует данные послепродажного обслуживания и возвращает ошибки по полям.
+func (s *Service) validateAfterSales(as *model.AfterSales) error {
+	var errs model.ValidationErrors
+
+	// Валидация ключа записи
+	utils.ValidatePeriodKey(&errs, as.DealerID, as.Quarter, as.Year)
+
+	for _, count := range []struct {
+		field string
+		value int
+	}{
+		{"recommended_stock", as.RecommendedStock},
+		{"warranty_stock", as.WarrantyStock},
+		{"foton_labor_hours", as.FotonLaborHours},
+		{"foton_warranty_hours", as.FotonWarrantyHours},
+		{"service_contracts", as.ServiceContracts},
+	} {
+		if count.value < 0 {
+			errs.Add(count.field, "cannot be negative")
 		}
 	}
-	if !isValid {
-		return fmt.Errorf("invalid as_decision: %s", as.ASDecision)
+
+	// Валидация решения
+	if !model.IsValidDecision(as.ASDecision) {
+		errs.Add("as_decision", "invalid value: %s", as.ASDecision)
 	}
 
-	return nil
+	return errs.Err()
 }
 
 // isValidQuarter проверяет валидность квартала.
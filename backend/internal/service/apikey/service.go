@@ -0,0 +1,365 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+)
+
+// ErrInvalidRequest некорректные параметры учетной записи или ключа.
+var ErrInvalidRequest = errors.New("invalid request")
+
+const (
+	// prefixBytes случайная открытая часть ключа: по ней ключ ищется в БД.
+	prefixBytes = 4
+	// secretBytes случайная секретная часть ключа.
+	secretBytes = 32
+)
+
+// accountNamePattern имя учетной записи попадает в аудит, поэтому без пробелов и спецсимволов.
+var accountNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,49}$`)
+
+// Repository интерфейс репозитория сервисных учетных записей.
+type Repository interface {
+	CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error
+	ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error)
+	GetServiceAccount(ctx context.Context, id int64) (*model.ServiceAccount, error)
+	DeleteServiceAccount(ctx context.Context, id int64) error
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	ListAPIKeys(ctx context.Context, accountID int64) ([]*model.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKeyPrincipal, error)
+	RevokeAPIKey(ctx context.Context, accountID, keyID int64, revokedBy string) (*model.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyID int64, ip string) error
+}
+
+// Service сервис сервисных учетных записей и ключей API.
+type Service struct {
+	repo   Repository
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewService создает новый экземпляр сервиса ключей API.
+func NewService(repo Repository, logger *slog.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// CreateServiceAccount создает сервисную учетную запись.
+func (s *Service) CreateServiceAccount(ctx context.Context, req model.ServiceAccountCreateRequest, createdBy string) (*model.ServiceAccount, error) {
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !accountNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("APIKeyService.CreateServiceAccount: %w: name must be 2-50 characters of a-z, 0-9, '.', '_' or '-'", ErrInvalidRequest)
+	}
+
+	role := req.Role
+	if role == "" {
+		role = model.UserRoleViewer
+	}
+	switch role {
+	case model.UserRoleAdmin, model.UserRoleManager, model.UserRoleSales, model.UserRoleViewer:
+	default:
+		return nil, fmt.Errorf("APIKeyService.CreateServiceAccount: %w: invalid role %q", ErrInvalidRequest, role)
+	}
+
+	account := &model.ServiceAccount{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		IsAdmin:     req.IsAdmin || role == model.UserRoleAdmin,
+		Role:        role,
+		CreatedBy:   createdBy,
+	}
+	if err := s.repo.CreateServiceAccount(ctx, account); err != nil {
+		return nil, fmt.Errorf("APIKeyService.CreateServiceAccount: %w", err)
+	}
+
+	s.logger.Info("Service account created",
+		slog.String("name", account.Name),
+		slog.Bool("is_admin", account.IsAdmin),
+		slog.String("created_by", createdBy),
+	)
+
+	return account, nil
+}
+
+// ListServiceAccounts возвращает сервисные учетные записи.
+func (s *Service) ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	accounts, err := s.repo.ListServiceAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.ListServiceAccounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetServiceAccount возвращает сервисную учетную запись по ID.
+func (s *Service) GetServiceAccount(ctx context.Context, id int64) (*model.ServiceAccount, error) {
+	account, err := s.repo.GetServiceAccount(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.GetServiceAccount: %w", err)
+	}
+	return account, nil
+}
+
+// DeleteServiceAccount удаляет учетную запись; ее ключи перестают действовать.
+func (s *Service) DeleteServiceAccount(ctx context.Context, id int64, deletedBy string) error {
+	if err := s.repo.DeleteServiceAccount(ctx, id); err != nil {
+		return fmt.Errorf("APIKeyService.DeleteServiceAccount: %w", err)
+	}
+
+	s.logger.Info("Service account deleted", slog.Int64("id", id), slog.String("deleted_by", deletedBy))
+	return nil
+}
+
+// CreateAPIKey выпускает ключ учетной записи. Значение ключа возвращается один раз и не хранится.
+func (s *Service) CreateAPIKey(ctx context.Context, accountID int64, req model.APIKeyCreateRequest, createdBy string) (*model.CreatedAPIKey, error) {
+	account, err := s.repo.GetServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.CreateAPIKey: %w", err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("APIKeyService.CreateAPIKey: %w: name is required and must not exceed 100 characters", ErrInvalidRequest)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, fmt.Errorf("APIKeyService.CreateAPIKey: %w: expires_at must be in the future", ErrInvalidRequest)
+	}
+
+	scopes, err := normalizeScopes(account, req.Scopes)
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.CreateAPIKey: %w: %s", ErrInvalidRequest, err.Error())
+	}
+
+	allowedIPs, err := normalizeAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.CreateAPIKey: %w: %s", ErrInvalidRequest, err.Error())
+	}
+
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.CreateAPIKey: %w", err)
+	}
+	rawKey := prefix + "_" + secret
+
+	key := &model.APIKey{
+		ServiceAccountID: account.ID,
+		Name:             name,
+		Prefix:           prefix,
+		Hash:             hashKey(rawKey),
+		AllowedIPs:       allowedIPs,
+		Scopes:           scopes,
+		ExpiresAt:        req.ExpiresAt,
+		CreatedBy:        createdBy,
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("APIKeyService.CreateAPIKey: %w", err)
+	}
+
+	s.logger.Info("API key created",
+		slog.String("service_account", account.Name),
+		slog.String("prefix", prefix),
+		slog.Any("scopes", scopes),
+		slog.String("created_by", createdBy),
+	)
+
+	return &model.CreatedAPIKey{APIKey: *key, Key: rawKey}, nil
+}
+
+// ListAPIKeys возвращает ключи учетной записи без их значений.
+func (s *Service) ListAPIKeys(ctx context.Context, accountID int64) ([]*model.APIKey, error) {
+	if _, err := s.repo.GetServiceAccount(ctx, accountID); err != nil {
+		return nil, fmt.Errorf("APIKeyService.ListAPIKeys: %w", err)
+	}
+
+	keys, err := s.repo.ListAPIKeys(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.ListAPIKeys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ учетной записи.
+func (s *Service) RevokeAPIKey(ctx context.Context, accountID, keyID int64, revokedBy string) (*model.APIKey, error) {
+	key, err := s.repo.RevokeAPIKey(ctx, accountID, keyID, revokedBy)
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.RevokeAPIKey: %w", err)
+	}
+
+	s.logger.Info("API key revoked",
+		slog.Int64("service_account_id", accountID),
+		slog.String("prefix", key.Prefix),
+		slog.String("revoked_by", revokedBy),
+	)
+
+	return key, nil
+}
+
+// IsAPIKey сообщает, похож ли токен на ключ API, а не на JWT.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, model.APIKeyPrefix)
+}
+
+// Authenticate проверяет ключ API и адрес клиента.
+// Для любого неподходящего ключа возвращается model.ErrInvalidAPIKey, для адреса вне списка - model.ErrAPIKeyIPNotAllowed.
+func (s *Service) Authenticate(ctx context.Context, rawKey, clientIP string) (*model.APIKeyPrincipal, error) {
+	prefix, ok := keyPrefix(rawKey)
+	if !ok {
+		return nil, model.ErrInvalidAPIKey
+	}
+
+	principal, err := s.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, model.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("APIKeyService.Authenticate: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(rawKey)), []byte(principal.Key.Hash)) != 1 {
+		return nil, model.ErrInvalidAPIKey
+	}
+	if !principal.Key.IsActive(s.now()) {
+		return nil, model.ErrInvalidAPIKey
+	}
+	if !ipAllowed(principal.Key.AllowedIPs, clientIP) {
+		s.logger.Warn("API key used from a disallowed address",
+			slog.String("prefix", prefix),
+			slog.String("ip", clientIP),
+		)
+		return nil, model.ErrAPIKeyIPNotAllowed
+	}
+
+	// Время последнего использования не должно мешать запросу
+	if err := s.repo.TouchAPIKey(ctx, principal.Key.ID, clientIP); err != nil {
+		s.logger.Error("APIKeyService.Authenticate: failed to record key usage", slog.String("error", err.Error()))
+	}
+
+	return principal, nil
+}
+
+// generateKey генерирует открытый префикс и секрет ключа: ddp_<8 hex>_<43 символа base64url>.
+func generateKey() (string, string, error) {
+	buf := make([]byte, prefixBytes+secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	prefix := model.APIKeyPrefix + hex.EncodeToString(buf[:prefixBytes])
+	secret := base64.RawURLEncoding.EncodeToString(buf[prefixBytes:])
+	return prefix, secret, nil
+}
+
+// keyPrefix возвращает открытую часть ключа.
+func keyPrefix(rawKey string) (string, bool) {
+	if !IsAPIKey(rawKey) {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(rawKey, model.APIKeyPrefix), "_")
+	if !ok || len(prefix) != prefixBytes*2 || secret == "" {
+		return "", false
+	}
+	return model.APIKeyPrefix + prefix, true
+}
+
+// hashKey хэширует ключ. Ключ случайный и длинный, поэтому медленный хэш паролей не нужен.
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes проверяет области ключа и убирает повторы. Нужна хотя бы одна область.
+// Административные области выдаются только ключам учетных записей администраторов,
+// изменение показателей - учетным записям с ролью, которой разрешена запись.
+func normalizeScopes(account *model.ServiceAccount, values []model.APIKeyScope) ([]model.APIKeyScope, error) {
+	var scopes []model.APIKeyScope
+	for _, value := range values {
+		scope := model.APIKeyScope(strings.ToLower(strings.TrimSpace(string(value))))
+		switch {
+		case !scope.IsValid():
+			return nil, fmt.Errorf("unknown scope %q", value)
+		case scope.RequiresAdmin() && !account.IsAdmin:
+			return nil, fmt.Errorf("scope %q requires an admin service account", scope)
+		case scope == model.APIKeyScopeWrite && account.Role != model.UserRoleManager && account.Role != model.UserRoleSales:
+			return nil, fmt.Errorf("scope %q requires the manager or sales role", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// normalizeAllowedIPs проверяет адреса и подсети и приводит их к виду CIDR.
+func normalizeAllowedIPs(values []string) ([]string, error) {
+	var prefixes []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		prefix, err := parseAllowedIP(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address or subnet %q", value)
+		}
+		prefixes = append(prefixes, prefix.String())
+	}
+	return prefixes, nil
+}
+
+// parseAllowedIP разбирает адрес (10.0.0.5) или подсеть (10.0.0.0/24).
+func parseAllowedIP(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ipAllowed проверяет адрес клиента по списку; пустой список разрешает любой адрес.
+func ipAllowed(allowed []string, clientIP string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, value := range allowed {
+		prefix, err := parseAllowedIP(value)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
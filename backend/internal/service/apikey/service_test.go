@@ -0,0 +1,193 @@
+package apikey
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+)
+
+// memoryRepository репозиторий учетных записей в памяти.
+type memoryRepository struct {
+	accounts map[int64]*model.ServiceAccount
+	keys     map[int64]*model.APIKey
+	touched  map[int64]string
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		accounts: make(map[int64]*model.ServiceAccount),
+		keys:     make(map[int64]*model.APIKey),
+		touched:  make(map[int64]string),
+	}
+}
+
+func (r *memoryRepository) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error {
+	for _, existing := range r.accounts {
+		if existing.Name == account.Name {
+			return repository.ErrAlreadyExists
+		}
+	}
+	account.ID = int64(len(r.accounts) + 1)
+	r.accounts[account.ID] = account
+	return nil
+}
+
+func (r *memoryRepository) ListServiceAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetServiceAccount(ctx context.Context, id int64) (*model.ServiceAccount, error) {
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return account, nil
+}
+
+func (r *memoryRepository) DeleteServiceAccount(ctx context.Context, id int64) error {
+	delete(r.accounts, id)
+	return nil
+}
+
+func (r *memoryRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	key.ID = int64(len(r.keys) + 1)
+	r.keys[key.ID] = key
+	return nil
+}
+
+func (r *memoryRepository) ListAPIKeys(ctx context.Context, accountID int64) ([]*model.APIKey, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKeyPrincipal, error) {
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			return &model.APIKeyPrincipal{Account: r.accounts[key.ServiceAccountID], Key: key}, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryRepository) RevokeAPIKey(ctx context.Context, accountID, keyID int64, revokedBy string) (*model.APIKey, error) {
+	key, ok := r.keys[keyID]
+	if !ok || key.ServiceAccountID != accountID {
+		return nil, repository.ErrNotFound
+	}
+	now := time.Now()
+	key.RevokedAt, key.RevokedBy = &now, revokedBy
+	return key, nil
+}
+
+func (r *memoryRepository) TouchAPIKey(ctx context.Context, keyID int64, ip string) error {
+	r.touched[keyID] = ip
+	return nil
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	account, err := service.CreateServiceAccount(ctx, model.ServiceAccountCreateRequest{Name: " BI-Ingest ", IsAdmin: true}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "bi-ingest", account.Name)
+	assert.Equal(t, model.UserRoleViewer, account.Role)
+
+	_, err = service.CreateServiceAccount(ctx, model.ServiceAccountCreateRequest{Name: "bi ingest"}, "admin")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	created, err := service.CreateAPIKey(ctx, account.ID, model.APIKeyCreateRequest{
+		Name:       "nightly job",
+		Scopes:     []model.APIKeyScope{" Ingest:Write ", model.APIKeyScopeRead, model.APIKeyScopeRead},
+		AllowedIPs: []string{"10.0.0.0/24", " 192.168.1.7 "},
+	}, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, []model.APIKeyScope{model.APIKeyScopeIngestWrite, model.APIKeyScopeRead}, created.Scopes)
+	assert.True(t, created.HasScope(model.APIKeyScopeIngestWrite))
+	assert.False(t, created.HasScope(model.APIKeyScopeImportsWrite))
+
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix+"_"))
+	assert.NotContains(t, created.Hash, created.Key)
+	assert.Equal(t, []string{"10.0.0.0/24", "192.168.1.7/32"}, created.AllowedIPs)
+
+	t.Run("valid key from an allowed address", func(t *testing.T) {
+		principal, err := service.Authenticate(ctx, created.Key, "10.0.0.42")
+		require.NoError(t, err)
+		assert.Equal(t, "svc:bi-ingest/"+created.Prefix, principal.Actor())
+		assert.Equal(t, "10.0.0.42", repo.touched[created.ID])
+	})
+
+	t.Run("address outside the allowlist", func(t *testing.T) {
+		_, err := service.Authenticate(ctx, created.Key, "10.0.1.1")
+		assert.ErrorIs(t, err, model.ErrAPIKeyIPNotAllowed)
+	})
+
+	t.Run("wrong secret and malformed keys", func(t *testing.T) {
+		for _, key := range []string{created.Prefix + "_wrong", "ddp_short", "eyJhbGciOiJIUzI1NiJ9"} {
+			_, err := service.Authenticate(ctx, key, "10.0.0.42")
+			assert.ErrorIs(t, err, model.ErrInvalidAPIKey, key)
+		}
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour)
+		expiring, err := service.CreateAPIKey(ctx, account.ID, model.APIKeyCreateRequest{Name: "temporary", Scopes: []model.APIKeyScope{model.APIKeyScopeRead}, ExpiresAt: &expiresAt}, "admin")
+		require.NoError(t, err)
+
+		_, err = service.Authenticate(ctx, expiring.Key, "203.0.113.5")
+		require.NoError(t, err)
+
+		service.now = func() time.Time { return expiresAt.Add(time.Second) }
+		defer func() { service.now = time.Now }()
+
+		_, err = service.Authenticate(ctx, expiring.Key, "203.0.113.5")
+		assert.ErrorIs(t, err, model.ErrInvalidAPIKey)
+	})
+
+	t.Run("revoked key", func(t *testing.T) {
+		_, err := service.RevokeAPIKey(ctx, account.ID, created.ID, "admin")
+		require.NoError(t, err)
+
+		_, err = service.Authenticate(ctx, created.Key, "10.0.0.42")
+		assert.ErrorIs(t, err, model.ErrInvalidAPIKey)
+	})
+
+	t.Run("invalid key requests", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		read := []model.APIKeyScope{model.APIKeyScopeRead}
+		for _, req := range []model.APIKeyCreateRequest{
+			{Name: "", Scopes: read},
+			{Name: "past", Scopes: read, ExpiresAt: &past},
+			{Name: "bad ip", Scopes: read, AllowedIPs: []string{"10.0.0.300"}},
+			{Name: "no scopes"},
+			{Name: "unknown scope", Scopes: []model.APIKeyScope{"admin"}},
+			{Name: "write for viewer", Scopes: []model.APIKeyScope{model.APIKeyScopeWrite}},
+		} {
+			_, err := service.CreateAPIKey(ctx, account.ID, req, "admin")
+			assert.ErrorIs(t, err, ErrInvalidRequest, req.Name)
+		}
+
+		_, err := service.CreateAPIKey(ctx, 404, model.APIKeyCreateRequest{Name: "missing", Scopes: read}, "admin")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("admin scopes need an admin account", func(t *testing.T) {
+		reporting, err := service.CreateServiceAccount(ctx, model.ServiceAccountCreateRequest{Name: "power-bi", Role: model.UserRoleManager}, "admin")
+		require.NoError(t, err)
+
+		_, err = service.CreateAPIKey(ctx, reporting.ID, model.APIKeyCreateRequest{Name: "imports", Scopes: []model.APIKeyScope{model.APIKeyScopeImportsRead}}, "admin")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+
+		key, err := service.CreateAPIKey(ctx, reporting.ID, model.APIKeyCreateRequest{Name: "refresh", Scopes: []model.APIKeyScope{model.APIKeyScopeRead, model.APIKeyScopeWrite}}, "admin")
+		require.NoError(t, err)
+		assert.Equal(t, []model.APIKeyScope{model.APIKeyScopeRead, model.APIKeyScopeWrite}, key.Scopes)
+	})
+}
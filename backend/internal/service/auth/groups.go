@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+// rolePriority порядок ролей: при членстве в нескольких группах побеждает старшая.
+var rolePriority = map[model.UserRole]int{
+	model.UserRoleViewer:  1,
+	model.UserRoleSales:   2,
+	model.UserRoleManager: 3,
+	model.UserRoleAdmin:   4,
+}
+
+// GroupMapping сопоставление группы каталога или провайдера OIDC значению платформы (роли или региону).
+type GroupMapping struct {
+	Group string // DN группы, ее CN или имя группы из токена
+	Value string
+}
+
+// ParseGroupMappings разбирает сопоставления вида "DDP-Admins=admin;CN=DDP-Sales,OU=Groups,DC=corp,DC=local=sales".
+// Значение отделяется последним "=", поэтому группу можно указать как CN или полным DN.
+func ParseGroupMappings(value string) ([]GroupMapping, error) {
+	var mappings []GroupMapping
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 || i == len(entry)-1 {
+			return nil, fmt.Errorf("ParseGroupMappings: invalid entry %q, expected group=value", entry)
+		}
+		mappings = append(mappings, GroupMapping{
+			Group: strings.TrimSpace(entry[:i]),
+			Value: strings.TrimSpace(entry[i+1:]),
+		})
+	}
+	return mappings, nil
+}
+
+// validateGroupRoles проверяет роли в сопоставлениях групп.
+func validateGroupRoles(roles []GroupMapping, defaultRole model.UserRole) error {
+	for _, mapping := range roles {
+		if _, ok := rolePriority[model.UserRole(mapping.Value)]; !ok {
+			return fmt.Errorf("group %q: unknown role %q", mapping.Group, mapping.Value)
+		}
+	}
+	if defaultRole != "" {
+		if _, ok := rolePriority[defaultRole]; !ok {
+			return fmt.Errorf("unknown default role %q", defaultRole)
+		}
+	}
+	if len(roles) == 0 && defaultRole == "" {
+		return fmt.Errorf("no group roles and no default role: nobody could log in")
+	}
+	return nil
+}
+
+// applyGroupMappings заполняет роль, признак администратора и регион по группам пользователя.
+// Возвращает false, если роль не определилась и роли по умолчанию нет.
+func applyGroupMappings(user *model.User, groups []string, roles, regions []GroupMapping, defaultRole model.UserRole) bool {
+	role := defaultRole
+	for _, mapping := range roles {
+		candidate := model.UserRole(mapping.Value)
+		if memberOf(groups, mapping.Group) && rolePriority[candidate] > rolePriority[role] {
+			role = candidate
+		}
+	}
+	if role == "" {
+		return false
+	}
+
+	user.Role = role
+	user.IsAdmin = role == model.UserRoleAdmin
+
+	for _, mapping := range regions {
+		if !memberOf(groups, mapping.Group) {
+			continue
+		}
+		if region, ok := model.Regions().Canonical(mapping.Value); ok {
+			user.Region = region
+			break
+		}
+	}
+	if user.Region == "" && user.IsAdmin {
+		user.Region = model.AllRussia
+	}
+
+	return true
+}
+
+// memberOf проверяет членство в группе, заданной именем, полным DN или CN.
+func memberOf(groups []string, group string) bool {
+	for _, dn := range groups {
+		if strings.EqualFold(dn, group) || strings.EqualFold(groupCN(dn), group) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupCN возвращает CN из DN группы; для имени без DN - пустую строку.
+func groupCN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+	for _, attribute := range parsed.RDNs[0].Attributes {
+		if strings.EqualFold(attribute.Type, "cn") {
+			return attribute.Value
+		}
+	}
+	return ""
+}
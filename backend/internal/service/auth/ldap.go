@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+const (
+	// DefaultLDAPUserFilter фильтр поиска пользователя в Active Directory.
+	DefaultLDAPUserFilter = "(&(objectClass=user)(sAMAccountName={login}))"
+
+	defaultLDAPTimeout = 5 * time.Second
+)
+
+// LDAPConfig настройки входа через LDAP/Active Directory.
+type LDAPConfig struct {
+	URL                string // ldap://dc.corp.local:389 или ldaps://dc.corp.local:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+
+	BindDN       string // Служебная учетная запись для поиска пользователей; пусто - анонимный поиск
+	BindPassword string
+
+	BaseDN     string
+	UserFilter string // {login} заменяется логином; по умолчанию DefaultLDAPUserFilter
+
+	// GroupBaseDN и GroupFilter включают поиск групп по члену ({dn} - DN пользователя)
+	// для каталогов без memberOf. Без них группы берутся из атрибута memberOf.
+	GroupBaseDN string
+	GroupFilter string
+
+	GroupRoles   []GroupMapping // Группа -> роль; роль admin дает права администратора
+	GroupRegions []GroupMapping // Группа -> регион; побеждает первая подходящая
+	DefaultRole  model.UserRole // Роль без подходящей группы; пусто - вход запрещен
+
+	Timeout time.Duration
+}
+
+// LDAPProvider входит в каталог от имени пользователя и переносит его группы в роль и регион.
+type LDAPProvider struct {
+	cfg LDAPConfig
+}
+
+// NewLDAPProvider конструктор LDAPProvider. Проверяет адрес каталога и сопоставления групп.
+func NewLDAPProvider(cfg LDAPConfig) (*LDAPProvider, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, fmt.Errorf("NewLDAPProvider: URL and base DN are required")
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = DefaultLDAPUserFilter
+	}
+	if !strings.Contains(cfg.UserFilter, "{login}") {
+		return nil, fmt.Errorf("NewLDAPProvider: user filter %q has no {login} placeholder", cfg.UserFilter)
+	}
+	if cfg.GroupFilter != "" && cfg.GroupBaseDN == "" {
+		cfg.GroupBaseDN = cfg.BaseDN
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultLDAPTimeout
+	}
+
+	if err := validateGroupRoles(cfg.GroupRoles, cfg.DefaultRole); err != nil {
+		return nil, fmt.Errorf("NewLDAPProvider: %w", err)
+	}
+
+	return &LDAPProvider{cfg: cfg}, nil
+}
+
+// Name имя провайдера.
+func (p *LDAPProvider) Name() string {
+	return string(model.AuthSourceLDAP)
+}
+
+// Authenticate ищет пользователя служебной учетной записью, проверяет пароль
+// входом от его имени и определяет роль и регион по группам.
+func (p *LDAPProvider) Authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	login = strings.ToLower(strings.TrimSpace(login))
+	if login == "" || password == "" {
+		// Пустой пароль в LDAP означает анонимный вход, а не проверку пароля
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := p.findUser(conn, login)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAPProvider.Authenticate: bind as %s: %w", entry.DN, err)
+	}
+
+	groups := entry.GetAttributeValues("memberOf")
+	if p.cfg.GroupFilter != "" {
+		found, err := p.findGroups(conn, entry.DN)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, found...)
+	}
+
+	user := &model.User{
+		Login:      login,
+		FirstName:  entry.GetAttributeValue("givenName"),
+		LastName:   entry.GetAttributeValue("sn"),
+		Email:      entry.GetAttributeValue("mail"),
+		AuthSource: model.AuthSourceLDAP,
+		ExternalID: entry.DN,
+	}
+	if !p.applyGroups(user, groups) {
+		return nil, fmt.Errorf("LDAPProvider.Authenticate: %s is not a member of any mapped group: %w", entry.DN, ErrInvalidCredentials)
+	}
+
+	return user, nil
+}
+
+// dial подключается к каталогу и при необходимости включает StartTLS.
+func (p *LDAPProvider) dial(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: p.cfg.Timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.cfg.InsecureSkipVerify} // Проверку сертификата отключают только на тестовых стендах
+
+	conn, err := ldap.DialURL(p.cfg.URL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("LDAPProvider.dial: %w", err)
+	}
+	conn.SetTimeout(p.cfg.Timeout)
+
+	if p.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAPProvider.dial: start TLS: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// bindService входит служебной учетной записью; без нее поиск выполняется анонимно.
+func (p *LDAPProvider) bindService(conn *ldap.Conn) error {
+	if p.cfg.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+		return fmt.Errorf("LDAPProvider.bindService: %w", err)
+	}
+	return nil
+}
+
+// findUser ищет единственную запись пользователя по логину.
+func (p *LDAPProvider) findUser(conn *ldap.Conn, login string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(p.cfg.UserFilter, "{login}", ldap.EscapeFilter(login))
+	request := ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(p.cfg.Timeout.Seconds()), false,
+		filter, []string{"givenName", "sn", "mail", "memberOf"}, nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("LDAPProvider.findUser: %w", err)
+	}
+
+	switch {
+	case result == nil || len(result.Entries) == 0:
+		return nil, ErrUnknownUser
+	case len(result.Entries) > 1:
+		return nil, fmt.Errorf("LDAPProvider.findUser: filter %s matches several entries", filter)
+	}
+	return result.Entries[0], nil
+}
+
+// findGroups ищет группы, в которых состоит пользователь, для каталогов без memberOf.
+func (p *LDAPProvider) findGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	// После проверки пароля соединение работает от имени пользователя,
+	// а читать группы может только служебная учетная запись
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	filter := strings.ReplaceAll(p.cfg.GroupFilter, "{dn}", ldap.EscapeFilter(userDN))
+	request := ldap.NewSearchRequest(
+		p.cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(p.cfg.Timeout.Seconds()), false,
+		filter, []string{"cn"}, nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("LDAPProvider.findGroups: %w", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+// applyGroups заполняет роль, признак администратора и регион по группам пользователя.
+func (p *LDAPProvider) applyGroups(user *model.User, groups []string) bool {
+	return applyGroupMappings(user, groups, p.cfg.GroupRoles, p.cfg.GroupRegions, p.cfg.DefaultRole)
+}
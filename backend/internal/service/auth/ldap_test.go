@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/testutil"
+)
+
+func TestLDAPProvider(t *testing.T) {
+	directory := testutil.SetupTestLDAP(t)
+	defer directory.Cleanup(t)
+
+	ctx := context.Background()
+
+	managerDN := directory.AddUser(t, "ivanov", "secret-1", "Ivan", "Ivanov", "ivanov@example.org")
+	salesDN := directory.AddUser(t, "petrov", "secret-2", "Petr", "Petrov", "petrov@example.org")
+	outsiderDN := directory.AddUser(t, "sidorov", "secret-3", "Sidor", "Sidorov", "sidorov@example.org")
+
+	directory.AddGroup(t, "ddp-managers", managerDN)
+	directory.AddGroup(t, "ddp-sales", managerDN, salesDN)
+	directory.AddGroup(t, "ddp-volga", managerDN)
+	directory.AddGroup(t, "staff", outsiderDN)
+
+	provider, err := NewLDAPProvider(LDAPConfig{
+		URL:          directory.URL,
+		BindDN:       directory.AdminDN,
+		BindPassword: directory.AdminPassword,
+		BaseDN:       directory.UsersDN,
+		UserFilter:   "(&(objectClass=inetOrgPerson)(uid={login}))",
+		GroupBaseDN:  directory.GroupsDN,
+		GroupFilter:  "(&(objectClass=groupOfNames)(member={dn}))",
+		GroupRoles: []GroupMapping{
+			{Group: "ddp-managers", Value: "manager"},
+			{Group: "ddp-sales", Value: "sales"},
+		},
+		GroupRegions: []GroupMapping{{Group: "ddp-volga", Value: "volga"}},
+	})
+	require.NoError(t, err)
+
+	t.Run("user with groups", func(t *testing.T) {
+		user, err := provider.Authenticate(ctx, "Ivanov", "secret-1")
+		require.NoError(t, err)
+
+		assert.Equal(t, "ivanov", user.Login)
+		assert.Equal(t, model.UserRoleManager, user.Role)
+		assert.False(t, user.IsAdmin)
+		assert.Equal(t, "Volga", user.Region)
+		assert.Equal(t, "ivanov@example.org", user.Email)
+		assert.Equal(t, model.AuthSourceLDAP, user.AuthSource)
+		assert.Equal(t, managerDN, user.ExternalID)
+	})
+
+	t.Run("sales user without region", func(t *testing.T) {
+		user, err := provider.Authenticate(ctx, "petrov", "secret-2")
+		require.NoError(t, err)
+		assert.Equal(t, model.UserRoleSales, user.Role)
+		assert.Empty(t, user.Region)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := provider.Authenticate(ctx, "ivanov", "secret-2")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("user outside mapped groups", func(t *testing.T) {
+		_, err := provider.Authenticate(ctx, "sidorov", "secret-3")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("unknown user and filter injection", func(t *testing.T) {
+		for _, login := range []string{"nobody", "*", "ivanov)(uid=*"} {
+			_, err := provider.Authenticate(ctx, login, "secret-1")
+			assert.ErrorIs(t, err, ErrUnknownUser, login)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+)
+
+// maxAttemptLoginLength длина колонки login в журнале: логин, введенный на форме, может быть любым.
+const maxAttemptLoginLength = 100
+
+// LoginAttemptRepository журнал попыток входа.
+type LoginAttemptRepository interface {
+	RecordLoginAttempt(ctx context.Context, attempt model.LoginAttempt) error
+	ListLoginAttempts(ctx context.Context, filter model.LoginAttemptFilter) ([]model.LoginAttempt, error)
+}
+
+// ListLoginAttempts возвращает журнал попыток входа, новые первыми.
+func (s *Service) ListLoginAttempts(ctx context.Context, filter model.LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	if s.attempts == nil {
+		return []model.LoginAttempt{}, nil
+	}
+
+	attempts, err := s.attempts.ListLoginAttempts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.ListLoginAttempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// UnlockUser снимает блокировку входа и задержки по учетной записи.
+func (s *Service) UnlockUser(ctx context.Context, userID int64, unlockedBy string) error {
+	login, err := s.repo.UnlockUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("AuthService.UnlockUser: %w", ErrUnknownUser)
+		}
+		return fmt.Errorf("AuthService.UnlockUser: %w", err)
+	}
+
+	s.throttle.reset(login)
+	s.logger.Info("User unlocked", "login", login, "unlocked_by", unlockedBy)
+	return nil
+}
+
+// checkThrottle отклоняет вход без проверки пароля, если по логину или адресу действует
+// задержка или учетная запись заблокирована.
+func (s *Service) checkThrottle(ctx context.Context, login, ip string, method model.LoginMethod) error {
+	now := s.now()
+	if wait := s.throttle.retryAfter(login, ip, now); wait > 0 {
+		s.recordAttempt(ctx, login, ip, method, model.LoginResultThrottled)
+		return &LoginThrottled{RetryAfter: wait}
+	}
+
+	if s.throttleCfg.MaxFailures <= 0 {
+		return nil
+	}
+
+	user, err := s.repo.GetUser(ctx, login)
+	if err != nil {
+		return fmt.Errorf("failed to check account lock: %w", err)
+	}
+	if user != nil && user.LockedUntil != nil && user.LockedUntil.After(now) {
+		s.recordAttempt(ctx, login, ip, method, model.LoginResultLocked)
+		return &LoginThrottled{RetryAfter: user.LockedUntil.Sub(now), Locked: true}
+	}
+	return nil
+}
+
+// loginFailed учитывает неудачный вход: задержки, счетчик блокировки и журнал.
+// Возвращает true, если учетная запись заблокирована.
+func (s *Service) loginFailed(ctx context.Context, login, ip string, method model.LoginMethod, result model.LoginResult) bool {
+	now := s.now()
+	s.throttle.fail(login, ip, now)
+	s.recordAttempt(ctx, login, ip, method, result)
+
+	if s.throttleCfg.MaxFailures <= 0 {
+		return false
+	}
+
+	lockedUntil, err := s.repo.RegisterLoginFailure(ctx, login, s.throttleCfg.MaxFailures, now, now.Add(s.throttleCfg.LockoutDuration))
+	if err != nil {
+		s.logger.Error("AuthService.loginFailed failed to register failure", "login", login, "error", err)
+		return false
+	}
+	if lockedUntil == nil || !lockedUntil.After(now) {
+		return false
+	}
+
+	s.logger.Warn("Account locked after failed logins", "login", login, "ip", ip, "locked_until", lockedUntil)
+	return true
+}
+
+// loginSucceeded сбрасывает счетчики учетной записи и записывает вход в журнал.
+func (s *Service) loginSucceeded(ctx context.Context, login, ip string, method model.LoginMethod) {
+	s.throttle.reset(login)
+	s.recordAttempt(ctx, login, ip, method, model.LoginResultSuccess)
+
+	if s.throttleCfg.MaxFailures <= 0 {
+		return
+	}
+	if err := s.repo.ResetLoginFailures(ctx, login); err != nil {
+		s.logger.Error("AuthService.loginSucceeded failed to reset failures", "login", login, "error", err)
+	}
+}
+
+// recordAttempt пишет попытку в журнал. Ошибка журнала не мешает входу.
+func (s *Service) recordAttempt(ctx context.Context, login, ip string, method model.LoginMethod, result model.LoginResult) {
+	if s.attempts == nil {
+		return
+	}
+
+	attempt := model.LoginAttempt{
+		Login:   truncate(login, maxAttemptLoginLength),
+		IP:      ip,
+		Method:  method,
+		Success: result == model.LoginResultSuccess,
+		Result:  result,
+	}
+	if err := s.attempts.RecordLoginAttempt(ctx, attempt); err != nil {
+		s.logger.Error("AuthService.recordAttempt failed to record login attempt", "login", login, "error", err)
+	}
+}
+
+// truncate обрезает строку до n символов под размер колонки.
+func truncate(value string, n int) string {
+	if runes := []rune(value); len(runes) > n {
+		return string(runes[:n])
+	}
+	return value
+}
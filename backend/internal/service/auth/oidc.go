@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// oidcLoginTTL сколько ждать возврата пользователя от провайдера.
+	oidcLoginTTL = 10 * time.Minute
+
+	// maxPendingOIDCLogins ограничивает незавершенные входы, чтобы их нельзя было копить бесконечно.
+	maxPendingOIDCLogins = 10000
+)
+
+var (
+	// ErrOIDCDisabled вход через OIDC не настроен.
+	ErrOIDCDisabled = errors.New("OIDC login is not configured")
+
+	// ErrInvalidOIDCState state не выдавался, уже использован или истек.
+	ErrInvalidOIDCState = errors.New("invalid or expired OIDC state")
+)
+
+// OIDCConfig настройки входа через OpenID Connect (authorization code + PKCE).
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string // Пусто для публичного клиента: код защищен только PKCE
+	RedirectURL  string // Адрес /auth/oidc/callback, зарегистрированный у провайдера
+	Scopes       []string
+	FrontendURL  string // Адрес фронтенда, куда возвращается пользователь после входа; пусто - тот же хост
+
+	LoginClaim  string // Claim с логином; по умолчанию preferred_username
+	GroupsClaim string // Claim со списком групп; по умолчанию groups
+	RegionClaim string // Claim с регионом; имеет приоритет над GroupRegions
+
+	GroupRoles   []GroupMapping // Группа -> роль; роль admin дает права администратора
+	GroupRegions []GroupMapping // Группа -> регион; побеждает первая подходящая
+	DefaultRole  model.UserRole // Роль без подходящей группы; пусто - вход запрещен
+
+	// TrustIDPMFA второй фактор платформы не запрашивается, если ID token подтверждает,
+	// что портал проверил второй фактор (amr содержит mfa). Иначе второй фактор проверяет платформа
+	TrustIDPMFA bool
+}
+
+// oidcMFAMethod значение claim amr (RFC 8176), которым портал подтверждает вход со вторым фактором.
+const oidcMFAMethod = "mfa"
+
+// OIDCIdentity пользователь, вошедший через провайдера OIDC.
+type OIDCIdentity struct {
+	User     *model.User
+	Redirect string // Страница платформы после входа
+	MFA      bool   // Портал подтвердил второй фактор, и платформа доверяет этому (TrustIDPMFA)
+}
+
+// oidcLogin незавершенный вход: ждет возврата пользователя с кодом.
+type oidcLogin struct {
+	nonce     string
+	verifier  string
+	redirect  string
+	expiresAt time.Time
+}
+
+// OIDCProvider ведет пользователя через провайдера OIDC и проверяет ID token по JWKS провайдера.
+type OIDCProvider struct {
+	cfg   OIDCConfig
+	oauth oauth2.Config
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+	pending  map[string]oidcLogin
+	now      func() time.Time
+}
+
+// NewOIDCProvider конструктор OIDCProvider. Настройки провайдера (discovery) читаются
+// при первом входе, чтобы недоступность провайдера не мешала запуску платформы.
+func NewOIDCProvider(cfg OIDCConfig) (*OIDCProvider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("NewOIDCProvider: issuer URL, client ID and redirect URL are required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"profile", "email"}
+	}
+	if cfg.LoginClaim == "" {
+		cfg.LoginClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if err := validateGroupRoles(cfg.GroupRoles, cfg.DefaultRole); err != nil {
+		return nil, fmt.Errorf("NewOIDCProvider: %w", err)
+	}
+
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range cfg.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return &OIDCProvider{
+		cfg: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+		},
+		pending: make(map[string]oidcLogin),
+		now:     time.Now,
+	}, nil
+}
+
+// AuthCodeURL начинает вход: возвращает адрес страницы входа провайдера и state,
+// по которому вход будет завершен. redirect - страница платформы после входа.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, redirect string) (string, string, error) {
+	config, err := p.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for key, login := range p.pending {
+		if now.After(login.expiresAt) {
+			delete(p.pending, key)
+		}
+	}
+	if len(p.pending) >= maxPendingOIDCLogins {
+		return "", "", fmt.Errorf("OIDCProvider.AuthCodeURL: too many pending logins")
+	}
+	p.pending[state] = oidcLogin{nonce: nonce, verifier: verifier, redirect: redirect, expiresAt: now.Add(oidcLoginTTL)}
+
+	return config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Exchange завершает вход: обменивает код на токены с проверкой PKCE, проверяет
+// подпись и claims ID token и возвращает пользователя вместе со страницей после входа.
+func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*OIDCIdentity, error) {
+	login, ok := p.takeLogin(state)
+	if !ok {
+		return nil, ErrInvalidOIDCState
+	}
+
+	config, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		return nil, fmt.Errorf("OIDCProvider.Exchange: exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("OIDCProvider.Exchange: token response has no id_token")
+	}
+
+	p.mu.Lock()
+	verifier := p.verifier
+	p.mu.Unlock()
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("OIDCProvider.Exchange: verify ID token: %w", err)
+	}
+	if idToken.Nonce != login.nonce {
+		return nil, fmt.Errorf("OIDCProvider.Exchange: ID token nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("OIDCProvider.Exchange: parse claims: %w", err)
+	}
+
+	user, err := p.userFromClaims(idToken.Subject, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCIdentity{
+		User:     user,
+		Redirect: login.redirect,
+		MFA:      p.cfg.TrustIDPMFA && slices.Contains(claimStrings(claims, "amr"), oidcMFAMethod),
+	}, nil
+}
+
+// FrontendURL адрес фронтенда, куда пользователь возвращается после входа.
+func (p *OIDCProvider) FrontendURL() string {
+	return p.cfg.FrontendURL
+}
+
+// discover читает настройки провайдера один раз и запоминает их.
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.verifier == nil {
+		provider, err := oidc.NewProvider(ctx, p.cfg.IssuerURL)
+		if err != nil {
+			return nil, fmt.Errorf("OIDCProvider.discover: %w", err)
+		}
+		p.oauth.Endpoint = provider.Endpoint()
+		p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID, Now: p.now})
+	}
+
+	config := p.oauth
+	return &config, nil
+}
+
+// takeLogin забирает незавершенный вход: state одноразовый.
+func (p *OIDCProvider) takeLogin(state string) (oidcLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	login, ok := p.pending[state]
+	if !ok {
+		return oidcLogin{}, false
+	}
+	delete(p.pending, state)
+
+	if p.now().After(login.expiresAt) {
+		return oidcLogin{}, false
+	}
+	return login, true
+}
+
+// userFromClaims переносит claims ID token в пользователя платформы.
+func (p *OIDCProvider) userFromClaims(subject string, claims map[string]any) (*model.User, error) {
+	login := strings.ToLower(strings.TrimSpace(claimString(claims, p.cfg.LoginClaim)))
+	if login == "" {
+		return nil, fmt.Errorf("OIDCProvider.userFromClaims: claim %q is empty", p.cfg.LoginClaim)
+	}
+
+	user := &model.User{
+		Login:      login,
+		FirstName:  claimString(claims, "given_name"),
+		LastName:   claimString(claims, "family_name"),
+		Email:      claimString(claims, "email"),
+		AuthSource: model.AuthSourceOIDC,
+		ExternalID: subject,
+	}
+
+	if !applyGroupMappings(user, claimStrings(claims, p.cfg.GroupsClaim), p.cfg.GroupRoles, p.cfg.GroupRegions, p.cfg.DefaultRole) {
+		return nil, fmt.Errorf("OIDCProvider.userFromClaims: %s is not a member of any mapped group: %w", login, ErrInvalidCredentials)
+	}
+
+	if p.cfg.RegionClaim != "" {
+		if region, ok := model.Regions().Canonical(claimString(claims, p.cfg.RegionClaim)); ok {
+			user.Region = region
+		}
+	}
+
+	return user, nil
+}
+
+// claimString возвращает строковый claim или пустую строку.
+func claimString(claims map[string]any, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimStrings возвращает claim со списком строк; одиночная строка считается списком из одного значения.
+func claimStrings(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// randomToken возвращает случайную строку для state и nonce.
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("randomToken: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/testutil"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+	mock := testutil.NewMockOIDCProvider(t, "dealer-platform", "client-secret")
+
+	provider, err := NewOIDCProvider(OIDCConfig{
+		IssuerURL:    mock.Issuer(),
+		ClientID:     mock.ClientID,
+		ClientSecret: mock.ClientSecret,
+		RedirectURL:  "http://platform.test/auth/oidc/callback",
+		GroupRoles: []GroupMapping{
+			{Group: "ddp-managers", Value: "manager"},
+			{Group: "ddp-admins", Value: "admin"},
+		},
+		GroupRegions: []GroupMapping{{Group: "ddp-volga", Value: "Volga"}},
+	})
+	require.NoError(t, err)
+
+	repo := &memoryRepository{users: map[string]*model.User{
+		"admin": {Login: "admin", Password: "local-pass", IsAdmin: true, Role: model.UserRoleAdmin, AuthSource: model.AuthSourceLocal},
+	}}
+	service := NewService(repo, jwt.NewService(), Options{OIDC: provider}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.True(t, service.OIDCEnabled())
+
+	// login проходит вход у провайдера и возвращает state и code из адреса возврата
+	login := func(t *testing.T, redirect string, claims map[string]any) (string, string) {
+		mock.SetClaims(claims)
+		authURL, state, err := service.OIDCLoginURL(ctx, redirect)
+		require.NoError(t, err)
+
+		callback := mock.Authorize(t, authURL)
+		assert.Equal(t, "/auth/oidc/callback", callback.Path)
+		assert.Equal(t, state, callback.Query().Get("state"))
+		return state, callback.Query().Get("code")
+	}
+
+	manager := map[string]any{
+		"sub":                "0a1b2c",
+		"preferred_username": "Ivanov",
+		"email":              "ivanov@example.org",
+		"given_name":         "Ivan",
+		"family_name":        "Ivanov",
+		"groups":             []string{"ddp-managers", "ddp-volga"},
+	}
+
+	t.Run("user is created from ID token claims", func(t *testing.T) {
+		state, code := login(t, "/sales", manager)
+
+		claims, redirect, err := service.OIDCLogin(ctx, state, code, "")
+		require.NoError(t, err)
+		assert.Equal(t, "ivanov", claims.Login)
+		assert.Equal(t, string(model.UserRoleManager), claims.Role)
+		assert.False(t, claims.IsAdmin)
+		assert.Equal(t, "/sales", redirect)
+
+		require.Contains(t, repo.users, "ivanov")
+		user := repo.users["ivanov"]
+		assert.Equal(t, model.AuthSourceOIDC, user.AuthSource)
+		assert.Equal(t, "0a1b2c", user.ExternalID)
+		assert.Equal(t, "Volga", user.Region)
+		assert.Equal(t, "ivanov@example.org", user.Email)
+	})
+
+	t.Run("state is single use", func(t *testing.T) {
+		state, code := login(t, "/", manager)
+
+		_, _, err := service.OIDCLogin(ctx, state, code, "")
+		require.NoError(t, err)
+
+		_, _, err = service.OIDCLogin(ctx, state, code, "")
+		assert.ErrorIs(t, err, ErrInvalidOIDCState)
+
+		_, _, err = service.OIDCLogin(ctx, "forged", code, "")
+		assert.ErrorIs(t, err, ErrInvalidOIDCState)
+	})
+
+	t.Run("expired state", func(t *testing.T) {
+		state, code := login(t, "/", manager)
+
+		provider.now = func() time.Time { return time.Now().Add(oidcLoginTTL + time.Minute) }
+		defer func() { provider.now = time.Now }()
+
+		_, _, err := service.OIDCLogin(ctx, state, code, "")
+		assert.ErrorIs(t, err, ErrInvalidOIDCState)
+	})
+
+	t.Run("code verifier must match the challenge", func(t *testing.T) {
+		state, code := login(t, "/", manager)
+
+		provider.mu.Lock()
+		pending := provider.pending[state]
+		pending.verifier = "tampered-verifier-tampered-verifier-tampered"
+		provider.pending[state] = pending
+		provider.mu.Unlock()
+
+		_, _, err := service.OIDCLogin(ctx, state, code, "")
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("user outside mapped groups", func(t *testing.T) {
+		state, code := login(t, "/", map[string]any{
+			"sub":                "9z8y7x",
+			"preferred_username": "sidorov",
+			"groups":             []string{"staff"},
+		})
+
+		_, _, err := service.OIDCLogin(ctx, state, code, "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		assert.NotContains(t, repo.users, "sidorov")
+	})
+
+	t.Run("local account is not taken over", func(t *testing.T) {
+		state, code := login(t, "/", map[string]any{
+			"sub":                "5e6f",
+			"preferred_username": "admin",
+			"groups":             []string{"ddp-admins"},
+		})
+
+		_, _, err := service.OIDCLogin(ctx, state, code, "")
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+		assert.Equal(t, model.AuthSourceLocal, repo.users["admin"].AuthSource)
+	})
+
+	t.Run("ID token signed by an unknown key", func(t *testing.T) {
+		mock.SignWithUnknownKey(t)
+		state, code := login(t, "/", manager)
+
+		_, _, err := service.OIDCLogin(ctx, state, code, "")
+		assert.ErrorContains(t, err, "verify ID token")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := NewService(repo, jwt.NewService(), Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		assert.False(t, disabled.OIDCEnabled())
+
+		_, _, err := disabled.OIDCLoginURL(ctx, "/")
+		assert.ErrorIs(t, err, ErrOIDCDisabled)
+	})
+}
+
+func TestOIDCLoginTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	mock := testutil.NewMockOIDCProvider(t, "dealer-platform", "client-secret")
+
+	newService := func(t *testing.T, trustMFA bool) *Service {
+		provider, err := NewOIDCProvider(OIDCConfig{
+			IssuerURL:    mock.Issuer(),
+			ClientID:     mock.ClientID,
+			ClientSecret: mock.ClientSecret,
+			RedirectURL:  "http://platform.test/auth/oidc/callback",
+			GroupRoles:   []GroupMapping{{Group: "ddp-admins", Value: "admin"}},
+			TrustIDPMFA:  trustMFA,
+		})
+		require.NoError(t, err)
+
+		twoFactor := newMemoryTwoFactorRepository()
+		twoFactor.policy.RequireAdmin2FA = true
+		repo := &memoryRepository{users: map[string]*model.User{}}
+		return NewService(repo, jwt.NewService(), Options{OIDC: provider, TwoFactor: twoFactor}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+
+	// login проходит вход у провайдера с заданными claims и завершает его
+	login := func(t *testing.T, service *Service, claims map[string]any) (*jwt.JWTClaims, string, error) {
+		mock.SetClaims(claims)
+		authURL, state, err := service.OIDCLoginURL(ctx, "/admin")
+		require.NoError(t, err)
+
+		callback := mock.Authorize(t, authURL)
+		return service.OIDCLogin(ctx, state, callback.Query().Get("code"), "")
+	}
+
+	admin := func(amr ...string) map[string]any {
+		claims := map[string]any{"sub": "9f8e7d", "preferred_username": "sidorov", "groups": []string{"ddp-admins"}}
+		if len(amr) > 0 {
+			claims["amr"] = amr
+		}
+		return claims
+	}
+
+	t.Run("admin without IdP MFA gets a challenge", func(t *testing.T) {
+		service := newService(t, false)
+
+		claims, redirect, err := login(t, service, admin("pwd", "mfa"))
+		assert.Nil(t, claims)
+		assert.Equal(t, "/admin", redirect)
+
+		var challenge *TwoFactorChallenge
+		require.ErrorAs(t, err, &challenge)
+		assert.True(t, challenge.EnrollmentRequired)
+		assert.NotEmpty(t, challenge.Token)
+	})
+
+	t.Run("trusted IdP MFA skips the platform factor", func(t *testing.T) {
+		service := newService(t, true)
+
+		claims, _, err := login(t, service, admin("pwd", "mfa"))
+		require.NoError(t, err)
+		assert.True(t, claims.IsAdmin)
+	})
+
+	t.Run("trusted IdP without mfa in amr", func(t *testing.T) {
+		service := newService(t, true)
+
+		_, _, err := login(t, service, admin("pwd"))
+		var challenge *TwoFactorChallenge
+		assert.ErrorAs(t, err, &challenge)
+	})
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
+)
+
+var (
+	// ErrWeakPassword новый пароль не соответствует политике паролей.
+	ErrWeakPassword = errors.New("password does not meet the password policy")
+
+	// ErrExternalPassword пароль учетной записи хранится в каталоге или на корпоративном портале.
+	ErrExternalPassword = errors.New("password is managed by the identity provider")
+)
+
+// PasswordPolicy требования к новому паролю. Нулевое значение требует только непустой пароль.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Validate проверяет пароль и перечисляет все нарушенные требования сразу,
+// чтобы пользователь не подбирал пароль за несколько попыток.
+// Ошибка соответствует ErrWeakPassword и model.ValidationErrors по полю new_password.
+func (p PasswordPolicy) Validate(password, login string) error {
+	var problems model.ValidationErrors
+
+	length := utf8.RuneCountInString(password)
+	if length == 0 || length < p.MinLength {
+		problems.Add("new_password", "must be at least %d characters", max(p.MinLength, 1))
+	}
+	// Ограничение bcrypt считается в байтах: кириллицей это 36 символов
+	if len(password) > passhash.MaxLength {
+		problems.Add("new_password", "must be at most %d bytes", passhash.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		problems.Add("new_password", "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		problems.Add("new_password", "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		problems.Add("new_password", "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems.Add("new_password", "must contain a special character")
+	}
+
+	if login != "" && strings.EqualFold(password, login) {
+		problems.Add("new_password", "must not match the login")
+	}
+
+	return weakPassword(problems)
+}
+
+// weakPassword объединяет ошибки полей с ErrWeakPassword; nil, если ошибок нет.
+func weakPassword(problems model.ValidationErrors) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrWeakPassword, problems)
+}
+
+// PasswordPolicy возвращает политику паролей для подсказки на форме смены пароля.
+func (s *Service) PasswordPolicy() PasswordPolicy {
+	return s.passwordPolicy
+}
+
+// upgradePasswordHash заменяет пароль, сохраненный до перехода на bcrypt, его хэшем.
+// Вызывается после успешной проверки пароля; ошибка только логируется, вход она не прерывает.
+func (s *Service) upgradePasswordHash(ctx context.Context, user *model.User, password string) {
+	if passhash.IsHash(user.Password) {
+		return
+	}
+
+	hash, err := passhash.Hash(password)
+	if err == nil {
+		err = s.repo.UpdatePassword(ctx, user.Login, hash)
+	}
+	if err != nil {
+		s.logger.Warn("AuthService.Login failed to rehash legacy password", "login", user.Login, "error", err)
+		return
+	}
+	user.Password = hash
+	s.logger.Info("Legacy password rehashed", "login", user.Login)
+}
+
+// ChangePassword меняет пароль локальной учетной записи после проверки текущего.
+// Неверный текущий пароль считается неудачным входом, чтобы его нельзя было подбирать
+// из чужой открытой сессии. После смены остальные сессии пользователя отзываются.
+func (s *Service) ChangePassword(ctx context.Context, claims *jwt.JWTClaims, req model.PasswordChangeRequest, ip string) error {
+	user, err := s.repo.GetUser(ctx, claims.Login)
+	if err != nil {
+		return fmt.Errorf("AuthService.ChangePassword: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("AuthService.ChangePassword: %w", ErrUnknownUser)
+	}
+	if user.AuthSource != model.AuthSourceLocal {
+		return fmt.Errorf("AuthService.ChangePassword: %w", ErrExternalPassword)
+	}
+
+	if err := s.checkThrottle(ctx, user.Login, ip, model.LoginMethodPassword); err != nil {
+		return fmt.Errorf("AuthService.ChangePassword: %w", err)
+	}
+	if !passhash.Verify(user.Password, req.CurrentPassword) {
+		s.loginFailed(ctx, user.Login, ip, model.LoginMethodPassword, model.LoginResultInvalidCredentials)
+		return fmt.Errorf("AuthService.ChangePassword: %w", ErrInvalidCredentials)
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		return fmt.Errorf("AuthService.ChangePassword: %w", weakPassword(model.ValidationErrors{
+			{Field: "new_password", Message: "must differ from the current password"},
+		}))
+	}
+	if err := s.passwordPolicy.Validate(req.NewPassword, user.Login); err != nil {
+		return fmt.Errorf("AuthService.ChangePassword: %w", err)
+	}
+
+	hash, err := passhash.Hash(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("AuthService.ChangePassword: %w", err)
+	}
+	if err := s.repo.UpdatePassword(ctx, user.Login, hash); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("AuthService.ChangePassword: %w", ErrUnknownUser)
+		}
+		return fmt.Errorf("AuthService.ChangePassword: %w", err)
+	}
+	s.logger.Info("Password changed", "login", user.Login)
+
+	if _, err := s.RevokeOtherSessions(ctx, claims); err != nil {
+		return fmt.Errorf("AuthService.ChangePassword: password changed, but other sessions were not revoked: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/typefunco/dealer_dev_platform/internal/mailer"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
+)
+
+const (
+	// passwordTokenBytes длина случайной части ссылки.
+	passwordTokenBytes = 32
+
+	// resetRequestInterval не чаще одного письма со сбросом пароля на пользователя,
+	// чтобы форму нельзя было использовать для рассылки писем.
+	resetRequestInterval = time.Minute
+
+	defaultInviteTTL = 72 * time.Hour
+	defaultResetTTL  = time.Hour
+)
+
+var (
+	// ErrEmailDisabled отправка писем не настроена.
+	ErrEmailDisabled = errors.New("email is not configured")
+
+	// ErrInvalidPasswordToken ссылка не существует, уже использована или истекла.
+	ErrInvalidPasswordToken = errors.New("password link is invalid or expired")
+
+	// ErrNoEmail у пользователя не указана почта.
+	ErrNoEmail = errors.New("user has no email address")
+
+	// ErrPasswordAlreadySet пользователь уже задал пароль; приглашение ему не нужно.
+	ErrPasswordAlreadySet = errors.New("user has already set a password")
+)
+
+// Mailer отправка писем.
+type Mailer interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+// PasswordTokenRepository хранилище ссылок для установки пароля.
+type PasswordTokenRepository interface {
+	CreatePasswordToken(ctx context.Context, token *model.PasswordToken) error
+	GetPasswordToken(ctx context.Context, tokenHash string) (*model.PasswordToken, error)
+	UsePasswordToken(ctx context.Context, id int64, password string, now time.Time) error
+}
+
+// PasswordLinkConfig настройки ссылок из писем.
+type PasswordLinkConfig struct {
+	BaseURL   string        // Адрес фронтенда, на него ведут ссылки
+	InviteTTL time.Duration // Срок приглашения (по умолчанию 72 часа)
+	ResetTTL  time.Duration // Срок ссылки для сброса пароля (по умолчанию 1 час)
+}
+
+// resetRequests время последнего письма со сбросом пароля по пользователям.
+type resetRequests struct {
+	mu   sync.Mutex
+	sent map[int64]time.Time
+}
+
+// allow отмечает отправку и сообщает, прошло ли достаточно времени с предыдущей.
+func (r *resetRequests) allow(userID int64, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, sent := range r.sent {
+		if now.Sub(sent) >= resetRequestInterval {
+			delete(r.sent, id)
+		}
+	}
+	if _, ok := r.sent[userID]; ok {
+		return false
+	}
+	r.sent[userID] = now
+	return true
+}
+
+// PasswordEmailsEnabled сообщает, настроены ли приглашения и сброс пароля по почте.
+func (s *Service) PasswordEmailsEnabled() bool {
+	return s.mailer != nil && s.passwordTokens != nil && s.passwordLinks.BaseURL != ""
+}
+
+// SendInvitation отправляет пользователю без пароля письмо со ссылкой для установки пароля.
+// Повторная отправка заменяет прежнюю ссылку. invitedBy - логин администратора.
+func (s *Service) SendInvitation(ctx context.Context, login, invitedBy string) error {
+	if !s.PasswordEmailsEnabled() {
+		return fmt.Errorf("AuthService.SendInvitation: %w", ErrEmailDisabled)
+	}
+
+	user, err := s.repo.GetUser(ctx, login)
+	if err != nil {
+		return fmt.Errorf("AuthService.SendInvitation: %w", err)
+	}
+	switch {
+	case user == nil:
+		return fmt.Errorf("AuthService.SendInvitation: %w", ErrUnknownUser)
+	case user.AuthSource != model.AuthSourceLocal:
+		return fmt.Errorf("AuthService.SendInvitation: %w", ErrExternalPassword)
+	case user.StatusAt(s.now()) != model.UserStatusActive:
+		return fmt.Errorf("AuthService.SendInvitation: %w", model.ErrAccountInactive)
+	case user.Password != "":
+		return fmt.Errorf("AuthService.SendInvitation: %w", ErrPasswordAlreadySet)
+	case user.Email == "":
+		return fmt.Errorf("AuthService.SendInvitation: %w", ErrNoEmail)
+	}
+
+	link, expiresAt, err := s.issuePasswordLink(ctx, user, model.PasswordTokenInvite, invitedBy)
+	if err != nil {
+		return fmt.Errorf("AuthService.SendInvitation: %w", err)
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Приглашение в Dealer Development Platform",
+		Text: fmt.Sprintf(
+			"Здравствуйте%s!\n\n"+
+				"Для вас создана учетная запись в Dealer Development Platform. Логин: %s\n\n"+
+				"Чтобы задать пароль, перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует до %s и открывается один раз.\n",
+			greetingName(user), user.Login, link, expiresAt.Format("02.01.2006 15:04 MST"),
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("AuthService.SendInvitation failed to send email: %w", err)
+	}
+
+	s.logger.Info("Invitation sent", "login", user.Login, "invited_by", invitedBy)
+	return nil
+}
+
+// RequestPasswordReset отправляет ссылку для сброса пароля пользователю с логином или почтой
+// loginOrEmail. Есть ли такой пользователь, вызывающий не узнает: ошибка возвращается только
+// если отправка писем не настроена или недоступна база.
+func (s *Service) RequestPasswordReset(ctx context.Context, loginOrEmail string) error {
+	if !s.PasswordEmailsEnabled() {
+		return fmt.Errorf("AuthService.RequestPasswordReset: %w", ErrEmailDisabled)
+	}
+
+	loginOrEmail = strings.TrimSpace(loginOrEmail)
+	if loginOrEmail == "" {
+		return nil
+	}
+
+	user, err := s.repo.GetUser(ctx, loginOrEmail)
+	if err != nil {
+		return fmt.Errorf("AuthService.RequestPasswordReset: %w", err)
+	}
+	if user == nil {
+		if user, err = s.repo.GetUserByEmail(ctx, loginOrEmail); err != nil {
+			return fmt.Errorf("AuthService.RequestPasswordReset: %w", err)
+		}
+	}
+	if user == nil || user.AuthSource != model.AuthSourceLocal || user.Email == "" || user.StatusAt(s.now()) != model.UserStatusActive {
+		s.logger.Info("Password reset requested for unknown, external or inactive account", "login", loginOrEmail)
+		return nil
+	}
+	if !s.resetRequests.allow(user.ID, s.now()) {
+		s.logger.Info("Password reset requested too often", "login", user.Login)
+		return nil
+	}
+
+	link, expiresAt, err := s.issuePasswordLink(ctx, user, model.PasswordTokenReset, "")
+	if err != nil {
+		return fmt.Errorf("AuthService.RequestPasswordReset: %w", err)
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля в Dealer Development Platform",
+		Text: fmt.Sprintf(
+			"Здравствуйте%s!\n\n"+
+				"Кто-то запросил сброс пароля для учетной записи %s. Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует до %s и открывается один раз. Если вы не запрашивали сброс, просто удалите это письмо.\n",
+			greetingName(user), user.Login, link, expiresAt.Format("02.01.2006 15:04 MST"),
+		),
+	})
+	if err != nil {
+		// Ошибку отправки не возвращаем: по ней можно было бы узнать, что пользователь существует
+		s.logger.Error("AuthService.RequestPasswordReset failed to send email", "login", user.Login, "error", err)
+		return nil
+	}
+
+	s.logger.Info("Password reset link sent", "login", user.Login)
+	return nil
+}
+
+// CheckPasswordToken проверяет ссылку до того, как пользователь введет пароль,
+// и возвращает ее назначение и логин.
+func (s *Service) CheckPasswordToken(ctx context.Context, rawToken string) (*model.PasswordToken, error) {
+	token, err := s.activePasswordToken(ctx, rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.CheckPasswordToken: %w", err)
+	}
+	return token, nil
+}
+
+// ResetPassword устанавливает пароль по ссылке из приглашения или письма о сбросе.
+// Ссылка погашается, блокировка входа снимается, все сессии пользователя завершаются.
+func (s *Service) ResetPassword(ctx context.Context, req model.PasswordResetRequest) error {
+	token, err := s.activePasswordToken(ctx, req.Token)
+	if err != nil {
+		return fmt.Errorf("AuthService.ResetPassword: %w", err)
+	}
+
+	if err := s.passwordPolicy.Validate(req.NewPassword, token.Login); err != nil {
+		return fmt.Errorf("AuthService.ResetPassword: %w", err)
+	}
+
+	hash, err := passhash.Hash(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("AuthService.ResetPassword: %w", err)
+	}
+	if err := s.passwordTokens.UsePasswordToken(ctx, token.ID, hash, s.now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("AuthService.ResetPassword: %w", ErrInvalidPasswordToken)
+		}
+		return fmt.Errorf("AuthService.ResetPassword: %w", err)
+	}
+	s.throttle.reset(token.Login)
+	s.logger.Info("Password set by email link", "login", token.Login, "purpose", token.Purpose)
+
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeUserSessions(ctx, token.Login, "", s.now()); err != nil {
+			return fmt.Errorf("AuthService.ResetPassword: password changed, but sessions were not revoked: %w", err)
+		}
+	}
+	return nil
+}
+
+// activePasswordToken находит действующую ссылку по токену из письма.
+func (s *Service) activePasswordToken(ctx context.Context, rawToken string) (*model.PasswordToken, error) {
+	if s.passwordTokens == nil || rawToken == "" {
+		return nil, ErrInvalidPasswordToken
+	}
+
+	token, err := s.passwordTokens.GetPasswordToken(ctx, hashPasswordToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidPasswordToken
+		}
+		return nil, err
+	}
+	if !token.Active(s.now()) {
+		return nil, ErrInvalidPasswordToken
+	}
+	return token, nil
+}
+
+// issuePasswordLink сохраняет новую ссылку для пользователя и возвращает ее адрес и срок.
+func (s *Service) issuePasswordLink(ctx context.Context, user *model.User, purpose model.PasswordTokenPurpose, createdBy string) (string, time.Time, error) {
+	buf := make([]byte, passwordTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	rawToken := base64.RawURLEncoding.EncodeToString(buf)
+
+	ttl := s.passwordLinks.ResetTTL
+	if purpose == model.PasswordTokenInvite {
+		ttl = s.passwordLinks.InviteTTL
+	}
+
+	token := &model.PasswordToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashPasswordToken(rawToken),
+		CreatedBy: createdBy,
+		ExpiresAt: s.now().Add(ttl),
+	}
+	if err := s.passwordTokens.CreatePasswordToken(ctx, token); err != nil {
+		return "", time.Time{}, err
+	}
+
+	// Токен во фрагменте адреса не попадает в логи веб-сервера и в Referer
+	link := strings.TrimRight(s.passwordLinks.BaseURL, "/") + "/set-password#token=" + rawToken
+	return link, token.ExpiresAt, nil
+}
+
+// hashPasswordToken хэширует токен ссылки. Токен случайный и длинный, поэтому медленный хэш не нужен.
+func hashPasswordToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// greetingName имя для обращения в письме: ", Иван" или пусто.
+func greetingName(user *model.User) string {
+	if user.FirstName == "" {
+		return ""
+	}
+	return ", " + user.FirstName
+}
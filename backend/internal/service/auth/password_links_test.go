@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/mailer"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
+)
+
+// memoryPasswordTokenRepository хранилище ссылок в памяти поверх memoryRepository.
+type memoryPasswordTokenRepository struct {
+	users  *memoryRepository
+	tokens []*model.PasswordToken
+}
+
+func (r *memoryPasswordTokenRepository) CreatePasswordToken(ctx context.Context, token *model.PasswordToken) error {
+	kept := r.tokens[:0]
+	for _, existing := range r.tokens {
+		if existing.UserID != token.UserID || existing.Purpose != token.Purpose || existing.UsedAt != nil {
+			kept = append(kept, existing)
+		}
+	}
+	r.tokens = kept
+
+	token.ID = int64(len(r.tokens) + 100)
+	copied := *token
+	r.tokens = append(r.tokens, &copied)
+	return nil
+}
+
+func (r *memoryPasswordTokenRepository) GetPasswordToken(ctx context.Context, tokenHash string) (*model.PasswordToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			copied.Login = r.login(token.UserID)
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryPasswordTokenRepository) UsePasswordToken(ctx context.Context, id int64, password string, now time.Time) error {
+	for _, token := range r.tokens {
+		if token.ID != id {
+			continue
+		}
+		if !token.Active(now) {
+			return repository.ErrNotFound
+		}
+		token.UsedAt = &now
+		user := r.users.users[r.login(token.UserID)]
+		user.Password = password
+		user.LockedUntil = nil
+		return nil
+	}
+	return repository.ErrNotFound
+}
+
+func (r *memoryPasswordTokenRepository) login(userID int64) string {
+	for login, user := range r.users.users {
+		if user.ID == userID {
+			return login
+		}
+	}
+	return ""
+}
+
+// recordingMailer запоминает отправленные письма.
+type recordingMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// linkToken достает токен из ссылки в последнем письме.
+func (m *recordingMailer) linkToken(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, m.sent)
+	text := m.sent[len(m.sent)-1].Text
+	_, rest, ok := strings.Cut(text, "https://ddp.example.com/set-password#token=")
+	require.True(t, ok, "letter has no link: %s", text)
+	token, _, _ := strings.Cut(rest, "\n")
+	return token
+}
+
+func TestPasswordLinks(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	jwtService := jwt.NewService()
+
+	type fixture struct {
+		service  *Service
+		users    *memoryRepository
+		tokens   *memoryPasswordTokenRepository
+		mail     *recordingMailer
+		sessions *memorySessionRepository
+		clock    time.Time
+	}
+	setup := func() *fixture {
+		f := &fixture{
+			users: &memoryRepository{users: map[string]*model.User{
+				"new.user@example.com": {ID: 1, Login: "new.user@example.com", FirstName: "Анна", Email: "new.user@example.com", Role: model.UserRoleSales, AuthSource: model.AuthSourceLocal},
+				"petrov":               {ID: 2, Login: "petrov", Password: "petrov-pass1", Email: "Petrov@Example.com", Role: model.UserRoleManager, AuthSource: model.AuthSourceLocal},
+				"ivanov":               {ID: 3, Login: "ivanov", Email: "ivanov@example.com", Role: model.UserRoleManager, AuthSource: model.AuthSourceLDAP},
+				"sidorov":              {ID: 4, Login: "sidorov", Role: model.UserRoleViewer, AuthSource: model.AuthSourceLocal},
+			}},
+			mail:     &recordingMailer{},
+			sessions: &memorySessionRepository{},
+			clock:    time.Now(),
+		}
+		f.tokens = &memoryPasswordTokenRepository{users: f.users}
+		f.service = NewService(f.users, jwtService, Options{
+			Sessions:       f.sessions,
+			PasswordPolicy: PasswordPolicy{MinLength: 8, RequireDigit: true},
+			Mailer:         f.mail,
+			PasswordTokens: f.tokens,
+			PasswordLinks:  PasswordLinkConfig{BaseURL: "https://ddp.example.com/"},
+		}, logger)
+		f.service.now = func() time.Time { return f.clock }
+		return f
+	}
+
+	t.Run("invited user sets a password once", func(t *testing.T) {
+		f := setup()
+		require.NoError(t, f.service.SendInvitation(ctx, "new.user@example.com", "admin"))
+		require.Len(t, f.mail.sent, 1)
+		assert.Equal(t, "new.user@example.com", f.mail.sent[0].To)
+		assert.Contains(t, f.mail.sent[0].Text, "Здравствуйте, Анна!")
+		assert.Equal(t, "admin", f.tokens.tokens[0].CreatedBy)
+		assert.Equal(t, f.clock.Add(defaultInviteTTL), f.tokens.tokens[0].ExpiresAt)
+
+		token := f.mail.linkToken(t)
+		assert.NotEqual(t, token, f.tokens.tokens[0].TokenHash, "only the hash is stored")
+
+		checked, err := f.service.CheckPasswordToken(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "new.user@example.com", checked.Login)
+		assert.Equal(t, model.PasswordTokenInvite, checked.Purpose)
+
+		require.NoError(t, f.service.ResetPassword(ctx, model.PasswordResetRequest{Token: token, NewPassword: "first-pass1"}))
+		_, err = f.service.Login(ctx, "new.user@example.com", "first-pass1", "")
+		assert.NoError(t, err)
+
+		err = f.service.ResetPassword(ctx, model.PasswordResetRequest{Token: token, NewPassword: "second-pass2"})
+		assert.ErrorIs(t, err, ErrInvalidPasswordToken, "link is single-use")
+		assert.ErrorIs(t, f.service.SendInvitation(ctx, "new.user@example.com", "admin"), ErrPasswordAlreadySet)
+	})
+
+	t.Run("resend replaces the previous invitation", func(t *testing.T) {
+		f := setup()
+		require.NoError(t, f.service.SendInvitation(ctx, "new.user@example.com", "admin"))
+		first := f.mail.linkToken(t)
+		require.NoError(t, f.service.SendInvitation(ctx, "new.user@example.com", "admin"))
+		second := f.mail.linkToken(t)
+
+		_, err := f.service.CheckPasswordToken(ctx, first)
+		assert.ErrorIs(t, err, ErrInvalidPasswordToken)
+		_, err = f.service.CheckPasswordToken(ctx, second)
+		assert.NoError(t, err)
+	})
+
+	t.Run("invitation is rejected", func(t *testing.T) {
+		f := setup()
+		assert.ErrorIs(t, f.service.SendInvitation(ctx, "petrov", "admin"), ErrPasswordAlreadySet)
+		assert.ErrorIs(t, f.service.SendInvitation(ctx, "ivanov", "admin"), ErrExternalPassword)
+		assert.ErrorIs(t, f.service.SendInvitation(ctx, "sidorov", "admin"), ErrNoEmail)
+		assert.ErrorIs(t, f.service.SendInvitation(ctx, "nobody", "admin"), ErrUnknownUser)
+		f.users.users["new.user@example.com"].Status = model.UserStatusSuspended
+		assert.ErrorIs(t, f.service.SendInvitation(ctx, "new.user@example.com", "admin"), model.ErrAccountInactive)
+		f.users.users["new.user@example.com"].Status = model.UserStatusActive
+		assert.Empty(t, f.mail.sent)
+
+		f.mail.err = errors.New("connection refused")
+		assert.Error(t, f.service.SendInvitation(ctx, "new.user@example.com", "admin"))
+	})
+
+	t.Run("expired link is rejected", func(t *testing.T) {
+		f := setup()
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "petrov"))
+		token := f.mail.linkToken(t)
+		assert.Equal(t, f.clock.Add(defaultResetTTL), f.tokens.tokens[0].ExpiresAt)
+
+		f.clock = f.clock.Add(defaultResetTTL)
+		err := f.service.ResetPassword(ctx, model.PasswordResetRequest{Token: token, NewPassword: "new-pass-2"})
+		assert.ErrorIs(t, err, ErrInvalidPasswordToken)
+		assert.Equal(t, "petrov-pass1", f.users.users["petrov"].Password)
+
+		_, err = f.service.CheckPasswordToken(ctx, "not-a-token")
+		assert.ErrorIs(t, err, ErrInvalidPasswordToken)
+	})
+
+	t.Run("reset by login or email", func(t *testing.T) {
+		f := setup()
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "petrov"))
+		require.Len(t, f.mail.sent, 1)
+		assert.Equal(t, "Petrov@Example.com", f.mail.sent[0].To)
+
+		f.clock = f.clock.Add(resetRequestInterval)
+		require.NoError(t, f.service.RequestPasswordReset(ctx, " petrov@example.com "))
+		require.Len(t, f.mail.sent, 2)
+		assert.Equal(t, "Petrov@Example.com", f.mail.sent[1].To)
+	})
+
+	t.Run("reset does not reveal accounts", func(t *testing.T) {
+		f := setup()
+		assert.NoError(t, f.service.RequestPasswordReset(ctx, "nobody"))
+		assert.NoError(t, f.service.RequestPasswordReset(ctx, "ivanov"), "directory account")
+		assert.NoError(t, f.service.RequestPasswordReset(ctx, "sidorov"), "no email")
+		f.users.users["petrov"].Status = model.UserStatusDeactivated
+		assert.NoError(t, f.service.RequestPasswordReset(ctx, "petrov"), "deactivated account")
+		assert.Empty(t, f.mail.sent)
+		f.users.users["petrov"].Status = model.UserStatusActive
+
+		f.mail.err = errors.New("connection refused")
+		assert.NoError(t, f.service.RequestPasswordReset(ctx, "petrov"), "send failure")
+	})
+
+	t.Run("reset requests are rate limited", func(t *testing.T) {
+		f := setup()
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "petrov"))
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "petrov@example.com"))
+		assert.Len(t, f.mail.sent, 1)
+
+		f.clock = f.clock.Add(resetRequestInterval)
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "petrov"))
+		assert.Len(t, f.mail.sent, 2)
+	})
+
+	t.Run("reset unlocks the account and signs out everywhere", func(t *testing.T) {
+		f := setup()
+		lockedUntil := f.clock.Add(time.Hour)
+		f.users.users["petrov"].LockedUntil = &lockedUntil
+		issued, err := jwtService.NewClaims("petrov", false, string(model.UserRoleManager))
+		require.NoError(t, err)
+		session, err := f.service.StartSession(ctx, issued, "", "")
+		require.NoError(t, err)
+		claims, err := jwtService.ValidateJWT(session)
+		require.NoError(t, err)
+
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "petrov"))
+		token := f.mail.linkToken(t)
+
+		err = f.service.ResetPassword(ctx, model.PasswordResetRequest{Token: token, NewPassword: "short"})
+		var fields model.ValidationErrors
+		require.ErrorAs(t, err, &fields)
+		assert.Equal(t, "petrov-pass1", f.users.users["petrov"].Password)
+
+		require.NoError(t, f.service.ResetPassword(ctx, model.PasswordResetRequest{Token: token, NewPassword: "new-pass-2"}))
+		assert.True(t, passhash.Verify(f.users.users["petrov"].Password, "new-pass-2"))
+		assert.Nil(t, f.users.users["petrov"].LockedUntil)
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, claims, ""), model.ErrSessionRevoked)
+	})
+
+	t.Run("disabled without mailer", func(t *testing.T) {
+		service := NewService(&memoryRepository{users: map[string]*model.User{}}, jwtService, Options{}, logger)
+		assert.False(t, service.PasswordEmailsEnabled())
+		assert.ErrorIs(t, service.SendInvitation(ctx, "petrov", "admin"), ErrEmailDisabled)
+		assert.ErrorIs(t, service.RequestPasswordReset(ctx, "petrov"), ErrEmailDisabled)
+		_, err := service.CheckPasswordToken(ctx, "token")
+		assert.ErrorIs(t, err, ErrInvalidPasswordToken)
+	})
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
+)
+
+var (
+	// ErrInvalidCredentials неверный логин или пароль. Причина не раскрывается клиенту.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrUnknownUser провайдер не знает пользователя: вход передается следующему провайдеру.
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+// Provider источник учетных записей для входа по логину и паролю.
+// Провайдеры опрашиваются по очереди, пока один из них не примет пароль.
+type Provider interface {
+	// Name короткое имя провайдера для логов.
+	Name() string
+
+	// Authenticate проверяет пароль и возвращает пользователя.
+	// Для пользователя внешнего провайдера AuthSource отличен от local:
+	// такая запись создается или обновляется в users после входа.
+	Authenticate(ctx context.Context, login, password string) (*model.User, error)
+}
+
+// LocalProvider проверяет пароли, которые хранит платформа.
+type LocalProvider struct {
+	repo Repository
+}
+
+// NewLocalProvider конструктор LocalProvider.
+func NewLocalProvider(repo Repository) *LocalProvider {
+	return &LocalProvider{repo: repo}
+}
+
+// Name имя провайдера.
+func (p *LocalProvider) Name() string {
+	return string(model.AuthSourceLocal)
+}
+
+// Authenticate проверяет пароль локальной учетной записи.
+// Пользователей внешних провайдеров не принимает: пароля у них нет.
+func (p *LocalProvider) Authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	user, err := p.repo.GetUser(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("LocalProvider.Authenticate: %w", err)
+	}
+
+	if user == nil || user.AuthSource != model.AuthSourceLocal {
+		return nil, ErrUnknownUser
+	}
+
+	if !passhash.Verify(user.Password, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
@@ -2,17 +2,27 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/typefunco/dealer_dev_platform/internal/model"
 	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
 )
 
 type Repository interface {
 	CreateUser(ctx context.Context, user model.User) error
 	DeleteUser(ctx context.Context, login string) error
 	GetUser(ctx context.Context, login string) (*model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	UpsertExternalUser(ctx context.Context, user model.User) (*model.User, error)
+	RegisterLoginFailure(ctx context.Context, login string, maxFailures int, now, lockUntil time.Time) (*time.Time, error)
+	ResetLoginFailures(ctx context.Context, login string) error
+	UnlockUser(ctx context.Context, id int64) (string, error)
+	UpdatePassword(ctx context.Context, login, password string) error
 	Ping(ctx context.Context) error
 }
 
@@ -20,63 +30,267 @@ type JWTRepository interface {
 	ValidateJWT(jwt string) (*jwt.JWTClaims, error)
 	ValidateJWTLegacy(jwt string) error
 	GenerateJWT(login string, isAdmin bool, role string) (string, error)
+	NewClaims(login string, isAdmin bool, role string) (*jwt.JWTClaims, error)
+	SignJWT(claims *jwt.JWTClaims) (string, error)
+}
+
+// Options настройки входа через внешних провайдеров, второго фактора и ограничения попыток.
+type Options struct {
+	// Providers провайдеры входа по паролю (LDAP). Опрашиваются в переданном порядке,
+	// локальные учетные записи проверяются последними.
+	Providers []Provider
+
+	// OIDC вход через корпоративный портал; nil - выключен.
+	OIDC *OIDCProvider
+
+	// TwoFactor хранилище второго фактора (TOTP) и политики входа; nil - второй фактор выключен.
+	// Вход через OIDC тоже требует второй фактор платформы, кроме случая, когда включен OIDCConfig.TrustIDPMFA
+	// и amr в ID token содержит mfa.
+	TwoFactor TwoFactorRepository
+
+	// Throttle задержки после неудачных входов и блокировка учетной записи; нулевое значение - без ограничений.
+	Throttle ThrottleConfig
+
+	// Attempts журнал попыток входа; nil - попытки не записываются.
+	Attempts LoginAttemptRepository
+
+	// Sessions хранилище сессий; nil - сессии не отслеживаются, и JWT действует до истечения.
+	Sessions SessionRepository
+
+	// PasswordPolicy требования к паролю при его смене пользователем.
+	PasswordPolicy PasswordPolicy
+
+	// Mailer отправка приглашений и ссылок для сброса пароля; nil - письма не отправляются.
+	Mailer Mailer
+
+	// PasswordTokens хранилище ссылок из писем; nil - приглашения и сброс пароля выключены.
+	PasswordTokens PasswordTokenRepository
+
+	// PasswordLinks адрес и сроки ссылок из писем.
+	PasswordLinks PasswordLinkConfig
 }
 
 type Service struct {
-	repo   Repository
-	jwt    JWTRepository
-	logger *slog.Logger
+	repo      Repository
+	jwt       JWTRepository
+	providers []Provider
+	oidc      *OIDCProvider
+	twoFactor TwoFactorRepository
+	attempts  LoginAttemptRepository
+	sessions  SessionRepository
+	logger    *slog.Logger
+	now       func() time.Time
+
+	throttleCfg    ThrottleConfig
+	throttle       *loginThrottle
+	passwordPolicy PasswordPolicy
+
+	mailer         Mailer
+	passwordTokens PasswordTokenRepository
+	passwordLinks  PasswordLinkConfig
+	resetRequests  *resetRequests
+
+	challengesMu sync.Mutex
+	challenges   map[string]*loginChallenge
 }
 
 // NewService конструктор auth Service.
-func NewService(repo Repository, jwt JWTRepository, logger *slog.Logger) *Service {
-	return &Service{repo: repo, jwt: jwt, logger: logger}
+func NewService(repo Repository, jwt JWTRepository, opts Options, logger *slog.Logger) *Service {
+	providers := append(append([]Provider{}, opts.Providers...), NewLocalProvider(repo))
+	if opts.PasswordLinks.InviteTTL <= 0 {
+		opts.PasswordLinks.InviteTTL = defaultInviteTTL
+	}
+	if opts.PasswordLinks.ResetTTL <= 0 {
+		opts.PasswordLinks.ResetTTL = defaultResetTTL
+	}
+	return &Service{
+		repo:           repo,
+		jwt:            jwt,
+		providers:      providers,
+		oidc:           opts.OIDC,
+		twoFactor:      opts.TwoFactor,
+		attempts:       opts.Attempts,
+		sessions:       opts.Sessions,
+		logger:         logger,
+		now:            time.Now,
+		throttleCfg:    opts.Throttle,
+		throttle:       newLoginThrottle(opts.Throttle),
+		passwordPolicy: opts.PasswordPolicy,
+		mailer:         opts.Mailer,
+		passwordTokens: opts.PasswordTokens,
+		passwordLinks:  opts.PasswordLinks,
+		resetRequests:  &resetRequests{sent: make(map[int64]time.Time)},
+		challenges:     make(map[string]*loginChallenge),
+	}
 }
 
-// Login метод логина пользователя.
-func (s *Service) Login(ctx context.Context, login string, password string) (*jwt.JWTClaims, error) {
+// Login метод логина пользователя. Приостановленному или отключенному пользователю
+// с верным паролем возвращается model.ErrAccountInactive. Если пользователю нужен второй фактор,
+// вместо claims возвращается ошибка *TwoFactorChallenge с токеном второго шага.
+// При частых неудачах или блокировке учетной записи возвращается *LoginThrottled.
+// ip адрес клиента для ограничения попыток и журнала входов.
+func (s *Service) Login(ctx context.Context, login string, password string, ip string) (*jwt.JWTClaims, error) {
 	if login == "" || password == "" {
 		return nil, fmt.Errorf("AuthService.Login username or password is empty")
 	}
 
-	user, err := s.repo.GetUser(ctx, login)
+	if err := s.checkThrottle(ctx, login, ip, model.LoginMethodPassword); err != nil {
+		return nil, fmt.Errorf("AuthService.Login: %w", err)
+	}
+
+	user, err := s.authenticate(ctx, login, password)
 	if err != nil {
-		return nil, fmt.Errorf("AuthService.GetUser no user %w", err)
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.loginFailed(ctx, login, ip, model.LoginMethodPassword, model.LoginResultInvalidCredentials)
+		} else {
+			// Недоступный каталог не говорит о подборе пароля и в счетчики не идет
+			s.recordAttempt(ctx, login, ip, model.LoginMethodPassword, model.LoginResultError)
+		}
+		return nil, err
+	}
+
+	if user.StatusAt(s.now()) != model.UserStatusActive {
+		s.recordAttempt(ctx, login, ip, model.LoginMethodPassword, model.LoginResultInactive)
+		return nil, fmt.Errorf("AuthService.Login: %w", model.ErrAccountInactive)
 	}
 
-	if user == nil {
-		return nil, fmt.Errorf("AuthService.GetUser user not found")
+	challenge, err := s.twoFactorChallenge(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.Login: %w", err)
+	}
+	if challenge != nil {
+		return nil, challenge
 	}
 
-	// В реальном приложении здесь должна быть проверка хеша пароля
-	if user.Password != password {
-		return nil, fmt.Errorf("AuthService.Login invalid password")
+	s.loginSucceeded(ctx, user.Login, ip, model.LoginMethodPassword)
+	return s.issueClaims(user)
+}
+
+// OIDCEnabled сообщает, настроен ли вход через OIDC.
+func (s *Service) OIDCEnabled() bool {
+	return s.oidc != nil
+}
+
+// OIDCFrontendURL адрес фронтенда, куда пользователь возвращается после входа через OIDC.
+func (s *Service) OIDCFrontendURL() string {
+	if s.oidc == nil {
+		return ""
 	}
+	return s.oidc.FrontendURL()
+}
 
-	// Генерируем JWT с информацией о пользователе
-	token, err := s.jwt.GenerateJWT(user.Login, user.IsAdmin, string(user.Role))
+// OIDCLoginURL начинает вход через OIDC: возвращает адрес провайдера и state.
+func (s *Service) OIDCLoginURL(ctx context.Context, redirect string) (string, string, error) {
+	if s.oidc == nil {
+		return "", "", ErrOIDCDisabled
+	}
+	return s.oidc.AuthCodeURL(ctx, redirect)
+}
+
+// OIDCLogin завершает вход через OIDC: создает или обновляет пользователя
+// и возвращает claims сессии платформы и страницу после входа.
+// Пароль проверяет портал, поэтому задержки и блокировка здесь не действуют. Второй фактор платформы
+// запрашивается так же, как при входе по паролю: вместо claims возвращается ошибка *TwoFactorChallenge.
+// Исключение - портал подтвердил второй фактор в claim amr и ему разрешено доверять (OIDCConfig.TrustIDPMFA).
+func (s *Service) OIDCLogin(ctx context.Context, state, code, ip string) (*jwt.JWTClaims, string, error) {
+	if s.oidc == nil {
+		return nil, "", ErrOIDCDisabled
+	}
+
+	identity, err := s.oidc.Exchange(ctx, state, code)
+	if err != nil {
+		return nil, "", fmt.Errorf("AuthService.OIDCLogin: %w", err)
+	}
+
+	stored, err := s.repo.UpsertExternalUser(ctx, *identity.User)
 	if err != nil {
-		s.logger.Error("AuthService.GenerateJWT failed to generate JWT", "error", err)
-		return nil, fmt.Errorf("AuthService.GenerateJWT failed to generate JWT: %w", err)
+		s.recordAttempt(ctx, identity.User.Login, ip, model.LoginMethodOIDC, model.LoginResultNoAccess)
+		return nil, "", fmt.Errorf("AuthService.OIDCLogin failed to sync user: %w", err)
+	}
+	if stored.StatusAt(s.now()) != model.UserStatusActive {
+		s.recordAttempt(ctx, stored.Login, ip, model.LoginMethodOIDC, model.LoginResultInactive)
+		return nil, "", fmt.Errorf("AuthService.OIDCLogin: %w", model.ErrAccountInactive)
+	}
+
+	if !identity.MFA {
+		challenge, err := s.twoFactorChallenge(ctx, stored)
+		if err != nil {
+			return nil, "", fmt.Errorf("AuthService.OIDCLogin: %w", err)
+		}
+		if challenge != nil {
+			return nil, identity.Redirect, challenge
+		}
 	}
 
-	// Валидируем токен для получения claims
-	claims, err := s.jwt.ValidateJWT(token)
+	s.loginSucceeded(ctx, stored.Login, ip, model.LoginMethodOIDC)
+
+	claims, err := s.issueClaims(stored)
 	if err != nil {
-		s.logger.Error("AuthService.ValidateJWT failed to validate generated JWT", "error", err)
-		return nil, fmt.Errorf("AuthService.ValidateJWT failed to validate generated JWT: %w", err)
+		return nil, "", err
 	}
+	return claims, identity.Redirect, nil
+}
 
+// issueClaims создает claims нового токена пользователя. Токен подписывает StartSession,
+// и сессия сохраняется с ID (jti) этих claims.
+func (s *Service) issueClaims(user *model.User) (*jwt.JWTClaims, error) {
+	claims, err := s.jwt.NewClaims(user.Login, user.IsAdmin, string(user.Role))
+	if err != nil {
+		s.logger.Error("AuthService.issueClaims failed to create JWT claims", "error", err)
+		return nil, fmt.Errorf("AuthService.issueClaims failed to create JWT claims: %w", err)
+	}
 	return claims, nil
 }
 
+// authenticate опрашивает провайдеров по очереди. Пользователь внешнего провайдера
+// создается или обновляется в users. Недоступный провайдер не мешает входу через
+// следующий: его ошибка возвращается, только если никто не принял пароль.
+func (s *Service) authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	var providerErr error
+	for _, provider := range s.providers {
+		user, err := provider.Authenticate(ctx, login, password)
+		if err != nil {
+			if !errors.Is(err, ErrUnknownUser) && !errors.Is(err, ErrInvalidCredentials) {
+				s.logger.Warn("AuthService.Login provider failed", "provider", provider.Name(), "login", login, "error", err)
+				providerErr = err
+			}
+			continue
+		}
+
+		if user.AuthSource == model.AuthSourceLocal {
+			s.upgradePasswordHash(ctx, user, password)
+			return user, nil
+		}
+
+		stored, err := s.repo.UpsertExternalUser(ctx, *user)
+		if err != nil {
+			// Логин занят локальной учетной записью: она не переходит под управление каталога
+			s.logger.Warn("AuthService.Login failed to sync external user", "provider", provider.Name(), "login", user.Login, "error", err)
+			providerErr = err
+			continue
+		}
+		return stored, nil
+	}
+
+	if providerErr != nil {
+		return nil, fmt.Errorf("AuthService.Login: %w", providerErr)
+	}
+	return nil, fmt.Errorf("AuthService.Login: %w", ErrInvalidCredentials)
+}
+
 // Signup создает JWT и ошибку.
 func (s *Service) Signup(ctx context.Context, user model.User) (string, error) {
 	if user.Login == "" || user.Password == "" {
 		return "", fmt.Errorf("AuthService.Signup username or password is empty")
 	}
 
-	err := s.repo.CreateUser(ctx, user)
+	hash, err := passhash.Hash(user.Password)
+	if err != nil {
+		return "", fmt.Errorf("AuthService.Signup: %w", err)
+	}
+	user.Password = hash
+
+	err = s.repo.CreateUser(ctx, user)
 	if err != nil {
 		return "", fmt.Errorf("AuthService.CreateUser error %w", err)
 	}
@@ -92,8 +306,3 @@ func (s *Service) Signup(ctx context.Context, user model.User) (string, error) {
 func (s *Service) PingDatabase(ctx context.Context) error {
 	return s.repo.Ping(ctx)
 }
-
-// GenerateToken генерирует JWT токен для пользователя
-func (s *Service) GenerateToken(login string, isAdmin bool, role string) (string, error) {
-	return s.jwt.GenerateJWT(login, isAdmin, role)
-}
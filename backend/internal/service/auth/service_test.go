@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
+)
+
+// memoryRepository репозиторий пользователей в памяти.
+type memoryRepository struct {
+	users    map[string]*model.User
+	failures map[string]int
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, user model.User) error {
+	r.users[user.Login] = &user
+	return nil
+}
+
+func (r *memoryRepository) DeleteUser(ctx context.Context, login string) error {
+	delete(r.users, login)
+	return nil
+}
+
+func (r *memoryRepository) GetUser(ctx context.Context, login string) (*model.User, error) {
+	return r.users[login], nil
+}
+
+func (r *memoryRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	var found *model.User
+	for _, user := range r.users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) {
+			if found != nil {
+				return nil, nil
+			}
+			found = user
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryRepository) UpsertExternalUser(ctx context.Context, user model.User) (*model.User, error) {
+	if existing, ok := r.users[user.Login]; ok && existing.AuthSource == model.AuthSourceLocal {
+		return nil, repository.ErrAlreadyExists
+	}
+	user.Password = ""
+	r.users[user.Login] = &user
+	return &user, nil
+}
+
+func (r *memoryRepository) RegisterLoginFailure(ctx context.Context, login string, maxFailures int, now, lockUntil time.Time) (*time.Time, error) {
+	user, ok := r.users[login]
+	if !ok {
+		return nil, nil
+	}
+	if r.failures == nil {
+		r.failures = make(map[string]int)
+	}
+	if user.LockedUntil != nil && !user.LockedUntil.After(now) {
+		r.failures[login] = 0
+		user.LockedUntil = nil
+	}
+	r.failures[login]++
+	if r.failures[login] >= maxFailures {
+		user.LockedUntil = &lockUntil
+	}
+	return user.LockedUntil, nil
+}
+
+func (r *memoryRepository) ResetLoginFailures(ctx context.Context, login string) error {
+	delete(r.failures, login)
+	if user, ok := r.users[login]; ok {
+		user.LockedUntil = nil
+	}
+	return nil
+}
+
+func (r *memoryRepository) UnlockUser(ctx context.Context, id int64) (string, error) {
+	for login, user := range r.users {
+		if user.ID == id {
+			delete(r.failures, login)
+			user.LockedUntil = nil
+			return login, nil
+		}
+	}
+	return "", repository.ErrNotFound
+}
+
+func (r *memoryRepository) UpdatePassword(ctx context.Context, login, password string) error {
+	user, ok := r.users[login]
+	if !ok || user.AuthSource != model.AuthSourceLocal {
+		return repository.ErrNotFound
+	}
+	user.Password = password
+	return nil
+}
+
+func (r *memoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// stubProvider внешний провайдер с заранее заданными ответами.
+type stubProvider struct {
+	users map[string]*model.User
+	err   error
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func (p *stubProvider) Authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	user, ok := p.users[login]
+	if !ok {
+		return nil, ErrUnknownUser
+	}
+	if password != "directory-pass" {
+		return nil, ErrInvalidCredentials
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func TestLoginProviders(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	newRepository := func() *memoryRepository {
+		return &memoryRepository{users: map[string]*model.User{
+			"admin": {Login: "admin", Password: "local-pass", IsAdmin: true, Role: model.UserRoleAdmin, AuthSource: model.AuthSourceLocal},
+		}}
+	}
+	directory := &stubProvider{users: map[string]*model.User{
+		"ivanov": {Login: "ivanov", Role: model.UserRoleManager, Region: "Volga", AuthSource: model.AuthSourceLDAP, ExternalID: "uid=ivanov,ou=users"},
+		"admin":  {Login: "admin", Role: model.UserRoleViewer, AuthSource: model.AuthSourceLDAP, ExternalID: "uid=admin,ou=users"},
+	}}
+
+	t.Run("directory user is created on first login", func(t *testing.T) {
+		repo := newRepository()
+		service := NewService(repo, jwt.NewService(), Options{Providers: []Provider{directory}}, logger)
+
+		claims, err := service.Login(ctx, "ivanov", "directory-pass", "")
+		require.NoError(t, err)
+		assert.Equal(t, "ivanov", claims.Login)
+		assert.Equal(t, string(model.UserRoleManager), claims.Role)
+
+		require.Contains(t, repo.users, "ivanov")
+		assert.Equal(t, model.AuthSourceLDAP, repo.users["ivanov"].AuthSource)
+		assert.Equal(t, "Volga", repo.users["ivanov"].Region)
+	})
+
+	t.Run("directory user cannot log in with a local password", func(t *testing.T) {
+		repo := newRepository()
+		service := NewService(repo, jwt.NewService(), Options{Providers: []Provider{directory}}, logger)
+
+		_, err := service.Login(ctx, "ivanov", "directory-pass", "")
+		require.NoError(t, err)
+
+		_, err = NewService(repo, jwt.NewService(), Options{}, logger).Login(ctx, "ivanov", "directory-pass", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("local account keeps working and is not taken over", func(t *testing.T) {
+		repo := newRepository()
+		service := NewService(repo, jwt.NewService(), Options{Providers: []Provider{directory}}, logger)
+
+		claims, err := service.Login(ctx, "admin", "local-pass", "")
+		require.NoError(t, err)
+		assert.True(t, claims.IsAdmin)
+
+		_, err = service.Login(ctx, "admin", "directory-pass", "")
+		require.Error(t, err)
+		assert.Equal(t, model.AuthSourceLocal, repo.users["admin"].AuthSource)
+	})
+
+	t.Run("legacy plaintext password is rehashed on login", func(t *testing.T) {
+		repo := newRepository()
+		service := NewService(repo, jwt.NewService(), Options{}, logger)
+
+		_, err := service.Login(ctx, "admin", "wrong", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		assert.Equal(t, "local-pass", repo.users["admin"].Password)
+
+		_, err = service.Login(ctx, "admin", "local-pass", "")
+		require.NoError(t, err)
+		assert.True(t, passhash.IsHash(repo.users["admin"].Password))
+		assert.True(t, passhash.Verify(repo.users["admin"].Password, "local-pass"))
+
+		_, err = service.Login(ctx, "admin", "local-pass", "")
+		require.NoError(t, err)
+	})
+
+	t.Run("unavailable directory falls back to local accounts", func(t *testing.T) {
+		down := &stubProvider{err: errors.New("connection refused")}
+		service := NewService(newRepository(), jwt.NewService(), Options{Providers: []Provider{down}}, logger)
+
+		claims, err := service.Login(ctx, "admin", "local-pass", "")
+		require.NoError(t, err)
+		assert.Equal(t, "admin", claims.Login)
+
+		_, err = service.Login(ctx, "ivanov", "directory-pass", "")
+		assert.ErrorContains(t, err, "connection refused")
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		service := NewService(newRepository(), jwt.NewService(), Options{Providers: []Provider{directory}}, logger)
+
+		_, err := service.Login(ctx, "ivanov", "wrong", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = service.Login(ctx, "nobody", "wrong", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestParseGroupMappings(t *testing.T) {
+	mappings, err := ParseGroupMappings(" DDP-Admins=admin; CN=DDP-Sales,OU=Groups,DC=corp,DC=local=sales ;")
+	require.NoError(t, err)
+	assert.Equal(t, []GroupMapping{
+		{Group: "DDP-Admins", Value: "admin"},
+		{Group: "CN=DDP-Sales,OU=Groups,DC=corp,DC=local", Value: "sales"},
+	}, mappings)
+
+	for _, value := range []string{"DDP-Admins", "=admin", "DDP-Admins="} {
+		_, err := ParseGroupMappings(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLDAPApplyGroups(t *testing.T) {
+	provider, err := NewLDAPProvider(LDAPConfig{
+		URL:    "ldap://localhost:389",
+		BaseDN: "dc=corp,dc=local",
+		GroupRoles: []GroupMapping{
+			{Group: "DDP-Sales", Value: "sales"},
+			{Group: "CN=DDP-Managers,OU=Groups,DC=corp,DC=local", Value: "manager"},
+			{Group: "DDP-Admins", Value: "admin"},
+		},
+		GroupRegions: []GroupMapping{
+			{Group: "DDP-Volga", Value: "Поволжье"},
+			{Group: "DDP-Central", Value: "Central"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		groups  []string
+		ok      bool
+		role    model.UserRole
+		isAdmin bool
+		region  string
+	}{
+		{
+			name:   "highest role wins, region by first mapping",
+			groups: []string{"CN=DDP-Central,OU=Groups,DC=corp,DC=local", "cn=ddp-sales,ou=groups,dc=corp,dc=local", "CN=DDP-Managers,OU=Groups,DC=corp,DC=local", "CN=DDP-Volga,OU=Groups,DC=corp,DC=local"},
+			ok:     true,
+			role:   model.UserRoleManager,
+			region: "Volga",
+		},
+		{
+			name:    "admin without region gets all regions",
+			groups:  []string{"CN=DDP-Admins,OU=Groups,DC=corp,DC=local"},
+			ok:      true,
+			role:    model.UserRoleAdmin,
+			isAdmin: true,
+			region:  model.AllRussia,
+		},
+		{
+			name:   "full DN does not match a group with the same CN elsewhere",
+			groups: []string{"CN=DDP-Managers,OU=Other,DC=corp,DC=local"},
+			ok:     false,
+		},
+		{
+			name:   "no mapped groups",
+			groups: []string{"CN=Domain Users,CN=Users,DC=corp,DC=local"},
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &model.User{}
+			ok := provider.applyGroups(user, tt.groups)
+			require.Equal(t, tt.ok, ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, tt.role, user.Role)
+			assert.Equal(t, tt.isAdmin, user.IsAdmin)
+			assert.Equal(t, tt.region, user.Region)
+		})
+	}
+
+	_, err = NewLDAPProvider(LDAPConfig{URL: "ldap://localhost:389", BaseDN: "dc=corp,dc=local"})
+	assert.Error(t, err, "provider without roles would reject everyone")
+
+	_, err = NewLDAPProvider(LDAPConfig{URL: "ldap://localhost:389", BaseDN: "dc=corp,dc=local", GroupRoles: []GroupMapping{{Group: "DDP", Value: "root"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+)
+
+// sessionTouchInterval как часто обновлять время последнего запроса сессии:
+// писать в базу на каждый запрос незачем.
+const sessionTouchInterval = time.Minute
+
+// maxUserAgentLength длина колонки user_agent.
+const maxUserAgentLength = 500
+
+// ErrSessionNotFound у пользователя нет такой активной сессии.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository хранилище сессий пользователей.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, login string, session *model.Session) error
+	GetSessionByJTI(ctx context.Context, jti string) (*model.Session, error)
+	TouchSession(ctx context.Context, id int64, ip string, seenAt time.Time) error
+	ListActiveSessions(ctx context.Context, login string, now time.Time) ([]model.Session, error)
+	RevokeSession(ctx context.Context, login string, id int64, now time.Time) error
+	RevokeUserSessions(ctx context.Context, login, exceptJTI string, now time.Time) (int64, error)
+}
+
+// StartSession подписывает JWT из claims, выданных при входе, и сохраняет его сессию по ID (jti) этих claims.
+// ip и userAgent показываются пользователю в списке сессий.
+func (s *Service) StartSession(ctx context.Context, claims *jwt.JWTClaims, ip, userAgent string) (string, error) {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return "", fmt.Errorf("AuthService.StartSession: claims have no token ID or expiration")
+	}
+
+	token, err := s.jwt.SignJWT(claims)
+	if err != nil {
+		return "", fmt.Errorf("AuthService.StartSession failed to sign JWT: %w", err)
+	}
+
+	if s.sessions == nil {
+		return token, nil
+	}
+
+	session := &model.Session{
+		JTI:       claims.ID,
+		IP:        ip,
+		UserAgent: truncate(userAgent, maxUserAgentLength),
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if err := s.sessions.CreateSession(ctx, claims.Login, session); err != nil {
+		return "", fmt.Errorf("AuthService.StartSession: %w", err)
+	}
+
+	return token, nil
+}
+
+// ValidateSession проверяет, что сессия токена не отозвана. Токены без ID (выданные до
+// появления сессий) не принимаются: после обновления пользователь входит заново.
+// Если учетная запись приостановлена или отключена, возвращается model.ErrAccountInactive.
+func (s *Service) ValidateSession(ctx context.Context, claims *jwt.JWTClaims, ip string) error {
+	if s.sessions == nil {
+		return nil
+	}
+	if claims.ID == "" {
+		return model.ErrSessionRevoked
+	}
+
+	session, err := s.sessions.GetSessionByJTI(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.ErrSessionRevoked
+		}
+		return fmt.Errorf("AuthService.ValidateSession: %w", err)
+	}
+
+	now := s.now()
+	if !session.Active(now) {
+		return model.ErrSessionRevoked
+	}
+	// Сессии отзываются при смене состояния, но приостановка может начаться позже по дате
+	if model.EffectiveUserStatus(session.UserStatus, session.UserStatusFrom, session.UserStatusUntil, now) != model.UserStatusActive {
+		return model.ErrAccountInactive
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessions.TouchSession(ctx, session.ID, ip, now); err != nil {
+			s.logger.Warn("AuthService.ValidateSession failed to touch session", "session_id", session.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// ListSessions возвращает активные сессии пользователя и отмечает текущую.
+func (s *Service) ListSessions(ctx context.Context, claims *jwt.JWTClaims) ([]model.Session, error) {
+	if s.sessions == nil {
+		return []model.Session{}, nil
+	}
+
+	sessions, err := s.sessions.ListActiveSessions(ctx, claims.Login, s.now())
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.ListSessions: %w", err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].JTI == claims.ID
+	}
+	return sessions, nil
+}
+
+// RevokeSession отзывает сессию пользователя: токен этой сессии больше не принимается.
+func (s *Service) RevokeSession(ctx context.Context, login string, id int64) error {
+	if s.sessions == nil {
+		return fmt.Errorf("AuthService.RevokeSession: %w", ErrSessionNotFound)
+	}
+
+	if err := s.sessions.RevokeSession(ctx, login, id, s.now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("AuthService.RevokeSession: %w", ErrSessionNotFound)
+		}
+		return fmt.Errorf("AuthService.RevokeSession: %w", err)
+	}
+
+	s.logger.Info("Session revoked", "login", login, "session_id", id)
+	return nil
+}
+
+// Logout отзывает сессию, из которой пришел запрос.
+func (s *Service) Logout(ctx context.Context, claims *jwt.JWTClaims) error {
+	if s.sessions == nil || claims.ID == "" {
+		return nil
+	}
+
+	session, err := s.sessions.GetSessionByJTI(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("AuthService.Logout: %w", err)
+	}
+
+	if err := s.sessions.RevokeSession(ctx, claims.Login, session.ID, s.now()); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("AuthService.Logout: %w", err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions отзывает все сессии пользователя, кроме текущей, и возвращает их число.
+func (s *Service) RevokeOtherSessions(ctx context.Context, claims *jwt.JWTClaims) (int64, error) {
+	if s.sessions == nil {
+		return 0, nil
+	}
+
+	revoked, err := s.sessions.RevokeUserSessions(ctx, claims.Login, claims.ID, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("AuthService.RevokeOtherSessions: %w", err)
+	}
+
+	if revoked > 0 {
+		s.logger.Info("Other sessions revoked", "login", claims.Login, "count", revoked)
+	}
+	return revoked, nil
+}
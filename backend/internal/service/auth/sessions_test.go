@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
+)
+
+// memorySessionRepository хранилище сессий в памяти. users - источник состояния учетных записей.
+type memorySessionRepository struct {
+	sessions []*model.Session
+	logins   map[int64]string
+	users    *memoryRepository
+}
+
+func (r *memorySessionRepository) CreateSession(ctx context.Context, login string, session *model.Session) error {
+	if r.logins == nil {
+		r.logins = make(map[int64]string)
+	}
+	session.ID = int64(len(r.sessions) + 1)
+	session.CreatedAt = session.ExpiresAt.Add(-time.Hour)
+	session.LastSeenAt = session.CreatedAt
+	copied := *session
+	r.sessions = append(r.sessions, &copied)
+	r.logins[session.ID] = login
+	return nil
+}
+
+func (r *memorySessionRepository) GetSessionByJTI(ctx context.Context, jti string) (*model.Session, error) {
+	for _, session := range r.sessions {
+		if session.JTI == jti {
+			copied := *session
+			if r.users != nil {
+				if user, ok := r.users.users[r.logins[session.ID]]; ok {
+					copied.UserStatus, copied.UserStatusFrom, copied.UserStatusUntil = user.Status, user.StatusFrom, user.StatusUntil
+				}
+			}
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memorySessionRepository) TouchSession(ctx context.Context, id int64, ip string, seenAt time.Time) error {
+	session := r.sessions[id-1]
+	session.IP = ip
+	session.LastSeenAt = seenAt
+	return nil
+}
+
+func (r *memorySessionRepository) ListActiveSessions(ctx context.Context, login string, now time.Time) ([]model.Session, error) {
+	var sessions []model.Session
+	for _, session := range r.sessions {
+		if r.logins[session.ID] == login && session.Active(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *memorySessionRepository) RevokeSession(ctx context.Context, login string, id int64, now time.Time) error {
+	if id <= 0 || int(id) > len(r.sessions) || r.logins[id] != login || r.sessions[id-1].RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	r.sessions[id-1].RevokedAt = &now
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeUserSessions(ctx context.Context, login, exceptJTI string, now time.Time) (int64, error) {
+	var revoked int64
+	for _, session := range r.sessions {
+		if r.logins[session.ID] == login && session.JTI != exceptJTI && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	jwtService := jwt.NewService()
+
+	type fixture struct {
+		service  *Service
+		users    *memoryRepository
+		sessions *memorySessionRepository
+		clock    time.Time
+	}
+	setup := func() *fixture {
+		f := &fixture{
+			users: &memoryRepository{users: map[string]*model.User{
+				"petrov": {ID: 2, Login: "petrov", Password: "petrov-pass1", Role: model.UserRoleManager, AuthSource: model.AuthSourceLocal},
+				"ivanov": {ID: 3, Login: "ivanov", Role: model.UserRoleManager, AuthSource: model.AuthSourceLDAP},
+			}},
+			sessions: &memorySessionRepository{},
+			clock:    time.Now(),
+		}
+		f.service = NewService(f.users, jwtService, Options{
+			Sessions:       f.sessions,
+			PasswordPolicy: PasswordPolicy{MinLength: 8, RequireDigit: true},
+		}, logger)
+		f.service.now = func() time.Time { return f.clock }
+		return f
+	}
+	// signIn входит под login и возвращает claims выданного токена
+	signIn := func(t *testing.T, f *fixture, login, ip string) *jwt.JWTClaims {
+		issued, err := jwtService.NewClaims(login, false, string(model.UserRoleManager))
+		require.NoError(t, err)
+		token, err := f.service.StartSession(ctx, issued, ip, "Firefox")
+		require.NoError(t, err)
+		claims, err := jwtService.ValidateJWT(token)
+		require.NoError(t, err)
+		assert.Equal(t, issued.ID, claims.ID)
+		return claims
+	}
+
+	t.Run("token is accepted until its session is revoked", func(t *testing.T) {
+		f := setup()
+		laptop := signIn(t, f, "petrov", "10.0.0.1")
+		phone := signIn(t, f, "petrov", "10.0.0.2")
+
+		require.NoError(t, f.service.ValidateSession(ctx, laptop, "10.0.0.1"))
+		require.NoError(t, f.service.ValidateSession(ctx, phone, "10.0.0.2"))
+
+		sessions, err := f.service.ListSessions(ctx, laptop)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		assert.True(t, sessions[0].Current)
+		assert.False(t, sessions[1].Current)
+		assert.Equal(t, "Firefox", sessions[1].UserAgent)
+
+		require.NoError(t, f.service.RevokeSession(ctx, "petrov", sessions[1].ID))
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, phone, "10.0.0.2"), model.ErrSessionRevoked)
+		assert.NoError(t, f.service.ValidateSession(ctx, laptop, "10.0.0.1"))
+
+		assert.ErrorIs(t, f.service.RevokeSession(ctx, "petrov", sessions[1].ID), ErrSessionNotFound)
+		assert.ErrorIs(t, f.service.RevokeSession(ctx, "ivanov", sessions[0].ID), ErrSessionNotFound, "another user's session")
+	})
+
+	t.Run("logout revokes only the current session", func(t *testing.T) {
+		f := setup()
+		laptop := signIn(t, f, "petrov", "")
+		phone := signIn(t, f, "petrov", "")
+
+		require.NoError(t, f.service.Logout(ctx, laptop))
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, laptop, ""), model.ErrSessionRevoked)
+		assert.NoError(t, f.service.ValidateSession(ctx, phone, ""))
+
+		// Повторный выход не ошибка
+		assert.NoError(t, f.service.Logout(ctx, laptop))
+	})
+
+	t.Run("sign out other sessions", func(t *testing.T) {
+		f := setup()
+		laptop := signIn(t, f, "petrov", "")
+		phone := signIn(t, f, "petrov", "")
+		tablet := signIn(t, f, "petrov", "")
+
+		revoked, err := f.service.RevokeOtherSessions(ctx, laptop)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), revoked)
+		assert.NoError(t, f.service.ValidateSession(ctx, laptop, ""))
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, phone, ""), model.ErrSessionRevoked)
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, tablet, ""), model.ErrSessionRevoked)
+	})
+
+	t.Run("token without a session is rejected", func(t *testing.T) {
+		f := setup()
+
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, &jwt.JWTClaims{Login: "petrov"}, ""), model.ErrSessionRevoked, "token issued before sessions")
+
+		token, err := jwtService.GenerateJWT("petrov", false, string(model.UserRoleManager))
+		require.NoError(t, err)
+		claims, err := jwtService.ValidateJWT(token)
+		require.NoError(t, err)
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, claims, ""), model.ErrSessionRevoked)
+	})
+
+	t.Run("last seen is updated at most once a minute", func(t *testing.T) {
+		f := setup()
+		claims := signIn(t, f, "petrov", "10.0.0.1")
+		seen := f.sessions.sessions[0].LastSeenAt
+
+		f.clock = seen.Add(30 * time.Second)
+		require.NoError(t, f.service.ValidateSession(ctx, claims, "10.0.0.9"))
+		assert.Equal(t, seen, f.sessions.sessions[0].LastSeenAt)
+
+		f.clock = seen.Add(2 * time.Minute)
+		require.NoError(t, f.service.ValidateSession(ctx, claims, "10.0.0.9"))
+		assert.Equal(t, f.clock, f.sessions.sessions[0].LastSeenAt)
+		assert.Equal(t, "10.0.0.9", f.sessions.sessions[0].IP)
+	})
+
+	t.Run("password change signs out other sessions", func(t *testing.T) {
+		f := setup()
+		laptop := signIn(t, f, "petrov", "")
+		phone := signIn(t, f, "petrov", "")
+
+		err := f.service.ChangePassword(ctx, laptop, model.PasswordChangeRequest{CurrentPassword: "petrov-pass1", NewPassword: "new-pass-2"}, "")
+		require.NoError(t, err)
+		assert.True(t, passhash.Verify(f.users.users["petrov"].Password, "new-pass-2"))
+		assert.NoError(t, f.service.ValidateSession(ctx, laptop, ""))
+		assert.ErrorIs(t, f.service.ValidateSession(ctx, phone, ""), model.ErrSessionRevoked)
+
+		_, err = f.service.Login(ctx, "petrov", "new-pass-2", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("password change is rejected", func(t *testing.T) {
+		f := setup()
+		petrov := signIn(t, f, "petrov", "")
+
+		err := f.service.ChangePassword(ctx, petrov, model.PasswordChangeRequest{CurrentPassword: "wrong", NewPassword: "new-pass-2"}, "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+		err = f.service.ChangePassword(ctx, petrov, model.PasswordChangeRequest{CurrentPassword: "petrov-pass1", NewPassword: "short"}, "")
+		assert.ErrorIs(t, err, ErrWeakPassword)
+		var fields model.ValidationErrors
+		require.ErrorAs(t, err, &fields)
+		assert.Len(t, fields, 2)
+
+		err = f.service.ChangePassword(ctx, petrov, model.PasswordChangeRequest{CurrentPassword: "petrov-pass1", NewPassword: "petrov-pass1"}, "")
+		assert.ErrorIs(t, err, ErrWeakPassword)
+
+		ivanov := signIn(t, f, "ivanov", "")
+		err = f.service.ChangePassword(ctx, ivanov, model.PasswordChangeRequest{CurrentPassword: "directory-pass", NewPassword: "new-pass-2"}, "")
+		assert.ErrorIs(t, err, ErrExternalPassword)
+
+		assert.Equal(t, "petrov-pass1", f.users.users["petrov"].Password)
+	})
+}
+
+func TestInactiveAccount(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	jwtService := jwt.NewService()
+
+	now := time.Now()
+	tomorrow := now.Add(24 * time.Hour)
+	users := &memoryRepository{users: map[string]*model.User{
+		"petrov":  {ID: 2, Login: "petrov", Password: "petrov-pass1", Role: model.UserRoleManager, AuthSource: model.AuthSourceLocal},
+		"sidorov": {ID: 3, Login: "sidorov", Password: "sidorov-pass1", Role: model.UserRoleSales, AuthSource: model.AuthSourceLocal, Status: model.UserStatusDeactivated},
+		"ivanov": {ID: 4, Login: "ivanov", Password: "ivanov-pass1", Role: model.UserRoleSales, AuthSource: model.AuthSourceLocal,
+			Status: model.UserStatusSuspended, StatusFrom: &tomorrow},
+	}}
+	sessions := &memorySessionRepository{users: users}
+	service := NewService(users, jwtService, Options{Sessions: sessions}, logger)
+	clock := now
+	service.now = func() time.Time { return clock }
+
+	_, err := service.Login(ctx, "sidorov", "sidorov-pass1", "")
+	assert.ErrorIs(t, err, model.ErrAccountInactive)
+	_, err = service.Login(ctx, "sidorov", "wrong", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "status is not revealed without the password")
+
+	// Приостановка с завтрашнего дня: сегодня вход разрешен, завтра токен перестает приниматься
+	ivanov, err := service.Login(ctx, "ivanov", "ivanov-pass1", "")
+	require.NoError(t, err)
+	token, err := service.StartSession(ctx, ivanov, "", "")
+	require.NoError(t, err)
+	claims, err := jwtService.ValidateJWT(token)
+	require.NoError(t, err)
+	require.NoError(t, service.ValidateSession(ctx, claims, ""))
+
+	clock = tomorrow
+	assert.ErrorIs(t, service.ValidateSession(ctx, claims, ""), model.ErrAccountInactive)
+	_, err = service.Login(ctx, "ivanov", "ivanov-pass1", "")
+	assert.ErrorIs(t, err, model.ErrAccountInactive)
+
+	// После возврата в работу вход снова разрешен
+	users.users["ivanov"].Status = model.UserStatusActive
+	users.users["ivanov"].StatusFrom = nil
+	assert.NoError(t, service.ValidateSession(ctx, claims, ""))
+	_, err = service.Login(ctx, "petrov", "petrov-pass1", "")
+	assert.NoError(t, err)
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		password string
+		problems []string
+	}{
+		{name: "meets every requirement", password: "Зима-2025-года"},
+		{name: "empty", password: "", problems: []string{
+			"must be at least 10 characters",
+			"must contain an uppercase letter",
+			"must contain a lowercase letter",
+			"must contain a digit",
+			"must contain a special character",
+		}},
+		{name: "no symbol or digit", password: "Correcthorse", problems: []string{"must contain a digit", "must contain a special character"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, "petrov")
+			if tt.problems == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var fields model.ValidationErrors
+			require.ErrorAs(t, err, &fields)
+			assert.ErrorIs(t, err, ErrWeakPassword)
+			messages := make([]string, 0, len(fields))
+			for _, field := range fields {
+				assert.Equal(t, "new_password", field.Field)
+				messages = append(messages, field.Message)
+			}
+			assert.Equal(t, tt.problems, messages)
+		})
+	}
+
+	err := PasswordPolicy{RequireDigit: true}.Validate("Petrov1", "petrov1")
+	var fields model.ValidationErrors
+	require.ErrorAs(t, err, &fields)
+	assert.Equal(t, model.ValidationErrors{{Field: "new_password", Message: "must not match the login"}}, fields)
+
+	assert.Error(t, PasswordPolicy{}.Validate("", ""), "zero policy still requires a password")
+}
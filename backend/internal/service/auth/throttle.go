@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxThrottleEntries ограничивает число отслеживаемых логинов и адресов, чтобы перебор
+// случайных логинов не раздувал память. При переполнении забываются самые давние неудачи.
+const maxThrottleEntries = 100000
+
+// ThrottleConfig ограничение попыток входа. Нулевое значение выключает ограничения.
+type ThrottleConfig struct {
+	// MaxFailures неудачных входов подряд, после которых учетная запись блокируется
+	// на LockoutDuration. 0 - не блокировать.
+	MaxFailures     int
+	LockoutDuration time.Duration
+
+	// BackoffBase задержка после первой неудачи; каждая следующая неудача удваивает ее
+	// до BackoffMax. 0 - без задержек.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// IPMaxFailures неудач с одного адреса, после которых задержка начинает действовать
+	// и для адреса: так перебор по многим логинам тоже замедляется. 0 - без ограничения по адресу.
+	IPMaxFailures int
+
+	// Window через сколько после последней неудачи счетчик задержек забывается.
+	Window time.Duration
+}
+
+// LoginThrottled вход отклонен без проверки пароля: слишком частые неудачи или учетная запись заблокирована.
+type LoginThrottled struct {
+	RetryAfter time.Duration
+	Locked     bool // Учетная запись заблокирована; иначе действует задержка
+}
+
+func (e *LoginThrottled) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("account is locked, retry after %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// RetryAfterSeconds время ожидания в секундах для заголовка Retry-After, с округлением вверх.
+func (e *LoginThrottled) RetryAfterSeconds() int {
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// throttleEntry неудачи подряд по одному ключу.
+type throttleEntry struct {
+	failures    int
+	lastFailure time.Time
+}
+
+// loginThrottle экспоненциальные задержки между попытками входа по логину и по адресу.
+// Хранится в памяти процесса: при нескольких экземплярах у каждого свой счетчик,
+// а блокировку учетной записи, общую для всех, хранит база.
+type loginThrottle struct {
+	cfg ThrottleConfig
+
+	mu      sync.Mutex
+	entries map[string]*throttleEntry
+}
+
+func newLoginThrottle(cfg ThrottleConfig) *loginThrottle {
+	return &loginThrottle{cfg: cfg, entries: make(map[string]*throttleEntry)}
+}
+
+// accountKey ключ учетной записи: логины LDAP не зависят от регистра.
+func accountKey(login string) string {
+	return "login:" + strings.ToLower(login)
+}
+
+// ipKey ключ адреса; пустой адрес не отслеживается.
+func ipKey(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	return "ip:" + ip
+}
+
+// retryAfter сколько осталось ждать до следующей попытки входа; 0 - можно пробовать.
+func (t *loginThrottle) retryAfter(login, ip string, now time.Time) time.Duration {
+	if t.cfg.BackoffBase <= 0 {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	wait := t.wait(accountKey(login), 0, now)
+	if t.cfg.IPMaxFailures > 0 {
+		if ipWait := t.wait(ipKey(ip), t.cfg.IPMaxFailures, now); ipWait > wait {
+			wait = ipWait
+		}
+	}
+	return wait
+}
+
+// wait задержка по ключу: первые free неудач бесплатны, дальше задержка удваивается.
+func (t *loginThrottle) wait(key string, free int, now time.Time) time.Duration {
+	entry, ok := t.entries[key]
+	if !ok || entry.failures <= free {
+		return 0
+	}
+
+	delay := t.cfg.BackoffBase * time.Duration(math.Pow(2, float64(min(entry.failures-free-1, 30))))
+	if t.cfg.BackoffMax > 0 && delay > t.cfg.BackoffMax {
+		delay = t.cfg.BackoffMax
+	}
+
+	remaining := entry.lastFailure.Add(delay).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// fail учитывает неудачный вход по логину и по адресу.
+func (t *loginThrottle) fail(login, ip string, now time.Time) {
+	if t.cfg.BackoffBase <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.entries) >= maxThrottleEntries {
+		t.prune(now)
+	}
+
+	keys := []string{accountKey(login)}
+	if key := ipKey(ip); key != "" && t.cfg.IPMaxFailures > 0 {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		entry, ok := t.entries[key]
+		if !ok || t.expired(entry, now) {
+			entry = &throttleEntry{}
+			t.entries[key] = entry
+		}
+		entry.failures++
+		entry.lastFailure = now
+	}
+}
+
+// reset забывает неудачи учетной записи после успешного входа или разблокировки.
+// Счетчик адреса не сбрасывается: иначе перебор можно разбавлять входом в свою учетную запись.
+func (t *loginThrottle) reset(login string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, accountKey(login))
+}
+
+func (t *loginThrottle) expired(entry *throttleEntry, now time.Time) bool {
+	return t.cfg.Window > 0 && now.Sub(entry.lastFailure) > t.cfg.Window
+}
+
+// prune удаляет забытые записи, а если этого мало - записи старше средней давности.
+func (t *loginThrottle) prune(now time.Time) {
+	for key, entry := range t.entries {
+		if t.expired(entry, now) {
+			delete(t.entries, key)
+		}
+	}
+	if len(t.entries) < maxThrottleEntries {
+		return
+	}
+
+	var average time.Duration
+	for _, entry := range t.entries {
+		average += now.Sub(entry.lastFailure) / time.Duration(len(t.entries))
+	}
+	for key, entry := range t.entries {
+		if now.Sub(entry.lastFailure) >= average {
+			delete(t.entries, key)
+		}
+	}
+}
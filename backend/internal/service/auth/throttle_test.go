@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+)
+
+// memoryLoginAttemptRepository журнал попыток входа в памяти.
+type memoryLoginAttemptRepository struct {
+	attempts []model.LoginAttempt
+}
+
+func (r *memoryLoginAttemptRepository) RecordLoginAttempt(ctx context.Context, attempt model.LoginAttempt) error {
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) ListLoginAttempts(ctx context.Context, filter model.LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	return r.attempts, nil
+}
+
+// results итоги записанных попыток по порядку.
+func (r *memoryLoginAttemptRepository) results() []model.LoginResult {
+	results := make([]model.LoginResult, 0, len(r.attempts))
+	for _, attempt := range r.attempts {
+		results = append(results, attempt.Result)
+	}
+	return results
+}
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	type fixture struct {
+		service  *Service
+		users    *memoryRepository
+		attempts *memoryLoginAttemptRepository
+		clock    time.Time
+	}
+	setup := func(cfg ThrottleConfig) *fixture {
+		f := &fixture{
+			users: &memoryRepository{users: map[string]*model.User{
+				"petrov":  {ID: 2, Login: "petrov", Password: "petrov-pass", Role: model.UserRoleManager, AuthSource: model.AuthSourceLocal},
+				"sidorov": {ID: 3, Login: "sidorov", Password: "sidorov-pass", Role: model.UserRoleManager, AuthSource: model.AuthSourceLocal},
+			}},
+			attempts: &memoryLoginAttemptRepository{},
+			clock:    time.Date(2025, 10, 31, 9, 0, 0, 0, time.UTC),
+		}
+		f.service = NewService(f.users, jwt.NewService(), Options{Throttle: cfg, Attempts: f.attempts}, logger)
+		f.service.now = func() time.Time { return f.clock }
+		return f
+	}
+	// throttled проверяет, что вход отклонен без проверки пароля, и возвращает время ожидания
+	throttled := func(t *testing.T, err error) *LoginThrottled {
+		var throttled *LoginThrottled
+		require.True(t, errors.As(err, &throttled), "expected throttling, got %v", err)
+		return throttled
+	}
+
+	t.Run("backoff doubles after each failure", func(t *testing.T) {
+		f := setup(ThrottleConfig{BackoffBase: time.Second, BackoffMax: 4 * time.Second, Window: time.Hour})
+
+		_, err := f.service.Login(ctx, "petrov", "wrong", "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+		assert.Equal(t, time.Second, throttled(t, err).RetryAfter)
+
+		for _, wait := range []time.Duration{2 * time.Second, 4 * time.Second, 4 * time.Second} {
+			f.clock = f.clock.Add(time.Minute)
+			_, err = f.service.Login(ctx, "petrov", "wrong", "10.0.0.1")
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+
+			_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+			assert.Equal(t, wait, throttled(t, err).RetryAfter)
+		}
+
+		f.clock = f.clock.Add(4 * time.Second)
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+		require.NoError(t, err)
+
+		// Успешный вход сбрасывает задержку учетной записи
+		_, err = f.service.Login(ctx, "petrov", "wrong", "10.0.0.1")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+		assert.Equal(t, time.Second, throttled(t, err).RetryAfter)
+	})
+
+	t.Run("failures are forgotten after the window", func(t *testing.T) {
+		f := setup(ThrottleConfig{BackoffBase: time.Second, BackoffMax: time.Minute, Window: 10 * time.Minute})
+
+		for range 5 {
+			_, err := f.service.Login(ctx, "petrov", "wrong", "")
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+			f.clock = f.clock.Add(time.Minute)
+		}
+
+		f.clock = f.clock.Add(11 * time.Minute)
+		_, err := f.service.Login(ctx, "petrov", "wrong", "")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "")
+		assert.Equal(t, time.Second, throttled(t, err).RetryAfter)
+	})
+
+	t.Run("account is locked after max failures until unlocked", func(t *testing.T) {
+		f := setup(ThrottleConfig{MaxFailures: 3, LockoutDuration: 15 * time.Minute})
+
+		for range 3 {
+			_, err := f.service.Login(ctx, "petrov", "wrong", "10.0.0.1")
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+		require.NotNil(t, f.users.users["petrov"].LockedUntil)
+
+		// Верный пароль не помогает, пока действует блокировка
+		_, err := f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.2")
+		locked := throttled(t, err)
+		assert.True(t, locked.Locked)
+		assert.Equal(t, 15*time.Minute, locked.RetryAfter)
+
+		require.NoError(t, f.service.UnlockUser(ctx, 2, "admin"))
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.2")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, f.service.UnlockUser(ctx, 42, "admin"), ErrUnknownUser)
+	})
+
+	t.Run("lock expires and the count starts over", func(t *testing.T) {
+		f := setup(ThrottleConfig{MaxFailures: 2, LockoutDuration: 15 * time.Minute})
+
+		for range 2 {
+			_, err := f.service.Login(ctx, "petrov", "wrong", "")
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+
+		f.clock = f.clock.Add(15*time.Minute + time.Second)
+		_, err := f.service.Login(ctx, "petrov", "wrong", "")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "")
+		require.NoError(t, err)
+		assert.Nil(t, f.users.users["petrov"].LockedUntil)
+	})
+
+	t.Run("one address is slowed down across logins", func(t *testing.T) {
+		f := setup(ThrottleConfig{BackoffBase: time.Second, BackoffMax: time.Minute, IPMaxFailures: 2, Window: time.Hour})
+
+		for _, login := range []string{"petrov", "sidorov", "nobody"} {
+			f.clock = f.clock.Add(time.Minute)
+			_, err := f.service.Login(ctx, login, "wrong", "10.0.0.1")
+			require.ErrorIs(t, err, ErrInvalidCredentials)
+		}
+
+		// Третья неудача с адреса сверх двух бесплатных: задержка для всех логинов с него
+		_, err := f.service.Login(ctx, "sidorov", "sidorov-pass", "10.0.0.1")
+		assert.Equal(t, time.Second, throttled(t, err).RetryAfter)
+
+		// С другого адреса sidorov входит: его собственная задержка уже прошла
+		_, err = f.service.Login(ctx, "sidorov", "sidorov-pass", "10.0.0.2")
+		require.NoError(t, err)
+	})
+
+	t.Run("attempts are recorded with the address", func(t *testing.T) {
+		f := setup(ThrottleConfig{BackoffBase: time.Second, BackoffMax: time.Minute, Window: time.Hour})
+
+		_, err := f.service.Login(ctx, "petrov", "wrong", "10.0.0.1")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+		throttled(t, err)
+		f.clock = f.clock.Add(time.Second)
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+		require.NoError(t, err)
+
+		assert.Equal(t, []model.LoginResult{
+			model.LoginResultInvalidCredentials,
+			model.LoginResultThrottled,
+			model.LoginResultSuccess,
+		}, f.attempts.results())
+		for _, attempt := range f.attempts.attempts {
+			assert.Equal(t, "petrov", attempt.Login)
+			assert.Equal(t, "10.0.0.1", attempt.IP)
+			assert.Equal(t, model.LoginMethodPassword, attempt.Method)
+		}
+		assert.True(t, f.attempts.attempts[2].Success)
+	})
+
+	t.Run("unavailable directory is not counted as a failure", func(t *testing.T) {
+		f := setup(ThrottleConfig{MaxFailures: 1, LockoutDuration: time.Minute, BackoffBase: time.Second, Window: time.Hour})
+		f.service.providers = append([]Provider{&stubProvider{err: errors.New("connection refused")}}, f.service.providers...)
+
+		_, err := f.service.Login(ctx, "ivanov", "any", "10.0.0.1")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, []model.LoginResult{model.LoginResultError, model.LoginResultSuccess}, f.attempts.results())
+	})
+}
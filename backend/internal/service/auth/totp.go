@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// totpIssuer название платформы в приложении-аутентификаторе.
+	totpIssuer = "Dealer Development Platform"
+
+	totpDigits      = 6
+	totpPeriod      = 30 * time.Second
+	totpSecretBytes = 20 // 160 бит, как рекомендует RFC 4226
+
+	// totpSkew сколько соседних интервалов принимается из-за расхождения часов телефона и сервера.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5 // 40 бит: восемь символов base32
+)
+
+// totpEncoding кодировка секрета, которую понимают приложения-аутентификаторы.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateTOTPSecret генерирует секрет TOTP в base32.
+func generateTOTPSecret() (string, error) {
+	buf := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generateTOTPSecret: %w", err)
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// totpStep номер 30-секундного интервала для момента t.
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// totpCode код интервала step (HOTP из RFC 4226 со счетчиком-интервалом, HMAC-SHA1).
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// verifyTOTP проверяет код в окне ±totpSkew интервалов и возвращает интервал, которому он соответствует.
+func verifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// provisioningURI адрес otpauth://, который приложение-аутентификатор читает из QR-кода.
+func provisioningURI(account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", totpIssuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(totpDigits))
+	values.Set("period", fmt.Sprint(int(totpPeriod/time.Second)))
+
+	label := url.PathEscape(totpIssuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// generateRecoveryCodes генерирует коды восстановления вида xxxx-xxxx и их хэши для хранения.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		buf := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("generateRecoveryCodes: %w", err)
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(buf))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode хэширует код восстановления без учета регистра, пробелов и дефисов.
+// Код случайный, а число попыток входа ограничено, поэтому медленный хэш паролей не нужен.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
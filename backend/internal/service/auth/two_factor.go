@@ -0,0 +1,487 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+)
+
+const (
+	// twoFactorChallengeTTL сколько ждать кода после верного пароля.
+	twoFactorChallengeTTL = 5 * time.Minute
+
+	// maxTwoFactorAttempts попыток ввода кода на один вход; потом нужно снова ввести пароль.
+	maxTwoFactorAttempts = 5
+
+	// maxPendingChallenges ограничивает незавершенные входы, чтобы их нельзя было копить бесконечно.
+	maxPendingChallenges = 10000
+)
+
+var (
+	// ErrTwoFactorNotEnabled второй фактор не подключен или не настроен.
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+
+	// ErrTwoFactorAlreadyEnabled второй фактор уже подключен.
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrTwoFactorMandatory политика не разрешает отключить второй фактор.
+	ErrTwoFactorMandatory = errors.New("two-factor authentication is required by policy")
+
+	// ErrInvalidTwoFactorCode код TOTP или код восстановления не подошел.
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+	// ErrInvalidChallenge вход не начинался, истек или исчерпал попытки.
+	ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+)
+
+// TwoFactorRepository хранилище второго фактора и политики входа.
+type TwoFactorRepository interface {
+	GetTOTP(ctx context.Context, userID int64) (*model.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID int64, secret string) error
+	EnableTOTP(ctx context.Context, userID, step int64, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	CountRecoveryCodes(ctx context.Context, userID int64) (int, error)
+	DeleteTOTP(ctx context.Context, userID int64) error
+	GetAuthPolicy(ctx context.Context) (*model.AuthPolicy, error)
+	UpdateAuthPolicy(ctx context.Context, policy *model.AuthPolicy) error
+}
+
+// TwoFactorChallenge возвращается из Login вместо claims, когда пароль верный,
+// но JWT выдается только после второго шага (CompleteTwoFactorLogin).
+type TwoFactorChallenge struct {
+	Token              string
+	EnrollmentRequired bool // Политика требует второй фактор, а он еще не подключен
+	ExpiresAt          time.Time
+}
+
+func (c *TwoFactorChallenge) Error() string {
+	return "two-factor authentication required"
+}
+
+// loginChallenge незавершенный вход: пароль проверен, ждем код.
+type loginChallenge struct {
+	user      *model.User
+	enroll    bool
+	attempts  int
+	expiresAt time.Time
+}
+
+// TwoFactorStatus возвращает состояние второго фактора пользователя.
+func (s *Service) TwoFactorStatus(ctx context.Context, login string) (*model.TwoFactorStatus, error) {
+	user, err := s.twoFactorUser(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.TwoFactorStatus: %w", err)
+	}
+
+	totp, err := s.getTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.TwoFactorStatus: %w", err)
+	}
+	required, err := s.twoFactorRequired(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.TwoFactorStatus: %w", err)
+	}
+
+	status := &model.TwoFactorStatus{Required: required}
+	if totp.Enabled() {
+		status.Enabled = true
+		status.EnabledAt = totp.EnabledAt
+		if status.RecoveryCodesLeft, err = s.twoFactor.CountRecoveryCodes(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("AuthService.TwoFactorStatus: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// StartTwoFactorEnrollment начинает подключение второго фактора: выдает новый секрет и QR-код.
+// Второй фактор включается только после подтверждения кодом (ConfirmTwoFactor).
+func (s *Service) StartTwoFactorEnrollment(ctx context.Context, login string) (*model.TwoFactorEnrollment, error) {
+	user, err := s.twoFactorUser(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.StartTwoFactorEnrollment: %w", err)
+	}
+	return s.startEnrollment(ctx, user)
+}
+
+// ConfirmTwoFactor включает второй фактор по первому коду из приложения и возвращает коды восстановления.
+func (s *Service) ConfirmTwoFactor(ctx context.Context, login, code string) ([]string, error) {
+	user, err := s.twoFactorUser(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.ConfirmTwoFactor: %w", err)
+	}
+	return s.confirmEnrollment(ctx, user, code)
+}
+
+// DisableTwoFactor отключает второй фактор после проверки кода.
+// Администратору отключить его нельзя, если политика требует второй фактор.
+func (s *Service) DisableTwoFactor(ctx context.Context, login, code string) error {
+	user, err := s.twoFactorUser(ctx, login)
+	if err != nil {
+		return fmt.Errorf("AuthService.DisableTwoFactor: %w", err)
+	}
+
+	required, err := s.twoFactorRequired(ctx, user)
+	if err != nil {
+		return fmt.Errorf("AuthService.DisableTwoFactor: %w", err)
+	}
+	if required {
+		return fmt.Errorf("AuthService.DisableTwoFactor: %w", ErrTwoFactorMandatory)
+	}
+
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		return fmt.Errorf("AuthService.DisableTwoFactor: %w", err)
+	}
+	if err := s.twoFactor.DeleteTOTP(ctx, user.ID); err != nil {
+		return fmt.Errorf("AuthService.DisableTwoFactor: %w", err)
+	}
+
+	s.logger.Info("Two-factor authentication disabled", "login", user.Login)
+	return nil
+}
+
+// RegenerateRecoveryCodes выпускает новые коды восстановления после проверки кода; старые перестают действовать.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, login, code string) ([]string, error) {
+	user, err := s.twoFactorUser(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.RegenerateRecoveryCodes: %w", err)
+	}
+
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		return nil, fmt.Errorf("AuthService.RegenerateRecoveryCodes: %w", err)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.RegenerateRecoveryCodes: %w", err)
+	}
+	if err := s.twoFactor.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, fmt.Errorf("AuthService.RegenerateRecoveryCodes: %w", err)
+	}
+
+	s.logger.Info("Recovery codes regenerated", "login", user.Login)
+	return codes, nil
+}
+
+// ResetTwoFactor сбрасывает второй фактор пользователя, потерявшего телефон и коды восстановления.
+// При следующем входе пользователь подключит его заново, если этого требует политика.
+func (s *Service) ResetTwoFactor(ctx context.Context, userID int64, resetBy string) error {
+	if s.twoFactor == nil {
+		return fmt.Errorf("AuthService.ResetTwoFactor: %w", ErrTwoFactorNotEnabled)
+	}
+
+	if err := s.twoFactor.DeleteTOTP(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("AuthService.ResetTwoFactor: %w", ErrTwoFactorNotEnabled)
+		}
+		return fmt.Errorf("AuthService.ResetTwoFactor: %w", err)
+	}
+
+	s.logger.Info("Two-factor authentication reset", "user_id", userID, "reset_by", resetBy)
+	return nil
+}
+
+// GetAuthPolicy возвращает политику входа.
+func (s *Service) GetAuthPolicy(ctx context.Context) (*model.AuthPolicy, error) {
+	if s.twoFactor == nil {
+		return &model.AuthPolicy{}, nil
+	}
+
+	policy, err := s.twoFactor.GetAuthPolicy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("AuthService.GetAuthPolicy: %w", err)
+	}
+	return policy, nil
+}
+
+// UpdateAuthPolicy меняет политику входа. Администраторы без второго фактора
+// подключат его при следующем входе; уже выданные JWT продолжают действовать до истечения.
+func (s *Service) UpdateAuthPolicy(ctx context.Context, req model.AuthPolicyUpdateRequest, updatedBy string) (*model.AuthPolicy, error) {
+	if s.twoFactor == nil {
+		return nil, fmt.Errorf("AuthService.UpdateAuthPolicy: %w", ErrTwoFactorNotEnabled)
+	}
+
+	policy := &model.AuthPolicy{RequireAdmin2FA: req.RequireAdmin2FA, UpdatedBy: updatedBy}
+	if err := s.twoFactor.UpdateAuthPolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("AuthService.UpdateAuthPolicy: %w", err)
+	}
+
+	s.logger.Info("Auth policy updated", "require_admin_2fa", policy.RequireAdmin2FA, "updated_by", updatedBy)
+	return policy, nil
+}
+
+// StartChallengeEnrollment выдает секрет для подключения второго фактора во время входа,
+// когда политика требует его, а он еще не подключен.
+func (s *Service) StartChallengeEnrollment(ctx context.Context, token string) (*model.TwoFactorEnrollment, error) {
+	challenge, ok := s.peekChallenge(token)
+	if !ok || !challenge.enroll {
+		return nil, fmt.Errorf("AuthService.StartChallengeEnrollment: %w", ErrInvalidChallenge)
+	}
+	return s.startEnrollment(ctx, challenge.user)
+}
+
+// CompleteTwoFactorLogin завершает вход кодом TOTP или кодом восстановления и выпускает JWT.
+// Если второй фактор подключался во время входа, возвращаются коды восстановления.
+// Неверный код считается неудачным входом; если учетная запись заблокирована, вход начинается заново.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, token, code, ip string) (*jwt.JWTClaims, []string, error) {
+	challenge, ok := s.attemptChallenge(token)
+	if !ok {
+		return nil, nil, fmt.Errorf("AuthService.CompleteTwoFactorLogin: %w", ErrInvalidChallenge)
+	}
+
+	var recoveryCodes []string
+	var err error
+	if challenge.enroll {
+		recoveryCodes, err = s.confirmEnrollment(ctx, challenge.user, code)
+	} else {
+		err = s.verifySecondFactor(ctx, challenge.user, code)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.logger.Warn("AuthService.CompleteTwoFactorLogin invalid code", "login", challenge.user.Login, "attempt", challenge.attempts)
+			if s.loginFailed(ctx, challenge.user.Login, ip, model.LoginMethodTwoFactor, model.LoginResultInvalidCode) {
+				s.dropChallenge(token)
+			}
+		}
+		return nil, nil, fmt.Errorf("AuthService.CompleteTwoFactorLogin: %w", err)
+	}
+
+	s.dropChallenge(token)
+	s.loginSucceeded(ctx, challenge.user.Login, ip, model.LoginMethodTwoFactor)
+
+	claims, err := s.issueClaims(challenge.user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claims, recoveryCodes, nil
+}
+
+// twoFactorChallenge решает, нужен ли пользователю второй шаг входа, и начинает его.
+func (s *Service) twoFactorChallenge(ctx context.Context, user *model.User) (*TwoFactorChallenge, error) {
+	if s.twoFactor == nil {
+		return nil, nil
+	}
+
+	totp, err := s.getTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enroll := false
+	if !totp.Enabled() {
+		required, err := s.twoFactorRequired(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		enroll = true
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	s.challengesMu.Lock()
+	defer s.challengesMu.Unlock()
+
+	now := s.now()
+	for key, challenge := range s.challenges {
+		if now.After(challenge.expiresAt) {
+			delete(s.challenges, key)
+		}
+	}
+	if len(s.challenges) >= maxPendingChallenges {
+		return nil, fmt.Errorf("too many pending two-factor logins")
+	}
+
+	expiresAt := now.Add(twoFactorChallengeTTL)
+	s.challenges[token] = &loginChallenge{user: user, enroll: enroll, expiresAt: expiresAt}
+
+	return &TwoFactorChallenge{Token: token, EnrollmentRequired: enroll, ExpiresAt: expiresAt}, nil
+}
+
+// peekChallenge возвращает незавершенный вход, не расходуя попытку.
+func (s *Service) peekChallenge(token string) (loginChallenge, bool) {
+	s.challengesMu.Lock()
+	defer s.challengesMu.Unlock()
+
+	challenge, ok := s.challenges[token]
+	if !ok || s.now().After(challenge.expiresAt) || challenge.attempts >= maxTwoFactorAttempts {
+		return loginChallenge{}, false
+	}
+	return *challenge, true
+}
+
+// attemptChallenge расходует попытку ввода кода. Вход с исчерпанными попытками удаляется.
+func (s *Service) attemptChallenge(token string) (loginChallenge, bool) {
+	s.challengesMu.Lock()
+	defer s.challengesMu.Unlock()
+
+	challenge, ok := s.challenges[token]
+	if !ok {
+		return loginChallenge{}, false
+	}
+	if s.now().After(challenge.expiresAt) || challenge.attempts >= maxTwoFactorAttempts {
+		delete(s.challenges, token)
+		return loginChallenge{}, false
+	}
+
+	challenge.attempts++
+	return *challenge, true
+}
+
+// dropChallenge удаляет завершенный вход: токен второго шага одноразовый.
+func (s *Service) dropChallenge(token string) {
+	s.challengesMu.Lock()
+	defer s.challengesMu.Unlock()
+	delete(s.challenges, token)
+}
+
+// startEnrollment сохраняет новый секрет и готовит данные для приложения-аутентификатора.
+func (s *Service) startEnrollment(ctx context.Context, user *model.User) (*model.TwoFactorEnrollment, error) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactor.SaveTOTPSecret(ctx, user.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	uri := provisioningURI(user.Login, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %w", err)
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// confirmEnrollment включает второй фактор по коду из приложения и выпускает коды восстановления.
+func (s *Service) confirmEnrollment(ctx context.Context, user *model.User, code string) ([]string, error) {
+	totp, err := s.getTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if totp.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := verifyTOTP(totp.Secret, normalizeCode(code), s.now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.EnableTOTP(ctx, user.ID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Two-factor authentication enabled", "login", user.Login)
+	return codes, nil
+}
+
+// verifySecondFactor принимает код TOTP (каждый интервал один раз) или неиспользованный код восстановления.
+func (s *Service) verifySecondFactor(ctx context.Context, user *model.User, code string) error {
+	totp, err := s.getTOTP(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !totp.Enabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeCode(code)
+	if step, ok := verifyTOTP(totp.Secret, code, s.now()); ok {
+		accepted, err := s.twoFactor.UseTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	if len(code) == totpDigits {
+		return ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.twoFactor.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	s.logger.Warn("Recovery code used", "login", user.Login)
+	return nil
+}
+
+// twoFactorRequired сообщает, требует ли политика второй фактор от пользователя.
+func (s *Service) twoFactorRequired(ctx context.Context, user *model.User) (bool, error) {
+	if !user.IsAdmin {
+		return false, nil
+	}
+	policy, err := s.twoFactor.GetAuthPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return policy.RequireAdmin2FA, nil
+}
+
+// getTOTP возвращает TOTP пользователя или nil, если подключение не начиналось.
+func (s *Service) getTOTP(ctx context.Context, userID int64) (*model.TOTP, error) {
+	totp, err := s.twoFactor.GetTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return totp, err
+}
+
+// twoFactorUser находит пользователя JWT для управления вторым фактором.
+func (s *Service) twoFactorUser(ctx context.Context, login string) (*model.User, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	user, err := s.repo.GetUser(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUnknownUser
+	}
+	return user, nil
+}
+
+// normalizeCode убирает пробелы, которые приложения и пользователи вставляют в коды.
+func normalizeCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/jwt"
+)
+
+// memoryTwoFactorRepository хранилище второго фактора в памяти.
+type memoryTwoFactorRepository struct {
+	totp   map[int64]*model.TOTP
+	codes  map[int64]map[string]bool // хэш -> использован
+	policy model.AuthPolicy
+}
+
+func newMemoryTwoFactorRepository() *memoryTwoFactorRepository {
+	return &memoryTwoFactorRepository{totp: map[int64]*model.TOTP{}, codes: map[int64]map[string]bool{}}
+}
+
+func (r *memoryTwoFactorRepository) GetTOTP(ctx context.Context, userID int64) (*model.TOTP, error) {
+	totp, ok := r.totp[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *totp
+	return &copied, nil
+}
+
+func (r *memoryTwoFactorRepository) SaveTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	if r.totp[userID].Enabled() {
+		return repository.ErrAlreadyExists
+	}
+	r.totp[userID] = &model.TOTP{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) EnableTOTP(ctx context.Context, userID, step int64, codeHashes []string) error {
+	totp, ok := r.totp[userID]
+	if !ok || totp.Enabled() {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	totp.EnabledAt = &now
+	totp.LastUsedStep = step
+	return r.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+}
+
+func (r *memoryTwoFactorRepository) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	totp, ok := r.totp[userID]
+	if !ok || !totp.Enabled() || totp.LastUsedStep >= step {
+		return false, nil
+	}
+	totp.LastUsedStep = step
+	return true, nil
+}
+
+func (r *memoryTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	used, ok := r.codes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.codes[userID][codeHash] = true
+	return true, nil
+}
+
+func (r *memoryTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	r.codes[userID] = map[string]bool{}
+	for _, hash := range codeHashes {
+		r.codes[userID][hash] = false
+	}
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	count := 0
+	for _, used := range r.codes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryTwoFactorRepository) DeleteTOTP(ctx context.Context, userID int64) error {
+	if _, ok := r.totp[userID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.totp, userID)
+	delete(r.codes, userID)
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) GetAuthPolicy(ctx context.Context) (*model.AuthPolicy, error) {
+	policy := r.policy
+	return &policy, nil
+}
+
+func (r *memoryTwoFactorRepository) UpdateAuthPolicy(ctx context.Context, policy *model.AuthPolicy) error {
+	r.policy = *policy
+	return nil
+}
+
+func TestTOTPCode(t *testing.T) {
+	// Тестовые векторы RFC 6238 (SHA1), последние шесть цифр
+	secret := []byte("12345678901234567890")
+	for unix, code := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		assert.Equal(t, code, totpCode(secret, totpStep(time.Unix(unix, 0))), "t=%d", unix)
+	}
+
+	encoded := totpEncoding.EncodeToString(secret)
+	now := time.Unix(1111111111, 0)
+
+	step, ok := verifyTOTP(encoded, "050471", now)
+	require.True(t, ok)
+	assert.Equal(t, totpStep(now), step)
+
+	// Соседний интервал принимается из-за расхождения часов, дальний - нет
+	_, ok = verifyTOTP(encoded, totpCode(secret, totpStep(now)-1), now)
+	assert.True(t, ok)
+	_, ok = verifyTOTP(encoded, totpCode(secret, totpStep(now)-2), now)
+	assert.False(t, ok)
+	_, ok = verifyTOTP(encoded, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(provisioningURI("ivanov", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/"+totpIssuer+":ivanov", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, totpIssuer, uri.Query().Get("issuer"))
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	type fixture struct {
+		service *Service
+		repo    *memoryTwoFactorRepository
+		clock   time.Time
+	}
+	setup := func(t *testing.T) *fixture {
+		users := &memoryRepository{users: map[string]*model.User{
+			"admin":  {ID: 1, Login: "admin", Password: "admin-pass", IsAdmin: true, Role: model.UserRoleAdmin, AuthSource: model.AuthSourceLocal},
+			"petrov": {ID: 2, Login: "petrov", Password: "petrov-pass", Role: model.UserRoleManager, AuthSource: model.AuthSourceLocal},
+		}}
+		f := &fixture{repo: newMemoryTwoFactorRepository(), clock: time.Date(2025, 10, 30, 9, 0, 0, 0, time.UTC)}
+		f.service = NewService(users, jwt.NewService(), Options{TwoFactor: f.repo}, logger)
+		f.service.now = func() time.Time { return f.clock }
+		return f
+	}
+	// code возвращает текущий код пользователя, сдвигая часы на следующий интервал,
+	// чтобы код не совпал с уже использованным
+	code := func(f *fixture, userID int64) string {
+		f.clock = f.clock.Add(totpPeriod)
+		secret, err := totpEncoding.DecodeString(f.repo.totp[userID].Secret)
+		require.NoError(t, err)
+		return totpCode(secret, totpStep(f.clock))
+	}
+	// enroll подключает второй фактор и возвращает коды восстановления
+	enroll := func(t *testing.T, f *fixture, login string, userID int64) []string {
+		enrollment, err := f.service.StartTwoFactorEnrollment(ctx, login)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(enrollment.ProvisioningURI, "otpauth://totp/"))
+		assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+
+		codes, err := f.service.ConfirmTwoFactor(ctx, login, code(f, userID))
+		require.NoError(t, err)
+		require.Len(t, codes, recoveryCodeCount)
+		return codes
+	}
+	// challenge входит по паролю и ждет запроса второго фактора
+	challenge := func(t *testing.T, f *fixture, login, password string) *TwoFactorChallenge {
+		_, err := f.service.Login(ctx, login, password, "")
+		var challenge *TwoFactorChallenge
+		require.True(t, errors.As(err, &challenge), "expected a two-factor challenge, got %v", err)
+		return challenge
+	}
+
+	t.Run("without two-factor password login issues JWT", func(t *testing.T) {
+		f := setup(t)
+		claims, err := f.service.Login(ctx, "petrov", "petrov-pass", "")
+		require.NoError(t, err)
+		assert.Equal(t, "petrov", claims.Login)
+	})
+
+	t.Run("enrollment is not active until confirmed", func(t *testing.T) {
+		f := setup(t)
+		_, err := f.service.StartTwoFactorEnrollment(ctx, "petrov")
+		require.NoError(t, err)
+
+		_, err = f.service.ConfirmTwoFactor(ctx, "petrov", "000000")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+		claims, err := f.service.Login(ctx, "petrov", "petrov-pass", "")
+		require.NoError(t, err)
+		assert.Equal(t, "petrov", claims.Login)
+	})
+
+	t.Run("JWT is issued only after the code", func(t *testing.T) {
+		f := setup(t)
+		enroll(t, f, "petrov", 2)
+
+		ch := challenge(t, f, "petrov", "petrov-pass")
+		assert.False(t, ch.EnrollmentRequired)
+
+		_, _, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, "000000", "")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+		claims, recoveryCodes, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, code(f, 2), "")
+		require.NoError(t, err)
+		assert.Equal(t, "petrov", claims.Login)
+		assert.Empty(t, recoveryCodes)
+
+		// Токен второго шага одноразовый
+		_, _, err = f.service.CompleteTwoFactorLogin(ctx, ch.Token, code(f, 2), "")
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+
+	t.Run("code cannot be replayed", func(t *testing.T) {
+		f := setup(t)
+		enroll(t, f, "petrov", 2)
+		current := code(f, 2)
+
+		_, _, err := f.service.CompleteTwoFactorLogin(ctx, challenge(t, f, "petrov", "petrov-pass").Token, current, "")
+		require.NoError(t, err)
+
+		_, _, err = f.service.CompleteTwoFactorLogin(ctx, challenge(t, f, "petrov", "petrov-pass").Token, current, "")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("recovery code works once", func(t *testing.T) {
+		f := setup(t)
+		codes := enroll(t, f, "petrov", 2)
+
+		_, _, err := f.service.CompleteTwoFactorLogin(ctx, challenge(t, f, "petrov", "petrov-pass").Token, strings.ToUpper(codes[0]), "")
+		require.NoError(t, err)
+
+		_, _, err = f.service.CompleteTwoFactorLogin(ctx, challenge(t, f, "petrov", "petrov-pass").Token, codes[0], "")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+		status, err := f.service.TwoFactorStatus(ctx, "petrov")
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, recoveryCodeCount-1, status.RecoveryCodesLeft)
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		f := setup(t)
+		enroll(t, f, "petrov", 2)
+		ch := challenge(t, f, "petrov", "petrov-pass")
+
+		for range maxTwoFactorAttempts {
+			_, _, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, "000000", "")
+			assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		}
+
+		_, _, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, code(f, 2), "")
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+
+	t.Run("challenge expires", func(t *testing.T) {
+		f := setup(t)
+		enroll(t, f, "petrov", 2)
+		ch := challenge(t, f, "petrov", "petrov-pass")
+
+		f.clock = f.clock.Add(twoFactorChallengeTTL)
+		_, _, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, code(f, 2), "")
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+
+	t.Run("policy makes two-factor mandatory for admins", func(t *testing.T) {
+		f := setup(t)
+		_, err := f.service.UpdateAuthPolicy(ctx, model.AuthPolicyUpdateRequest{RequireAdmin2FA: true}, "admin")
+		require.NoError(t, err)
+
+		// Обычный пользователь входит как раньше
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "")
+		require.NoError(t, err)
+
+		// Администратор без второго фактора подключает его во время входа
+		ch := challenge(t, f, "admin", "admin-pass")
+		assert.True(t, ch.EnrollmentRequired)
+
+		_, err = f.service.StartChallengeEnrollment(ctx, ch.Token)
+		require.NoError(t, err)
+
+		claims, recoveryCodes, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, code(f, 1), "")
+		require.NoError(t, err)
+		assert.True(t, claims.IsAdmin)
+		assert.Len(t, recoveryCodes, recoveryCodeCount)
+
+		// Отключить второй фактор нельзя, пока действует политика
+		status, err := f.service.TwoFactorStatus(ctx, "admin")
+		require.NoError(t, err)
+		assert.True(t, status.Required)
+		assert.ErrorIs(t, f.service.DisableTwoFactor(ctx, "admin", code(f, 1)), ErrTwoFactorMandatory)
+
+		// Дальше администратор входит с кодом
+		ch = challenge(t, f, "admin", "admin-pass")
+		assert.False(t, ch.EnrollmentRequired)
+		_, err = f.service.StartChallengeEnrollment(ctx, ch.Token)
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+
+	t.Run("disable and admin reset", func(t *testing.T) {
+		f := setup(t)
+		enroll(t, f, "petrov", 2)
+
+		require.NoError(t, f.service.DisableTwoFactor(ctx, "petrov", code(f, 2)))
+		_, err := f.service.Login(ctx, "petrov", "petrov-pass", "")
+		require.NoError(t, err)
+
+		enroll(t, f, "petrov", 2)
+		require.NoError(t, f.service.ResetTwoFactor(ctx, 2, "admin"))
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, f.service.ResetTwoFactor(ctx, 2, "admin"), ErrTwoFactorNotEnabled)
+	})
+
+	t.Run("wrong codes lock the account and end the login", func(t *testing.T) {
+		f := setup(t)
+		f.service.throttleCfg = ThrottleConfig{MaxFailures: 2, LockoutDuration: 15 * time.Minute}
+		enroll(t, f, "petrov", 2)
+
+		ch := challenge(t, f, "petrov", "petrov-pass")
+		for range 2 {
+			_, _, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, "000000", "10.0.0.1")
+			require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		}
+
+		_, _, err := f.service.CompleteTwoFactorLogin(ctx, ch.Token, code(f, 2), "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+
+		_, err = f.service.Login(ctx, "petrov", "petrov-pass", "10.0.0.1")
+		var throttled *LoginThrottled
+		require.True(t, errors.As(err, &throttled))
+		assert.True(t, throttled.Locked)
+	})
+
+	t.Run("wrong password never reaches the second step", func(t *testing.T) {
+		f := setup(t)
+		enroll(t, f, "petrov", 2)
+
+		_, err := f.service.Login(ctx, "petrov", "wrong", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
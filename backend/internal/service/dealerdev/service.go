@@ -7,45 +7,42 @@ import (
 	"time"
 
 	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/utils"
 )
 
 // Repository интерфейс репозитория DealerDevelopment.
 type Repository interface {
 	Create(ctx context.Context, dd *model.DealerDevelopment) (int, error)
 	GetByID(ctx context.Context, id int) (*model.DealerDevelopment, error)
-	GetByDealerAndPeriod(ctx context.Context, dealerID int, period time.Time) (*model.DealerDevelopment, error)
+	GetByDealerAndPeriod(ctx context.Context, dealerID int, quarter string, year int) (*model.DealerDevelopment, error)
 	GetAllByPeriod(ctx context.Context, period time.Time) ([]*model.DealerDevelopment, error)
-	Update(ctx context.Context, id int, updates map[string]interface{}) error
+	Update(ctx context.Context, id int, version int, patch model.Patch) error
 	UpdateFull(ctx context.Context, dd *model.DealerDevelopment) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int, version int) error
 	GetWithDetailsByPeriod(ctx context.Context, quarter string, year int, region string) ([]*model.DealerDevWithDetails, error)
 }
 
-// ExcelRepository интерфейс репозитория для работы с Excel данными дилер-девелопмента.
-type ExcelRepository interface {
-	GetDealerNetTableName(year int, quarter string) string
-	TableExists(ctx context.Context, year int, quarter string) (bool, error)
-	GetDealerDevDataFromExcel(ctx context.Context, year int, quarter string, region string) ([]*model.DealerDevWithDetails, error)
-}
-
 // Service сервис для работы с данными развития дилеров.
 type Service struct {
-	repo      Repository
-	excelRepo ExcelRepository
-	logger    *slog.Logger
+	repo   Repository
+	logger *slog.Logger
 }
 
 // NewService создает новый экземпляр сервиса DealerDev.
-func NewService(repo Repository, excelRepo ExcelRepository, logger *slog.Logger) *Service {
+func NewService(repo Repository, logger *slog.Logger) *Service {
 	return &Service{
-		repo:      repo,
-		excelRepo: excelRepo,
-		logger:    logger,
+		repo:   repo,
+		logger: logger,
 	}
 }
 
 // GetDealerDevByPeriod возвращает список данных развития дилеров за период.
 func (s *Service) GetDealerDevByPeriod(ctx context.Context, quarter string, year int, region string) ([]*model.DealerDevWithDetails, error) {
+	quarter = utils.NormalizeQuarter(quarter)
+	if canonical, ok := model.Regions().Canonical(region); ok {
+		region = canonical
+	}
+
 	// Валидация квартала
 	if !isValidQuarter(quarter) {
 		return nil, fmt.Errorf("DealerDevService.GetDealerDevByPeriod: invalid quarter: %s", quarter)
@@ -56,44 +53,6 @@ func (s *Service) GetDealerDevByPeriod(ctx context.Context, quarter string, year
 		return nil, fmt.Errorf("DealerDevService.GetDealerDevByPeriod: invalid year: %d", year)
 	}
 
-	// Если указаны год и квартал, используем Excel данные
-	if year > 0 && quarter != "" {
-		s.logger.Info("Getting dealer dev data from Excel table",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-			slog.String("region", region),
-		)
-
-		// Проверяем существование таблицы
-		exists, err := s.excelRepo.TableExists(ctx, year, quarter)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check Excel table existence: %w", err)
-		}
-
-		if !exists {
-			s.logger.Warn("Excel table does not exist",
-				slog.Int("year", year),
-				slog.String("quarter", quarter),
-			)
-			return []*model.DealerDevWithDetails{}, nil
-		}
-
-		// Получаем данные из Excel таблицы
-		dealerDevData, err := s.excelRepo.GetDealerDevDataFromExcel(ctx, year, quarter, region)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get dealer dev data from Excel: %w", err)
-		}
-
-		s.logger.Info("Dealer dev data retrieved from Excel",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-			slog.String("region", region),
-			slog.Int("count", len(dealerDevData)),
-		)
-
-		return dealerDevData, nil
-	}
-
 	ddList, err := s.repo.GetWithDetailsByPeriod(ctx, quarter, year, region)
 	if err != nil {
 		s.logger.Error("DealerDevService.GetDealerDevByPeriod: failed to get dealer dev data",
@@ -135,6 +94,8 @@ func (s *Service) GetDealerDevByID(ctx context.Context, id int) (*model.DealerDe
 
 // CreateDealerDev создает новую запись развития дилера.
 func (s *Service) CreateDealerDev(ctx context.Context, dd *model.DealerDevelopment) (int, error) {
+	dd.Quarter = utils.NormalizeQuarter(dd.Quarter)
+
 	// Валидация
 	if err := s.validateDealerDev(dd); err != nil {
 		return 0, fmt.Errorf("DealerDevService.CreateDealerDev: validation failed: %w", err)
@@ -162,16 +123,17 @@ func (s *Service) CreateDealerDev(ctx context.Context, dd *model.DealerDevelopme
 }
 
 // UpdateDealerDev обновляет данные развития дилера.
-func (s *Service) UpdateDealerDev(ctx context.Context, id int, updates map[string]interface{}) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) UpdateDealerDev(ctx context.Context, id int, version int, patch model.Patch) error {
 	if id <= 0 {
 		return fmt.Errorf("DealerDevService.UpdateDealerDev: invalid ID: %d", id)
 	}
 
-	if len(updates) == 0 {
-		return fmt.Errorf("DealerDevService.UpdateDealerDev: no fields to update")
+	if patch.IsEmpty() {
+		return fmt.Errorf("DealerDevService.UpdateDealerDev: %w", model.ErrEmptyPatch)
 	}
 
-	err := s.repo.Update(ctx, id, updates)
+	err := s.repo.Update(ctx, id, version, patch)
 	if err != nil {
 		s.logger.Error("DealerDevService.UpdateDealerDev: failed to update",
 			"id", id,
@@ -188,12 +150,13 @@ func (s *Service) UpdateDealerDev(ctx context.Context, id int, updates map[strin
 }
 
 // DeleteDealerDev удаляет запись развития дилера.
-func (s *Service) DeleteDealerDev(ctx context.Context, id int) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) DeleteDealerDev(ctx context.Context, id int, version int) error {
 	if id <= 0 {
 		return fmt.Errorf("DealerDevService.DeleteDealerDev: invalid ID: %d", id)
 	}
 
-	err := s.repo.Delete(ctx, id)
+	err := s.repo.Delete(ctx, id, version)
 	if err != nil {
 		s.logger.Error("DealerDevService.DeleteDealerDev: failed to delete",
 			"id", id,
@@ -209,55 +172,126 @@ func (s *Service) DeleteDealerDev(ctx context.Context, id int) error {
 	return nil
 }
 
-// validateDealerDev валидирует данные развития дилера.
-func (s *Service) validateDealerDev(dd *model.DealerDevelopment) error {
-	if dd.DealerID <= 0 {
-		return fmt.Errorf("dealer_id is required")
+// GetDealerDevByDealerAndPeriod возвращает запись развития дилера за квартал.
+func (s *Service) GetDealerDevByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey) (*model.DealerDevelopment, error) {
+	key.Quarter = utils.NormalizeQuarter(key.Quarter)
+
+	dd, err := s.repo.GetByDealerAndPeriod(ctx, key.DealerID, key.Quarter, key.Year)
+	if err != nil {
+		return nil, fmt.Errorf("DealerDevService.GetDealerDevByDealerAndPeriod: %w", err)
+	}
+
+	return dd, nil
+}
+
+// ReplaceDealerDev полностью заменяет запись развития дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) ReplaceDealerDev(ctx context.Context, key model.DealerPeriodKey, version int, dd *model.DealerDevelopment) (*model.DealerDevelopment, error) {
+	current, err := s.GetDealerDevByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("DealerDevService.ReplaceDealerDev: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("DealerDevService.ReplaceDealerDev: %w", err)
+	}
+
+	// Ключ записи берется из пути, а не из тела запроса
+	dd.ID = current.ID
+	dd.DealerID = current.DealerID
+	dd.Quarter = current.Quarter
+	dd.Year = current.Year
+	dd.CreatedAt = current.CreatedAt
+	dd.Version = current.Version
+
+	if err := s.validateDealerDev(dd); err != nil {
+		return nil, fmt.Errorf("DealerDevService.ReplaceDealerDev: validation failed: %w", err)
+	}
+
+	if err := s.repo.UpdateFull(ctx, dd); err != nil {
+		s.logger.Error("DealerDevService.ReplaceDealerDev: failed to update",
+			"id", dd.ID,
+			"error", err,
+		)
+		return nil, fmt.Errorf("DealerDevService.ReplaceDealerDev: %w", err)
+	}
+
+	s.logger.Info("DealerDevService.ReplaceDealerDev: successfully updated",
+		"id", dd.ID,
+	)
+
+	return dd, nil
+}
+
+// PatchDealerDev частично обновляет запись развития дилера за квартал.
+// Изменения проверяются схемой на уровне полей, итоговая запись - правилами validateDealerDev.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) PatchDealerDev(ctx context.Context, key model.DealerPeriodKey, version int, patch model.Patch) (*model.DealerDevelopment, error) {
+	current, err := s.GetDealerDevByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("DealerDevService.PatchDealerDev: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("DealerDevService.PatchDealerDev: %w", err)
+	}
+
+	merged := *current
+	if err := patch.Apply(&merged); err != nil {
+		return nil, fmt.Errorf("DealerDevService.PatchDealerDev: %w", err)
+	}
+
+	if err := s.validateDealerDev(&merged); err != nil {
+		return nil, fmt.Errorf("DealerDevService.PatchDealerDev: validation failed: %w", err)
 	}
 
-	// Валидация квартала и года
-	if dd.Quarter == "" {
-		return fmt.Errorf("quarter is required")
+	if err := s.UpdateDealerDev(ctx, current.ID, current.Version, patch); err != nil {
+		return nil, fmt.Errorf("DealerDevService.PatchDealerDev: %w", err)
 	}
-	if dd.Year <= 0 {
-		return fmt.Errorf("year is required")
+
+	return s.GetDealerDevByDealerAndPeriod(ctx, key)
+}
+
+// DeleteDealerDevByDealerAndPeriod удаляет запись развития дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) DeleteDealerDevByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey, version int) error {
+	current, err := s.GetDealerDevByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return fmt.Errorf("DealerDevService.DeleteDealerDevByDealerAndPeriod: %w", err)
 	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return fmt.Errorf("DealerDevService.DeleteDealerDevByDealerAndPeriod: %w", err)
+	}
+
+	return s.DeleteDealerDev(ctx, current.ID, current.Version)
+}
+
+// validateDealerDev валидирует данные развития дилера и возвращает ошибки по полям.
+func (s *Service) validateDealerDev(dd *model.DealerDevelopment) error {
+	var errs model.ValidationErrors
+
+	// Валидация ключа записи
+	utils.ValidatePeriodKey(&errs, dd.DealerID, dd.Quarter, dd.Year)
 
 	if dd.CheckListScore < 0 || dd.CheckListScore > 100 {
-		return fmt.Errorf("check_list_score must be between 0 and 100")
+		errs.Add("check_list_score", "must be between 0 and 100")
 	}
 
 	// Валидация класса дилера
-	validClasses := []string{"A", "B", "C", "D"}
-	isValid := false
-	for _, class := range validClasses {
-		if dd.DealershipClass == class {
-			isValid = true
-			break
-		}
-	}
-	if !isValid {
-		return fmt.Errorf("invalid dealership_class: %s", dd.DealershipClass)
+	switch model.DealershipClass(dd.DealershipClass) {
+	case model.DealershipClassA, model.DealershipClassB, model.DealershipClassC, model.DealershipClassD:
+	default:
+		errs.Add("dealership_class", "invalid value: %s", dd.DealershipClass)
 	}
 
 	// Валидация рекомендации
-	validRecs := []string{"Planned Result", "Needs Development", "Find New Candidate", "Close Down"}
-	recValid := false
-	for _, rec := range validRecs {
-		if dd.DDRecommendation == rec {
-			recValid = true
-			break
-		}
-	}
-	if !recValid {
-		return fmt.Errorf("invalid dealer_dev_recommendation: %s", dd.DDRecommendation)
+	if !model.IsValidDecision(dd.DDRecommendation) {
+		errs.Add("dealer_dev_recommendation", "invalid value: %s", dd.DDRecommendation)
 	}
 
 	if dd.MarketingInvestments < 0 {
-		return fmt.Errorf("marketing_investments cannot be negative")
+		errs.Add("marketing_investments", "cannot be negative")
 	}
 
-	return nil
+	return errs.Err()
 }
 
 // isValidQuarter проверяет валидность квартала.
@@ -1,5 +1,41 @@
 package dealerdev_test
 
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/service/dealerdev"
+)
+
+func TestDealerDevService_CreateDealerDevValidation(t *testing.T) {
+	service := dealerdev.NewService(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := service.CreateDealerDev(context.Background(), &model.DealerDevelopment{
+		Quarter:              "q5",
+		Year:                 2024,
+		CheckListScore:       120,
+		DealershipClass:      "E",
+		DDRecommendation:     "Planned Result",
+		MarketingInvestments: -1,
+	})
+	require.Error(t, err)
+
+	var validationErrs model.ValidationErrors
+	require.True(t, errors.As(err, &validationErrs))
+
+	fields := make([]string, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields = append(fields, fieldErr.Field)
+	}
+	assert.Equal(t, []string{"dealer_id", "quarter", "check_list_score", "dealership_class", "marketing_investments"}, fields)
+}
+
 // import (
 // 	"context"
 // 	"testing"
@@ -13,6 +49,77 @@ package dealerdev_test
 // 	"github.com/typefunco/dealer_dev_platform/internal/testutil"
 // )
 
+func TestDealerDevelopmentPatchSchema(t *testing.T) {
+	t.Run("allowed fields are typed and mapped to columns", func(t *testing.T) {
+		patch, err := model.DealerDevelopmentPatchSchema.Decode([]byte(`{"dealership_class": "B", "check_list_score": 85}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{"dealer_ship_class": "B", "check_list_score": int64(85)}, patch.Columns())
+
+		dd := model.DealerDevelopment{ID: 1, DealershipClass: "A", CheckListScore: 10}
+		require.NoError(t, patch.Apply(&dd))
+		assert.Equal(t, "B", dd.DealershipClass)
+		assert.Equal(t, 85, dd.CheckListScore)
+	})
+
+	t.Run("unknown, forbidden and mistyped fields are rejected", func(t *testing.T) {
+		_, err := model.DealerDevelopmentPatchSchema.Decode([]byte(`{"id": 7, "dealer_id": 2, "check_list_score": "high", "branding": null, "rank": 1}`))
+
+		var validationErrs model.ValidationErrors
+		require.True(t, errors.As(err, &validationErrs))
+		assert.Equal(t, model.ValidationErrors{
+			{Field: "branding", Message: "cannot be null"},
+			{Field: "check_list_score", Message: "must be an integer"},
+			{Field: "dealer_id", Message: "cannot be changed"},
+			{Field: "id", Message: "cannot be changed"},
+			{Field: "rank", Message: "unknown field"},
+		}, validationErrs)
+	})
+
+	t.Run("value constraints are checked", func(t *testing.T) {
+		_, err := model.DealerDevelopmentPatchSchema.Decode([]byte(`{"check_list_score": 120, "dealership_class": "E"}`))
+
+		var validationErrs model.ValidationErrors
+		require.True(t, errors.As(err, &validationErrs))
+		assert.Len(t, validationErrs, 2)
+	})
+
+	t.Run("empty patch", func(t *testing.T) {
+		_, err := model.DealerDevelopmentPatchSchema.Decode([]byte(`{}`))
+		assert.ErrorIs(t, err, model.ErrEmptyPatch)
+	})
+}
+
+// versionedRepository репозиторий-заглушка с одной записью; любые изменения в БД приводят к панике.
+type versionedRepository struct {
+	dealerdev.Repository
+	current *model.DealerDevelopment
+}
+
+func (r versionedRepository) GetByDealerAndPeriod(ctx context.Context, dealerID int, quarter string, year int) (*model.DealerDevelopment, error) {
+	current := *r.current
+	return &current, nil
+}
+
+func TestDealerDevService_StaleVersion(t *testing.T) {
+	repo := versionedRepository{current: &model.DealerDevelopment{
+		ID: 1, DealerID: 7, Quarter: "Q1", Year: 2024, DealershipClass: "A", DDRecommendation: "Planned Result", Version: 3,
+	}}
+	service := dealerdev.NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	key := model.DealerPeriodKey{DealerID: 7, Quarter: "q1", Year: 2024}
+
+	_, err := service.ReplaceDealerDev(context.Background(), key, 2, &model.DealerDevelopment{DealershipClass: "B"})
+	assert.ErrorIs(t, err, model.ErrVersionConflict)
+
+	patch, err := model.DealerDevelopmentPatchSchema.Decode([]byte(`{"dealership_class": "B"}`))
+	require.NoError(t, err)
+	_, err = service.PatchDealerDev(context.Background(), key, 2, patch)
+	assert.ErrorIs(t, err, model.ErrVersionConflict)
+
+	err = service.DeleteDealerDevByDealerAndPeriod(context.Background(), key, 4)
+	assert.ErrorIs(t, err, model.ErrVersionConflict)
+}
+
 // func TestDealerDevService_CreateDealerDev(t *testing.T) {
 // 	// Настройка тестовой базы данных
 // 	testDB := testutil.SetupTestDB(t)
@@ -0,0 +1,313 @@
+package excel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+// ErrDatasetUnavailable данные набора отсутствуют: загрузка отклонена, заменена или удалена, либо это файл брендов.
+var ErrDatasetUnavailable = errors.New("dataset data is not available")
+
+// ErrDatasetNotRestorable набор нельзя восстановить: он не в корзине.
+var ErrDatasetNotRestorable = errors.New("dataset is not in trash")
+
+// brandsImportColumns колонки, которые файл брендов меняет в загрузке квартала.
+var brandsImportColumns = []string{"dealer", "city", "brands_in_portfolio", "byside_businesses"}
+
+// Catalog каталог загруженных наборов данных.
+type Catalog interface {
+	RecordImport(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error
+	RecordFailedImport(ctx context.Context, dataset *model.ImportDataset) error
+	List(ctx context.Context) ([]*model.ImportDataset, error)
+	GetByID(ctx context.Context, id int64) (*model.ImportDataset, error)
+	MarkPeriodDeleted(ctx context.Context, tx pgx.Tx, year int, quarter string, deletedBy string) error
+	ListDeleted(ctx context.Context) ([]*model.ImportDataset, error)
+	ListExpired(ctx context.Context, before time.Time) ([]*model.ImportDataset, error)
+	Restore(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error
+	MarkPurged(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error
+	GetIngestRequest(ctx context.Context, owner, key string) (*model.IngestRequest, error)
+	RecordIngestRequest(ctx context.Context, tx pgx.Tx, request *model.IngestRequest) error
+	PurgeIngestRequests(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ListImports возвращает каталог загрузок, начиная с последних.
+func (s *Service) ListImports(ctx context.Context) ([]*model.ImportDataset, error) {
+	datasets, err := s.catalog.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setPurgeAt(datasets...)
+	return datasets, nil
+}
+
+// ListDeletedImports возвращает корзину: удаленные кварталы, которые еще можно восстановить.
+func (s *Service) ListDeletedImports(ctx context.Context) ([]*model.ImportDataset, error) {
+	datasets, err := s.catalog.ListDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setPurgeAt(datasets...)
+	return datasets, nil
+}
+
+// GetImport возвращает запись каталога загрузок.
+func (s *Service) GetImport(ctx context.Context, id int64) (*model.ImportDataset, error) {
+	dataset, err := s.catalog.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.setPurgeAt(dataset)
+	return dataset, nil
+}
+
+// GetImportData возвращает строки набора с пагинацией и их общее количество.
+func (s *Service) GetImportData(ctx context.Context, id int64, limit, offset int) (*model.ImportDataset, []map[string]interface{}, int, error) {
+	dataset, err := s.datasetWithData(ctx, id)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	rows, total, err := s.dynamicRepo.GetDealerMetricsRows(ctx, dataset.Year, dataset.Quarter, limit, offset)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return dataset, rows, total, nil
+}
+
+// DeleteImport переносит квартал набора в корзину: строки уходят в архив, записи квартала в каталоге помечаются удаленными.
+// Квартал можно восстановить через RestoreImport, пока не истек срок хранения корзины.
+func (s *Service) DeleteImport(ctx context.Context, id int64, deletedBy string) error {
+	dataset, err := s.datasetWithData(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.dynamicRepo.BeginTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.dynamicRepo.ArchiveDealerMetricsPeriod(ctx, tx, dataset.Year, dataset.Quarter, dataset.ArchiveTableName()); err != nil {
+		return err
+	}
+
+	if err := s.catalog.MarkPeriodDeleted(ctx, tx, dataset.Year, dataset.Quarter, deletedBy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("Import moved to trash",
+		slog.Int64("dataset_id", id),
+		slog.Int("year", dataset.Year),
+		slog.String("quarter", dataset.Quarter),
+		slog.String("deleted_by", deletedBy),
+	)
+
+	return nil
+}
+
+// RestoreImport возвращает квартал набора из корзины.
+// Если квартал загружен заново, возвращается repository.ErrAlreadyExists.
+func (s *Service) RestoreImport(ctx context.Context, id int64) (*model.ImportDataset, error) {
+	dataset, err := s.catalog.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dataset.IsRestorable() {
+		return nil, fmt.Errorf("dataset %d (%s, %s): %w", dataset.ID, dataset.Kind, dataset.Status, ErrDatasetNotRestorable)
+	}
+
+	tx, err := s.dynamicRepo.BeginTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Каталог проверяет, что у квартала нет новой загрузки, до переноса партиции
+	if err := s.catalog.Restore(ctx, tx, dataset); err != nil {
+		return nil, err
+	}
+
+	if err := s.dynamicRepo.RestoreDealerMetricsPeriod(ctx, tx, dataset.Year, dataset.Quarter, dataset.ArchiveTableName()); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("Import restored from trash",
+		slog.Int64("dataset_id", id),
+		slog.Int("year", dataset.Year),
+		slog.String("quarter", dataset.Quarter),
+	)
+
+	return s.catalog.GetByID(ctx, id)
+}
+
+// PurgeExpiredImports окончательно удаляет кварталы, которые пролежали в корзине дольше срока хранения.
+// Возвращает количество удаленных наборов; ошибка одного набора не останавливает остальные.
+func (s *Service) PurgeExpiredImports(ctx context.Context) (int, error) {
+	datasets, err := s.catalog.ListExpired(ctx, time.Now().Add(-s.trashRetention))
+	if err != nil {
+		return 0, fmt.Errorf("ExcelService.PurgeExpiredImports: %w", err)
+	}
+
+	purged := 0
+	for _, dataset := range datasets {
+		if err := s.purgeImport(ctx, dataset); err != nil {
+			s.logger.Error("Failed to purge import",
+				slog.Int64("dataset_id", dataset.ID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// StartPurge периодически очищает корзину загрузок и устаревшие ключи идемпотентности загрузки через API.
+func (s *Service) StartPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpiredImports(ctx)
+			if err != nil {
+				s.logger.Error("ExcelService.StartPurge: failed to purge trash", slog.String("error", err.Error()))
+				continue
+			}
+			if purged > 0 {
+				s.logger.Info("ExcelService.StartPurge: trash purged", slog.Int("datasets", purged))
+			}
+
+			keys, err := s.catalog.PurgeIngestRequests(ctx, time.Now().Add(-IngestKeyRetention))
+			if err != nil {
+				s.logger.Error("ExcelService.StartPurge: failed to purge idempotency keys", slog.String("error", err.Error()))
+				continue
+			}
+			if keys > 0 {
+				s.logger.Info("ExcelService.StartPurge: idempotency keys purged", slog.Int64("keys", keys))
+			}
+		}
+	}
+}
+
+// purgeImport удаляет архив набора и помечает записи квартала окончательно удаленными.
+func (s *Service) purgeImport(ctx context.Context, dataset *model.ImportDataset) error {
+	tx, err := s.dynamicRepo.BeginTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.dynamicRepo.DropArchivedDealerMetrics(ctx, tx, dataset.ArchiveTableName()); err != nil {
+		return err
+	}
+
+	if err := s.catalog.MarkPurged(ctx, tx, dataset); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("Import purged",
+		slog.Int64("dataset_id", dataset.ID),
+		slog.Int("year", dataset.Year),
+		slog.String("quarter", dataset.Quarter),
+	)
+
+	return nil
+}
+
+// setPurgeAt заполняет время очистки корзины у наборов, которые можно восстановить.
+func (s *Service) setPurgeAt(datasets ...*model.ImportDataset) {
+	for _, dataset := range datasets {
+		if dataset.IsRestorable() {
+			purgeAt := dataset.DeletedAt.Add(s.trashRetention)
+			dataset.PurgeAt = &purgeAt
+		}
+	}
+}
+
+// PromoteImport повторно переносит набор в нормализованные таблицы.
+func (s *Service) PromoteImport(ctx context.Context, id int64) (*model.PromotionResult, error) {
+	dataset, err := s.datasetWithData(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.dynamicRepo.BeginTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	promotion, err := s.promote(ctx, tx, dataset.Year, dataset.Quarter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return promotion, nil
+}
+
+// datasetWithData возвращает набор, строки которого лежат в dealer_metrics.
+func (s *Service) datasetWithData(ctx context.Context, id int64) (*model.ImportDataset, error) {
+	dataset, err := s.catalog.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dataset.HasData() {
+		return nil, fmt.Errorf("dataset %d (%s, %s): %w", dataset.ID, dataset.Kind, dataset.Status, ErrDatasetUnavailable)
+	}
+
+	return dataset, nil
+}
+
+// recordFailedImport записывает отклоненную загрузку в каталог; ошибка записи только логируется.
+func (s *Service) recordFailedImport(ctx context.Context, dataset *model.ImportDataset) {
+	if err := s.catalog.RecordFailedImport(ctx, dataset); err != nil {
+		s.logger.Error("Failed to record failed import",
+			slog.String("file_name", dataset.SourceFile),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// sheetErrorsSummary собирает ошибки листов в одну строку для каталога.
+func sheetErrorsSummary(sheetErrors []model.ExcelError) string {
+	messages := make([]string, len(sheetErrors))
+	for i, sheetError := range sheetErrors {
+		messages[i] = fmt.Sprintf("%s: %s", sheetError.SheetName, sheetError.Error)
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,360 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// csvManifestName манифест архива CSV с периодом и регионами файлов.
+	csvManifestName = "manifest.json"
+
+	// maxCSVFileSize размер распакованного CSV из архива, сверх которого файл отклоняется.
+	maxCSVFileSize = 256 << 20
+)
+
+// csvPeriodPattern период в имени файла: Q3_2025, 2025-Q3.
+var csvPeriodPattern = regexp.MustCompile(`(?i)q[1-4][_\-\s]*\d{4}|\d{4}[_\-\s]*q[1-4]`)
+
+// csvManifest манифест архива CSV: период загрузки и регион каждого файла.
+// Без манифеста период берется из имени архива, регион - из имени файла (Central_2025_Q3.csv).
+type csvManifest struct {
+	Year    int    `json:"year"`
+	Quarter string `json:"quarter"`
+	Files   []struct {
+		File   string `json:"file"`
+		Region string `json:"region"`
+	} `json:"files"`
+}
+
+// csvSheet файл CSV одного региона.
+type csvSheet struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// csvBook выгрузка в CSV: один файл или архив с файлом на каждый регион.
+type csvBook struct {
+	sheets   []csvSheet
+	manifest *csvManifest
+	close    func() error
+}
+
+// openCSV открывает одиночный CSV: регион и период берутся из имени файла.
+func openCSV(file io.Reader, fileName string) (spreadsheet, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csvBook{
+		sheets: []csvSheet{{
+			name: csvSheetName(fileName),
+			open: func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil },
+		}},
+		close: func() error { return nil },
+	}, nil
+}
+
+// openCSVArchive открывает zip-архив с CSV на каждый регион и необязательным манифестом.
+func openCSVArchive(file io.Reader, _ string) (spreadsheet, error) {
+	archive, closeArchive, _, err := openArchive(file)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := newCSVArchiveBook(archive)
+	if err != nil {
+		closeArchive()
+		return nil, err
+	}
+	book.close = closeArchive
+	return book, nil
+}
+
+// newCSVArchiveBook собирает листы архива по манифесту или по именам файлов.
+func newCSVArchiveBook(archive *zip.Reader) (*csvBook, error) {
+	files := make(map[string]*zip.File)
+	var names []string
+	var manifestFile *zip.File
+
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(path.Base(f.Name), ".") {
+			continue
+		}
+		switch base := strings.ToLower(path.Base(f.Name)); {
+		case base == csvManifestName:
+			manifestFile = f
+		case strings.HasSuffix(base, ".csv"):
+			files[path.Base(f.Name)] = f
+			names = append(names, path.Base(f.Name))
+		}
+	}
+
+	book := &csvBook{}
+
+	if manifestFile == nil {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("archive contains no CSV files")
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			book.sheets = append(book.sheets, csvSheet{name: csvSheetName(name), open: files[name].Open})
+		}
+		return book, nil
+	}
+
+	manifest, err := readCSVManifest(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	book.manifest = manifest
+
+	listed := make(map[string]bool, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		f, ok := files[path.Base(entry.File)]
+		if !ok {
+			return nil, fmt.Errorf("%s: file %s not found in archive", csvManifestName, entry.File)
+		}
+		name := entry.Region
+		if name == "" {
+			name = csvSheetName(entry.File)
+		}
+		listed[path.Base(entry.File)] = true
+		book.sheets = append(book.sheets, csvSheet{name: name, open: f.Open})
+	}
+
+	// Файл без записи в манифесте не загружается молча: это почти всегда ошибка в манифесте
+	for _, name := range names {
+		if !listed[name] {
+			return nil, fmt.Errorf("%s: file %s is not listed", csvManifestName, name)
+		}
+	}
+
+	return book, nil
+}
+
+// readCSVManifest читает и проверяет манифест архива.
+func readCSVManifest(f *zip.File) (*csvManifest, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", csvManifestName, err)
+	}
+	defer r.Close()
+
+	var manifest csvManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", csvManifestName, err)
+	}
+
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("%s lists no files", csvManifestName)
+	}
+
+	if manifest.Year != 0 || manifest.Quarter != "" {
+		manifest.Quarter = strings.ToUpper(strings.TrimSpace(manifest.Quarter))
+		if _, err := model.QuarterStart(manifest.Year, manifest.Quarter); err != nil || manifest.Year < 2000 {
+			return nil, fmt.Errorf("%s: invalid period %d %s", csvManifestName, manifest.Year, manifest.Quarter)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// csvSheetName название листа для файла CSV: имя файла без расширения и периода (Central_2025_Q3.csv -> Central).
+func csvSheetName(fileName string) string {
+	name := strings.TrimSuffix(path.Base(fileName), path.Ext(fileName))
+	name = csvPeriodPattern.ReplaceAllString(name, "")
+	name = strings.Trim(name, "_- ")
+	if name == "" {
+		return strings.TrimSuffix(path.Base(fileName), path.Ext(fileName))
+	}
+	return name
+}
+
+// SheetList возвращает файлы регионов.
+func (b *csvBook) SheetList() []string {
+	names := make([]string, len(b.sheets))
+	for i, sheet := range b.sheets {
+		names[i] = sheet.name
+	}
+	return names
+}
+
+// Rows читает записи файла региона.
+func (b *csvBook) Rows(sheetName string) (sheetRows, error) {
+	for _, sheet := range b.sheets {
+		if sheet.name == sheetName {
+			return sheet.rows()
+		}
+	}
+	return nil, fmt.Errorf("sheet %s not found", sheetName)
+}
+
+// HeaderRow определяет строку заголовков: выгрузка шаблона начинается с заголовка листа в одной ячейке,
+// выгрузка из учетной системы - сразу с заголовков колонок.
+func (b *csvBook) HeaderRow(sheetName string) (int, error) {
+	rows, err := b.Rows(sheetName)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var filled []int
+	for len(filled) < 2 && rows.Next() {
+		row, err := rows.Columns()
+		if err != nil {
+			return 0, err
+		}
+		count := 0
+		for _, value := range row {
+			if strings.TrimSpace(value) != "" {
+				count++
+			}
+		}
+		filled = append(filled, count)
+	}
+
+	if len(filled) == 2 && filled[0] <= 1 && filled[1] > 1 {
+		return 2, nil
+	}
+	return 1, rows.Error()
+}
+
+// HeaderMerges в CSV нет объединенных ячеек.
+func (b *csvBook) HeaderMerges(string, int) ([]headerMerge, error) {
+	return nil, nil
+}
+
+// Formulas в CSV нет формул.
+func (b *csvBook) Formulas(string) (*sheetFormulas, error) {
+	return nil, nil
+}
+
+// Period возвращает период из манифеста архива.
+func (b *csvBook) Period() (int, string, bool) {
+	if b.manifest == nil || b.manifest.Year == 0 {
+		return 0, "", false
+	}
+	return b.manifest.Year, b.manifest.Quarter, true
+}
+
+// Close закрывает архив.
+func (b *csvBook) Close() error {
+	if b.close == nil {
+		return nil
+	}
+	return b.close()
+}
+
+// rows читает файл целиком, чтобы определить кодировку и разделитель.
+func (sheet csvSheet) rows() (sheetRows, error) {
+	r, err := sheet.open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", sheet.name, err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxCSVFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", sheet.name, err)
+	}
+	if len(data) > maxCSVFileSize {
+		return nil, fmt.Errorf("file %s exceeds %d MB", sheet.name, maxCSVFileSize>>20)
+	}
+
+	data, err = decodeCSV(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", sheet.name, err)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectCSVDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	return &csvRows{reader: reader}, nil
+}
+
+// decodeCSV убирает BOM и переводит выгрузки в Windows-1251 в UTF-8.
+func decodeCSV(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return data, nil
+	}
+	return charmap.Windows1251.NewDecoder().Bytes(data)
+}
+
+// detectCSVDelimiter выбирает разделитель по первой строке: русский Excel сохраняет CSV через точку с запятой.
+func detectCSVDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+
+	delimiter, best := ',', 0
+	for _, candidate := range []rune{';', '\t', ','} {
+		if count := bytes.Count(line, []byte(string(candidate))); count > best {
+			delimiter, best = candidate, count
+		}
+	}
+	return delimiter
+}
+
+// csvRows записи CSV.
+type csvRows struct {
+	reader *csv.Reader
+	row    []string
+	err    error
+}
+
+// Next читает следующую запись.
+func (r *csvRows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	row, err := r.reader.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		r.err = err
+		return false
+	}
+
+	// Как excelize, отбрасываем пустые ячейки в конце строки
+	end := len(row)
+	for end > 0 && strings.TrimSpace(row[end-1]) == "" {
+		end--
+	}
+	r.row = row[:end]
+	return true
+}
+
+// Columns возвращает значения текущей записи.
+func (r *csvRows) Columns() ([]string, error) {
+	return r.row, nil
+}
+
+// Error возвращает ошибку чтения.
+func (r *csvRows) Error() error {
+	return r.err
+}
+
+// Close ничего не освобождает: файл прочитан целиком.
+func (r *csvRows) Close() error {
+	return nil
+}
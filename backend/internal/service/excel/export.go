@@ -0,0 +1,139 @@
+package excel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportPageSize количество строк квартала, читаемых из dealer_metrics за один запрос при выгрузке.
+const exportPageSize = 1000
+
+// ExportImport выгружает квартал набора в XLSX в формате шаблона загрузки: лист на регион (Q3-Central),
+// заголовок листа в первой строке, колонки со второй. Периодо-независимые колонки получают названия
+// квартала (spare_parts_sales_q3), поэтому файл можно загрузить обратно без правок.
+func (s *Service) ExportImport(ctx context.Context, id int64, w io.Writer) (*model.ImportDataset, error) {
+	dataset, err := s.datasetWithData(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := s.dynamicRepo.GetDealerMetricsRows(ctx, dataset.Year, dataset.Quarter, exportPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quarter rows: %w", err)
+		}
+		rows = append(rows, page...)
+		if len(page) == 0 || offset+len(page) >= total {
+			break
+		}
+	}
+
+	book, err := exportWorkbook(dataset, rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build workbook: %w", err)
+	}
+	defer book.Close()
+
+	if _, err := book.WriteTo(w); err != nil {
+		return nil, fmt.Errorf("failed to write workbook: %w", err)
+	}
+
+	return dataset, nil
+}
+
+// ExportFileName возвращает имя файла выгрузки, из которого загрузка снова определит квартал и год.
+func ExportFileName(dataset *model.ImportDataset) string {
+	return fmt.Sprintf("dealer_net_%s_%d.xlsx", dataset.Quarter, dataset.Year)
+}
+
+// exportWorkbook раскладывает строки квартала по листам регионов.
+func exportWorkbook(dataset *model.ImportDataset, rows []map[string]interface{}) (*excelize.File, error) {
+	columns := exportColumns(rows)
+	headers := model.PeriodColumnNames(columns, dataset.Quarter)
+
+	var regions []string
+	byRegion := make(map[string][]map[string]interface{})
+	for _, row := range rows {
+		region, _ := row["region"].(string)
+		if _, ok := byRegion[region]; !ok {
+			regions = append(regions, region)
+		}
+		byRegion[region] = append(byRegion[region], row)
+	}
+
+	book := excelize.NewFile()
+	defaultSheet := book.GetSheetName(0)
+	for _, region := range regions {
+		sheet := dataset.Quarter
+		if region != "" {
+			sheet += "-" + region
+		}
+		if _, err := book.NewSheet(sheet); err != nil {
+			book.Close()
+			return nil, fmt.Errorf("error creating sheet %s: %w", sheet, err)
+		}
+
+		if err := book.SetCellValue(sheet, "A1", fmt.Sprintf("Dealer Net %s %d", dataset.Quarter, dataset.Year)); err != nil {
+			book.Close()
+			return nil, fmt.Errorf("error writing sheet %s: %w", sheet, err)
+		}
+		if err := book.SetSheetRow(sheet, "A2", &headers); err != nil {
+			book.Close()
+			return nil, fmt.Errorf("error writing sheet %s: %w", sheet, err)
+		}
+		for i, row := range byRegion[region] {
+			values := make([]interface{}, len(columns))
+			for j, column := range columns {
+				values[j] = row[column]
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, i+3)
+			if err := book.SetSheetRow(sheet, cell, &values); err != nil {
+				book.Close()
+				return nil, fmt.Errorf("error writing sheet %s: %w", sheet, err)
+			}
+		}
+	}
+
+	if len(regions) > 0 {
+		if err := book.DeleteSheet(defaultSheet); err != nil {
+			book.Close()
+			return nil, fmt.Errorf("error removing default sheet: %w", err)
+		}
+	}
+	return book, nil
+}
+
+// exportColumns возвращает колонки выгрузки: показатели в порядке model.DealerMetricColumns,
+// затем колонки из extra по алфавиту. Колонки без значений во всех строках пропускаются.
+func exportColumns(rows []map[string]interface{}) []string {
+	present := make(map[string]bool)
+	for _, row := range rows {
+		for column := range row {
+			present[column] = true
+		}
+	}
+	delete(present, "id")
+
+	var columns []string
+	for _, column := range model.DealerMetricColumns {
+		if present[column] {
+			columns = append(columns, column)
+			delete(present, column)
+		}
+	}
+
+	extra := make([]string, 0, len(present))
+	for column := range present {
+		extra = append(extra, column)
+	}
+	sort.Strings(extra)
+
+	return slices.Concat(columns, extra)
+}
@@ -0,0 +1,43 @@
+package excel
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+func TestExportWorkbook(t *testing.T) {
+	dataset := &model.ImportDataset{ID: 1, Year: 2025, Quarter: "Q1"}
+	rows := []map[string]interface{}{
+		{"id": 1, "dealer": "Alfa", "region": "Central", "city": "Moscow", model.ColumnSparePartsSalesQuarter: "1200", "new_kpi": "7"},
+		{"id": 2, "dealer": "Beta", "region": "Volga", model.ColumnSparePartsSalesYtdPct: "15"},
+		{"id": 3, "dealer": "Gamma", "region": "Central"},
+	}
+
+	book, err := exportWorkbook(dataset, rows)
+	require.NoError(t, err)
+	defer book.Close()
+
+	assert.Equal(t, []string{"Q1-Central", "Q1-Volga"}, book.GetSheetList())
+
+	central, err := book.GetRows("Q1-Central")
+	require.NoError(t, err)
+	require.Len(t, central, 4)
+	assert.Equal(t, []string{"dealer", "region", "city", "spare_parts_sales_q1", "spare_parts_sales_ytd_percent", "new_kpi"}, central[1])
+	assert.Equal(t, []string{"Alfa", "Central", "Moscow", "1200", "", "7"}, central[2])
+	assert.Equal(t, []string{"Gamma", "Central"}, central[3])
+
+	// Названия квартала при повторной загрузке сводятся обратно к периодо-независимым колонкам
+	service := NewService(nil, nil, nil, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mapping := service.mapHeaders(central[1])
+	assert.Equal(t, []string{"dealer", "region", "city", model.ColumnSparePartsSalesQuarter, model.ColumnSparePartsSalesYtdPct, "new_kpi"}, mapping.Columns)
+
+	fileInfo, err := service.parseFileName(ExportFileName(dataset))
+	require.NoError(t, err)
+	assert.Equal(t, "Q1", fileInfo.Quarter)
+	assert.Equal(t, 2025, fileInfo.Year)
+}
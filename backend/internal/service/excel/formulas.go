@@ -0,0 +1,273 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/xuri/excelize/v2"
+)
+
+// maxFormulaErrors сколько невычисленных формул листа попадает в отчет по отдельности.
+const maxFormulaErrors = 100
+
+// workbook книга Excel вместе с ее архивом.
+// excelize читает строки потоково, но отдает только сохраненные значения, поэтому формулы без
+// сохраненного результата ищутся в XML листа напрямую, не загружая лист в память.
+type workbook struct {
+	*excelize.File
+	archive      *zip.Reader
+	closeArchive func() error
+}
+
+// openDealerNetWorkbook открывает книгу dealer_net вместе с ее zip-архивом.
+func openDealerNetWorkbook(file io.Reader) (*workbook, error) {
+	archive, closeArchive, file, err := openArchive(file)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := openWorkbook(file)
+	if err != nil {
+		closeArchive()
+		return nil, err
+	}
+
+	return &workbook{File: f, archive: archive, closeArchive: closeArchive}, nil
+}
+
+// Close закрывает книгу и архив.
+func (w *workbook) Close() error {
+	err := w.File.Close()
+	if closeErr := w.closeArchive(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// uncachedFormulas возвращает ячейки листа, в которых есть формула, но нет сохраненного значения.
+func (w *workbook) uncachedFormulas(sheetName string) (map[string]bool, error) {
+	partName, err := w.sheetPartName(sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := w.archive.Open(partName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sheet %s: %w", partName, err)
+	}
+	defer part.Close()
+
+	return scanUncachedFormulas(part)
+}
+
+// scanUncachedFormulas потоково читает XML листа и собирает ячейки с формулой без значения.
+func scanUncachedFormulas(r io.Reader) (map[string]bool, error) {
+	cells := make(map[string]bool)
+	decoder := xml.NewDecoder(r)
+
+	var (
+		cell                string
+		hasFormula, inValue bool
+		hasValue            bool
+	)
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return cells, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "c":
+				cell, hasFormula, hasValue = "", false, false
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "r" {
+						cell = attr.Value
+					}
+				}
+			case "f":
+				hasFormula = true
+			case "v", "is":
+				inValue = true
+			}
+		case xml.CharData:
+			if inValue && len(bytes.TrimSpace(t)) > 0 {
+				hasValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "is":
+				inValue = false
+			case "c":
+				if hasFormula && !hasValue && cell != "" {
+					cells[cell] = true
+				}
+			}
+		}
+	}
+}
+
+// sheetPartName находит файл листа в архиве по связям книги.
+func (w *workbook) sheetPartName(sheetName string) (string, error) {
+	var book struct {
+		Sheets []struct {
+			Name string     `xml:"name,attr"`
+			Attr []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := w.decodePart("xl/workbook.xml", &book); err != nil {
+		return "", err
+	}
+
+	var relID string
+	for _, sheet := range book.Sheets {
+		if sheet.Name != sheetName {
+			continue
+		}
+		for _, attr := range sheet.Attr {
+			if attr.Name.Local == "id" {
+				relID = attr.Value
+			}
+		}
+	}
+	if relID == "" {
+		return "", fmt.Errorf("sheet %s not found in workbook", sheetName)
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := w.decodePart("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != relID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return "", fmt.Errorf("sheet %s has no part in workbook", sheetName)
+}
+
+// decodePart читает XML-файл архива.
+func (w *workbook) decodePart(name string, v interface{}) error {
+	part, err := w.archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer part.Close()
+
+	if err := xml.NewDecoder(part).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// sheetFormulas вычисляет формулы листа без сохраненного значения и ведет отчет по колонкам.
+type sheetFormulas struct {
+	f         *excelize.File
+	sheetName string
+	uncached  map[string]bool
+	reports   map[string]*model.ExcelFormulaReport
+	order     []string
+	errors    []model.ExcelError
+}
+
+// newSheetFormulas ищет на листе формулы без сохраненного значения.
+// Если таких нет, возвращается nil: лист читается как есть.
+func newSheetFormulas(w *workbook, sheetName string) (*sheetFormulas, error) {
+	uncached, err := w.uncachedFormulas(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	if len(uncached) == 0 {
+		return nil, nil
+	}
+
+	return &sheetFormulas{
+		f:         w.File,
+		sheetName: sheetName,
+		uncached:  uncached,
+		reports:   make(map[string]*model.ExcelFormulaReport),
+	}, nil
+}
+
+// value возвращает значение ячейки: сохраненное значение из файла или результат формулы.
+// GetCellFormula и CalcCellValue загружают лист в память, но только для книг с невычисленными формулами.
+func (sf *sheetFormulas) value(column string, col, row int, cached string) string {
+	if sf == nil {
+		return cached
+	}
+
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil || !sf.uncached[cell] {
+		if cached != "" {
+			sf.report(column).Literal++
+		}
+		return cached
+	}
+
+	report := sf.report(column)
+	value, err := sf.f.CalcCellValue(sf.sheetName, cell)
+	if err != nil {
+		report.Failed++
+		if len(sf.errors) < maxFormulaErrors {
+			formula, _ := sf.f.GetCellFormula(sf.sheetName, cell)
+			sf.errors = append(sf.errors, model.ExcelError{
+				SheetName: sf.sheetName,
+				Row:       row,
+				Column:    column,
+				Message:   fmt.Sprintf("Failed to evaluate formula %s in cell %s", formula, cell),
+				Error:     err.Error(),
+			})
+		}
+		return ""
+	}
+
+	report.Computed = append(report.Computed, cell)
+	return value
+}
+
+// report возвращает отчет колонки, создавая его при первом обращении.
+func (sf *sheetFormulas) report(column string) *model.ExcelFormulaReport {
+	report, ok := sf.reports[column]
+	if !ok {
+		report = &model.ExcelFormulaReport{SheetName: sf.sheetName, Column: column}
+		sf.reports[column] = report
+		sf.order = append(sf.order, column)
+	}
+	return report
+}
+
+// results возвращает отчеты колонок, в которых были формулы, и ошибки вычисления.
+func (sf *sheetFormulas) results() ([]model.ExcelFormulaReport, []model.ExcelError) {
+	if sf == nil {
+		return nil, nil
+	}
+
+	var reports []model.ExcelFormulaReport
+	for _, column := range sf.order {
+		if report := sf.reports[column]; len(report.Computed) > 0 || report.Failed > 0 {
+			reports = append(reports, *report)
+		}
+	}
+	return reports, sf.errors
+}
@@ -0,0 +1,386 @@
+package excel
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/xuri/excelize/v2"
+)
+
+// maxHeaderRows максимальное количество строк заголовка листа.
+const maxHeaderRows = 3
+
+// headerField каноническое поле таблицы dealer_net и допустимые варианты заголовка.
+type headerField struct {
+	Name     string          // Название колонки в БД, которое используют читатели
+	Required bool            // Лист без этого поля не загружается
+	Aliases  []string        // Варианты заголовка в шаблонах (RU и EN)
+	Repeat   string          // Поле повторного заголовка в одноуровневых шаблонах: второй HDT без группы - выкуп
+	Kind     model.ValueKind // Тип значения для нормализации; пустой - текст
+}
+
+// dealerNetHeaderFields словарь заголовков таблицы dealer_net.
+var dealerNetHeaderFields = []headerField{
+	{Name: "dealer", Required: true, Aliases: []string{"Dealer", "Dealer name", "Дилер", "Наименование дилера", "Дилерский центр"}},
+	{Name: "region", Aliases: []string{"Region", "Регион"}},
+	{Name: "city", Aliases: []string{"City", "Город"}},
+	{Name: "manager", Aliases: []string{"Manager", "Regional manager", "RM", "Менеджер", "Региональный менеджер"}},
+	{Name: "class", Aliases: []string{"Class", "Dealer class", "Dealership class", "Класс", "Класс дилера"}},
+	{Name: "check_list_percent", Aliases: []string{"Check list %", "Checklist %", "Check list score", "Чек-лист %", "Чек лист %"}, Kind: model.ValueKindPercent},
+	{Name: "marketing_investments", Aliases: []string{"Marketing investments", "Маркетинговые инвестиции", "Инвестиции в маркетинг"}, Kind: model.ValueKindNumber},
+	{Name: "branding", Aliases: []string{"Branding", "Брендинг"}, Kind: model.ValueKindBool},
+	{Name: "dealer_development", Aliases: []string{"Dealer development", "DD", "Развитие дилера"}},
+	{Name: "stock_hdt", Aliases: []string{"HDT", "Stock HDT", "Сток HDT", "Склад HDT"}, Repeat: "buyout_hdt", Kind: model.ValueKindNumber},
+	{Name: "stock_mdt", Aliases: []string{"MDT", "Stock MDT", "Сток MDT", "Склад MDT"}, Repeat: "buyout_mdt", Kind: model.ValueKindNumber},
+	{Name: "stock_ldt", Aliases: []string{"LDT", "Stock LDT", "Сток LDT", "Склад LDT"}, Repeat: "buyout_ldt", Kind: model.ValueKindNumber},
+	{Name: "buyout_hdt", Aliases: []string{"Buyout HDT", "Выкуп HDT"}, Kind: model.ValueKindNumber},
+	{Name: "buyout_mdt", Aliases: []string{"Buyout MDT", "Выкуп MDT"}, Kind: model.ValueKindNumber},
+	{Name: "buyout_ldt", Aliases: []string{"Buyout LDT", "Выкуп LDT"}, Kind: model.ValueKindNumber},
+	{Name: "sales", Aliases: []string{"Sales", "Sales decision", "Продажи"}},
+	{Name: "service_contracts_sales", Aliases: []string{"Service contracts sales", "Продажи сервисных контрактов"}, Kind: model.ValueKindNumber},
+	{Name: model.ColumnSparePartsSalesQuarter, Aliases: append(quarterAliases("Spare parts sales %s", "Продажи запчастей %s"),
+		"Spare parts sales quarter", "Продажи запчастей за квартал"), Kind: model.ValueKindNumber},
+	{Name: model.ColumnSparePartsSalesYtdPct, Aliases: []string{"spare_parts_sales_ytd_percent", "Spare parts sales YTD %", "Продажи запчастей YTD %"}, Kind: model.ValueKindPercent},
+	{Name: "warranty_stock_percent", Aliases: []string{"Warranty stock %", "Гарантийный склад %"}, Kind: model.ValueKindPercent},
+	{Name: "recommended_stock_percent", Aliases: []string{"Recommended stock %", "Рекомендованный склад %"}, Kind: model.ValueKindPercent},
+	{Name: "foton_labour_hours", Aliases: []string{"Foton labour hours", "Foton labor hours", "Нормочасы Foton"}, Kind: model.ValueKindNumber},
+	{Name: "foton_labour_hours_share", Aliases: []string{"Foton labour hours share", "Foton labor hours share", "Доля нормочасов Foton"}, Kind: model.ValueKindPercent},
+	{Name: "warranty_hours", Aliases: []string{"Warranty hours", "Гарантийные нормочасы"}, Kind: model.ValueKindNumber},
+	{Name: "service_contracts_hours", Aliases: []string{"Service contracts hours", "Нормочасы сервисных контрактов"}, Kind: model.ValueKindNumber},
+	{Name: "as_trainings", Aliases: []string{"AS trainings", "After sales trainings", "Обучение сервиса"}, Kind: model.ValueKindBool},
+	{Name: "aftersales", Aliases: []string{"Aftersales", "After sales", "Сервис", "Послепродажное обслуживание"}},
+	{Name: "joint_decision", Aliases: []string{"Joint decision", "Совместное решение"}},
+}
+
+// quarterAliases разворачивает шаблоны заголовков с кварталом (%s) в варианты для Q1..Q4.
+func quarterAliases(patterns ...string) []string {
+	var aliases []string
+	for _, pattern := range patterns {
+		for _, quarter := range []string{"Q1", "Q2", "Q3", "Q4"} {
+			aliases = append(aliases, fmt.Sprintf(pattern, quarter))
+		}
+	}
+	return aliases
+}
+
+// headerIndex индекс словаря заголовков: санитизированный вариант -> каноническое поле.
+var headerIndex = buildHeaderIndex(dealerNetHeaderFields)
+
+// headerRepeats поля, в которые попадает повторный заголовок одноуровневого шаблона.
+var headerRepeats = func() map[string]string {
+	repeats := make(map[string]string)
+	for _, field := range dealerNetHeaderFields {
+		if field.Repeat != "" {
+			repeats[field.Name] = field.Repeat
+		}
+	}
+	return repeats
+}()
+
+// headerKinds типы значений канонических полей.
+var headerKinds = func() map[string]model.ValueKind {
+	kinds := make(map[string]model.ValueKind)
+	for _, field := range dealerNetHeaderFields {
+		if field.Kind != "" {
+			kinds[field.Name] = field.Kind
+		}
+	}
+	return kinds
+}()
+
+// columnKind возвращает тип значения колонки: у полей словаря - из словаря,
+// у прочих колонок с датой в заголовке ("Дата аудита", "Audit date") - дата.
+func columnKind(name string) model.ValueKind {
+	if kind, ok := headerKinds[name]; ok {
+		return kind
+	}
+	for _, word := range strings.Split(name, "_") {
+		if word == "date" || word == "дата" {
+			return model.ValueKindDate
+		}
+	}
+	return model.ValueKindText
+}
+
+// buildHeaderIndex строит индекс словаря заголовков.
+func buildHeaderIndex(fields []headerField) map[string]string {
+	index := make(map[string]string, len(fields)*4)
+	for _, field := range fields {
+		index[field.Name] = field.Name
+		for _, alias := range field.Aliases {
+			index[sanitizeHeaderName(alias)] = field.Name
+		}
+	}
+	return index
+}
+
+// headerMapping результат сопоставления заголовков листа с колонками таблицы.
+type headerMapping struct {
+	Columns  []string          // Колонки таблицы в порядке листа
+	Indexes  []int             // Индекс колонки листа для каждой колонки таблицы
+	Kinds    []model.ValueKind // Тип значения каждой колонки таблицы
+	Unmapped []string          // Заголовки, которых нет в словаре
+	Missing  []string          // Отсутствующие обязательные поля
+}
+
+// mapHeaders сопоставляет одноуровневые заголовки листа со словарем по названию, а не по позиции.
+func (s *Service) mapHeaders(headers []string) *headerMapping {
+	columns := make([][]string, len(headers))
+	for i, header := range headers {
+		columns[i] = []string{header}
+	}
+	return s.mapHeaderColumns(columns)
+}
+
+// mapHeaderColumns сопоставляет заголовки листа со словарем по названию.
+// Каждая колонка - части заголовка сверху вниз: группа и подзаголовок ("Stock", "HDT").
+func (s *Service) mapHeaderColumns(columns [][]string) *headerMapping {
+	mapping := &headerMapping{}
+	usedNames := make(map[string]int)
+
+	for i, parts := range columns {
+		parts = compactHeaderParts(parts)
+		// Пропускаем пустые заголовки
+		if len(parts) == 0 {
+			continue
+		}
+
+		name, known := resolveHeader(parts)
+		if !known && (name == "" || (name[0] >= '0' && name[0] <= '9')) {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+
+		// Повторяющиеся заголовки: второй HDT без группы - выкуп, остальные нумеруются
+		if count, exists := usedNames[name]; exists {
+			usedNames[name] = count + 1
+			if repeat, ok := headerRepeats[name]; ok && usedNames[repeat] == 0 {
+				name = repeat
+			} else {
+				name = fmt.Sprintf("%s_%d", name, count+1)
+				_, known = headerIndex[name]
+			}
+			usedNames[name]++
+		} else {
+			usedNames[name] = 1
+		}
+
+		// Ограничиваем длину
+		if len(name) > 63 {
+			name = name[:63]
+		}
+
+		if !known {
+			mapping.Unmapped = append(mapping.Unmapped, strings.Join(parts, " / "))
+		}
+
+		mapping.Columns = append(mapping.Columns, name)
+		mapping.Indexes = append(mapping.Indexes, i)
+		mapping.Kinds = append(mapping.Kinds, columnKind(name))
+	}
+
+	for _, field := range dealerNetHeaderFields {
+		if field.Required && usedNames[field.Name] == 0 {
+			mapping.Missing = append(mapping.Missing, field.Name)
+		}
+	}
+
+	return mapping
+}
+
+// resolveHeader ищет заголовок в словаре: сначала группа с подзаголовком ("Stock HDT"),
+// затем подзаголовок без группы. Неизвестный заголовок собирается из всех частей.
+func resolveHeader(parts []string) (string, bool) {
+	for start := range parts {
+		if name, ok := headerIndex[sanitizeHeaderName(strings.Join(parts[start:], " "))]; ok {
+			return name, true
+		}
+	}
+	return sanitizeHeaderName(strings.Join(parts, " ")), false
+}
+
+// compactHeaderParts убирает пустые части заголовка и повторы ячейки, объединенной по вертикали.
+func compactHeaderParts(parts []string) []string {
+	compacted := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" || (len(compacted) > 0 && compacted[len(compacted)-1] == part) {
+			continue
+		}
+		compacted = append(compacted, part)
+	}
+	return compacted
+}
+
+// headerMerge объединенная ячейка заголовка в координатах листа (строки и колонки с 1).
+type headerMerge struct {
+	FirstRow, LastRow int
+	FirstCol, LastCol int
+	Value             string
+}
+
+// sheetHeaderLayout возвращает объединенные ячейки заголовка и количество строк заголовка листа.
+// Объединенные ячейки читаются только для многоуровневых заголовков: в Excel GetMergeCells загружает весь лист в память.
+// Группа, объединенная по горизонтали, оставляет пустые ячейки в первой строке заголовка, поэтому
+// заголовок без пропусков считается одноуровневым, если количество строк не задано в настройках.
+func (s *Service) sheetHeaderLayout(book spreadsheet, sheetName string, headerRow int) ([]headerMerge, int, error) {
+	headerRows := s.headerRows
+	if headerRows > maxHeaderRows {
+		headerRows = maxHeaderRows
+	}
+
+	if headerRows == 0 {
+		row, err := readSheetRow(book, sheetName, headerRow)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !slices.Contains(row, "") {
+			return nil, 1, nil
+		}
+	}
+
+	if headerRows == 1 {
+		return nil, 1, nil
+	}
+
+	merges, err := book.HeaderMerges(sheetName, headerRow)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if headerRows == 0 {
+		headerRows = detectHeaderRows(merges, headerRow)
+	}
+
+	return merges, headerRows, nil
+}
+
+// readSheetRow читает одну строку листа потоково, не загружая лист целиком.
+func readSheetRow(book spreadsheet, sheetName string, rowNumber int) ([]string, error) {
+	rows, err := book.Rows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows from sheet: %w", err)
+	}
+	defer rows.Close()
+
+	for current := 1; rows.Next(); current++ {
+		if current < rowNumber {
+			continue
+		}
+		row, err := rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", rowNumber, err)
+		}
+		return row, nil
+	}
+
+	return nil, rows.Error()
+}
+
+// sheetHeaderMerges возвращает объединенные ячейки, которые начинаются в строках заголовка.
+func sheetHeaderMerges(f *excelize.File, sheetName string, headerRow int) ([]headerMerge, error) {
+	mergeCells, err := f.GetMergeCells(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merged cells: %w", err)
+	}
+
+	var merges []headerMerge
+	for _, cell := range mergeCells {
+		firstCol, firstRow, err := excelize.CellNameToCoordinates(cell.GetStartAxis())
+		if err != nil {
+			continue
+		}
+		lastCol, lastRow, err := excelize.CellNameToCoordinates(cell.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		if firstRow < headerRow || firstRow >= headerRow+maxHeaderRows {
+			continue
+		}
+		merges = append(merges, headerMerge{
+			FirstRow: firstRow, LastRow: lastRow,
+			FirstCol: firstCol, LastCol: lastCol,
+			Value: cell.GetCellValue(),
+		})
+	}
+
+	return merges, nil
+}
+
+// detectHeaderRows определяет количество строк заголовка по объединенным ячейкам.
+// Группа, объединенная по горизонтали ("Stock" над HDT/MDT/LDT), означает строку подзаголовков под ней;
+// ячейка, объединенная по вертикали ("Dealer" на две строки), задает высоту заголовка сама.
+func detectHeaderRows(merges []headerMerge, headerRow int) int {
+	rows := 1
+	for _, merge := range merges {
+		height := merge.LastRow - headerRow + 1
+		if merge.LastCol > merge.FirstCol {
+			height++
+		}
+		if height > rows {
+			rows = height
+		}
+	}
+	if rows > maxHeaderRows {
+		rows = maxHeaderRows
+	}
+	return rows
+}
+
+// headerColumns собирает части заголовка каждой колонки из строк заголовка.
+// Значение объединенной ячейки есть только в ее левой верхней ячейке, поэтому оно распространяется на всю область.
+func headerColumns(rows [][]string, merges []headerMerge, headerRow int) [][]string {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	for _, merge := range merges {
+		if merge.LastCol > width {
+			width = merge.LastCol
+		}
+	}
+
+	columns := make([][]string, width)
+	for col := range columns {
+		columns[col] = make([]string, len(rows))
+		for r, row := range rows {
+			if col < len(row) {
+				columns[col][r] = row[col]
+			}
+		}
+	}
+
+	for _, merge := range merges {
+		for r := merge.FirstRow - headerRow; r <= merge.LastRow-headerRow && r < len(rows); r++ {
+			for col := merge.FirstCol - 1; col < merge.LastCol; col++ {
+				columns[col][r] = merge.Value
+			}
+		}
+	}
+
+	return columns
+}
+
+// sanitizeHeaderName приводит текст заголовка к названию колонки.
+func sanitizeHeaderName(header string) string {
+	name := strings.ToLower(strings.TrimSpace(header))
+	name = strings.ReplaceAll(name, " ", "_")
+	name = strings.ReplaceAll(name, "-", "_")
+	name = strings.ReplaceAll(name, ".", "_")
+	name = strings.ReplaceAll(name, "/", "_")
+	name = strings.ReplaceAll(name, "\\", "_")
+	name = strings.ReplaceAll(name, "(", "_")
+	name = strings.ReplaceAll(name, ")", "_")
+	name = strings.ReplaceAll(name, "%", "_percent")
+
+	// Убираем множественные underscore
+	for strings.Contains(name, "__") {
+		name = strings.ReplaceAll(name, "__", "_")
+	}
+
+	// Убираем начальные и конечные underscore
+	return strings.Trim(name, "_")
+}
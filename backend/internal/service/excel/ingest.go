@@ -0,0 +1,381 @@
+package excel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+)
+
+// IngestKeyRetention сколько хранится ответ по Idempotency-Key; после этого ключ можно использовать заново.
+const IngestKeyRetention = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidIngestRequest тело запроса загрузки через API не разобрано или пакет пуст.
+	ErrInvalidIngestRequest = errors.New("invalid ingest request")
+
+	// ErrIdempotencyKeyReused Idempotency-Key уже использован с другим телом запроса.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
+)
+
+// ingestIdentityColumns колонки записи, которые задаются полями записи, а не показателями.
+var ingestIdentityColumns = map[string]bool{"dealer": true, "city": true, "region": true}
+
+// ingestPeriod проверенные записи одного квартала.
+type ingestPeriod struct {
+	year    int
+	quarter string
+	indexes []int                          // Позиции записей в запросе
+	values  []map[string]string            // Нормализованные значения по колонкам
+	used    map[string]bool                // Колонки, заполненные хотя бы в одной записи
+	keys    map[string]map[string]struct{} // Дилер и город, уже встреченные в квартале
+}
+
+// IngestDealerMetrics загружает пакет показателей дилеров из внешней системы.
+// Показатели проверяются по тому же словарю колонок и нормализуются так же, как ячейки Excel.
+// Пакет пишется целиком в одной транзакции: если хотя бы одна запись отклонена, не сохраняется ничего.
+// Ответ сохраняется по ключу идемпотентности: повтор запроса с тем же ключом и телом возвращает его без повторной записи.
+func (s *Service) IngestDealerMetrics(ctx context.Context, owner, key string, body []byte) (*model.IngestDealerMetricsResponse, error) {
+	hash := sha256.Sum256(body)
+	requestHash := hex.EncodeToString(hash[:])
+
+	if response, err := s.replayIngest(ctx, owner, key, requestHash); err != nil || response != nil {
+		return response, err
+	}
+
+	var request model.IngestDealerMetricsRequest
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIngestRequest, err.Error())
+	}
+	if len(request.Records) == 0 {
+		return nil, fmt.Errorf("%w: records are empty", ErrInvalidIngestRequest)
+	}
+	if len(request.Records) > model.MaxIngestRecords {
+		return nil, fmt.Errorf("%w: %d records exceed the limit of %d", ErrInvalidIngestRequest, len(request.Records), model.MaxIngestRecords)
+	}
+
+	response, periods := validateIngestRecords(request.Records)
+
+	tx, err := s.dynamicRepo.BeginTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if response.Status == model.IngestStatusCompleted {
+		for _, period := range periods {
+			columns, rows := period.rows()
+
+			replaced, err := s.dynamicRepo.MergeDealerMetrics(ctx, tx, period.year, period.quarter, columns, rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to write dealer_metrics: %w", err)
+			}
+			for i, index := range period.indexes {
+				if replaced[i] {
+					response.Records[index].Status = model.IngestRecordReplaced
+					response.Replaced++
+				} else {
+					response.Records[index].Status = model.IngestRecordCreated
+					response.Created++
+				}
+			}
+
+			promotion, err := s.promote(ctx, tx, period.year, period.quarter)
+			if err != nil {
+				return nil, err
+			}
+			if promotion != nil {
+				response.Promotion = append(response.Promotion, promotion)
+			}
+
+			dataset := &model.ImportDataset{
+				Kind:       model.ImportKindIngest,
+				Year:       period.year,
+				Quarter:    period.quarter,
+				SourceFile: "ingest:" + key,
+				RowCount:   len(rows),
+				ImportedBy: owner,
+				Schema:     model.ImportSchema{Columns: columns},
+			}
+			if err := s.catalog.RecordImport(ctx, tx, dataset); err != nil {
+				return nil, fmt.Errorf("failed to record import: %w", err)
+			}
+			response.DatasetIDs = append(response.DatasetIDs, dataset.ID)
+		}
+	}
+
+	// Отклоненный пакет тоже сохраняется по ключу: повтор того же запроса получит тот же отчет
+	err = s.catalog.RecordIngestRequest(ctx, tx, &model.IngestRequest{
+		Owner:          owner,
+		IdempotencyKey: key,
+		RequestHash:    requestHash,
+		Response:       response,
+	})
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		// Параллельный запрос с тем же ключом успел сохранить результат раньше
+		tx.Rollback(ctx)
+		return s.replayIngest(ctx, owner, key, requestHash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record ingest request: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("Dealer metrics ingested",
+		slog.String("owner", owner),
+		slog.String("idempotency_key", key),
+		slog.String("status", string(response.Status)),
+		slog.Int("created", response.Created),
+		slog.Int("replaced", response.Replaced),
+		slog.Int("rejected", response.Rejected),
+	)
+
+	return response, nil
+}
+
+// replayIngest возвращает сохраненный ответ по ключу идемпотентности или nil, если ключ еще не использован.
+func (s *Service) replayIngest(ctx context.Context, owner, key, requestHash string) (*model.IngestDealerMetricsResponse, error) {
+	stored, err := s.catalog.GetIngestRequest(ctx, owner, key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest request: %w", err)
+	}
+
+	if stored.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	stored.Response.Replayed = true
+	return stored.Response, nil
+}
+
+// validateIngestRecords проверяет записи и раскладывает корректные по кварталам.
+// Если есть отклоненные записи, остальные получают статус skipped: пакет не сохраняется.
+func validateIngestRecords(records []model.IngestDealerMetricsRecord) (*model.IngestDealerMetricsResponse, []*ingestPeriod) {
+	response := &model.IngestDealerMetricsResponse{
+		Status:  model.IngestStatusCompleted,
+		Records: make([]model.IngestRecordResult, len(records)),
+	}
+
+	byPeriod := make(map[string]*ingestPeriod)
+	var periods []*ingestPeriod
+
+	for i, record := range records {
+		result := &response.Records[i]
+		result.Index = i
+		result.Dealer = strings.TrimSpace(record.Dealer)
+		result.Year = record.Year
+		result.Quarter = strings.ToUpper(strings.TrimSpace(record.Quarter))
+
+		values, errs := validateIngestRecord(record, result.Quarter)
+
+		name := model.DealerNetPeriodName(result.Year, result.Quarter)
+		period := byPeriod[name]
+		if len(errs) == 0 && period != nil {
+			if _, ok := period.keys[strings.ToLower(values["dealer"])][strings.ToLower(values["city"])]; ok {
+				errs = append(errs, "dealer and city are repeated in the batch for this quarter")
+			}
+		}
+
+		if len(errs) > 0 {
+			result.Status = model.IngestRecordRejected
+			result.Errors = errs
+			response.Rejected++
+			continue
+		}
+
+		if period == nil {
+			period = &ingestPeriod{
+				year:    result.Year,
+				quarter: result.Quarter,
+				used:    make(map[string]bool),
+				keys:    make(map[string]map[string]struct{}),
+			}
+			byPeriod[name] = period
+			periods = append(periods, period)
+		}
+		period.add(i, values)
+	}
+
+	if response.Rejected > 0 {
+		response.Status = model.IngestStatusRejected
+		for i := range response.Records {
+			if response.Records[i].Status == "" {
+				response.Records[i].Status = model.IngestRecordSkipped
+			}
+		}
+		return response, nil
+	}
+
+	sort.Slice(periods, func(i, j int) bool {
+		if periods[i].year != periods[j].year {
+			return periods[i].year < periods[j].year
+		}
+		return periods[i].quarter < periods[j].quarter
+	})
+	return response, periods
+}
+
+// validateIngestRecord проверяет запись и возвращает нормализованные значения по колонкам dealer_metrics.
+func validateIngestRecord(record model.IngestDealerMetricsRecord, quarter string) (map[string]string, []string) {
+	var errs []string
+	values := make(map[string]string, len(record.Metrics)+3)
+
+	if _, err := model.QuarterStart(record.Year, quarter); err != nil || record.Year < 2000 {
+		errs = append(errs, fmt.Sprintf("invalid period: year %d, quarter %q", record.Year, record.Quarter))
+	}
+
+	values["dealer"] = strings.TrimSpace(record.Dealer)
+	if values["dealer"] == "" {
+		errs = append(errs, "dealer is required")
+	}
+
+	values["city"] = strings.TrimSpace(record.City)
+	if values["city"] == "" {
+		errs = append(errs, "city is required")
+	}
+
+	if region, ok := model.Regions().Resolve(record.Region); ok {
+		values["region"] = region.Name
+	} else {
+		errs = append(errs, fmt.Sprintf("unknown region %q", record.Region))
+	}
+
+	// Ключи показателей сопоставляются со словарем заголовков, как заголовки листа
+	names := make([]string, 0, len(record.Metrics))
+	for name := range record.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sources := make(map[string]string, len(names))
+	for _, name := range names {
+		column, ok := headerIndex[sanitizeHeaderName(name)]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Sprintf("unknown metric %q", name))
+			continue
+		case ingestIdentityColumns[column]:
+			errs = append(errs, fmt.Sprintf("metric %q duplicates the %s field of the record", name, column))
+			continue
+		case sources[column] != "":
+			errs = append(errs, fmt.Sprintf("metrics %q and %q both map to %s", sources[column], name, column))
+			continue
+		}
+		sources[column] = name
+
+		value, err := ingestMetricValue(record.Metrics[name], columnKind(column))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("metric %q: %s", name, err.Error()))
+			continue
+		}
+		if value != "" {
+			values[column] = value
+		}
+	}
+
+	return values, errs
+}
+
+// ingestMetricValue приводит JSON-значение показателя к виду для хранения по типу колонки.
+// Строки разбираются так же, как ячейки Excel; null означает пустое значение.
+func ingestMetricValue(raw json.RawMessage, kind model.ValueKind) (string, error) {
+	raw = bytes.TrimSpace(raw)
+
+	var value string
+	switch {
+	case len(raw) == 0 || string(raw) == "null":
+		return "", nil
+	case raw[0] == '"':
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return "", err
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return "", nil
+		}
+	case string(raw) == "true" || string(raw) == "false":
+		if kind != model.ValueKindBool && kind != model.ValueKindText {
+			return "", fmt.Errorf("boolean is not a %s value", kind)
+		}
+		return string(model.YesNoOf(string(raw) == "true")), nil
+	case raw[0] == '-' || (raw[0] >= '0' && raw[0] <= '9'):
+		// Число JSON всегда с десятичной точкой: разбираем без эвристик разделителей разрядов
+		if kind == model.ValueKindNumber || kind == model.ValueKindPercent {
+			number, err := strconv.ParseFloat(string(raw), 64)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(number, 'f', -1, 64), nil
+		}
+		value = string(raw)
+	default:
+		return "", fmt.Errorf("expected a string, number, boolean or null")
+	}
+
+	if kind == model.ValueKindText {
+		return value, nil
+	}
+
+	normalized, err := model.NormalizeValue(kind, value)
+	if err != nil {
+		return "", fmt.Errorf("unrecognized %s value %q", kind, value)
+	}
+	return normalized, nil
+}
+
+// add добавляет проверенную запись в квартал.
+func (p *ingestPeriod) add(index int, values map[string]string) {
+	p.indexes = append(p.indexes, index)
+	p.values = append(p.values, values)
+	for column := range values {
+		p.used[column] = true
+	}
+
+	dealer := strings.ToLower(values["dealer"])
+	if p.keys[dealer] == nil {
+		p.keys[dealer] = make(map[string]struct{})
+	}
+	p.keys[dealer][strings.ToLower(values["city"])] = struct{}{}
+}
+
+// rows собирает строки квартала в порядке словаря колонок.
+func (p *ingestPeriod) rows() ([]string, [][]interface{}) {
+	var columns []string
+	for _, field := range dealerNetHeaderFields {
+		if p.used[field.Name] {
+			columns = append(columns, field.Name)
+		}
+	}
+
+	rows := make([][]interface{}, len(p.values))
+	for i, values := range p.values {
+		row := make([]interface{}, len(columns))
+		for j, column := range columns {
+			if value, ok := values[column]; ok {
+				row[j] = value
+			}
+		}
+		rows[i] = row
+	}
+
+	return columns, rows
+}
@@ -0,0 +1,217 @@
+package excel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+)
+
+// ingestRepository репозиторий-заглушка, который запоминает дилеров кварталов.
+type ingestRepository struct {
+	benchRepository
+	dealers map[string]bool
+	writes  int
+}
+
+func (r *ingestRepository) MergeDealerMetrics(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, rows [][]interface{}) ([]bool, error) {
+	r.writes++
+	replaced := make([]bool, len(rows))
+	for i, row := range rows {
+		key := model.DealerNetPeriodName(year, quarter) + "|" + strings.ToLower(row[0].(string))
+		replaced[i] = r.dealers[key]
+		r.dealers[key] = true
+	}
+	return replaced, nil
+}
+
+// ingestCatalog каталог-заглушка, который хранит ответы по ключам идемпотентности.
+type ingestCatalog struct {
+	benchCatalog
+	requests map[string]*model.IngestRequest
+}
+
+func (c *ingestCatalog) GetIngestRequest(ctx context.Context, owner, key string) (*model.IngestRequest, error) {
+	request, ok := c.requests[owner+"|"+key]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	// Ответ читается из JSONB: копия без служебных полей
+	data, err := json.Marshal(request.Response)
+	if err != nil {
+		return nil, err
+	}
+	stored := *request
+	stored.Response = &model.IngestDealerMetricsResponse{}
+	return &stored, json.Unmarshal(data, stored.Response)
+}
+
+func (c *ingestCatalog) RecordIngestRequest(ctx context.Context, tx pgx.Tx, request *model.IngestRequest) error {
+	c.requests[request.Owner+"|"+request.IdempotencyKey] = request
+	return nil
+}
+
+func newIngestService() (*Service, *ingestRepository) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &ingestRepository{
+		benchRepository: benchRepository{DynamicTableRepository: repository.NewDynamicTableRepository(nil, logger)},
+		dealers:         make(map[string]bool),
+	}
+	catalog := &ingestCatalog{requests: make(map[string]*model.IngestRequest)}
+	return NewService(repo, catalog, nil, Options{}, logger), repo
+}
+
+func TestIngestMetricValue(t *testing.T) {
+	tests := []struct {
+		raw     string
+		kind    model.ValueKind
+		want    string
+		wantErr bool
+	}{
+		{raw: `1234.5`, kind: model.ValueKindNumber, want: "1234.5"},
+		{raw: `"1 234,5"`, kind: model.ValueKindNumber, want: "1234.5"},
+		{raw: `"85 %"`, kind: model.ValueKindPercent, want: "85"},
+		{raw: `true`, kind: model.ValueKindBool, want: "Yes"},
+		{raw: `"Нет"`, kind: model.ValueKindBool, want: "No"},
+		{raw: `"31.12.2025"`, kind: model.ValueKindDate, want: "2025-12-31"},
+		{raw: `null`, kind: model.ValueKindNumber, want: ""},
+		{raw: `"Gold"`, kind: model.ValueKindText, want: "Gold"},
+		{raw: `"n/a"`, kind: model.ValueKindNumber, wantErr: true},
+		{raw: `true`, kind: model.ValueKindNumber, wantErr: true},
+		{raw: `[1]`, kind: model.ValueKindText, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ingestMetricValue(json.RawMessage(tt.raw), tt.kind)
+		if tt.wantErr {
+			assert.Error(t, err, tt.raw)
+			continue
+		}
+		if assert.NoError(t, err, tt.raw) {
+			assert.Equal(t, tt.want, got, tt.raw)
+		}
+	}
+}
+
+func TestIngestDealerMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid records reject the whole batch", func(t *testing.T) {
+		service, repo := newIngestService()
+
+		body := []byte(`{"records": [
+			{"year": 2025, "quarter": "q3", "dealer": "Alfa", "city": "Moscow", "region": "Central", "metrics": {"Stock HDT": 12, "Брендинг": "Да"}},
+			{"year": 2025, "quarter": "Q5", "dealer": "", "city": "Kazan", "region": "Atlantis", "metrics": {"unknown": 1, "check_list_percent": "n/a"}},
+			{"year": 2025, "quarter": "Q3", "dealer": "alfa", "city": "moscow", "region": "Central"}
+		]}`)
+
+		response, err := service.IngestDealerMetrics(ctx, "admin", "batch-1", body)
+		require.NoError(t, err)
+
+		assert.Equal(t, model.IngestStatusRejected, response.Status)
+		assert.Equal(t, 2, response.Rejected)
+		assert.Equal(t, 0, repo.writes)
+
+		records := response.Records
+		require.Len(t, records, 3)
+		assert.Equal(t, model.IngestRecordSkipped, records[0].Status)
+		assert.Equal(t, model.IngestRecordRejected, records[1].Status)
+		assert.Len(t, records[1].Errors, 5)
+		assert.Equal(t, model.IngestRecordRejected, records[2].Status)
+		assert.Contains(t, records[2].Errors[0], "repeated")
+	})
+
+	t.Run("valid batch is written per quarter and replayed by key", func(t *testing.T) {
+		service, repo := newIngestService()
+		repo.dealers["dealer_net_2025_q3|alfa"] = true
+
+		body := []byte(`{"records": [
+			{"year": 2025, "quarter": "Q3", "dealer": "Alfa", "city": "Moscow", "region": "Central", "metrics": {"stock_hdt": "1 250"}},
+			{"year": 2025, "quarter": "Q2", "dealer": "Beta", "city": "Kazan", "region": "Volga", "metrics": {"Check list %": 87.5}}
+		]}`)
+
+		response, err := service.IngestDealerMetrics(ctx, "admin", "batch-2", body)
+		require.NoError(t, err)
+		assert.Equal(t, model.IngestStatusCompleted, response.Status)
+		assert.Equal(t, model.IngestRecordReplaced, response.Records[0].Status)
+		assert.Equal(t, model.IngestRecordCreated, response.Records[1].Status)
+		assert.Equal(t, 2, repo.writes)
+		assert.False(t, response.Replayed)
+
+		replayed, err := service.IngestDealerMetrics(ctx, "admin", "batch-2", body)
+		require.NoError(t, err)
+		assert.True(t, replayed.Replayed)
+		assert.Equal(t, response.Records, replayed.Records)
+		assert.Equal(t, 2, repo.writes)
+
+		_, err = service.IngestDealerMetrics(ctx, "admin", "batch-2", []byte(`{"records": []}`))
+		assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		service, _ := newIngestService()
+
+		for _, body := range []string{`{"records": []}`, `{"rows": []}`, `not json`} {
+			_, err := service.IngestDealerMetrics(ctx, "admin", body, []byte(body))
+			assert.ErrorIs(t, err, ErrInvalidIngestRequest, body)
+		}
+	})
+}
+
+// datasetCatalog каталог-заглушка с одной записью.
+type datasetCatalog struct {
+	benchCatalog
+	dataset *model.ImportDataset
+}
+
+func (c datasetCatalog) GetByID(ctx context.Context, id int64) (*model.ImportDataset, error) {
+	return c.dataset, nil
+}
+
+func TestIngestDatasetHoldsQuarterData(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		kind    model.ImportKind
+		status  model.ImportStatus
+		hasData bool
+	}{
+		{kind: model.ImportKindIngest, status: model.ImportStatusCompleted, hasData: true},
+		{kind: model.ImportKindDealerNet, status: model.ImportStatusCompleted, hasData: true},
+		{kind: model.ImportKindIngest, status: model.ImportStatusSuperseded},
+		{kind: model.ImportKindBrands, status: model.ImportStatusCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind)+" "+string(tt.status), func(t *testing.T) {
+			dataset := &model.ImportDataset{ID: 7, Kind: tt.kind, Year: 2025, Quarter: "Q2", Status: tt.status}
+			service := NewService(nil, datasetCatalog{dataset: dataset}, nil, Options{}, logger)
+
+			_, err := service.datasetWithData(ctx, dataset.ID)
+			if tt.hasData {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrDatasetUnavailable)
+			}
+		})
+	}
+
+	t.Run("deleted ingest is restorable", func(t *testing.T) {
+		deletedAt := time.Now()
+		dataset := &model.ImportDataset{ID: 8, Kind: model.ImportKindIngest, Status: model.ImportStatusDeleted, DeletedAt: &deletedAt}
+		service := NewService(nil, datasetCatalog{dataset: dataset}, nil, Options{}, logger)
+
+		got, err := service.GetImport(ctx, dataset.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, got.PurgeAt)
+	})
+}
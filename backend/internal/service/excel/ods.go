@@ -0,0 +1,375 @@
+package excel
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// odsContentPart содержимое книги OpenDocument.
+const odsContentPart = "content.xml"
+
+// Пространства имен OpenDocument, в которых лежат таблицы и текст ячеек.
+const (
+	odsTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// odsBook книга OpenDocument (.ods). Листы читаются потоково из content.xml.
+type odsBook struct {
+	archive *zip.Reader
+	close   func() error
+	sheets  []string
+}
+
+// openODS открывает книгу OpenDocument.
+func openODS(file io.Reader, _ string) (spreadsheet, error) {
+	archive, closeArchive, _, err := openArchive(file)
+	if err != nil {
+		return nil, err
+	}
+
+	book := &odsBook{archive: archive, close: closeArchive}
+	if book.sheets, err = book.readSheetList(); err != nil {
+		closeArchive()
+		return nil, err
+	}
+	return book, nil
+}
+
+// readSheetList собирает названия таблиц, пропуская их содержимое.
+func (b *odsBook) readSheetList() ([]string, error) {
+	part, err := b.archive.Open(odsContentPart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", odsContentPart, err)
+	}
+	defer part.Close()
+
+	var sheets []string
+	decoder := xml.NewDecoder(part)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return sheets, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", odsContentPart, err)
+		}
+		if start, ok := token.(xml.StartElement); ok && isODSElement(start, odsTableNS, "table") {
+			sheets = append(sheets, odsAttr(start, odsTableNS, "name"))
+			if err := decoder.Skip(); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", odsContentPart, err)
+			}
+		}
+	}
+}
+
+// SheetList возвращает листы книги.
+func (b *odsBook) SheetList() []string {
+	return b.sheets
+}
+
+// Rows потоково читает строки таблицы.
+func (b *odsBook) Rows(sheetName string) (sheetRows, error) {
+	return b.rows(sheetName)
+}
+
+// rows открывает content.xml и переходит к началу таблицы.
+func (b *odsBook) rows(sheetName string) (*odsRows, error) {
+	part, err := b.archive.Open(odsContentPart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", odsContentPart, err)
+	}
+
+	decoder := xml.NewDecoder(part)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			part.Close()
+			return nil, fmt.Errorf("sheet %s not found", sheetName)
+		}
+		if err != nil {
+			part.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", odsContentPart, err)
+		}
+		if start, ok := token.(xml.StartElement); ok && isODSElement(start, odsTableNS, "table") {
+			if odsAttr(start, odsTableNS, "name") == sheetName {
+				return &odsRows{decoder: decoder, part: part}, nil
+			}
+			if err := decoder.Skip(); err != nil {
+				part.Close()
+				return nil, fmt.Errorf("failed to read %s: %w", odsContentPart, err)
+			}
+		}
+	}
+}
+
+// HeaderRow в шаблоне, сохраненном в OpenDocument, такая же, как в Excel.
+func (b *odsBook) HeaderRow(string) (int, error) {
+	return 2, nil
+}
+
+// HeaderMerges собирает объединенные ячейки из атрибутов number-columns-spanned и number-rows-spanned.
+func (b *odsBook) HeaderMerges(sheetName string, headerRow int) ([]headerMerge, error) {
+	rows, err := b.rows(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var merges []headerMerge
+	for rowNumber := 1; rowNumber < headerRow+maxHeaderRows && rows.Next(); rowNumber++ {
+		if rowNumber < headerRow {
+			continue
+		}
+		for _, span := range rows.spans {
+			merges = append(merges, headerMerge{
+				FirstRow: rowNumber, LastRow: rowNumber + span.rows - 1,
+				FirstCol: span.col, LastCol: span.col + span.cols - 1,
+				Value: span.value,
+			})
+		}
+	}
+
+	return merges, rows.Error()
+}
+
+// Formulas в OpenDocument всегда сохраняются вместе со значением, вычислять нечего.
+func (b *odsBook) Formulas(string) (*sheetFormulas, error) {
+	return nil, nil
+}
+
+// Period у книги OpenDocument не задается: период берется из имени файла.
+func (b *odsBook) Period() (int, string, bool) {
+	return 0, "", false
+}
+
+// Close закрывает архив.
+func (b *odsBook) Close() error {
+	return b.close()
+}
+
+// odsSpan объединенная ячейка строки.
+type odsSpan struct {
+	col, cols, rows int
+	value           string
+}
+
+// odsRows строки таблицы OpenDocument.
+// Повторы строк и ячеек (number-rows-repeated, number-columns-repeated) разворачиваются,
+// пустые ячейки в конце строки отбрасываются, как у excelize.
+type odsRows struct {
+	decoder *xml.Decoder
+	part    io.ReadCloser
+	row     []string
+	spans   []odsSpan
+	repeat  int
+	done    bool
+	err     error
+}
+
+// Next переходит к следующей строке таблицы.
+func (r *odsRows) Next() bool {
+	if r.repeat > 1 {
+		r.repeat--
+		r.spans = nil
+		return true
+	}
+	if r.done || r.err != nil {
+		return false
+	}
+
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			r.err = fmt.Errorf("failed to read %s: %w", odsContentPart, err)
+			return false
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if isODSElement(t, odsTableNS, "table-row") {
+				r.repeat = odsIntAttr(t, odsTableNS, "number-rows-repeated", 1)
+				if err := r.readRow(); err != nil {
+					r.err = err
+					return false
+				}
+				return true
+			}
+			if isODSElement(t, odsTableNS, "table-columns") || isODSElement(t, odsTableNS, "table-column") ||
+				isODSElement(t, odsTableNS, "shapes") || isODSElement(t, odsOfficeNS, "forms") {
+				if err := r.decoder.Skip(); err != nil {
+					r.err = err
+					return false
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Space == odsTableNS && t.Name.Local == "table" {
+				r.done = true
+				return false
+			}
+		}
+	}
+}
+
+// readRow читает ячейки строки до конца элемента table-row.
+func (r *odsRows) readRow() error {
+	r.row, r.spans = nil, nil
+	pending := 0 // Пустые ячейки, которые попадут в строку, только если за ними есть значение
+
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", odsContentPart, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !isODSElement(t, odsTableNS, "table-cell") && !isODSElement(t, odsTableNS, "covered-table-cell") {
+				if err := r.decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			value, err := r.readCell(t)
+			if err != nil {
+				return err
+			}
+
+			repeated := odsIntAttr(t, odsTableNS, "number-columns-repeated", 1)
+			if value == "" {
+				pending += repeated
+				continue
+			}
+
+			for ; pending > 0; pending-- {
+				r.row = append(r.row, "")
+			}
+			col := len(r.row) + 1
+			for i := 0; i < repeated; i++ {
+				r.row = append(r.row, value)
+			}
+
+			cols := odsIntAttr(t, odsTableNS, "number-columns-spanned", 1)
+			rows := odsIntAttr(t, odsTableNS, "number-rows-spanned", 1)
+			if cols > 1 || rows > 1 {
+				r.spans = append(r.spans, odsSpan{col: col, cols: cols, rows: rows, value: value})
+			}
+		case xml.EndElement:
+			if t.Name.Space == odsTableNS && t.Name.Local == "table-row" {
+				return nil
+			}
+		}
+	}
+}
+
+// readCell возвращает отображаемый текст ячейки, а без текста - значение по типу ячейки.
+func (r *odsRows) readCell(start xml.StartElement) (string, error) {
+	var (
+		paragraphs []string
+		text       strings.Builder
+		depth      int
+	)
+
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", odsContentPart, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case isODSElement(t, odsOfficeNS, "annotation"):
+				if err := r.decoder.Skip(); err != nil {
+					return "", err
+				}
+				continue
+			case isODSElement(t, odsTextNS, "p"):
+				text.Reset()
+			case isODSElement(t, odsTextNS, "s"):
+				text.WriteString(strings.Repeat(" ", odsIntAttr(t, odsTextNS, "c", 1)))
+			case isODSElement(t, odsTextNS, "tab"):
+				text.WriteByte('\t')
+			case isODSElement(t, odsTextNS, "line-break"):
+				text.WriteByte('\n')
+			}
+			depth++
+		case xml.CharData:
+			if depth > 0 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				return odsCellValue(start, strings.Join(paragraphs, "\n")), nil
+			}
+			depth--
+			if t.Name.Space == odsTextNS && t.Name.Local == "p" {
+				paragraphs = append(paragraphs, text.String())
+			}
+		}
+	}
+}
+
+// odsCellValue возвращает текст ячейки или, если его нет, значение из атрибутов office:*-value.
+func odsCellValue(cell xml.StartElement, text string) string {
+	if strings.TrimSpace(text) != "" {
+		return text
+	}
+
+	switch odsAttr(cell, odsOfficeNS, "value-type") {
+	case "float", "percentage", "currency":
+		return odsAttr(cell, odsOfficeNS, "value")
+	case "date":
+		return strings.TrimSuffix(odsAttr(cell, odsOfficeNS, "date-value"), "T00:00:00")
+	case "time":
+		return odsAttr(cell, odsOfficeNS, "time-value")
+	case "boolean":
+		return odsAttr(cell, odsOfficeNS, "boolean-value")
+	}
+	return ""
+}
+
+// Columns возвращает значения ячеек текущей строки.
+func (r *odsRows) Columns() ([]string, error) {
+	return r.row, nil
+}
+
+// Error возвращает ошибку чтения.
+func (r *odsRows) Error() error {
+	return r.err
+}
+
+// Close закрывает content.xml.
+func (r *odsRows) Close() error {
+	return r.part.Close()
+}
+
+// isODSElement проверяет пространство имен и имя элемента.
+func isODSElement(element xml.StartElement, space, local string) bool {
+	return element.Name.Space == space && element.Name.Local == local
+}
+
+// odsAttr возвращает значение атрибута элемента.
+func odsAttr(element xml.StartElement, space, local string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// odsIntAttr возвращает числовой атрибут элемента или значение по умолчанию.
+func odsIntAttr(element xml.StartElement, space, local string, fallback int) int {
+	value, err := strconv.Atoi(odsAttr(element, space, local))
+	if err != nil || value < 1 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,171 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrUnsupportedFormat формат файла загрузки не поддерживается.
+var ErrUnsupportedFormat = errors.New("unsupported file format")
+
+// spreadsheet книга загрузки в одном из поддерживаемых форматов.
+// Сопоставление заголовков, нормализация и вставка читают все форматы через этот интерфейс.
+type spreadsheet interface {
+	// SheetList возвращает листы книги; у CSV лист - файл региона.
+	SheetList() []string
+	// Rows потоково читает строки листа.
+	Rows(sheetName string) (sheetRows, error)
+	// HeaderRow возвращает номер строки заголовков колонок (с 1).
+	HeaderRow(sheetName string) (int, error)
+	// HeaderMerges возвращает объединенные ячейки, которые начинаются в строках заголовка.
+	HeaderMerges(sheetName string, headerRow int) ([]headerMerge, error)
+	// Formulas возвращает формулы листа без сохраненного значения; nil, если вычислять нечего.
+	Formulas(sheetName string) (*sheetFormulas, error)
+	// Period возвращает период из манифеста файла; ok=false - период берется из имени файла.
+	Period() (year int, quarter string, ok bool)
+	Close() error
+}
+
+// sheetRows построчное чтение листа.
+type sheetRows interface {
+	Next() bool
+	Columns() ([]string, error)
+	Error() error
+	Close() error
+}
+
+// inputFormat формат файла загрузки.
+type inputFormat struct {
+	Name       string
+	Extensions []string
+	Open       func(file io.Reader, fileName string) (spreadsheet, error)
+}
+
+// inputFormats поддерживаемые форматы загрузки.
+var inputFormats = []inputFormat{
+	{Name: "xlsx", Extensions: []string{".xlsx"}, Open: openXLSX},
+	{Name: "ods", Extensions: []string{".ods"}, Open: openODS},
+	{Name: "csv", Extensions: []string{".csv"}, Open: openCSV},
+	{Name: "csv_archive", Extensions: []string{".zip"}, Open: openCSVArchive},
+}
+
+// findInputFormat возвращает формат файла по расширению.
+func findInputFormat(fileName string) (*inputFormat, bool) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for i := range inputFormats {
+		for _, extension := range inputFormats[i].Extensions {
+			if ext == extension {
+				return &inputFormats[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// SupportedExtensions возвращает расширения файлов, которые принимает загрузка.
+func SupportedExtensions() []string {
+	var extensions []string
+	for _, format := range inputFormats {
+		extensions = append(extensions, format.Extensions...)
+	}
+	return extensions
+}
+
+// IsSupportedFile проверяет, что формат файла поддерживается загрузкой.
+func IsSupportedFile(fileName string) bool {
+	_, ok := findInputFormat(fileName)
+	return ok
+}
+
+// openSpreadsheet открывает файл загрузки ридером его формата.
+func openSpreadsheet(file io.Reader, fileName string) (spreadsheet, error) {
+	format, ok := findInputFormat(fileName)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", filepath.Ext(fileName), ErrUnsupportedFormat)
+	}
+
+	book, err := format.Open(file, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s file: %w", format.Name, err)
+	}
+	return book, nil
+}
+
+// openArchive открывает zip-архив файла: файл на диске читается по пути, остальное - из памяти.
+// Возвращает архив, функцию закрытия и reader, из которого файл можно прочитать еще раз.
+func openArchive(file io.Reader) (*zip.Reader, func() error, io.Reader, error) {
+	if osFile, ok := file.(*os.File); ok {
+		zr, err := zip.OpenReader(osFile.Name())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return &zr.Reader, zr.Close, file, nil
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return archive, func() error { return nil }, bytes.NewReader(data), nil
+}
+
+// openXLSX открывает книгу Excel.
+func openXLSX(file io.Reader, _ string) (spreadsheet, error) {
+	return openDealerNetWorkbook(file)
+}
+
+// SheetList возвращает листы книги.
+func (w *workbook) SheetList() []string {
+	return w.GetSheetList()
+}
+
+// Rows потоково читает строки листа.
+func (w *workbook) Rows(sheetName string) (sheetRows, error) {
+	rows, err := w.File.Rows(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	return xlsxRows{rows}, nil
+}
+
+// HeaderRow в шаблоне Excel: строка 1 - заголовок листа, заголовки колонок во второй строке.
+func (w *workbook) HeaderRow(string) (int, error) {
+	return 2, nil
+}
+
+// HeaderMerges возвращает объединенные ячейки заголовка.
+func (w *workbook) HeaderMerges(sheetName string, headerRow int) ([]headerMerge, error) {
+	return sheetHeaderMerges(w.File, sheetName, headerRow)
+}
+
+// Formulas возвращает формулы листа без сохраненного значения.
+func (w *workbook) Formulas(sheetName string) (*sheetFormulas, error) {
+	return newSheetFormulas(w, sheetName)
+}
+
+// Period у книги Excel не задается: период берется из имени файла.
+func (w *workbook) Period() (int, string, bool) {
+	return 0, "", false
+}
+
+// xlsxRows строки листа excelize.
+type xlsxRows struct {
+	*excelize.Rows
+}
+
+// Columns возвращает значения ячеек текущей строки.
+func (r xlsxRows) Columns() ([]string, error) {
+	return r.Rows.Columns()
+}
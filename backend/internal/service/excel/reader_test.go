@@ -0,0 +1,237 @@
+package excel
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// buildZip собирает zip-архив из файлов в памяти.
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// readAllRows читает все строки листа.
+func readAllRows(t *testing.T, book spreadsheet, sheetName string) [][]string {
+	t.Helper()
+
+	rows, err := readSheetRows(book, sheetName)
+	require.NoError(t, err)
+	return rows
+}
+
+func TestFindInputFormat(t *testing.T) {
+	for fileName, want := range map[string]string{
+		"dealer_net_Q3_2025.xlsx": "xlsx",
+		"Central_2025_Q3.CSV":     "csv",
+		"dealer_net_Q3_2025.ods":  "ods",
+		"dealer_net_Q3_2025.zip":  "csv_archive",
+	} {
+		format, ok := findInputFormat(fileName)
+		if assert.True(t, ok, fileName) {
+			assert.Equal(t, want, format.Name)
+		}
+	}
+
+	assert.False(t, IsSupportedFile("dealer_net_Q3_2025.xls"))
+
+	_, err := openSpreadsheet(bytes.NewReader(nil), "dealer_net.pdf")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestCSVReader(t *testing.T) {
+	t.Run("windows-1251 with semicolons and a title row", func(t *testing.T) {
+		content, err := charmap.Windows1251.NewEncoder().String("Dealer Net Q3 2025;;\nДилер;Город;Брендинг;;\n\"Автоцентр; Север\";Москва;Да\n")
+		require.NoError(t, err)
+
+		book, err := openCSV(bytes.NewReader([]byte(content)), "Central_2025_Q3.csv")
+		require.NoError(t, err)
+		defer book.Close()
+
+		assert.Equal(t, []string{"Central"}, book.SheetList())
+
+		headerRow, err := book.HeaderRow("Central")
+		require.NoError(t, err)
+		assert.Equal(t, 2, headerRow)
+
+		assert.Equal(t, [][]string{
+			{"Dealer Net Q3 2025"},
+			{"Дилер", "Город", "Брендинг"},
+			{"Автоцентр; Север", "Москва", "Да"},
+		}, readAllRows(t, book, "Central"))
+	})
+
+	t.Run("UTF-8 export starts with the header", func(t *testing.T) {
+		book, err := openCSV(bytes.NewReader([]byte("\xef\xbb\xbfDealer,City\nAlfa,Kazan\n")), "Q3_2025-Volga.csv")
+		require.NoError(t, err)
+		defer book.Close()
+
+		assert.Equal(t, []string{"Volga"}, book.SheetList())
+
+		headerRow, err := book.HeaderRow("Volga")
+		require.NoError(t, err)
+		assert.Equal(t, 1, headerRow)
+		assert.Equal(t, [][]string{{"Dealer", "City"}, {"Alfa", "Kazan"}}, readAllRows(t, book, "Volga"))
+	})
+}
+
+func TestCSVArchiveReader(t *testing.T) {
+	t.Run("manifest sets period and regions", func(t *testing.T) {
+		data := buildZip(t, map[string]string{
+			"manifest.json": `{"year": 2025, "quarter": "q3", "files": [{"file": "nw.csv", "region": "North West"}, {"file": "c.csv", "region": "Central"}]}`,
+			"nw.csv":        "Dealer;City\nAlfa;Saint Petersburg\n",
+			"c.csv":         "Dealer;City\nBeta;Moscow\n",
+		})
+
+		book, err := openCSVArchive(bytes.NewReader(data), "regions.zip")
+		require.NoError(t, err)
+		defer book.Close()
+
+		year, quarter, ok := book.Period()
+		assert.True(t, ok)
+		assert.Equal(t, 2025, year)
+		assert.Equal(t, "Q3", quarter)
+		assert.Equal(t, []string{"North West", "Central"}, book.SheetList())
+		assert.Equal(t, [][]string{{"Dealer", "City"}, {"Beta", "Moscow"}}, readAllRows(t, book, "Central"))
+	})
+
+	t.Run("without manifest regions come from file names", func(t *testing.T) {
+		data := buildZip(t, map[string]string{
+			"export/Volga_2025_Q3.csv":   "Dealer,City\n",
+			"export/Central_2025_Q3.csv": "Dealer,City\n",
+			"__MACOSX/._Central.csv":     "",
+		})
+
+		book, err := openCSVArchive(bytes.NewReader(data), "dealer_net_2025_Q3.zip")
+		require.NoError(t, err)
+		defer book.Close()
+
+		_, _, ok := book.Period()
+		assert.False(t, ok)
+		assert.Equal(t, []string{"Central", "Volga"}, book.SheetList())
+	})
+
+	t.Run("files missing from manifest are rejected", func(t *testing.T) {
+		data := buildZip(t, map[string]string{
+			"manifest.json": `{"files": [{"file": "c.csv", "region": "Central"}]}`,
+			"c.csv":         "Dealer;City\n",
+			"extra.csv":     "Dealer;City\n",
+		})
+
+		_, err := openCSVArchive(bytes.NewReader(data), "regions.zip")
+		assert.ErrorContains(t, err, "extra.csv is not listed")
+	})
+}
+
+// odsContent content.xml книги OpenDocument с двухуровневым заголовком.
+const odsContent = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+ <office:body><office:spreadsheet>
+  <table:table table:name="Q3-Central">
+   <table:table-column table:number-columns-repeated="5"/>
+   <table:table-row><table:table-cell table:number-columns-spanned="5"><text:p>Dealer Net Q3 2025</text:p></table:table-cell><table:covered-table-cell table:number-columns-repeated="4"/></table:table-row>
+   <table:table-row>
+    <table:table-cell table:number-rows-spanned="2"><text:p>Dealer</text:p></table:table-cell>
+    <table:table-cell table:number-rows-spanned="2"><text:p>City</text:p></table:table-cell>
+    <table:table-cell table:number-columns-spanned="2"><text:p>Stock</text:p></table:table-cell>
+    <table:covered-table-cell/>
+    <table:table-cell table:number-rows-spanned="2"><text:p>Check list %</text:p></table:table-cell>
+   </table:table-row>
+   <table:table-row>
+    <table:covered-table-cell table:number-columns-repeated="2"/>
+    <table:table-cell><text:p>HDT</text:p></table:table-cell>
+    <table:table-cell><text:p>MDT</text:p></table:table-cell>
+    <table:covered-table-cell/>
+   </table:table-row>
+   <table:table-row>
+    <table:table-cell office:value-type="string"><text:p>Авто<text:s text:c="2"/>Центр</text:p><office:annotation><text:p>note</text:p></office:annotation></table:table-cell>
+    <table:table-cell><text:p>Москва</text:p></table:table-cell>
+    <table:table-cell office:value-type="float" office:value="1250"><text:p>1 250</text:p></table:table-cell>
+    <table:table-cell office:value-type="float" office:value="3"/>
+    <table:table-cell office:value-type="percentage" office:value="0.85"><text:p>85 %</text:p></table:table-cell>
+    <table:table-cell table:number-columns-repeated="16379"/>
+   </table:table-row>
+   <table:table-row table:number-rows-repeated="2"><table:table-cell><text:p>Beta</text:p></table:table-cell><table:table-cell/><table:table-cell><text:p>7</text:p></table:table-cell></table:table-row>
+   <table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="16384"/></table:table-row>
+  </table:table>
+  <table:table table:name="Notes"><table:table-row><table:table-cell><text:p>n/a</text:p></table:table-cell></table:table-row></table:table>
+ </office:spreadsheet></office:body>
+</office:document-content>`
+
+func TestODSReader(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"mimetype":    "application/vnd.oasis.opendocument.spreadsheet",
+		"content.xml": odsContent,
+	})
+
+	book, err := openODS(bytes.NewReader(data), "dealer_net_Q3_2025.ods")
+	require.NoError(t, err)
+	defer book.Close()
+
+	assert.Equal(t, []string{"Q3-Central", "Notes"}, book.SheetList())
+
+	rows := readAllRows(t, book, "Q3-Central")
+	require.Greater(t, len(rows), 6)
+	assert.Equal(t, []string{"Dealer Net Q3 2025"}, rows[0])
+	assert.Equal(t, []string{"Dealer", "City", "Stock", "", "Check list %"}, rows[1])
+	assert.Equal(t, []string{"", "", "HDT", "MDT"}, rows[2])
+	assert.Equal(t, []string{"Авто  Центр", "Москва", "1 250", "3", "85 %"}, rows[3])
+	assert.Equal(t, []string{"Beta", "", "7"}, rows[4])
+	assert.Equal(t, []string{"Beta", "", "7"}, rows[5])
+	assert.Empty(t, rows[6])
+
+	service := NewService(nil, nil, nil, Options{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	merges, headerRows, err := service.sheetHeaderLayout(book, "Q3-Central", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, headerRows)
+
+	mapping := service.mapHeaderColumns(headerColumns(rows[1:3], merges, 2))
+	assert.Equal(t, []string{"dealer", "city", "stock_hdt", "stock_mdt", "check_list_percent"}, mapping.Columns)
+}
+
+func TestProcessCSVArchive(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := benchRepository{DynamicTableRepository: repository.NewDynamicTableRepository(nil, logger)}
+	service := NewService(repo, benchCatalog{}, nil, Options{}, logger)
+
+	data := buildZip(t, map[string]string{
+		"manifest.json": `{"year": 2025, "quarter": "Q2", "files": [{"file": "central.csv", "region": "Central"}, {"file": "volga.csv", "region": "Volga"}]}`,
+		"central.csv":   "Дилер;Город;Сток HDT;Брендинг\nАльфа;Москва;1 250;Да\nБета;Тула;n/a;Нет\n",
+		"volga.csv":     "Dealer,City,Stock HDT\nGamma,Kazan,12\n",
+	})
+
+	result, err := service.ProcessExcelFile(context.Background(), bytes.NewReader(data), "regions.zip", "")
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Errors)
+
+	assert.Equal(t, 3, result.TotalRows)
+	if assert.Len(t, result.TablesCreated, 1) {
+		assert.Equal(t, 2025, result.TablesCreated[0].Year)
+		assert.Equal(t, "Q2", result.TablesCreated[0].Quarter)
+	}
+	if assert.Len(t, result.ValueIssues, 1) {
+		assert.Equal(t, "C3", result.ValueIssues[0].Cell)
+		assert.Equal(t, "n/a", result.ValueIssues[0].Value)
+	}
+}
@@ -2,9 +2,12 @@ package excel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -16,40 +19,138 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
+const (
+	// importBatchSize количество строк, накапливаемых перед записью в БД.
+	importBatchSize = 1000
+
+	// unzipXMLSizeLimit размер распакованного листа, сверх которого он читается из временного файла.
+	unzipXMLSizeLimit = 4 << 20
+)
+
+// dealerNetImport состояние потоковой загрузки файла dealer_net в dealer_metrics.
+type dealerNetImport struct {
+	tx             pgx.Tx
+	year           int
+	quarter        string
+	columns        []string
+	periodPrepared bool
+	inserted       int
+	progress       repository.InsertProgressFunc
+	columnReports  []model.ExcelColumnReport
+	formulaReports []model.ExcelFormulaReport
+	formulaErrors  []model.ExcelError
+	valueIssues    []model.ExcelValueIssue
+}
+
+// Promoter переносит загрузку dealer_net за квартал в нормализованные таблицы.
+type Promoter interface {
+	Promote(ctx context.Context, tx pgx.Tx, year int, quarter string) (*model.PromotionResult, error)
+}
+
+// Options настройки загрузки Excel файлов.
+type Options struct {
+	TrashRetention time.Duration // Сколько удаленные кварталы хранятся в корзине до окончательного удаления
+	HeaderRows     int           // Количество строк заголовка листа; 0 - определяется по объединенным ячейкам
+}
+
 // Service сервис для работы с Excel файлами.
 type Service struct {
-	dynamicRepo repository.DynamicTableRepository
-	logger      *slog.Logger
+	dynamicRepo    repository.DynamicTableRepository
+	catalog        Catalog
+	promoter       Promoter
+	trashRetention time.Duration
+	headerRows     int
+	logger         *slog.Logger
 }
 
 // NewService создает новый экземпляр сервиса Excel.
-func NewService(dynamicRepo repository.DynamicTableRepository, logger *slog.Logger) *Service {
+// Если promoter равен nil, загрузка только наполняет staging-таблицу dealer_metrics.
+func NewService(dynamicRepo repository.DynamicTableRepository, catalog Catalog, promoter Promoter, opts Options, logger *slog.Logger) *Service {
 	return &Service{
-		dynamicRepo: dynamicRepo,
-		logger:      logger,
+		dynamicRepo:    dynamicRepo,
+		catalog:        catalog,
+		promoter:       promoter,
+		trashRetention: opts.TrashRetention,
+		headerRows:     opts.HeaderRows,
+		logger:         logger,
 	}
 }
 
 // ProcessExcelFile обрабатывает Excel файл и создает единую таблицу в БД.
-func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName string) (*model.ExcelProcessingResult, error) {
+// importedBy - логин администратора, который записывается в каталог загрузок.
+func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName string, importedBy string) (*model.ExcelProcessingResult, error) {
+	return s.ProcessExcelFileWithProgress(ctx, file, fileName, importedBy, s.logInsertProgress(fileName))
+}
+
+// ProcessExcelFileWithProgress обрабатывает Excel файл, сообщая о прогрессе вставки строк.
+// Каждая загрузка, в том числе отклоненная, записывается в каталог загрузок.
+func (s *Service) ProcessExcelFileWithProgress(ctx context.Context, file io.Reader, fileName string, importedBy string, progress repository.InsertProgressFunc) (*model.ExcelProcessingResult, error) {
+	// Парсим метаданные из названия файла
+	fileInfo, err := s.parseFileName(fileName)
+	if err != nil {
+		s.logger.Error("Failed to parse file name",
+			slog.String("file_name", fileName),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to parse file name: %w", err)
+	}
+
+	dataset := &model.ImportDataset{
+		Kind:       model.ImportKindDealerNet,
+		Year:       fileInfo.Year,
+		Quarter:    fileInfo.Quarter,
+		SourceFile: fileName,
+		ImportedBy: importedBy,
+	}
+
+	// Открываем файл ридером его формата: XLSX, ODS или CSV
+	book, err := openSpreadsheet(file, fileName)
+	if err != nil {
+		dataset.Error = err.Error()
+		s.recordFailedImport(ctx, dataset)
+		return nil, err
+	}
+	defer book.Close()
+
+	// Период из манифеста архива CSV важнее имени файла
+	if year, quarter, ok := book.Period(); ok {
+		fileInfo.Year, fileInfo.Quarter = year, quarter
+		fileInfo.TableName = model.DealerNetPeriodName(year, quarter)
+		dataset.Year, dataset.Quarter = year, quarter
+	}
+
+	result, err := s.importDealerNet(ctx, book, fileInfo, dataset, progress)
+	if err != nil {
+		dataset.Error = err.Error()
+		s.recordFailedImport(ctx, dataset)
+		return nil, err
+	}
+
+	if !result.Success {
+		dataset.Error = sheetErrorsSummary(result.Errors)
+		dataset.Schema.ColumnReports = result.ColumnReports
+		dataset.Schema.ValueIssues = result.ValueIssues
+		s.recordFailedImport(ctx, dataset)
+	}
+
+	result.DatasetID = dataset.ID
+	return result, nil
+}
+
+// importDealerNet загружает файл dealer_net в dealer_metrics и записывает успешную загрузку в каталог.
+func (s *Service) importDealerNet(ctx context.Context, book spreadsheet, fileInfo *model.ExcelFileInfo, dataset *model.ImportDataset, progress repository.InsertProgressFunc) (*model.ExcelProcessingResult, error) {
 	startTime := time.Now()
+	fileName := fileInfo.FileName
 
 	s.logger.Info("Starting Excel file processing",
 		slog.String("file_name", fileName),
 		slog.Time("start_time", startTime),
 	)
 
-	// Читаем Excel файл
-	f, err := excelize.OpenReader(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open Excel file: %w", err)
-	}
-	defer f.Close()
-
 	// Получаем список листов
-	sheetList := f.GetSheetList()
+	sheetList := book.SheetList()
 	if len(sheetList) == 0 {
-		return nil, fmt.Errorf("Excel file contains no sheets")
+		return nil, fmt.Errorf("file contains no sheets")
 	}
 
 	s.logger.Info("Found sheets in Excel file",
@@ -65,16 +166,6 @@ func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName
 	}
 	defer tx.Rollback(ctx)
 
-	// Парсим метаданные из названия файла
-	fileInfo, err := s.parseFileName(fileName)
-	if err != nil {
-		s.logger.Error("Failed to parse file name",
-			slog.String("file_name", fileName),
-			slog.String("error", err.Error()),
-		)
-		return nil, fmt.Errorf("failed to parse file name: %w", err)
-	}
-
 	s.logger.Info("File info parsed",
 		slog.String("file_name", fileName),
 		slog.String("table_name", fileInfo.TableName),
@@ -82,9 +173,13 @@ func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName
 		slog.Int("year", fileInfo.Year),
 	)
 
-	// Обрабатываем все листы и собираем данные
-	var allData []model.ExcelRowWithRegion
-	var commonColumns []string
+	// Читаем листы построчно и сразу пишем данные в dealer_metrics
+	imp := &dealerNetImport{
+		tx:       tx,
+		year:     fileInfo.Year,
+		quarter:  fileInfo.Quarter,
+		progress: progress,
+	}
 	var errors []model.ExcelError
 
 	for _, sheetName := range sheetList {
@@ -108,7 +203,7 @@ func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName
 		}
 
 		// Обрабатываем лист
-		sheetData, columns, err := s.processSheetForUnifiedTable(ctx, tx, f, sheetName, region)
+		sheetRows, err := s.processSheetForUnifiedTable(ctx, imp, book, sheetName, region)
 		if err != nil {
 			s.logger.Error("Failed to process sheet",
 				slog.String("sheet_name", sheetName),
@@ -125,20 +220,9 @@ func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName
 		s.logger.Info("Sheet processed successfully",
 			slog.String("sheet_name", sheetName),
 			slog.String("region", region),
-			slog.Int("rows", len(sheetData)),
-			slog.Int("columns", len(columns)),
+			slog.Int("rows", sheetRows),
+			slog.Int("columns", len(imp.columns)),
 		)
-
-		// Проверяем структуру колонок
-		if len(commonColumns) == 0 {
-			commonColumns = columns
-		} else {
-			if !s.compareColumnStructures(commonColumns, columns) {
-				return nil, fmt.Errorf("sheet %s has different column structure", sheetName)
-			}
-		}
-
-		allData = append(allData, sheetData...)
 	}
 
 	// Если есть ошибки, откатываем транзакцию
@@ -150,21 +234,34 @@ func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName
 			Success:        false,
 			TablesCreated:  []model.ExcelTableMetadata{},
 			Errors:         errors,
+			ColumnReports:  imp.columnReports,
+			ValueIssues:    imp.valueIssues,
 			TotalRows:      0,
 			ProcessingTime: time.Since(startTime),
 		}, nil
 	}
 
-	// Создаем единую таблицу dealer_net
-	err = s.dynamicRepo.CreateDealerNetTable(ctx, tx, fileInfo.Year, fileInfo.Quarter, commonColumns)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create dealer_net table: %w", err)
+	// Заменяем загрузку квартала, даже если ни на одном листе не нашлось заголовков
+	if !imp.periodPrepared {
+		if err := s.dynamicRepo.PrepareDealerMetricsPeriod(ctx, tx, imp.year, imp.quarter); err != nil {
+			return nil, fmt.Errorf("failed to prepare dealer_metrics period: %w", err)
+		}
 	}
 
-	// Вставляем все данные в таблицу dealer_net
-	err = s.insertDealerNetData(ctx, tx, fileInfo.Year, fileInfo.Quarter, commonColumns, allData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to insert dealer_net data: %w", err)
+	// Переносим загруженные строки в нормализованные таблицы в той же транзакции
+	var promotion *model.PromotionResult
+	if imp.inserted > 0 {
+		promotion, err = s.promote(ctx, tx, fileInfo.Year, fileInfo.Quarter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Запись в каталоге фиксируется вместе с данными
+	dataset.RowCount = imp.inserted
+	dataset.Schema = model.ImportSchema{Columns: imp.columns, ColumnReports: imp.columnReports, FormulaReports: imp.formulaReports, ValueIssues: imp.valueIssues}
+	if err := s.catalog.RecordImport(ctx, tx, dataset); err != nil {
+		return nil, fmt.Errorf("failed to record import: %w", err)
 	}
 
 	// Коммитим транзакцию
@@ -173,12 +270,12 @@ func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName
 	}
 
 	processingTime := time.Since(startTime)
-	tableName := s.dynamicRepo.GetDealerNetTableName(fileInfo.Year, fileInfo.Quarter)
+	tableName := model.DealerNetPeriodName(fileInfo.Year, fileInfo.Quarter)
 
 	s.logger.Info("Excel file processing completed successfully",
 		slog.String("file_name", fileName),
 		slog.String("table_name", tableName),
-		slog.Int("total_rows", len(allData)),
+		slog.Int("total_rows", imp.inserted),
 		slog.Duration("processing_time", processingTime),
 	)
 
@@ -187,90 +284,48 @@ func (s *Service) ProcessExcelFile(ctx context.Context, file io.Reader, fileName
 		TablesCreated: []model.ExcelTableMetadata{
 			{
 				TableName: tableName,
-				RowsCount: len(allData),
+				Year:      fileInfo.Year,
+				Quarter:   fileInfo.Quarter,
+				RowsCount: imp.inserted,
 				CreatedAt: time.Now(),
-				Columns:   commonColumns,
+				Columns:   imp.columns,
 			},
 		},
 		Errors:         []model.ExcelError{},
-		TotalRows:      len(allData),
+		ColumnReports:  imp.columnReports,
+		FormulaReports: imp.formulaReports,
+		FormulaErrors:  imp.formulaErrors,
+		ValueIssues:    imp.valueIssues,
+		TotalRows:      imp.inserted,
 		ProcessingTime: processingTime,
+		Promotion:      promotion,
 	}, nil
 }
 
-// processSheet обрабатывает отдельный лист Excel.
-func (s *Service) processSheet(ctx context.Context, tx pgx.Tx, f *excelize.File, sheetName string) (*model.ExcelTableMetadata, error) {
-	// Парсим метаданные из названия листа
-	sheetInfo, err := s.parseSheetName(sheetName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse sheet name: %w", err)
-	}
-
-	// Читаем данные листа
-	rows, err := f.GetRows(sheetName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get rows from sheet: %w", err)
-	}
-
-	if len(rows) == 0 {
-		s.logger.Warn("Sheet is empty", slog.String("sheet_name", sheetName))
+// promote переносит загрузку dealer_net в нормализованные таблицы, если перенос подключен.
+func (s *Service) promote(ctx context.Context, tx pgx.Tx, year int, quarter string) (*model.PromotionResult, error) {
+	if s.promoter == nil {
 		return nil, nil
 	}
 
-	// Первая строка - заголовки
-	headers := rows[0]
-	if len(headers) == 0 {
-		return nil, fmt.Errorf("sheet has no headers")
+	promotion, err := s.promoter.Promote(ctx, tx, year, quarter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to promote dealer_net: %w", err)
 	}
 
-	// Санитизируем заголовки
-	sanitizedHeaders := s.sanitizeHeaders(headers)
+	return promotion, nil
+}
 
-	// Создаем схему таблицы
-	schema := &model.DynamicTableSchema{
-		TableName: sheetInfo.TableName,
-		Columns:   make([]model.DynamicTableColumn, len(sanitizedHeaders)),
-	}
+// openWorkbook открывает книгу с ограничением памяти на распакованные листы.
+func openWorkbook(file io.Reader) (*excelize.File, error) {
+	opts := excelize.Options{UnzipXMLSizeLimit: unzipXMLSizeLimit}
 
-	for i, header := range sanitizedHeaders {
-		schema.Columns[i] = model.DynamicTableColumn{
-			Name:     header,
-			DataType: "TEXT",
-			Nullable: true,
-		}
-	}
-
-	// Создаем таблицу
-	createQuery := s.buildCreateTableQuery(schema.TableName, schema.Columns)
-	_, err = tx.Exec(ctx, createQuery)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
-	}
-
-	// Подготавливаем данные для вставки
-	dataRows := rows[1:] // Пропускаем заголовки
-	if len(dataRows) == 0 {
-		s.logger.Info("No data rows to insert", slog.String("sheet_name", sheetName))
-		return &model.ExcelTableMetadata{
-			TableName: schema.TableName,
-			RowsCount: 0,
-			CreatedAt: time.Now(),
-			Columns:   sanitizedHeaders,
-		}, nil
+	// Файл на диске открываем по пути, не копируя его через io.Reader
+	if osFile, ok := file.(*os.File); ok {
+		return excelize.OpenFile(osFile.Name(), opts)
 	}
 
-	// Вставляем данные
-	err = s.insertSheetData(ctx, tx, schema.TableName, sanitizedHeaders, dataRows)
-	if err != nil {
-		return nil, fmt.Errorf("failed to insert data: %w", err)
-	}
-
-	return &model.ExcelTableMetadata{
-		TableName: schema.TableName,
-		RowsCount: len(dataRows),
-		CreatedAt: time.Now(),
-		Columns:   sanitizedHeaders,
-	}, nil
+	return excelize.OpenReader(file, opts)
 }
 
 // parseSheetName парсит название листа и извлекает метаданные.
@@ -308,6 +363,13 @@ func (s *Service) extractQuarterAndYear(sheetName string) (string, int) {
 		return quarter, year
 	}
 
+	// Год перед кварталом: Central_2025_Q3.csv
+	yearQuarterRegex := regexp.MustCompile(`(?i)(\d{4})[_\-\s]*q([1-4])`)
+	if matches := yearQuarterRegex.FindStringSubmatch(sheetName); len(matches) >= 3 {
+		year, _ := strconv.Atoi(matches[1])
+		return "Q" + matches[2], year
+	}
+
 	// Если год не найден, используем текущий год
 	currentYear := time.Now().Year()
 
@@ -413,80 +475,6 @@ func (s *Service) sanitizeHeaders(headers []string) []string {
 	return sanitized
 }
 
-// insertSheetData вставляет данные листа в таблицу.
-func (s *Service) insertSheetData(ctx context.Context, tx pgx.Tx, tableName string, columns []string, rows [][]string) error {
-	if len(rows) == 0 {
-		return nil
-	}
-
-	// Строим SQL запрос
-	query := s.buildInsertQuery(tableName, columns, len(rows))
-
-	// Подготавливаем аргументы
-	args := make([]interface{}, 0, len(rows)*len(columns))
-	for _, row := range rows {
-		for i := range columns {
-			var value interface{}
-			if i < len(row) && row[i] != "" {
-				value = row[i]
-			} else {
-				value = nil
-			}
-			args = append(args, value)
-		}
-	}
-
-	// Выполняем запрос
-	_, err := tx.Exec(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("failed to insert data: %w", err)
-	}
-
-	return nil
-}
-
-// buildCreateTableQuery строит SQL запрос для создания таблицы.
-func (s *Service) buildCreateTableQuery(tableName string, columns []model.DynamicTableColumn) string {
-	var columnDefs []string
-
-	// Добавляем стандартные колонки
-	columnDefs = append(columnDefs, "id BIGSERIAL PRIMARY KEY")
-
-	// Добавляем колонки из схемы
-	for _, col := range columns {
-		columnDefs = append(columnDefs, fmt.Sprintf("%s TEXT", col.Name))
-	}
-
-	// Добавляем колонку created_at
-	columnDefs = append(columnDefs, "created_at TIMESTAMP DEFAULT NOW()")
-
-	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)",
-		tableName,
-		strings.Join(columnDefs, ",\n    "))
-
-	return query
-}
-
-// buildInsertQuery строит SQL запрос для вставки данных.
-func (s *Service) buildInsertQuery(tableName string, columns []string, rowCount int) string {
-	// Создаем плейсхолдеры для значений
-	var placeholders []string
-	for i := 0; i < rowCount; i++ {
-		var rowPlaceholders []string
-		for j := 0; j < len(columns); j++ {
-			rowPlaceholders = append(rowPlaceholders, fmt.Sprintf("$%d", i*len(columns)+j+1))
-		}
-		placeholders = append(placeholders, fmt.Sprintf("(%s)", strings.Join(rowPlaceholders, ", ")))
-	}
-
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
-
-	return query
-}
-
 // getCurrentQuarter возвращает текущий квартал.
 func (s *Service) getCurrentQuarter() string {
 	month := int(time.Now().Month())
@@ -502,376 +490,302 @@ func (s *Service) getCurrentQuarter() string {
 	}
 }
 
-// GetDynamicTables возвращает список динамически созданных таблиц.
-func (s *Service) GetDynamicTables(ctx context.Context) ([]*model.ExcelTableMetadata, error) {
-	return s.dynamicRepo.ListDynamicTables(ctx)
-}
-
-// GetTableMetadata возвращает метаданные таблицы.
-func (s *Service) GetTableMetadata(ctx context.Context, tableName string) (*model.ExcelTableMetadata, error) {
-	return s.dynamicRepo.GetTableMetadata(ctx, tableName)
-}
-
 // parseFileName парсит название файла и извлекает метаданные.
 func (s *Service) parseFileName(fileName string) (*model.ExcelFileInfo, error) {
 	// Убираем расширение
-	name := strings.TrimSuffix(fileName, ".xlsx")
-	name = strings.TrimSuffix(name, ".XLSX")
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
 	// Извлекаем квартал и год
 	quarter, year := s.extractQuarterAndYear(name)
 
-	// Генерируем название таблицы
-	tableName := fmt.Sprintf("dealer_net_%d_%s", year, strings.ToLower(quarter))
-
 	return &model.ExcelFileInfo{
 		FileName:  fileName,
 		Quarter:   quarter,
 		Year:      year,
-		TableName: tableName,
+		TableName: model.DealerNetPeriodName(year, quarter),
 	}, nil
 }
 
-// extractRegionFromSheetName извлекает регион из названия листа.
-func (s *Service) extractRegionFromSheetName(sheetName string) (string, error) {
-	// Формат: Q3-Central, Q3-NW, Q3-Volga, etc.
-	parts := strings.Split(sheetName, "-")
-	if len(parts) != 2 {
-		return "", fmt.Errorf("invalid sheet name format: %s", sheetName)
-	}
-
-	region := strings.TrimSpace(parts[1])
+// sheetQuarterPrefix префикс квартала в названии листа (Q3-, Q3_, Q3 ).
+var sheetQuarterPrefix = regexp.MustCompile(`(?i)^\s*q[1-4]\s*[-_ ]\s*`)
 
-	// Маппинг сокращений
-	regionMap := map[string]string{
-		"NW":      "North West",
-		"Central": "Central",
-		"Volga":   "Volga",
-		"South":   "South",
-		"Ural":    "Ural",
-		"Siberia": "Siberia",
-		"FE":      "Far East",
-	}
+// extractRegionFromSheetName извлекает регион из названия листа по справочнику регионов.
+func (s *Service) extractRegionFromSheetName(sheetName string) (string, error) {
+	// Формат: Q3-Central, Q3-NW, Q3-Северо-Запад и т.д.
+	name := sheetQuarterPrefix.ReplaceAllString(sheetName, "")
 
-	if mappedRegion, exists := regionMap[region]; exists {
-		return mappedRegion, nil
+	region, ok := model.Regions().Resolve(name)
+	if !ok {
+		return "", fmt.Errorf("unknown region in sheet name: %s", sheetName)
 	}
 
-	return region, nil
+	return region.Name, nil
 }
 
-// processSheetForUnifiedTable обрабатывает лист для объединенной таблицы.
-func (s *Service) processSheetForUnifiedTable(ctx context.Context, tx pgx.Tx, f *excelize.File, sheetName string, region string) ([]model.ExcelRowWithRegion, []string, error) {
+// processSheetForUnifiedTable построчно читает лист и пачками записывает строки в dealer_metrics.
+// Формулы без сохраненного значения вычисляются, остальные ячейки берутся из файла как есть.
+func (s *Service) processSheetForUnifiedTable(ctx context.Context, imp *dealerNetImport, book spreadsheet, sheetName string, region string) (int, error) {
 	s.logger.Info("Processing sheet for unified table",
 		slog.String("sheet_name", sheetName),
 		slog.String("region", region),
 	)
 
-	// Читаем данные листа
-	rows, err := f.GetRows(sheetName)
+	// В шаблоне строка 1 - заголовок листа, заголовки колонок начинаются со строки 2
+	headerRow, err := book.HeaderRow(sheetName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get rows from sheet: %w", err)
+		return 0, err
 	}
 
-	s.logger.Info("Sheet rows read",
-		slog.String("sheet_name", sheetName),
-		slog.Int("total_rows", len(rows)),
-	)
-
-	if len(rows) < 3 {
-		s.logger.Warn("Sheet has less than 3 rows", slog.String("sheet_name", sheetName))
-		return []model.ExcelRowWithRegion{}, []string{}, nil
+	merges, headerRows, err := s.sheetHeaderLayout(book, sheetName, headerRow)
+	if err != nil {
+		return 0, err
 	}
+	dataRow := headerRow + headerRows
 
-	// Строка 2 (индекс 1) - заголовки колонок
-	headers := rows[1]
-	s.logger.Info("Headers row",
-		slog.String("sheet_name", sheetName),
-		slog.Int("headers_count", len(headers)),
-		slog.String("headers", strings.Join(headers, "|")),
-	)
-
-	if len(headers) == 0 {
-		return nil, nil, fmt.Errorf("sheet has no headers in row 2")
+	formulas, err := book.Formulas(sheetName)
+	if err != nil {
+		return 0, err
 	}
 
-	// Санитизируем заголовки и фильтруем пустые
-	sanitizedHeaders := s.sanitizeAndFilterHeaders(headers)
-
-	// Данные начинаются с строки 3 (индекс 2)
-	dataRows := rows[2:]
-	s.logger.Info("Data rows",
-		slog.String("sheet_name", sheetName),
-		slog.Int("data_rows_count", len(dataRows)),
+	rows, err := book.Rows(sheetName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows from sheet: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		mapping     *headerMapping
+		header      [][]string
+		dealerIndex = -1
+		regionIndex = -1
+		rowNumber   int
+		processed   int
+		batch       = make([][]interface{}, 0, importBatchSize)
 	)
 
-	var result []model.ExcelRowWithRegion
-	for rowIndex, row := range dataRows {
-		if len(row) == 0 {
-			continue // Пропускаем пустые строки
-		}
+	for rows.Next() {
+		rowNumber++
 
-		values := make(map[string]interface{})
-		for i, header := range sanitizedHeaders {
-			var value interface{}
-			if i < len(row) && row[i] != "" {
-				value = row[i]
-			} else {
-				value = nil
-			}
-			values[header] = value
+		row, err := rows.Columns()
+		if err != nil {
+			return processed, fmt.Errorf("failed to read row %d: %w", rowNumber, err)
 		}
 
-		// Перезаписываем значение region из названия листа
-		values["region"] = region
-
-		// Проверяем, что дилер не пустой - если пустой, пропускаем запись
-		dealerValue, exists := values["dealer"]
-		if !exists || dealerValue == nil || dealerValue == "" {
-			s.logger.Warn("Skipping row with empty dealer",
-				slog.String("sheet_name", sheetName),
-				slog.Int("row_index", rowIndex),
-			)
+		if rowNumber < headerRow {
 			continue
 		}
 
-		result = append(result, model.ExcelRowWithRegion{
-			Region: region,
-			Values: values,
-		})
+		// Строки заголовка: группа ("Stock") над подзаголовками (HDT/MDT/LDT)
+		if rowNumber < dataRow {
+			header = append(header, row)
+			if rowNumber < dataRow-1 {
+				continue
+			}
 
-		// Логируем первые несколько строк для отладки
-		if rowIndex < 3 {
-			s.logger.Info("Sample data row",
+			columns := headerColumns(header, merges, headerRow)
+			if len(columns) == 0 {
+				return 0, fmt.Errorf("sheet has no headers in row %d", headerRow)
+			}
+
+			s.logger.Info("Headers rows",
 				slog.String("sheet_name", sheetName),
-				slog.Int("row_index", rowIndex),
-				slog.String("row_data", strings.Join(row, "|")),
+				slog.Int("header_rows", headerRows),
+				slog.Int("headers_count", len(columns)),
 			)
-		}
-	}
 
-	s.logger.Info("Sheet processing completed",
-		slog.String("sheet_name", sheetName),
-		slog.Int("processed_rows", len(result)),
-		slog.Int("columns_count", len(sanitizedHeaders)),
-	)
+			// Сопоставляем заголовки со словарем по названию
+			mapping = s.mapHeaderColumns(columns)
+			if len(mapping.Unmapped) > 0 || len(mapping.Missing) > 0 {
+				imp.columnReports = append(imp.columnReports, model.ExcelColumnReport{
+					SheetName: sheetName,
+					Unmapped:  mapping.Unmapped,
+					Missing:   mapping.Missing,
+				})
+			}
+			if len(mapping.Missing) > 0 {
+				return 0, fmt.Errorf("missing required columns: %s", strings.Join(mapping.Missing, ", "))
+			}
 
-	return result, sanitizedHeaders, nil
-}
+			for i, column := range mapping.Columns {
+				switch column {
+				case "dealer":
+					dealerIndex = i
+				case "region":
+					regionIndex = i
+				}
+			}
 
-// sanitizeAndFilterHeaders санитизирует заголовки и фильтрует пустые.
-func (s *Service) sanitizeAndFilterHeaders(headers []string) []string {
-	var sanitized []string
-	usedNames := make(map[string]int)
+			// Регион известен из названия листа, поэтому колонка region есть всегда
+			if regionIndex < 0 {
+				mapping.Columns = append(mapping.Columns, "region")
+				mapping.Indexes = append(mapping.Indexes, -1)
+				mapping.Kinds = append(mapping.Kinds, model.ValueKindText)
+				regionIndex = len(mapping.Columns) - 1
+			}
 
-	for i, header := range headers {
-		// Пропускаем пустые заголовки
-		if strings.TrimSpace(header) == "" {
+			if err := s.prepareDealerMetrics(ctx, imp, mapping.Columns); err != nil {
+				return 0, err
+			}
 			continue
 		}
 
-		// Санитизируем название
-		name := strings.ToLower(header)
-		name = strings.ReplaceAll(name, " ", "_")
-		name = strings.ReplaceAll(name, "-", "_")
-		name = strings.ReplaceAll(name, ".", "_")
-		name = strings.ReplaceAll(name, "/", "_")
-		name = strings.ReplaceAll(name, "\\", "_")
-		name = strings.ReplaceAll(name, "(", "_")
-		name = strings.ReplaceAll(name, ")", "_")
-		name = strings.ReplaceAll(name, "%", "_percent")
+		// Данные начинаются после строк заголовка
+		if len(row) == 0 {
+			continue // Пропускаем пустые строки
+		}
 
-		// Убираем множественные underscore
-		for strings.Contains(name, "__") {
-			name = strings.ReplaceAll(name, "__", "_")
+		values := make([]interface{}, len(mapping.Columns))
+		for i, index := range mapping.Indexes {
+			if index < 0 {
+				continue
+			}
+			var cached string
+			if index < len(row) {
+				cached = row[index]
+			}
+			if value := formulas.value(mapping.Columns[i], index+1, rowNumber, cached); value != "" {
+				values[i] = imp.normalize(sheetName, mapping.Columns[i], mapping.Kinds[i], index+1, rowNumber, value)
+			}
 		}
 
-		// Убираем начальные и конечные underscore
-		name = strings.Trim(name, "_")
+		// Перезаписываем значение region из названия листа
+		values[regionIndex] = region
 
-		// Если название пустое или начинается с цифры
-		if name == "" || (len(name) > 0 && name[0] >= '0' && name[0] <= '9') {
-			name = fmt.Sprintf("column_%d", i+1)
+		// Проверяем, что дилер не пустой - если пустой, пропускаем запись
+		if values[dealerIndex] == nil {
+			s.logger.Warn("Skipping row with empty dealer",
+				slog.String("sheet_name", sheetName),
+				slog.Int("row_number", rowNumber),
+			)
+			continue
 		}
 
-		// Обрабатываем дубликаты
-		if count, exists := usedNames[name]; exists {
-			usedNames[name] = count + 1
-			name = fmt.Sprintf("%s_%d", name, count+1)
-		} else {
-			usedNames[name] = 1
-		}
+		batch = append(batch, values)
+		processed++
 
-		// Ограничиваем длину
-		if len(name) > 63 {
-			name = name[:63]
+		if len(batch) == importBatchSize {
+			if err := s.flushDealerNetBatch(ctx, imp, mapping.Columns, batch); err != nil {
+				return processed, err
+			}
+			batch = batch[:0]
 		}
-
-		sanitized = append(sanitized, name)
 	}
 
-	s.logger.Info("Headers sanitized",
-		slog.Int("original_count", len(headers)),
-		slog.Int("sanitized_count", len(sanitized)),
-		slog.String("sanitized_headers", strings.Join(sanitized, ", ")),
-	)
-
-	return sanitized
-}
+	if err := rows.Error(); err != nil {
+		return processed, fmt.Errorf("failed to iterate rows: %w", err)
+	}
 
-// compareColumnStructures сравнивает структуры колонок.
-func (s *Service) compareColumnStructures(cols1, cols2 []string) bool {
-	if len(cols1) != len(cols2) {
-		return false
+	if mapping == nil || rowNumber < dataRow {
+		s.logger.Warn("Sheet has no data rows", slog.String("sheet_name", sheetName))
+		return 0, nil
 	}
 
-	for i, col1 := range cols1 {
-		if col1 != cols2[i] {
-			return false
-		}
+	if err := s.flushDealerNetBatch(ctx, imp, mapping.Columns, batch); err != nil {
+		return processed, err
 	}
 
-	return true
+	formulaReports, formulaErrors := formulas.results()
+	imp.formulaReports = append(imp.formulaReports, formulaReports...)
+	imp.formulaErrors = append(imp.formulaErrors, formulaErrors...)
+
+	s.logger.Info("Sheet processing completed",
+		slog.String("sheet_name", sheetName),
+		slog.Int("processed_rows", processed),
+		slog.Int("columns_count", len(mapping.Columns)),
+	)
+
+	return processed, nil
 }
 
-// insertDealerNetData вставляет данные в таблицу dealer_net.
-func (s *Service) insertDealerNetData(ctx context.Context, tx pgx.Tx, year int, quarter string, columns []string, data []model.ExcelRowWithRegion) error {
-	if len(data) == 0 {
-		s.logger.Info("No data to insert",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-		)
-		return nil
+// ambiguousNumberReason пояснение к числу, запятую в котором можно прочитать и как разряды, и как десятичную.
+const ambiguousNumberReason = "ambiguous thousands separator: write 12 500 for a whole number or 12.5 for a fraction"
+
+// normalize приводит значение ячейки к виду для хранения по типу колонки.
+// Нераспознанное значение сохраняется как есть и попадает в отчет загрузки.
+func (imp *dealerNetImport) normalize(sheetName, column string, kind model.ValueKind, col, row int, value string) string {
+	if kind == model.ValueKindText {
+		return value
 	}
 
-	// Преобразуем данные в формат для репозитория
-	rows := make([][]interface{}, len(data))
-	for i, row := range data {
-		rowValues := make([]interface{}, len(columns))
-		for j, col := range columns {
-			value, exists := row.Values[col]
-			if !exists || value == "" {
-				rowValues[j] = nil
-			} else {
-				rowValues[j] = value
-			}
+	normalized, err := model.NormalizeValue(kind, value)
+	if err != nil {
+		cell, _ := excelize.CoordinatesToCellName(col, row)
+		issue := model.ExcelValueIssue{
+			SheetName: sheetName,
+			Cell:      cell,
+			Column:    column,
+			Kind:      kind,
+			Value:     value,
+		}
+		if errors.Is(err, model.ErrAmbiguousNumber) {
+			issue.Reason = ambiguousNumberReason
 		}
-		rows[i] = rowValues
+		imp.valueIssues = append(imp.valueIssues, issue)
+		return value
 	}
 
-	// Используем репозиторий для вставки данных
-	return s.dynamicRepo.InsertDealerNetData(ctx, tx, year, quarter, columns, rows)
+	return normalized
 }
 
-// createUnifiedTable создает единую таблицу.
-func (s *Service) createUnifiedTable(ctx context.Context, tx pgx.Tx, tableName string, columns []string) error {
-	var columnDefs []string
-
-	// Добавляем стандартные колонки
-	columnDefs = append(columnDefs, "id BIGSERIAL PRIMARY KEY")
-
-	// Добавляем колонки из схемы
-	for _, col := range columns {
-		if col == "dealer" {
-			// Колонка dealer должна быть NOT NULL
-			columnDefs = append(columnDefs, fmt.Sprintf("%s TEXT NOT NULL", col))
-		} else {
-			columnDefs = append(columnDefs, fmt.Sprintf("%s TEXT", col))
+// prepareDealerMetrics на первом листе заменяет загрузку квартала и накапливает колонки всех листов.
+func (s *Service) prepareDealerMetrics(ctx context.Context, imp *dealerNetImport, columns []string) error {
+	if !imp.periodPrepared {
+		if err := s.dynamicRepo.PrepareDealerMetricsPeriod(ctx, imp.tx, imp.year, imp.quarter); err != nil {
+			return fmt.Errorf("failed to prepare dealer_metrics period: %w", err)
 		}
+		imp.periodPrepared = true
 	}
 
-	// Добавляем колонку created_at
-	columnDefs = append(columnDefs, "created_at TIMESTAMP DEFAULT NOW()")
-
-	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)",
-		tableName,
-		strings.Join(columnDefs, ",\n    "))
-
-	s.logger.Info("Creating unified table",
-		slog.String("table", tableName),
-		slog.String("query", query),
-	)
+	existing := make(map[string]bool, len(imp.columns))
+	for _, column := range imp.columns {
+		existing[column] = true
+	}
 
-	_, err := tx.Exec(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to create unified table %s: %w", tableName, err)
+	for _, column := range columns {
+		if !existing[column] {
+			imp.columns = append(imp.columns, column)
+		}
 	}
 
-	s.logger.Info("Unified table created successfully", slog.String("table", tableName))
 	return nil
 }
 
-// insertUnifiedData вставляет данные в единую таблицу.
-func (s *Service) insertUnifiedData(ctx context.Context, tx pgx.Tx, tableName string, columns []string, data []model.ExcelRowWithRegion) error {
-	if len(data) == 0 {
-		s.logger.Info("No data to insert", slog.String("table", tableName))
+// flushDealerNetBatch записывает накопленную пачку строк в dealer_metrics.
+func (s *Service) flushDealerNetBatch(ctx context.Context, imp *dealerNetImport, columns []string, batch [][]interface{}) error {
+	if len(batch) == 0 {
 		return nil
 	}
 
-	// Используем колонки как есть
-	allColumns := columns
-
-	// Строим SQL запрос
-	query := s.buildUnifiedInsertQuery(tableName, allColumns, len(data))
-
-	s.logger.Info("Inserting unified data",
-		slog.String("table", tableName),
-		slog.Int("rows", len(data)),
-		slog.Int("columns", len(allColumns)),
-	)
-
-	// Подготавливаем аргументы
-	args := make([]interface{}, 0, len(data)*len(allColumns))
-	for _, row := range data {
-		// Добавляем значения колонок
-		for _, col := range columns {
-			value, exists := row.Values[col]
-			if !exists || value == "" {
-				args = append(args, nil)
-			} else {
-				args = append(args, value)
-			}
+	var progress repository.InsertProgressFunc
+	if imp.progress != nil {
+		offset := imp.inserted
+		progress = func(inserted, _ int) {
+			imp.progress(offset+inserted, 0)
 		}
 	}
 
-	// Выполняем запрос
-	_, err := tx.Exec(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("failed to insert unified data into table %s: %w", tableName, err)
+	if err := s.dynamicRepo.InsertDealerMetrics(ctx, imp.tx, imp.year, imp.quarter, columns, batch, progress); err != nil {
+		return fmt.Errorf("failed to insert dealer_metrics data: %w", err)
 	}
 
-	s.logger.Info("Unified data inserted successfully",
-		slog.String("table", tableName),
-		slog.Int("rows", len(data)),
-	)
-
+	imp.inserted += len(batch)
 	return nil
 }
 
-// buildUnifiedInsertQuery строит SQL запрос для вставки данных в единую таблицу.
-func (s *Service) buildUnifiedInsertQuery(tableName string, columns []string, rowCount int) string {
-	// Создаем плейсхолдеры для значений
-	var placeholders []string
-	for i := 0; i < rowCount; i++ {
-		var rowPlaceholders []string
-		for j := 0; j < len(columns); j++ {
-			rowPlaceholders = append(rowPlaceholders, fmt.Sprintf("$%d", i*len(columns)+j+1))
-		}
-		placeholders = append(placeholders, fmt.Sprintf("(%s)", strings.Join(rowPlaceholders, ", ")))
+// logInsertProgress возвращает колбэк, который пишет прогресс вставки в лог.
+func (s *Service) logInsertProgress(fileName string) repository.InsertProgressFunc {
+	return func(inserted, total int) {
+		s.logger.Info("Dealer_net insert progress",
+			slog.String("file_name", fileName),
+			slog.Int("inserted", inserted),
+			slog.Int("total", total),
+		)
 	}
-
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
-
-	return query
 }
 
 // ProcessBrandsFile обрабатывает файл с брендами и обновляет существующие записи в БД.
-func (s *Service) ProcessBrandsFile(ctx context.Context, file io.Reader, fileName string) (*model.BrandsUploadResponse, error) {
-	startTime := time.Now()
-
+// Загрузка, в том числе отклоненная, записывается в каталог загрузок.
+func (s *Service) ProcessBrandsFile(ctx context.Context, file io.Reader, fileName string, importedBy string) (*model.BrandsUploadResponse, error) {
 	s.logger.Info("Processing brands file",
 		slog.String("file_name", fileName),
 	)
@@ -882,31 +796,54 @@ func (s *Service) ProcessBrandsFile(ctx context.Context, file io.Reader, fileNam
 		return nil, fmt.Errorf("failed to parse file name: %w", err)
 	}
 
-	// Проверяем существование таблицы
-	exists, err := s.dynamicRepo.TableExists(ctx, fileInfo.TableName)
+	dataset := &model.ImportDataset{
+		Kind:       model.ImportKindBrands,
+		Year:       fileInfo.Year,
+		Quarter:    fileInfo.Quarter,
+		SourceFile: fileName,
+		ImportedBy: importedBy,
+	}
+
+	response, err := s.importBrands(ctx, file, fileInfo, dataset)
+	if err != nil {
+		dataset.Error = err.Error()
+		s.recordFailedImport(ctx, dataset)
+		return nil, err
+	}
+
+	response.DatasetID = dataset.ID
+	return response, nil
+}
+
+// importBrands применяет бренды к загруженному кварталу и записывает загрузку в каталог.
+func (s *Service) importBrands(ctx context.Context, file io.Reader, fileInfo *model.BrandsFileInfo, dataset *model.ImportDataset) (*model.BrandsUploadResponse, error) {
+	startTime := time.Now()
+
+	// Бренды дополняют уже загруженный квартал
+	exists, err := s.dynamicRepo.DealerMetricsPeriodExists(ctx, fileInfo.Year, fileInfo.Quarter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check table existence: %w", err)
+		return nil, fmt.Errorf("failed to check period existence: %w", err)
 	}
 
 	if !exists {
-		return nil, fmt.Errorf("table %s does not exist", fileInfo.TableName)
+		return nil, fmt.Errorf("period %s is not loaded", fileInfo.TableName)
 	}
 
-	// Читаем Excel файл
-	f, err := excelize.OpenReader(file)
+	// Читаем файл ридером его формата
+	book, err := openSpreadsheet(file, fileInfo.FileName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open Excel file: %w", err)
+		return nil, err
 	}
-	defer f.Close()
+	defer book.Close()
 
 	// Получаем первый лист
-	sheetList := f.GetSheetList()
+	sheetList := book.SheetList()
 	if len(sheetList) == 0 {
-		return nil, fmt.Errorf("Excel file contains no sheets")
+		return nil, fmt.Errorf("file contains no sheets")
 	}
 
 	// Парсим лист с брендами
-	updates, err := s.parseBrandsSheet(f, sheetList[0])
+	updates, err := s.parseBrandsSheet(book, sheetList[0])
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse brands sheet: %w", err)
 	}
@@ -924,6 +861,18 @@ func (s *Service) ProcessBrandsFile(ctx context.Context, file io.Reader, fileNam
 		return nil, fmt.Errorf("failed to update dealer brands: %w", err)
 	}
 
+	// Бренды и бизнесы переносятся в нормализованные таблицы вместе с остальными показателями
+	promotion, err := s.promote(ctx, tx, fileInfo.Year, fileInfo.Quarter)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset.RowCount = updatedCount
+	dataset.Schema = model.ImportSchema{Columns: brandsImportColumns}
+	if err := s.catalog.RecordImport(ctx, tx, dataset); err != nil {
+		return nil, fmt.Errorf("failed to record import: %w", err)
+	}
+
 	// Коммитим транзакцию
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -942,14 +891,14 @@ func (s *Service) ProcessBrandsFile(ctx context.Context, file io.Reader, fileNam
 		UpdatedCount:    updatedCount,
 		NotFoundDealers: notFoundDealers,
 		ProcessingTime:  processingTime.String(),
+		Promotion:       promotion,
 	}, nil
 }
 
 // parseBrandsFileName парсит имя файла и извлекает год и квартал.
 func (s *Service) parseBrandsFileName(fileName string) (*model.BrandsFileInfo, error) {
 	// Убираем расширение
-	name := strings.TrimSuffix(fileName, ".xlsx")
-	name = strings.TrimSuffix(name, ".XLSX")
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 
 	// Извлекаем год и квартал: Name_2024_Q3.xlsx, Name-2024-Q3.xlsx, Name_2024_3.xlsx
 	re := regexp.MustCompile(`(\d{4})[_-](Q?[1-4])`)
@@ -970,19 +919,37 @@ func (s *Service) parseBrandsFileName(fileName string) (*model.BrandsFileInfo, e
 		quarter = "Q" + quarter
 	}
 
-	tableName := fmt.Sprintf("dealer_net_%d_%s", year, strings.ToLower(quarter))
-
 	return &model.BrandsFileInfo{
 		FileName:  fileName,
 		Year:      year,
 		Quarter:   quarter,
-		TableName: tableName,
+		TableName: model.DealerNetPeriodName(year, quarter),
 	}, nil
 }
 
-// parseBrandsSheet парсит лист Excel с брендами.
-func (s *Service) parseBrandsSheet(f *excelize.File, sheetName string) ([]model.BrandsUpdate, error) {
-	rows, err := f.GetRows(sheetName)
+// readSheetRows читает все строки листа; файл брендов небольшой, поэтому читается целиком.
+func readSheetRows(book spreadsheet, sheetName string) ([][]string, error) {
+	rows, err := book.Rows(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result [][]string
+	for rows.Next() {
+		row, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Error()
+}
+
+// parseBrandsSheet парсит лист с брендами.
+func (s *Service) parseBrandsSheet(book spreadsheet, sheetName string) ([]model.BrandsUpdate, error) {
+	rows, err := readSheetRows(book, sheetName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rows: %w", err)
 	}
@@ -0,0 +1,206 @@
+package excel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/repository"
+	"github.com/typefunco/dealer_dev_platform/internal/testutil"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	benchRowsCount    = 20000
+	benchMetricsCount = 57
+)
+
+// benchTx транзакция-заглушка, которая вычитывает COPY-источник без обращения к БД.
+type benchTx struct {
+	pgx.Tx
+}
+
+func (benchTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func (benchTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	var n int64
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return n, err
+		}
+		if len(values) != len(columnNames) {
+			return n, fmt.Errorf("expected %d values, got %d", len(columnNames), len(values))
+		}
+		n++
+	}
+	return n, rowSrc.Err()
+}
+
+func (benchTx) Commit(ctx context.Context) error   { return nil }
+func (benchTx) Rollback(ctx context.Context) error { return nil }
+
+// benchRepository настоящий репозиторий, которому подменена транзакция.
+type benchRepository struct {
+	repository.DynamicTableRepository
+}
+
+func (benchRepository) BeginTransaction(ctx context.Context) (pgx.Tx, error) {
+	return benchTx{}, nil
+}
+
+// benchCatalog каталог-заглушка, который не сохраняет записи.
+type benchCatalog struct{}
+
+func (benchCatalog) RecordImport(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error {
+	return nil
+}
+
+func (benchCatalog) RecordFailedImport(ctx context.Context, dataset *model.ImportDataset) error {
+	return nil
+}
+
+func (benchCatalog) List(ctx context.Context) ([]*model.ImportDataset, error) { return nil, nil }
+
+func (benchCatalog) GetByID(ctx context.Context, id int64) (*model.ImportDataset, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (benchCatalog) MarkPeriodDeleted(ctx context.Context, tx pgx.Tx, year int, quarter string, deletedBy string) error {
+	return nil
+}
+
+func (benchCatalog) ListDeleted(ctx context.Context) ([]*model.ImportDataset, error) { return nil, nil }
+
+func (benchCatalog) ListExpired(ctx context.Context, before time.Time) ([]*model.ImportDataset, error) {
+	return nil, nil
+}
+
+func (benchCatalog) Restore(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error {
+	return nil
+}
+
+func (benchCatalog) MarkPurged(ctx context.Context, tx pgx.Tx, dataset *model.ImportDataset) error {
+	return nil
+}
+
+func (benchCatalog) GetIngestRequest(ctx context.Context, owner, key string) (*model.IngestRequest, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (benchCatalog) RecordIngestRequest(ctx context.Context, tx pgx.Tx, request *model.IngestRequest) error {
+	return nil
+}
+
+func (benchCatalog) PurgeIngestRequests(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// buildBenchWorkbook генерирует книгу с одним листом на rowsCount строк дилеров.
+func buildBenchWorkbook(b *testing.B, rowsCount int) []byte {
+	b.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheetName = "Q1-Central"
+	if err := f.SetSheetName("Sheet1", sheetName); err != nil {
+		b.Fatal(err)
+	}
+
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if err := sw.SetRow("A1", []interface{}{"Dealer Net Q1 2025"}); err != nil {
+		b.Fatal(err)
+	}
+
+	headers := []interface{}{"Dealer", "City", "Region"}
+	for i := 0; i < benchMetricsCount; i++ {
+		headers = append(headers, fmt.Sprintf("Metric %d", i+1))
+	}
+	if err := sw.SetRow("A2", headers); err != nil {
+		b.Fatal(err)
+	}
+
+	for r := 0; r < rowsCount; r++ {
+		row := make([]interface{}, 0, len(headers))
+		row = append(row, fmt.Sprintf("Dealer %d", r+1), "Moscow", "Central")
+		for i := 0; i < benchMetricsCount; i++ {
+			row = append(row, r*i)
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, r+3)
+		if err := sw.SetRow(cell, row); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		b.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		b.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// BenchmarkParseExcelFile20k измеряет разбор книги и подготовку строк к COPY без базы данных:
+// benchTx вычитывает COPY-источник в памяти. Скорость вставки в Postgres показывает
+// BenchmarkProcessExcelFile20kPostgres.
+func BenchmarkParseExcelFile20k(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := benchRepository{DynamicTableRepository: repository.NewDynamicTableRepository(nil, logger)}
+	runProcessExcelBenchmark(b, NewService(repo, benchCatalog{}, nil, Options{}, logger))
+}
+
+// BenchmarkProcessExcelFile20kPostgres измеряет полную загрузку книги через pgx CopyFrom
+// в Postgres из тестового контейнера. Каждая итерация заново загружает тот же квартал.
+func BenchmarkProcessExcelFile20kPostgres(b *testing.B) {
+	testDB := testutil.SetupTestDB(b)
+	defer testDB.Cleanup(b)
+	testDB.RunMigrations(b)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := NewService(
+		repository.NewDynamicTableRepository(testDB.Pool, logger),
+		repository.NewImportCatalogRepository(testDB.Pool, logger),
+		nil, Options{}, logger,
+	)
+	runProcessExcelBenchmark(b, service)
+}
+
+// runProcessExcelBenchmark загружает книгу на benchRowsCount строк b.N раз и сообщает скорость в строках в секунду.
+func runProcessExcelBenchmark(b *testing.B, service *Service) {
+	data := buildBenchWorkbook(b, benchRowsCount)
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var inserted int
+		result, err := service.ProcessExcelFileWithProgress(context.Background(), bytes.NewReader(data), "dealer_net_Q1_2025.xlsx", "",
+			func(done, total int) { inserted = done })
+		if err != nil {
+			b.Fatal(err)
+		}
+		if !result.Success || result.TotalRows != benchRowsCount || inserted != benchRowsCount {
+			b.Fatalf("unexpected result: success=%v rows=%d inserted=%d", result.Success, result.TotalRows, inserted)
+		}
+	}
+
+	b.ReportMetric(float64(benchRowsCount*b.N)/b.Elapsed().Seconds(), "rows/s")
+}
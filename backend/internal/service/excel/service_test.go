@@ -1,6 +1,7 @@
 package excel
 
 import (
+	"bytes"
 	"context"
 	"log/slog"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/xuri/excelize/v2"
 )
 
 // MockDynamicTableRepository мок репозитория для тестов.
@@ -122,6 +124,37 @@ func TestParseSheetName(t *testing.T) {
 	}
 }
 
+func TestExtractRegionFromSheetName(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	service := &Service{logger: logger}
+
+	tests := []struct {
+		sheetName string
+		expected  string
+		wantErr   bool
+	}{
+		{sheetName: "Q3-Central", expected: "Central"},
+		{sheetName: "Q3-NW", expected: "North West"},
+		{sheetName: "Q3-FE", expected: "Far East"},
+		{sheetName: "Q1 - Kavkaz", expected: "Caucasus"},
+		{sheetName: "Q2-Северо-Запад", expected: "North West"},
+		{sheetName: "Volga", expected: "Volga"},
+		{sheetName: "Q3-Unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sheetName, func(t *testing.T) {
+			result, err := service.extractRegionFromSheetName(tt.sheetName)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
 func TestSanitizeHeaders(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	service := &Service{logger: logger}
@@ -304,40 +337,254 @@ func TestGenerateTableName(t *testing.T) {
 	}
 }
 
-func TestBuildCreateTableQuery(t *testing.T) {
+func TestMapHeaders(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	service := &Service{logger: logger}
 
-	tableName := "test_table"
-	columns := []model.DynamicTableColumn{
-		{Name: "dealer_name", DataType: "TEXT", Nullable: true},
-		{Name: "region", DataType: "TEXT", Nullable: true},
-		{Name: "city", DataType: "TEXT", Nullable: true},
+	t.Run("RU and EN variants map to canonical fields", func(t *testing.T) {
+		mapping := service.mapHeaders([]string{"Дилер", "Город", "Регион", "Check list %", "Класс дилера"})
+
+		assert.Equal(t, []string{"dealer", "city", "region", "check_list_percent", "class"}, mapping.Columns)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, mapping.Indexes)
+		assert.Empty(t, mapping.Unmapped)
+		assert.Empty(t, mapping.Missing)
+	})
+
+	t.Run("reordered sheet keeps positions of source columns", func(t *testing.T) {
+		mapping := service.mapHeaders([]string{"City", "", "Dealer name"})
+
+		assert.Equal(t, []string{"city", "dealer"}, mapping.Columns)
+		assert.Equal(t, []int{0, 2}, mapping.Indexes)
+	})
+
+	t.Run("repeated stock headers become buyout columns", func(t *testing.T) {
+		mapping := service.mapHeaders([]string{"Dealer", "City", "HDT", "MDT", "HDT", "MDT"})
+
+		assert.Equal(t, []string{"dealer", "city", "stock_hdt", "stock_mdt", "buyout_hdt", "buyout_mdt"}, mapping.Columns)
+		assert.Empty(t, mapping.Unmapped)
+	})
+
+	t.Run("group and sub-header are combined", func(t *testing.T) {
+		mapping := service.mapHeaderColumns([][]string{
+			{"Dealer", "Dealer"}, {"", "City"},
+			{"Buyout", "HDT"}, {"Buyout", "MDT"},
+			{"Сток", "HDT"}, {"Сток", "MDT"},
+			{"Sales", "Service contracts sales"}, {"New", "KPI"},
+		})
+
+		assert.Equal(t, []string{"dealer", "city", "buyout_hdt", "buyout_mdt", "stock_hdt", "stock_mdt", "service_contracts_sales", "new_kpi"}, mapping.Columns)
+		assert.Equal(t, []string{"New / KPI"}, mapping.Unmapped)
+	})
+
+	t.Run("quarter specific headers map to period neutral columns", func(t *testing.T) {
+		for _, header := range []string{"spare_parts_sales_q1", "Spare parts sales Q4", "Продажи запчастей Q3"} {
+			mapping := service.mapHeaders([]string{"Dealer", "City", header, "Spare parts sales YTD %"})
+
+			assert.Equal(t, []string{"dealer", "city", model.ColumnSparePartsSalesQuarter, model.ColumnSparePartsSalesYtdPct}, mapping.Columns, header)
+			assert.Empty(t, mapping.Unmapped, header)
+		}
+	})
+
+	t.Run("unknown and missing required columns are reported", func(t *testing.T) {
+		mapping := service.mapHeaders([]string{"Город", "New KPI"})
+
+		assert.Equal(t, []string{"city", "new_kpi"}, mapping.Columns)
+		assert.Equal(t, []string{"New KPI"}, mapping.Unmapped)
+		assert.Equal(t, []string{"dealer"}, mapping.Missing)
+	})
+
+	t.Run("city column is optional", func(t *testing.T) {
+		mapping := service.mapHeaders([]string{"Dealer", "Region"})
+
+		assert.Equal(t, []string{"dealer", "region"}, mapping.Columns)
+		assert.Empty(t, mapping.Missing)
+	})
+
+	t.Run("columns carry value kinds", func(t *testing.T) {
+		mapping := service.mapHeaders([]string{"Dealer", "City", "Брендинг", "Check list %", "Buyout HDT", "Дата аудита", "New KPI"})
+
+		assert.Equal(t, []model.ValueKind{
+			model.ValueKindText, model.ValueKindText, model.ValueKindBool, model.ValueKindPercent,
+			model.ValueKindNumber, model.ValueKindDate, model.ValueKindText,
+		}, mapping.Kinds)
+	})
+}
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		kind  model.ValueKind
+		value string
+		want  string
+	}{
+		{model.ValueKindNumber, "1 234 567,89", "1234567.89"},
+		{model.ValueKindNumber, "1\u00a0250\u00a0000", "1250000"},
+		{model.ValueKindNumber, "1,234,567.89", "1234567.89"},
+		{model.ValueKindNumber, "1.234.567", "1234567"},
+		{model.ValueKindNumber, "12,5 млн", "12500000"},
+		{model.ValueKindNumber, "12,5 млн руб.", "12500000"},
+		{model.ValueKindNumber, "$1.2m", "1200000"},
+		{model.ValueKindNumber, "350 тыс.", "350000"},
+		{model.ValueKindNumber, "(1 500)", "-1500"},
+		{model.ValueKindNumber, "-42", "-42"},
+		{model.ValueKindNumber, "12,5", "12.5"},
+		{model.ValueKindNumber, "0,125", "0.125"},
+		{model.ValueKindNumber, "1,2345", "1.2345"},
+		{model.ValueKindNumber, "12 500,000", "12500"},
+		{model.ValueKindPercent, "12,125%", "12.125"},
+		{model.ValueKindPercent, "85 %", "85"},
+		{model.ValueKindPercent, "87,6%", "87.6"},
+		{model.ValueKindPercent, "85", "85"},
+		{model.ValueKindBool, "Y", "Yes"},
+		{model.ValueKindBool, "да", "Yes"},
+		{model.ValueKindBool, "+", "Yes"},
+		{model.ValueKindBool, "Нет", "No"},
+		{model.ValueKindBool, "-", "No"},
+		{model.ValueKindBool, "n", "No"},
+		{model.ValueKindDate, "31.12.2025", "2025-12-31"},
+		{model.ValueKindDate, "2025-12-31", "2025-12-31"},
+		{model.ValueKindDate, "31 декабря 2025", "2025-12-31"},
+		{model.ValueKindDate, "Dec 31, 2025", "2025-12-31"},
+		{model.ValueKindDate, "46022", "2025-12-31"},
+		{model.ValueKindText, "Как есть", "Как есть"},
 	}
 
-	result := service.buildCreateTableQuery(tableName, columns)
+	for _, tt := range tests {
+		t.Run(string(tt.kind)+" "+tt.value, func(t *testing.T) {
+			got, err := model.NormalizeValue(tt.kind, tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for kind, value := range map[model.ValueKind]string{
+		model.ValueKindNumber:  "n/a",
+		model.ValueKindPercent: "высокий",
+		model.ValueKindBool:    "может быть",
+		model.ValueKindDate:    "Q4",
+	} {
+		_, err := model.NormalizeValue(kind, value)
+		assert.ErrorIs(t, err, model.ErrUnrecognizedValue, value)
+	}
 
-	assert.Contains(t, result, "CREATE TABLE IF NOT EXISTS test_table")
-	assert.Contains(t, result, "id BIGSERIAL PRIMARY KEY")
-	assert.Contains(t, result, "dealer_name TEXT")
-	assert.Contains(t, result, "region TEXT")
-	assert.Contains(t, result, "city TEXT")
-	assert.Contains(t, result, "created_at TIMESTAMP DEFAULT NOW()")
+	// Одна запятая перед тремя цифрами читается и как английские разряды, и как русская дробь
+	for _, value := range []string{"12,500", "1,234", "$1,500", "12,500 руб."} {
+		_, err := model.NormalizeValue(model.ValueKindNumber, value)
+		assert.ErrorIs(t, err, model.ErrAmbiguousNumber, value)
+		assert.ErrorIs(t, err, model.ErrUnrecognizedValue, value)
+	}
 }
 
-func TestBuildInsertQuery(t *testing.T) {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	service := &Service{logger: logger}
+func TestNormalizeReportsUnrecognizedCells(t *testing.T) {
+	imp := &dealerNetImport{}
+
+	assert.Equal(t, "1500", imp.normalize("Q1", "stock_hdt", model.ValueKindNumber, 3, 5, "1 500"))
+	assert.Equal(t, "n/a", imp.normalize("Q1", "branding", model.ValueKindBool, 4, 5, "n/a"))
+	assert.Equal(t, "12,500", imp.normalize("Q1", "stock_mdt", model.ValueKindNumber, 5, 5, "12,500"))
+	assert.Equal(t, []model.ExcelValueIssue{
+		{SheetName: "Q1", Cell: "D5", Column: "branding", Kind: model.ValueKindBool, Value: "n/a"},
+		{SheetName: "Q1", Cell: "E5", Column: "stock_mdt", Kind: model.ValueKindNumber, Value: "12,500", Reason: ambiguousNumberReason},
+	}, imp.valueIssues)
+}
+
+func TestHeaderFieldsAreDealerMetricColumns(t *testing.T) {
+	// Поле словаря вне dealer_metrics попало бы в extra и не дошло бы до переноса
+	for _, field := range dealerNetHeaderFields {
+		assert.True(t, model.IsDealerMetricColumn(field.Name), field.Name)
+	}
+}
+
+func TestHeaderColumnsFromMergedCells(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "Sheet1"
+	rows := map[string][]interface{}{
+		"A1": {"Dealer Net Q1 2025"},
+		"A2": {"Dealer", "City", "Stock", "", "", "Buyout", "", ""},
+		"A3": {"", "", "HDT", "MDT", "LDT", "HDT", "MDT", "LDT"},
+	}
+	for cell, values := range rows {
+		assert.NoError(t, f.SetSheetRow(sheet, cell, &values))
+	}
+	for _, merge := range [][2]string{{"A1", "H1"}, {"A2", "A3"}, {"B2", "B3"}, {"C2", "E2"}, {"F2", "H2"}} {
+		assert.NoError(t, f.MergeCell(sheet, merge[0], merge[1]))
+	}
+
+	service := NewService(nil, nil, nil, Options{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	merges, headerRows, err := service.sheetHeaderLayout(&workbook{File: f}, sheet, 2)
+	assert.NoError(t, err)
+	assert.Len(t, merges, 4, "title row is not a header merge")
+	assert.Equal(t, 2, headerRows)
+
+	header, err := f.GetRows(sheet)
+	assert.NoError(t, err)
+
+	mapping := service.mapHeaderColumns(headerColumns(header[1:3], merges, 2))
+
+	assert.Equal(t, []string{"dealer", "city", "stock_hdt", "stock_mdt", "stock_ldt", "buyout_hdt", "buyout_mdt", "buyout_ldt"}, mapping.Columns)
+	assert.Empty(t, mapping.Unmapped)
+	assert.Empty(t, mapping.Missing)
+}
+
+func TestDetectHeaderRows(t *testing.T) {
+	assert.Equal(t, 1, detectHeaderRows(nil, 2), "no merged cells means a flat header")
+	assert.Equal(t, 2, detectHeaderRows([]headerMerge{{FirstRow: 2, LastRow: 3, FirstCol: 1, LastCol: 1}}, 2))
+	assert.Equal(t, maxHeaderRows, detectHeaderRows([]headerMerge{{FirstRow: 3, LastRow: 4, FirstCol: 2, LastCol: 4}}, 2))
+}
+
+func TestSheetFormulasEvaluateUncachedCells(t *testing.T) {
+	f := excelize.NewFile()
+	const sheet = "Sheet1"
+	assert.NoError(t, f.SetSheetRow(sheet, "A1", &[]interface{}{"Dealer", "Sales", "Target", "Margin %", "Broken"}))
+	assert.NoError(t, f.SetSheetRow(sheet, "A2", &[]interface{}{"Автоцентр", 50, nil, "42"}))
+	// SetCellFormula сохраняет формулу без результата, как генераторы отчетов и экспорт LibreOffice
+	assert.NoError(t, f.SetCellFormula(sheet, "C2", "B2*4"))
+	assert.NoError(t, f.SetCellFormula(sheet, "E2", "NOSUCHFUNCTION(B2)"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, f.Write(&buf))
+	assert.NoError(t, f.Close())
+
+	w, err := openDealerNetWorkbook(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	defer w.Close()
+
+	formulas, err := newSheetFormulas(w, sheet)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"C2": true, "E2": true}, formulas.uncached)
+
+	assert.Equal(t, "50", formulas.value("sales", 2, 2, "50"))
+	assert.Equal(t, "200", formulas.value("target", 3, 2, ""))
+	assert.Equal(t, "42", formulas.value("margin_percent", 4, 2, "42"))
+	assert.Empty(t, formulas.value("broken", 5, 2, ""))
+
+	reports, errs := formulas.results()
+	assert.Equal(t, []model.ExcelFormulaReport{
+		{SheetName: sheet, Column: "target", Computed: []string{"C2"}},
+		{SheetName: sheet, Column: "broken", Failed: 1},
+	}, reports)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, 2, errs[0].Row)
+		assert.Equal(t, "broken", errs[0].Column)
+		assert.Contains(t, errs[0].Message, "NOSUCHFUNCTION(B2)")
+	}
+}
+
+func TestSheetFormulasSkipSheetsWithCachedValues(t *testing.T) {
+	f := excelize.NewFile()
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]interface{}{"Dealer", "Sales"}))
 
-	tableName := "test_table"
-	columns := []string{"dealer_name", "region", "city"}
-	rowCount := 2
+	var buf bytes.Buffer
+	assert.NoError(t, f.Write(&buf))
+	assert.NoError(t, f.Close())
 
-	result := service.buildInsertQuery(tableName, columns, rowCount)
+	w, err := openDealerNetWorkbook(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	defer w.Close()
 
-	assert.Contains(t, result, "INSERT INTO test_table")
-	assert.Contains(t, result, "(dealer_name, region, city)")
-	assert.Contains(t, result, "VALUES")
-	assert.Contains(t, result, "($1, $2, $3)")
-	assert.Contains(t, result, "($4, $5, $6)")
+	formulas, err := newSheetFormulas(w, "Sheet1")
+	assert.NoError(t, err)
+	assert.Nil(t, formulas)
+	assert.Equal(t, "10", formulas.value("sales", 2, 2, "10"))
 }
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/utils"
 )
 
 type Repository interface {
@@ -13,9 +14,11 @@ type Repository interface {
 	GetWithDetailsByPeriod(ctx context.Context, quarter string, year int, region string) ([]*model.PerformanceWithDetails, error)
 	GetWithFilters(ctx context.Context, filters *model.FilterParams) ([]*model.PerformanceWithDetails, error)
 	GetByID(ctx context.Context, id int64) (*model.PerformanceSales, error)
+	GetByDealerAndPeriod(ctx context.Context, dealerID int64, quarter string, year int) (*model.PerformanceSales, error)
 	Create(ctx context.Context, perf *model.PerformanceSales) (int64, error)
-	Update(ctx context.Context, id int64, updates map[string]interface{}) error
-	Delete(ctx context.Context, id int64) error
+	Update(ctx context.Context, id int64, version int, patch model.Patch) error
+	UpdateFull(ctx context.Context, perf *model.PerformanceSales) error
+	Delete(ctx context.Context, id int64, version int) error
 }
 
 type Service struct {
@@ -113,6 +116,8 @@ func (s *Service) GetPerformanceByID(ctx context.Context, id int64) (*model.Perf
 
 // CreatePerformance создает новую запись производительности.
 func (s *Service) CreatePerformance(ctx context.Context, perf *model.PerformanceSales) (int64, error) {
+	perf.Quarter = utils.NormalizeQuarter(perf.Quarter)
+
 	// Валидация
 	if err := s.validatePerformance(perf); err != nil {
 		return 0, fmt.Errorf("PerformanceService.CreatePerformance: validation failed: %w", err)
@@ -138,16 +143,17 @@ func (s *Service) CreatePerformance(ctx context.Context, perf *model.Performance
 }
 
 // UpdatePerformance обновляет данные производительности.
-func (s *Service) UpdatePerformance(ctx context.Context, id int64, updates map[string]interface{}) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) UpdatePerformance(ctx context.Context, id int64, version int, patch model.Patch) error {
 	if id <= 0 {
 		return fmt.Errorf("PerformanceService.UpdatePerformance: invalid ID: %d", id)
 	}
 
-	if len(updates) == 0 {
-		return fmt.Errorf("PerformanceService.UpdatePerformance: no fields to update")
+	if patch.IsEmpty() {
+		return fmt.Errorf("PerformanceService.UpdatePerformance: %w", model.ErrEmptyPatch)
 	}
 
-	err := s.repository.Update(ctx, id, updates)
+	err := s.repository.Update(ctx, id, version, patch)
 	if err != nil {
 		s.logger.Error("PerformanceService.UpdatePerformance: failed to update",
 			"id", id,
@@ -164,12 +170,13 @@ func (s *Service) UpdatePerformance(ctx context.Context, id int64, updates map[s
 }
 
 // DeletePerformance удаляет запись производительности.
-func (s *Service) DeletePerformance(ctx context.Context, id int64) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) DeletePerformance(ctx context.Context, id int64, version int) error {
 	if id <= 0 {
 		return fmt.Errorf("PerformanceService.DeletePerformance: invalid ID: %d", id)
 	}
 
-	err := s.repository.Delete(ctx, id)
+	err := s.repository.Delete(ctx, id, version)
 	if err != nil {
 		s.logger.Error("PerformanceService.DeletePerformance: failed to delete",
 			"id", id,
@@ -185,31 +192,135 @@ func (s *Service) DeletePerformance(ctx context.Context, id int64) error {
 	return nil
 }
 
-// validatePerformance валидирует данные производительности.
-func (s *Service) validatePerformance(perf *model.PerformanceSales) error {
-	if perf.DealerID <= 0 {
-		return fmt.Errorf("dealer_id is required")
+// GetPerformanceByDealerAndPeriod возвращает запись производительности дилера за квартал.
+func (s *Service) GetPerformanceByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey) (*model.PerformanceSales, error) {
+	key.Quarter = utils.NormalizeQuarter(key.Quarter)
+
+	perf, err := s.repository.GetByDealerAndPeriod(ctx, int64(key.DealerID), key.Quarter, key.Year)
+	if err != nil {
+		return nil, fmt.Errorf("PerformanceService.GetPerformanceByDealerAndPeriod: %w", err)
+	}
+
+	return perf, nil
+}
+
+// ReplacePerformance полностью заменяет запись производительности дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) ReplacePerformance(ctx context.Context, key model.DealerPeriodKey, version int, perf *model.PerformanceSales) (*model.PerformanceSales, error) {
+	current, err := s.GetPerformanceByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("PerformanceService.ReplacePerformance: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("PerformanceService.ReplacePerformance: %w", err)
+	}
+
+	// Ключ записи берется из пути, а не из тела запроса
+	perf.ID = current.ID
+	perf.DealerID = current.DealerID
+	perf.Quarter = current.Quarter
+	perf.Year = current.Year
+	perf.CreatedAt = current.CreatedAt
+	perf.Version = current.Version
+
+	if err := s.validatePerformance(perf); err != nil {
+		return nil, fmt.Errorf("PerformanceService.ReplacePerformance: validation failed: %w", err)
+	}
+
+	if err := s.repository.UpdateFull(ctx, perf); err != nil {
+		s.logger.Error("PerformanceService.ReplacePerformance: failed to update",
+			"id", perf.ID,
+			"error", err,
+		)
+		return nil, fmt.Errorf("PerformanceService.ReplacePerformance: %w", err)
+	}
+
+	s.logger.Info("PerformanceService.ReplacePerformance: successfully updated",
+		"id", perf.ID,
+	)
+
+	return perf, nil
+}
+
+// PatchPerformance частично обновляет запись производительности дилера за квартал.
+// Изменения проверяются схемой на уровне полей, итоговая запись - правилами validatePerformance.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) PatchPerformance(ctx context.Context, key model.DealerPeriodKey, version int, patch model.Patch) (*model.PerformanceSales, error) {
+	current, err := s.GetPerformanceByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("PerformanceService.PatchPerformance: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("PerformanceService.PatchPerformance: %w", err)
+	}
+
+	merged := *current
+	if err := patch.Apply(&merged); err != nil {
+		return nil, fmt.Errorf("PerformanceService.PatchPerformance: %w", err)
 	}
 
-	// Валидация периода
-	if perf.Quarter == "" || perf.Year == 0 {
-		return fmt.Errorf("quarter and year are required")
+	if err := s.validatePerformance(&merged); err != nil {
+		return nil, fmt.Errorf("PerformanceService.PatchPerformance: validation failed: %w", err)
+	}
+
+	if err := s.UpdatePerformance(ctx, int64(current.ID), current.Version, patch); err != nil {
+		return nil, fmt.Errorf("PerformanceService.PatchPerformance: %w", err)
+	}
+
+	return s.GetPerformanceByDealerAndPeriod(ctx, key)
+}
+
+// DeletePerformanceByDealerAndPeriod удаляет запись производительности дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) DeletePerformanceByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey, version int) error {
+	current, err := s.GetPerformanceByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return fmt.Errorf("PerformanceService.DeletePerformanceByDealerAndPeriod: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return fmt.Errorf("PerformanceService.DeletePerformanceByDealerAndPeriod: %w", err)
 	}
 
+	return s.DeletePerformance(ctx, int64(current.ID), current.Version)
+}
+
+// validatePerformance валидирует данные производительности и возвращает ошибки по полям.
+func (s *Service) validatePerformance(perf *model.PerformanceSales) error {
+	var errs model.ValidationErrors
+
+	// Валидация ключа записи
+	utils.ValidatePeriodKey(&errs, perf.DealerID, perf.Quarter, perf.Year)
+
 	// Валидация финансовых показателей (если не nil)
+	if perf.QuantitySold != nil && *perf.QuantitySold < 0 {
+		errs.Add("quantity_sold", "cannot be negative")
+	}
+
 	if perf.SalesRevenue != nil && *perf.SalesRevenue < 0 {
-		return fmt.Errorf("sales_revenue cannot be negative")
+		errs.Add("sales_revenue", "cannot be negative")
 	}
 
 	if perf.SalesCost != nil && *perf.SalesCost < 0 {
-		return fmt.Errorf("sales_cost cannot be negative")
+		errs.Add("sales_cost", "cannot be negative")
 	}
 
 	if perf.SalesMargin != nil && *perf.SalesMargin < 0 {
-		return fmt.Errorf("sales_margin cannot be negative")
+		errs.Add("sales_margin", "cannot be negative")
 	}
 
-	return nil
+	for _, pct := range []struct {
+		field string
+		value *float64
+	}{
+		{"sales_margin_pct", perf.SalesMarginPct},
+		{"sales_profit_pct", perf.SalesProfitPct},
+	} {
+		if pct.value != nil && (*pct.value < -100 || *pct.value > 100) {
+			errs.Add(pct.field, "must be between -100 and 100")
+		}
+	}
+
+	return errs.Err()
 }
 
 // isValidQuarter проверяет валидность квартала.
@@ -0,0 +1,248 @@
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/utils"
+)
+
+// Repository интерфейс репозитория переноса dealer_net в нормализованные таблицы.
+type Repository interface {
+	BeginTransaction(ctx context.Context) (pgx.Tx, error)
+	LoadDealerNetRows(ctx context.Context, tx pgx.Tx, year int, quarter string) ([]map[string]string, error)
+	SaveDealerNetRecords(ctx context.Context, tx pgx.Tx, year int, quarter string, records []*model.DealerNetRecord, result *model.PromotionResult) error
+	ListPendingPeriods(ctx context.Context) ([]model.PromotionPeriod, error)
+}
+
+// Service переносит загрузки dealer_net из dealer_metrics в нормализованную модель:
+// dealers, sales, dealer_dev, after_sales, dealer_brands и dealer_businesses.
+type Service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+// NewService создает новый экземпляр сервиса переноса.
+func NewService(repo Repository, logger *slog.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Promote переносит загрузку dealer_net за квартал в нормализованные таблицы в транзакции tx.
+// Вызывается из загрузки Excel до коммита, поэтому ошибка переноса откатывает и саму загрузку.
+func (s *Service) Promote(ctx context.Context, tx pgx.Tx, year int, quarter string) (*model.PromotionResult, error) {
+	quarter = utils.NormalizeQuarter(quarter)
+	if !utils.IsValidQuarter(quarter) {
+		return nil, fmt.Errorf("PromotionService.Promote: invalid quarter: %s", quarter)
+	}
+
+	rows, err := s.repo.LoadDealerNetRows(ctx, tx, year, quarter)
+	if err != nil {
+		return nil, fmt.Errorf("PromotionService.Promote: %w", err)
+	}
+
+	result := &model.PromotionResult{
+		Year:     year,
+		Quarter:  quarter,
+		RowsRead: len(rows),
+	}
+
+	records := make([]*model.DealerNetRecord, 0, len(rows))
+	for i, row := range rows {
+		record, ok := mapDealerNetRow(row)
+		if !ok {
+			result.SkippedRows = append(result.SkippedRows, fmt.Sprintf("row %d: dealer name is empty", i+1))
+			continue
+		}
+		records = append(records, record)
+	}
+
+	if err := s.repo.SaveDealerNetRecords(ctx, tx, year, quarter, records, result); err != nil {
+		s.logger.Error("PromotionService.Promote: failed to save records",
+			"year", year,
+			"quarter", quarter,
+			"error", err,
+		)
+		return nil, fmt.Errorf("PromotionService.Promote: %w", err)
+	}
+
+	s.logger.Info("PromotionService.Promote: dealer_net promoted",
+		"year", year,
+		"quarter", quarter,
+		"rows", result.RowsRead,
+		"skipped", len(result.SkippedRows),
+		"dealers_created", result.DealersCreated,
+	)
+
+	return result, nil
+}
+
+// PromotePeriod повторно переносит уже загруженный квартал dealer_net в отдельной транзакции.
+func (s *Service) PromotePeriod(ctx context.Context, year int, quarter string) (*model.PromotionResult, error) {
+	tx, err := s.repo.BeginTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("PromotionService.PromotePeriod: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := s.Promote(ctx, tx, year, quarter)
+	if err != nil {
+		return nil, fmt.Errorf("PromotionService.PromotePeriod: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("PromotionService.PromotePeriod: failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// PromotePending переносит кварталы dealer_metrics, которые еще не переносились, например загруженные
+// до появления переноса. Кварталы обрабатываются от старых к новым, чтобы у дилера осталось
+// совместное решение последнего квартала. Ошибка квартала логируется и не останавливает остальные.
+func (s *Service) PromotePending(ctx context.Context) (int, error) {
+	periods, err := s.repo.ListPendingPeriods(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("PromotionService.PromotePending: %w", err)
+	}
+
+	promoted := 0
+	for _, period := range periods {
+		if _, err := s.PromotePeriod(ctx, period.Year, period.Quarter); err != nil {
+			s.logger.Error("PromotionService.PromotePending: failed to promote period",
+				"year", period.Year,
+				"quarter", period.Quarter,
+				"error", err,
+			)
+			continue
+		}
+		promoted++
+	}
+
+	return promoted, nil
+}
+
+// mapDealerNetRow раскладывает строку dealer_net по нормализованным моделям.
+// Строка без названия дилера не переносится.
+func mapDealerNetRow(row map[string]string) (*model.DealerNetRecord, bool) {
+	name := row["dealer"]
+	if name == "" {
+		return nil, false
+	}
+
+	region := row["region"]
+	if resolved, ok := model.Regions().Resolve(region); ok {
+		region = resolved.Name
+	}
+
+	record := &model.DealerNetRecord{
+		Dealer: model.Dealer{
+			DealerNameRu:  name,
+			City:          row["city"],
+			Region:        region,
+			Manager:       row["manager"],
+			JointDecision: parseDecision(row["joint_decision"]),
+		},
+		Sales: model.Sales{
+			StockHDT:              parseInt(row["stock_hdt"]),
+			StockMDT:              parseInt(row["stock_mdt"]),
+			StockLDT:              parseInt(row["stock_ldt"]),
+			BuyoutHDT:             parseInt(row["buyout_hdt"]),
+			BuyoutMDT:             parseInt(row["buyout_mdt"]),
+			BuyoutLDT:             parseInt(row["buyout_ldt"]),
+			ServiceContractsSales: parseInt(row["service_contracts_sales"]),
+			SalesDecision:         row["sales"],
+		},
+		DealerDevelopment: model.DealerDevelopment{
+			CheckListScore:       parseInt(row["check_list_percent"]),
+			DealershipClass:      parseClass(row["class"]),
+			Branding:             parseYesNo(row["branding"]),
+			MarketingInvestments: int64(parseInt(row["marketing_investments"])),
+			DDRecommendation:     row["dealer_development"],
+		},
+		AfterSales: model.AfterSales{
+			RecommendedStock:       parseInt(row["recommended_stock_percent"]),
+			WarrantyStock:          parseInt(row["warranty_stock_percent"]),
+			FotonLaborHours:        parseInt(row["foton_labour_hours"]),
+			FotonWarrantyHours:     parseInt(row["warranty_hours"]),
+			ServiceContracts:       parseInt(row["service_contracts_hours"]),
+			ASTrainings:            parseYesNo(row["as_trainings"]),
+			ASDecision:             row["aftersales"],
+			SparePartsSalesQuarter: row[model.ColumnSparePartsSalesQuarter],
+			SparePartsSalesYtdPct:  row[model.ColumnSparePartsSalesYtdPct],
+			FotonLabourHoursShare:  row["foton_labour_hours_share"],
+		},
+	}
+
+	if value, ok := row["brands_in_portfolio"]; ok {
+		record.Brands = splitList(value)
+	}
+	if value, ok := row["byside_businesses"]; ok {
+		record.Businesses = splitList(value)
+	}
+
+	return record, true
+}
+
+// parseInt разбирает целое из ячейки в русской или английской записи; дробные значения округляются.
+// Значения нормализуются при загрузке, нераспознанные попадают в отчет загрузки и дают 0.
+func parseInt(value string) int {
+	number, err := model.ParseNumber(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")))
+	if err != nil {
+		return 0
+	}
+	return int(math.Round(number))
+}
+
+// parseYesNo разбирает флаг Yes/No из ячейки; нераспознанное значение - No.
+func parseYesNo(value string) bool {
+	flag, _ := model.ParseYesNo(value)
+	return flag
+}
+
+// parseDecision возвращает решение из списка model.Decisions без учета регистра.
+// Пустое или нераспознанное значение - nil: сохраненное совместное решение дилера не меняется.
+func parseDecision(value string) *string {
+	value = strings.TrimSpace(value)
+	for _, decision := range model.Decisions {
+		if strings.EqualFold(value, decision) {
+			return &decision
+		}
+	}
+	return nil
+}
+
+// parseClass возвращает класс дилера A-D или пустую строку, если значение не распознано.
+func parseClass(value string) string {
+	class := strings.ToUpper(strings.TrimSpace(value))
+	switch model.DealershipClass(class) {
+	case model.DealershipClassA, model.DealershipClassB, model.DealershipClassC, model.DealershipClassD:
+		return class
+	}
+	return ""
+}
+
+// splitList разбирает список через запятую или точку с запятой без пустых значений и повторов.
+func splitList(value string) []string {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+
+	items := make([]string, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		item := strings.TrimSpace(part)
+		key := strings.ToLower(item)
+		if item == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		items = append(items, item)
+	}
+	return items
+}
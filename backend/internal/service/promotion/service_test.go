@@ -0,0 +1,138 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+// memoryTx транзакция-заглушка без обращения к БД.
+type memoryTx struct {
+	pgx.Tx
+}
+
+func (memoryTx) Commit(ctx context.Context) error   { return nil }
+func (memoryTx) Rollback(ctx context.Context) error { return nil }
+
+// memoryRepository репозиторий переноса в памяти: строки кварталов и перенесенные кварталы.
+type memoryRepository struct {
+	rows     map[string][]map[string]string
+	promoted []string
+	failing  string
+}
+
+func (r *memoryRepository) BeginTransaction(ctx context.Context) (pgx.Tx, error) {
+	return memoryTx{}, nil
+}
+
+func (r *memoryRepository) LoadDealerNetRows(ctx context.Context, tx pgx.Tx, year int, quarter string) ([]map[string]string, error) {
+	period := model.DealerNetPeriodName(year, quarter)
+	if period == r.failing {
+		return nil, errors.New("connection reset")
+	}
+	return r.rows[period], nil
+}
+
+func (r *memoryRepository) SaveDealerNetRecords(ctx context.Context, tx pgx.Tx, year int, quarter string, records []*model.DealerNetRecord, result *model.PromotionResult) error {
+	r.promoted = append(r.promoted, model.DealerNetPeriodName(year, quarter))
+	return nil
+}
+
+func (r *memoryRepository) ListPendingPeriods(ctx context.Context) ([]model.PromotionPeriod, error) {
+	return []model.PromotionPeriod{{Year: 2024, Quarter: "Q4"}, {Year: 2025, Quarter: "Q1"}, {Year: 2025, Quarter: "Q2"}}, nil
+}
+
+func TestMapDealerNetRow(t *testing.T) {
+	record, ok := mapDealerNetRow(map[string]string{
+		"dealer":                    "Автоцентр",
+		"city":                      "Казань",
+		"region":                    "Волга",
+		"manager":                   "Иванов",
+		"class":                     " b ",
+		"check_list_percent":        "87,6%",
+		"marketing_investments":     "1 250 000",
+		"branding":                  "Y",
+		"dealer_development":        "Planned Result",
+		"stock_hdt":                 "3",
+		"buyout_mdt":                "2",
+		"sales":                     "Needs Development",
+		"recommended_stock_percent": "45%",
+		"as_trainings":              "Yes",
+		"spare_parts_sales_quarter": "1 200 000",
+		"aftersales":                "Close Down",
+		"brands_in_portfolio":       "Foton, KAMAZ; foton,",
+		"joint_decision":            " find new candidate ",
+	})
+	require.True(t, ok)
+
+	assert.Equal(t, "Автоцентр", record.Dealer.DealerNameRu)
+	require.NotNil(t, record.Dealer.JointDecision)
+	assert.Equal(t, "Find New Candidate", *record.Dealer.JointDecision)
+	assert.Equal(t, "Казань", record.Dealer.City)
+	assert.Equal(t, "Volga", record.Dealer.Region)
+	assert.Equal(t, "Иванов", record.Dealer.Manager)
+
+	assert.Equal(t, "B", record.DealerDevelopment.DealershipClass)
+	assert.Equal(t, 88, record.DealerDevelopment.CheckListScore)
+	assert.Equal(t, int64(1250000), record.DealerDevelopment.MarketingInvestments)
+	assert.True(t, record.DealerDevelopment.Branding)
+	assert.Equal(t, "Planned Result", record.DealerDevelopment.DDRecommendation)
+
+	assert.Equal(t, 3, record.Sales.StockHDT)
+	assert.Equal(t, 2, record.Sales.BuyoutMDT)
+	assert.Equal(t, "Needs Development", record.Sales.SalesDecision)
+
+	assert.Equal(t, 45, record.AfterSales.RecommendedStock)
+	assert.True(t, record.AfterSales.ASTrainings)
+	assert.Equal(t, "1 200 000", record.AfterSales.SparePartsSalesQuarter)
+	assert.Equal(t, "Close Down", record.AfterSales.ASDecision)
+
+	assert.Equal(t, []string{"Foton", "KAMAZ"}, record.Brands)
+	assert.Nil(t, record.Businesses, "businesses are left untouched when the column is empty")
+}
+
+func TestMapDealerNetRow_Defaults(t *testing.T) {
+	record, ok := mapDealerNetRow(map[string]string{
+		"dealer":    "Дилер",
+		"class":     "Premium",
+		"stock_hdt": "n/a",
+	})
+	require.True(t, ok)
+
+	assert.Empty(t, record.DealerDevelopment.DealershipClass)
+	assert.Zero(t, record.Sales.StockHDT)
+	assert.False(t, record.AfterSales.ASTrainings)
+	assert.Nil(t, record.Dealer.JointDecision, "missing joint decision keeps the stored one")
+
+	record, ok = mapDealerNetRow(map[string]string{"dealer": "Дилер", "joint_decision": "Maybe"})
+	require.True(t, ok)
+	assert.Nil(t, record.Dealer.JointDecision, "unknown joint decision keeps the stored one")
+}
+
+func TestMapDealerNetRow_SkipsRowWithoutDealer(t *testing.T) {
+	_, ok := mapDealerNetRow(map[string]string{"city": "Москва"})
+	assert.False(t, ok)
+}
+
+func TestPromotePending(t *testing.T) {
+	repo := &memoryRepository{
+		rows: map[string][]map[string]string{
+			"dealer_net_2024_q4": {{"dealer": "Автоцентр"}},
+			"dealer_net_2025_q2": {{"dealer": "Автоцентр", "joint_decision": "Close Down"}},
+		},
+		failing: "dealer_net_2025_q1",
+	}
+	service := NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	promoted, err := service.PromotePending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, promoted, "a failed period does not stop the others")
+	assert.Equal(t, []string{"dealer_net_2024_q4", "dealer_net_2025_q2"}, repo.promoted, "periods are promoted oldest first")
+}
@@ -0,0 +1,127 @@
+package region
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+var (
+	// ErrInvalidAlias алиас пустой или совпадает со служебным значением.
+	ErrInvalidAlias = errors.New("invalid alias")
+
+	// ErrAliasConflict алиас уже указывает на другой регион.
+	ErrAliasConflict = errors.New("alias is already used by another region")
+)
+
+// Repository интерфейс репозитория регионов.
+type Repository interface {
+	ListRegions(ctx context.Context) ([]model.Region, error)
+	GetRegionByCode(ctx context.Context, code string) (*model.Region, error)
+	AddAlias(ctx context.Context, regionID int64, alias string) (*model.RegionAlias, error)
+	DeleteAlias(ctx context.Context, regionID int64, alias string) error
+}
+
+// Service сервис справочника регионов.
+type Service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+// NewService создает новый экземпляр сервиса регионов.
+func NewService(repo Repository, logger *slog.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Load загружает справочник регионов из БД и делает его текущим.
+func (s *Service) Load(ctx context.Context) error {
+	regions, err := s.repo.ListRegions(ctx)
+	if err != nil {
+		return fmt.Errorf("RegionService.Load: %w", err)
+	}
+
+	if len(regions) == 0 {
+		s.logger.Warn("RegionService.Load: regions table is empty, keeping current registry")
+		return nil
+	}
+
+	model.SetRegionRegistry(model.NewRegionRegistry(regions))
+	s.logger.Info("RegionService.Load: region registry loaded", slog.Int("regions", len(regions)))
+	return nil
+}
+
+// StartRefresh периодически перечитывает справочник, чтобы изменения с других инстансов применялись без деплоя.
+func (s *Service) StartRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil {
+				s.logger.Error("RegionService.StartRefresh: failed to reload regions", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// GetRegions возвращает текущий список регионов.
+func (s *Service) GetRegions() []model.Region {
+	return model.Regions().List()
+}
+
+// AddAlias добавляет алиас региону и перезагружает справочник.
+func (s *Service) AddAlias(ctx context.Context, code string, alias string) (*model.Region, error) {
+	alias = strings.TrimSpace(alias)
+	if model.NormalizeRegionKey(alias) == "" || model.IsAllRussia(alias) {
+		return nil, fmt.Errorf("RegionService.AddAlias: %w", ErrInvalidAlias)
+	}
+
+	region, err := s.repo.GetRegionByCode(ctx, strings.ToUpper(code))
+	if err != nil {
+		return nil, fmt.Errorf("RegionService.AddAlias: %w", err)
+	}
+
+	if existing, ok := model.Regions().Resolve(alias); ok && existing.Code != region.Code {
+		return nil, fmt.Errorf("RegionService.AddAlias: %s points to %s: %w", alias, existing.Code, ErrAliasConflict)
+	}
+
+	if _, err := s.repo.AddAlias(ctx, region.ID, alias); err != nil {
+		return nil, fmt.Errorf("RegionService.AddAlias: %w", err)
+	}
+
+	if err := s.Load(ctx); err != nil {
+		return nil, fmt.Errorf("RegionService.AddAlias: %w", err)
+	}
+
+	updated, _ := model.Regions().Resolve(region.Code)
+	return updated, nil
+}
+
+// DeleteAlias удаляет алиас региона и перезагружает справочник.
+func (s *Service) DeleteAlias(ctx context.Context, code string, alias string) error {
+	region, err := s.repo.GetRegionByCode(ctx, strings.ToUpper(code))
+	if err != nil {
+		return fmt.Errorf("RegionService.DeleteAlias: %w", err)
+	}
+
+	if err := s.repo.DeleteAlias(ctx, region.ID, strings.TrimSpace(alias)); err != nil {
+		return fmt.Errorf("RegionService.DeleteAlias: %w", err)
+	}
+
+	if err := s.Load(ctx); err != nil {
+		return fmt.Errorf("RegionService.DeleteAlias: %w", err)
+	}
+
+	return nil
+}
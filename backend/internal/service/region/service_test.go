@@ -0,0 +1,132 @@
+package region
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
+
+// MockRepository мок репозитория регионов для тестов.
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) ListRegions(ctx context.Context) ([]model.Region, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Region), args.Error(1)
+}
+
+func (m *MockRepository) GetRegionByCode(ctx context.Context, code string) (*model.Region, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Region), args.Error(1)
+}
+
+func (m *MockRepository) AddAlias(ctx context.Context, regionID int64, alias string) (*model.RegionAlias, error) {
+	args := m.Called(ctx, regionID, alias)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RegionAlias), args.Error(1)
+}
+
+func (m *MockRepository) DeleteAlias(ctx context.Context, regionID int64, alias string) error {
+	args := m.Called(ctx, regionID, alias)
+	return args.Error(0)
+}
+
+func newTestService(repo Repository) *Service {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return NewService(repo, logger)
+}
+
+func TestServiceAddAlias(t *testing.T) {
+	defer model.SetRegionRegistry(model.NewRegionRegistry(model.DefaultRegions()))
+	model.SetRegionRegistry(model.NewRegionRegistry(model.DefaultRegions()))
+
+	ctx := context.Background()
+	volga := &model.Region{ID: 3, Code: "VOLGA", Name: "Volga", NameRu: "Поволжье"}
+
+	t.Run("alias is added and registry reloaded", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := newTestService(repo)
+
+		reloaded := model.DefaultRegions()
+		reloaded[2].Aliases = append(reloaded[2].Aliases, "Privolzhye")
+
+		repo.On("GetRegionByCode", ctx, "VOLGA").Return(volga, nil)
+		repo.On("AddAlias", ctx, int64(3), "Privolzhye").Return(&model.RegionAlias{ID: 1, RegionID: 3, Alias: "Privolzhye"}, nil)
+		repo.On("ListRegions", ctx).Return(reloaded, nil)
+
+		updated, err := service.AddAlias(ctx, "volga", " Privolzhye ")
+		require.NoError(t, err)
+		assert.Equal(t, "VOLGA", updated.Code)
+
+		canonical, ok := model.Regions().Canonical("privolzhye")
+		assert.True(t, ok)
+		assert.Equal(t, "Volga", canonical)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("alias of another region is rejected", func(t *testing.T) {
+		repo := &MockRepository{}
+		service := newTestService(repo)
+
+		repo.On("GetRegionByCode", ctx, "VOLGA").Return(volga, nil)
+
+		_, err := service.AddAlias(ctx, "VOLGA", "Kavkaz")
+		assert.ErrorIs(t, err, ErrAliasConflict)
+		repo.AssertNotCalled(t, "AddAlias", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty alias is rejected", func(t *testing.T) {
+		service := newTestService(&MockRepository{})
+
+		_, err := service.AddAlias(ctx, "VOLGA", "  ")
+		assert.ErrorIs(t, err, ErrInvalidAlias)
+
+		_, err = service.AddAlias(ctx, "VOLGA", "all-russia")
+		assert.ErrorIs(t, err, ErrInvalidAlias)
+	})
+}
+
+func TestRegionRegistryCanonical(t *testing.T) {
+	registry := model.NewRegionRegistry(model.DefaultRegions())
+
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{input: "Central", expected: "Central", valid: true},
+		{input: "north-west", expected: "North West", valid: true},
+		{input: "NORTH_WEST", expected: "North West", valid: true},
+		{input: "NW", expected: "North West", valid: true},
+		{input: "Kavkaz", expected: "Caucasus", valid: true},
+		{input: "n-caucasus", expected: "Caucasus", valid: true},
+		{input: "Северо-Запад", expected: "North West", valid: true},
+		{input: "far-east", expected: "Far East", valid: true},
+		{input: "all", expected: model.AllRussia, valid: true},
+		{input: "all-russia", expected: model.AllRussia, valid: true},
+		{input: "Moscow", expected: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, ok := registry.Canonical(tt.input)
+			assert.Equal(t, tt.valid, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
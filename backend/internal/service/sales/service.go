@@ -15,32 +15,23 @@ type Repository interface {
 	GetByID(ctx context.Context, id int64) (*model.Sales, error)
 	GetByDealerAndPeriod(ctx context.Context, dealerID int64, quarter string, year int) (*model.Sales, error)
 	GetAllByPeriod(ctx context.Context, quarter string, year int) ([]*model.Sales, error)
-	Update(ctx context.Context, id int64, updates map[string]interface{}) error
+	Update(ctx context.Context, id int64, version int, patch model.Patch) error
 	UpdateFull(ctx context.Context, sales *model.Sales) error
-	Delete(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id int64, version int) error
 	GetWithDetailsByPeriod(ctx context.Context, quarter string, year int, region string) ([]*model.SalesWithDetails, error)
 }
 
-// ExcelRepository интерфейс репозитория для работы с Excel данными продаж.
-type ExcelRepository interface {
-	GetDealerNetTableName(year int, quarter string) string
-	TableExists(ctx context.Context, year int, quarter string) (bool, error)
-	GetSalesDataFromExcel(ctx context.Context, year int, quarter string, region string) ([]*model.SalesWithDetails, error)
-}
-
 // Service сервис для работы с данными продаж.
 type Service struct {
-	repo      Repository
-	excelRepo ExcelRepository
-	logger    *slog.Logger
+	repo   Repository
+	logger *slog.Logger
 }
 
 // NewService создает новый экземпляр сервиса Sales.
-func NewService(repo Repository, excelRepo ExcelRepository, logger *slog.Logger) *Service {
+func NewService(repo Repository, logger *slog.Logger) *Service {
 	return &Service{
-		repo:      repo,
-		excelRepo: excelRepo,
-		logger:    logger,
+		repo:   repo,
+		logger: logger,
 	}
 }
 
@@ -50,46 +41,9 @@ func (s *Service) GetSalesByPeriod(ctx context.Context, quarter string, year int
 	if err := utils.ValidateFilters(quarter, year, region); err != nil {
 		return nil, fmt.Errorf("SalesService.GetSalesByPeriod: %w", err)
 	}
+	region, _ = model.Regions().Canonical(region)
+	quarter = utils.NormalizeQuarter(quarter)
 
-	// Если указаны год и квартал, используем Excel данные
-	if year > 0 && quarter != "" {
-		s.logger.Info("Getting sales data from Excel table",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-			slog.String("region", region),
-		)
-
-		// Проверяем существование таблицы
-		exists, err := s.excelRepo.TableExists(ctx, year, quarter)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check Excel table existence: %w", err)
-		}
-
-		if !exists {
-			s.logger.Warn("Excel table does not exist",
-				slog.Int("year", year),
-				slog.String("quarter", quarter),
-			)
-			return []*model.SalesWithDetails{}, nil
-		}
-
-		// Получаем данные из Excel таблицы
-		salesData, err := s.excelRepo.GetSalesDataFromExcel(ctx, year, quarter, region)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get sales data from Excel: %w", err)
-		}
-
-		s.logger.Info("Sales data retrieved from Excel",
-			slog.Int("year", year),
-			slog.String("quarter", quarter),
-			slog.String("region", region),
-			slog.Int("count", len(salesData)),
-		)
-
-		return salesData, nil
-	}
-
-	// Используем обычные данные из основной таблицы
 	salesList, err := s.repo.GetWithDetailsByPeriod(ctx, quarter, year, region)
 	if err != nil {
 		s.logger.Error("SalesService.GetSalesByPeriod: failed to get sales data",
@@ -131,6 +85,8 @@ func (s *Service) GetSalesByID(ctx context.Context, id int64) (*model.Sales, err
 
 // CreateSales создает новую запись продаж.
 func (s *Service) CreateSales(ctx context.Context, sales *model.Sales) (int64, error) {
+	sales.Quarter = utils.NormalizeQuarter(sales.Quarter)
+
 	// Валидация
 	if err := s.validateSales(sales); err != nil {
 		return 0, fmt.Errorf("SalesService.CreateSales: validation failed: %w", err)
@@ -158,16 +114,17 @@ func (s *Service) CreateSales(ctx context.Context, sales *model.Sales) (int64, e
 }
 
 // UpdateSales обновляет данные продаж.
-func (s *Service) UpdateSales(ctx context.Context, id int64, updates map[string]interface{}) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) UpdateSales(ctx context.Context, id int64, version int, patch model.Patch) error {
 	if id <= 0 {
 		return fmt.Errorf("SalesService.UpdateSales: invalid ID: %d", id)
 	}
 
-	if len(updates) == 0 {
-		return fmt.Errorf("SalesService.UpdateSales: no fields to update")
+	if patch.IsEmpty() {
+		return fmt.Errorf("SalesService.UpdateSales: %w", model.ErrEmptyPatch)
 	}
 
-	err := s.repo.Update(ctx, id, updates)
+	err := s.repo.Update(ctx, id, version, patch)
 	if err != nil {
 		s.logger.Error("SalesService.UpdateSales: failed to update",
 			"id", id,
@@ -184,12 +141,13 @@ func (s *Service) UpdateSales(ctx context.Context, id int64, updates map[string]
 }
 
 // DeleteSales удаляет запись продаж.
-func (s *Service) DeleteSales(ctx context.Context, id int64) error {
+// version - ожидаемая версия записи, 0 - без проверки версии.
+func (s *Service) DeleteSales(ctx context.Context, id int64, version int) error {
 	if id <= 0 {
 		return fmt.Errorf("SalesService.DeleteSales: invalid ID: %d", id)
 	}
 
-	err := s.repo.Delete(ctx, id)
+	err := s.repo.Delete(ctx, id, version)
 	if err != nil {
 		s.logger.Error("SalesService.DeleteSales: failed to delete",
 			"id", id,
@@ -205,34 +163,128 @@ func (s *Service) DeleteSales(ctx context.Context, id int64) error {
 	return nil
 }
 
-// validateSales валидирует данные продаж.
-func (s *Service) validateSales(sales *model.Sales) error {
-	if sales.DealerID <= 0 {
-		return fmt.Errorf("dealer_id is required")
+// GetSalesByDealerAndPeriod возвращает запись продаж дилера за квартал.
+func (s *Service) GetSalesByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey) (*model.Sales, error) {
+	key.Quarter = utils.NormalizeQuarter(key.Quarter)
+
+	sales, err := s.repo.GetByDealerAndPeriod(ctx, int64(key.DealerID), key.Quarter, key.Year)
+	if err != nil {
+		return nil, fmt.Errorf("SalesService.GetSalesByDealerAndPeriod: %w", err)
 	}
 
-	// Валидация периода
-	if sales.Quarter == "" {
-		return fmt.Errorf("quarter is required")
+	return sales, nil
+}
+
+// ReplaceSales полностью заменяет запись продаж дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) ReplaceSales(ctx context.Context, key model.DealerPeriodKey, version int, sales *model.Sales) (*model.Sales, error) {
+	current, err := s.GetSalesByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("SalesService.ReplaceSales: %w", err)
 	}
-	if sales.Year == 0 {
-		return fmt.Errorf("year is required")
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("SalesService.ReplaceSales: %w", err)
 	}
 
-	// Валидация решения
-	if sales.SalesDecision != "" {
-		validDecisions := []string{"Planned Result", "Needs Development", "Find New Candidate", "Close Down"}
-		isValid := false
-		for _, decision := range validDecisions {
-			if sales.SalesDecision == decision {
-				isValid = true
-				break
-			}
-		}
-		if !isValid {
-			return fmt.Errorf("invalid sales_decision: %s", sales.SalesDecision)
+	// Ключ записи берется из пути, а не из тела запроса
+	sales.ID = current.ID
+	sales.DealerID = current.DealerID
+	sales.Quarter = current.Quarter
+	sales.Year = current.Year
+	sales.CreatedAt = current.CreatedAt
+	sales.Version = current.Version
+
+	if err := s.validateSales(sales); err != nil {
+		return nil, fmt.Errorf("SalesService.ReplaceSales: validation failed: %w", err)
+	}
+
+	if err := s.repo.UpdateFull(ctx, sales); err != nil {
+		s.logger.Error("SalesService.ReplaceSales: failed to update",
+			"id", sales.ID,
+			"error", err,
+		)
+		return nil, fmt.Errorf("SalesService.ReplaceSales: %w", err)
+	}
+
+	s.logger.Info("SalesService.ReplaceSales: successfully updated",
+		"id", sales.ID,
+	)
+
+	return sales, nil
+}
+
+// PatchSales частично обновляет запись продаж дилера за квартал.
+// Изменения проверяются схемой на уровне полей, итоговая запись - правилами validateSales.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) PatchSales(ctx context.Context, key model.DealerPeriodKey, version int, patch model.Patch) (*model.Sales, error) {
+	current, err := s.GetSalesByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("SalesService.PatchSales: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return nil, fmt.Errorf("SalesService.PatchSales: %w", err)
+	}
+
+	merged := *current
+	if err := patch.Apply(&merged); err != nil {
+		return nil, fmt.Errorf("SalesService.PatchSales: %w", err)
+	}
+
+	if err := s.validateSales(&merged); err != nil {
+		return nil, fmt.Errorf("SalesService.PatchSales: validation failed: %w", err)
+	}
+
+	if err := s.UpdateSales(ctx, int64(current.ID), current.Version, patch); err != nil {
+		return nil, fmt.Errorf("SalesService.PatchSales: %w", err)
+	}
+
+	return s.GetSalesByDealerAndPeriod(ctx, key)
+}
+
+// DeleteSalesByDealerAndPeriod удаляет запись продаж дилера за квартал.
+// Если версия задана и не совпадает с текущей, возвращается model.ErrVersionConflict.
+func (s *Service) DeleteSalesByDealerAndPeriod(ctx context.Context, key model.DealerPeriodKey, version int) error {
+	current, err := s.GetSalesByDealerAndPeriod(ctx, key)
+	if err != nil {
+		return fmt.Errorf("SalesService.DeleteSalesByDealerAndPeriod: %w", err)
+	}
+	if err := model.CheckVersion(version, current.Version); err != nil {
+		return fmt.Errorf("SalesService.DeleteSalesByDealerAndPeriod: %w", err)
+	}
+
+	return s.DeleteSales(ctx, int64(current.ID), current.Version)
+}
+
+// validateSales валидирует данные продаж и возвращает ошибки по полям.
+func (s *Service) validateSales(sales *model.Sales) error {
+	var errs model.ValidationErrors
+
+	// Валидация ключа записи
+	utils.ValidatePeriodKey(&errs, sales.DealerID, sales.Quarter, sales.Year)
+
+	// Валидация остатков и выкупа
+	for _, count := range []struct {
+		field string
+		value int
+	}{
+		{"stock_hdt", sales.StockHDT},
+		{"stock_mdt", sales.StockMDT},
+		{"stock_ldt", sales.StockLDT},
+		{"buyout_hdt", sales.BuyoutHDT},
+		{"buyout_mdt", sales.BuyoutMDT},
+		{"buyout_ldt", sales.BuyoutLDT},
+		{"foton_salesmen", sales.FotonSalesmen},
+		{"service_contracts_sales", sales.ServiceContractsSales},
+	} {
+		if count.value < 0 {
+			errs.Add(count.field, "cannot be negative")
 		}
 	}
 
-	return nil
+	// Валидация решения
+	if sales.SalesDecision != "" && !model.IsValidDecision(sales.SalesDecision) {
+		errs.Add("sales_decision", "invalid value: %s", sales.SalesDecision)
+	}
+
+	return errs.Err()
 }
@@ -2,13 +2,35 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/typefunco/dealer_dev_platform/internal/model"
+	"github.com/typefunco/dealer_dev_platform/internal/utils/passhash"
 )
 
+// ErrOwnStatus администратор не может приостановить или отключить собственную учетную запись.
+var ErrOwnStatus = errors.New("cannot suspend or deactivate your own account")
+
+// ErrProfileManagedExternally имя и почту пользователя LDAP или OIDC при каждом входе перезаписывает провайдер.
+var ErrProfileManagedExternally = errors.New("profile is managed by the identity provider")
+
+// emailRegex проверяет формат адреса почты.
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// maxNameLength длина колонок first_name и last_name.
+const maxNameLength = 100
+
+// maxStatusReasonLength наибольшая длина причины смены состояния.
+const maxStatusReasonLength = 500
+
+// deactivateReason причина по умолчанию, когда пользователя удаляют без указания причины.
+const deactivateReason = "deleted by administrator"
+
 // Repository интерфейс репозитория пользователей.
 type Repository interface {
 	GetUserByID(ctx context.Context, id int64) (*model.User, error)
@@ -16,7 +38,7 @@ type Repository interface {
 	GetUsers(ctx context.Context, filter model.UserFilter) ([]*model.User, error)
 	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
 	UpdateUser(ctx context.Context, id int64, update model.UserUpdate) (*model.User, error)
-	DeleteUser(ctx context.Context, id int64) error
+	SetUserStatus(ctx context.Context, id int64, change model.UserStatusChange, now time.Time) (*model.User, error)
 }
 
 // Service сервис для работы с пользователями.
@@ -65,6 +87,11 @@ func (s *Service) GetUserByLogin(ctx context.Context, login string) (*model.User
 
 // GetUsers возвращает список пользователей согласно фильтру.
 func (s *Service) GetUsers(ctx context.Context, filter model.UserFilter) ([]*model.UserResponse, error) {
+	if filter.Region != nil {
+		region := canonicalRegion(*filter.Region)
+		filter.Region = &region
+	}
+
 	users, err := s.repo.GetUsers(ctx, filter)
 	if err != nil {
 		s.logger.Error("UserService.GetUsers: failed to get users", "error", err)
@@ -82,16 +109,57 @@ func (s *Service) GetUsers(ctx context.Context, filter model.UserFilter) ([]*mod
 // CreateUser создает нового пользователя.
 func (s *Service) CreateUser(ctx context.Context, req model.UserCreateRequest) (*model.UserResponse, error) {
 	// Валидация
-	if err := s.validateCreateRequest(req); err != nil {
+	if err := s.validateCreateRequest(req, true); err != nil {
 		return nil, fmt.Errorf("UserService.CreateUser: validation failed: %w", err)
 	}
 
+	createdUser, err := s.createUser(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("UserService.CreateUser: %w", err)
+	}
+
+	s.logger.Info("UserService.CreateUser: user created successfully", "id", createdUser.ID, "login", createdUser.Login)
+	return s.toUserResponse(createdUser), nil
+}
+
+// InviteUser создает пользователя без пароля: пароль он задаст сам по ссылке из приглашения,
+// а до этого войти по паролю нельзя. Почта обязательна - на нее уходит приглашение.
+func (s *Service) InviteUser(ctx context.Context, req model.UserCreateRequest) (*model.UserResponse, error) {
+	req.Password = ""
+	if err := s.validateCreateRequest(req, false); err != nil {
+		return nil, fmt.Errorf("UserService.InviteUser: validation failed: %w", err)
+	}
+	if req.Email == "" {
+		return nil, fmt.Errorf("UserService.InviteUser: validation failed: email is required")
+	}
+
+	createdUser, err := s.createUser(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("UserService.InviteUser: %w", err)
+	}
+
+	s.logger.Info("UserService.InviteUser: user invited", "id", createdUser.ID, "login", createdUser.Login)
+	return s.toUserResponse(createdUser), nil
+}
+
+// createUser сохраняет проверенный запрос на создание пользователя.
+func (s *Service) createUser(ctx context.Context, req model.UserCreateRequest) (*model.User, error) {
+	// Приглашенный пользователь создается без пароля: пустое значение не принимает никакой пароль
+	var password string
+	if req.Password != "" {
+		hash, err := passhash.Hash(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		password = hash
+	}
+
 	user := &model.User{
 		Login:     req.Login,
-		Password:  req.Password, // В реальном приложении здесь должно быть хеширование пароля
+		Password:  password,
 		IsAdmin:   req.IsAdmin,
 		Role:      req.Role,
-		Region:    req.Region,
+		Region:    canonicalRegion(req.Region),
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Email:     req.Email,
@@ -99,12 +167,10 @@ func (s *Service) CreateUser(ctx context.Context, req model.UserCreateRequest) (
 
 	createdUser, err := s.repo.CreateUser(ctx, user)
 	if err != nil {
-		s.logger.Error("UserService.CreateUser: failed to create user", "login", req.Login, "error", err)
-		return nil, fmt.Errorf("UserService.CreateUser: %w", err)
+		s.logger.Error("UserService.createUser: failed to create user", "login", req.Login, "error", err)
+		return nil, err
 	}
-
-	s.logger.Info("UserService.CreateUser: user created successfully", "id", createdUser.ID, "login", createdUser.Login)
-	return s.toUserResponse(createdUser), nil
+	return createdUser, nil
 }
 
 // UpdateUser обновляет пользователя.
@@ -118,11 +184,18 @@ func (s *Service) UpdateUser(ctx context.Context, id int64, update model.UserUpd
 		return nil, fmt.Errorf("UserService.UpdateUser: validation failed: %w", err)
 	}
 
-	// Если обновляется пароль, здесь должно быть хеширование
-	// if update.Password != nil {
-	//     hashedPassword := hashPassword(*update.Password)
-	//     update.Password = &hashedPassword
-	// }
+	if update.Region != nil {
+		region := canonicalRegion(*update.Region)
+		update.Region = &region
+	}
+
+	if update.Password != nil {
+		hash, err := passhash.Hash(*update.Password)
+		if err != nil {
+			return nil, fmt.Errorf("UserService.UpdateUser: %w", err)
+		}
+		update.Password = &hash
+	}
 
 	updatedUser, err := s.repo.UpdateUser(ctx, id, update)
 	if err != nil {
@@ -134,32 +207,160 @@ func (s *Service) UpdateUser(ctx context.Context, id int64, update model.UserUpd
 	return s.toUserResponse(updatedUser), nil
 }
 
-// DeleteUser удаляет пользователя.
-func (s *Service) DeleteUser(ctx context.Context, id int64) error {
+// UpdateProfile меняет имя и почту пользователя с логином login по его собственному запросу.
+// expectedVersion - версия из If-Match; nil - без проверки.
+// Если почта занята другим пользователем, возвращается repository.ErrAlreadyExists.
+func (s *Service) UpdateProfile(ctx context.Context, login string, req model.ProfileUpdateRequest, expectedVersion *int) (*model.UserResponse, error) {
+	user, err := s.repo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("UserService.UpdateProfile: %w", err)
+	}
+	if user.AuthSource != model.AuthSourceLocal {
+		return nil, fmt.Errorf("UserService.UpdateProfile: %w", ErrProfileManagedExternally)
+	}
+
+	var fields model.ValidationErrors
+	update := model.UserUpdate{
+		ExpectedVersion: expectedVersion,
+		FirstName:       profileName(req.FirstName, "first_name", &fields),
+		LastName:        profileName(req.LastName, "last_name", &fields),
+	}
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if email != "" && !emailRegex.MatchString(email) {
+			fields.Add("email", "invalid email format")
+		}
+		update.Email = &email
+	}
+	if err := fields.Err(); err != nil {
+		return nil, fmt.Errorf("UserService.UpdateProfile: %w", err)
+	}
+
+	if update.FirstName == nil && update.LastName == nil && update.Email == nil {
+		return s.toUserResponse(user), nil
+	}
+
+	updated, err := s.repo.UpdateUser(ctx, user.ID, update)
+	if err != nil {
+		return nil, fmt.Errorf("UserService.UpdateProfile: %w", err)
+	}
+
+	s.logger.Info("UserService.UpdateProfile: profile updated", "login", login)
+	return s.toUserResponse(updated), nil
+}
+
+// ChangeStatus меняет состояние учетной записи пользователя id: приостанавливает, отключает
+// или возвращает в работу. Записи пользователя остаются; сессии приостановленного или
+// отключенного пользователя завершаются, вход и токены не принимаются.
+func (s *Service) ChangeStatus(ctx context.Context, id int64, change model.UserStatusChange) (*model.UserResponse, error) {
 	if id <= 0 {
-		return fmt.Errorf("UserService.DeleteUser: invalid user ID")
+		return nil, fmt.Errorf("UserService.ChangeStatus: invalid user ID")
 	}
 
-	err := s.repo.DeleteUser(ctx, id)
+	now := time.Now()
+	change.Reason = strings.TrimSpace(change.Reason)
+	if err := validateStatusChange(change, now); err != nil {
+		return nil, fmt.Errorf("UserService.ChangeStatus: %w", err)
+	}
+
+	if change.Status != model.UserStatusActive {
+		user, err := s.repo.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("UserService.ChangeStatus: %w", err)
+		}
+		if user.Login == change.ChangedBy {
+			return nil, fmt.Errorf("UserService.ChangeStatus: %w", ErrOwnStatus)
+		}
+	}
+
+	updated, err := s.repo.SetUserStatus(ctx, id, change, now)
 	if err != nil {
-		s.logger.Error("UserService.DeleteUser: failed to delete user", "id", id, "error", err)
-		return fmt.Errorf("UserService.DeleteUser: %w", err)
+		s.logger.Error("UserService.ChangeStatus: failed to change status", "id", id, "error", err)
+		return nil, fmt.Errorf("UserService.ChangeStatus: %w", err)
 	}
 
-	s.logger.Info("UserService.DeleteUser: user deleted successfully", "id", id)
-	return nil
+	s.logger.Info("UserService.ChangeStatus: status changed",
+		"id", id, "login", updated.Login, "status", change.Status, "changed_by", change.ChangedBy)
+	return s.toUserResponse(updated), nil
+}
+
+// DeactivateUser отключает пользователя вместо удаления. reason - причина; пусто - удален администратором.
+func (s *Service) DeactivateUser(ctx context.Context, id int64, reason, changedBy string) (*model.UserResponse, error) {
+	if strings.TrimSpace(reason) == "" {
+		reason = deactivateReason
+	}
+	return s.ChangeStatus(ctx, id, model.UserStatusChange{
+		Status:    model.UserStatusDeactivated,
+		Reason:    reason,
+		ChangedBy: changedBy,
+	})
+}
+
+// validateStatusChange проверяет смену состояния и возвращает все нарушения сразу.
+func validateStatusChange(change model.UserStatusChange, now time.Time) error {
+	var fields model.ValidationErrors
+	switch {
+	case !change.Status.Valid():
+		fields.Add("status", "must be one of %s, %s, %s", model.UserStatusActive, model.UserStatusSuspended, model.UserStatusDeactivated)
+	case change.Status == model.UserStatusActive:
+		if change.From != nil {
+			fields.Add("effective_from", "is not allowed when reactivating")
+		}
+	case change.Reason == "":
+		fields.Add("reason", "is required")
+	}
+	if len([]rune(change.Reason)) > maxStatusReasonLength {
+		fields.Add("reason", "must be at most %d characters", maxStatusReasonLength)
+	}
+	if change.Until != nil {
+		switch {
+		case change.Status != model.UserStatusSuspended:
+			fields.Add("effective_until", "is allowed only for suspension")
+		case change.From != nil && !change.Until.After(*change.From):
+			fields.Add("effective_until", "must be after effective_from")
+		case !change.Until.After(now):
+			fields.Add("effective_until", "must be in the future")
+		}
+	}
+	return fields.Err()
+}
+
+// profileName обрезает пробелы вокруг имени и проверяет длину; nil - поле не меняется.
+func profileName(value *string, field string, fields *model.ValidationErrors) *string {
+	if value == nil {
+		return nil
+	}
+	name := strings.TrimSpace(*value)
+	if len([]rune(name)) > maxNameLength {
+		fields.Add(field, "must be at most %d characters", maxNameLength)
+	}
+	return &name
+}
+
+// canonicalRegion приводит регион к названию из справочника, если он там есть.
+func canonicalRegion(region string) string {
+	if canonical, ok := model.Regions().Canonical(region); ok {
+		return canonical
+	}
+	return region
 }
 
 // validateCreateRequest валидирует запрос на создание пользователя.
-func (s *Service) validateCreateRequest(req model.UserCreateRequest) error {
+// requirePassword - false для приглашенного пользователя, который задаст пароль сам.
+func (s *Service) validateCreateRequest(req model.UserCreateRequest, requirePassword bool) error {
 	if req.Login == "" {
 		return fmt.Errorf("login is required")
 	}
-	if req.Password == "" {
-		return fmt.Errorf("password is required")
-	}
-	if len(req.Password) < 6 {
-		return fmt.Errorf("password must be at least 6 characters")
+	if requirePassword {
+		if req.Password == "" {
+			return fmt.Errorf("password is required")
+		}
+		if len(req.Password) < 6 {
+			return fmt.Errorf("password must be at least 6 characters")
+		}
+		if len(req.Password) > passhash.MaxLength {
+			return fmt.Errorf("password must be at most %d bytes", passhash.MaxLength)
+		}
 	}
 	if req.Role == "" {
 		return fmt.Errorf("role is required")
@@ -176,7 +377,6 @@ func (s *Service) validateCreateRequest(req model.UserCreateRequest) error {
 	}
 	// Проверка email формата
 	if req.Email != "" {
-		emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 		if !emailRegex.MatchString(req.Email) {
 			return fmt.Errorf("invalid email format")
 		}
@@ -192,6 +392,9 @@ func (s *Service) validateUpdateRequest(update model.UserUpdate) error {
 	if update.Password != nil && len(*update.Password) < 6 {
 		return fmt.Errorf("password must be at least 6 characters")
 	}
+	if update.Password != nil && len(*update.Password) > passhash.MaxLength {
+		return fmt.Errorf("password must be at most %d bytes", passhash.MaxLength)
+	}
 	if update.Role != nil {
 		validRoles := map[model.UserRole]bool{
 			model.UserRoleAdmin:   true,
@@ -209,14 +412,24 @@ func (s *Service) validateUpdateRequest(update model.UserUpdate) error {
 // toUserResponse преобразует User в UserResponse (без пароля).
 func (s *Service) toUserResponse(user *model.User) *model.UserResponse {
 	return &model.UserResponse{
-		ID:        user.ID,
-		Login:     user.Login,
-		IsAdmin:   user.IsAdmin,
-		Role:      user.Role,
-		Region:    user.Region,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
+		ID:          user.ID,
+		Login:       user.Login,
+		IsAdmin:     user.IsAdmin,
+		Role:        user.Role,
+		Region:      user.Region,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		AuthSource:  user.AuthSource,
+		LockedUntil: user.LockedUntil,
+		Version:     user.Version,
+		CreatedAt:   user.CreatedAt,
+
+		Status:          user.Status,
+		StatusReason:    user.StatusReason,
+		StatusFrom:      user.StatusFrom,
+		StatusUntil:     user.StatusUntil,
+		StatusChangedBy: user.StatusChangedBy,
+		StatusChangedAt: user.StatusChangedAt,
 	}
 }
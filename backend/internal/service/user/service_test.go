@@ -2,7 +2,9 @@ package user_test
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -248,7 +250,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 	})
 }
 
-func TestUserService_DeleteUser(t *testing.T) {
+func TestUserService_UpdateProfile(t *testing.T) {
 	// Настройка тестовой базы данных
 	testDB := testutil.SetupTestDB(t)
 	defer testDB.Cleanup(t)
@@ -260,37 +262,198 @@ func TestUserService_DeleteUser(t *testing.T) {
 
 	ctx := context.Background()
 
-	t.Run("successful deletion", func(t *testing.T) {
+	t.Run("successful update", func(t *testing.T) {
 		defer testDB.CleanupTable(t, "users")
 
-		// Создаем пользователя
-		createReq := model.UserCreateRequest{
-			Login:     "testuser",
-			Password:  "password123",
-			Email:     "test@example.com",
-			Role:      model.UserRoleAdmin,
-			FirstName: "Test",
-			LastName:  "User",
-		}
-		created, err := service.CreateUser(ctx, createReq)
+		created, err := service.CreateUser(ctx, model.UserCreateRequest{
+			Login:    "testuser",
+			Password: "password123",
+			Email:    "test@example.com",
+			Role:     model.UserRoleViewer,
+		})
 		require.NoError(t, err)
 
-		// Удаляем пользователя
-		err = service.DeleteUser(ctx, created.ID)
+		updated, err := service.UpdateProfile(ctx, created.Login, model.ProfileUpdateRequest{
+			FirstName: stringPtr("  Иван "),
+			Email:     stringPtr("ivan@example.com"),
+		}, &created.Version)
 		require.NoError(t, err)
+		assert.Equal(t, "Иван", updated.FirstName)
+		assert.Equal(t, "ivan@example.com", updated.Email)
+		assert.Equal(t, model.UserRoleViewer, updated.Role)
+	})
 
-		// Проверяем, что пользователь удален
-		_, err = service.GetUserByID(ctx, created.ID)
-		require.Error(t, err)
+	t.Run("email of another user is rejected", func(t *testing.T) {
+		defer testDB.CleanupTable(t, "users")
+
+		_, err := service.CreateUser(ctx, model.UserCreateRequest{
+			Login:    "admin",
+			Password: "password123",
+			Email:    "Admin@Example.com",
+			Role:     model.UserRoleAdmin,
+		})
+		require.NoError(t, err)
+
+		created, err := service.CreateUser(ctx, model.UserCreateRequest{
+			Login:    "testuser",
+			Password: "password123",
+			Role:     model.UserRoleViewer,
+		})
+		require.NoError(t, err)
+
+		_, err = service.UpdateProfile(ctx, created.Login, model.ProfileUpdateRequest{
+			Email: stringPtr("admin@example.com"),
+		}, nil)
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+		_, err = service.InviteUser(ctx, model.UserCreateRequest{
+			Login: "other",
+			Email: "ADMIN@example.com",
+			Role:  model.UserRoleViewer,
+		})
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
 	})
 
-	t.Run("delete non-existent user", func(t *testing.T) {
-		err := service.DeleteUser(ctx, 99999)
-		require.Error(t, err)
+	t.Run("validation errors are reported per field", func(t *testing.T) {
+		defer testDB.CleanupTable(t, "users")
+
+		created, err := service.CreateUser(ctx, model.UserCreateRequest{
+			Login:    "testuser",
+			Password: "password123",
+			Role:     model.UserRoleViewer,
+		})
+		require.NoError(t, err)
+
+		_, err = service.UpdateProfile(ctx, created.Login, model.ProfileUpdateRequest{
+			LastName: stringPtr(strings.Repeat("я", 101)),
+			Email:    stringPtr("not-an-email"),
+		}, nil)
+		var fields model.ValidationErrors
+		require.ErrorAs(t, err, &fields)
+		assert.Len(t, fields, 2)
+	})
+}
+
+func TestUserService_ChangeStatus(t *testing.T) {
+	// Настройка тестовой базы данных
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Cleanup(t)
+	testDB.RunMigrations(t)
+
+	logger := testutil.GetTestLogger()
+	repo := repository.NewUserRepository(testDB.Pool, logger)
+	service := user.NewService(repo, logger)
+
+	ctx := context.Background()
+
+	createUser := func(t *testing.T, login string) *model.UserResponse {
+		created, err := service.CreateUser(ctx, model.UserCreateRequest{
+			Login:    login,
+			Password: "password123",
+			Email:    login + "@example.com",
+			Role:     model.UserRoleSales,
+		})
+		require.NoError(t, err)
+		return created
+	}
+
+	t.Run("deactivation keeps the user", func(t *testing.T) {
+		defer testDB.CleanupTable(t, "users")
+		created := createUser(t, "testuser")
+		assert.Equal(t, model.UserStatusActive, created.Status)
+
+		deactivated, err := service.DeactivateUser(ctx, created.ID, "", "admin")
+		require.NoError(t, err)
+		assert.Equal(t, model.UserStatusDeactivated, deactivated.StatusAt(time.Now()))
+		assert.Equal(t, "deleted by administrator", deactivated.StatusReason)
+		assert.Equal(t, "admin", deactivated.StatusChangedBy)
+		assert.Greater(t, deactivated.Version, created.Version)
+
+		// Пользователь остается в базе, но не попадает в список действующих
+		retrieved, err := service.GetUserByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.UserStatusDeactivated, retrieved.Status)
+
+		active, err := service.GetUsers(ctx, model.UserFilter{Statuses: []model.UserStatus{model.UserStatusActive, model.UserStatusSuspended}})
+		require.NoError(t, err)
+		assert.Empty(t, active)
+
+		all, err := service.GetUsers(ctx, model.UserFilter{})
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("suspension with dates and reactivation", func(t *testing.T) {
+		defer testDB.CleanupTable(t, "users")
+		created := createUser(t, "testuser")
+
+		from := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		until := from.Add(7 * 24 * time.Hour)
+		suspended, err := service.ChangeStatus(ctx, created.ID, model.UserStatusChange{
+			Status:    model.UserStatusSuspended,
+			Reason:    "  vacation  ",
+			From:      &from,
+			Until:     &until,
+			ChangedBy: "admin",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "vacation", suspended.StatusReason)
+		assert.Equal(t, model.UserStatusActive, suspended.StatusAt(time.Now()), "suspension starts tomorrow")
+		assert.Equal(t, model.UserStatusSuspended, suspended.StatusAt(from.Add(time.Hour)))
+		assert.Equal(t, model.UserStatusActive, suspended.StatusAt(until))
+
+		scheduled, err := service.GetUsers(ctx, model.UserFilter{Statuses: []model.UserStatus{model.UserStatusSuspended}})
+		require.NoError(t, err)
+		assert.Empty(t, scheduled, "filter uses the status in effect now")
+
+		version := suspended.Version
+		reactivated, err := service.ChangeStatus(ctx, created.ID, model.UserStatusChange{
+			Status:          model.UserStatusActive,
+			ChangedBy:       "admin",
+			ExpectedVersion: &version,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.UserStatusActive, reactivated.Status)
+		assert.Nil(t, reactivated.StatusFrom)
+		assert.Nil(t, reactivated.StatusUntil)
+
+		_, err = service.ChangeStatus(ctx, created.ID, model.UserStatusChange{
+			Status:          model.UserStatusActive,
+			ExpectedVersion: &version,
+		})
+		assert.ErrorIs(t, err, model.ErrVersionConflict)
+	})
+
+	t.Run("invalid change", func(t *testing.T) {
+		defer testDB.CleanupTable(t, "users")
+		created := createUser(t, "testuser")
+
+		past := time.Now().Add(-time.Hour)
+		_, err := service.ChangeStatus(ctx, created.ID, model.UserStatusChange{Status: model.UserStatusDeactivated, Until: &past})
+		var fields model.ValidationErrors
+		require.ErrorAs(t, err, &fields)
+		assert.Equal(t, model.ValidationErrors{
+			{Field: "reason", Message: "is required"},
+			{Field: "effective_until", Message: "is allowed only for suspension"},
+		}, fields)
+
+		_, err = service.ChangeStatus(ctx, created.ID, model.UserStatusChange{Status: "deleted", Reason: "x"})
+		require.ErrorAs(t, err, &fields)
+
+		_, err = service.DeactivateUser(ctx, created.ID, "", "testuser")
+		assert.ErrorIs(t, err, user.ErrOwnStatus)
+	})
+
+	t.Run("non-existent user", func(t *testing.T) {
+		_, err := service.DeactivateUser(ctx, 99999, "", "admin")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+
+		_, err = service.ChangeStatus(ctx, 99999, model.UserStatusChange{Status: model.UserStatusActive})
+		assert.ErrorIs(t, err, repository.ErrNotFound)
 	})
 
 	t.Run("invalid ID", func(t *testing.T) {
-		err := service.DeleteUser(ctx, 0)
+		_, err := service.DeactivateUser(ctx, 0, "", "admin")
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid user ID")
 	})
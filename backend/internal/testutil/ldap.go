@@ -0,0 +1,131 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// TestLDAP представляет тестовый каталог OpenLDAP
+type TestLDAP struct {
+	Container     testcontainers.Container
+	URL           string
+	BaseDN        string
+	UsersDN       string
+	GroupsDN      string
+	AdminDN       string
+	AdminPassword string
+}
+
+// SetupTestLDAP запускает OpenLDAP контейнер с пустыми ветками пользователей и групп.
+// Без Docker тест пропускается.
+func SetupTestLDAP(t *testing.T) *TestLDAP {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+
+	tl := &TestLDAP{
+		BaseDN:        "dc=example,dc=org",
+		UsersDN:       "ou=users,dc=example,dc=org",
+		GroupsDN:      "ou=groups,dc=example,dc=org",
+		AdminDN:       "cn=admin,dc=example,dc=org",
+		AdminPassword: "adminpass",
+	}
+
+	// Создаем OpenLDAP контейнер
+	container, err := testcontainers.Run(ctx, "osixia/openldap:1.5.0",
+		testcontainers.WithEnv(map[string]string{
+			"LDAP_ORGANISATION":   "Dealer Platform",
+			"LDAP_DOMAIN":         "example.org",
+			"LDAP_ADMIN_PASSWORD": tl.AdminPassword,
+			"LDAP_TLS":            "false",
+		}),
+		testcontainers.WithExposedPorts("389/tcp"),
+		testcontainers.WithWaitStrategy(
+			wait.ForListeningPort("389/tcp").WithStartupTimeout(60*time.Second)),
+	)
+	require.NoError(t, err)
+	tl.Container = container
+
+	endpoint, err := container.PortEndpoint(ctx, "389/tcp", "ldap")
+	require.NoError(t, err)
+	tl.URL = endpoint
+
+	// Во время первичной настройки slapd перезапускается, поэтому ждем успешного входа
+	var conn *ldap.Conn
+	require.Eventually(t, func() bool {
+		conn, err = tl.connect()
+		return err == nil
+	}, 60*time.Second, 500*time.Millisecond, "OpenLDAP did not accept admin bind")
+	defer conn.Close()
+
+	for _, dn := range []string{tl.UsersDN, tl.GroupsDN} {
+		request := ldap.NewAddRequest(dn, nil)
+		request.Attribute("objectClass", []string{"organizationalUnit"})
+		require.NoError(t, conn.Add(request))
+	}
+
+	return tl
+}
+
+// AddUser добавляет пользователя в ветку пользователей и возвращает его DN
+func (tl *TestLDAP) AddUser(t *testing.T, uid, password, firstName, lastName, email string) string {
+	conn, err := tl.connect()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	dn := fmt.Sprintf("uid=%s,%s", ldap.EscapeDN(uid), tl.UsersDN)
+	request := ldap.NewAddRequest(dn, nil)
+	request.Attribute("objectClass", []string{"inetOrgPerson"})
+	request.Attribute("uid", []string{uid})
+	request.Attribute("cn", []string{firstName + " " + lastName})
+	request.Attribute("givenName", []string{firstName})
+	request.Attribute("sn", []string{lastName})
+	request.Attribute("mail", []string{email})
+	request.Attribute("userPassword", []string{password})
+	require.NoError(t, conn.Add(request))
+
+	return dn
+}
+
+// AddGroup добавляет группу groupOfNames с участниками и возвращает ее DN
+func (tl *TestLDAP) AddGroup(t *testing.T, name string, memberDNs ...string) string {
+	conn, err := tl.connect()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	dn := fmt.Sprintf("cn=%s,%s", ldap.EscapeDN(name), tl.GroupsDN)
+	request := ldap.NewAddRequest(dn, nil)
+	request.Attribute("objectClass", []string{"groupOfNames"})
+	request.Attribute("cn", []string{name})
+	request.Attribute("member", memberDNs)
+	require.NoError(t, conn.Add(request))
+
+	return dn
+}
+
+// Cleanup останавливает контейнер каталога
+func (tl *TestLDAP) Cleanup(t *testing.T) {
+	if tl.Container != nil {
+		err := tl.Container.Terminate(context.Background())
+		require.NoError(t, err)
+	}
+}
+
+// connect подключается к каталогу от имени администратора
+func (tl *TestLDAP) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(tl.URL)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(tl.AdminDN, tl.AdminPassword); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
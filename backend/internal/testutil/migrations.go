@@ -145,8 +145,9 @@ func (mf *MigrationFacade) applyMigration(ctx context.Context, pool *pgxpool.Poo
 		return fmt.Errorf("failed to read migration file: %w", err)
 	}
 
-	// Выполняем SQL
-	_, err = pool.Exec(ctx, string(content))
+	// Выполняем только секцию Up: секция Down откатила бы миграцию в том же запросе
+	up, _, _ := strings.Cut(string(content), "-- +goose Down")
+	_, err = pool.Exec(ctx, up)
 	if err != nil {
 		return fmt.Errorf("failed to execute migration SQL: %w", err)
 	}
@@ -140,3 +140,59 @@ func TestMigrationFacade_ExtractMigrationNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrationFacade_MigratesDealerNetTables(t *testing.T) {
+	ctx := context.Background()
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	// Квартал, загруженный до появления dealer_metrics, с колонкой вне словаря заголовков
+	_, err := testDB.Pool.Exec(ctx, `
+		CREATE TABLE dealer_net_2024_q1 (
+			id BIGSERIAL PRIMARY KEY,
+			dealer TEXT NOT NULL,
+			city TEXT,
+			joint_decision TEXT,
+			comment TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+		INSERT INTO dealer_net_2024_q1 (dealer, city, joint_decision, comment) VALUES
+			('Автоцентр', 'Москва', 'Planned Result', 'O''Brien'),
+			('', 'Казань', NULL, NULL);
+	`)
+	require.NoError(t, err)
+
+	migrationFacade := NewMigrationFacade("../../migrations", GetTestLogger())
+	require.NoError(t, migrationFacade.ApplyAllMigrations(ctx, testDB.Pool))
+
+	var (
+		period        time.Time
+		year          int
+		quarter       string
+		city          string
+		jointDecision string
+		comment       string
+	)
+	err = testDB.Pool.QueryRow(ctx, `
+		SELECT period, year, quarter, city, joint_decision, extra->>'comment'
+		FROM dealer_metrics WHERE dealer = 'Автоцентр'
+	`).Scan(&period, &year, &quarter, &city, &jointDecision, &comment)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01", period.Format("2006-01-02"))
+	assert.Equal(t, 2024, year)
+	assert.Equal(t, "Q1", quarter)
+	assert.Equal(t, "Москва", city)
+	assert.Equal(t, "Planned Result", jointDecision)
+	assert.Equal(t, "O'Brien", comment)
+
+	var rows int
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM dealer_metrics`).Scan(&rows))
+	assert.Equal(t, 1, rows, "rows without dealer are skipped")
+
+	var exists bool
+	err = testDB.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_schema = 'public' AND table_name = 'dealer_net_2024_q1')
+	`).Scan(&exists)
+	require.NoError(t, err)
+	assert.False(t, exists, "dealer_net table is dropped after migration")
+}
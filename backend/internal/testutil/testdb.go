@@ -23,7 +23,7 @@ type TestDB struct {
 }
 
 // SetupTestDB настраивает тестовую базу данных с PostgreSQL контейнером
-func SetupTestDB(t *testing.T) *TestDB {
+func SetupTestDB(t testing.TB) *TestDB {
 	ctx := context.Background()
 
 	// Создаем PostgreSQL контейнер
@@ -59,7 +59,7 @@ func SetupTestDB(t *testing.T) *TestDB {
 }
 
 // Cleanup очищает ресурсы тестовой базы данных
-func (tdb *TestDB) Cleanup(t *testing.T) {
+func (tdb *TestDB) Cleanup(t testing.TB) {
 	if tdb.Pool != nil {
 		tdb.Pool.Close()
 	}
@@ -70,7 +70,7 @@ func (tdb *TestDB) Cleanup(t *testing.T) {
 }
 
 // RunMigrations выполняет миграции базы данных автоматически
-func (tdb *TestDB) RunMigrations(t *testing.T) {
+func (tdb *TestDB) RunMigrations(t testing.TB) {
 	ctx := context.Background()
 	logger := GetTestLogger()
 
@@ -93,14 +93,14 @@ func GetTestLogger() *slog.Logger {
 }
 
 // CleanupTable очищает таблицу после теста
-func (tdb *TestDB) CleanupTable(t *testing.T, tableName string) {
+func (tdb *TestDB) CleanupTable(t testing.TB, tableName string) {
 	ctx := context.Background()
 	_, err := tdb.Pool.Exec(ctx, fmt.Sprintf("TRUNCATE TABLE %s CASCADE", tableName))
 	require.NoError(t, err)
 }
 
 // CleanupAllTables очищает все таблицы после теста автоматически
-func (tdb *TestDB) CleanupAllTables(t *testing.T) {
+func (tdb *TestDB) CleanupAllTables(t testing.TB) {
 	ctx := context.Background()
 
 	// Получаем список всех таблиц из базы данных
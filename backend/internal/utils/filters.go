@@ -12,9 +12,9 @@ import (
 // Универсальная функция для всех эндпоинтов.
 func ParseFilterParamsFromContext(c echo.Context) *model.FilterParams {
 	region := c.QueryParam("region")
-	// Применяем маппинг регионов
-	if mappedRegion := mapRegion(region); mappedRegion != "" {
-		region = mappedRegion
+	// Приводим регион к хранимому названию по справочнику
+	if canonical, ok := model.Regions().Canonical(region); ok {
+		region = canonical
 	}
 
 	filters := &model.FilterParams{
@@ -56,26 +56,6 @@ func ParseFilterParamsFromContext(c echo.Context) *model.FilterParams {
 	return filters
 }
 
-// mapRegion применяет маппинг регионов для совместимости с фронтендом
-func mapRegion(region string) string {
-	regionMapping := map[string]string{
-		"all":        "all-russia",
-		"central":    "Central",
-		"north-west": "North West",
-		"volga":      "Volga",
-		"south":      "South",
-		"n-caucasus": "Kavkaz",
-		"ural":       "Ural",
-		"siberia":    "Siberia",
-		"far-east":   "Far East",
-	}
-
-	if mapped, exists := regionMapping[region]; exists {
-		return mapped
-	}
-	return ""
-}
-
 // SetDefaultFilters устанавливает значения по умолчанию для фильтров.
 // Используется для обратной совместимости.
 func SetDefaultFilters(filters *model.FilterParams, defaults map[string]interface{}) {
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -27,18 +29,40 @@ func NewService() *Service {
 	return &Service{}
 }
 
+// GenerateJWT выпускает токен пользователя. Каждый токен получает свой ID (jti),
+// по которому сервер хранит и отзывает сессию.
 func (s *Service) GenerateJWT(login string, isAdmin bool, role string) (string, error) {
-	claims := JWTClaims{
+	claims, err := s.NewClaims(login, isAdmin, role)
+	if err != nil {
+		return "", err
+	}
+	return s.SignJWT(claims)
+}
+
+// NewClaims создает claims нового токена пользователя с собственным ID (jti) и сроком действия.
+// Токен из них получается через SignJWT.
+func (s *Service) NewClaims(login string, isAdmin bool, role string) (*JWTClaims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	claims := &JWTClaims{
 		Login:   login,
 		IsAdmin: isAdmin,
 		Role:    role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(id),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
+	return claims, nil
+}
 
+// SignJWT подписывает claims и возвращает токен.
+func (s *Service) SignJWT(claims *JWTClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(secretKey)
 	if err != nil {
@@ -0,0 +1,40 @@
+package passhash
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxLength максимальная длина пароля в байтах: bcrypt не учитывает байты после 72-го.
+const MaxLength = 72
+
+// Hash возвращает bcrypt-хэш пароля для хранения в users.password.
+func Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("passhash.Hash: %w", err)
+	}
+	return string(hash), nil
+}
+
+// IsHash сообщает, что сохраненный пароль уже хэширован bcrypt.
+func IsHash(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") || strings.HasPrefix(stored, "$2b$") || strings.HasPrefix(stored, "$2y$")
+}
+
+// Verify сравнивает пароль с сохраненным значением. Пароли, сохраненные до перехода на bcrypt,
+// хранятся открытым текстом и сравниваются за постоянное время; их нужно перехэшировать (IsHash).
+// Пустое сохраненное значение не принимает никакой пароль.
+func Verify(stored, password string) bool {
+	if stored == "" {
+		return false
+	}
+	if !IsHash(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/typefunco/dealer_dev_platform/internal/model"
+)
 
 // Validation utilities for the dealer development platform
 
@@ -25,20 +29,9 @@ func IsValidYear(year int) bool {
 	return year >= 2020 && year <= 2030
 }
 
-// IsValidRegion проверяет валидность региона.
+// IsValidRegion проверяет валидность региона по справочнику регионов.
 func IsValidRegion(region string) bool {
-	validRegions := map[string]bool{
-		"all-russia": true,
-		"Central":    true,
-		"North West": true,
-		"Volga":      true,
-		"South":      true,
-		"Kavkaz":     true,
-		"Ural":       true,
-		"Siberia":    true,
-		"Far East":   true,
-	}
-	return validRegions[region]
+	return model.Regions().IsValid(region)
 }
 
 // NormalizeQuarter нормализует квартал к верхнему регистру.
@@ -73,3 +66,22 @@ func ValidateFilters(quarter string, year int, region string) error {
 
 	return nil
 }
+
+// ValidatePeriodKey проверяет ключ записи показателей дилера и добавляет ошибки по полям.
+func ValidatePeriodKey(errs *model.ValidationErrors, dealerID int, quarter string, year int) {
+	if dealerID <= 0 {
+		errs.Add("dealer_id", "is required")
+	}
+
+	if quarter == "" {
+		errs.Add("quarter", "is required")
+	} else if !IsValidQuarter(quarter) {
+		errs.Add("quarter", "must be one of Q1, Q2, Q3, Q4")
+	}
+
+	if year == 0 {
+		errs.Add("year", "is required")
+	} else if !IsValidYear(year) {
+		errs.Add("year", "must be between 2020 and 2030")
+	}
+}
@@ -0,0 +1,66 @@
+-- +goose Up
+ALTER TABLE regions ADD COLUMN IF NOT EXISTS name_ru VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS region_aliases (
+    id BIGSERIAL PRIMARY KEY,
+    region_id BIGINT NOT NULL REFERENCES regions(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_region_aliases_alias ON region_aliases (LOWER(alias));
+CREATE INDEX IF NOT EXISTS idx_region_aliases_region_id ON region_aliases (region_id);
+
+-- Справочник регионов: код, английское название (хранится в данных) и русское название
+INSERT INTO regions (code, name, name_ru) VALUES
+('CENTRAL', 'Central', 'Центр'),
+('NORTH_WEST', 'North West', 'Северо-Запад'),
+('VOLGA', 'Volga', 'Поволжье'),
+('SOUTH', 'South', 'Юг'),
+('URAL', 'Ural', 'Урал'),
+('SIBERIA', 'Siberia', 'Сибирь'),
+('FAR_EAST', 'Far East', 'Дальний Восток'),
+('CAUCASUS', 'Caucasus', 'Кавказ')
+ON CONFLICT (code) DO UPDATE SET name_ru = EXCLUDED.name_ru;
+
+-- Алиасы для названий листов и параметров запросов
+INSERT INTO region_aliases (region_id, alias)
+SELECT r.id, a.alias
+FROM regions r
+JOIN (VALUES
+    ('CENTRAL', 'Center'),
+    ('CENTRAL', 'Центральный'),
+    ('NORTH_WEST', 'NW'),
+    ('VOLGA', 'Волга'),
+    ('FAR_EAST', 'FE'),
+    ('CAUCASUS', 'Kavkaz'),
+    ('CAUCASUS', 'N-Caucasus'),
+    ('CAUCASUS', 'Северный Кавказ')
+) AS a(code, alias) ON a.code = r.code
+ON CONFLICT DO NOTHING;
+
+-- Приводим уже сохраненные названия регионов к справочнику
+UPDATE dealers SET region = 'Caucasus' WHERE region = 'Kavkaz';
+UPDATE users SET region = 'Caucasus' WHERE region = 'Kavkaz';
+UPDATE users SET region = 'North West' WHERE region = 'North-West';
+
+-- +goose StatementBegin
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOR t IN
+        SELECT c.table_name
+        FROM information_schema.columns c
+        WHERE c.table_schema = 'public'
+          AND c.table_name LIKE 'dealer\_net\_%'
+          AND c.column_name = 'region'
+    LOOP
+        EXECUTE format('UPDATE %I SET region = %L WHERE region = %L', t, 'Caucasus', 'Kavkaz');
+    END LOOP;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS region_aliases;
+ALTER TABLE regions DROP COLUMN IF EXISTS name_ru;
@@ -0,0 +1,69 @@
+-- +goose Up
+-- Колонки продаж запчастей, зависящие от квартала, приводятся к периодо-независимым названиям
+-- +goose StatementBegin
+DO $$
+DECLARE
+    r RECORD;
+BEGIN
+    FOR r IN
+        SELECT c.table_name, c.column_name
+        FROM information_schema.columns c
+        WHERE c.table_schema = 'public'
+          AND c.table_name LIKE 'dealer\_net\_%'
+          AND c.column_name ~ '^spare_parts_sales_q[1-4]$'
+          AND NOT EXISTS (
+              SELECT 1 FROM information_schema.columns e
+              WHERE e.table_schema = 'public'
+                AND e.table_name = c.table_name
+                AND e.column_name = 'spare_parts_sales_quarter'
+          )
+    LOOP
+        EXECUTE format('ALTER TABLE %I RENAME COLUMN %I TO spare_parts_sales_quarter', r.table_name, r.column_name);
+    END LOOP;
+
+    FOR r IN
+        SELECT c.table_name
+        FROM information_schema.columns c
+        WHERE c.table_schema = 'public'
+          AND c.table_name LIKE 'dealer\_net\_%'
+          AND c.column_name = 'spare_parts_sales_ytd_percent'
+          AND NOT EXISTS (
+              SELECT 1 FROM information_schema.columns e
+              WHERE e.table_schema = 'public'
+                AND e.table_name = c.table_name
+                AND e.column_name = 'spare_parts_sales_ytd_pct'
+          )
+    LOOP
+        EXECUTE format('ALTER TABLE %I RENAME COLUMN spare_parts_sales_ytd_percent TO spare_parts_sales_ytd_pct', r.table_name);
+    END LOOP;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+DECLARE
+    r RECORD;
+BEGIN
+    FOR r IN
+        SELECT c.table_name
+        FROM information_schema.columns c
+        WHERE c.table_schema = 'public'
+          AND c.table_name ~ '^dealer_net_[0-9]{4}_q[1-4]$'
+          AND c.column_name = 'spare_parts_sales_quarter'
+    LOOP
+        EXECUTE format('ALTER TABLE %I RENAME COLUMN spare_parts_sales_quarter TO %I',
+            r.table_name, 'spare_parts_sales_' || right(r.table_name, 2));
+    END LOOP;
+
+    FOR r IN
+        SELECT c.table_name
+        FROM information_schema.columns c
+        WHERE c.table_schema = 'public'
+          AND c.table_name LIKE 'dealer\_net\_%'
+          AND c.column_name = 'spare_parts_sales_ytd_pct'
+    LOOP
+        EXECUTE format('ALTER TABLE %I RENAME COLUMN spare_parts_sales_ytd_pct TO spare_parts_sales_ytd_percent', r.table_name);
+    END LOOP;
+END $$;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Версия записи для оптимистичной блокировки: увеличивается при каждом изменении
+ALTER TABLE sales ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE dealer_dev ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE after_sales ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE performance ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Совместное решение по дилеру заполняется вручную через UI
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS joint_decision VARCHAR(50);
+
+-- +goose Down
+ALTER TABLE dealers DROP COLUMN IF EXISTS joint_decision;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
+ALTER TABLE dealers DROP COLUMN IF EXISTS version;
+ALTER TABLE performance DROP COLUMN IF EXISTS version;
+ALTER TABLE after_sales DROP COLUMN IF EXISTS version;
+ALTER TABLE dealer_dev DROP COLUMN IF EXISTS version;
+ALTER TABLE sales DROP COLUMN IF EXISTS version;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Показатели запчастей и доля часов Foton переносятся из dealer_net в after_sales как есть
+ALTER TABLE after_sales ADD COLUMN IF NOT EXISTS spare_parts_sales_quarter VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE after_sales ADD COLUMN IF NOT EXISTS spare_parts_sales_ytd_pct VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE after_sales ADD COLUMN IF NOT EXISTS foton_labour_hours_share VARCHAR(50) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE after_sales DROP COLUMN IF EXISTS foton_labour_hours_share;
+ALTER TABLE after_sales DROP COLUMN IF EXISTS spare_parts_sales_ytd_pct;
+ALTER TABLE after_sales DROP COLUMN IF EXISTS spare_parts_sales_quarter;
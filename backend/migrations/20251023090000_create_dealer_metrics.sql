@@ -0,0 +1,122 @@
+-- +goose Up
+-- Единая таблица показателей дилеров вместо таблиц dealer_net_<год>_<квартал>.
+-- Период (первый день квартала) - ключ партиционирования, каждая партиция хранит один квартал.
+CREATE TABLE IF NOT EXISTS dealer_metrics (
+    id BIGSERIAL,
+    period DATE NOT NULL,
+    year INTEGER NOT NULL,
+    quarter VARCHAR(2) NOT NULL,
+    dealer TEXT NOT NULL,
+    region TEXT,
+    city TEXT,
+    manager TEXT,
+    class TEXT,
+    check_list_percent TEXT,
+    marketing_investments TEXT,
+    branding TEXT,
+    dealer_development TEXT,
+    hdt TEXT,
+    mdt TEXT,
+    ldt TEXT,
+    hdt_2 TEXT,
+    mdt_2 TEXT,
+    ldt_2 TEXT,
+    sales TEXT,
+    service_contracts_sales TEXT,
+    spare_parts_sales_quarter TEXT,
+    spare_parts_sales_ytd_pct TEXT,
+    warranty_stock_percent TEXT,
+    recommended_stock_percent TEXT,
+    foton_labour_hours TEXT,
+    foton_labour_hours_share TEXT,
+    warranty_hours TEXT,
+    service_contracts_hours TEXT,
+    as_trainings TEXT,
+    aftersales TEXT,
+    joint_decision TEXT,
+    brands_in_portfolio TEXT,
+    byside_businesses TEXT,
+    extra JSONB NOT NULL DEFAULT '{}'::jsonb, -- Колонки загрузки, которых нет в словаре заголовков
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (period, id),
+    CONSTRAINT dealer_metrics_period_check CHECK (
+        period = date_trunc('quarter', period)::date
+        AND year = EXTRACT(YEAR FROM period)::integer
+        AND quarter = 'Q' || EXTRACT(QUARTER FROM period)::integer
+    )
+) PARTITION BY RANGE (period);
+
+CREATE INDEX IF NOT EXISTS idx_dealer_metrics_dealer ON dealer_metrics (period, LOWER(dealer), LOWER(COALESCE(city, '')));
+
+-- Партиция квартала создается при первой загрузке периода
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ensure_dealer_metrics_partition(p_period DATE) RETURNS VOID AS $$
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF dealer_metrics FOR VALUES FROM (%L) TO (%L)',
+        format('dealer_metrics_%s_q%s', EXTRACT(YEAR FROM p_period)::integer, EXTRACT(QUARTER FROM p_period)::integer),
+        p_period,
+        (p_period + INTERVAL '3 months')::date
+    );
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Переносим загруженные кварталы и удаляем таблицы dealer_net
+-- +goose StatementBegin
+DO $$
+DECLARE
+    r RECORD;
+    v_period DATE;
+BEGIN
+    FOR r IN
+        SELECT table_name,
+               substring(table_name FROM '^dealer_net_(\d{4})_q[1-4]$')::integer AS year,
+               substring(table_name FROM '_q([1-4])$')::integer AS quarter
+        FROM information_schema.tables
+        WHERE table_schema = 'public'
+          AND table_name ~ '^dealer_net_\d{4}_q[1-4]$'
+    LOOP
+        v_period := make_date(r.year, r.quarter * 3 - 2, 1);
+        PERFORM ensure_dealer_metrics_partition(v_period);
+
+        EXECUTE format(
+            'INSERT INTO dealer_metrics (period, year, quarter, dealer, region, city, manager, class, check_list_percent, marketing_investments, branding, dealer_development, hdt, mdt, ldt, hdt_2, mdt_2, ldt_2, sales, service_contracts_sales, spare_parts_sales_quarter, spare_parts_sales_ytd_pct, warranty_stock_percent, recommended_stock_percent, foton_labour_hours, foton_labour_hours_share, warranty_hours, service_contracts_hours, as_trainings, aftersales, joint_decision, brands_in_portfolio, byside_businesses, extra, created_at)
+             SELECT %L::date, %s, %L, j->>''dealer'', j->>''region'', j->>''city'', j->>''manager'', j->>''class'', j->>''check_list_percent'', j->>''marketing_investments'', j->>''branding'', j->>''dealer_development'', j->>''hdt'', j->>''mdt'', j->>''ldt'', j->>''hdt_2'', j->>''mdt_2'', j->>''ldt_2'', j->>''sales'', j->>''service_contracts_sales'', j->>''spare_parts_sales_quarter'', j->>''spare_parts_sales_ytd_pct'', j->>''warranty_stock_percent'', j->>''recommended_stock_percent'', j->>''foton_labour_hours'', j->>''foton_labour_hours_share'', j->>''warranty_hours'', j->>''service_contracts_hours'', j->>''as_trainings'', j->>''aftersales'', j->>''joint_decision'', j->>''brands_in_portfolio'', j->>''byside_businesses'',
+                    jsonb_strip_nulls(j - ARRAY[''id'', ''created_at'', ''dealer'', ''region'', ''city'', ''manager'', ''class'', ''check_list_percent'', ''marketing_investments'', ''branding'', ''dealer_development'', ''hdt'', ''mdt'', ''ldt'', ''hdt_2'', ''mdt_2'', ''ldt_2'', ''sales'', ''service_contracts_sales'', ''spare_parts_sales_quarter'', ''spare_parts_sales_ytd_pct'', ''warranty_stock_percent'', ''recommended_stock_percent'', ''foton_labour_hours'', ''foton_labour_hours_share'', ''warranty_hours'', ''service_contracts_hours'', ''as_trainings'', ''aftersales'', ''joint_decision'', ''brands_in_portfolio'', ''byside_businesses'']::text[]),
+                    COALESCE((j->>''created_at'')::timestamp, NOW())
+             FROM (SELECT to_jsonb(t) AS j FROM %I t) s
+             WHERE COALESCE(j->>''dealer'', '''') <> ''''
+             ORDER BY (j->>''id'')::bigint',
+            v_period, r.year, 'Q' || r.quarter, r.table_name
+        );
+
+        EXECUTE format('DROP TABLE %I', r.table_name);
+    END LOOP;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- Кварталы возвращаются в таблицы dealer_net; колонки из extra не восстанавливаются
+-- +goose StatementBegin
+DO $$
+DECLARE
+    r RECORD;
+    v_table TEXT;
+BEGIN
+    FOR r IN SELECT DISTINCT year, quarter FROM dealer_metrics
+    LOOP
+        v_table := format('dealer_net_%s_%s', r.year, lower(r.quarter));
+
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I (id BIGSERIAL PRIMARY KEY, dealer TEXT NOT NULL, region TEXT, city TEXT, manager TEXT, class TEXT, check_list_percent TEXT, marketing_investments TEXT, branding TEXT, dealer_development TEXT, hdt TEXT, mdt TEXT, ldt TEXT, hdt_2 TEXT, mdt_2 TEXT, ldt_2 TEXT, sales TEXT, service_contracts_sales TEXT, spare_parts_sales_quarter TEXT, spare_parts_sales_ytd_pct TEXT, warranty_stock_percent TEXT, recommended_stock_percent TEXT, foton_labour_hours TEXT, foton_labour_hours_share TEXT, warranty_hours TEXT, service_contracts_hours TEXT, as_trainings TEXT, aftersales TEXT, joint_decision TEXT, brands_in_portfolio TEXT, byside_businesses TEXT, created_at TIMESTAMP DEFAULT NOW())', v_table);
+        EXECUTE format(
+            'INSERT INTO %I (dealer, region, city, manager, class, check_list_percent, marketing_investments, branding, dealer_development, hdt, mdt, ldt, hdt_2, mdt_2, ldt_2, sales, service_contracts_sales, spare_parts_sales_quarter, spare_parts_sales_ytd_pct, warranty_stock_percent, recommended_stock_percent, foton_labour_hours, foton_labour_hours_share, warranty_hours, service_contracts_hours, as_trainings, aftersales, joint_decision, brands_in_portfolio, byside_businesses, created_at)
+             SELECT dealer, region, city, manager, class, check_list_percent, marketing_investments, branding, dealer_development, hdt, mdt, ldt, hdt_2, mdt_2, ldt_2, sales, service_contracts_sales, spare_parts_sales_quarter, spare_parts_sales_ytd_pct, warranty_stock_percent, recommended_stock_percent, foton_labour_hours, foton_labour_hours_share, warranty_hours, service_contracts_hours, as_trainings, aftersales, joint_decision, brands_in_portfolio, byside_businesses, created_at FROM dealer_metrics WHERE year = %s AND quarter = %L ORDER BY id',
+            v_table, r.year, r.quarter
+        );
+    END LOOP;
+END $$;
+-- +goose StatementEnd
+
+DROP FUNCTION IF EXISTS ensure_dealer_metrics_partition(DATE);
+DROP TABLE IF EXISTS dealer_metrics;
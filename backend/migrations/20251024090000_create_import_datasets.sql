@@ -0,0 +1,32 @@
+-- +goose Up
+-- Каталог загрузок Excel: админские операции адресуют загрузку по ID, а не по имени таблицы.
+CREATE TABLE IF NOT EXISTS import_datasets (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('dealer_net', 'brands')),
+    year INTEGER NOT NULL,
+    quarter VARCHAR(2) NOT NULL CHECK (quarter IN ('Q1', 'Q2', 'Q3', 'Q4')),
+    source_file TEXT NOT NULL,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    imported_by VARCHAR(100),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('completed', 'failed', 'superseded', 'deleted')),
+    schema JSONB NOT NULL DEFAULT '{}'::jsonb, -- Колонки загруженного файла и отчет по ним
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_datasets_period ON import_datasets (year, quarter, kind);
+
+-- В каждом квартале актуальна только одна загрузка данных квартала: файл dealer_net или пакет через API
+CREATE UNIQUE INDEX IF NOT EXISTS idx_import_datasets_current_period
+    ON import_datasets (year, quarter)
+    WHERE kind IN ('dealer_net', 'ingest') AND status = 'completed';
+
+-- Кварталы, загруженные до появления каталога
+INSERT INTO import_datasets (kind, year, quarter, source_file, row_count, status, created_at, updated_at)
+SELECT 'dealer_net', year, quarter, 'dealer_net_' || year || '_' || LOWER(quarter), COUNT(*), 'completed', MIN(created_at), MIN(created_at)
+FROM dealer_metrics
+GROUP BY year, quarter;
+
+-- +goose Down
+DROP TABLE IF EXISTS import_datasets;
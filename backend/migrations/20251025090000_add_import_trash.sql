@@ -0,0 +1,32 @@
+-- +goose Up
+-- Корзина загрузок: удаленный квартал переносится в схему archive и хранится до окончания срока хранения.
+CREATE SCHEMA IF NOT EXISTS archive;
+
+ALTER TABLE import_datasets
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(100);
+
+ALTER TABLE import_datasets DROP CONSTRAINT IF EXISTS import_datasets_status_check;
+ALTER TABLE import_datasets ADD CONSTRAINT import_datasets_status_check
+    CHECK (status IN ('completed', 'failed', 'superseded', 'deleted', 'purged'));
+
+-- Данные, удаленные до появления корзины, восстановить нельзя
+UPDATE import_datasets SET status = 'purged', updated_at = NOW() WHERE status = 'deleted';
+
+CREATE INDEX IF NOT EXISTS idx_import_datasets_trash ON import_datasets (deleted_at) WHERE status = 'deleted';
+
+-- +goose Down
+-- Архивы кварталов удаляются вместе со схемой archive
+UPDATE import_datasets SET status = 'deleted' WHERE status = 'purged';
+
+DROP INDEX IF EXISTS idx_import_datasets_trash;
+
+ALTER TABLE import_datasets DROP CONSTRAINT IF EXISTS import_datasets_status_check;
+ALTER TABLE import_datasets ADD CONSTRAINT import_datasets_status_check
+    CHECK (status IN ('completed', 'failed', 'superseded', 'deleted'));
+
+ALTER TABLE import_datasets
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+
+DROP SCHEMA IF EXISTS archive CASCADE;
@@ -0,0 +1,61 @@
+-- +goose Up
+-- Колонки сток/выкуп называются по группе заголовка (stock_hdt, buyout_hdt) вместо hdt и hdt_2.
+-- Архивы кварталов в корзине отсоединены от dealer_metrics, поэтому переименовываются отдельно.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    r RECORD;
+    c RECORD;
+BEGIN
+    FOR r IN
+        SELECT 'public' AS table_schema, 'dealer_metrics' AS table_name
+        UNION ALL
+        SELECT table_schema, table_name FROM information_schema.tables
+        WHERE table_schema = 'archive' AND table_name LIKE 'dealer\_metrics\_import\_%'
+    LOOP
+        FOR c IN
+            SELECT * FROM (VALUES
+                ('hdt', 'stock_hdt'), ('mdt', 'stock_mdt'), ('ldt', 'stock_ldt'),
+                ('hdt_2', 'buyout_hdt'), ('mdt_2', 'buyout_mdt'), ('ldt_2', 'buyout_ldt')
+            ) AS v(old_name, new_name)
+        LOOP
+            IF EXISTS (
+                SELECT 1 FROM information_schema.columns
+                WHERE table_schema = r.table_schema AND table_name = r.table_name AND column_name = c.old_name
+            ) THEN
+                EXECUTE format('ALTER TABLE %I.%I RENAME COLUMN %I TO %I', r.table_schema, r.table_name, c.old_name, c.new_name);
+            END IF;
+        END LOOP;
+    END LOOP;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+DECLARE
+    r RECORD;
+    c RECORD;
+BEGIN
+    FOR r IN
+        SELECT 'public' AS table_schema, 'dealer_metrics' AS table_name
+        UNION ALL
+        SELECT table_schema, table_name FROM information_schema.tables
+        WHERE table_schema = 'archive' AND table_name LIKE 'dealer\_metrics\_import\_%'
+    LOOP
+        FOR c IN
+            SELECT * FROM (VALUES
+                ('stock_hdt', 'hdt'), ('stock_mdt', 'mdt'), ('stock_ldt', 'ldt'),
+                ('buyout_hdt', 'hdt_2'), ('buyout_mdt', 'mdt_2'), ('buyout_ldt', 'ldt_2')
+            ) AS v(old_name, new_name)
+        LOOP
+            IF EXISTS (
+                SELECT 1 FROM information_schema.columns
+                WHERE table_schema = r.table_schema AND table_name = r.table_name AND column_name = c.old_name
+            ) THEN
+                EXECUTE format('ALTER TABLE %I.%I RENAME COLUMN %I TO %I', r.table_schema, r.table_name, c.old_name, c.new_name);
+            END IF;
+        END LOOP;
+    END LOOP;
+END $$;
+-- +goose StatementEnd
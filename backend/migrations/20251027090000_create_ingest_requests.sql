@@ -0,0 +1,26 @@
+-- +goose Up
+-- Загрузки показателей через API записываются в каталог отдельным типом
+ALTER TABLE import_datasets DROP CONSTRAINT IF EXISTS import_datasets_kind_check;
+ALTER TABLE import_datasets ADD CONSTRAINT import_datasets_kind_check
+    CHECK (kind IN ('dealer_net', 'brands', 'ingest'));
+
+-- Ответы на запросы загрузки через API по Idempotency-Key: повтор запроса возвращает сохраненный ответ
+CREATE TABLE IF NOT EXISTS ingest_requests (
+    owner VARCHAR(100) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL, -- SHA-256 тела запроса
+    response JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (owner, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingest_requests_created_at ON ingest_requests (created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS ingest_requests;
+
+DELETE FROM import_datasets WHERE kind = 'ingest';
+
+ALTER TABLE import_datasets DROP CONSTRAINT IF EXISTS import_datasets_kind_check;
+ALTER TABLE import_datasets ADD CONSTRAINT import_datasets_kind_check
+    CHECK (kind IN ('dealer_net', 'brands'));
@@ -0,0 +1,36 @@
+-- +goose Up
+-- Сервисные учетные записи интеграций: входят только по ключам API
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    role VARCHAR(50) NOT NULL DEFAULT 'viewer',
+    created_by VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Ключи API: хранится SHA-256 ключа, префикс открыт и уникален
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    service_account_id BIGINT NOT NULL REFERENCES service_accounts (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}', -- Адреса и подсети (CIDR), с которых разрешен вход
+    scopes TEXT[] NOT NULL DEFAULT '{}', -- Области доступа: ключ проходит только на маршруты с выданной областью
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45),
+    revoked_at TIMESTAMP,
+    revoked_by VARCHAR(100),
+    created_by VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_service_account ON api_keys (service_account_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS service_accounts;
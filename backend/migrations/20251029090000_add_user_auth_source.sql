@@ -0,0 +1,9 @@
+-- +goose Up
+-- Источник учетной записи: local - пароль хранит платформа, ldap - вход через Active Directory
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auth_source VARCHAR(20) NOT NULL DEFAULT 'local';
+-- DN записи в каталоге для пользователей, созданных при первом входе через LDAP
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(512);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS auth_source;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Одноразовые ссылки для установки пароля: приглашение нового пользователя и сброс забытого пароля.
+-- Хранится только SHA-256 токена, сам токен есть лишь в письме
+CREATE TABLE IF NOT EXISTS password_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL, -- invite или reset
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_by VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_tokens_user_id ON password_tokens (user_id, purpose);
+
+-- +goose Down
+DROP TABLE IF EXISTS password_tokens;
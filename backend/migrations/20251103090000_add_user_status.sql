@@ -0,0 +1,22 @@
+-- +goose Up
+-- Состояние учетной записи вместо удаления: записи, созданные пользователем, остаются
+-- со ссылкой на него. Приостановка и отключение действуют с status_from до status_until
+-- (NULL - без ограничения); вне этого интервала учетная запись активна.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'suspended', 'deactivated'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_from TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_by VARCHAR(100);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_status;
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_by;
+ALTER TABLE users DROP COLUMN IF EXISTS status_until;
+ALTER TABLE users DROP COLUMN IF EXISTS status_from;
+ALTER TABLE users DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS status;
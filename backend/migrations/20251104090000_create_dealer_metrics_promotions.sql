@@ -0,0 +1,12 @@
+-- +goose Up
+-- Кварталы dealer_metrics, перенесенные в нормализованные таблицы.
+-- Кварталы без записи (загруженные до появления переноса) переносятся при запуске сервера.
+CREATE TABLE IF NOT EXISTS dealer_metrics_promotions (
+    period DATE PRIMARY KEY,
+    year INTEGER NOT NULL,
+    quarter VARCHAR(2) NOT NULL,
+    promoted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS dealer_metrics_promotions;
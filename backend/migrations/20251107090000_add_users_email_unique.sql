@@ -0,0 +1,16 @@
+-- +goose Up
+-- Почта однозначно определяет пользователя: по ней приходят приглашения и сброс пароля.
+-- Если адрес уже записан у нескольких пользователей, он остается у самой ранней учетной записи,
+-- у остальных почта очищается и задается заново
+UPDATE users u
+SET email = NULL, updated_at = NOW()
+WHERE u.email IS NOT NULL AND u.email <> ''
+  AND EXISTS (
+    SELECT 1 FROM users o
+    WHERE LOWER(o.email) = LOWER(u.email) AND o.id < u.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email)) WHERE email <> '';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_email_lower;
@@ -40,13 +40,12 @@ func main() {
 
 	// Инициализируем репозитории
 	dealerRepo := repository.NewDealerRepository(pool)
-	excelDealerRepo := repository.NewExcelDealerRepository(pool, logger)
 	afterSalesRepo := repository.NewAfterSalesRepository(pool)
 	performanceRepo := repository.NewPerformanceRepository(pool)
 
 	// Инициализируем сервисы
-	dealerService := dealer.NewService(dealerRepo, excelDealerRepo, logger)
-	afterSalesService := aftersales.NewService(afterSalesRepo, excelDealerRepo, logger)
+	dealerService := dealer.NewService(dealerRepo, logger)
+	afterSalesService := aftersales.NewService(afterSalesRepo, logger)
 	performanceService := performance.NewService(performanceRepo, logger)
 
 	ctx := context.Background()
@@ -10,6 +10,8 @@ import DealerCard from './pages/DealerCard'
 import BrandDemo from './pages/BrandDemo'
 import Login from './pages/Login'
 import ForgotPassword from './pages/ForgotPassword'
+import SsoCallback from './pages/SsoCallback'
+import SetPassword from './pages/SetPassword'
 import Admin from './pages/Admin'
 import ExcelUploadPage from './pages/ExcelUpload'
 import ExcelTablesPage from './pages/ExcelTables'
@@ -188,7 +190,9 @@ const AppContent: React.FC = () => {
       {/* Routes */}
       <Routes>
         <Route path="/login" element={<Login />} />
+        <Route path="/login/sso" element={<SsoCallback />} />
         <Route path="/forgot-password" element={<ForgotPassword />} />
+        <Route path="/set-password" element={<SetPassword />} />
         
         {/* Protected Routes */}
         <Route path="/" element={
@@ -16,7 +16,7 @@ export interface AfterSalesDealer {
   asTrainings: boolean;
   csi: string;
   asDecision: 'Needs development' | 'Planned Result' | 'Find New Candidate' | 'Close Down';
-  sparePartsSalesQ3: string;
+  sparePartsSalesQuarter: string;
   sparePartsSalesYtd: string;
 }
 
@@ -14,6 +14,20 @@ export interface LoginResponse {
   }
 }
 
+// Ответ на вход, когда пароль верный, но нужен код второго фактора
+export interface TwoFactorChallenge {
+  two_factor_required: true
+  challenge_token: string
+  enrollment_required: boolean
+  expires_at: string
+}
+
+export interface TwoFactorEnrollment {
+  secret: string
+  provisioning_uri: string
+  qr_code: string
+}
+
 export interface User {
   login: string
   is_admin: boolean
@@ -25,7 +39,7 @@ const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || ''
 /**
  * Выполняет логин пользователя
  */
-export async function login(credentials: LoginRequest): Promise<LoginResponse> {
+export async function login(credentials: LoginRequest): Promise<LoginResponse | TwoFactorChallenge> {
   const response = await fetch(`${API_BASE_URL}/auth/login`, {
     method: 'POST',
     headers: {
@@ -51,10 +65,203 @@ export async function login(credentials: LoginRequest): Promise<LoginResponse> {
   return result
 }
 
+export function isTwoFactorChallenge(response: LoginResponse | TwoFactorChallenge): response is TwoFactorChallenge {
+  return 'two_factor_required' in response && response.two_factor_required
+}
+
+/**
+ * Завершает вход кодом из приложения-аутентификатора или кодом восстановления
+ */
+export async function completeTwoFactorLogin(
+  challengeToken: string,
+  code: string
+): Promise<LoginResponse & { recovery_codes?: string[] }> {
+  const response = await fetch(`${API_BASE_URL}/auth/login/2fa`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ challenge_token: challengeToken, code }),
+  })
+
+  const result = await response.json()
+  if (!response.ok) {
+    throw new Error(result.error || 'Verification failed')
+  }
+
+  localStorage.setItem('auth_token', result.token)
+  return result
+}
+
+/**
+ * Выдает секрет и QR-код для подключения второго фактора во время входа
+ */
+export async function startTwoFactorEnrollment(challengeToken: string): Promise<TwoFactorEnrollment> {
+  const response = await fetch(`${API_BASE_URL}/auth/login/2fa/enroll`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ challenge_token: challengeToken }),
+  })
+
+  const result = await response.json()
+  if (!response.ok) {
+    throw new Error(result.error || 'Enrollment failed')
+  }
+  return result
+}
+
+export interface AuthProviders {
+  password: boolean
+  oidc: boolean
+  password_reset: boolean // Доступен сброс пароля по почте
+}
+
+/**
+ * Возвращает доступные способы входа
+ */
+export async function getAuthProviders(): Promise<AuthProviders> {
+  const response = await fetch(`${API_BASE_URL}/auth/providers`)
+  if (!response.ok) {
+    return { password: true, oidc: false, password_reset: false }
+  }
+  return response.json()
+}
+
+export interface PasswordLink {
+  login: string
+  purpose: 'invite' | 'reset'
+  expires_at: string
+}
+
+/**
+ * Запрашивает письмо со ссылкой для сброса пароля.
+ * Ответ одинаковый, есть ли пользователь с таким логином или почтой
+ */
+export async function forgotPassword(login: string): Promise<void> {
+  const response = await fetch(`${API_BASE_URL}/auth/password/forgot`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ login }),
+  })
+
+  if (!response.ok) {
+    const error = await response.json()
+    throw new Error(error.error || 'Failed to request password reset')
+  }
+}
+
+/**
+ * Токен ссылки из письма: бэкенд передает его во фрагменте адреса /set-password#token=...
+ */
+export function getPasswordLinkToken(hash: string): string {
+  return new URLSearchParams(hash.replace(/^#/, '')).get('token') || ''
+}
+
+/**
+ * Проверяет ссылку из письма до ввода пароля
+ */
+export async function checkPasswordLink(token: string): Promise<PasswordLink> {
+  const response = await fetch(`${API_BASE_URL}/auth/password/check`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ token }),
+  })
+
+  const result = await response.json()
+  if (!response.ok) {
+    throw new Error(result.error || 'Link is invalid or expired')
+  }
+  return result
+}
+
+/**
+ * Задает пароль по ссылке из приглашения или письма о сбросе.
+ * Нарушения политики паролей приходят в поле fields ответа 422
+ */
+export async function resetPassword(token: string, newPassword: string): Promise<void> {
+  const response = await fetch(`${API_BASE_URL}/auth/password/reset`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ token, new_password: newPassword }),
+  })
+
+  if (!response.ok) {
+    const error = await response.json()
+    if (response.status === 422 && error.fields) {
+      throw new Error(error.fields.map((field: { message: string }) => field.message).join(', '))
+    }
+    throw new Error(error.error || 'Failed to set password')
+  }
+}
+
+/**
+ * Адрес входа через корпоративный портал (OIDC).
+ * После входа бэкенд возвращает пользователя на /login/sso с токеном во фрагменте адреса
+ */
+export function getOidcLoginUrl(redirect: string): string {
+  return `${API_BASE_URL}/auth/oidc/login?redirect=${encodeURIComponent(redirect)}`
+}
+
+/**
+ * Завершает вход через корпоративный портал: сохраняет токен из фрагмента адреса
+ * и возвращает страницу, на которую нужно перейти.
+ * Если требуется второй фактор, вместо токена возвращает challenge
+ */
+export function completeOidcLogin(hash: string): { redirect: string; challenge?: TwoFactorChallenge } {
+  const params = new URLSearchParams(hash.replace(/^#/, ''))
+  const error = params.get('error')
+  if (error) {
+    throw new Error(error)
+  }
+
+  const redirect = params.get('redirect') || '/'
+  const challengeToken = params.get('challenge_token')
+  if (challengeToken) {
+    return {
+      redirect,
+      challenge: {
+        two_factor_required: true,
+        challenge_token: challengeToken,
+        enrollment_required: params.get('enrollment_required') === 'true',
+        expires_at: params.get('expires_at') || '',
+      },
+    }
+  }
+
+  const token = params.get('token')
+  if (!token) {
+    throw new Error('Login failed')
+  }
+
+  localStorage.setItem('auth_token', token)
+  return { redirect }
+}
+
 /**
  * Выполняет логаут пользователя
  */
 export async function logout(): Promise<void> {
+  // Завершаем сессию на сервере, чтобы токен перестал приниматься
+  const token = getAuthToken()
+  if (token) {
+    try {
+      await fetch(`${API_BASE_URL}/api/me/sessions/current`, {
+        method: 'DELETE',
+        headers: { 'Authorization': `Bearer ${token}` },
+      })
+    } catch (err) {
+      console.error('Failed to revoke session:', err)
+    }
+  }
+
   // Удаляем токен из localStorage
   localStorage.removeItem('auth_token')
   console.log('Token removed from localStorage')
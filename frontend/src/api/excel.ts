@@ -6,6 +6,7 @@ export interface ExcelUploadResponse {
   tables_created: string[];
   rows_inserted: number;
   processing_time: number;
+  dataset_id?: number;
 }
 
 export interface BrandsUploadResponse {
@@ -14,16 +15,34 @@ export interface BrandsUploadResponse {
   updated_count: number;
   not_found_dealers: string[];
   processing_time: string;
+  dataset_id?: number;
 }
 
-export interface ExcelTableMetadata {
-  table_name: string;
-  rows_count: number;
+export type ImportKind = 'dealer_net' | 'brands' | 'ingest';
+
+export type ImportStatus = 'completed' | 'failed' | 'superseded' | 'deleted' | 'purged';
+
+export interface ImportDataset {
+  id: number;
+  kind: ImportKind;
+  year: number;
+  quarter: string;
+  source_file: string;
+  row_count: number;
+  imported_by?: string;
+  status: ImportStatus;
+  schema: {
+    columns: string[] | null;
+  };
+  error?: string;
+  deleted_at?: string;
+  deleted_by?: string;
+  purge_at?: string;
   created_at: string;
-  columns: string[];
+  updated_at: string;
 }
 
-export interface ExcelTableData {
+export interface ImportDatasetData {
   data: Record<string, any>[];
   pagination: {
     limit: number;
@@ -71,16 +90,11 @@ export const uploadExcelFile = async (file: File): Promise<ExcelUploadResponse>
   return response.json();
 };
 
-// Получение списка таблиц
-export const getExcelTables = async (): Promise<ExcelTableMetadata[]> => {
-  // Получаем токен из localStorage
-    
-    // Получаем токен из localStorage
-  
-  // Получаем токен из localStorage
+// Получение каталога загрузок
+export const getImportDatasets = async (): Promise<ImportDataset[]> => {
   const token = localStorage.getItem('auth_token');
   
-  const response = await fetch(`${API_BASE_URL}/api/admin/excel/tables`, {
+  const response = await fetch(`${API_BASE_URL}/api/admin/excel/imports`, {
     headers: {
         'Content-Type': 'application/json',
         ...(token && { 'Authorization': `Bearer ${token}` }),
@@ -88,22 +102,17 @@ export const getExcelTables = async (): Promise<ExcelTableMetadata[]> => {
   });
   
   if (!response.ok) {
-    throw new Error('Failed to fetch Excel tables');
+    throw new Error('Failed to fetch imports');
   }
 
   return response.json();
 };
 
-// Получение метаданных таблицы
-export const getExcelTableMetadata = async (tableName: string): Promise<ExcelTableMetadata> => {
-  // Получаем токен из localStorage
-    
-    // Получаем токен из localStorage
-  
-  // Получаем токен из localStorage
+// Получение записи каталога загрузок
+export const getImportDataset = async (id: number): Promise<ImportDataset> => {
   const token = localStorage.getItem('auth_token');
   
-  const response = await fetch(`${API_BASE_URL}/api/admin/excel/tables/${tableName}`, {
+  const response = await fetch(`${API_BASE_URL}/api/admin/excel/imports/${id}`, {
     headers: {
         'Content-Type': 'application/json',
         ...(token && { 'Authorization': `Bearer ${token}` }),
@@ -111,25 +120,22 @@ export const getExcelTableMetadata = async (tableName: string): Promise<ExcelTab
   });
   
   if (!response.ok) {
-    throw new Error('Failed to fetch table metadata');
+    throw new Error('Failed to fetch import');
   }
 
   return response.json();
 };
 
-// Получение данных таблицы
-export const getExcelTableData = async (
-  tableName: string, 
+// Получение данных загрузки
+export const getImportDatasetData = async (
+  id: number, 
   limit: number = 10, 
-  offset: number = 0
-): Promise<ExcelTableData> => {
-  // Получаем токен из localStorage
-  
-  // Получаем токен из localStorage
+  page: number = 1
+): Promise<ImportDatasetData> => {
   const token = localStorage.getItem('auth_token');
   
   const response = await fetch(
-    `${API_BASE_URL}/api/admin/excel/tables/${tableName}/data?limit=${limit}&offset=${offset}`,
+    `${API_BASE_URL}/api/admin/excel/imports/${id}/data?limit=${limit}&page=${page}`,
     {
       headers: {
         'Content-Type': 'application/json',
@@ -139,22 +145,35 @@ export const getExcelTableData = async (
   );
   
   if (!response.ok) {
-    throw new Error('Failed to fetch table data');
+    throw new Error('Failed to fetch import data');
   }
 
   return response.json();
 };
 
-// Удаление таблицы
-export const deleteExcelTable = async (tableName: string): Promise<void> => {
-  // Получаем токен из localStorage
-    
-    // Получаем токен из localStorage
+// Выгрузка квартала в XLSX в формате шаблона загрузки
+export const exportImportDataset = async (id: number): Promise<Blob> => {
+  const token = localStorage.getItem('auth_token');
   
-  // Получаем токен из localStorage
+  const response = await fetch(`${API_BASE_URL}/api/admin/excel/imports/${id}/export`, {
+    headers: {
+        ...(token && { 'Authorization': `Bearer ${token}` }),
+      }
+  });
+  
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({}));
+    throw new Error(error.error || 'Failed to export import');
+  }
+
+  return response.blob();
+};
+
+// Перенос загрузки в корзину
+export const deleteImportDataset = async (id: number): Promise<void> => {
   const token = localStorage.getItem('auth_token');
   
-  const response = await fetch(`${API_BASE_URL}/api/admin/excel/tables/${tableName}`, {
+  const response = await fetch(`${API_BASE_URL}/api/admin/excel/imports/${id}`, {
     method: 'DELETE',
     headers: {
         'Content-Type': 'application/json',
@@ -163,8 +182,29 @@ export const deleteExcelTable = async (tableName: string): Promise<void> => {
   });
   
   if (!response.ok) {
-    throw new Error('Failed to delete table');
+    const error = await response.json().catch(() => ({}));
+    throw new Error(error.error || 'Failed to delete import');
+  }
+};
+
+// Восстановление загрузки из корзины
+export const restoreImportDataset = async (id: number): Promise<ImportDataset> => {
+  const token = localStorage.getItem('auth_token');
+  
+  const response = await fetch(`${API_BASE_URL}/api/admin/excel/imports/${id}/restore`, {
+    method: 'POST',
+    headers: {
+        'Content-Type': 'application/json',
+        ...(token && { 'Authorization': `Bearer ${token}` }),
+      }
+  });
+  
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({}));
+    throw new Error(error.error || 'Failed to restore import');
   }
+
+  return response.json();
 };
 
 // Предварительный просмотр файла (парсинг названия файла)
@@ -0,0 +1,120 @@
+// API клиент для профиля, пароля и сессий текущего пользователя
+import { API_BASE_URL, apiRequest } from './index';
+
+export interface Me {
+  id: number;
+  login: string;
+  is_admin: boolean;
+  role: string;
+  region: string;
+  first_name: string;
+  last_name: string;
+  email: string;
+  auth_source: 'local' | 'ldap' | 'oidc';
+  version: number;
+  created_at: string;
+}
+
+export interface UpdateMeRequest {
+  first_name?: string;
+  last_name?: string;
+  email?: string;
+}
+
+export interface Session {
+  id: number;
+  ip?: string;
+  user_agent?: string;
+  created_at: string;
+  last_seen_at: string;
+  expires_at: string;
+  current: boolean;
+}
+
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+// Ошибка с описанием нарушенных требований по полям
+export class ValidationError extends Error {
+  constructor(message: string, public fields: FieldError[]) {
+    super(message);
+  }
+}
+
+export function getMe(): Promise<Me> {
+  return apiRequest<Me>('/me');
+}
+
+/**
+ * Обновить имя и почту. Профиль пользователей каталога и портала меняет только провайдер
+ */
+export async function updateMe(data: UpdateMeRequest, version?: number): Promise<Me> {
+  const response = await send('/me', 'PATCH', data, version ? { 'If-Match': `"${version}"` } : {});
+  if (response.status === 409) {
+    // 409 приходит и при устаревшей версии, и когда почта занята другим пользователем
+    const error = await response.json().catch(() => ({}));
+    if (!error.current && error.error) {
+      throw new Error(error.error);
+    }
+    throw new Error('Profile was modified in another session, reload and try again');
+  }
+  return parse<Me>(response, 'Failed to update profile');
+}
+
+/**
+ * Сменить пароль. Остальные сессии пользователя после смены завершаются
+ */
+export async function changePassword(currentPassword: string, newPassword: string): Promise<void> {
+  const response = await send('/me/password', 'POST', {
+    current_password: currentPassword,
+    new_password: newPassword,
+  });
+  if (!response.ok) {
+    await parse(response, 'Failed to change password');
+  }
+}
+
+export function getSessions(): Promise<Session[]> {
+  return apiRequest<Session[]>('/me/sessions');
+}
+
+export async function revokeSession(id: number): Promise<void> {
+  const response = await send(`/me/sessions/${id}`, 'DELETE');
+  if (!response.ok) {
+    await parse(response, 'Failed to revoke session');
+  }
+}
+
+/**
+ * Завершить все сессии, кроме текущей. Возвращает число завершенных сессий
+ */
+export async function revokeOtherSessions(): Promise<number> {
+  const result = await apiRequest<{ revoked: number }>('/me/sessions', { method: 'DELETE' });
+  return result.revoked;
+}
+
+function send(endpoint: string, method: string, body?: unknown, headers: Record<string, string> = {}): Promise<Response> {
+  const token = localStorage.getItem('auth_token');
+  return fetch(`${API_BASE_URL}/api${endpoint}`, {
+    method,
+    headers: {
+      'Content-Type': 'application/json',
+      ...(token && { 'Authorization': `Bearer ${token}` }),
+      ...headers,
+    },
+    ...(body !== undefined && { body: JSON.stringify(body) }),
+  });
+}
+
+async function parse<T>(response: Response, fallback: string): Promise<T> {
+  const result = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    if (Array.isArray(result.fields)) {
+      throw new ValidationError(result.error || fallback, result.fields);
+    }
+    throw new Error(result.error || fallback);
+  }
+  return result as T;
+}
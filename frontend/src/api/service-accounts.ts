@@ -0,0 +1,98 @@
+// API клиент для сервисных учетных записей и ключей API интеграций
+import { API_BASE_URL, apiRequest } from './index';
+
+export type ServiceAccountRole = 'admin' | 'manager' | 'sales' | 'viewer';
+
+export interface ServiceAccount {
+  id: number;
+  name: string;
+  description?: string;
+  is_admin: boolean;
+  role: ServiceAccountRole;
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateServiceAccountRequest {
+  name: string;
+  description?: string;
+  is_admin?: boolean;
+  role?: ServiceAccountRole;
+}
+
+// Области доступа ключа: ingest:write и imports:* выдаются только ключам учетных записей администраторов,
+// write - учетным записям с ролью manager или sales
+export type ApiKeyScope = 'read' | 'write' | 'ingest:write' | 'imports:read' | 'imports:write';
+
+export interface ApiKey {
+  id: number;
+  service_account_id: number;
+  name: string;
+  prefix: string;
+  allowed_ips?: string[];
+  scopes: ApiKeyScope[];
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+  revoked_at?: string;
+  revoked_by?: string;
+  created_by?: string;
+  created_at: string;
+}
+
+export interface CreateApiKeyRequest {
+  name: string;
+  scopes: ApiKeyScope[];
+  expires_at?: string;
+  allowed_ips?: string[];
+}
+
+// Значение ключа возвращается только при выпуске
+export interface CreatedApiKey extends ApiKey {
+  key: string;
+}
+
+export function getServiceAccounts(): Promise<ServiceAccount[]> {
+  return apiRequest<ServiceAccount[]>('/admin/service-accounts');
+}
+
+export function createServiceAccount(data: CreateServiceAccountRequest): Promise<ServiceAccount> {
+  return apiRequest<ServiceAccount>('/admin/service-accounts', {
+    method: 'POST',
+    body: JSON.stringify(data),
+  });
+}
+
+export async function deleteServiceAccount(id: number): Promise<void> {
+  const token = localStorage.getItem('auth_token');
+
+  const response = await fetch(`${API_BASE_URL}/api/admin/service-accounts/${id}`, {
+    method: 'DELETE',
+    headers: {
+      ...(token && { 'Authorization': `Bearer ${token}` }),
+    },
+  });
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Request failed' }));
+    throw new Error(error.error || `HTTP ${response.status}`);
+  }
+}
+
+export function getApiKeys(accountId: number): Promise<ApiKey[]> {
+  return apiRequest<ApiKey[]>(`/admin/service-accounts/${accountId}/keys`);
+}
+
+export function createApiKey(accountId: number, data: CreateApiKeyRequest): Promise<CreatedApiKey> {
+  return apiRequest<CreatedApiKey>(`/admin/service-accounts/${accountId}/keys`, {
+    method: 'POST',
+    body: JSON.stringify(data),
+  });
+}
+
+export function revokeApiKey(accountId: number, keyId: number): Promise<ApiKey> {
+  return apiRequest<ApiKey>(`/admin/service-accounts/${accountId}/keys/${keyId}/revoke`, {
+    method: 'POST',
+  });
+}
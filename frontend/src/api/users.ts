@@ -11,7 +11,24 @@ export interface User {
   region: string;
   position: string;
   createdAt: string;
-  status: 'active' | 'inactive';
+  status: UserStatus; // Действующее состояние учетной записи
+  lockedUntil?: string; // Вход временно заблокирован после неудачных попыток
+  version: number;
+  scheduledStatus?: UserStatus; // Приостановка или отключение с будущей даты statusFrom
+  statusReason?: string;
+  statusFrom?: string;
+  statusUntil?: string;
+  statusChangedBy?: string;
+  statusChangedAt?: string;
+}
+
+export type UserStatus = 'active' | 'suspended' | 'deactivated';
+
+export interface UserStatusChange {
+  status: UserStatus;
+  reason?: string; // Обязательна для приостановки и отключения
+  effective_from?: string; // ISO 8601; без даты - сразу
+  effective_until?: string; // Только для приостановки
 }
 
 export interface CreateUserRequest {
@@ -28,7 +45,6 @@ export interface UpdateUserRequest {
   lastName?: string;
   region?: string;
   position?: string;
-  status?: 'active' | 'inactive';
 }
 
 export interface UserCredentials {
@@ -66,6 +82,7 @@ export interface UserFilters {
   search?: string;
   region?: string;
   position?: string;
+  status?: UserStatus[] | 'all'; // По умолчанию действующие и приостановленные
   page?: number;
   limit?: number;
 }
@@ -79,6 +96,9 @@ export async function getUsers(filters?: UserFilters): Promise<UsersResponse> {
   if (filters?.search) params.append('search', filters.search);
   if (filters?.region) params.append('region', filters.region);
   if (filters?.position) params.append('position', filters.position);
+  if (filters?.status) {
+    params.append('status', Array.isArray(filters.status) ? filters.status.join(',') : filters.status);
+  }
   if (filters?.page) params.append('page', filters.page.toString());
   if (filters?.limit) params.append('limit', filters.limit.toString());
 
@@ -159,8 +179,9 @@ export async function createUser(data: CreateUserRequest): Promise<CreateUserRes
 
 /**
  * Обновить пользователя
+ * version - версия, на основе которой сделаны изменения; при конфликте сервер вернет 409
  */
-export async function updateUser(id: string, data: UpdateUserRequest): Promise<User> {
+export async function updateUser(id: string, data: UpdateUserRequest, version?: number): Promise<User> {
   // Получаем токен из localStorage
     
     // Получаем токен из localStorage
@@ -172,6 +193,7 @@ export async function updateUser(id: string, data: UpdateUserRequest): Promise<U
     method: 'PUT',
     headers: {
         'Content-Type': 'application/json',
+        'If-Match': version ? `"${version}"` : '*',
         ...(token && { 'Authorization': `Bearer ${token}` }),
       },
     body: JSON.stringify(data),
@@ -179,6 +201,9 @@ export async function updateUser(id: string, data: UpdateUserRequest): Promise<U
   
   if (!response.ok) {
     const error = await response.json();
+    if (response.status === 409 && error.current) {
+      throw new Error('User was modified by someone else, reload and try again');
+    }
     throw new Error(error.error || 'Failed to update user');
   }
   
@@ -186,9 +211,10 @@ export async function updateUser(id: string, data: UpdateUserRequest): Promise<U
 }
 
 /**
- * Удалить пользователя
+ * Удалить пользователя: учетная запись отключается, созданные пользователем записи остаются.
+ * Вернуть ее можно через reactivateUser
  */
-export async function deleteUser(id: string): Promise<void> {
+export async function deleteUser(id: string, reason?: string): Promise<void> {
   // Получаем токен из localStorage
     
     // Получаем токен из localStorage
@@ -196,7 +222,8 @@ export async function deleteUser(id: string): Promise<void> {
   // Получаем токен из localStorage
   const token = localStorage.getItem('auth_token');
   
-  const response = await fetch(`${API_BASE_URL}/api/users/${id}`, {
+  const query = reason ? `?reason=${encodeURIComponent(reason)}` : '';
+  const response = await fetch(`${API_BASE_URL}/api/admin/users/${id}${query}`, {
     method: 'DELETE',
     headers: {
         'Content-Type': 'application/json',
@@ -210,6 +237,129 @@ export async function deleteUser(id: string): Promise<void> {
   }
 }
 
+/**
+ * Приостановить, отключить или вернуть в работу учетную запись (только для администраторов).
+ * version - версия пользователя; при конфликте сервер вернет 409
+ */
+export async function changeUserStatus(id: string, change: UserStatusChange, version?: number): Promise<User> {
+  const token = localStorage.getItem('auth_token');
+
+  const response = await fetch(`${API_BASE_URL}/api/admin/users/${id}/status`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      ...(version && { 'If-Match': `"${version}"` }),
+      ...(token && { 'Authorization': `Bearer ${token}` }),
+    },
+    body: JSON.stringify(change),
+  });
+
+  const result = await response.json();
+  if (!response.ok) {
+    if (response.status === 409 && result.current) {
+      throw new Error('User was modified by someone else, reload and try again');
+    }
+    if (response.status === 422 && result.fields) {
+      throw new Error(result.fields.map((field: { field: string; message: string }) => `${field.field} ${field.message}`).join(', '));
+    }
+    throw new Error(result.error || 'Failed to change user status');
+  }
+
+  return result;
+}
+
+/**
+ * Вернуть приостановленную или отключенную учетную запись в работу
+ */
+export async function reactivateUser(id: string, reason?: string): Promise<User> {
+  const token = localStorage.getItem('auth_token');
+
+  const response = await fetch(`${API_BASE_URL}/api/admin/users/${id}/reactivate`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      ...(token && { 'Authorization': `Bearer ${token}` }),
+    },
+    body: JSON.stringify({ reason }),
+  });
+
+  const result = await response.json();
+  if (!response.ok) {
+    throw new Error(result.error || 'Failed to reactivate user');
+  }
+
+  return result;
+}
+
+/**
+ * Снять блокировку входа после неудачных попыток (только для администраторов)
+ */
+export async function unlockUser(id: string): Promise<void> {
+  const token = localStorage.getItem('auth_token');
+
+  const response = await fetch(`${API_BASE_URL}/api/admin/users/${id}/unlock`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      ...(token && { 'Authorization': `Bearer ${token}` }),
+    }
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(error.error || 'Failed to unlock user');
+  }
+}
+
+export interface InviteUserResponse {
+  user: User;
+  invitationSent: boolean; // false - пользователь создан, но письмо не ушло; можно отправить повторно
+}
+
+/**
+ * Пригласить пользователя по почте: пароль он задаст сам по ссылке из письма.
+ * Если отправка писем не настроена, сервер вернет 404
+ */
+export async function inviteUser(data: CreateUserRequest): Promise<InviteUserResponse> {
+  const token = localStorage.getItem('auth_token');
+
+  const response = await fetch(`${API_BASE_URL}/api/admin/users/invite`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      ...(token && { 'Authorization': `Bearer ${token}` }),
+    },
+    body: JSON.stringify(data),
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(error.error || 'Failed to invite user');
+  }
+
+  return response.json();
+}
+
+/**
+ * Отправить приглашение повторно; прежняя ссылка перестает действовать
+ */
+export async function resendInvitation(id: string): Promise<void> {
+  const token = localStorage.getItem('auth_token');
+
+  const response = await fetch(`${API_BASE_URL}/api/admin/users/${id}/invite`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      ...(token && { 'Authorization': `Bearer ${token}` }),
+    }
+  });
+
+  if (!response.ok) {
+    const error = await response.json();
+    throw new Error(error.error || 'Failed to send invitation');
+  }
+}
+
 /**
  * Получить статистику пользователей
  */
@@ -0,0 +1,135 @@
+import React, { useEffect, useState } from 'react'
+import { useAuth } from '../contexts/AuthContext'
+import { startTwoFactorEnrollment, TwoFactorChallenge, TwoFactorEnrollment } from '../api/auth'
+
+interface TwoFactorStepProps {
+  challenge: TwoFactorChallenge
+  onComplete: () => void
+  onCancel: () => void
+}
+
+// Второй шаг входа: код из приложения-аутентификатора или код восстановления.
+// Если политика требует второй фактор, а он не подключен, сначала показывается QR-код для подключения
+const TwoFactorStep: React.FC<TwoFactorStepProps> = ({ challenge, onComplete, onCancel }) => {
+  const { completeTwoFactor, error, clearError } = useAuth()
+  const [code, setCode] = useState('')
+  const [isLoading, setIsLoading] = useState(false)
+  const [enrollment, setEnrollment] = useState<TwoFactorEnrollment | null>(null)
+  const [enrollmentError, setEnrollmentError] = useState<string | null>(null)
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null)
+
+  useEffect(() => {
+    if (!challenge.enrollment_required) return
+    startTwoFactorEnrollment(challenge.challenge_token)
+      .then(setEnrollment)
+      .catch(err => setEnrollmentError(err instanceof Error ? err.message : 'Enrollment failed'))
+  }, [challenge])
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    setIsLoading(true)
+    try {
+      const result = await completeTwoFactor(challenge.challenge_token, code)
+      if (result.recoveryCodes && result.recoveryCodes.length > 0) {
+        // Коды восстановления показываются один раз: ждем, пока пользователь их сохранит
+        setRecoveryCodes(result.recoveryCodes)
+      } else {
+        onComplete()
+      }
+    } catch (err) {
+      // Ошибка уже обработана в хуке useAuth
+      console.error('Two-factor verification failed:', err)
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  if (recoveryCodes) {
+    return (
+      <div className="space-y-6">
+        <div className="text-center">
+          <h2 className="text-xl font-bold text-gray-900 mb-2">Save your recovery codes</h2>
+          <p className="text-sm text-gray-600">
+            Each code can be used once if you lose access to your authenticator app. They will not be shown again.
+          </p>
+        </div>
+        <div className="grid grid-cols-2 gap-2 bg-gray-50 rounded-xl p-4 font-mono text-sm text-gray-800">
+          {recoveryCodes.map(recoveryCode => (
+            <span key={recoveryCode}>{recoveryCode}</span>
+          ))}
+        </div>
+        <button
+          type="button"
+          onClick={onComplete}
+          className="w-full flex justify-center py-3 px-4 border border-transparent rounded-xl shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200"
+        >
+          I have saved the codes
+        </button>
+      </div>
+    )
+  }
+
+  return (
+    <form onSubmit={handleSubmit} className="space-y-6">
+      <div className="text-center">
+        <h2 className="text-xl font-bold text-gray-900 mb-2">Two-factor authentication</h2>
+        <p className="text-sm text-gray-600">
+          {challenge.enrollment_required
+            ? 'Administrators must use an authenticator app. Scan the QR code and enter the 6-digit code.'
+            : 'Enter the 6-digit code from your authenticator app or one of your recovery codes.'}
+        </p>
+      </div>
+
+      {(error || enrollmentError) && (
+        <div className="bg-red-100 border border-red-400 text-red-700 px-4 py-3 rounded-lg">
+          {error || enrollmentError}
+        </div>
+      )}
+
+      {enrollment && (
+        <div className="flex flex-col items-center space-y-2">
+          <img src={enrollment.qr_code} alt="Authenticator QR code" className="w-48 h-48" />
+          <p className="text-xs text-gray-500">Or enter the key manually:</p>
+          <code className="text-sm font-mono text-gray-800 break-all text-center">{enrollment.secret}</code>
+        </div>
+      )}
+
+      <input
+        id="code"
+        name="code"
+        type="text"
+        inputMode="numeric"
+        autoComplete="one-time-code"
+        autoFocus
+        required
+        value={code}
+        onChange={e => {
+          setCode(e.target.value)
+          if (error) clearError()
+        }}
+        className="block w-full px-3 py-3 border border-gray-300 rounded-xl text-center tracking-widest focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent transition-all duration-200"
+        placeholder="123456"
+      />
+
+      <button
+        type="submit"
+        disabled={isLoading || (challenge.enrollment_required && !enrollment)}
+        className="w-full flex justify-center py-3 px-4 border border-transparent rounded-xl shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200 disabled:opacity-50 disabled:cursor-not-allowed"
+      >
+        {isLoading ? 'Проверка...' : 'Verify'}
+      </button>
+
+      <div className="text-center">
+        <button
+          type="button"
+          onClick={onCancel}
+          className="text-sm font-medium text-blue-600 hover:text-blue-500 transition-colors duration-200"
+        >
+          Back to Sign In
+        </button>
+      </div>
+    </form>
+  )
+}
+
+export default TwoFactorStep
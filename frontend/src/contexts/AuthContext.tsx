@@ -1,12 +1,22 @@
 import { createContext, useContext, useState, useEffect, useCallback, ReactNode } from 'react'
-import { login as apiLogin, logout as apiLogout, isAuthenticated, getUserFromToken, User } from '../api/auth'
+import {
+  login as apiLogin,
+  logout as apiLogout,
+  completeTwoFactorLogin,
+  isAuthenticated,
+  isTwoFactorChallenge,
+  getUserFromToken,
+  TwoFactorChallenge,
+  User,
+} from '../api/auth'
 
 interface AuthContextType {
   user: User | null
   isAuthenticated: boolean
   isAdmin: boolean
   isLoading: boolean
-  login: (credentials: { login: string; password: string }) => Promise<User>
+  login: (credentials: { login: string; password: string }) => Promise<User | TwoFactorChallenge>
+  completeTwoFactor: (challengeToken: string, code: string) => Promise<{ user: User; recoveryCodes?: string[] }>
   logout: () => Promise<void>
   error: string | null
   clearError: () => void
@@ -58,7 +68,7 @@ export function AuthProvider({ children }: AuthProviderProps) {
   }, [])
 
   // Функция логина
-  const handleLogin = useCallback(async (credentials: { login: string; password: string }): Promise<User> => {
+  const handleLogin = useCallback(async (credentials: { login: string; password: string }): Promise<User | TwoFactorChallenge> => {
     try {
       setIsLoading(true)
       setError(null)
@@ -66,6 +76,12 @@ export function AuthProvider({ children }: AuthProviderProps) {
       console.log('Starting login process...')
       const response = await apiLogin(credentials)
       console.log('Login response:', response)
+
+      // Пароль верный, но токен выдается только после кода второго фактора
+      if (isTwoFactorChallenge(response)) {
+        setIsLoading(false)
+        return response
+      }
       
       // Обновляем состояние пользователя
       setUser(response.user)
@@ -87,6 +103,19 @@ export function AuthProvider({ children }: AuthProviderProps) {
     }
   }, [])
 
+  // Второй шаг входа: код из приложения или код восстановления
+  const handleCompleteTwoFactor = useCallback(async (challengeToken: string, code: string) => {
+    try {
+      setError(null)
+      const response = await completeTwoFactorLogin(challengeToken, code)
+      setUser(response.user)
+      return { user: response.user, recoveryCodes: response.recovery_codes }
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Verification failed')
+      throw err
+    }
+  }, [])
+
   // Функция логаута
   const handleLogout = useCallback(async () => {
     try {
@@ -110,6 +139,7 @@ export function AuthProvider({ children }: AuthProviderProps) {
     isAdmin: user?.is_admin || false,
     isLoading,
     login: handleLogin,
+    completeTwoFactor: handleCompleteTwoFactor,
     logout: handleLogout,
     error,
     clearError,
@@ -2,13 +2,14 @@ import { useState, useCallback } from 'react';
 import { 
   uploadExcelFile, 
   uploadBrandsFile,
-  getExcelTables, 
-  getExcelTableData, 
-  deleteExcelTable,
+  getImportDatasets, 
+  getImportDatasetData, 
+  deleteImportDataset,
+  restoreImportDataset,
   ExcelUploadResponse,
   BrandsUploadResponse,
-  ExcelTableMetadata,
-  ExcelTableData,
+  ImportDataset,
+  ImportDatasetData,
   ExcelFilePreview,
   parseFileName
 } from '../api/excel';
@@ -113,63 +114,73 @@ export const useExcelUpload = () => {
   };
 };
 
-export const useExcelTables = () => {
-  const [tables, setTables] = useState<ExcelTableMetadata[]>([]);
+export const useImportDatasets = () => {
+  const [datasets, setDatasets] = useState<ImportDataset[]>([]);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
 
-  const fetchTables = useCallback(async () => {
+  const fetchDatasets = useCallback(async () => {
     setLoading(true);
     setError(null);
     
     try {
-      const data = await getExcelTables();
-      setTables(data);
+      const data = await getImportDatasets();
+      setDatasets(data ?? []);
     } catch (err) {
-      setError(err instanceof Error ? err.message : 'Failed to fetch tables');
+      setError(err instanceof Error ? err.message : 'Failed to fetch imports');
     } finally {
       setLoading(false);
     }
   }, []);
 
-  const deleteTable = useCallback(async (tableName: string) => {
+  const deleteDataset = useCallback(async (id: number) => {
     try {
-      await deleteExcelTable(tableName);
-      await fetchTables(); // Обновляем список
+      await deleteImportDataset(id);
+      await fetchDatasets(); // Обновляем список
     } catch (err) {
-      setError(err instanceof Error ? err.message : 'Failed to delete table');
+      setError(err instanceof Error ? err.message : 'Failed to delete import');
     }
-  }, [fetchTables]);
+  }, [fetchDatasets]);
+
+  const restoreDataset = useCallback(async (id: number) => {
+    try {
+      await restoreImportDataset(id);
+      await fetchDatasets(); // Обновляем список
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to restore import');
+    }
+  }, [fetchDatasets]);
 
   return {
-    tables,
+    datasets,
     loading,
     error,
-    fetchTables,
-    deleteTable,
+    fetchDatasets,
+    deleteDataset,
+    restoreDataset,
   };
 };
 
-export const useExcelTableData = (tableName: string | null) => {
-  const [data, setData] = useState<ExcelTableData | null>(null);
+export const useImportDatasetData = (id: number | null) => {
+  const [data, setData] = useState<ImportDatasetData | null>(null);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
 
-  const fetchData = useCallback(async (limit: number = 10, offset: number = 0) => {
-    if (!tableName) return;
+  const fetchData = useCallback(async (limit: number = 10, page: number = 1) => {
+    if (id === null) return;
     
     setLoading(true);
     setError(null);
     
     try {
-      const result = await getExcelTableData(tableName, limit, offset);
+      const result = await getImportDatasetData(id, limit, page);
       setData(result);
     } catch (err) {
-      setError(err instanceof Error ? err.message : 'Failed to fetch table data');
+      setError(err instanceof Error ? err.message : 'Failed to fetch import data');
     } finally {
       setLoading(false);
     }
-  }, [tableName]);
+  }, [id]);
 
   return {
     data,
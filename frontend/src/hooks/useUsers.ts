@@ -68,7 +68,8 @@ export function useUsers(options: UseUsersOptions = {}) {
       setLoading(true);
       setError(null);
       
-      await userApi.updateUser(id, data);
+      const version = users.find((user) => user.id === id)?.version;
+      await userApi.updateUser(id, data, version);
       
       // Перезагрузить список пользователей
       await loadUsers();
@@ -79,7 +80,7 @@ export function useUsers(options: UseUsersOptions = {}) {
     } finally {
       setLoading(false);
     }
-  }, [loadUsers]);
+  }, [users, loadUsers]);
 
   // Удаление пользователя
   const deleteUser = useCallback(async (id: string) => {
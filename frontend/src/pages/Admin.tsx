@@ -476,7 +476,7 @@ const Admin: React.FC = () => {
               <div>
                 <h5 className="text-sm font-medium text-blue-800 mb-1">Excel Processing Information</h5>
                 <ul className="text-xs text-blue-700 space-y-1">
-                  <li>• Supported formats: .xlsx, .xls</li>
+                  <li>• Supported formats: .xlsx, .ods, .csv, .zip (one CSV per region with optional manifest.json)</li>
                   <li>• Files are processed into unified tables by quarter and year</li>
                   <li>• Data starts from row 3 (row 2 contains headers)</li>
                   <li>• Region information is extracted from sheet names</li>
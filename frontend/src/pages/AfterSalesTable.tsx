@@ -315,11 +315,11 @@ const AfterSalesTable: React.FC = () => {
               </th>
               <th 
                 className="px-6 py-4 text-center text-sm font-bold text-white uppercase tracking-wider cursor-pointer hover:bg-blue-800 hover:bg-opacity-30 transition-colors duration-200"
-                onClick={() => handleSort('sparePartsSalesQ3')}
+                onClick={() => handleSort('sparePartsSalesQuarter')}
               >
                 <div className="flex items-center justify-center space-x-1">
-                  <span>Spare Parts Sales Q3</span>
-                  {getSortIcon('sparePartsSalesQ3')}
+                  <span>Spare Parts Sales {quarterFromUrl || navigationFilters.quarter || 'Quarter'}</span>
+                  {getSortIcon('sparePartsSalesQuarter')}
                 </div>
               </th>
               <th 
@@ -399,7 +399,7 @@ const AfterSalesTable: React.FC = () => {
                   <div className="text-sm text-white">{dealer.wStockPercent}</div>
                 </td>
                 <td className="px-6 py-4 whitespace-nowrap text-center">
-                  <div className="text-sm text-white">{dealer.sparePartsSalesQ3}</div>
+                  <div className="text-sm text-white">{dealer.sparePartsSalesQuarter}</div>
                 </td>
                 <td className="px-6 py-4 whitespace-nowrap text-center">
                   <div className="text-sm text-white">{dealer.sparePartsSalesYtd}</div>
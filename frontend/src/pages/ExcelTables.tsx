@@ -1,31 +1,52 @@
 import React, { useEffect, useState } from 'react';
-import { useExcelTables, useExcelTableData } from '../hooks/useExcel';
+import { useImportDatasets, useImportDatasetData } from '../hooks/useExcel';
+import { ImportDataset, ImportStatus } from '../api/excel';
+
+const statusLabels: Record<ImportStatus, string> = {
+  completed: 'Загружено',
+  failed: 'Ошибка',
+  superseded: 'Заменено',
+  deleted: 'В корзине',
+  purged: 'Удалено',
+};
+
+// Строки квартала пишут файл dealer_net и пакеты через API; брендам собственных данных не нужно
+const holdsQuarterData = (dataset: ImportDataset) =>
+  dataset.kind === 'dealer_net' || dataset.kind === 'ingest';
+
+// Данные доступны только у актуальной загрузки квартала
+const hasData = (dataset: ImportDataset) =>
+  holdsQuarterData(dataset) && dataset.status === 'completed';
+
+// Из корзины восстанавливается загрузка квартала вместе с брендами
+const isRestorable = (dataset: ImportDataset) =>
+  holdsQuarterData(dataset) && dataset.status === 'deleted' && !!dataset.deleted_at;
 
 const ExcelTablesPage: React.FC = () => {
-  const { tables, loading, error, fetchTables, deleteTable } = useExcelTables();
-  const [selectedTable, setSelectedTable] = useState<string | null>(null);
-  const [showDeleteConfirm, setShowDeleteConfirm] = useState<string | null>(null);
-  const { data: tableData, loading: dataLoading, fetchData } = useExcelTableData(selectedTable);
+  const { datasets, loading, error, fetchDatasets, deleteDataset, restoreDataset } = useImportDatasets();
+  const [selectedDataset, setSelectedDataset] = useState<ImportDataset | null>(null);
+  const [showDeleteConfirm, setShowDeleteConfirm] = useState<ImportDataset | null>(null);
+  const { data: tableData, loading: dataLoading, fetchData } = useImportDatasetData(selectedDataset?.id ?? null);
 
   useEffect(() => {
-    fetchTables();
-  }, [fetchTables]);
+    fetchDatasets();
+  }, [fetchDatasets]);
 
   useEffect(() => {
-    if (selectedTable) {
-      fetchData(10, 0);
+    if (selectedDataset) {
+      fetchData(10, 1);
     }
-  }, [selectedTable, fetchData]);
+  }, [selectedDataset, fetchData]);
 
-  const handleDeleteTable = async (tableName: string) => {
+  const handleDeleteDataset = async (id: number) => {
     try {
-      await deleteTable(tableName);
+      await deleteDataset(id);
       setShowDeleteConfirm(null);
-      if (selectedTable === tableName) {
-        setSelectedTable(null);
+      if (selectedDataset?.id === id) {
+        setSelectedDataset(null);
       }
     } catch (err) {
-      console.error('Failed to delete table:', err);
+      console.error('Failed to delete import:', err);
     }
   };
 
@@ -38,7 +59,7 @@ const ExcelTablesPage: React.FC = () => {
       <div className="min-h-screen bg-gray-50 flex items-center justify-center">
         <div className="text-center">
           <div className="animate-spin rounded-full h-12 w-12 border-b-2 border-blue-600 mx-auto mb-4"></div>
-          <p className="text-gray-600">Загрузка таблиц...</p>
+          <p className="text-gray-600">Загрузка каталога...</p>
         </div>
       </div>
     );
@@ -49,10 +70,10 @@ const ExcelTablesPage: React.FC = () => {
       <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8">
         <div className="mb-8">
           <h1 className="text-3xl font-bold text-gray-900 mb-2">
-            Excel таблицы
+            Загрузки Excel
           </h1>
           <p className="text-gray-600">
-            Просмотр и управление созданными таблицами
+            Каталог загруженных файлов: период, статус и данные загрузки
           </p>
         </div>
 
@@ -63,50 +84,77 @@ const ExcelTablesPage: React.FC = () => {
         )}
 
         <div className="grid grid-cols-1 lg:grid-cols-3 gap-6">
-          {/* Список таблиц */}
+          {/* Каталог загрузок */}
           <div className="lg:col-span-1">
             <div className="bg-white rounded-lg shadow-lg p-6">
               <h2 className="text-lg font-semibold text-gray-900 mb-4">
-                Созданные таблицы ({tables.length})
+                Загрузки ({datasets.length})
               </h2>
               
-              {tables.length === 0 ? (
+              {datasets.length === 0 ? (
                 <p className="text-gray-500 text-center py-8">
-                  Нет созданных таблиц
+                  Нет загрузок
                 </p>
               ) : (
                 <div className="space-y-3">
-                  {tables.map((table) => (
+                  {datasets.map((dataset) => (
                     <div
-                      key={table.table_name}
-                      className={`p-4 border rounded-lg cursor-pointer transition-colors ${
-                        selectedTable === table.table_name
+                      key={dataset.id}
+                      className={`p-4 border rounded-lg transition-colors ${
+                        hasData(dataset) ? 'cursor-pointer' : 'opacity-70'
+                      } ${
+                        selectedDataset?.id === dataset.id
                           ? 'border-blue-500 bg-blue-50'
                           : 'border-gray-200 hover:border-gray-300'
                       }`}
-                      onClick={() => setSelectedTable(table.table_name)}
+                      onClick={() => hasData(dataset) && setSelectedDataset(dataset)}
                     >
                       <div className="flex justify-between items-start">
                         <div className="flex-1">
                           <h3 className="font-medium text-gray-900 text-sm">
-                            {table.table_name}
+                            {dataset.source_file}
                           </h3>
                           <p className="text-xs text-gray-500 mt-1">
-                            {table.rows_count} строк • {table.columns.length} колонок
+                            {dataset.kind} • {dataset.quarter} {dataset.year} • {statusLabels[dataset.status]}
+                          </p>
+                          <p className="text-xs text-gray-500 mt-1">
+                            {dataset.row_count} строк • {dataset.schema.columns?.length ?? 0} колонок
                           </p>
+                          {dataset.error && (
+                            <p className="text-xs text-red-500 mt-1">{dataset.error}</p>
+                          )}
                           <p className="text-xs text-gray-400 mt-1">
-                            {formatDate(table.created_at)}
+                            {formatDate(dataset.created_at)}
+                            {dataset.imported_by && ` • ${dataset.imported_by}`}
                           </p>
+                          {dataset.purge_at && (
+                            <p className="text-xs text-orange-500 mt-1">
+                              Будет удалено окончательно {formatDate(dataset.purge_at)}
+                            </p>
+                          )}
                         </div>
-                        <button
-                          onClick={(e) => {
-                            e.stopPropagation();
-                            setShowDeleteConfirm(table.table_name);
-                          }}
-                          className="text-red-500 hover:text-red-700 text-xs"
-                        >
-                          Удалить
-                        </button>
+                        {hasData(dataset) && (
+                          <button
+                            onClick={(e) => {
+                              e.stopPropagation();
+                              setShowDeleteConfirm(dataset);
+                            }}
+                            className="text-red-500 hover:text-red-700 text-xs"
+                          >
+                            Удалить
+                          </button>
+                        )}
+                        {isRestorable(dataset) && (
+                          <button
+                            onClick={(e) => {
+                              e.stopPropagation();
+                              restoreDataset(dataset.id);
+                            }}
+                            className="text-blue-500 hover:text-blue-700 text-xs"
+                          >
+                            Восстановить
+                          </button>
+                        )}
                       </div>
                     </div>
                   ))}
@@ -115,16 +163,16 @@ const ExcelTablesPage: React.FC = () => {
             </div>
           </div>
 
-          {/* Данные таблицы */}
+          {/* Данные загрузки */}
           <div className="lg:col-span-2">
-            {selectedTable ? (
+            {selectedDataset ? (
               <div className="bg-white rounded-lg shadow-lg p-6">
                 <div className="flex justify-between items-center mb-6">
                   <h2 className="text-lg font-semibold text-gray-900">
-                    Данные таблицы: {selectedTable}
+                    Данные загрузки: {selectedDataset.source_file}
                   </h2>
                   <button
-                    onClick={() => setSelectedTable(null)}
+                    onClick={() => setSelectedDataset(null)}
                     className="text-gray-500 hover:text-gray-700"
                   >
                     <svg className="w-5 h-5" fill="none" stroke="currentColor" viewBox="0 0 24 24">
@@ -192,10 +240,10 @@ const ExcelTablesPage: React.FC = () => {
                     </svg>
                   </div>
                   <h3 className="text-lg font-medium text-gray-900 mb-2">
-                    Выберите таблицу
+                    Выберите загрузку
                   </h3>
                   <p className="text-gray-500">
-                    Выберите таблицу из списка слева для просмотра данных
+                    Выберите загрузку из списка слева для просмотра данных
                   </p>
                 </div>
               </div>
@@ -211,12 +259,12 @@ const ExcelTablesPage: React.FC = () => {
                 Подтверждение удаления
               </h3>
               <p className="text-gray-600 mb-6">
-                Вы уверены, что хотите удалить таблицу "{showDeleteConfirm}"? 
-                Это действие нельзя отменить.
+                Вы уверены, что хотите удалить данные загрузки "{showDeleteConfirm.source_file}" за {showDeleteConfirm.quarter} {showDeleteConfirm.year}? 
+                Квартал можно будет восстановить из корзины до окончания срока хранения.
               </p>
               <div className="flex space-x-4">
                 <button
-                  onClick={() => handleDeleteTable(showDeleteConfirm)}
+                  onClick={() => handleDeleteDataset(showDeleteConfirm.id)}
                   className="flex-1 bg-red-600 text-white px-4 py-2 rounded-lg hover:bg-red-700 transition-colors"
                 >
                   Удалить
@@ -228,13 +228,13 @@ const ExcelUploadPage: React.FC = () => {
                   </svg>
                 </div>
                 <div>
-                  <p className="text-lg font-medium text-gray-900">Выберите Excel файл</p>
-                  <p className="text-sm text-gray-500">Поддерживаются файлы .xlsx и .xls</p>
+                  <p className="text-lg font-medium text-gray-900">Выберите файл</p>
+                  <p className="text-sm text-gray-500">Поддерживаются файлы .xlsx, .ods, .csv и .zip с CSV по регионам</p>
                 </div>
                 <input
                   ref={fileInputRef}
                   type="file"
-                  accept=".xlsx,.xls"
+                  accept=".xlsx,.ods,.csv,.zip"
                   onChange={handleFileSelect}
                   className="hidden"
                 />
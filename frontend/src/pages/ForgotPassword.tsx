@@ -1,64 +1,30 @@
 import React, { useState } from 'react'
 import { Link } from 'react-router-dom'
+import { forgotPassword } from '../api/auth'
 
+// Запрос ссылки для сброса пароля. Сервер отвечает одинаково, есть такой пользователь или нет,
+// поэтому после отправки всегда показывается одно и то же сообщение
 const ForgotPassword: React.FC = () => {
-  const [step, setStep] = useState<'email' | 'code'>('email')
-  const [email, setEmail] = useState('')
-  const [code, setCode] = useState('')
+  const [login, setLogin] = useState('')
   const [isLoading, setIsLoading] = useState(false)
-  const [message, setMessage] = useState('')
+  const [sent, setSent] = useState(false)
+  const [error, setError] = useState<string | null>(null)
 
-  const handleSendCode = async (e: React.FormEvent) => {
+  const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
     setIsLoading(true)
-    setMessage('')
+    setError(null)
 
     try {
-      // Simulate API call to send code
-      await new Promise(resolve => setTimeout(resolve, 1000))
-      
-      // Check if email exists in database (simulated)
-      if (email.includes('@')) {
-        setMessage('Verification code has been sent to your email')
-        setStep('code')
-      } else {
-        setMessage('Email not found in our database')
-      }
-    } catch (error) {
-      setMessage('Error sending code. Please try again.')
+      await forgotPassword(login.trim())
+      setSent(true)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to request password reset')
     } finally {
       setIsLoading(false)
     }
   }
 
-  const handleVerifyCode = async (e: React.FormEvent) => {
-    e.preventDefault()
-    setIsLoading(true)
-    setMessage('')
-
-    try {
-      // Simulate API call to verify code
-      await new Promise(resolve => setTimeout(resolve, 1000))
-      
-      if (code.length === 6) {
-        setMessage('Code verified successfully! You can now reset your password.')
-        // Here you would typically redirect to password reset page
-      } else {
-        setMessage('Invalid code. Please check and try again.')
-      }
-    } catch (error) {
-      setMessage('Error verifying code. Please try again.')
-    } finally {
-      setIsLoading(false)
-    }
-  }
-
-  const handleBackToEmail = () => {
-    setStep('email')
-    setCode('')
-    setMessage('')
-  }
-
   return (
     <div className="min-h-screen bg-gradient-to-br from-blue-900 via-blue-800 to-blue-700 flex items-center justify-center px-4">
       <div className="max-w-md w-full">
@@ -88,109 +54,70 @@ const ForgotPassword: React.FC = () => {
           </Link>
         </div>
 
+
         {/* Form Container */}
         <div className="bg-white rounded-3xl shadow-2xl p-8">
           {/* Header */}
           <div className="text-center mb-8">
             <h1 className="text-3xl font-bold text-gray-900 mb-2">
-              {step === 'email' ? 'Reset Password' : 'Enter Code'}
+              Reset Password
             </h1>
             <p className="text-gray-600">
-              {step === 'email' 
-                ? 'Enter your email to receive a verification code'
-                : `We sent a code to ${email}`
+              {sent
+                ? 'Check your inbox'
+                : 'Enter your login or email to receive a reset link'
               }
             </p>
           </div>
 
           {/* Message Display */}
-          {message && (
-            <div className={`mb-6 p-4 rounded-xl text-sm ${
-              message.includes('Error') || message.includes('Invalid') || message.includes('not found')
-                ? 'bg-red-50 text-red-700 border border-red-200'
-                : 'bg-green-50 text-green-700 border border-green-200'
-            }`}>
-              {message}
+          {error && (
+            <div className="mb-6 p-4 rounded-xl text-sm bg-red-50 text-red-700 border border-red-200">
+              {error}
             </div>
           )}
 
-          {/* Email Step */}
-          {step === 'email' && (
-            <form onSubmit={handleSendCode} className="space-y-6">
-              <div>
-                <label htmlFor="email" className="block text-sm font-medium text-gray-700 mb-2">
-                  Email Address
-                </label>
-                <div className="relative">
-                  <div className="absolute inset-y-0 left-0 pl-3 flex items-center pointer-events-none">
-                    <svg className="h-5 w-5 text-gray-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-                      <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M16 12a4 4 0 10-8 0 4 4 0 008 0zm0 0v1.5a2.5 2.5 0 005 0V12a9 9 0 10-9 9m4.5-1.206a8.959 8.959 0 01-4.5 1.207" />
-                    </svg>
-                  </div>
-                  <input
-                    id="email"
-                    type="email"
-                    required
-                    value={email}
-                    onChange={(e) => setEmail(e.target.value)}
-                    className="block w-full pl-10 pr-3 py-3 border border-gray-300 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent transition-all duration-200"
-                    placeholder="Enter your email address"
-                  />
-                </div>
+          {sent ? (
+            <div className="space-y-6">
+              <div className="p-4 rounded-xl text-sm bg-green-50 text-green-700 border border-green-200">
+                If an account with this login or email exists, we have sent a link to set a new password.
+                The link can be used once and expires soon.
               </div>
-
-              <button
-                type="submit"
-                disabled={isLoading}
-                className="w-full flex justify-center py-3 px-4 border border-transparent rounded-xl shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200 transform hover:scale-105 disabled:opacity-50 disabled:cursor-not-allowed disabled:transform-none"
+              <Link
+                to="/login"
+                className="w-full flex justify-center py-3 px-4 border border-transparent rounded-xl shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200"
               >
-                {isLoading ? (
-                  <div className="flex items-center">
-                    <svg className="animate-spin -ml-1 mr-3 h-5 w-5 text-white" xmlns="http://www.w3.org/2000/svg" fill="none" viewBox="0 0 24 24">
-                      <circle className="opacity-25" cx="12" cy="12" r="10" stroke="currentColor" strokeWidth="4"></circle>
-                      <path className="opacity-75" fill="currentColor" d="M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z"></path>
-                    </svg>
-                    Sending...
-                  </div>
-                ) : (
-                  'Send Code'
-                )}
-              </button>
-            </form>
-          )}
-
-          {/* Code Step */}
-          {step === 'code' && (
-            <form onSubmit={handleVerifyCode} className="space-y-6">
+                Back to Sign In
+              </Link>
+            </div>
+          ) : (
+            <form onSubmit={handleSubmit} className="space-y-6">
               <div>
-                <label htmlFor="code" className="block text-sm font-medium text-gray-700 mb-2">
-                  Verification Code
+                <label htmlFor="login" className="block text-sm font-medium text-gray-700 mb-2">
+                  Login or Email
                 </label>
                 <div className="relative">
                   <div className="absolute inset-y-0 left-0 pl-3 flex items-center pointer-events-none">
                     <svg className="h-5 w-5 text-gray-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
-                      <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M9 12l2 2 4-4m6 2a9 9 0 11-18 0 9 9 0 0118 0z" />
+                      <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M16 12a4 4 0 10-8 0 4 4 0 008 0zm0 0v1.5a2.5 2.5 0 005 0V12a9 9 0 10-9 9m4.5-1.206a8.959 8.959 0 01-4.5 1.207" />
                     </svg>
                   </div>
                   <input
-                    id="code"
+                    id="login"
                     type="text"
                     required
-                    maxLength={6}
-                    value={code}
-                    onChange={(e) => setCode(e.target.value.replace(/\D/g, ''))}
-                    className="block w-full pl-10 pr-3 py-3 border border-gray-300 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent transition-all duration-200 text-center text-lg font-mono tracking-widest"
-                    placeholder="000000"
+                    autoComplete="username"
+                    value={login}
+                    onChange={(e) => setLogin(e.target.value)}
+                    className="block w-full pl-10 pr-3 py-3 border border-gray-300 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent transition-all duration-200"
+                    placeholder="Enter your login or email"
                   />
                 </div>
-                <p className="text-xs text-gray-500 mt-2 text-center">
-                  Enter the 6-digit code sent to your email
-                </p>
               </div>
 
               <button
                 type="submit"
-                disabled={isLoading || code.length !== 6}
+                disabled={isLoading || !login.trim()}
                 className="w-full flex justify-center py-3 px-4 border border-transparent rounded-xl shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200 transform hover:scale-105 disabled:opacity-50 disabled:cursor-not-allowed disabled:transform-none"
               >
                 {isLoading ? (
@@ -199,21 +126,12 @@ const ForgotPassword: React.FC = () => {
                       <circle className="opacity-25" cx="12" cy="12" r="10" stroke="currentColor" strokeWidth="4"></circle>
                       <path className="opacity-75" fill="currentColor" d="M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z"></path>
                     </svg>
-                    Verifying...
+                    Sending...
                   </div>
                 ) : (
-                  'Verify Code'
+                  'Send Reset Link'
                 )}
               </button>
-
-              {/* Back to Email Button */}
-              <button
-                type="button"
-                onClick={handleBackToEmail}
-                className="w-full py-2 px-4 border border-gray-300 rounded-xl text-sm font-medium text-gray-700 bg-white hover:bg-gray-50 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200"
-              >
-                Back to Email
-              </button>
             </form>
           )}
 
@@ -1,6 +1,8 @@
-import React, { useState } from 'react'
+import React, { useEffect, useState } from 'react'
 import { Link, useNavigate, useLocation } from 'react-router-dom'
 import { useAuth } from '../contexts/AuthContext'
+import { getAuthProviders, getOidcLoginUrl, TwoFactorChallenge } from '../api/auth'
+import TwoFactorStep from '../components/TwoFactorStep'
 
 const Login: React.FC = () => {
   const [formData, setFormData] = useState({
@@ -9,6 +11,8 @@ const Login: React.FC = () => {
   })
   const [showPassword, setShowPassword] = useState(false)
   const [isLoading, setIsLoading] = useState(false)
+  const [oidcEnabled, setOidcEnabled] = useState(false)
+  const [challenge, setChallenge] = useState<TwoFactorChallenge | null>(null)
   
   const { login, error, clearError } = useAuth()
   const navigate = useNavigate()
@@ -20,6 +24,13 @@ const Login: React.FC = () => {
   console.log('Login page - location.state:', location.state)
   console.log('Login page - from pathname:', from)
 
+  // Кнопка входа через корпоративный портал показывается, только если он настроен
+  useEffect(() => {
+    getAuthProviders()
+      .then(providers => setOidcEnabled(providers.oidc))
+      .catch(() => setOidcEnabled(false))
+  }, [])
+
   const handleInputChange = (e: React.ChangeEvent<HTMLInputElement>) => {
     const { name, value } = e.target
     setFormData(prev => ({
@@ -40,6 +51,12 @@ const Login: React.FC = () => {
         login: formData.username,
         password: formData.password
       })
+
+      // Нужен второй шаг: код из приложения-аутентификатора
+      if ('two_factor_required' in user) {
+        setChallenge(user)
+        return
+      }
       
       console.log('Login successful, user:', user)
       console.log('Redirecting to:', from)
@@ -96,7 +113,18 @@ const Login: React.FC = () => {
             </p>
           </div>
 
-          {/* Form */}
+          {challenge ? (
+            <TwoFactorStep
+              challenge={challenge}
+              onComplete={() => navigate(from, { replace: true })}
+              onCancel={() => {
+                clearError()
+                setChallenge(null)
+                setFormData(prev => ({ ...prev, password: '' }))
+              }}
+            />
+          ) : (
+          /* Form */
           <form onSubmit={handleSubmit} className="space-y-6">
             {/* Error Message */}
             {error && (
@@ -207,6 +235,16 @@ const Login: React.FC = () => {
               )}
             </button>
 
+            {/* Corporate SSO */}
+            {oidcEnabled && (
+              <a
+                href={getOidcLoginUrl(from)}
+                className="w-full flex justify-center py-3 px-4 border border-gray-300 rounded-xl text-sm font-medium text-gray-700 bg-white hover:bg-gray-50 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200"
+              >
+                Sign in with corporate account
+              </a>
+            )}
+
             {/* Don't have account */}
             <div className="text-center">
               <span className="text-sm text-gray-600">Don't have account? </span>
@@ -215,6 +253,7 @@ const Login: React.FC = () => {
               </a>
             </div>
           </form>
+          )}
 
 
 
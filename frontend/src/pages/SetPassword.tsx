@@ -0,0 +1,157 @@
+import React, { useEffect, useState } from 'react'
+import { Link } from 'react-router-dom'
+import { checkPasswordLink, getPasswordLinkToken, PasswordLink, resetPassword } from '../api/auth'
+
+// Установка пароля по ссылке из приглашения или письма о сбросе: /set-password#token=...
+const SetPassword: React.FC = () => {
+  const [token] = useState(() => getPasswordLinkToken(window.location.hash))
+  const [link, setLink] = useState<PasswordLink | null>(null)
+  const [linkError, setLinkError] = useState<string | null>(null)
+  const [password, setPassword] = useState('')
+  const [confirmation, setConfirmation] = useState('')
+  const [isLoading, setIsLoading] = useState(false)
+  const [error, setError] = useState<string | null>(null)
+  const [done, setDone] = useState(false)
+
+  useEffect(() => {
+    // Токен не должен оставаться в адресной строке и истории браузера
+    window.history.replaceState(null, '', window.location.pathname)
+
+    if (!token) {
+      setLinkError('Link is invalid or expired')
+      return
+    }
+    checkPasswordLink(token)
+      .then(setLink)
+      .catch(err => setLinkError(err instanceof Error ? err.message : 'Link is invalid or expired'))
+  }, [token])
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (password !== confirmation) {
+      setError('Passwords do not match')
+      return
+    }
+
+    setIsLoading(true)
+    setError(null)
+    try {
+      await resetPassword(token, password)
+      setDone(true)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to set password')
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  const inputClassName = 'block w-full px-3 py-3 border border-gray-300 rounded-xl focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-transparent transition-all duration-200'
+
+  const renderContent = () => {
+    if (done) {
+      return (
+        <div className="space-y-6">
+          <div className="p-4 rounded-xl text-sm bg-green-50 text-green-700 border border-green-200">
+            Your password has been set. Sign in with your login and the new password.
+          </div>
+          <Link
+            to="/login"
+            className="w-full flex justify-center py-3 px-4 border border-transparent rounded-xl shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 transition-all duration-200"
+          >
+            Go to Sign In
+          </Link>
+        </div>
+      )
+    }
+
+    if (linkError) {
+      return (
+        <div className="space-y-6 text-center">
+          <div className="p-4 rounded-xl text-sm bg-red-50 text-red-700 border border-red-200">
+            {linkError}
+          </div>
+          <Link to="/forgot-password" className="font-medium text-blue-600 hover:text-blue-500 transition-colors duration-200">
+            Request a new link
+          </Link>
+        </div>
+      )
+    }
+
+    if (!link) {
+      return <p className="text-center text-gray-600">Checking the link...</p>
+    }
+
+    return (
+      <form onSubmit={handleSubmit} className="space-y-6">
+        {error && (
+          <div className="p-4 rounded-xl text-sm bg-red-50 text-red-700 border border-red-200">
+            {error}
+          </div>
+        )}
+
+        <div>
+          <label htmlFor="password" className="block text-sm font-medium text-gray-700 mb-2">
+            New Password
+          </label>
+          <input
+            id="password"
+            type="password"
+            required
+            autoComplete="new-password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            className={inputClassName}
+            placeholder="Enter a new password"
+          />
+        </div>
+
+        <div>
+          <label htmlFor="confirmation" className="block text-sm font-medium text-gray-700 mb-2">
+            Confirm Password
+          </label>
+          <input
+            id="confirmation"
+            type="password"
+            required
+            autoComplete="new-password"
+            value={confirmation}
+            onChange={(e) => setConfirmation(e.target.value)}
+            className={inputClassName}
+            placeholder="Repeat the new password"
+          />
+        </div>
+
+        <button
+          type="submit"
+          disabled={isLoading || !password || !confirmation}
+          className="w-full flex justify-center py-3 px-4 border border-transparent rounded-xl shadow-sm text-sm font-medium text-white bg-blue-600 hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-blue-500 transition-all duration-200 disabled:opacity-50 disabled:cursor-not-allowed"
+        >
+          {isLoading ? 'Saving...' : 'Set Password'}
+        </button>
+
+        <p className="text-xs text-gray-500 text-center">
+          The link expires {new Date(link.expires_at).toLocaleString()}
+        </p>
+      </form>
+    )
+  }
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-blue-900 via-blue-800 to-blue-700 flex items-center justify-center px-4">
+      <div className="max-w-md w-full bg-white rounded-3xl shadow-2xl p-8">
+        <div className="text-center mb-8">
+          <h1 className="text-3xl font-bold text-gray-900 mb-2">
+            {link?.purpose === 'invite' ? 'Welcome' : 'Set New Password'}
+          </h1>
+          <p className="text-gray-600">
+            {link ? `Choose a password for ${link.login}` : 'Set a password for your account'}
+          </p>
+        </div>
+
+        {renderContent()}
+      </div>
+    </div>
+  )
+}
+
+export default SetPassword
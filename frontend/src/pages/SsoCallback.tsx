@@ -0,0 +1,55 @@
+import React, { useEffect, useState } from 'react'
+import { Link, useNavigate } from 'react-router-dom'
+import { completeOidcLogin, TwoFactorChallenge } from '../api/auth'
+import TwoFactorStep from '../components/TwoFactorStep'
+
+// Страница возврата после входа через корпоративный портал
+const SsoCallback: React.FC = () => {
+  const navigate = useNavigate()
+  const [error, setError] = useState<string | null>(null)
+  const [challenge, setChallenge] = useState<TwoFactorChallenge | null>(null)
+  const [redirect, setRedirect] = useState('/')
+
+  useEffect(() => {
+    try {
+      const { redirect, challenge } = completeOidcLogin(window.location.hash)
+      if (challenge) {
+        // Портал не подтвердил второй фактор: запрашиваем код платформы
+        setRedirect(redirect)
+        setChallenge(challenge)
+        return
+      }
+      // Полная перезагрузка, чтобы AuthContext прочитал новый токен
+      window.location.replace(redirect)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Login failed')
+    }
+  }, [])
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-blue-900 via-blue-800 to-blue-700 flex items-center justify-center px-4">
+      <div className="max-w-md w-full bg-white rounded-3xl shadow-2xl p-8 text-center">
+        {challenge ? (
+          <TwoFactorStep
+            challenge={challenge}
+            onComplete={() => window.location.replace(redirect)}
+            onCancel={() => navigate('/login', { replace: true })}
+          />
+        ) : error ? (
+          <>
+            <div className="bg-red-100 border border-red-400 text-red-700 px-4 py-3 rounded-lg mb-6">
+              {error}
+            </div>
+            <Link to="/login" className="font-medium text-blue-600 hover:text-blue-500 transition-colors duration-200">
+              Back to Sign In
+            </Link>
+          </>
+        ) : (
+          <p className="text-gray-600">Signing you in...</p>
+        )}
+      </div>
+    </div>
+  )
+}
+
+export default SsoCallback